@@ -0,0 +1,45 @@
+"""Checks the generated Python client against the JSON the server sends,
+using the golden files of the server tests. Run with make python-client-test."""
+
+import os
+import unittest
+
+import cracklord_client
+
+TESTDATA = os.path.join(os.path.dirname(__file__), "..", "..", "cmd", "queued", "testdata")
+
+
+def golden(name):
+    with open(os.path.join(TESTDATA, name)) as f:
+        return f.read()
+
+
+class TestModels(unittest.TestCase):
+    def test_job(self):
+        resp = cracklord_client.JobReadResp.from_json(golden("api_job.json"))
+        self.assertEqual(resp.status, 200)
+        self.assertEqual(resp.job.name, "Domain dump")
+        self.assertEqual(resp.job.outputdata[0][1], "password")
+        self.assertEqual(resp.job.checkpoint.crackedhashes, 2)
+        self.assertEqual(resp.job.history[0].action, "created")
+
+    def test_resources(self):
+        resp = cracklord_client.ResListResp.from_json(golden("api_resources.json"))
+        res = resp.resources[0]
+        self.assertEqual(res.name, "gpu01")
+        self.assertEqual(res.tools[0].id, "hashcat")
+        self.assertEqual(res.inventory.gpus[0].memory, 8192)
+        self.assertEqual(res.profiles, [])
+
+
+class TestAPI(unittest.TestCase):
+    def test_operations(self):
+        client = cracklord_client.ApiClient(cracklord_client.Configuration(host="http://localhost"))
+        jobs = cracklord_client.JobsApi(client)
+        for name in ("get_jobs", "create_job", "read_job", "job_results"):
+            self.assertTrue(hasattr(jobs, name), name)
+        self.assertTrue(hasattr(cracklord_client.UsersApi(client), "login"))
+
+
+if __name__ == "__main__":
+    unittest.main()
@@ -0,0 +1,104 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Groups of API endpoints access can be limited to networks for
+const (
+	ACCESS_API        = "api"        // Every API endpoint
+	ACCESS_LOGIN      = "login"      // Logging in
+	ACCESS_ADMIN      = "admin"      // Administering the cluster
+	ACCESS_ENROLLMENT = "enrollment" // Resources registering themselves
+)
+
+// Resources registering themselves by name
+func isEnrollmentRequest(path string) bool {
+	return strings.HasPrefix(path, "/api/resources/named/")
+}
+
+// Check if a request is for an endpoint of an access group. Enrollment is under
+// the resource endpoints but is not administration, so resources only have to
+// be on the enrollment networks.
+func inAccessGroup(group string, r *http.Request) bool {
+	switch group {
+	case ACCESS_API:
+		return strings.HasPrefix(r.URL.Path, "/api/")
+	case ACCESS_LOGIN:
+		return r.URL.Path == "/api/login"
+	case ACCESS_ADMIN:
+		return isAdminRequest(r.Method, r.URL.Path) && !isEnrollmentRequest(r.URL.Path)
+	case ACCESS_ENROLLMENT:
+		return isEnrollmentRequest(r.URL.Path)
+	}
+	return false
+}
+
+// The networks clients of an access group must be in, any when Allow is
+// empty, and those they must not be in
+type AccessRule struct {
+	Group string
+	Allow []*net.IPNet
+	Deny  []*net.IPNet
+}
+
+// Check if a client is refused by the rule and why
+func (a AccessRule) refuses(remote string) (string, bool) {
+	if inNetworks(a.Deny, remote) {
+		return "denied network", true
+	}
+	if len(a.Allow) > 0 && !inNetworks(a.Allow, remote) {
+		return "not an allowed network", true
+	}
+	return "", false
+}
+
+// Negroni middleware allowing or denying clients by network for groups of API
+// endpoints, for deployments that cannot put a firewall in front of the queue
+// server. A request must pass the rules of every group it is in.
+type AccessMiddleware struct {
+	M     *MessageCatalog
+	Rules []AccessRule
+}
+
+func NewAccessMiddleware(m *MessageCatalog, rules []AccessRule) *AccessMiddleware {
+	return &AccessMiddleware{M: m, Rules: rules}
+}
+
+func (a *AccessMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	remote := clientIP(r.RemoteAddr, r.Header.Get("X-Forwarded-For"))
+
+	for _, rule := range a.Rules {
+		if !inAccessGroup(rule.Group, r) {
+			continue
+		}
+
+		reason, refused := rule.refuses(remote)
+		if !refused {
+			continue
+		}
+
+		var resp ErrorResp
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_ACCESS_DENIED)
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		newRespEncoder(rw).Encode(resp)
+
+		log.WithFields(log.Fields{
+			"group":  rule.Group,
+			"reason": reason,
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"remote": remote,
+		}).Warn("Request refused by the network access rules.")
+
+		return
+	}
+
+	next(rw, r)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessGroups(t *testing.T) {
+	for _, c := range []struct {
+		method, path string
+		groups       []string
+	}{
+		{"POST", "/api/login", []string{ACCESS_API, ACCESS_LOGIN}},
+		{"GET", "/api/jobs", []string{ACCESS_API}},
+		{"GET", "/api/resources", []string{ACCESS_API, ACCESS_ADMIN}},
+		{"PUT", "/api/queue", []string{ACCESS_API, ACCESS_ADMIN}},
+		{"GET", "/api/resources/named/gpu1", []string{ACCESS_API, ACCESS_ENROLLMENT}},
+		{"PUT", "/api/resources/named/gpu1", []string{ACCESS_API, ACCESS_ENROLLMENT}},
+		{"GET", "/index.html", nil},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+
+		var got []string
+		for _, group := range []string{ACCESS_API, ACCESS_LOGIN, ACCESS_ADMIN, ACCESS_ENROLLMENT} {
+			if inAccessGroup(group, r) {
+				got = append(got, group)
+			}
+		}
+		if len(got) != len(c.groups) {
+			t.Errorf("Expected %s %s to be in %v, got %v", c.method, c.path, c.groups, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.groups[i] {
+				t.Errorf("Expected %s %s to be in %v, got %v", c.method, c.path, c.groups, got)
+				break
+			}
+		}
+	}
+}
+
+func TestAccessRuleRefuses(t *testing.T) {
+	rule := AccessRule{
+		Allow: parseNetworks("10.0.0.0/8"),
+		Deny:  parseNetworks("10.0.5.0/24"),
+	}
+
+	for remote, refused := range map[string]bool{
+		"10.1.2.3":    false,
+		"10.0.5.7":    true, // Denied even though it is also allowed
+		"192.168.1.1": true,
+	} {
+		if _, got := rule.refuses(remote); got != refused {
+			t.Errorf("Expected %s to be refused %v, got %v", remote, refused, got)
+		}
+	}
+
+	// Without an allow list only the denied networks are refused
+	rule.Allow = nil
+	if _, got := rule.refuses("192.168.1.1"); got {
+		t.Error("Client outside the deny list was refused without an allow list")
+	}
+	if _, got := rule.refuses("10.0.5.7"); !got {
+		t.Error("Denied client was served without an allow list")
+	}
+}
+
+func TestSetupAccessAdminNetworks(t *testing.T) {
+	if m := setupAccess(map[string]string{}, NewMessageCatalog(), nil); m != nil {
+		t.Errorf("Expected no middleware without rules, got %+v", m.Rules)
+	}
+
+	// AdminNetworks alone limits administration
+	m := setupAccess(map[string]string{}, NewMessageCatalog(), parseNetworks("10.0.9.0/24"))
+	if m == nil || len(m.Rules) != 1 || m.Rules[0].Group != ACCESS_ADMIN || len(m.Rules[0].Allow) != 1 {
+		t.Fatalf("Expected an admin rule for AdminNetworks, got %+v", m)
+	}
+
+	// And adds to the admin networks of the Access section
+	m = setupAccess(map[string]string{
+		"adminallow":     "10.0.5.0/24",
+		"enrollmentdeny": "10.0.66.0/24",
+	}, NewMessageCatalog(), parseNetworks("10.0.9.0/24"))
+	if m == nil || len(m.Rules) != 2 {
+		t.Fatalf("Expected admin and enrollment rules, got %+v", m)
+	}
+	admin := m.Rules[0]
+	if admin.Group != ACCESS_ADMIN || len(admin.Allow) != 2 {
+		t.Errorf("Expected AdminNetworks to be merged into the admin rule, got %+v", admin)
+	}
+	for remote, refused := range map[string]bool{"10.0.5.1": false, "10.0.9.1": false, "10.0.7.1": true} {
+		if _, got := admin.refuses(remote); got != refused {
+			t.Errorf("Expected %s to be refused administration %v, got %v", remote, refused, got)
+		}
+	}
+}
+
+func TestAccessMiddleware(t *testing.T) {
+	// The example configuration, resources enroll from their own network
+	m := setupAccess(map[string]string{
+		"adminallow":      "10.0.5.0/24",
+		"enrollmentallow": "10.0.64.0/18",
+	}, NewMessageCatalog(), nil)
+
+	for _, c := range []struct {
+		method, path, remote string
+		served               bool
+	}{
+		{"PUT", "/api/resources/named/gpu1", "10.0.64.9", true},
+		{"PUT", "/api/resources/named/gpu1", "10.0.5.9", false},
+		{"GET", "/api/resources", "10.0.5.9", true},
+		{"GET", "/api/resources", "10.0.64.9", false},
+		{"GET", "/api/jobs", "192.168.1.1", true},
+	} {
+		served := false
+		next := func(rw http.ResponseWriter, r *http.Request) {
+			served = true
+		}
+
+		r := httptest.NewRequest(c.method, c.path, nil)
+		r.RemoteAddr = c.remote + ":40000"
+		rw := httptest.NewRecorder()
+		m.ServeHTTP(rw, r, next)
+
+		if served != c.served {
+			t.Errorf("Expected %s %s from %s to be served %v", c.method, c.path, c.remote, c.served)
+		}
+		if c.served {
+			continue
+		}
+
+		var resp ErrorResp
+		json.NewDecoder(rw.Body).Decode(&resp)
+		if rw.Code != RESP_CODE_FORBIDDEN || resp.Status != RESP_CODE_FORBIDDEN || resp.MessageKey != MSG_ACCESS_DENIED {
+			t.Errorf("Expected %s %s from %s to get a 403, got %d %+v", c.method, c.path, c.remote, rw.Code, resp)
+		}
+	}
+}
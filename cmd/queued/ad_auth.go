@@ -29,6 +29,16 @@ func (a *ADAuth) SetRealm(realm string) {
 	log.WithField("realm", realm).Debug("AD authentication realm set.")
 }
 
+// Check that a domain controller for the realm can be reached
+func (a *ADAuth) Healthy() error {
+	conn, err := kerb.DefaultDial("tcp", a.realm)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
 // Function to log in a user
 func (a *ADAuth) Login(user, pass string) (User, error) {
 	// Setup Credential Config
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// Endpoints that administer the cluster rather than run jobs, every method of
+// them. Operations of other endpoints only Administrators can call are found
+// through Admin in apiOperations.
+var adminPaths = []string{
+	"/api/resources",
+	"/api/resourcemanagers",
+	"/api/binaries",
+	"/api/stats/routes",
+}
+
+// Check if a request is for one of the endpoints that administer the cluster
+func isAdminRequest(method, path string) bool {
+	for _, p := range adminPaths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+
+	for _, op := range apiOperations {
+		if op.Admin && op.Method == method && matchPath(op.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a path is one of a path template of apiOperations, where a {name}
+// segment is any one segment
+func matchPath(template, path string) bool {
+	want := strings.Split(template, "/")
+	got := strings.Split(path, "/")
+	if len(want) != len(got) {
+		return false
+	}
+
+	for i := range want {
+		if pathParam.MatchString(want[i]) {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type adminListenerKey struct{}
+
+// Mark the requests of a listener as coming in on one for administration
+func adminListener(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), adminListenerKey{}, true)))
+	})
+}
+
+func fromAdminListener(r *http.Request) bool {
+	admin, _ := r.Context().Value(adminListenerKey{}).(bool)
+	return admin
+}
+
+// Negroni middleware keeping the endpoints that administer the cluster away
+// from the analysts using the job API. When Split is set they are only served
+// on the administrative listeners and are not found on the others.
+type AdminMiddleware struct {
+	Split bool
+}
+
+func NewAdminMiddleware(split bool) *AdminMiddleware {
+	return &AdminMiddleware{Split: split}
+}
+
+func (a *AdminMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if a.Split && isAdminRequest(r.Method, r.URL.Path) && !fromAdminListener(r) {
+		log.WithFields(log.Fields{
+			"method": r.Method,
+			"path":   r.URL.Path,
+			"remote": r.RemoteAddr,
+		}).Debug("Administrative endpoint requested on the job API listener.")
+
+		http.NotFound(rw, r)
+		return
+	}
+
+	next(rw, r)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminRequests(t *testing.T) {
+	for _, c := range []struct {
+		method, path string
+		admin        bool
+	}{
+		{"GET", "/api/resources", true},
+		{"GET", "/api/resources/manager/id", true},
+		{"GET", "/api/stats/routes", true},
+		{"GET", "/api/queues", false},
+		{"PUT", "/api/queues/gpu", true},
+		{"DELETE", "/api/queues/gpu", true},
+		{"PUT", "/api/queue", true},
+		{"POST", "/api/queue/simulate", true},
+		{"GET", "/api/queue/export", true},
+		{"POST", "/api/queue/import", true},
+		{"GET", "/api/reservations", false},
+		{"POST", "/api/reservations", true},
+		{"DELETE", "/api/reservations/id", true},
+		{"GET", "/api/jobs", false},
+		{"PUT", "/api/jobs/id", false},
+		{"PUT", "/api/jobs/id/queue", true},
+		{"POST", "/api/jobs/id/approve", true},
+		{"PUT", "/api/jobs//queue", false},
+		{"GET", "/api/tools/id/defaults", false},
+		{"PUT", "/api/tools/id/defaults", true},
+	} {
+		if got := isAdminRequest(c.method, c.path); got != c.admin {
+			t.Errorf("Expected %s %s to be administrative %v, got %v", c.method, c.path, c.admin, got)
+		}
+	}
+}
+
+func TestAdminOperationsNeedAdministrator(t *testing.T) {
+	a, token := testController(t)
+
+	// Operations kept to the administrative listeners are refused to other
+	// users, so none an analyst needs is hidden from them
+	for _, op := range apiOperations {
+		if !op.Admin {
+			continue
+		}
+
+		rw := apiRequest(a, op.Method, pathParam.ReplaceAllString(op.Path, "x"), token, nil)
+		if rw.Code != RESP_CODE_UNAUTHORIZED {
+			t.Errorf("Expected %s %s to refuse a Standard User, got %d", op.Method, op.Path, rw.Code)
+		}
+	}
+}
+
+func TestAdminMiddlewareSplit(t *testing.T) {
+	next := func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	for _, c := range []struct {
+		method, path string
+		admin        bool
+		want         int
+	}{
+		{"PUT", "/api/queue", false, http.StatusNotFound},
+		{"PUT", "/api/queue", true, http.StatusOK},
+		{"PUT", "/api/queues/gpu", false, http.StatusNotFound},
+		{"POST", "/api/reservations", false, http.StatusNotFound},
+		{"GET", "/api/reservations", false, http.StatusOK},
+		{"GET", "/api/jobs", false, http.StatusOK},
+	} {
+		r := httptest.NewRequest(c.method, c.path, nil)
+		rw := httptest.NewRecorder()
+		h := http.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			NewAdminMiddleware(true).ServeHTTP(rw, r, next)
+		}))
+		if c.admin {
+			h = adminListener(h)
+		}
+		h.ServeHTTP(rw, r)
+
+		if rw.Code != c.want {
+			t.Errorf("Expected %s %s from an admin listener %v to give %d, got %d", c.method, c.path, c.admin, c.want, rw.Code)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/notify"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"strconv"
+	"time"
+)
+
+// How often the queue is checked for problems to page about
+var AlertCheckInterval = time.Minute
+
+// The progress of a running job and when it last changed
+type progressMark struct {
+	progress float64
+	cracked  int64
+	at       time.Time
+}
+
+/*
+ * Pages operators through PagerDuty or Opsgenie about problems with the queue
+ * itself: resources that stay offline, a state file that cannot be written,
+ * backups that fail and running jobs that stop making progress. These are for
+ * whoever keeps the queue running, users are told about their jobs with
+ * digests instead.
+ */
+type AlertMonitor struct {
+	Q            *queue.Queue
+	Pagers       []notify.Pager
+	OfflineAfter time.Duration // 0 does not page about resources
+	StuckAfter   time.Duration // 0 does not page about jobs
+
+	offlineSince map[string]time.Time
+	progress     map[string]progressMark
+	open         map[string]bool // Keys of alerts that have been triggered
+}
+
+func NewAlertMonitor(q *queue.Queue, pagers []notify.Pager, offlineAfter, stuckAfter time.Duration) *AlertMonitor {
+	return &AlertMonitor{
+		Q:            q,
+		Pagers:       pagers,
+		OfflineAfter: offlineAfter,
+		StuckAfter:   stuckAfter,
+		offlineSince: map[string]time.Time{},
+		progress:     map[string]progressMark{},
+		open:         map[string]bool{},
+	}
+}
+
+func (m *AlertMonitor) Start() {
+	go func() {
+		for {
+			m.check(time.Now())
+			time.Sleep(AlertCheckInterval)
+		}
+	}()
+}
+
+// Look for problems, every alert that should be open is returned by key
+func (m *AlertMonitor) problems(now time.Time) map[string]notify.Alert {
+	alerts := map[string]notify.Alert{}
+
+	if since, err := m.Q.StateError(); err != nil {
+		alerts["queue-state"] = notify.Alert{
+			Key:      "queue-state",
+			Summary:  "CrackLord queue cannot write its state file: " + err.Error(),
+			Severity: notify.SEVERITY_CRITICAL,
+			Details: map[string]string{
+				"statefile": queue.StateFileLocation,
+				"since":     since.Format(time.RFC3339),
+			},
+		}
+	}
+
+	if since, err := m.Q.BackupError(); err != nil {
+		alerts["queue-backup"] = notify.Alert{
+			Key:      "queue-backup",
+			Summary:  "CrackLord queue cannot be backed up: " + err.Error(),
+			Severity: notify.SEVERITY_ERROR,
+			Details: map[string]string{
+				"since": since.Format(time.RFC3339),
+			},
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, r := range m.Q.Snapshot().Resources {
+		if r.Status != common.STATUS_QUIT {
+			continue
+		}
+		seen[r.ID] = true
+
+		since, ok := m.offlineSince[r.ID]
+		if !ok {
+			since = now
+			m.offlineSince[r.ID] = since
+		}
+
+		if m.OfflineAfter > 0 && now.Sub(since) >= m.OfflineAfter {
+			key := "resource-offline-" + r.ID
+			alerts[key] = notify.Alert{
+				Key:      key,
+				Summary:  "CrackLord resource " + r.Name + " has been offline for " + now.Sub(since).Truncate(time.Minute).String(),
+				Severity: notify.SEVERITY_ERROR,
+				Details: map[string]string{
+					"resource": r.Name,
+					"address":  r.Address,
+					"manager":  r.Manager,
+					"since":    since.Format(time.RFC3339),
+				},
+			}
+		}
+	}
+	for id := range m.offlineSince {
+		if !seen[id] {
+			delete(m.offlineSince, id)
+		}
+	}
+
+	seen = map[string]bool{}
+	for _, j := range m.Q.AllJobs() {
+		if j.Status != common.STATUS_RUNNING {
+			continue
+		}
+		seen[j.UUID] = true
+
+		mark, ok := m.progress[j.UUID]
+		if !ok || mark.progress != j.Progress || mark.cracked != j.CrackedHashes {
+			mark = progressMark{progress: j.Progress, cracked: j.CrackedHashes, at: now}
+			m.progress[j.UUID] = mark
+		}
+
+		if m.StuckAfter > 0 && now.Sub(mark.at) >= m.StuckAfter {
+			key := "job-stuck-" + j.UUID
+			alerts[key] = notify.Alert{
+				Key:      key,
+				Summary:  "CrackLord job " + j.Name + " has made no progress for " + now.Sub(mark.at).Truncate(time.Minute).String(),
+				Severity: notify.SEVERITY_WARNING,
+				Details: map[string]string{
+					"job":      j.UUID,
+					"name":     j.Name,
+					"owner":    j.Owner,
+					"resource": j.ResAssigned,
+					"progress": strconv.FormatFloat(j.Progress, 'f', 2, 64),
+					"since":    mark.at.Format(time.RFC3339),
+				},
+			}
+		}
+	}
+	for id := range m.progress {
+		if !seen[id] {
+			delete(m.progress, id)
+		}
+	}
+
+	return alerts
+}
+
+// Trigger new problems and resolve the ones that went away. Alerts a pager
+// could not be reached for are tried again on the next check.
+func (m *AlertMonitor) check(now time.Time) {
+	alerts := m.problems(now)
+
+	for key, a := range alerts {
+		if m.open[key] {
+			continue
+		}
+
+		if m.send(key, func(p notify.Pager) error { return p.Trigger(a) }) {
+			m.open[key] = true
+			log.WithField("alert", key).Warn(a.Summary)
+		}
+	}
+
+	for key := range m.open {
+		if _, ok := alerts[key]; ok {
+			continue
+		}
+
+		if m.send(key, func(p notify.Pager) error { return p.Resolve(key) }) {
+			delete(m.open, key)
+			log.WithField("alert", key).Info("Alert resolved.")
+		}
+	}
+}
+
+// Send to every pager, true when all of them accepted it
+func (m *AlertMonitor) send(key string, f func(p notify.Pager) error) bool {
+	ok := true
+	for _, p := range m.Pagers {
+		if err := f(p); err != nil {
+			log.WithFields(log.Fields{
+				"pager": p.Name(),
+				"alert": key,
+				"error": err.Error(),
+			}).Error("Unable to send alert.")
+			ok = false
+		}
+	}
+	return ok
+}
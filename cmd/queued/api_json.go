@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+)
+
+/*
+ * Conventions for the JSON of the API types, which typed clients rely on:
+ *
+ * Field names are the Go field name in lower case with nothing between the
+ * words, such as resourceid, and every exported field of an API type has a
+ * json tag naming it.
+ *
+ * Lists and maps are always sent. Empty and missing ones are sent as [] and
+ * {} rather than null, which responses are encoded with respEncoder to do, so
+ * handlers do not need to make every one.
+ *
+ * Fields that only apply to some objects, such as errors of a single result
+ * or details only administrators see, are omitempty so they are left out
+ * instead of sent as null or a zero value. Optional objects and times are
+ * pointers for the same reason.
+ *
+ * Times are sent in UTC as RFC 3339, whatever zone the queue runs in, which
+ * respEncoder also does. Times set by resources are moved to the clock of the
+ * queue when they are received, so times from different resources compare.
+ */
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// Encodes API responses with empty lists and maps in place of nil ones
+type respEncoder struct {
+	enc *json.Encoder
+}
+
+func newRespEncoder(w io.Writer) *respEncoder {
+	return &respEncoder{enc: json.NewEncoder(w)}
+}
+
+func (e *respEncoder) Encode(v interface{}) error {
+	return e.enc.Encode(apiValue(v))
+}
+
+// Get a copy of a response with every nil list and map made empty and every
+// time in UTC. The response itself is not changed as it may share lists with
+// the queue.
+func apiValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	return fillEmpty(reflect.ValueOf(v)).Interface()
+}
+
+func fillEmpty(v reflect.Value) reflect.Value {
+	t := v.Type()
+
+	switch {
+	case t == timeType:
+		return reflect.ValueOf(v.Interface().(time.Time).UTC())
+	case t.Kind() == reflect.Ptr && t.Elem() == timeType && !v.IsNil():
+		utc := v.Elem().Interface().(time.Time).UTC()
+		return reflect.ValueOf(&utc)
+	}
+
+	// Other types with their own encoding are sent as they are
+	if t.Implements(jsonMarshaler) || reflect.PtrTo(t).Implements(jsonMarshaler) {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || t.Elem().Kind() != reflect.Struct {
+			return v
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(fillEmpty(v.Elem()))
+		return p
+
+	case reflect.Struct:
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+			c.Field(i).Set(fillEmpty(c.Field(i)))
+		}
+		return c
+
+	case reflect.Slice:
+		// Byte slices are sent as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		if v.IsNil() {
+			return reflect.MakeSlice(t, 0, 0)
+		}
+		if !holdsStructs(t.Elem()) {
+			return v
+		}
+
+		s := reflect.MakeSlice(t, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(fillEmpty(v.Index(i)))
+		}
+		return s
+
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.MakeMap(t)
+		}
+	}
+
+	return v
+}
+
+// Check if values of a type may have lists or maps of their own to fill
+func holdsStructs(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	return !t.Implements(jsonMarshaler) && !reflect.PtrTo(t).Implements(jsonMarshaler)
+}
+
+// The clock offset of a resource in seconds and when it was measured, nil if
+// it never was
+func newAPIClock(offset time.Duration, read time.Time) (float64, *time.Time) {
+	if read.IsZero() {
+		return 0, nil
+	}
+	return offset.Seconds(), &read
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "Write the golden files from the current output")
+
+// Every type of the API, keep this up to date when adding one
+var apiTypes = []interface{}{
+	LoginReq{},
+	LoginResp{},
+	ErrorResp{},
+	LogoutResp{},
+	APIUser{},
+	UserMeResp{},
+	APISession{},
+	UserSessionsResp{},
+	UserSessionRevokeResp{},
+	APINotificationPrefs{},
+	UserNotificationsResp{},
+	UserPasswordReq{},
+	UserPasswordResp{},
+	APITool{},
+	APIToolDetail{},
+	ToolsResp{},
+	ToolsGetResp{},
+	APIResourceManager{},
+	APIResourceManagerDetail{},
+	ResourceManagersResp{},
+	ResourceManagerGetResp{},
+	APIJob{},
+	APIJobDetail{},
+	APICheckpoint{},
+	APIJobEvent{},
+	APIMetric{},
+	GetJobsResp{},
+	JobOutputResp{},
+	APIAccountDiff{},
+	JobDiffResp{},
+	PolicyReportReq{},
+	APIPasswordPolicy{},
+	APIPolicyAccount{},
+	PolicyReportResp{},
+	APIPwnedAccount{},
+	PwnedReportResp{},
+	APIHashMode{},
+	QuickCrackReq{},
+	APIQuickCrack{},
+	QuickCrackResp{},
+	JobLogResp{},
+	JobChangesResp{},
+	JobCreateReq{},
+	APIConstraints{},
+	JobCreateResp{},
+	APIInputIssue{},
+	JobBatchReq{},
+	JobBatchResult{},
+	JobBatchResp{},
+	JobReadResp{},
+	JobUpdateReq{},
+	JobOwnerReq{},
+	JobUpdateResp{},
+	JobDeleteResp{},
+	APIResource{},
+	APIProfile{},
+	APIGPU{},
+	APIInventory{},
+	ResListResp{},
+	ResCreateReq{},
+	ResCreateResp{},
+	ResReadResp{},
+	ResLogsResp{},
+	ResUpdateReq{},
+	ResUpdateResp{},
+	ResRescanResp{},
+	APIToolBinary{},
+	BinaryUploadReq{},
+	BinaryCurrentReq{},
+	BinaryListResp{},
+	ResProfilesReq{},
+	ResProfilesResp{},
+	ResRegisterReq{},
+	APIResourceConfig{},
+	ResConfigResp{},
+	ResDeleteReq{},
+	ResDeleteResp{},
+	QueueUpdateReq{},
+	QueueUpdateResp{},
+	APIReservation{},
+	ReservationListResp{},
+	ReservationCreateReq{},
+	ReservationCreateResp{},
+	ReservationDeleteResp{},
+	APINamedQueue{},
+	QueueListResp{},
+	QueueSetReq{},
+	QueueSetResp{},
+	QueueDeleteResp{},
+	JobMoveReq{},
+	QueueSimulateReq{},
+	APIPlannedJob{},
+	QueueSimulateResp{},
+	APIQueueImport{},
+	QueueImportResp{},
+	APIWordlistTask{},
+	WordlistTasksResp{},
+	WordlistProcessReq{},
+	WordlistCrawlReq{},
+	WordlistProcessResp{},
+	WordlistTaskResp{},
+	APIMarkovModel{},
+	MarkovModelsResp{},
+	MarkovModelUploadResp{},
+	APIGeneratedWordlist{},
+	GeneratedWordlistsResp{},
+	WordlistGenerateReq{},
+	WordlistGenerateResp{},
+	ToolPreviewReq{},
+	ToolPreviewResp{},
+	ToolEstimateReq{},
+	APIEstimate{},
+	ToolEstimateResp{},
+	ResUpdateRolloutReq{},
+	APIResourceUpdate{},
+	APIUpdate{},
+	ResUpdateRolloutResp{},
+	HealthResp{},
+	ToolDefaultsReq{},
+	ToolDefaultsResp{},
+	APIParamStats{},
+	APIToolStats{},
+	ToolStatsResp{},
+	APILatencyBucket{},
+	APIRouteStats{},
+	RouteStatsResp{},
+	NTDSIngestReq{},
+	APINTDSSummary{},
+	NTDSIngestResp{},
+	KerberosIngestReq{},
+	APIKerberosGroup{},
+	KerberosIngestResp{},
+	MessagesResp{},
+	APIBudget{},
+	BudgetsResp{},
+}
+
+// Check the output of a test against its golden file in testdata
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unable to read golden file, run the tests with -update to write it: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output of %s does not match its golden file, run the tests with -update if the change is wanted\ngot:\n%s", name, got)
+	}
+}
+
+func encodeAPI(t *testing.T, v interface{}) []byte {
+	var buf bytes.Buffer
+	if err := newRespEncoder(&buf).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	// Indent the output so changes to the golden files can be reviewed
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestAPIFieldNames(t *testing.T) {
+	for _, v := range apiTypes {
+		typ := reflect.TypeOf(v)
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Anonymous {
+				continue
+			}
+
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name != strings.ToLower(f.Name) {
+				t.Errorf("Expected %s.%s to be named %q, got %q", typ.Name(), f.Name, strings.ToLower(f.Name), name)
+			}
+		}
+	}
+}
+
+func TestAPIEmptyGolden(t *testing.T) {
+	// Each type is encoded on its own as a response would be
+	empty := map[string]json.RawMessage{}
+	for _, v := range apiTypes {
+		var buf bytes.Buffer
+		if err := newRespEncoder(&buf).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		empty[reflect.TypeOf(v).Name()] = buf.Bytes()
+	}
+
+	checkGolden(t, "api_empty.json", encodeAPI(t, empty))
+}
+
+func TestAPIJobGolden(t *testing.T) {
+	start := time.Date(2016, 3, 1, 12, 0, 0, 0, time.UTC)
+	resp := JobReadResp{
+		Status:     RESP_CODE_OK,
+		Message:    "OK",
+		MessageKey: MSG_OK,
+		Job: APIJobDetail{
+			ID:               "a5a0c228-2a7e-4a1b-8a4f-01a3c5e9d7c2",
+			Name:             "Domain dump",
+			Status:           "running",
+			ResourceID:       "5c4b8d6e-8f1d-4d5a-9e0b-2f3c4d5e6f70",
+			Owner:            "alice",
+			StartTime:        start,
+			ETC:              "1 Hour",
+			CrackedHashes:    2,
+			TotalHashes:      10,
+			Progress:         42.5,
+			Params:           map[string]string{"hashmode": "1000"},
+			ToolID:           "hashcat",
+			PerformanceTitle: "MH/s",
+			PerformanceData:  map[string]string{"1456833600": "1200.5"},
+			OutputTitles:     []string{"Hash", "Plaintext"},
+			OutputData:       [][]string{{"8846f7eaee8fb117ad06bdd830b7586c", "password"}},
+			History: []APIJobEvent{
+				{Time: start, User: "alice", Action: "created"},
+			},
+			Checkpoint: &APICheckpoint{Taken: start, Progress: 40, CrackedHashes: 2},
+			Project:    "acme",
+		},
+	}
+
+	checkGolden(t, "api_job.json", encodeAPI(t, resp))
+}
+
+func TestAPIResourceGolden(t *testing.T) {
+	resp := ResListResp{
+		Status:     RESP_CODE_OK,
+		Message:    "OK",
+		MessageKey: MSG_OK,
+		Resources: []APIResource{
+			{
+				ID:      "5c4b8d6e-8f1d-4d5a-9e0b-2f3c4d5e6f70",
+				Name:    "gpu01",
+				Address: "10.0.0.5:9443",
+				Manager: "directconnect",
+				Status:  "running",
+				Tools:   []APITool{{ID: "hashcat", Name: "Hashcat", Version: "3.00"}},
+				Inventory: &APIInventory{
+					GPUs:     []APIGPU{{Model: "GTX 1080", Memory: 8192, Driver: "367.44"}},
+					CPUCores: 8,
+					Memory:   32768,
+					Platform: "linux/amd64",
+				},
+			},
+		},
+	}
+
+	checkGolden(t, "api_resources.json", encodeAPI(t, resp))
+}
+
+func TestRespEncoderKeepsResponse(t *testing.T) {
+	resp := GetJobsResp{Jobs: []APIJob{{ID: "1"}}}
+	var buf bytes.Buffer
+	newRespEncoder(&buf).Encode(resp)
+
+	var nilJobs GetJobsResp
+	buf.Reset()
+	newRespEncoder(&buf).Encode(nilJobs)
+	if !strings.Contains(buf.String(), `"jobs":[]`) {
+		t.Errorf("Expected a nil list to be sent empty, got %s", buf.String())
+	}
+	if nilJobs.Jobs != nil {
+		t.Error("Expected the response to be left unchanged")
+	}
+}
+
+func TestRespEncoderSendsUTC(t *testing.T) {
+	zone := time.FixedZone("queue", -4*60*60)
+	stalled := time.Date(2026, 10, 14, 8, 30, 0, 0, zone)
+	resp := JobReadResp{Job: APIJobDetail{
+		StartTime: time.Date(2026, 10, 14, 8, 0, 0, 0, zone),
+		Stalled:   &stalled,
+	}}
+
+	var buf bytes.Buffer
+	newRespEncoder(&buf).Encode(resp)
+
+	for _, want := range []string{`"starttime":"2026-10-14T12:00:00Z"`, `"stalled":"2026-10-14T12:30:00Z"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected %s in %s", want, buf.String())
+		}
+	}
+	if resp.Job.Stalled.Location() != zone {
+		t.Error("Expected the response to be left unchanged")
+	}
+}
@@ -1,236 +1,145 @@
 package main
 
 import (
-	"encoding/json"
-	"time"
+	"github.com/jmmcatee/cracklord/common/api"
 )
 
-// Login Request Structure
-type LoginReq struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-}
-
-// Login Response Structure
-type LoginResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Token   string `json:"token"`
-	Role    string `json:"role"`
-}
-
-// Logout Response Structure
-type LogoutResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
-
-// Tool API structure
-type APITool struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Version string `json:"version"`
-}
-
-type APIToolDetail struct {
-	ID      string           `json:"id"`
-	Name    string           `json:"name"`
-	Version string           `json:"version"`
-	Form    *json.RawMessage `json:"form"`
-	Schema  *json.RawMessage `json:"schema"`
-}
-
-// Tools List Response Structure
-type ToolsResp struct {
-	Status  int       `json:"status"`
-	Message string    `json:"message"`
-	Tools   []APITool `json:"tools"`
-}
-
-// Get Tools structures
-type ToolsGetResp struct {
-	Status  int           `json:"status"`
-	Message string        `json:"message"`
-	Tool    APIToolDetail `json:"tool"`
-}
-
-// Resource Manager API structure
-type APIResourceManager struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-type APIResourceManagerDetail struct {
-	ID          string           `json:"id"`
-	Name        string           `json:"name"`
-	Description string           `json:"description"`
-	Form        *json.RawMessage `json:"form"`
-	Schema      *json.RawMessage `json:"schema"`
-}
-
-// Tools List Response Structure
-type ResourceManagersResp struct {
-	Status           int                  `json:"status"`
-	Message          string               `json:"message"`
-	ResourceManagers []APIResourceManager `json:"resourcemanagers"`
-}
-
-type ResourceManagerGetResp struct {
-	Status          int                      `json:"status"`
-	Message         string                   `json:"message"`
-	ResourceManager APIResourceManagerDetail `json:"resourcemanager"`
-}
-
-// API Jobs structure
-type APIJob struct {
-	ID            string    `json:"id"`
-	Name          string    `json:"name"`
-	Status        string    `json:"status"`
-	ResourceID    string    `json:"resourceid"`
-	Owner         string    `json:"owner"`
-	StartTime     time.Time `json:"starttime"`
-	ETC           string    `json:"etc"`
-	CrackedHashes int64     `json:"crackedhashes"`
-	TotalHashes   int64     `json:"totalhashes"`
-	Progress      float64   `json:"progress"`
-	ToolID        string    `json:"toolid"`
-}
-
-type APIJobDetail struct {
-	ID               string            `json:"id"`
-	Name             string            `json:"name"`
-	Status           string            `json:"status"`
-	ResourceID       string            `json:"resourceid"`
-	Owner            string            `json:"owner"`
-	StartTime        time.Time         `json:"starttime"`
-	ETC              string            `json:"etc"`
-	CrackedHashes    int64             `json:"crackedhashes"`
-	TotalHashes      int64             `json:"totalhashes"`
-	Progress         float64           `json:"progress"`
-	Params           map[string]string `json:"params"`
-	ToolID           string            `json:"toolid"`
-	PerformanceTitle string            `json:"performancetitle"`
-	PerformanceData  map[string]string `json:"performancedata"`
-	OutputTitles     []string          `json:"outputtitles"`
-	OutputData       [][]string        `json:"outputdata"`
-}
-
-// Get Jobs structure
-type GetJobsResp struct {
-	Status  int      `json:"status"`
-	Message string   `json:"message"`
-	Jobs    []APIJob `json:"jobs"`
-}
-
-// Create Jobs request
-type JobCreateReq struct {
-	ToolID string                 `json:"toolid"`
-	Name   string                 `json:"name"`
-	Params map[string]interface{} `json:"params"`
-}
-
-// Create Job response
-type JobCreateResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	JobID   string `json:"jobid"`
-}
-
-// Read Job resposne
-type JobReadResp struct {
-	Status  int          `json:"status"`
-	Message string       `json:"message"`
-	Job     APIJobDetail `json:"job"`
-}
-
-// Update Job Request
-type JobUpdateReq struct {
-	APIJob
-}
-
-// Update Job Response
-type JobUpdateResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	Job     APIJob `json:"job"`
-}
-
-// Delete Job response
-type JobDeleteResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
-
-// Resource API structure
-type APIResource struct {
-	ID      string            `json:"id"`
-	Name    string            `json:"name"`
-	Address string            `json:"address"`
-	Manager string            `json:"manager"`
-	Params  map[string]string `json:"params"`
-	Status  string            `json:"status"`
-	Tools   []APITool         `json:"tools"`
-}
-
-// List resource structs
-type ResListResp struct {
-	Status    int           `json:"status"`
-	Message   string        `json:"message"`
-	Resources []APIResource `json:"resources"`
-}
-
-// Create resource structs
-type ResCreateReq struct {
-	Manager string            `json:"manager"`
-	Params  map[string]string `json:"params"`
-}
-
-type ResCreateResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
-
-// Read a resource struct
-type ResReadResp struct {
-	Status   int         `json:"status"`
-	Message  string      `json:"message"`
-	Resource APIResource `json:"resource"`
-}
-
-// Update a resource struct
-type ResUpdateReq struct {
-	ID      string            `json:"id"`
-	Manager string            `json:"manager"`
-	Params  map[string]string `json:"params"`
-	Status  string            `json:"status"`
-	Tools   []APITool         `json:"tools"`
-}
-
-type ResUpdateResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
-
-// Delete a resource struct
-type ResDeleteReq struct {
-	ID      string            `json:"id"`
-	Manager string            `json:"manager"`
-	Params  map[string]string `json:"params"`
-	Status  string            `json:"status"`
-	Tools   []APITool         `json:"tools"`
-}
-
-// Delete a resource struct
-type ResDeleteResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
-
-type QueueUpdateReq struct {
-	JobOrder []string `json:"joborder"`
-}
-
-type QueueUpdateResp struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-}
+// The API types live in common/api so clients use the same structures as the
+// server. They keep their names here so handlers can use them directly.
+type (
+	LoginReq                 = api.LoginReq
+	LoginResp                = api.LoginResp
+	ErrorResp                = api.ErrorResp
+	LogoutResp               = api.LogoutResp
+	APIUser                  = api.APIUser
+	UserMeResp               = api.UserMeResp
+	APISession               = api.APISession
+	UserSessionsResp         = api.UserSessionsResp
+	UserSessionRevokeResp    = api.UserSessionRevokeResp
+	APINotificationPrefs     = api.APINotificationPrefs
+	UserNotificationsResp    = api.UserNotificationsResp
+	UserPasswordReq          = api.UserPasswordReq
+	UserPasswordResp         = api.UserPasswordResp
+	APITool                  = api.APITool
+	APIToolDetail            = api.APIToolDetail
+	ToolsResp                = api.ToolsResp
+	ToolsGetResp             = api.ToolsGetResp
+	APIResourceManager       = api.APIResourceManager
+	APIResourceManagerDetail = api.APIResourceManagerDetail
+	ResourceManagersResp     = api.ResourceManagersResp
+	ResourceManagerGetResp   = api.ResourceManagerGetResp
+	APIJob                   = api.APIJob
+	APIJobDetail             = api.APIJobDetail
+	APICheckpoint            = api.APICheckpoint
+	APIJobEvent              = api.APIJobEvent
+	APIMetric                = api.APIMetric
+	GetJobsResp              = api.GetJobsResp
+	JobOutputResp            = api.JobOutputResp
+	APIAccountDiff           = api.APIAccountDiff
+	JobDiffResp              = api.JobDiffResp
+	PolicyReportReq          = api.PolicyReportReq
+	APIPasswordPolicy        = api.APIPasswordPolicy
+	APIPolicyAccount         = api.APIPolicyAccount
+	PolicyReportResp         = api.PolicyReportResp
+	APIPwnedAccount          = api.APIPwnedAccount
+	PwnedReportResp          = api.PwnedReportResp
+	APIHashMode              = api.APIHashMode
+	QuickCrackReq            = api.QuickCrackReq
+	APIQuickCrack            = api.APIQuickCrack
+	QuickCrackResp           = api.QuickCrackResp
+	JobLogResp               = api.JobLogResp
+	JobChangesResp           = api.JobChangesResp
+	JobCreateReq             = api.JobCreateReq
+	APIConstraints           = api.APIConstraints
+	JobCreateResp            = api.JobCreateResp
+	APIInputIssue            = api.APIInputIssue
+	JobBatchReq              = api.JobBatchReq
+	JobBatchResult           = api.JobBatchResult
+	JobBatchResp             = api.JobBatchResp
+	JobReadResp              = api.JobReadResp
+	JobUpdateReq             = api.JobUpdateReq
+	JobOwnerReq              = api.JobOwnerReq
+	JobUpdateResp            = api.JobUpdateResp
+	JobDeleteResp            = api.JobDeleteResp
+	APIResource              = api.APIResource
+	APIProfile               = api.APIProfile
+	APIGPU                   = api.APIGPU
+	APIInventory             = api.APIInventory
+	ResListResp              = api.ResListResp
+	ResCreateReq             = api.ResCreateReq
+	ResCreateResp            = api.ResCreateResp
+	ResReadResp              = api.ResReadResp
+	ResLogsResp              = api.ResLogsResp
+	ResUpdateReq             = api.ResUpdateReq
+	ResUpdateResp            = api.ResUpdateResp
+	ResRescanResp            = api.ResRescanResp
+	APIToolBinary            = api.APIToolBinary
+	BinaryUploadReq          = api.BinaryUploadReq
+	BinaryCurrentReq         = api.BinaryCurrentReq
+	BinaryListResp           = api.BinaryListResp
+	ResProfilesReq           = api.ResProfilesReq
+	ResProfilesResp          = api.ResProfilesResp
+	ResRegisterReq           = api.ResRegisterReq
+	APIResourceConfig        = api.APIResourceConfig
+	ResConfigResp            = api.ResConfigResp
+	ResDeleteReq             = api.ResDeleteReq
+	ResDeleteResp            = api.ResDeleteResp
+	QueueUpdateReq           = api.QueueUpdateReq
+	QueueUpdateResp          = api.QueueUpdateResp
+	APIReservation           = api.APIReservation
+	ReservationListResp      = api.ReservationListResp
+	ReservationCreateReq     = api.ReservationCreateReq
+	ReservationCreateResp    = api.ReservationCreateResp
+	ReservationDeleteResp    = api.ReservationDeleteResp
+	APINamedQueue            = api.APINamedQueue
+	QueueListResp            = api.QueueListResp
+	QueueSetReq              = api.QueueSetReq
+	QueueSetResp             = api.QueueSetResp
+	QueueDeleteResp          = api.QueueDeleteResp
+	JobMoveReq               = api.JobMoveReq
+	QueueSimulateReq         = api.QueueSimulateReq
+	APIPlannedJob            = api.APIPlannedJob
+	QueueSimulateResp        = api.QueueSimulateResp
+	APIQueueImport           = api.APIQueueImport
+	QueueImportResp          = api.QueueImportResp
+	APIWordlistTask          = api.APIWordlistTask
+	WordlistTasksResp        = api.WordlistTasksResp
+	WordlistProcessReq       = api.WordlistProcessReq
+	WordlistCrawlReq         = api.WordlistCrawlReq
+	WordlistProcessResp      = api.WordlistProcessResp
+	WordlistTaskResp         = api.WordlistTaskResp
+	APIMarkovModel           = api.APIMarkovModel
+	MarkovModelsResp         = api.MarkovModelsResp
+	MarkovModelUploadResp    = api.MarkovModelUploadResp
+	APIGeneratedWordlist     = api.APIGeneratedWordlist
+	GeneratedWordlistsResp   = api.GeneratedWordlistsResp
+	WordlistGenerateReq      = api.WordlistGenerateReq
+	WordlistGenerateResp     = api.WordlistGenerateResp
+	ToolPreviewReq           = api.ToolPreviewReq
+	ToolPreviewResp          = api.ToolPreviewResp
+	ToolEstimateReq          = api.ToolEstimateReq
+	APIEstimate              = api.APIEstimate
+	ToolEstimateResp         = api.ToolEstimateResp
+	ResUpdateRolloutReq      = api.ResUpdateRolloutReq
+	APIResourceUpdate        = api.APIResourceUpdate
+	APIUpdate                = api.APIUpdate
+	ResUpdateRolloutResp     = api.ResUpdateRolloutResp
+	HealthResp               = api.HealthResp
+	ToolDefaultsReq          = api.ToolDefaultsReq
+	ToolDefaultsResp         = api.ToolDefaultsResp
+	APIParamStats            = api.APIParamStats
+	APIToolStats             = api.APIToolStats
+	ToolStatsResp            = api.ToolStatsResp
+	APILatencyBucket         = api.APILatencyBucket
+	APIRouteStats            = api.APIRouteStats
+	RouteStatsResp           = api.RouteStatsResp
+	NTDSIngestReq            = api.NTDSIngestReq
+	APINTDSSummary           = api.APINTDSSummary
+	NTDSIngestResp           = api.NTDSIngestResp
+	KerberosIngestReq        = api.KerberosIngestReq
+	APIKerberosGroup         = api.APIKerberosGroup
+	KerberosIngestResp       = api.KerberosIngestResp
+	MessagesResp             = api.MessagesResp
+	APIBudget                = api.APIBudget
+	BudgetsResp              = api.BudgetsResp
+)
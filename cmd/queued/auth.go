@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	log "github.com/Sirupsen/logrus"
 	"sync"
@@ -28,10 +31,18 @@ var SessionExpiration = 30 * time.Minute
  * Structure used to represent a user logged into the API.
  */
 type User struct {
-	Username  string
-	Groups    []string
-	LogOnTime time.Time
-	Timeout   time.Time
+	Username           string
+	Groups             []string
+	LogOnTime          time.Time
+	Timeout            time.Time
+	ImpersonatedBy     string
+	MustChangePassword bool
+
+	// Where the session was started from, set by the token store and login
+	SessionID  string
+	RemoteAddr string
+	UserAgent  string
+	Location   string
 }
 
 func (u *User) EffectiveRole() string {
@@ -77,6 +88,32 @@ func (u *User) Allowed(required string) bool {
 	return false
 }
 
+/*
+ * Administrators can act on behalf of another user by providing this header
+ * with the username to impersonate. The returned user keeps the groups of the
+ * Administrator so that they can still fix any job, but work is performed and
+ * owned as the impersonated user.
+ */
+const ImpersonateHeader = "ImpersonateUser"
+
+func (u *User) Impersonate(target string) (User, error) {
+	if !u.Allowed(Administrator) {
+		return User{}, errors.New("Only administrators can impersonate other users.")
+	}
+
+	// Build a copy of the admin with the target username
+	imp := *u
+	imp.Username = target
+	imp.ImpersonatedBy = u.Username
+
+	log.WithFields(log.Fields{
+		"admin":         u.Username,
+		"impersonating": target,
+	}).Warn("Administrator is acting on behalf of another user.")
+
+	return imp, nil
+}
+
 /*
  * This interface is used to allow multiple different types of authenticator
  * mechanisms to be used. Given a username and password it should return User
@@ -89,56 +126,136 @@ type Authenticator interface {
 	Login(user, pass string) (User, error)
 }
 
+/*
+ * Authenticators that manage their own user passwords can also implement this
+ * interface so users are able to change their own password through the API.
+ * Authenticators backed by an external directory, such as Active Directory,
+ * should not implement it.
+ */
+type PasswordChanger interface {
+	ChangePassword(user, oldpass, newpass string) error
+}
+
+/*
+ * Authenticators that depend on an external service can implement this
+ * interface so the readiness check is able to report when that service cannot
+ * be reached.
+ */
+type HealthChecker interface {
+	Healthy() error
+}
+
 /*
  * The token store saves the valid tokens and the time they expire. The 30
- * minute timer is renewed after every successful check.
+ * minute timer is renewed after every successful check. Token stores must be
+ * thread safe.
+ */
+type TokenStore interface {
+	AddToken(token string, user User) error
+	RemoveToken(token string)
+	CheckToken(token string) bool
+	GetUser(token string) (User, error)
+
+	// Clear the forced password change flag for every session of a user once
+	// the password has been changed
+	PasswordChanged(username string)
+
+	// The sessions of a user that have not expired and ending one of them
+	Sessions(username string) []User
+	RevokeSession(username, id string) bool
+}
+
+// Number of random bytes a session token is made from
+const tokenBytes = 32
+
+// Generate a new session token from the system random source
+func NewSessionToken() (string, error) {
+	seed := make([]byte, tokenBytes)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(seed)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Token stores only keep the hash of each token, so a copy of the store or of
+// Redis can not be used to take over sessions
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// The public ID of a session, which can be shown to the user without
+// giving away the token
+func sessionID(hash string) string {
+	return hash[:16]
+}
+
+/*
+ * Tokens kept in the memory of the queue server. They are lost when it is
+ * restarted and are not shared with other queue servers.
  */
-type TokenStore struct {
-	store map[string]*User
+type MemoryTokenStore struct {
+	store map[string]*session // Sessions by token hash
 	sync.Mutex
 }
 
-func NewTokenStore() TokenStore {
-	return TokenStore{
-		store: map[string]*User{},
+type session struct {
+	hash string
+	user User
+}
+
+func NewTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		store: map[string]*session{},
 	}
 }
 
-func (t *TokenStore) AddToken(token string, user User) {
+// Find the session of a token. The store is keyed by the hash so how long a
+// lookup takes says nothing about the token itself.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (t *MemoryTokenStore) session(token string) (*session, bool) {
+	s, ok := t.store[hashToken(token)]
+	return s, ok
+}
+
+func (t *MemoryTokenStore) AddToken(token string, user User) error {
 	t.Lock()
 	defer t.Unlock()
 
-	t.store[token] = &user
-	t.store[token].Timeout = time.Now().Add(30 * time.Minute)
+	h := hashToken(token)
+	user.Timeout = time.Now().Add(SessionExpiration)
+	user.SessionID = sessionID(h)
+	t.store[h] = &session{hash: h, user: user}
 
-	log.WithFields(log.Fields{
-		"user":  user.Username,
-		"token": token,
-	}).Debug("Token added to user store.")
+	log.WithField("user", user.Username).Debug("Token added to user store.")
+
+	return nil
 }
 
-func (t *TokenStore) RemoveToken(token string) {
+func (t *MemoryTokenStore) RemoveToken(token string) {
 	t.Lock()
 	defer t.Unlock()
 
-	delete(t.store, token)
+	delete(t.store, hashToken(token))
 }
 
-func (t *TokenStore) CheckToken(token string) bool {
+func (t *MemoryTokenStore) CheckToken(token string) bool {
 	t.Lock()
 	defer t.Unlock()
 
-	if user, ok := t.store[token]; ok {
+	if s, ok := t.session(token); ok {
 		// Check that this ticket hasn't timed out
-		if 0 > user.Timeout.Sub(time.Now()) {
+		if 0 > s.user.Timeout.Sub(time.Now()) {
 			// Token has expired so we should return false and remove the token
-			delete(t.store, token)
+			delete(t.store, s.hash)
 			log.Warn("Token was attempted that has timed out and is no longer valid.")
 			return false
 		}
 
 		// Token exists and has not timed out so return true and reset time
-		t.store[token].Timeout = time.Now().Add(30 * time.Minute)
+		s.user.Timeout = time.Now().Add(SessionExpiration)
 		return true
 	}
 
@@ -146,15 +263,54 @@ func (t *TokenStore) CheckToken(token string) bool {
 	return false
 }
 
-func (t *TokenStore) GetUser(token string) (User, error) {
+func (t *MemoryTokenStore) PasswordChanged(username string) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, s := range t.store {
+		if s.user.Username == username {
+			s.user.MustChangePassword = false
+		}
+	}
+}
+
+func (t *MemoryTokenStore) GetUser(token string) (User, error) {
 	t.Lock()
 	defer t.Unlock()
 
 	// Check for valid token
-	if user, ok := t.store[token]; ok {
+	if s, ok := t.session(token); ok {
 		// return the user we just got
-		return *user, nil
+		return s.user, nil
 	}
 
 	return User{}, errors.New("Invalid Token")
 }
+
+func (t *MemoryTokenStore) Sessions(username string) []User {
+	t.Lock()
+	defer t.Unlock()
+
+	users := []User{}
+	for _, s := range t.store {
+		if s.user.Username == username && time.Now().Before(s.user.Timeout) {
+			users = append(users, s.user)
+		}
+	}
+
+	return users
+}
+
+func (t *MemoryTokenStore) RevokeSession(username, id string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	for h, s := range t.store {
+		if s.user.Username == username && s.user.SessionID == id {
+			delete(t.store, h)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSessionToken(t *testing.T) {
+	a, err := NewSessionToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSessionToken()
+
+	if len(a) != 64 || a == b {
+		t.Errorf("Unexpected tokens %q and %q", a, b)
+	}
+}
+
+func TestTokenStoreKeepsHashes(t *testing.T) {
+	store := NewTokenStore()
+	token, _ := NewSessionToken()
+	store.AddToken(token, User{Username: "alice", Groups: []string{StandardUser}})
+
+	// Only the hash of the token is kept, so a copy of the store is no use
+	for h, s := range store.store {
+		if h == token || s.hash == token || strings.Contains(s.user.SessionID, token) {
+			t.Error("Token is stored in the clear")
+		}
+		if h != hashToken(token) {
+			t.Errorf("Session is stored under %q", h)
+		}
+	}
+
+	if !store.CheckToken(token) {
+		t.Fatal("Token was not accepted")
+	}
+	if u, err := store.GetUser(token); err != nil || u.Username != "alice" {
+		t.Errorf("Unexpected user %+v %v", u, err)
+	}
+
+	// The hash itself is not a token
+	if store.CheckToken(hashToken(token)) {
+		t.Error("Hash of the token was accepted as a token")
+	}
+
+	// Neither is a token with one character changed
+	tampered := []byte(token)
+	if tampered[0] == 'a' {
+		tampered[0] = 'b'
+	} else {
+		tampered[0] = 'a'
+	}
+	if store.CheckToken(string(tampered)) {
+		t.Error("Tampered token was accepted")
+	}
+	if _, err := store.GetUser(string(tampered)); err == nil {
+		t.Error("Tampered token gave a user")
+	}
+
+	store.RemoveToken(token)
+	if store.CheckToken(token) {
+		t.Error("Removed token was accepted")
+	}
+}
+
+func TestTokenStoreExpires(t *testing.T) {
+	store := NewTokenStore()
+	store.AddToken("token", User{Username: "alice"})
+	store.store[hashToken("token")].user.Timeout = time.Now().Add(-time.Second)
+
+	if store.CheckToken("token") {
+		t.Error("Expired token was accepted")
+	}
+	if len(store.store) != 0 {
+		t.Error("Expired session was not removed")
+	}
+}
+
+func TestSetupINIAuthenticator(t *testing.T) {
+	auth := setupAuthenticator("INI", map[string]string{
+		"adminuser":    "admin",
+		"adminpass":    "adminpass",
+		"standarduser": "user",
+		"standardpass": "userpass",
+		"readonlyuser": "read",
+		"readonlypass": "readpass",
+	})
+
+	for user, pass := range map[string]string{"admin": "adminpass", "user": "userpass", "read": "readpass"} {
+		if _, err := auth.Login(user, pass); err != nil {
+			t.Errorf("Expected %s to log in with their password, got %v", user, err)
+		}
+		if _, err := auth.Login(user, user); err == nil {
+			t.Errorf("Expected %s not to log in with their username as the password", user)
+		}
+	}
+}
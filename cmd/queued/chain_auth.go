@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+)
+
+// Chain Auth structure used to try multiple authenticators in order. Each
+// authenticator is responsible for its own mapping of users to roles.
+type ChainAuth struct {
+	Auths []Authenticator
+	Names []string
+}
+
+// Add an authenticator to the end of the chain
+func (a *ChainAuth) Add(name string, auth Authenticator) {
+	a.Auths = append(a.Auths, auth)
+	a.Names = append(a.Names, name)
+
+	log.WithField("authenticator", name).Debug("Authenticator added to the chain.")
+}
+
+// Try each authenticator in order and return the first successful login
+func (a *ChainAuth) Login(user, pass string) (User, error) {
+	for i, auth := range a.Auths {
+		u, err := auth.Login(user, pass)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"user":          user,
+				"authenticator": a.Names[i],
+			}).Debug("Authenticator in chain failed, trying the next.")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"user":          user,
+			"authenticator": a.Names[i],
+		}).Info("User logged in through authentication chain.")
+
+		return u, nil
+	}
+
+	return User{}, errors.New("Login failed for all configured authenticators.")
+}
+
+// Change the password using the first authenticator in the chain that supports
+// password changes and accepts the old password
+func (a *ChainAuth) ChangePassword(user, oldpass, newpass string) error {
+	err := errors.New("The configured authentication does not support changing passwords.")
+
+	for _, auth := range a.Auths {
+		pc, ok := auth.(PasswordChanger)
+		if !ok {
+			continue
+		}
+
+		err = pc.ChangePassword(user, oldpass, newpass)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// The chain is healthy as long as one of its authenticators is usable
+func (a *ChainAuth) Healthy() error {
+	err := errors.New("No authenticators are configured.")
+
+	for i, auth := range a.Auths {
+		hc, ok := auth.(HealthChecker)
+		if !ok {
+			return nil
+		}
+
+		err = hc.Healthy()
+		if err == nil {
+			return nil
+		}
+
+		log.WithFields(log.Fields{
+			"authenticator": a.Names[i],
+			"error":         err.Error(),
+		}).Debug("Authenticator in chain is not healthy.")
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net"
+	"strings"
+)
+
+// Proxies in front of the queue server whose X-Forwarded-For header is
+// believed. Without any the address of the connection is always used.
+var TrustedProxies []*net.IPNet
+
+// Parse a comma separated list of networks or addresses
+func parseNetworks(list string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, n := range strings.Split(list, ",") {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+
+		if !strings.Contains(n, "/") {
+			if ip := net.ParseIP(n); ip != nil && ip.To4() != nil {
+				n += "/32"
+			} else {
+				n += "/128"
+			}
+		}
+
+		_, ipnet, err := net.ParseCIDR(n)
+		if err != nil {
+			log.WithField("network", n).Error("Unable to parse network in config file.")
+			continue
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets
+}
+
+func inNetworks(nets []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the address of the client that made a request. Addresses in the
+// X-Forwarded-For header are only used when they were added by trusted
+// proxies, taking the last one that is not a trusted proxy.
+func clientIP(remoteAddr, forwardedFor string) string {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+
+	if forwardedFor == "" || !inNetworks(TrustedProxies, ip) {
+		return ip
+	}
+
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		ip = hop
+		if !inNetworks(TrustedProxies, hop) {
+			break
+		}
+	}
+
+	return ip
+}
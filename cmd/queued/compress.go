@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content types that are already compressed and gain nothing from gzip
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+}
+
+// Negroni middleware that compresses responses with gzip for clients that
+// accept it. Job listings and output compress very well which matters to
+// users on slow links.
+type GzipMiddleware struct {
+	minSize int // Responses with a smaller Content-Length are sent as is
+	pool    sync.Pool
+}
+
+func NewGzipMiddleware(level, minSize int) (*GzipMiddleware, error) {
+	// Make sure the level is valid now instead of on the first request
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		return nil, err
+	}
+
+	g := &GzipMiddleware{minSize: minSize}
+	g.pool.New = func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	}
+
+	return g, nil
+}
+
+func (g *GzipMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// The response depends on Accept-Encoding either way so caches must know
+	rw.Header().Add("Vary", "Accept-Encoding")
+
+	if r.Method == "HEAD" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		next(rw, r)
+		return
+	}
+
+	grw := &gzipResponseWriter{ResponseWriter: rw, g: g}
+	defer grw.close()
+
+	next(grw, r)
+}
+
+// Check if gzip is listed in an Accept-Encoding header without a zero quality
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+
+		return q > 0
+	}
+
+	return false
+}
+
+// Wraps a response and decides when the headers are written whether the body
+// should be compressed
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	g       *GzipMiddleware
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.decided = true
+		if w.shouldCompress(code) {
+			h := w.Header()
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+
+			w.gz = w.g.pool.Get().(*gzip.Writer)
+			w.gz.Reset(w.ResponseWriter)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) shouldCompress(code int) bool {
+	// Responses without a body or with part of one are left alone
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.g.minSize {
+			return false
+		}
+	}
+
+	ct := strings.ToLower(h.Get("Content-Type"))
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush compressed data so far so streamed responses still reach the client
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("The response writer does not support hijacking")
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+
+	w.gz.Close()
+	w.gz.Reset(nil)
+	w.g.pool.Put(w.gz)
+	w.gz = nil
+}
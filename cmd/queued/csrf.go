@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/subtle"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// Cookie holding the session token when sessions are delivered as cookies
+	SessionCookie = "cracklord_session"
+
+	// The CSRF token is sent in a cookie the web interface can read and must
+	// be sent back in the header. These are the names AngularJS uses so $http
+	// adds the header on its own.
+	CSRFCookie = "XSRF-TOKEN"
+	CSRFHeader = "X-XSRF-TOKEN"
+)
+
+// How session tokens are delivered when they are set in a cookie on login
+// instead of returned in the response, so they can not be read by scripts on
+// the page
+type SessionCookies struct {
+	Secure   bool // Only sent over HTTPS
+	HttpOnly bool // Hidden from the web interface
+	SameSite http.SameSite
+}
+
+// Give the browser the session token of a login
+func (c *SessionCookies) set(rw http.ResponseWriter, token string) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: c.SameSite,
+	})
+}
+
+// Remove the session and CSRF cookies of a browser that logged out
+func (c *SessionCookies) clear(rw http.ResponseWriter) {
+	for _, name := range []string{SessionCookie, CSRFCookie} {
+		http.SetCookie(rw, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   c.Secure,
+			HttpOnly: name == SessionCookie && c.HttpOnly,
+			SameSite: c.SameSite,
+		})
+	}
+}
+
+// Negroni middleware protecting sessions carried in a cookie from cross site
+// request forgery. Browsers add cookies to requests made by other sites, so
+// requests that change anything must also send the CSRF cookie back in a
+// header, which other sites can not read (double submit). The session cookie
+// is then given to the handlers as the AuthorizationToken header. Requests
+// with the header token do nothing here, as browsers never add it themselves.
+type CSRFMiddleware struct {
+	M *MessageCatalog
+}
+
+func NewCSRFMiddleware(m *MessageCatalog) *CSRFMiddleware {
+	return &CSRFMiddleware{M: m}
+}
+
+// Requests that change state, logout is a GET but ends the session
+func csrfProtected(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return r.URL.Path == "/api/logout"
+	}
+	return true
+}
+
+// Give the browser a CSRF token for its session
+func setCSRFCookie(rw http.ResponseWriter, r *http.Request) (string, error) {
+	token, err := NewSessionToken()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(rw, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		Secure:   r.TLS != nil,
+		HttpOnly: false, // The web interface reads it to send the header
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return token, nil
+}
+
+func (c *CSRFMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	session, err := r.Cookie(SessionCookie)
+	if err != nil || session.Value == "" || r.Header.Get("AuthorizationToken") != "" {
+		next(rw, r)
+		return
+	}
+
+	var csrf string
+	if cookie, err := r.Cookie(CSRFCookie); err == nil {
+		csrf = cookie.Value
+	}
+
+	if csrfProtected(r) {
+		header := r.Header.Get(CSRFHeader)
+		if csrf == "" || subtle.ConstantTimeCompare([]byte(header), []byte(csrf)) != 1 || !sameOrigin(r) {
+			c.reject(rw, r)
+			return
+		}
+	} else if csrf == "" {
+		// The token is given on the first safe request of the session
+		if _, err := setCSRFCookie(rw, r); err != nil {
+			log.WithField("error", err.Error()).Error("Unable to generate a CSRF token.")
+		}
+	}
+
+	r.Header.Set("AuthorizationToken", session.Value)
+	next(rw, r)
+}
+
+// Check the Origin header, when the browser sends one, is this server
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (c *CSRFMiddleware) reject(rw http.ResponseWriter, r *http.Request) {
+	var resp ErrorResp
+	resp.Status = RESP_CODE_FORBIDDEN
+	resp.Message, resp.MessageKey = c.M.Localize(r, MSG_CSRF_INVALID)
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(RESP_CODE_FORBIDDEN)
+	newRespEncoder(rw).Encode(resp)
+
+	log.WithFields(log.Fields{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"remote": r.RemoteAddr,
+		"origin": r.Header.Get("Origin"),
+	}).Warn("Request with a session cookie was refused without a valid CSRF token.")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// When the queue server started, for the uptime in runtime diagnostics
+var startTime = time.Now()
+
+// Runtime statistics of the queue server process
+type DebugRuntime struct {
+	Uptime       string
+	Goroutines   int
+	HeapAlloc    uint64
+	HeapInuse    uint64
+	HeapObjects  uint64
+	Sys          uint64
+	NumGC        uint32
+	PauseTotalNs uint64
+	Queue        queue.Diagnostics
+}
+
+/*
+ * Handler of the optional debug listener. It serves the pprof profiles, such
+ * as /debug/pprof/heap and /debug/pprof/goroutine?debug=2 for a goroutine
+ * dump, and /debug/runtime with memory statistics and the sizes of what the
+ * queue holds, so memory growth can be found on a server that stays up for a
+ * whole engagement. Every request needs the session token of an Administrator
+ * in the AuthorizationToken header, as profiles show internal state.
+ */
+type DebugServer struct {
+	T   TokenStore
+	Q   *queue.Queue
+	mux *http.ServeMux
+}
+
+func NewDebugServer(t TokenStore, q *queue.Queue) *DebugServer {
+	d := &DebugServer{T: t, Q: q, mux: http.NewServeMux()}
+
+	d.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	d.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	d.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	d.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	d.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	d.mux.HandleFunc("/debug/runtime", d.runtime)
+
+	return d
+}
+
+func (d *DebugServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// Expired sessions are only refused when the token is checked
+	token := r.Header.Get("AuthorizationToken")
+	valid := d.T.CheckToken(token)
+	user, err := d.T.GetUser(token)
+	if !valid || err != nil || !user.Allowed(Administrator) {
+		http.Error(rw, "Unauthorized", RESP_CODE_UNAUTHORIZED)
+
+		log.WithFields(log.Fields{
+			"remote": r.RemoteAddr,
+			"path":   r.URL.Path,
+		}).Warn("An unauthorized request was made to the debug listener.")
+
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"path":     r.URL.Path,
+	}).Info("Debug listener request.")
+
+	d.mux.ServeHTTP(rw, r)
+}
+
+func (d *DebugServer) runtime(rw http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := DebugRuntime{
+		Uptime:       time.Since(startTime).String(),
+		Goroutines:   runtime.NumGoroutine(),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapInuse:    mem.HeapInuse,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+		Queue:        d.Q.Diagnostics(),
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(rw)
+	enc.SetIndent("", "  ")
+	enc.Encode(resp)
+}
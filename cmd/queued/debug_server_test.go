@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebugServerAuth(t *testing.T) {
+	store := NewTokenStore()
+	store.AddToken("admin", User{Username: "admin", Groups: []string{Administrator}})
+	store.AddToken("user", User{Username: "alice", Groups: []string{StandardUser}})
+	store.AddToken("expired", User{Username: "admin", Groups: []string{Administrator}})
+	store.store[hashToken("expired")].user.Timeout = time.Now().Add(-time.Second)
+
+	q := queue.NewQueue(filepath.Join(t.TempDir(), "state.json"), 60, 5, 0)
+	d := NewDebugServer(store, &q)
+
+	for token, want := range map[string]int{
+		"admin":   http.StatusOK,
+		"user":    RESP_CODE_UNAUTHORIZED,
+		"expired": RESP_CODE_UNAUTHORIZED,
+		"":        RESP_CODE_UNAUTHORIZED,
+	} {
+		r := httptest.NewRequest("GET", "/debug/runtime", nil)
+		r.Header.Set("AuthorizationToken", token)
+		rw := httptest.NewRecorder()
+		d.ServeHTTP(rw, r)
+
+		if rw.Code != want {
+			t.Errorf("Token %q gave %d instead of %d", token, rw.Code, want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/notify"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DIGEST_OFF   = "off"
+	DIGEST_DAILY = "daily" // Once a day at the digest time
+	DIGEST_SHIFT = "shift" // Every shift starting at the digest time
+)
+
+// How often the queue is checked for events to add to digests
+var DigestCheckInterval = time.Minute
+
+// How a user wants to be mailed about the queue
+type DigestPrefs struct {
+	Email     string
+	Schedule  string // DIGEST_OFF, DIGEST_DAILY or DIGEST_SHIFT
+	Resources bool   // Include resources that went offline, Administrators only
+	LastSent  time.Time
+}
+
+/*
+ * Collects jobs finishing, new cracks and resources going offline for each
+ * user and mails them a summary once a day or once a shift instead of a mail
+ * for every event. Preferences are kept in a file, the events collected since
+ * the last digest are lost when the queue server restarts.
+ */
+type Digester struct {
+	Q         *queue.Queue
+	Mail      *notify.SMTP
+	PrefsFile string
+	At        time.Duration // Time after midnight daily digests are sent and shifts start
+	Shift     time.Duration
+
+	tracker *notify.Tracker
+	prefs   map[string]DigestPrefs
+	pending map[string]*notify.Digest
+	sync.Mutex
+}
+
+func NewDigester(q *queue.Queue, mail *notify.SMTP, prefsFile string, at, shift time.Duration) *Digester {
+	d := &Digester{
+		Q:         q,
+		Mail:      mail,
+		PrefsFile: prefsFile,
+		At:        at,
+		Shift:     shift,
+		tracker:   notify.NewTracker(),
+		prefs:     map[string]DigestPrefs{},
+		pending:   map[string]*notify.Digest{},
+	}
+
+	if b, err := ioutil.ReadFile(prefsFile); err == nil {
+		if err := json.Unmarshal(b, &d.prefs); err != nil {
+			log.WithField("error", err.Error()).Error("Unable to read notification preferences.")
+		}
+	} else if !os.IsNotExist(err) {
+		log.WithField("error", err.Error()).Error("Unable to read notification preferences.")
+	}
+
+	return d
+}
+
+// Get the preferences of a user, digests are off until they are set
+func (d *Digester) Prefs(username string) DigestPrefs {
+	d.Lock()
+	defer d.Unlock()
+
+	p, ok := d.prefs[username]
+	if !ok {
+		p.Schedule = DIGEST_OFF
+	}
+	return p
+}
+
+// Change the preferences of a user. Events are only collected from the time
+// digests are turned on.
+func (d *Digester) SetPrefs(username string, p DigestPrefs) error {
+	switch p.Schedule {
+	case DIGEST_OFF, DIGEST_DAILY, DIGEST_SHIFT:
+	case "":
+		p.Schedule = DIGEST_OFF
+	default:
+		return errors.New("The digest schedule must be off, daily or shift.")
+	}
+	if p.Schedule != DIGEST_OFF && p.Email == "" {
+		return errors.New("An email address is needed to send digests.")
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	old := d.prefs[username]
+	p.LastSent = old.LastSent
+	if old.Schedule == "" || old.Schedule == DIGEST_OFF || p.LastSent.IsZero() {
+		p.LastSent = time.Now()
+	}
+
+	if p.Schedule == DIGEST_OFF {
+		delete(d.pending, username)
+	} else if d.pending[username] == nil {
+		d.pending[username] = &notify.Digest{Since: p.LastSent}
+	}
+
+	d.prefs[username] = p
+
+	// The change is still used when it cannot be saved, just not kept over
+	// a restart
+	if err := d.savePrefs(); err != nil {
+		log.WithField("error", err.Error()).Error("Unable to save notification preferences.")
+	}
+	return nil
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (d *Digester) savePrefs() error {
+	b, err := json.MarshalIndent(d.prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := d.PrefsFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, d.PrefsFile)
+}
+
+// Check the queue for events and send the digests that are due
+func (d *Digester) Start() {
+	go func() {
+		for {
+			d.collect()
+			d.sendDue(time.Now())
+			time.Sleep(DigestCheckInterval)
+		}
+	}()
+}
+
+func (d *Digester) collect() {
+	resources := map[string]string{}
+	for _, r := range d.Q.Snapshot().Resources {
+		resources[r.Name] = r.Status
+	}
+
+	events, offline := d.tracker.Update(d.Q.AllJobs(), resources)
+
+	d.Lock()
+	defer d.Unlock()
+
+	for username, p := range d.prefs {
+		if p.Schedule == DIGEST_OFF {
+			continue
+		}
+
+		digest := d.pending[username]
+		if digest == nil {
+			digest = &notify.Digest{Since: p.LastSent}
+			d.pending[username] = digest
+		}
+
+		if e, ok := events[username]; ok {
+			digest.Merge(*e)
+		}
+		if p.Resources && len(offline) > 0 {
+			digest.Merge(notify.Digest{Offline: offline})
+		}
+	}
+}
+
+func (d *Digester) period(schedule string) time.Duration {
+	if schedule == DIGEST_SHIFT && d.Shift > 0 {
+		return d.Shift
+	}
+	return 24 * time.Hour
+}
+
+// Mail the digests that are due, the events of mails that fail are sent with
+// the next digest
+func (d *Digester) sendDue(now time.Time) {
+	type mail struct {
+		username string
+		to       string
+		digest   *notify.Digest
+	}
+
+	// Due digests are taken out so events collected while mailing go in the
+	// next one
+	d.Lock()
+	var due []mail
+	for username, p := range d.prefs {
+		if p.Schedule == DIGEST_OFF || now.Before(notify.NextDigest(p.LastSent, d.At, d.period(p.Schedule))) {
+			continue
+		}
+
+		// Empty digests are not sent but still start a new period
+		if digest := d.pending[username]; digest != nil && !digest.Empty() {
+			due = append(due, mail{username: username, to: p.Email, digest: digest})
+		}
+
+		p.LastSent = now
+		d.prefs[username] = p
+		d.pending[username] = &notify.Digest{Since: now}
+	}
+	d.Unlock()
+
+	// Mail servers can be slow so they are not waited on with the lock held
+	for _, m := range due {
+		subject, body := m.digest.Mail(m.username, now)
+		err := d.Mail.Send([]string{m.to}, subject, body)
+		if err == nil {
+			log.WithField("user", m.username).Info("Notification digest sent.")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"user":  m.username,
+			"error": err.Error(),
+		}).Error("Unable to send notification digest.")
+
+		// Put the events back to be sent with the next digest
+		d.Lock()
+		if next := d.pending[m.username]; next != nil {
+			m.digest.Merge(*next)
+			d.pending[m.username] = m.digest
+		}
+		d.Unlock()
+	}
+
+	d.Lock()
+	if err := d.savePrefs(); err != nil {
+		log.WithField("error", err.Error()).Error("Unable to save notification preferences.")
+	}
+	d.Unlock()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// Write a successful JSON response with an ETag of its content. When the
+// client already has the same content from an earlier poll only a 304 is
+// sent back.
+func writeWithETag(rw http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(apiValue(v))
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to encode the response.")
+		rw.WriteHeader(RESP_CODE_ERROR)
+		return
+	}
+	data = append(data, '\n')
+
+	// The tag is weak because the body may be compressed on the way out
+	sum := sha1.Sum(data)
+	etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+
+	h := rw.Header()
+	h.Set("ETag", etag)
+	h.Set("Cache-Control", "no-cache")
+	h.Add("Vary", "Accept-Language")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rw.WriteHeader(RESP_CODE_OK)
+	rw.Write(data)
+}
+
+// Check if an If-None-Match header lists the tag. Weak and strong versions of
+// a tag match each other.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
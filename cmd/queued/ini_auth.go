@@ -3,13 +3,16 @@ package main
 import (
 	"errors"
 	log "github.com/Sirupsen/logrus"
+	"sync"
 	"time"
 )
 
 // INI Auth structure for implementing the basic authenticator
 type INIAuth struct {
-	UserPass map[string]string
-	UserMap  map[string]string
+	UserPass    map[string]string
+	UserMap     map[string]string
+	ForceChange map[string]bool
+	sync.RWMutex
 }
 
 // The Setup function is used to provide usernames and passwords and a mapping
@@ -17,11 +20,27 @@ type INIAuth struct {
 func (a *INIAuth) Setup(userpass map[string]string, usermap map[string]string) {
 	a.UserPass = userpass
 	a.UserMap = usermap
+	a.ForceChange = map[string]bool{}
 
 	log.Debug("INI authentication setup")
 }
 
+// Flag users that must change their password the next time they log in
+func (a *INIAuth) SetForcePasswordChange(users []string) {
+	a.Lock()
+	defer a.Unlock()
+
+	for _, user := range users {
+		a.ForceChange[user] = true
+	}
+
+	log.WithField("users", users).Debug("INI users flagged for password change.")
+}
+
 func (a *INIAuth) Login(user, pass string) (User, error) {
+	a.RLock()
+	defer a.RUnlock()
+
 	// Lookup the user
 	p, ok := a.UserPass[user]
 	if !ok {
@@ -48,6 +67,7 @@ func (a *INIAuth) Login(user, pass string) (User, error) {
 
 	u.Groups = append(u.Groups, group)
 	u.LogOnTime = time.Now()
+	u.MustChangePassword = a.ForceChange[user]
 
 	log.WithFields(log.Fields{
 		"user": u.Username, 
@@ -57,3 +77,33 @@ func (a *INIAuth) Login(user, pass string) (User, error) {
 
 	return u, nil
 }
+
+// Change the password of a user. Changes are only kept in memory and will be
+// lost when the queue server is restarted unless the configuration file is
+// also updated.
+func (a *INIAuth) ChangePassword(user, oldpass, newpass string) error {
+	a.Lock()
+	defer a.Unlock()
+
+	p, ok := a.UserPass[user]
+	if !ok {
+		log.WithField("user", user).Error("User not found.")
+		return errors.New("User not found.")
+	}
+
+	if p != oldpass {
+		log.WithField("user", user).Error("Bad password.")
+		return errors.New("Bad password")
+	}
+
+	if newpass == "" {
+		return errors.New("The new password cannot be empty.")
+	}
+
+	a.UserPass[user] = newpass
+	delete(a.ForceChange, user)
+
+	log.WithField("user", user).Info("User password changed.")
+
+	return nil
+}
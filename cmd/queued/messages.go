@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The locale of the built in messages
+const DefaultLocale = "en"
+
+// Message keys sent with every response so clients can show their own text
+const (
+	MSG_OK           = "status.ok"
+	MSG_CREATED      = "status.created"
+	MSG_NOCONTENT    = "status.nocontent"
+	MSG_NOTMODIFIED  = "status.notmodified"
+	MSG_BADREQ       = "status.badrequest"
+	MSG_UNAUTHORIZED = "status.unauthorized"
+	MSG_FORBIDDEN    = "status.forbidden"
+	MSG_NOTFOUND     = "status.notfound"
+	MSG_CONFLICT     = "status.conflict"
+	MSG_ERROR        = "status.error"
+	MSG_UNAVAILABLE  = "status.unavailable"
+
+	MSG_JOB_NOTFOUND           = "job.notfound"
+	MSG_JOB_CREATE_FAILED      = "job.create.failed"
+	MSG_JOBS_CREATE_FAILED     = "job.batch.create.failed"
+	MSG_JOB_BATCH_EMPTY        = "job.batch.empty"
+	MSG_JOB_INPUT_INVALID      = "job.input.invalid"
+	MSG_JOB_OVERRIDES_INVALID  = "job.overrides.invalid"
+	MSG_JOB_RESULTKEY_INVALID  = "job.resultkey.invalid"
+	MSG_JOB_READ_FAILED        = "job.read.failed"
+	MSG_JOB_UPDATE_FAILED      = "job.update.failed"
+	MSG_JOB_START_FAILED       = "job.start.failed"
+	MSG_JOB_PAUSE_FAILED       = "job.pause.failed"
+	MSG_JOB_STOP_FAILED        = "job.stop.failed"
+	MSG_JOB_FORCESTOP_FAILED   = "job.forcestop.failed"
+	MSG_JOB_DELETE_FAILED      = "job.delete.failed"
+	MSG_JOB_RESTORE_FAILED     = "job.restore.failed"
+	MSG_JOB_RESTORE_DENIED     = "job.restore.denied"
+	MSG_JOB_TRANSFER_DENIED    = "job.transfer.denied"
+	MSG_JOB_OWNER_REQUIRED     = "job.transfer.ownerrequired"
+	MSG_JOB_COST_DENIED        = "job.cost.denied"
+	MSG_JOB_COST_FAILED        = "job.cost.failed"
+	MSG_JOB_APPROVAL_DENIED    = "job.approval.denied"
+	MSG_JOB_APPROVAL_FAILED    = "job.approval.failed"
+	MSG_JOB_OUTPUT_FAILED      = "job.output.failed"
+	MSG_JOB_RESULTS_FAILED     = "job.results.failed"
+	MSG_JOB_RESULTS_RANGE      = "job.results.range"
+	MSG_JOB_RESULTS_ENCRYPTED  = "job.results.encrypted"
+	MSG_JOB_LOG_DENIED         = "job.log.denied"
+	MSG_JOB_LOG_DISABLED       = "job.log.disabled"
+	MSG_JOB_DIFF_FAILED        = "job.diff.failed"
+	MSG_JOB_POLICY_FAILED      = "job.policy.failed"
+	MSG_JOB_POLICY_INVALID     = "job.policy.invalid"
+	MSG_JOB_PWNED_DISABLED     = "job.pwned.disabled"
+	MSG_JOB_PWNED_FAILED       = "job.pwned.failed"
+	MSG_QUICK_DISABLED         = "job.quick.disabled"
+	MSG_QUICK_INVALID          = "job.quick.invalid"
+	MSG_QUICK_FAILED           = "job.quick.failed"
+	MSG_QUICK_NOTFOUND         = "job.quick.notfound"
+	MSG_JOB_CURSOR_INVALID     = "job.changes.cursorinvalid"
+	MSG_JOB_RESOLUTION_INVALID = "job.resolutioninvalid"
+
+	MSG_TOOL_FORM_INVALID    = "tool.form.invalid"
+	MSG_TOOL_PREVIEW_FAILED  = "tool.preview.failed"
+	MSG_TOOL_ESTIMATE_FAILED = "tool.estimate.failed"
+
+	MSG_RES_NOTFOUND          = "resource.notfound"
+	MSG_RES_LIST_NOTFOUND     = "resource.list.notfound"
+	MSG_RES_ADD_FAILED        = "resource.add.failed"
+	MSG_RES_UPDATE_FAILED     = "resource.update.failed"
+	MSG_RES_QUIT_FAILED       = "resource.quit.failed"
+	MSG_RES_DELETE_FAILED     = "resource.delete.failed"
+	MSG_RES_LOGS_FAILED       = "resource.logs.failed"
+	MSG_RES_LOGLINES_INVALID  = "resource.logs.linesinvalid"
+	MSG_RES_PROFILES_INVALID  = "resource.profiles.invalid"
+	MSG_RES_RESCAN_FAILED     = "resource.rescan.failed"
+	MSG_RES_REGISTER_CONFLICT = "resource.register.conflict"
+	MSG_RESMGR_NOTFOUND       = "resourcemanager.notfound"
+	MSG_UPDATE_NOTSTARTED     = "resource.update.notstarted"
+	MSG_UPDATE_BINARYREQUIRED = "resource.update.binaryrequired"
+
+	MSG_RESV_CREATE_FAILED = "reservation.create.failed"
+	MSG_RESV_NOTFOUND      = "reservation.notfound"
+
+	MSG_QUEUE_NOTFOUND      = "queue.notfound"
+	MSG_QUEUE_SET_FAILED    = "queue.set.failed"
+	MSG_QUEUE_DELETE_FAILED = "queue.delete.failed"
+	MSG_JOB_MOVE_FAILED     = "job.move.failed"
+
+	MSG_INGEST_DUMP_FAILED    = "ingest.dump.failed"
+	MSG_INGEST_NOHASHES       = "ingest.dump.nohashes"
+	MSG_INGEST_TICKETS_FAILED = "ingest.kerberos.failed"
+	MSG_INGEST_NOTICKETS      = "ingest.kerberos.notickets"
+
+	MSG_MODEL_NAME_INVALID = "wordlist.model.nameinvalid"
+	MSG_MODEL_STORE_FAILED = "wordlist.model.storefailed"
+
+	MSG_GENERATE_INVALID = "wordlist.generate.invalid"
+	MSG_GENERATE_FAILED  = "wordlist.generate.failed"
+
+	MSG_CSRF_INVALID     = "session.csrf.invalid"
+	MSG_SESSION_NOTFOUND = "session.notfound"
+	MSG_ACCESS_DENIED    = "access.denied"
+
+	MSG_DIGEST_DISABLED   = "notify.digest.disabled"
+	MSG_DIGEST_INVALID    = "notify.digest.invalid"
+	MSG_BINARIES_DISABLED = "binaries.disabled"
+	MSG_BINARY_INVALID    = "binaries.invalid"
+	MSG_BINARY_NOTFOUND   = "binaries.notfound"
+	MSG_BINARY_FAILED     = "binaries.failed"
+
+	MSG_PASSWORD_UNSUPPORTED     = "user.password.unsupported"
+	MSG_PASSWORD_CHANGE_FAILED   = "user.password.failed"
+	MSG_PASSWORD_CHANGE_REQUIRED = "user.password.required"
+
+	MSG_REPORT_NOJOBS = "report.nojobs"
+	MSG_REPORT_FAILED = "report.failed"
+
+	MSG_EVIDENCE_DISABLED = "job.evidence.disabled"
+	MSG_EVIDENCE_FAILED   = "job.evidence.failed"
+
+	MSG_IMPORT_FAILED = "queue.import.failed"
+
+	// Titles of the metrics of jobs, looked up by the name of the metric
+	MSG_METRIC_SPEED       = "metric.speed"
+	MSG_METRIC_PERFORMANCE = "metric.performance"
+	MSG_METRIC_PACKETS     = "metric.packets"
+	MSG_METRIC_CRACKED     = "metric.cracked"
+	MSG_METRIC_PLAINTEXT   = "metric.plaintext"
+	MSG_METRIC_PASSWORD    = "metric.password"
+	MSG_METRIC_LMPASSWORD  = "metric.lmpassword"
+	MSG_METRIC_HASH        = "metric.hash"
+	MSG_METRIC_NTHASH      = "metric.nthash"
+	MSG_METRIC_USERNAME    = "metric.username"
+)
+
+// The built in English messages. Messages taking a detail, such as an error,
+// have a %s for it.
+var defaultMessages = map[string]string{
+	MSG_OK:           RESP_CODE_OK_T,
+	MSG_CREATED:      RESP_CODE_CREATED_T,
+	MSG_NOCONTENT:    RESP_CODE_NOCONTENT_T,
+	MSG_NOTMODIFIED:  RESP_CODE_NOTMODIFIED_T,
+	MSG_BADREQ:       RESP_CODE_BADREQ_T,
+	MSG_UNAUTHORIZED: RESP_CODE_UNAUTHORIZED_T,
+	MSG_FORBIDDEN:    RESP_CODE_FORBIDDEN_T,
+	MSG_NOTFOUND:     RESP_CODE_NOTFOUND_T,
+	MSG_CONFLICT:     RESP_CODE_CONFLICT_T,
+	MSG_ERROR:        RESP_CODE_ERROR_T,
+	MSG_UNAVAILABLE:  RESP_CODE_UNAVAILABLE_T,
+
+	MSG_JOB_NOTFOUND:           "That job does not exist.",
+	MSG_JOB_CREATE_FAILED:      "An error occured when trying to create the job: %s",
+	MSG_JOBS_CREATE_FAILED:     "An error occured when trying to create the jobs: %s",
+	MSG_JOB_BATCH_EMPTY:        "No jobs were provided in the batch.",
+	MSG_JOB_INPUT_INVALID:      "The job input has problems the tool would fail on, see the issues or set force to create it anyway.",
+	MSG_JOB_OVERRIDES_INVALID:  "Unable to set the tool arguments or environment: %s",
+	MSG_JOB_RESULTKEY_INVALID:  "The result key cannot be used to encrypt the results: %s",
+	MSG_JOB_READ_FAILED:        "Unable to read the job: %s",
+	MSG_JOB_UPDATE_FAILED:      "Unable to update the job: %s",
+	MSG_JOB_START_FAILED:       "Unable to start the job: %s",
+	MSG_JOB_PAUSE_FAILED:       "Unable to pause the job: %s",
+	MSG_JOB_STOP_FAILED:        "Unable to stop the job: %s",
+	MSG_JOB_FORCESTOP_FAILED:   "Unable to force the job to stop: %s",
+	MSG_JOB_DELETE_FAILED:      "Unable to delete the job: %s",
+	MSG_JOB_RESTORE_FAILED:     "Unable to restore the job: %s",
+	MSG_JOB_RESTORE_DENIED:     "Only the owner of a job or an Administrator can restore it.",
+	MSG_JOB_TRANSFER_DENIED:    "Only the owner of a job or an Administrator can transfer it.",
+	MSG_JOB_OWNER_REQUIRED:     "The new owner of the job is required.",
+	MSG_JOB_COST_DENIED:        "Only the owner of a job or an Administrator can approve its cost.",
+	MSG_JOB_COST_FAILED:        "Unable to approve the cost of the job: %s",
+	MSG_JOB_APPROVAL_DENIED:    "A sensitive job must be approved by an Administrator other than the one that submitted it.",
+	MSG_JOB_APPROVAL_FAILED:    "Unable to approve the job: %s",
+	MSG_JOB_OUTPUT_FAILED:      "Unable to read the spilled output of the job: %s",
+	MSG_JOB_RESULTS_FAILED:     "Unable to read the result file of the job: %s",
+	MSG_JOB_RESULTS_RANGE:      "The requested range is not within the result file.",
+	MSG_JOB_RESULTS_ENCRYPTED:  "The results of the job are encrypted for its result key and cannot be read by the queue.",
+	MSG_JOB_LOG_DENIED:         "Only the owner of a job or an Administrator can read its debug log.",
+	MSG_JOB_LOG_DISABLED:       "The job was not created with debugging enabled.",
+	MSG_JOB_DIFF_FAILED:        "Unable to compare the jobs: %s",
+	MSG_JOB_POLICY_FAILED:      "Unable to check the job against the password policy: %s",
+	MSG_JOB_POLICY_INVALID:     "Password policy lengths and classes cannot be negative.",
+	MSG_JOB_PWNED_DISABLED:     "Pwned Passwords lookups are not configured on this server.",
+	MSG_JOB_PWNED_FAILED:       "Unable to look up the job in Pwned Passwords: %s",
+	MSG_QUICK_DISABLED:         "Quick cracks are not configured on this server.",
+	MSG_QUICK_INVALID:          "Unable to quick crack the hash: %s",
+	MSG_QUICK_FAILED:           "Unable to queue the quick crack: %s",
+	MSG_QUICK_NOTFOUND:         "That quick crack does not exist.",
+	MSG_JOB_CURSOR_INVALID:     "The since cursor must be a number returned by an earlier request.",
+	MSG_JOB_RESOLUTION_INVALID: "The resolution must be a number of seconds.",
+
+	MSG_TOOL_FORM_INVALID:    "There was an error parsing the tool form information: %s",
+	MSG_TOOL_PREVIEW_FAILED:  "Unable to preview the tool: %s",
+	MSG_TOOL_ESTIMATE_FAILED: "Unable to estimate the job: %s",
+
+	MSG_RES_NOTFOUND:          "That resource does not exist.",
+	MSG_RES_LIST_NOTFOUND:     "One of the resources provided does not exist.",
+	MSG_RES_ADD_FAILED:        "An error occured when trying to add the resource: %s",
+	MSG_RES_UPDATE_FAILED:     "An error occured while trying to update that resource: %s",
+	MSG_RES_QUIT_FAILED:       "An error occured while trying to quit that resource: %s",
+	MSG_RES_DELETE_FAILED:     "An error occured while trying to delete that resource: %s",
+	MSG_RES_LOGS_FAILED:       "Unable to read the resource logs: %s",
+	MSG_RES_LOGLINES_INVALID:  "The number of lines must be a positive number.",
+	MSG_RES_PROFILES_INVALID:  "Those resource profiles are not valid: %s",
+	MSG_RES_RESCAN_FAILED:     "Unable to rescan the resource tools: %s",
+	MSG_RES_REGISTER_CONFLICT: "The resource can not be changed to match the registration: %s",
+	MSG_RESMGR_NOTFOUND:       "That resource manager does not exist.",
+	MSG_UPDATE_NOTSTARTED:     "No resource update has been started.",
+	MSG_UPDATE_BINARYREQUIRED: "A binary and its signature are required.",
+
+	MSG_RESV_CREATE_FAILED: "Unable to reserve the resources: %s",
+	MSG_RESV_NOTFOUND:      "That reservation does not exist.",
+
+	MSG_QUEUE_NOTFOUND:      "That job queue does not exist.",
+	MSG_QUEUE_SET_FAILED:    "Unable to set the job queue: %s",
+	MSG_QUEUE_DELETE_FAILED: "Unable to remove the job queue: %s",
+	MSG_JOB_MOVE_FAILED:     "Unable to move the job: %s",
+
+	MSG_INGEST_DUMP_FAILED:    "Unable to parse the dump: %s",
+	MSG_INGEST_NOHASHES:       "No hashes in the dump matched the chosen subsets.",
+	MSG_INGEST_TICKETS_FAILED: "Unable to parse the tickets: %s",
+	MSG_INGEST_NOTICKETS:      "No supported Kerberos tickets were found.",
+
+	MSG_MODEL_NAME_INVALID: "Models can only be uploaded with a name of letters, numbers, dashes and underscores when a wordlist directory is configured.",
+	MSG_MODEL_STORE_FAILED: "Unable to store the markov model: %s",
+
+	MSG_GENERATE_INVALID: "Unable to generate the wordlist: %s",
+	MSG_GENERATE_FAILED:  "Unable to store the generated wordlist: %s",
+
+	MSG_CSRF_INVALID:     "The request did not include a valid CSRF token, reload the page and try again.",
+	MSG_SESSION_NOTFOUND: "That session does not exist.",
+	MSG_ACCESS_DENIED:    "This part of the API cannot be used from your network.",
+
+	MSG_DIGEST_DISABLED:   "Notification digests are not configured on this server.",
+	MSG_DIGEST_INVALID:    "Unable to save the notification settings: %s",
+	MSG_BINARIES_DISABLED: "The tool binary repository needs storage to be configured on this server.",
+	MSG_BINARY_INVALID:    "That tool binary is not valid: %s",
+	MSG_BINARY_NOTFOUND:   "That tool binary does not exist.",
+	MSG_BINARY_FAILED:     "Unable to update the tool binary repository: %s",
+
+	MSG_PASSWORD_UNSUPPORTED:     "The configured authentication does not support changing passwords.",
+	MSG_PASSWORD_CHANGE_FAILED:   "Unable to change the password: %s",
+	MSG_PASSWORD_CHANGE_REQUIRED: "You must change your password before using the rest of the API.",
+
+	MSG_REPORT_NOJOBS: "That project has no jobs to report on.",
+	MSG_REPORT_FAILED: "Unable to generate the report: %s",
+
+	MSG_EVIDENCE_DISABLED: "Evidence bundles need a signing key to be configured on this server.",
+	MSG_EVIDENCE_FAILED:   "Unable to export the evidence bundle: %s",
+
+	MSG_IMPORT_FAILED: "Unable to import the queue bundle: %s",
+
+	MSG_METRIC_SPEED:       "Speed",
+	MSG_METRIC_PERFORMANCE: "Performance",
+	MSG_METRIC_PACKETS:     "Packets / sec",
+	MSG_METRIC_CRACKED:     "Cracked hashes",
+	MSG_METRIC_PLAINTEXT:   "Plaintext",
+	MSG_METRIC_PASSWORD:    "Password",
+	MSG_METRIC_LMPASSWORD:  "LM Password",
+	MSG_METRIC_HASH:        "Hash",
+	MSG_METRIC_NTHASH:      "NT Hash",
+	MSG_METRIC_USERNAME:    "Username",
+}
+
+// Messages of every locale by message key. Locales are lower case language
+// tags such as de or pt-br.
+type MessageCatalog struct {
+	locales map[string]map[string]string
+}
+
+func NewMessageCatalog() *MessageCatalog {
+	return &MessageCatalog{
+		locales: map[string]map[string]string{DefaultLocale: defaultMessages},
+	}
+}
+
+// Load every <locale>.json file in a directory. Each file is an object of
+// message keys to text, and keys it does not have fall back to English.
+func (c *MessageCatalog) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+
+		var msgs map[string]string
+		err = json.Unmarshal(data, &msgs)
+		if err != nil {
+			return fmt.Errorf("%s: %s", f, err.Error())
+		}
+
+		locale := strings.ToLower(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if locale == DefaultLocale {
+			// Changes to the English text still keep every key
+			for k, v := range defaultMessages {
+				if _, ok := msgs[k]; !ok {
+					msgs[k] = v
+				}
+			}
+		}
+		c.locales[locale] = msgs
+
+		log.WithFields(log.Fields{
+			"locale":   locale,
+			"messages": len(msgs),
+		}).Debug("Message catalog loaded.")
+	}
+
+	return nil
+}
+
+// Pick the best supported locale from an Accept-Language header
+func (c *MessageCatalog) Negotiate(header string) string {
+	if c == nil {
+		return DefaultLocale
+	}
+
+	type tag struct {
+		locale string
+		q      float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		t := tag{locale: strings.ToLower(strings.TrimSpace(fields[0])), q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				t.q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		if t.locale != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if _, ok := c.locales[t.locale]; ok {
+			return t.locale
+		}
+		// A regional tag can use the catalog of its language
+		if i := strings.Index(t.locale, "-"); i > 0 {
+			if _, ok := c.locales[t.locale[:i]]; ok {
+				return t.locale[:i]
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// Get the text of a message in a locale, falling back to English
+func (c *MessageCatalog) Text(locale, key string) string {
+	if c != nil {
+		if msg, ok := c.locales[locale][key]; ok {
+			return msg
+		}
+	}
+
+	return defaultMessages[key]
+}
+
+// Get the message for the language of a request along with its key, so both
+// can be set on a response. A detail such as an error is put into the message
+// where it takes one.
+func (c *MessageCatalog) Localize(r *http.Request, key string, detail ...string) (string, string) {
+	msg := c.Text(c.Negotiate(r.Header.Get("Accept-Language")), key)
+	if len(detail) > 0 {
+		d := strings.Join(detail, " ")
+		if strings.Contains(msg, "%s") {
+			msg = strings.Replace(msg, "%s", d, 1)
+		} else {
+			msg += " " + d
+		}
+	}
+
+	return msg, key
+}
+
+// Get every message of the locale a client asks for so a user interface can
+// show the same text (GET - /api/messages)
+func (a *AppController) ListMessages(rw http.ResponseWriter, r *http.Request) {
+	var resp MessagesResp
+
+	// A locale in the query takes the place of the Accept-Language header
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = r.Header.Get("Accept-Language")
+	}
+	locale = a.M.Negotiate(locale)
+
+	resp.Locale = locale
+	resp.Messages = map[string]string{}
+	for k := range defaultMessages {
+		resp.Messages[k] = a.M.Text(locale, k)
+	}
+	if a.M != nil {
+		// Catalogs can carry extra keys for the user interface itself
+		for k, v := range a.M.locales[locale] {
+			resp.Messages[k] = v
+		}
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.Header().Set("Vary", "Accept-Language")
+	rw.WriteHeader(RESP_CODE_OK)
+	newRespEncoder(rw).Encode(resp)
+}
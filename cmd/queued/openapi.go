@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the API in the OpenAPI description
+const APIVersion = "1.0.0"
+
+// An operation of the API for the OpenAPI description, which clients such as
+// the Python client are generated from. Every route of the router must have
+// one, which the tests check.
+type apiOperation struct {
+	ID           string // Name of the handler, used as the method name of generated clients
+	Method       string
+	Path         string
+	Tag          string
+	Summary      string
+	Request      interface{} // Type of the JSON body, nil for none
+	RequestType  string      // Content type of a body that is not JSON
+	Response     interface{} // Type of the JSON response
+	ResponseType string      // Content type of a response that is not an API type
+	Status       int         // Status of a successful response, 200 when not set
+	Query        []string    // Optional query parameters
+	Public       bool        // No token is needed
+	Admin        bool        // Only Administrators can call it, so it is kept to the administrative listeners
+}
+
+var apiOperations = []apiOperation{
+	{ID: "Healthz", Method: "GET", Path: "/healthz", Tag: "health", Summary: "Check the server is alive", Response: HealthResp{}, Public: true},
+	{ID: "Readyz", Method: "GET", Path: "/readyz", Tag: "health", Summary: "Check the authentication backend and the queue are ready", Response: HealthResp{}, Public: true},
+	{ID: "ListMessages", Method: "GET", Path: "/api/messages", Tag: "messages", Summary: "Get every message of a locale", Response: MessagesResp{}, Query: []string{"locale"}, Public: true},
+	{ID: "Login", Method: "POST", Path: "/api/login", Tag: "users", Summary: "Log in and get a session token", Request: LoginReq{}, Response: LoginResp{}, Public: true},
+	{ID: "Logout", Method: "GET", Path: "/api/logout", Tag: "users", Summary: "End the session of the token", Response: LogoutResp{}},
+	{ID: "ReadUserMe", Method: "GET", Path: "/api/users/me", Tag: "users", Summary: "Read the current user profile", Response: UserMeResp{}},
+	{ID: "UpdateUserMe", Method: "PUT", Path: "/api/users/me", Tag: "users", Summary: "Change the password of the current user", Request: UserPasswordReq{}, Response: UserPasswordResp{}},
+	{ID: "ListUserSessions", Method: "GET", Path: "/api/users/me/sessions", Tag: "users", Summary: "List the sessions of the current user", Response: UserSessionsResp{}},
+	{ID: "RevokeUserSession", Method: "DELETE", Path: "/api/users/me/sessions/{id}", Tag: "users", Summary: "End one of the sessions of the current user", Response: UserSessionRevokeResp{}},
+	{ID: "GetUserNotifications", Method: "GET", Path: "/api/users/me/notifications", Tag: "users", Summary: "Get the notification settings of the current user", Response: UserNotificationsResp{}},
+	{ID: "UpdateUserNotifications", Method: "PUT", Path: "/api/users/me/notifications", Tag: "users", Summary: "Change the notification settings of the current user", Request: APINotificationPrefs{}, Response: UserNotificationsResp{}},
+	{ID: "ListTools", Method: "GET", Path: "/api/tools", Tag: "tools", Summary: "List the tools jobs can be created with", Response: ToolsResp{}},
+	{ID: "GetTool", Method: "GET", Path: "/api/tools/{id}", Tag: "tools", Summary: "Read a tool with its form and defaults", Response: ToolsGetResp{}},
+	{ID: "PreviewTool", Method: "POST", Path: "/api/tools/{id}/preview", Tag: "tools", Summary: "Preview the candidates of a tool", Request: ToolPreviewReq{}, Response: ToolPreviewResp{}},
+	{ID: "EstimateTool", Method: "POST", Path: "/api/tools/{id}/estimate", Tag: "tools", Summary: "Estimate the keyspace and run time of a job", Request: ToolEstimateReq{}, Response: ToolEstimateResp{}},
+	{ID: "ReadToolDefaults", Method: "GET", Path: "/api/tools/{id}/defaults", Tag: "tools", Summary: "Get the parameters applied to every job of a tool", Response: ToolDefaultsResp{}},
+	{ID: "UpdateToolDefaults", Method: "PUT", Path: "/api/tools/{id}/defaults", Tag: "tools", Summary: "Set the parameters applied to every job of a tool", Request: ToolDefaultsReq{}, Response: ToolDefaultsResp{}, Admin: true},
+	{ID: "IngestNTDS", Method: "POST", Path: "/api/ingest/ntds", Tag: "ingest", Summary: "Parse secretsdump or NTDS output and optionally create a job from the chosen subsets of it", Request: NTDSIngestReq{}, Response: NTDSIngestResp{}},
+	{ID: "IngestKerberos", Method: "POST", Path: "/api/ingest/kerberos", Tag: "ingest", Summary: "Parse Rubeus or GetUserSPNs output and optionally create a job for each encryption type found in it", Request: KerberosIngestReq{}, Response: KerberosIngestResp{}},
+	{ID: "ReadToolStats", Method: "GET", Path: "/api/stats/tools", Tag: "stats", Summary: "Get usage statistics for each tool and its parameters", Response: ToolStatsResp{}},
+	{ID: "ReadRouteStats", Method: "GET", Path: "/api/stats/routes", Tag: "stats", Summary: "Get the request latency of each API route, slowest first", Response: RouteStatsResp{}, Admin: true},
+	{ID: "ListResourceManagers", Method: "GET", Path: "/api/resourcemanagers", Tag: "resourcemanagers", Summary: "List the resource managers resources can be added with", Response: ResourceManagersResp{}},
+	{ID: "GetResourceManager", Method: "GET", Path: "/api/resourcemanagers/{id}", Tag: "resourcemanagers", Summary: "Read a resource manager with its form", Response: ResourceManagerGetResp{}},
+	{ID: "ListResource", Method: "GET", Path: "/api/resources", Tag: "resources", Summary: "List the resources connected to the queue", Response: ResListResp{}},
+	{ID: "CreateResource", Method: "POST", Path: "/api/resources", Tag: "resources", Summary: "Add a resource through a resource manager", Request: ResCreateReq{}, Response: ResCreateResp{}, Admin: true},
+	{ID: "ReadResourceUpdate", Method: "GET", Path: "/api/resources/update", Tag: "resources", Summary: "Get the status of the current or last rolling update", Response: ResUpdateRolloutResp{}, Admin: true},
+	{ID: "StartResourceUpdate", Method: "POST", Path: "/api/resources/update", Tag: "resources", Summary: "Start a rolling update of resourceservers", Request: ResUpdateRolloutReq{}, Response: ResUpdateRolloutResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "ListBinaries", Method: "GET", Path: "/api/binaries", Tag: "binaries", Summary: "List the tool binaries in the repository", Response: BinaryListResp{}, Admin: true},
+	{ID: "UploadBinary", Method: "POST", Path: "/api/binaries", Tag: "binaries", Summary: "Upload a signed build of a tool for a platform", Request: BinaryUploadReq{}, Response: BinaryListResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "SetCurrentBinary", Method: "PUT", Path: "/api/binaries/{tool}", Tag: "binaries", Summary: "Set the version of a tool every resource is kept at", Request: BinaryCurrentReq{}, Response: BinaryListResp{}, Admin: true},
+	{ID: "DeleteBinary", Method: "DELETE", Path: "/api/binaries/{tool}/{version}/{os}/{arch}", Tag: "binaries", Summary: "Delete a build from the repository", Response: BinaryListResp{}, Admin: true},
+	{ID: "ReadResourceConfig", Method: "GET", Path: "/api/resources/named/{name}", Tag: "resources", Summary: "Read the state of the resource registered under a name", Response: ResConfigResp{}, Admin: true},
+	{ID: "RegisterResource", Method: "PUT", Path: "/api/resources/named/{name}", Tag: "resources", Summary: "Add or change the resource registered under a name to match the registration", Request: ResRegisterReq{}, Response: ResConfigResp{}, Admin: true},
+	{ID: "ReadResourceLogs", Method: "GET", Path: "/api/resources/{id}/logs", Tag: "resources", Summary: "Read the recent logs of a resource", Response: ResLogsResp{}, Query: []string{"lines", "task"}, Admin: true},
+	{ID: "UpdateResourceProfiles", Method: "PUT", Path: "/api/resources/{id}/profiles", Tag: "resources", Summary: "Set the time of day profiles of a resource, replacing the ones it had", Request: ResProfilesReq{}, Response: ResProfilesResp{}, Admin: true},
+	{ID: "RescanResource", Method: "POST", Path: "/api/resources/{id}/rescan", Tag: "resources", Summary: "Have a resource load its tools again and report its inventory", Response: ResRescanResp{}, Admin: true},
+	{ID: "ReadResource", Method: "GET", Path: "/api/resources/{manager}/{id}", Tag: "resources", Summary: "Read a resource of a resource manager", Response: ResReadResp{}},
+	{ID: "UpdateResource", Method: "PUT", Path: "/api/resources/{id}", Tag: "resources", Summary: "Change the status, tools or parameters of a resource", Request: ResUpdateReq{}, Response: ResUpdateResp{}, Admin: true},
+	{ID: "DeleteResources", Method: "DELETE", Path: "/api/resources/{id}", Tag: "resources", Summary: "Remove a resource from its resource manager", Request: ResDeleteReq{}, Response: ResDeleteResp{}, Admin: true},
+	{ID: "GetJobs", Method: "GET", Path: "/api/jobs", Tag: "jobs", Summary: "List the jobs of the queue", Response: GetJobsResp{}},
+	{ID: "CreateJob", Method: "POST", Path: "/api/jobs", Tag: "jobs", Summary: "Create a job", Request: JobCreateReq{}, Response: JobCreateResp{}},
+	{ID: "CreateJobBatch", Method: "POST", Path: "/api/jobs/batch", Tag: "jobs", Summary: "Create several jobs at once", Request: JobBatchReq{}, Response: JobBatchResp{}},
+	{ID: "GetJobChanges", Method: "GET", Path: "/api/jobs/changes", Tag: "jobs", Summary: "Get the jobs changed since a cursor", Response: JobChangesResp{}, Query: []string{"since"}},
+	{ID: "CreateQuickCrack", Method: "POST", Path: "/api/jobs/quick", Tag: "jobs", Summary: "Run a single hash through the quick pipeline of top wordlists and a fast rule set", Request: QuickCrackReq{}, Response: QuickCrackResp{}, Status: RESP_CODE_CREATED},
+	{ID: "ReadQuickCrack", Method: "GET", Path: "/api/jobs/quick/{id}", Tag: "jobs", Summary: "Read the status of a quick crack, the plaintext is only shown to its owner and administrators", Response: QuickCrackResp{}},
+	{ID: "ReadJob", Method: "GET", Path: "/api/jobs/{id}", Tag: "jobs", Summary: "Read a job with its parameters, output and history", Response: JobReadResp{}, Query: []string{"resolution"}},
+	{ID: "UpdateJob", Method: "PUT", Path: "/api/jobs/{id}", Tag: "jobs", Summary: "Change the status of a job or the parameters of a draft job", Request: JobUpdateReq{}, Response: JobUpdateResp{}},
+	{ID: "DeleteJob", Method: "DELETE", Path: "/api/jobs/{id}", Tag: "jobs", Summary: "Remove a job from the queue, which is refused with a conflict while something still depends on the job unless it is forced", Response: JobDeleteResp{}, Query: []string{"force"}},
+	{ID: "StartJob", Method: "POST", Path: "/api/jobs/{id}/start", Tag: "jobs", Summary: "Launch a draft job", Response: JobUpdateResp{}},
+	{ID: "RestoreJob", Method: "POST", Path: "/api/jobs/{id}/restore", Tag: "jobs", Summary: "Continue a quit or failed job from its last checkpoint", Response: JobUpdateResp{}},
+	{ID: "ReadJobOutput", Method: "GET", Path: "/api/jobs/{id}/output", Tag: "jobs", Summary: "Read all of the output of a job, including rows the queue no longer keeps in memory", Response: JobOutputResp{}},
+	{ID: "JobResults", Method: "GET", Path: "/api/jobs/{id}/results", Tag: "jobs", Summary: "Stream the result file of a job from the resource running it", ResponseType: "text/plain"},
+	{ID: "ReadJobLog", Method: "GET", Path: "/api/jobs/{id}/log", Tag: "jobs", Summary: "Read the debug log of a job created with debugging enabled, which is the scheduling decisions of the queue and the resource along with the full output of the tool", Response: JobLogResp{}},
+	{ID: "MoveJob", Method: "PUT", Path: "/api/jobs/{id}/queue", Tag: "jobs", Summary: "Move a job that has not started to another named queue", Request: JobMoveReq{}, Response: JobUpdateResp{}, Admin: true},
+	{ID: "DiffJobs", Method: "GET", Path: "/api/jobs/{a}/diff/{b}", Tag: "jobs", Summary: "Compare the cracked accounts of an older job a with a newer job b over the same hash list, such as for year over year reporting", Response: JobDiffResp{}},
+	{ID: "JobPolicyReport", Method: "POST", Path: "/api/jobs/{id}/policy", Tag: "jobs", Summary: "Check the passwords a job cracked against the configured password policy, or parts of it given in the request, for a compliance summary that can go into a client report", Request: PolicyReportReq{}, Response: PolicyReportResp{}},
+	{ID: "JobPwnedReport", Method: "GET", Path: "/api/jobs/{id}/pwned", Tag: "jobs", Summary: "Look up the cracked passwords of a job in Pwned Passwords and return how often each account's password was seen in breaches", Response: PwnedReportResp{}, Query: []string{"hashes"}},
+	{ID: "TransferJob", Method: "PUT", Path: "/api/jobs/{id}/owner", Tag: "jobs", Summary: "Hand a job to another user", Request: JobOwnerReq{}, Response: JobUpdateResp{}},
+	{ID: "ApproveJobCost", Method: "POST", Path: "/api/jobs/{id}/cost", Tag: "jobs", Summary: "Let a job held back for going over the budget of its project run on resources with a cost", Response: JobUpdateResp{}},
+	{ID: "ApproveJob", Method: "POST", Path: "/api/jobs/{id}/approve", Tag: "jobs", Summary: "Let a sensitive job waiting for approval be dispatched, as an Administrator other than the one that submitted it", Response: JobUpdateResp{}, Admin: true},
+	{ID: "JobEvidence", Method: "GET", Path: "/api/jobs/{id}/evidence", Tag: "jobs", Summary: "Export a signed and timestamped evidence bundle of the metadata, parameters, input and result digests and audit trail of a job for chain of custody, as JSON or with format zip as a zip that also holds the results", ResponseType: "application/json", Query: []string{"format"}},
+	{ID: "ReorderQueue", Method: "PUT", Path: "/api/queue", Tag: "queue", Summary: "Change the order jobs are run in", Request: QueueUpdateReq{}, Response: QueueUpdateResp{}, Admin: true},
+	{ID: "SimulateQueue", Method: "POST", Path: "/api/queue/simulate", Tag: "queue", Summary: "Plan where the queue would run a set of hypothetical jobs and when they would finish without creating them", Request: QueueSimulateReq{}, Response: QueueSimulateResp{}, Admin: true},
+	{ID: "ExportQueue", Method: "GET", Path: "/api/queue/export", Tag: "queue", Summary: "Export the jobs, resources, tool defaults, named queues, reservations and statistics of the queue as a bundle to import into another queue server", ResponseType: "application/json", Admin: true},
+	{ID: "ImportQueue", Method: "POST", Path: "/api/queue/import", Tag: "queue", Summary: "Import a bundle exported by another queue server with the bundle as the body, adding only what this queue does not have", RequestType: "application/json", Response: QueueImportResp{}, Admin: true},
+	{ID: "ListReservations", Method: "GET", Path: "/api/reservations", Tag: "reservations", Summary: "List reservations that have not ended", Response: ReservationListResp{}},
+	{ID: "CreateReservation", Method: "POST", Path: "/api/reservations", Tag: "reservations", Summary: "Reserve resources for a project during a window", Request: ReservationCreateReq{}, Response: ReservationCreateResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "DeleteReservation", Method: "DELETE", Path: "/api/reservations/{id}", Tag: "reservations", Summary: "Remove a reservation", Response: ReservationDeleteResp{}, Admin: true},
+	{ID: "ListQueues", Method: "GET", Path: "/api/queues", Tag: "queues", Summary: "List the named queues jobs can be created in", Response: QueueListResp{}},
+	{ID: "SetQueue", Method: "PUT", Path: "/api/queues/{name}", Tag: "queues", Summary: "Create a named queue or replace its resources and policy", Request: QueueSetReq{}, Response: QueueSetResp{}, Admin: true},
+	{ID: "DeleteQueue", Method: "DELETE", Path: "/api/queues/{name}", Tag: "queues", Summary: "Remove a named queue that has no unfinished jobs", Response: QueueDeleteResp{}, Admin: true},
+	{ID: "GetBudgets", Method: "GET", Path: "/api/budgets", Tag: "queues", Summary: "List the budgets of projects for resources with a cost per hour and what each has spent", Response: BudgetsResp{}},
+	{ID: "ProjectReport", Method: "GET", Path: "/api/projects/{id}/report", Tag: "queues", Summary: "Assemble the engagement report of a project from its jobs as HTML, set download to true to save it as a file", ResponseType: "text/html"},
+	{ID: "ListWordlistTasks", Method: "GET", Path: "/api/wordlists/processing", Tag: "wordlists", Summary: "List wordlist processing tasks", Response: WordlistTasksResp{}, Admin: true},
+	{ID: "CreateWordlistTask", Method: "POST", Path: "/api/wordlists/processing", Tag: "wordlists", Summary: "Start processing a wordlist", Request: WordlistProcessReq{}, Response: WordlistProcessResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "ReadWordlistTask", Method: "GET", Path: "/api/wordlists/processing/{id}", Tag: "wordlists", Summary: "Read the status of wordlist processing", Response: WordlistTaskResp{}, Admin: true},
+	{ID: "CreateWordlistCrawl", Method: "POST", Path: "/api/wordlists/crawl", Tag: "wordlists", Summary: "Crawl sites for candidate words in the background, in the way of CeWL", Request: WordlistCrawlReq{}, Response: WordlistProcessResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "ListMarkovModels", Method: "GET", Path: "/api/wordlists/models", Tag: "wordlists", Summary: "List uploaded markov models", Response: MarkovModelsResp{}},
+	{ID: "UploadMarkovModel", Method: "PUT", Path: "/api/wordlists/models/{name}", Tag: "wordlists", Summary: "Upload a markov model with the raw file as the body, named with .hcstat2 for the format of newer hashcat versions", RequestType: "application/octet-stream", Response: MarkovModelUploadResp{}, Status: RESP_CODE_CREATED, Admin: true},
+	{ID: "ListGeneratedWordlists", Method: "GET", Path: "/api/wordlists/generated", Tag: "wordlists", Summary: "List the wordlists generated from terms or crawls", Response: GeneratedWordlistsResp{}},
+	{ID: "GenerateWordlist", Method: "PUT", Path: "/api/wordlists/generated/{name}", Tag: "wordlists", Summary: "Generate a targeted wordlist from company names, seasons, years and keywords with leetspeak and prefix and suffix mutations", Request: WordlistGenerateReq{}, Response: WordlistGenerateResp{}, Status: RESP_CODE_CREATED},
+	{ID: "ReadOpenAPI", Method: "GET", Path: "/api/openapi.json", Tag: "messages", Summary: "Get the OpenAPI description of the API", ResponseType: "application/json", Public: true},
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// Describe a type of the API as an OpenAPI schema. Structures are added to
+// schemas by name and referred to. Fields that are not omitempty are required
+// in types only sent by the server, as they are always sent.
+type schemaBuilder struct {
+	schemas  map[string]interface{}
+	requests map[reflect.Type]bool // Types sent by clients, which need no fields
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// Note a type and every type within it as sent by clients
+func (b *schemaBuilder) markRequest(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		b.markRequest(t.Elem())
+	case reflect.Struct:
+		if b.requests[t] || t == timeType {
+			return
+		}
+		b.requests[t] = true
+		b.markFields(t)
+	}
+}
+
+// Embedded structures are part of the type that embeds them, so only their
+// fields are noted
+func (b *schemaBuilder) markFields(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			b.markFields(f.Type)
+			continue
+		}
+		b.markRequest(f.Type)
+	}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawType:
+		// Any JSON value, such as the forms of tools
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := b.schema(t.Elem())
+		if _, ok := s["$ref"]; ok {
+			return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+
+	case reflect.Struct:
+		if _, ok := b.schemas[t.Name()]; !ok {
+			props := map[string]interface{}{}
+			required := []string{}
+			b.fields(t, props, &required)
+
+			s := map[string]interface{}{"type": "object", "properties": props}
+			if !b.requests[t] && len(required) > 0 {
+				sort.Strings(required)
+				s["required"] = required
+			}
+			b.schemas[t.Name()] = s
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	}
+
+	return map[string]interface{}{}
+}
+
+// Add the fields of a structure, including those of embedded structures
+func (b *schemaBuilder) fields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			b.fields(f.Type, props, required)
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = b.schema(f.Type)
+		if !strings.Contains(tag, ",omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// Build the OpenAPI 3 description of the API
+func openAPISpec() map[string]interface{} {
+	b := &schemaBuilder{
+		schemas:  map[string]interface{}{},
+		requests: map[reflect.Type]bool{},
+	}
+	for _, op := range apiOperations {
+		if op.Request != nil {
+			b.markRequest(reflect.TypeOf(op.Request))
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range apiOperations {
+		o := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"tags":        []string{op.Tag},
+		}
+
+		params := []interface{}{}
+		for _, m := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, q := range op.Query {
+			params = append(params, map[string]interface{}{
+				"name":   q,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			o["parameters"] = params
+		}
+
+		if op.Request != nil {
+			o["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(b.schema(reflect.TypeOf(op.Request))),
+			}
+		} else if op.RequestType != "" {
+			o["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					op.RequestType: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+				},
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = RESP_CODE_OK
+		}
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if op.Response != nil {
+			success["content"] = jsonContent(b.schema(reflect.TypeOf(op.Response)))
+		} else if op.ResponseType != "" {
+			success["content"] = map[string]interface{}{
+				op.ResponseType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		o["responses"] = map[string]interface{}{
+			strconv.Itoa(status): success,
+			"default": map[string]interface{}{
+				"description": "The error that stopped the request",
+				"content":     jsonContent(b.schema(reflect.TypeOf(ErrorResp{}))),
+			},
+		}
+
+		if op.Public {
+			o["security"] = []interface{}{}
+		}
+
+		path, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			path = map[string]interface{}{}
+			paths[op.Path] = path
+		}
+		path[strings.ToLower(op.Method)] = o
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "CrackLord queue server API",
+			"version": APIVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "AuthorizationToken",
+				},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"token": []string{}}},
+	}
+}
+
+// Get the OpenAPI description of the API, which does not require a token so
+// clients can be generated from a running server (GET - /api/openapi.json)
+func (a *AppController) ReadOpenAPI(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	writeWithETag(rw, r, openAPISpec())
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	var a AppController
+	router := a.Router()
+
+	// Each operation is routed to a route of the same path
+	ops := map[string]bool{}
+	for _, op := range apiOperations {
+		key := op.Method + " " + op.Path
+		if ops[key] {
+			t.Errorf("Expected one operation for %s", key)
+		}
+		ops[key] = true
+
+		var match mux.RouteMatch
+		path := pathParam.ReplaceAllString(op.Path, "x")
+		if !router.Match(httptest.NewRequest(op.Method, path, nil), &match) {
+			t.Errorf("Expected a route for the OpenAPI operation %s", key)
+			continue
+		}
+		if tpl, _ := match.Route.GetPathTemplate(); tpl != op.Path {
+			t.Errorf("Expected %s to be routed to %s, got %s", key, op.Path, tpl)
+		}
+	}
+
+	// And every route has an operation
+	var routes int
+	router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		routes++
+		return nil
+	})
+	if routes != len(apiOperations) {
+		t.Errorf("Expected an OpenAPI operation for each of the %d routes, got %d", routes, len(apiOperations))
+	}
+}
+
+func TestOpenAPIGolden(t *testing.T) {
+	out, err := json.MarshalIndent(openAPISpec(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkGolden(t, "openapi.json", append(out, '\n'))
+}
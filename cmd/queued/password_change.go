@@ -0,0 +1,73 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+// Requests a user who must change their password can still make, everything
+// needed to log in, read their profile, change the password and log out
+var passwordChangeAllowed = []struct {
+	method, path string
+}{
+	{"POST", "/api/login"},
+	{"GET", "/api/logout"},
+	{"GET", "/api/messages"},
+	{"GET", "/api/openapi.json"},
+	{"GET", "/api/users/me"},
+	{"PUT", "/api/users/me"},
+}
+
+// Negroni middleware refusing the API to sessions of users flagged to change
+// their password until they have changed it. Requests without a valid token
+// are left to the handlers to refuse.
+type PasswordChangeMiddleware struct {
+	T TokenStore
+	M *MessageCatalog
+}
+
+func NewPasswordChangeMiddleware(t TokenStore, m *MessageCatalog) *PasswordChangeMiddleware {
+	return &PasswordChangeMiddleware{T: t, M: m}
+}
+
+func passwordChangeAllows(method, path string) bool {
+	if !strings.HasPrefix(path, "/api/") {
+		return true
+	}
+
+	for _, req := range passwordChangeAllowed {
+		if req.method == method && req.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PasswordChangeMiddleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	token := r.Header.Get("AuthorizationToken")
+	if token == "" || passwordChangeAllows(r.Method, r.URL.Path) {
+		next(rw, r)
+		return
+	}
+
+	user, err := p.T.GetUser(token)
+	if err != nil || !user.MustChangePassword {
+		next(rw, r)
+		return
+	}
+
+	var resp ErrorResp
+	resp.Status = RESP_CODE_FORBIDDEN
+	resp.Message, resp.MessageKey = p.M.Localize(r, MSG_PASSWORD_CHANGE_REQUIRED)
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(RESP_CODE_FORBIDDEN)
+	newRespEncoder(rw).Encode(resp)
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"method":   r.Method,
+		"path":     r.URL.Path,
+	}).Warn("Request refused until the user changes their password.")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPasswordChangeRequired(t *testing.T) {
+	a, _ := testController(t)
+	auth := &INIAuth{}
+	auth.Setup(map[string]string{"bob": "changeme"}, map[string]string{"bob": StandardUser})
+	a.Auth = auth
+
+	token := "change-token"
+	a.T.AddToken(token, User{Username: "bob", Groups: []string{StandardUser}, MustChangePassword: true})
+
+	h := NewPasswordChangeMiddleware(a.T, a.M)
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+
+		r := httptest.NewRequest(method, path, &buf)
+		r.Header.Set("AuthorizationToken", token)
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, r, a.Router().ServeHTTP)
+		return rw
+	}
+
+	// Everything but the profile and the password change is refused
+	for _, c := range []struct{ method, path string }{
+		{"GET", "/api/jobs"},
+		{"POST", "/api/jobs"},
+		{"GET", "/api/tools"},
+		{"GET", "/api/users/me/sessions"},
+	} {
+		rw := request(c.method, c.path, nil)
+		var resp ErrorResp
+		json.NewDecoder(rw.Body).Decode(&resp)
+		if rw.Code != RESP_CODE_FORBIDDEN || resp.MessageKey != MSG_PASSWORD_CHANGE_REQUIRED {
+			t.Errorf("Expected %s %s to be refused before the password change, got %d %q", c.method, c.path, rw.Code, resp.MessageKey)
+		}
+	}
+	if rw := request("GET", "/api/users/me", nil); rw.Code != RESP_CODE_OK {
+		t.Errorf("Profile was refused before the password change: %d %s", rw.Code, rw.Body.String())
+	}
+
+	rw := request("PUT", "/api/users/me", UserPasswordReq{OldPassword: "changeme", NewPassword: "n3w-Passw0rd!"})
+	if rw.Code != RESP_CODE_OK {
+		t.Fatalf("Password change gave %d: %s", rw.Code, rw.Body.String())
+	}
+
+	// Once changed the session can use the API
+	if rw := request("GET", "/api/jobs", nil); rw.Code != http.StatusOK {
+		t.Errorf("Jobs were refused after the password change: %d %s", rw.Code, rw.Body.String())
+	}
+}
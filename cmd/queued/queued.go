@@ -1,31 +1,76 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
+	"crypto/ed25519"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/negroni"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/acme"
+	"github.com/jmmcatee/cracklord/common/azblob"
+	"github.com/jmmcatee/cracklord/common/evidence"
+	"github.com/jmmcatee/cracklord/common/geoip"
 	"github.com/jmmcatee/cracklord/common/log"
+	"github.com/jmmcatee/cracklord/common/notify"
+	"github.com/jmmcatee/cracklord/common/pwned"
 	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/jmmcatee/cracklord/common/redis"
+	"github.com/jmmcatee/cracklord/common/report"
+	"github.com/jmmcatee/cracklord/common/s3"
+	"github.com/jmmcatee/cracklord/common/vault"
+	"github.com/jmmcatee/cracklord/common/wordlist"
+	"github.com/jmmcatee/cracklord/plugins/exporters/dpat"
+	"github.com/jmmcatee/cracklord/plugins/exporters/neo4j"
+	"github.com/jmmcatee/cracklord/plugins/exporters/rest"
 	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/aws"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/azure"
 	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/directconnect"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/gcp"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/kubernetes"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/reverseconnect"
 	"github.com/unrolled/secure"
 	"github.com/vaughan0/go-ini"
-	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
 	// Define the flags
 	var confPath = flag.String("conf", "", "Configuration file to use")
+	var newKey = flag.Bool("newkey", false, "Print a new master key for encrypting configuration values and exit")
+	var encrypt = flag.Bool("encrypt", false, "Encrypt standard input with the master key for use in the configuration file and exit")
+	var printOpenAPI = flag.Bool("openapi", false, "Print the OpenAPI description of the API and exit")
+	var restore = flag.String("restore", "", "Restore a backup, or latest for the newest one, to the state file and job storage and exit")
+	var verifyBackup = flag.String("verifybackup", "", "Check a backup, or latest for the newest one, against its checksums and exit")
 
 	// Parse the flags
 	flag.Parse()
 
+	if *newKey || *encrypt {
+		if err := common.EncryptConfigFile(*confPath, *newKey, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *printOpenAPI {
+		out, _ := json.MarshalIndent(openAPISpec(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
 	// Read the configuration file
 	var confFile ini.File
 	var confErr error
@@ -136,9 +181,32 @@ func main() {
 		}
 	}
 
+	// Encrypted values in the config are decrypted with the master key
+	if key, err := common.MasterKeyFromConfig(genConf); err != nil {
+		log.Fatalf("Unable to load the master key. %s\n", err.Error())
+	} else if key != nil {
+		common.MasterKey = key
+		log.Info("Encrypted configuration values are decrypted with the master key.")
+	}
+
+	// Keys and passwords in the config can refer to secrets kept in Vault
+	if vc, err := vault.Setup(confFile.Section("Vault")); err != nil {
+		log.Fatalf("Unable to setup Vault. %s\n", err.Error())
+	} else if vc != nil {
+		common.Secrets = vc
+		common.SecretRefresh = vc.Refresh
+		log.WithField("address", vc.Addr).Info("Secrets are read from Vault.")
+	}
+
 	var statefile string
 	statefile = common.StripQuotes(genConf["StateFile"])
 
+	// Backups are restored before the queue reads the state file
+	if *restore != "" || *verifyBackup != "" {
+		runBackupCommand(confFile, statefile, *restore, *verifyBackup)
+		return
+	}
+
 	var updatetime int
 	var resourcetimeout int
 	utconf := common.StripQuotes(genConf["UpdateTime"])
@@ -163,6 +231,69 @@ func main() {
 	} else {
 		resourcetimeout = 5
 	}
+	var bandwidth int
+	bwconf := common.StripQuotes(genConf["ResourceBandwidth"])
+	if bwconf != "" {
+		var err error
+		bandwidth, err = strconv.Atoi(bwconf)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to parse resource bandwidth in config file.")
+			bandwidth = 0
+		}
+	}
+	if invconf := common.StripQuotes(genConf["InventoryInterval"]); invconf != "" {
+		minutes, err := strconv.Atoi(invconf)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to parse inventory interval in config file.")
+		} else {
+			queue.InventoryInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+	if v := common.StripQuotes(genConf["InterruptionInterval"]); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			log.WithField("InterruptionInterval", v).Error("Unable to parse interruption interval in config file.")
+		} else {
+			queue.InterruptionInterval = time.Duration(seconds) * time.Second
+		}
+	}
+	var checkpointinterval int
+	checkpointconf := common.StripQuotes(genConf["CheckpointInterval"])
+	if checkpointconf != "" {
+		var err error
+		checkpointinterval, err = strconv.Atoi(checkpointconf)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to parse checkpoint interval in config file.")
+			checkpointinterval = 0
+		}
+	}
+	var stalltimeout, stallrequeues int
+	if v := common.StripQuotes(genConf["StallTimeout"]); v != "" {
+		var err error
+		stalltimeout, err = strconv.Atoi(v)
+		if err != nil || stalltimeout < 0 {
+			log.WithField("StallTimeout", v).Error("Unable to parse stall timeout in config file.")
+			stalltimeout = 0
+		}
+	}
+	if v := common.StripQuotes(genConf["StallRequeues"]); v != "" {
+		var err error
+		stallrequeues, err = strconv.Atoi(v)
+		if err != nil || stallrequeues < 0 {
+			log.WithField("StallRequeues", v).Error("Unable to parse stall requeues in config file.")
+			stallrequeues = 0
+		}
+	}
+	var maxruntime int
+	maxrunconf := common.StripQuotes(genConf["MaxRuntime"])
+	if maxrunconf != "" {
+		var err error
+		maxruntime, err = strconv.Atoi(maxrunconf)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to parse maximum job runtime in config file.")
+			maxruntime = 0
+		}
+	}
 
 	log.WithFields(log.Fields{
 		"ip":   runIP,
@@ -184,100 +315,124 @@ func main() {
 	}
 
 	// Check for type of authentication and set conf
-	switch confAuth["type"] {
-	case "INI":
-		var i INIAuth
-
-		// Get the users
-		umap := map[string]string{}
-
-		au := common.StripQuotes(confAuth["adminuser"])
-		if au == "" {
-			log.Fatal("An administrative user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
-		ap := common.StripQuotes(confAuth["adminpass"])
-		if ap == "" {
-			log.Fatal("An administrative password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+	authtype := common.StripQuotes(confAuth["type"])
+	if authtype == "Chain" {
+		// Multiple authenticators are tried in the order they are listed
+		var chain ChainAuth
+		for _, t := range strings.Split(common.StripQuotes(confAuth["chain"]), ",") {
+			t = strings.TrimSpace(t)
+			if t == "" || t == "Chain" {
+				continue
+			}
+			chain.Add(t, setupAuthenticator(t, confAuth))
 		}
 
-		su := common.StripQuotes(confAuth["standarduser"])
-		if su == "" {
-			log.Fatal("An standard user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
-		sp := common.StripQuotes(confAuth["standarduser"])
-		if sp == "" {
-			log.Fatal("An standard password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		if len(chain.Auths) == 0 {
+			log.Fatal("No authenticators were configured for the chain. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
 		}
 
-		ru := common.StripQuotes(confAuth["readonlyuser"])
-		if ru == "" {
-			log.Fatal("An read only user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		server.Auth = &chain
+		log.WithField("chain", chain.Names).Info("Chained authentication configured successfully.")
+	} else {
+		server.Auth = setupAuthenticator(authtype, confAuth)
+	}
+
+	// Configure the TokenStore
+	server.T = setupTokenStore(confFile.Section("Sessions"))
+	server.Cookies = setupSessionCookies(confFile.Section("Sessions"))
+
+	// Logins are recorded with the address of the client, which is only taken
+	// from X-Forwarded-For when the connection is from a trusted proxy
+	TrustedProxies = parseNetworks(common.StripQuotes(genConf["TrustedProxies"]))
+	if path := common.StripQuotes(genConf["GeoIPDatabase"]); path != "" {
+		db, err := geoip.Open(path)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to load the GeoIP database.")
+		} else {
+			server.Geo = db
+			log.WithField("networks", db.Len()).Info("GeoIP database loaded.")
 		}
-		rp := common.StripQuotes(confAuth["readonlypass"])
-		if rp == "" {
-			log.Fatal("An read only password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+	}
+
+	// Messages sent in responses can be translated with a catalog for each locale
+	server.M = NewMessageCatalog()
+	if dir := common.StripQuotes(genConf["MessageCatalogDir"]); dir != "" {
+		if err := server.M.LoadDir(dir); err != nil {
+			log.WithField("error", err.Error()).Error("Unable to load message catalogs.")
 		}
+	}
 
-		umap[au] = ap
-		umap[su] = sp
-		umap[ru] = rp
+	// Configure wordlist processing, which is only enabled with a directory
+	server.W = wordlist.NewProcessor()
+	server.WordlistDir = common.StripQuotes(genConf["WordlistDir"])
+	server.HcstatBin = common.StripQuotes(genConf["HcstatBin"])
 
-		// Setup group mappings
-		gmap := map[string]string{}
+	// Configure the Queue
+	server.Q = queue.NewQueue(statefile, updatetime, resourcetimeout, maxruntime)
 
-		gmap[au] = Administrator
-		gmap[su] = StandardUser
-		gmap[ru] = ReadOnly
+	// Connections to resources can optionally go through a proxy
+	queue.ResourceProxy = common.StripQuotes(genConf["ResourceProxy"])
 
-		i.Setup(umap, gmap)
+	// The bandwidth limit is configured in kilobytes per second
+	queue.ResourceBandwidth = int64(bandwidth) * 1024
 
-		server.Auth = &i
+	// Long running jobs are checkpointed every interval of minutes
+	queue.CheckpointInterval = time.Duration(checkpointinterval) * time.Minute
 
-		log.Info("INI authentication setup complete.")
-	case "ActiveDirectory":
-		var ad ADAuth
+	// Running jobs without progress for the stall timeout in minutes are
+	// marked as stalled and queued again up to the number of requeues
+	queue.StallTimeout = time.Duration(stalltimeout) * time.Minute
+	queue.StallRequeues = stallrequeues
 
-		realm := common.StripQuotes(confAuth["realm"])
-		if realm == "" {
-			log.Fatal("No Active Directory realm was configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
-		ad.SetRealm(realm)
+	// Jobs behind the head of a queue only start where they do not delay it
+	queue.Backfill = common.StripQuotes(genConf["Backfill"]) == "true"
 
-		gmap := map[string]string{}
-		ro := common.StripQuotes(confAuth["ReadOnlyGroup"])
-		if ro == "" {
-			log.Fatal("A read only group was not provided. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
-		st := common.StripQuotes(confAuth["StandardGroup"])
-		if st == "" {
-			log.Fatal("A group for standard access was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
-		admin := common.StripQuotes(confAuth["AdminGroup"])
-		if admin == "" {
-			log.Fatal("A group for read only access was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
-		}
+	// Free resources are used before those with a cost and projects are kept
+	// within their budgets
+	queue.CostAware = common.StripQuotes(genConf["CostAware"]) == "true"
+	switch action := common.StripQuotes(genConf["BudgetAction"]); action {
+	case "":
+	case queue.BUDGET_REFUSE, queue.BUDGET_CONFIRM:
+		queue.BudgetAction = action
+	default:
+		log.WithField("BudgetAction", action).Error("Unknown budget action in config file, jobs over budget are refused.")
+	}
+	setupBudgets(confFile.Section("Budgets"))
 
-		gmap[ReadOnly] = ro
-		gmap[StandardUser] = st
-		gmap[Administrator] = admin
+	// Sensitive jobs need a second Administrator to approve them
+	setupApproval(confFile.Section("Approval"))
 
-		ad.Setup(gmap)
+	// Output and performance data kept in memory for each job
+	setupRetention(confFile.Section("Retention"))
+	setupExpiry(confFile.Section("Expiry"))
 
-		server.Auth = &ad
-		log.WithFields(log.Fields{
-			"readonly": ro,
-			"standard": st,
-			"admin":    admin,
-		}).Info("Active directory authentication configured successfully.")
-	}
+	// Policy cracked passwords are checked against for compliance reports
+	server.Policy = setupPasswordPolicy(confFile.Section("PasswordPolicy"))
+	server.Pwned = setupPwned(confFile.Section("PwnedPasswords"))
+	server.Reports = setupReports(confFile.Section("Reports"))
+	server.Evidence = setupEvidence(confFile.Section("Evidence"))
 
-	// Configure the TokenStore
-	server.T = NewTokenStore()
+	// Heavy reads can be served from the state file instead of the queue
+	server.Replica = setupReplica(confFile.Section("ReadReplica"), statefile)
 
-	// Configure the Queue
-	server.Q = queue.NewQueue(statefile, updatetime, resourcetimeout)
+	// Large job data such as spilled output is kept in storage
+	queue.JobStorage = setupStorage(confFile.Section("Storage"), common.StripQuotes(genConf["OutputSpillDir"]))
+	queue.Shared = setupSharedFiles(confFile.Section("SharedFiles"))
+
+	// Tool binaries uploaded for resources are kept with the job data
+	if queue.JobStorage != nil {
+		repo, err := queue.NewBinaryRepo(queue.JobStorage)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to read the tool binary repository.")
+		} else {
+			queue.Binaries = repo
+		}
+	}
 
-	caBytes, err := ioutil.ReadFile(caCertPath)
+	// Deadlines, retries and the circuit breaker for calls to resources
+	setupResourceRPC(genConf)
+
+	caBytes, err := common.ReadSecretFile(caCertPath)
 	if err != nil {
 		println("ERROR: " + err.Error())
 	}
@@ -285,15 +440,17 @@ func main() {
 	caPool := x509.NewCertPool()
 	caPool.AppendCertsFromPEM(caBytes)
 
-	tlscert, err := tls.LoadX509KeyPair(CertPath, KeyPath)
+	// The pair is used for both ends of connections and replaced when rotated
+	tlscert, err := common.LoadKeyPair(CertPath, KeyPath)
 	if err != nil {
 		log.Fatalf("Failed to load cert pair for Q and R connecton. %s\n", err.Error())
 	}
+	tlscert.Watch()
 
 	// Setup TLS connection for the Queue and Resource communication
 	qandrTLSConfig := &tls.Config{}
-	qandrTLSConfig.Certificates = make([]tls.Certificate, 1)
-	qandrTLSConfig.Certificates[0] = tlscert
+	qandrTLSConfig.GetCertificate = tlscert.GetCertificate
+	qandrTLSConfig.GetClientCertificate = tlscert.GetClientCertificate
 	qandrTLSConfig.RootCAs = caPool
 	qandrTLSConfig.ClientCAs = caPool
 	qandrTLSConfig.CipherSuites = []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA,
@@ -309,12 +466,14 @@ func main() {
 
 	// Check if we are using a different TLS configuration for the API portion of the Queue
 	if useSepAPITLS {
-		apiCert, err := tls.LoadX509KeyPair(APICertPath, APIKeyPath)
+		apiCert, err := common.LoadKeyPair(common.StripQuotes(APICertPath), common.StripQuotes(APIKeyPath))
 		if err != nil {
 			log.Fatalf("API Cert and Key set, but could not be loaded. %s\n", err.Error())
 		}
+		apiCert.Watch()
+
 		apiTLSConfig := &tls.Config{
-			Certificates: []tls.Certificate{apiCert},
+			GetCertificate: apiCert.GetCertificate,
 			CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA,
 				tls.TLS_RSA_WITH_AES_256_CBC_SHA,
 				tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
@@ -333,6 +492,28 @@ func main() {
 		server.TLS = qandrTLSConfig
 	}
 
+	// The API certificate can instead be requested and renewed through ACME
+	if domains := common.StripQuotes(genConf["ACMEDomains"]); domains != "" {
+		mgr, err := setupACME(genConf, domains)
+		if err != nil {
+			log.Fatalf("Unable to get a certificate through ACME. %s\n", err.Error())
+		}
+
+		server.TLS = &tls.Config{
+			GetCertificate: mgr.GetCertificate,
+			CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+				tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+				tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			MinVersion:             tls.VersionTLS12,
+			SessionTicketsDisabled: true,
+		}
+	}
+
 	// Add some nice security stuff
 	secureMiddleware := secure.New(secure.Options{
 		SSLRedirect:             true,
@@ -357,6 +538,16 @@ func main() {
 		server.Q.AddResourceManager(resmgr_dc)
 	}
 
+	// Setup the reverse connect manager if we have an address to listen on
+	if resRC, ok := confResMgr["reverseconnect"]; ok {
+		resmgr_rc, err := reverseconnectresourcemanager.Setup(common.StripQuotes(resRC), &server.Q, qandrTLSConfig)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup reverse connect resource manager.")
+		} else {
+			server.Q.AddResourceManager(resmgr_rc)
+		}
+	}
+
 	// Now let's setup the AWS manager if we have a config file
 	if resDC, ok := confResMgr["aws"]; ok {
 		resmgr_aws, err := awsresourcemanager.Setup(resDC, &server.Q, qandrTLSConfig, caCertPath, caKeyPath)
@@ -367,23 +558,1320 @@ func main() {
 		}
 	}
 
+	// The other cloud providers are set up the same way
+	if resGCP, ok := confResMgr["gcp"]; ok {
+		resmgr_gcp, err := gcpresourcemanager.Setup(common.StripQuotes(resGCP), &server.Q, qandrTLSConfig, caCertPath, caKeyPath)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup GCP resource manager.")
+		} else {
+			server.Q.AddResourceManager(resmgr_gcp)
+		}
+	}
+	if resAzure, ok := confResMgr["azure"]; ok {
+		resmgr_azure, err := azureresourcemanager.Setup(common.StripQuotes(resAzure), &server.Q, qandrTLSConfig, caCertPath, caKeyPath)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup Azure resource manager.")
+		} else {
+			server.Q.AddResourceManager(resmgr_azure)
+		}
+	}
+
+	// Pods can be run on a Kubernetes cluster as the queue needs them
+	if resK8s, ok := confResMgr["kubernetes"]; ok {
+		resmgr_k8s, err := kubernetesresourcemanager.Setup(common.StripQuotes(resK8s), &server.Q, qandrTLSConfig)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup Kubernetes resource manager.")
+		} else {
+			server.Q.AddResourceManager(resmgr_k8s)
+		}
+	}
+
+	// SETUP EXPORTERS
+	// Newly cracked credentials can be pushed to other tools for analysis
+	confExport := confFile.Section("Exporters")
+	if path, ok := confExport["dpat"]; ok {
+		exp, err := dpatexporter.Setup(common.StripQuotes(path))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup DPAT exporter.")
+		} else {
+			server.Q.AddExporter(exp)
+		}
+	}
+	if path, ok := confExport["neo4j"]; ok {
+		exp, err := neo4jexporter.Setup(common.StripQuotes(path))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup Neo4j exporter.")
+		} else {
+			server.Q.AddExporter(exp)
+		}
+	}
+	if path, ok := confExport["rest"]; ok {
+		exp, err := restexporter.Setup(common.StripQuotes(path))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup REST exporter.")
+		} else {
+			server.Q.AddExporter(exp)
+		}
+	}
+
+	// Users can be mailed digests of the queue when a mail server is set
+	server.D = setupDigests(confFile.Section("Notifications"), &server.Q)
+	if server.D != nil {
+		server.D.Start()
+	}
+
+	// Single hashes can be run through a canned pipeline when its wordlists are set
+	server.Quick = setupQuickCrack(confFile.Section("QuickCrack"), &server.Q, server.D)
+	if server.Quick != nil {
+		server.Q.AddJobReferrer(server.Quick)
+		server.Quick.Start()
+	}
+
+	// Operators can be paged about problems with the queue itself
+	if mon := setupAlerts(confFile.Section("Alerts"), &server.Q); mon != nil {
+		mon.Start()
+	}
+
+	// The state and job storage can be backed up on a schedule
+	if target, every := setupBackups(confFile.Section("Backup")); target != nil && every > 0 {
+		server.Q.StartBackups(target, every)
+	}
+
 	// Build the Negroni handler
 	n := negroni.New(negroni.NewRecovery(),
-		cracklog.NewNegroniLogger(),
-		negroni.NewStatic(http.Dir(webRoot)))
+		cracklog.NewNegroniLogger())
+
+	// Compress web files and API responses for clients that accept it
+	if gz := setupCompression(genConf); gz != nil {
+		n.Use(gz)
+	}
 
+	n.Use(negroni.NewStatic(http.Dir(webRoot)))
 	n.Use(negroni.HandlerFunc(secureMiddleware.HandlerFuncWithNext))
-	n.UseHandler(server.Router())
+
+	// Clients can be allowed or denied parts of the API by their network
+	access := setupAccess(confFile.Section("Access"), server.M, parseNetworks(common.StripQuotes(genConf["AdminNetworks"])))
+	if access != nil {
+		n.Use(access)
+	}
+
+	// Sessions carried in a cookie must prove requests came from the web interface
+	n.Use(NewCSRFMiddleware(server.M))
+
+	// Users flagged to change their password can do nothing else until they do
+	n.Use(NewPasswordChangeMiddleware(server.T, server.M))
+
+	// Cluster administration can be kept to its own listeners
+	adminAddrs := splitList(common.StripQuotes(genConf["AdminListenAddresses"]))
+	n.Use(NewAdminMiddleware(len(adminAddrs) > 0))
+
+	// Record the latency of each route and log slow requests
+	router := server.Router()
+	server.Metrics = NewRouteMetrics(router, server.T, setupSlowRequests(genConf))
+	n.Use(server.Metrics)
+	n.UseHandler(router)
 	log.Debug("Negroni handler started.")
 
-	listen, err := tls.Listen("tcp", runIP+":"+runPort, server.TLS)
-	if err != nil {
-		println("ERROR: Unable to bind to '" + runIP + ":" + runPort + "':" + err.Error())
-		return
+	// The API can listen on several addresses, by default just BindIP and BindPort
+	addrs := []string{runIP + ":" + runPort}
+	if la := common.StripQuotes(genConf["ListenAddresses"]); la != "" {
+		addrs = splitList(la)
 	}
 
-	err = http.Serve(listen, n)
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		listen, err := tls.Listen("tcp", addr, server.TLS)
+		if err != nil {
+			println("ERROR: Unable to bind to '" + addr + "':" + err.Error())
+			return
+		}
+
+		log.WithField("addr", addr).Info("Listening for API connections.")
+		listeners = append(listeners, listen)
+	}
+
+	var adminListeners []net.Listener
+	for _, addr := range adminAddrs {
+		listen, err := tls.Listen("tcp", addr, server.TLS)
+		if err != nil {
+			println("ERROR: Unable to bind the admin listener to '" + addr + "':" + err.Error())
+			return
+		}
+
+		log.WithField("addr", addr).Info("Listening for administrative API connections.")
+		adminListeners = append(adminListeners, listen)
+	}
+
+	// Local tooling and reverse proxies can connect over a UNIX socket instead
+	if sock := common.StripQuotes(genConf["ListenSocket"]); sock != "" {
+		mode := os.FileMode(0660)
+		if m := common.StripQuotes(genConf["ListenSocketMode"]); m != "" {
+			parsed, err := strconv.ParseUint(m, 8, 32)
+			if err != nil {
+				println("ERROR: Unable to parse ListenSocketMode: " + err.Error())
+				return
+			}
+			mode = os.FileMode(parsed)
+		}
+
+		listen, err := listenSocket(sock, mode)
+		if err != nil {
+			println("ERROR: Unable to listen on socket '" + sock + "':" + err.Error())
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"socket": sock,
+			"mode":   mode,
+		}).Info("Listening for API connections.")
+		listeners = append(listeners, listen)
+	}
+
+	// Profiles and runtime diagnostics are only served when asked for, on their
+	// own address so they can be kept off the network the API is on
+	if addr := common.StripQuotes(genConf["DebugListen"]); addr != "" {
+		listen, err := tls.Listen("tcp", addr, server.TLS)
+		if err != nil {
+			println("ERROR: Unable to bind the debug listener to '" + addr + "':" + err.Error())
+			return
+		}
+
+		log.WithField("addr", addr).Warn("Listening for debug connections, profiles are served to administrators.")
+		go func() {
+			err := http.Serve(listen, NewDebugServer(server.T, &server.Q))
+			log.WithField("error", err.Error()).Error("The debug listener stopped.")
+		}()
+	}
+
+	// Serve every listener and stop if any of them fail
+	errs := make(chan error, len(listeners)+len(adminListeners))
+	for _, listen := range listeners {
+		go func(l net.Listener) {
+			errs <- http.Serve(l, n)
+		}(listen)
+	}
+	for _, listen := range adminListeners {
+		go func(l net.Listener) {
+			errs <- http.Serve(l, adminListener(n))
+		}(listen)
+	}
+
+	err = <-errs
 	if err != nil {
 		log.Fatal("Unable to start up web server: " + err.Error())
 	}
 }
+
+// Split a comma separated list from the config, dropping empty entries
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Listen on a UNIX domain socket with the given permissions. A socket file
+// left over from a previous run is removed first.
+func listenSocket(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.New("File exists and is not a socket.")
+		}
+		os.Remove(path)
+	}
+
+	listen, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, mode)
+	if err != nil {
+		listen.Close()
+		return nil, err
+	}
+
+	return listen, nil
+}
+
+// Setup the ACME certificate manager from the General section of the
+// configuration file and get the first certificate
+func setupACME(genConf ini.Section, domains string) (*acme.Manager, error) {
+	var names []string
+	for _, d := range strings.Split(domains, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			names = append(names, d)
+		}
+	}
+
+	dirURL := common.StripQuotes(genConf["ACMEDirectory"])
+	if dirURL == "" {
+		dirURL = acme.LetsEncryptURL
+	}
+
+	cacheDir := common.StripQuotes(genConf["ACMECacheDir"])
+	if cacheDir == "" {
+		cacheDir = "/var/cracklord/acme"
+	}
+
+	var solver acme.Solver
+	switch common.StripQuotes(genConf["ACMEChallenge"]) {
+	case "", "http-01":
+		httpSolver := acme.NewHTTPSolver()
+		solver = httpSolver
+
+		bind := common.StripQuotes(genConf["ACMEHTTPBind"])
+		if bind == "" {
+			bind = ":80"
+		}
+
+		// Everything other than challenges is sent to the HTTPS API
+		redirect := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			http.Redirect(rw, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusMovedPermanently)
+		})
+
+		go func() {
+			err := http.ListenAndServe(bind, httpSolver.Handler(redirect))
+			if err != nil {
+				log.WithField("error", err.Error()).Error("Unable to start the ACME challenge server.")
+			}
+		}()
+	case "dns-01":
+		hook := common.StripQuotes(genConf["ACMEDNSHook"])
+		if hook == "" {
+			return nil, errors.New("ACMEDNSHook is required for the dns-01 challenge.")
+		}
+
+		wait := 60
+		if w := common.StripQuotes(genConf["ACMEDNSWait"]); w != "" {
+			var err error
+			wait, err = strconv.Atoi(w)
+			if err != nil {
+				return nil, errors.New("Unable to parse ACMEDNSWait: " + err.Error())
+			}
+		}
+
+		solver = &acme.DNSHookSolver{
+			Command: hook,
+			Wait:    time.Duration(wait) * time.Second,
+		}
+	default:
+		return nil, errors.New("ACMEChallenge must be http-01 or dns-01.")
+	}
+
+	mgr, err := acme.NewManager(dirURL, cacheDir, common.StripQuotes(genConf["ACMEEmail"]), names, solver)
+	if err != nil {
+		return nil, err
+	}
+
+	err = mgr.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"domains":   names,
+		"directory": dirURL,
+		"challenge": solver.Type(),
+	}).Info("ACME certificate management started.")
+
+	return mgr, nil
+}
+
+// Read the timeouts, retries, circuit breaker and dispatch settings used when
+// calling resources. Settings that are missing or invalid keep the queue
+// defaults.
+func setupResourceRPC(genConf ini.Section) {
+	seconds := func(key string, d *time.Duration) {
+		if v := common.StripQuotes(genConf[key]); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.WithField("setting", key).Error("Unable to parse resource RPC setting in config file.")
+				return
+			}
+			*d = time.Duration(n) * time.Second
+		}
+	}
+	count := func(key string, c *int) {
+		if v := common.StripQuotes(genConf[key]); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				log.WithField("setting", key).Error("Unable to parse resource RPC setting in config file.")
+				return
+			}
+			*c = n
+		}
+	}
+
+	seconds("RPCTimeout", &queue.RPCTimeout)
+	seconds("SlowRPCTimeout", &queue.SlowRPCTimeout)
+	count("RPCRetries", &queue.RPCRetries)
+	count("BreakerFailures", &queue.BreakerFailures)
+	seconds("BreakerCooldown", &queue.BreakerCooldown)
+	count("DispatchWorkers", &queue.DispatchWorkers)
+
+	log.WithFields(log.Fields{
+		"timeout":         queue.RPCTimeout,
+		"slowtimeout":     queue.SlowRPCTimeout,
+		"retries":         queue.RPCRetries,
+		"breakerfailures": queue.BreakerFailures,
+		"breakercooldown": queue.BreakerCooldown,
+		"dispatchworkers": queue.DispatchWorkers,
+	}).Debug("Resource RPC settings configured.")
+}
+
+// Read how long API requests can take before they are logged as slow, in
+// milliseconds. Requests are not logged when it is 0.
+func setupSlowRequests(genConf ini.Section) time.Duration {
+	v := common.StripQuotes(genConf["SlowRequest"])
+	if v == "" {
+		return 2 * time.Second
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.WithField("setting", "SlowRequest").Error("Unable to parse slow request setting in config file.")
+		return 2 * time.Second
+	}
+
+	return time.Duration(n) * time.Millisecond
+}
+
+// Parse performance tiers written as age:interval pairs separated by commas,
+// such as 1h:1m,24h:1h. An empty list keeps every sample.
+func parseTiers(v string) ([]common.PerformanceTier, error) {
+	tiers := []common.PerformanceTier{}
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 {
+			return nil, errors.New("Performance tiers must be age:interval pairs")
+		}
+
+		after, err := time.ParseDuration(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		interval, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if interval < time.Second {
+			return nil, errors.New("Performance tier intervals must be at least a second")
+		}
+
+		tiers = append(tiers, common.PerformanceTier{After: after, Interval: interval})
+	}
+
+	return tiers, nil
+}
+
+// Build the storage for job data from the Storage section. Without one, a
+// spill directory is used as local storage.
+func setupStorage(confStore ini.Section, spillDir string) queue.Storage {
+	store := openStorage(confStore, spillDir)
+	if store != nil {
+		log.WithField("storage", store.Name()).Info("Job data storage configured.")
+	}
+	return store
+}
+
+// Open the storage of a section with a type and the settings of the type, the
+// spill directory is used when there is no type
+func openStorage(confStore ini.Section, spillDir string) queue.Storage {
+	get := func(key string) string {
+		return common.StripQuotes(confStore[key])
+	}
+
+	var store queue.Storage
+	switch strings.ToLower(get("type")) {
+	case "":
+		if spillDir == "" {
+			return nil
+		}
+
+		local, err := queue.NewLocalStorage(spillDir)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to create the output spill directory.")
+			return nil
+		}
+		store = local
+	case "local":
+		local, err := queue.NewLocalStorage(get("path"))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to create the local storage directory.")
+			return nil
+		}
+		store = local
+	case "s3":
+		c := s3.New(get("endpoint"), get("region"), get("bucket"), get("accesskey"), configSecret("secretkey", get("secretkey")), get("pathstyle") == "true")
+		store = queue.NewS3Storage(c, get("prefix"))
+	case "azure":
+		c, err := azblob.New(get("account"), configSecret("key", get("key")), get("container"), get("endpoint"))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to setup Azure Blob Storage.")
+			return nil
+		}
+		store = queue.NewAzureStorage(c, get("prefix"))
+	default:
+		log.WithField("type", get("type")).Error("Unknown storage type in config file.")
+		return nil
+	}
+
+	return store
+}
+
+// Read where backups are kept and how often they are taken, a nil storage if
+// they are not
+func setupBackups(confBackup ini.Section) (queue.Storage, time.Duration) {
+	get := func(key string) string {
+		return common.StripQuotes(confBackup[key])
+	}
+
+	if get("type") == "" {
+		return nil, 0
+	}
+
+	if v := get("keep"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.WithField("keep", v).Error("Unable to parse the number of backups to keep in config file.")
+		} else {
+			queue.BackupKeep = n
+		}
+	}
+
+	target := openStorage(confBackup, "")
+	if target == nil {
+		return nil, 0
+	}
+
+	every := 24 * time.Hour
+	if v := get("interval"); v != "" {
+		h, err := strconv.Atoi(v)
+		if err != nil || h < 0 {
+			log.WithField("interval", v).Error("Unable to parse the backup interval in config file.")
+		} else {
+			every = time.Duration(h) * time.Hour
+		}
+	}
+
+	// The storage is still needed to restore without scheduled backups
+	if every == 0 {
+		return target, 0
+	}
+
+	log.WithFields(log.Fields{
+		"storage":  target.Name(),
+		"interval": every,
+		"keep":     queue.BackupKeep,
+	}).Info("Scheduled backups are enabled.")
+	return target, every
+}
+
+// Build the read replica heavy read endpoints are served from, nil when it is
+// not enabled
+func setupReplica(confReplica ini.Section, statefile string) *queue.Replica {
+	get := func(key string) string {
+		return common.StripQuotes(confReplica[key])
+	}
+
+	if get("enabled") != "true" {
+		return nil
+	}
+	if statefile == "" {
+		log.Error("The read replica needs a StateFile to read from, heavy reads are served by the queue.")
+		return nil
+	}
+
+	parse := func(key string, def int) int {
+		v := get(key)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.WithField(key, v).Error("Unable to parse read replica setting in config file.")
+			return def
+		}
+		return n
+	}
+
+	workers := parse("workers", 4)
+	maxLag := time.Duration(parse("maxlag", 60)) * time.Second
+
+	log.WithFields(log.Fields{
+		"workers": workers,
+		"maxlag":  maxLag,
+	}).Info("Heavy reads are served from the read replica.")
+	return queue.NewReplica(statefile, workers, maxLag)
+}
+
+// Restore or verify a backup from the backup storage of the configuration
+func runBackupCommand(confFile ini.File, statefile, restoreID, verifyID string) {
+	target, _ := setupBackups(confFile.Section("Backup"))
+	if target == nil {
+		log.Fatal("No backup storage is configured in the Backup section of the configuration file.")
+	}
+
+	if verifyID != "" {
+		m, err := queue.VerifyBackup(target, verifyID)
+		if err != nil {
+			log.Fatalf("Backup failed verification. %s\n", err.Error())
+		}
+
+		log.WithFields(log.Fields{
+			"backup": m.ID,
+			"taken":  m.Taken.Format(time.RFC3339),
+			"files":  len(m.Files),
+		}).Info("Backup matches its checksums.")
+		return
+	}
+
+	store := setupStorage(confFile.Section("Storage"), common.StripQuotes(confFile.Section("General")["OutputSpillDir"]))
+	m, err := queue.RestoreBackup(target, restoreID, statefile, store)
+	if err != nil {
+		log.Fatalf("Unable to restore the backup. %s\n", err.Error())
+	}
+
+	log.WithFields(log.Fields{
+		"backup":    m.ID,
+		"taken":     m.Taken.Format(time.RFC3339),
+		"files":     len(m.Files),
+		"statefile": statefile,
+	}).Info("Backup restored, the queue server can be started.")
+}
+
+// Get a setting that can be encrypted with the master key or refer to a
+// secret in Vault, empty if it cannot be read
+func configSecret(setting, v string) string {
+	secret, err := common.ResolveSecret(v)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"setting": setting,
+			"error":   err.Error(),
+		}).Error("Unable to read secret setting in config file.")
+	}
+
+	return secret
+}
+
+// Build the bucket resources get shared wordlists and rules from with
+// presigned URLs, nil if no bucket is set
+func setupSharedFiles(confShared ini.Section) *queue.SharedFiles {
+	get := func(key string) string {
+		return common.StripQuotes(confShared[key])
+	}
+
+	if get("bucket") == "" {
+		return nil
+	}
+
+	expires := time.Hour
+	if e := get("expires"); e != "" {
+		n, err := strconv.Atoi(e)
+		if err != nil || n <= 0 {
+			log.WithField("expires", e).Error("Unable to parse the shared file URL expiry in config file.")
+		} else {
+			expires = time.Duration(n) * time.Second
+		}
+	}
+
+	c := s3.New(get("endpoint"), get("region"), get("bucket"), get("accesskey"), configSecret("secretkey", get("secretkey")), get("pathstyle") == "true")
+
+	log.WithFields(log.Fields{
+		"bucket":  c.Bucket,
+		"expires": expires,
+	}).Info("Shared files bucket configured.")
+	return queue.NewSharedFiles(c, get("prefix"), expires)
+}
+
+// Read the networks allowed and denied each group of API endpoints, as
+// <group>allow and <group>deny. The networks of AdminNetworks in the General
+// section are also allowed administration. Nil without any rules.
+func setupAccess(confAccess ini.Section, m *MessageCatalog, adminNets []*net.IPNet) *AccessMiddleware {
+	var rules []AccessRule
+	for _, group := range []string{ACCESS_API, ACCESS_LOGIN, ACCESS_ADMIN, ACCESS_ENROLLMENT} {
+		rule := AccessRule{
+			Group: group,
+			Allow: parseNetworks(common.StripQuotes(confAccess[group+"allow"])),
+			Deny:  parseNetworks(common.StripQuotes(confAccess[group+"deny"])),
+		}
+		if group == ACCESS_ADMIN {
+			rule.Allow = append(rule.Allow, adminNets...)
+		}
+		if len(rule.Allow) == 0 && len(rule.Deny) == 0 {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"group": group,
+			"allow": len(rule.Allow),
+			"deny":  len(rule.Deny),
+		}).Info("Network access rules configured.")
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+	return NewAccessMiddleware(m, rules)
+}
+
+// Read whether logins deliver the session token in a cookie instead of the
+// response. The cookie is secure and hidden from scripts unless turned off.
+func setupSessionCookies(confSess ini.Section) *SessionCookies {
+	get := func(key string) string {
+		return strings.ToLower(common.StripQuotes(confSess[key]))
+	}
+
+	if get("cookie") != "true" {
+		return nil
+	}
+
+	c := &SessionCookies{
+		Secure:   get("cookiesecure") != "false",
+		HttpOnly: get("cookiehttponly") != "false",
+		SameSite: http.SameSiteStrictMode,
+	}
+
+	switch get("cookiesamesite") {
+	case "", "strict":
+	case "lax":
+		c.SameSite = http.SameSiteLaxMode
+	case "none":
+		c.SameSite = http.SameSiteNoneMode
+	default:
+		log.WithField("cookiesamesite", get("cookiesamesite")).Error("Unknown SameSite mode in config file, using strict.")
+	}
+
+	log.WithFields(log.Fields{
+		"secure":   c.Secure,
+		"httponly": c.HttpOnly,
+	}).Info("Session tokens are delivered in cookies.")
+	return c
+}
+
+// Keep session tokens in memory or, when type is redis, in Redis so they
+// survive restarts and are shared by every queue server using it
+func setupTokenStore(confSess ini.Section) TokenStore {
+	get := func(key string) string {
+		return common.StripQuotes(confSess[key])
+	}
+
+	switch strings.ToLower(get("type")) {
+	case "", "memory":
+		return NewTokenStore()
+	case "redis":
+		db, err := strconv.Atoi(get("db"))
+		if err != nil && get("db") != "" {
+			log.WithField("db", get("db")).Error("Unable to parse the Redis database in config file.")
+		}
+
+		var tlsConfig *tls.Config
+		if get("tls") == "true" {
+			host, _, _ := net.SplitHostPort(get("address"))
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+
+		c := redis.New(get("address"), configSecret("password", get("password")), db, tlsConfig)
+		common.WatchSecrets(func() {
+			if password, err := common.ResolveSecret(get("password")); err == nil {
+				c.SetPassword(password)
+				log.Info("Using the rotated Redis password.")
+			}
+		}, get("password"))
+		if err := c.Ping(); err != nil {
+			log.WithFields(log.Fields{
+				"address": get("address"),
+				"error":   err.Error(),
+			}).Error("Unable to connect to Redis, logins will fail until it can be reached.")
+		}
+
+		log.WithField("address", get("address")).Info("Session tokens are kept in Redis.")
+		return NewRedisTokenStore(c, get("prefix"))
+	default:
+		log.WithField("type", get("type")).Fatal("Unknown session store type in config file.")
+		return nil
+	}
+}
+
+// Read the mail server and schedule of notification digests, digests are
+// disabled without a mail server
+func setupDigests(confNotify ini.Section, q *queue.Queue) *Digester {
+	get := func(key string) string {
+		return common.StripQuotes(confNotify[key])
+	}
+
+	if get("smtp") == "" {
+		return nil
+	}
+
+	at := 8 * time.Hour
+	if v := get("digestat"); v != "" {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			log.WithField("digestat", v).Error("Unable to parse the digest time in config file.")
+		} else {
+			at = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		}
+	}
+
+	shift := 8 * time.Hour
+	if v := get("shifthours"); v != "" {
+		h, err := strconv.Atoi(v)
+		if err != nil || h <= 0 || h > 24 {
+			log.WithField("shifthours", v).Error("Unable to parse the shift length in config file.")
+		} else {
+			shift = time.Duration(h) * time.Hour
+		}
+	}
+
+	prefsFile := get("prefsfile")
+	if prefsFile == "" {
+		prefsFile = "notifications.json"
+	}
+
+	mail := notify.NewSMTP(get("smtp"), get("username"), configSecret("password", get("password")), get("from"))
+	common.WatchSecrets(func() {
+		if password, err := common.ResolveSecret(get("password")); err == nil {
+			mail.SetPassword(password)
+			log.Info("Using the rotated mail server password.")
+		}
+	}, get("password"))
+
+	log.WithField("smtp", get("smtp")).Info("Notification digests are enabled.")
+	return NewDigester(q, mail, prefsFile, at, shift)
+}
+
+// Read the pipeline of quick cracks, the wordlists of the tool tried in order
+// with one set of rules. Quick cracks are disabled without any wordlists.
+func setupQuickCrack(confQuick ini.Section, q *queue.Queue, d *Digester) *QuickCracker {
+	get := func(key string) string {
+		return common.StripQuotes(confQuick[key])
+	}
+
+	var dicts []string
+	for _, dict := range strings.Split(get("dictionaries"), ",") {
+		if dict = strings.TrimSpace(dict); dict != "" {
+			dicts = append(dicts, dict)
+		}
+	}
+	if len(dicts) == 0 {
+		return nil
+	}
+
+	tool := get("tool")
+	if tool == "" {
+		tool = "hashcat"
+	}
+
+	rules, ok := confQuick["rules"]
+	if !ok {
+		rules = "best64"
+	}
+
+	log.WithFields(log.Fields{
+		"tool":         tool,
+		"dictionaries": len(dicts),
+		"queue":        get("queue"),
+	}).Info("Quick cracks are enabled.")
+	return NewQuickCracker(q, tool, dicts, common.StripQuotes(rules), get("queue"), d)
+}
+
+// Read the on call services operators are paged through, nothing is paged
+// without one. Resources are paged about after being offline for
+// resourceoffline minutes and jobs after no progress for stuckjob hours.
+func setupAlerts(confAlert ini.Section, q *queue.Queue) *AlertMonitor {
+	get := func(key string) string {
+		return common.StripQuotes(confAlert[key])
+	}
+
+	source := get("source")
+	if source == "" {
+		source, _ = os.Hostname()
+	}
+
+	var pagers []notify.Pager
+	if key := get("pagerduty"); key != "" {
+		pagers = append(pagers, notify.NewPagerDuty(configSecret("pagerduty", key), source))
+	}
+	if key := get("opsgenie"); key != "" {
+		og := notify.NewOpsgenie(configSecret("opsgenie", key), source)
+		if u := get("opsgenieurl"); u != "" {
+			og.URL = u
+		}
+		pagers = append(pagers, og)
+	}
+	if len(pagers) == 0 {
+		return nil
+	}
+
+	parse := func(key string, def int) int {
+		v := get(key)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.WithField(key, v).Error("Unable to parse alert setting in config file.")
+			return def
+		}
+		return n
+	}
+
+	offline := time.Duration(parse("resourceoffline", 15)) * time.Minute
+	stuck := time.Duration(parse("stuckjob", 4)) * time.Hour
+
+	log.WithFields(log.Fields{
+		"pagers":          len(pagers),
+		"resourceoffline": offline,
+		"stuckjob":        stuck,
+	}).Info("Operational alerts are enabled.")
+	return NewAlertMonitor(q, pagers, offline, stuck)
+}
+
+// Read the password policy compliance reports use by default. Banned words and
+// breached passwords can be given in files of one per line.
+func setupPasswordPolicy(confPolicy ini.Section) common.PasswordPolicy {
+	get := func(key string) string {
+		return common.StripQuotes(confPolicy[key])
+	}
+
+	var p common.PasswordPolicy
+	for key, n := range map[string]*int{"minlength": &p.MinLength, "minclasses": &p.MinClasses} {
+		if v := get(key); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 0 {
+				log.WithField("setting", key).Error("Unable to parse password policy setting in config file.")
+				continue
+			}
+			*n = i
+		}
+	}
+
+	for _, w := range strings.Split(get("bannedwords"), ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			p.BannedWords = append(p.BannedWords, w)
+		}
+	}
+
+	readLines := func(path string, add func(string)) {
+		f, err := os.Open(path)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"file":  path,
+				"error": err.Error(),
+			}).Error("Unable to open password policy file.")
+			return
+		}
+		defer f.Close()
+
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if line := strings.TrimRight(s.Text(), "\r"); line != "" {
+				add(line)
+			}
+		}
+	}
+
+	if path := get("bannedwordsfile"); path != "" {
+		readLines(path, func(w string) { p.BannedWords = append(p.BannedWords, w) })
+	}
+	if path := get("breachlist"); path != "" {
+		p.Breached = map[string]bool{}
+		readLines(path, func(pw string) { p.Breached[pw] = true })
+	}
+
+	log.WithFields(log.Fields{
+		"minlength":   p.MinLength,
+		"minclasses":  p.MinClasses,
+		"bannedwords": len(p.BannedWords),
+		"breached":    len(p.Breached),
+	}).Debug("Password policy configured.")
+
+	return p
+}
+
+// Open the Pwned Passwords corpus cracked passwords are looked up in, either a
+// local copy or the range API. There are no lookups without a source.
+func setupPwned(confPwned ini.Section) pwned.Source {
+	source := common.StripQuotes(confPwned["source"])
+	if source == "" {
+		return nil
+	}
+
+	kind := strings.ToLower(common.StripQuotes(confPwned["hashes"]))
+	if kind == "" {
+		kind = pwned.SHA1
+	}
+
+	if source == "api" {
+		api, err := pwned.NewAPI(kind)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to set up the Pwned Passwords API.")
+			return nil
+		}
+		if u := common.StripQuotes(confPwned["url"]); u != "" {
+			api.URL = u
+		}
+
+		log.WithFields(log.Fields{
+			"url":    api.URL,
+			"hashes": kind,
+		}).Info("Pwned Passwords range API configured.")
+		return api
+	}
+
+	corpus, err := pwned.OpenCorpus(source, kind)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"file":  source,
+			"error": err.Error(),
+		}).Error("Unable to open the Pwned Passwords corpus.")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"file":   source,
+		"hashes": kind,
+	}).Info("Pwned Passwords corpus opened.")
+	return corpus
+}
+
+// Read how long the hashes and results of finished jobs are kept. Default sets
+// it for every job and any other key is a project name with its own expiry.
+func setupExpiry(confExp ini.Section) {
+	for key, v := range confExp {
+		d, err := time.ParseDuration(common.StripQuotes(v))
+		if err != nil || d < 0 {
+			log.WithField("setting", key).Error("Unable to parse job expiry setting in config file.")
+			continue
+		}
+
+		if key == "Default" {
+			queue.DefaultExpiry = d
+		} else {
+			queue.ProjectExpiry[key] = d
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"default":  queue.DefaultExpiry,
+		"projects": len(queue.ProjectExpiry),
+	}).Debug("Job expiry configured.")
+}
+
+// Read the budgets of projects for resources with a cost. Default sets it for
+// projects that are not listed and any other key is a project name.
+func setupBudgets(confBud ini.Section) {
+	for key, v := range confBud {
+		budget, err := strconv.ParseFloat(common.StripQuotes(v), 64)
+		if err != nil || budget < 0 {
+			log.WithField("setting", key).Error("Unable to parse project budget in config file.")
+			continue
+		}
+
+		if key == "Default" {
+			queue.DefaultBudget = budget
+		} else {
+			queue.ProjectBudgets[key] = budget
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"default":  queue.DefaultBudget,
+		"projects": len(queue.ProjectBudgets),
+	}).Debug("Project budgets configured.")
+}
+
+// Read which jobs are always sensitive and need a second Administrator to
+// approve them, by the hash modes they crack and the projects they are for
+func setupApproval(confApp ini.Section) {
+	for _, mode := range splitList(common.StripQuotes(confApp["algorithms"])) {
+		queue.ApprovalAlgorithms[mode] = true
+	}
+	for _, project := range splitList(common.StripQuotes(confApp["projects"])) {
+		queue.ApprovalProjects[project] = true
+	}
+
+	log.WithFields(log.Fields{
+		"algorithms": len(queue.ApprovalAlgorithms),
+		"projects":   len(queue.ApprovalProjects),
+	}).Debug("Job approval configured.")
+}
+
+// Read the template and methodology notes of engagement reports. The built in
+// template is used when there is none or it cannot be read.
+func setupReports(confReports ini.Section) report.Generator {
+	tmpl := common.StripQuotes(confReports["template"])
+	notes := common.StripQuotes(confReports["methodology"])
+
+	gen, err := report.NewGenerator(tmpl, notes)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"template":    tmpl,
+			"methodology": notes,
+			"error":       err.Error(),
+		}).Error("Unable to load the engagement report settings, using the built in report.")
+
+		gen = report.Generator{}
+		gen.Template, _ = report.ParseTemplate("")
+		return gen
+	}
+
+	log.WithFields(log.Fields{
+		"template": tmpl,
+		"notes":    len(gen.Notes),
+	}).Debug("Engagement reports configured.")
+
+	return gen
+}
+
+// Load the key evidence bundles of jobs are signed with, nil when none is
+// configured so they cannot be exported
+func setupEvidence(confEvidence ini.Section) ed25519.PrivateKey {
+	path := common.StripQuotes(confEvidence["signingkey"])
+	if path == "" {
+		return nil
+	}
+
+	data, err := common.ReadSecretFile(path)
+	if err == nil {
+		var key ed25519.PrivateKey
+		key, err = evidence.ParseSigningKey(data)
+		if err == nil {
+			_, id, _ := evidence.PublicKey(key)
+			log.WithField("key", id).Info("Evidence bundles will be signed.")
+			return key
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"path":  path,
+		"error": err.Error(),
+	}).Error("Unable to load the evidence signing key, evidence bundles cannot be exported.")
+	return nil
+}
+
+// Read the output retention settings. MaxOutputRows and MaxPerformancePoints
+// set the default, PerformanceTiers sets how performance data is averaged
+// and any other key is a tool name set to "rows,points".
+func setupRetention(confRet ini.Section) {
+	parse := func(key, v string) (int, bool) {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			log.WithField("setting", key).Error("Unable to parse output retention setting in config file.")
+			return 0, false
+		}
+		return n, true
+	}
+
+	for key, v := range confRet {
+		v = common.StripQuotes(v)
+
+		switch key {
+		case "MaxOutputRows":
+			if n, ok := parse(key, v); ok {
+				queue.DefaultRetention.MaxOutputRows = n
+			}
+		case "MaxPerformancePoints":
+			if n, ok := parse(key, v); ok {
+				queue.DefaultRetention.MaxPerformancePoints = n
+			}
+		case "PerformanceTiers":
+			tiers, err := parseTiers(v)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"setting": key,
+					"error":   err.Error(),
+				}).Error("Unable to parse output retention setting in config file.")
+				continue
+			}
+			queue.PerformanceTiers = tiers
+		default:
+			parts := strings.Split(v, ",")
+			if len(parts) != 2 {
+				log.WithField("setting", key).Error("Tool output retention must be set to rows,points in config file.")
+				continue
+			}
+
+			rows, ok := parse(key, parts[0])
+			if !ok {
+				continue
+			}
+			points, ok := parse(key, parts[1])
+			if !ok {
+				continue
+			}
+
+			queue.ToolRetention[key] = queue.Retention{
+				MaxOutputRows:        rows,
+				MaxPerformancePoints: points,
+			}
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"maxoutputrows":        queue.DefaultRetention.MaxOutputRows,
+		"maxperformancepoints": queue.DefaultRetention.MaxPerformancePoints,
+		"tools":                len(queue.ToolRetention),
+	}).Debug("Output retention configured.")
+}
+
+// Build the gzip middleware from the compression settings, nil if responses
+// should not be compressed
+func setupCompression(genConf ini.Section) negroni.Handler {
+	if c := strings.ToLower(common.StripQuotes(genConf["Compression"])); c == "false" || c == "off" {
+		log.Info("Response compression is disabled.")
+		return nil
+	}
+
+	level := gzip.DefaultCompression
+	if v := common.StripQuotes(genConf["CompressionLevel"]); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil {
+			log.WithField("setting", "CompressionLevel").Error("Unable to parse compression setting in config file.")
+		} else {
+			level = l
+		}
+	}
+
+	minSize := 1024
+	if v := common.StripQuotes(genConf["CompressionMinSize"]); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.WithField("setting", "CompressionMinSize").Error("Unable to parse compression setting in config file.")
+		} else {
+			minSize = n
+		}
+	}
+
+	gz, err := NewGzipMiddleware(level, minSize)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"level": level,
+		}).Error("Unable to setup response compression.")
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"level":   level,
+		"minsize": minSize,
+	}).Debug("Response compression configured.")
+
+	return gz
+}
+
+// Build the authenticator of the given type from the Authentication section of
+// the configuration file
+func setupAuthenticator(authtype string, confAuth ini.Section) Authenticator {
+	switch authtype {
+	case "INI":
+		var i INIAuth
+
+		// Get the users
+		umap := map[string]string{}
+
+		au := common.StripQuotes(confAuth["adminuser"])
+		if au == "" {
+			log.Fatal("An administrative user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		ap := common.StripQuotes(confAuth["adminpass"])
+		if ap == "" {
+			log.Fatal("An administrative password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+
+		su := common.StripQuotes(confAuth["standarduser"])
+		if su == "" {
+			log.Fatal("An standard user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		sp := common.StripQuotes(confAuth["standardpass"])
+		if sp == "" {
+			log.Fatal("An standard password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+
+		ru := common.StripQuotes(confAuth["readonlyuser"])
+		if ru == "" {
+			log.Fatal("An read only user was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		rp := common.StripQuotes(confAuth["readonlypass"])
+		if rp == "" {
+			log.Fatal("An read only password was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+
+		umap[au] = ap
+		umap[su] = sp
+		umap[ru] = rp
+
+		// Setup group mappings
+		gmap := map[string]string{}
+
+		gmap[au] = Administrator
+		gmap[su] = StandardUser
+		gmap[ru] = ReadOnly
+
+		i.Setup(umap, gmap)
+
+		// Users that must change their password on their next login
+		fpc := common.StripQuotes(confAuth["forcepasswordchange"])
+		if fpc != "" {
+			var users []string
+			for _, u := range strings.Split(fpc, ",") {
+				users = append(users, strings.TrimSpace(u))
+			}
+			i.SetForcePasswordChange(users)
+		}
+
+		log.Info("INI authentication setup complete.")
+
+		return &i
+	case "ActiveDirectory":
+		var ad ADAuth
+
+		realm := common.StripQuotes(confAuth["realm"])
+		if realm == "" {
+			log.Fatal("No Active Directory realm was configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		ad.SetRealm(realm)
+
+		gmap := map[string]string{}
+		ro := common.StripQuotes(confAuth["ReadOnlyGroup"])
+		if ro == "" {
+			log.Fatal("A read only group was not provided. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		st := common.StripQuotes(confAuth["StandardGroup"])
+		if st == "" {
+			log.Fatal("A group for standard access was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+		admin := common.StripQuotes(confAuth["AdminGroup"])
+		if admin == "" {
+			log.Fatal("A group for read only access was not configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+		}
+
+		gmap[ReadOnly] = ro
+		gmap[StandardUser] = st
+		gmap[Administrator] = admin
+
+		ad.Setup(gmap)
+
+		log.WithFields(log.Fields{
+			"readonly": ro,
+			"standard": st,
+			"admin":    admin,
+		}).Info("Active directory authentication configured successfully.")
+
+		return &ad
+	}
+
+	log.WithField("type", authtype).Fatal("An unknown authentication type was configured. See https://github.com/jmmcatee/cracklord/src/wiki/Configuration-Files#queue-auth")
+	return nil
+}
+
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/notify"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/pborman/uuid"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	QUICK_RUNNING   = "running"
+	QUICK_CRACKED   = "cracked"
+	QUICK_EXHAUSTED = "exhausted" // Every job of the pipeline finished without cracking the hash
+)
+
+// How often the jobs of quick cracks are checked for the hash being cracked
+var QuickCheckInterval = 5 * time.Second
+
+// How long finished quick cracks are kept to be read
+var QuickKeep = 24 * time.Hour
+
+// A single hash run through the quick pipeline
+type QuickCrack struct {
+	ID        string
+	Owner     string
+	Hash      string
+	Mode      string
+	ModeName  string
+	Jobs      []string // One job for each wordlist of the pipeline
+	Status    string
+	Plaintext string
+	Created   time.Time
+	Finished  time.Time
+
+	webhook *notify.Webhook
+}
+
+/*
+ * Runs single hashes, such as one from a CTF, through a canned pipeline of the
+ * top wordlists with a fast rule set so nobody has to fill in a job form for
+ * one hash. Each wordlist is a job and the rest are quit as soon as one of
+ * them cracks the hash, then the owner is told through a webhook they gave
+ * and by mail when notifications are set up. Quick cracks are only kept in
+ * memory, their jobs are ordinary jobs of the queue.
+ */
+type QuickCracker struct {
+	Q            *queue.Queue
+	Tool         string   // Name of the tool the jobs use
+	Dictionaries []string // Dictionaries of the tool tried in order
+	Rules        string   // Rules used with every dictionary
+	Queue        string   // Named queue the jobs go in, so they can be given their own resources
+	D            *Digester
+
+	cracks map[string]*QuickCrack
+	sync.Mutex
+}
+
+func NewQuickCracker(q *queue.Queue, tool string, dicts []string, rules, queueName string, d *Digester) *QuickCracker {
+	return &QuickCracker{
+		Q:            q,
+		Tool:         tool,
+		Dictionaries: dicts,
+		Rules:        rules,
+		Queue:        queueName,
+		D:            d,
+		cracks:       map[string]*QuickCrack{},
+	}
+}
+
+func (c *QuickCracker) Start() {
+	go func() {
+		for {
+			time.Sleep(QuickCheckInterval)
+			c.check()
+		}
+	}()
+}
+
+// The UUID of the newest version of the tool that can take jobs
+func (c *QuickCracker) toolUUID() (string, error) {
+	var found []common.Tool
+	for _, t := range c.Q.ActiveTools() {
+		if strings.EqualFold(t.Name, c.Tool) {
+			found = append(found, t)
+		}
+	}
+	if len(found) == 0 {
+		return "", errors.New("No resource provides " + c.Tool + ".")
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Version > found[j].Version })
+	return found[0].UUID, nil
+}
+
+// Queue the pipeline for a hash
+func (c *QuickCracker) Submit(owner, hash string, mode common.HashMode, hook *notify.Webhook) (QuickCrack, error) {
+	tool, err := c.toolUUID()
+	if err != nil {
+		return QuickCrack{}, err
+	}
+
+	qc := &QuickCrack{
+		ID:       uuid.New(),
+		Owner:    owner,
+		Hash:     hash,
+		Mode:     mode.Mode,
+		ModeName: mode.Name,
+		Status:   QUICK_RUNNING,
+		Created:  time.Now(),
+		webhook:  hook,
+	}
+
+	short := hash
+	if len(short) > 16 {
+		short = short[:16] + "..."
+	}
+
+	var jobs []common.Job
+	for _, dict := range c.Dictionaries {
+		params := map[string]string{
+			"algorithm":         mode.Mode,
+			"hashes":            hash,
+			"dict_dictionaries": dict,
+		}
+		if c.Rules != "" {
+			params["dict_rules"] = c.Rules
+		}
+
+		j := common.NewJob(tool, fmt.Sprintf("Quick crack %s (%s)", short, dict), owner, params)
+		j.Queue = c.Queue
+		jobs = append(jobs, j)
+		qc.Jobs = append(qc.Jobs, j.UUID)
+	}
+
+	if _, err := c.Q.AddJobs(jobs); err != nil {
+		return QuickCrack{}, err
+	}
+
+	c.Lock()
+	c.cracks[qc.ID] = qc
+	c.Unlock()
+
+	log.WithFields(log.Fields{
+		"id":    qc.ID,
+		"owner": owner,
+		"mode":  mode.Mode,
+		"jobs":  len(jobs),
+	}).Info("Quick crack submitted.")
+
+	return *qc, nil
+}
+
+// Get a copy of a quick crack by ID
+func (c *QuickCracker) Get(id string) (QuickCrack, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	qc, ok := c.cracks[id]
+	if !ok {
+		return QuickCrack{}, false
+	}
+	return *qc, true
+}
+
+// A job that cracked the hash of a quick crack is kept until the quick crack is
+// checked, as the plaintext is read from its output. Other jobs of a quick
+// crack can always be deleted, the rest of its pipeline still tries the hash.
+func (c *QuickCracker) JobInUse(job common.Job) string {
+	if job.CrackedHashes == 0 {
+		return ""
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	for _, qc := range c.cracks {
+		if qc.Status != QUICK_RUNNING {
+			continue
+		}
+		for _, id := range qc.Jobs {
+			if id == job.UUID {
+				return "it cracked the hash of quick crack " + qc.ID + ", which has not read the plaintext yet"
+			}
+		}
+	}
+	return ""
+}
+
+// Drop a deleted job from the quick crack it was part of, a quick crack left
+// without jobs is exhausted the next time it is checked
+func (c *QuickCracker) JobDeleted(jobuuid string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, qc := range c.cracks {
+		for i, id := range qc.Jobs {
+			if id == jobuuid {
+				qc.Jobs = append(qc.Jobs[:i:i], qc.Jobs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Look for quick cracks that were cracked or ran out of wordlists
+func (c *QuickCracker) check() {
+	c.Lock()
+	var running []QuickCrack
+	for id, qc := range c.cracks {
+		if qc.Status == QUICK_RUNNING {
+			running = append(running, *qc)
+		} else if time.Since(qc.Finished) > QuickKeep {
+			delete(c.cracks, id)
+		}
+	}
+	c.Unlock()
+
+	for _, qc := range running {
+		status, plaintext := c.progress(qc)
+		if status == QUICK_RUNNING {
+			continue
+		}
+
+		// The rest of the pipeline is not needed once the hash is cracked
+		if status == QUICK_CRACKED {
+			for _, id := range qc.Jobs {
+				if j, err := c.Q.JobInfo(id); err == nil && !common.IsDone(j.Status) {
+					c.Q.QuitJob(id)
+				}
+			}
+		}
+
+		c.Lock()
+		if stored, ok := c.cracks[qc.ID]; ok {
+			stored.Status = status
+			stored.Plaintext = plaintext
+			stored.Finished = time.Now()
+			qc = *stored
+		}
+		c.Unlock()
+
+		log.WithFields(log.Fields{
+			"id":     qc.ID,
+			"owner":  qc.Owner,
+			"status": status,
+		}).Info("Quick crack finished.")
+
+		c.notify(qc)
+	}
+}
+
+// Check the jobs of a quick crack, the plaintext is returned once one of them
+// cracks the hash
+func (c *QuickCracker) progress(qc QuickCrack) (string, string) {
+	finished := 0
+	for _, id := range qc.Jobs {
+		j, err := c.Q.JobInfo(id)
+		if err != nil {
+			// Deleted jobs will not crack it
+			finished++
+			continue
+		}
+
+		if j.CrackedHashes > 0 {
+			if out, err := c.Q.JobOutput(id); err == nil {
+				for _, pw := range out.Plaintexts() {
+					return QUICK_CRACKED, pw
+				}
+			}
+		}
+		if common.IsDone(j.Status) {
+			finished++
+		}
+	}
+
+	if finished == len(qc.Jobs) {
+		return QUICK_EXHAUSTED, ""
+	}
+	return QUICK_RUNNING, ""
+}
+
+// The event posted to webhooks when a quick crack finishes
+type quickEvent struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Hash   string `json:"hash"`
+	Mode   string `json:"mode"`
+}
+
+// Tell the owner how a quick crack finished. The plaintext is left out so it
+// is only read through the API.
+func (c *QuickCracker) notify(qc QuickCrack) {
+	logger := log.WithFields(log.Fields{
+		"id":    qc.ID,
+		"owner": qc.Owner,
+	})
+
+	if qc.webhook != nil {
+		err := qc.webhook.Send(quickEvent{
+			ID:     qc.ID,
+			Status: qc.Status,
+			Hash:   qc.Hash,
+			Mode:   qc.Mode,
+		})
+		if err != nil {
+			logger.WithField("error", err.Error()).Error("Unable to post quick crack webhook.")
+		}
+	}
+
+	if c.D == nil {
+		return
+	}
+	to := c.D.Prefs(qc.Owner).Email
+	if to == "" {
+		return
+	}
+
+	subject := "CrackLord quick crack: no match"
+	body := fmt.Sprintf("The quick pipeline finished without cracking %s (%s).\n", qc.Hash, qc.ModeName)
+	if qc.Status == QUICK_CRACKED {
+		subject = "CrackLord quick crack: cracked"
+		body = fmt.Sprintf("%s (%s) was cracked, quick crack %s has the plaintext.\n", qc.Hash, qc.ModeName, qc.ID)
+	}
+
+	if err := c.D.Mail.Send([]string{to}, subject, body); err != nil {
+		logger.WithField("error", err.Error()).Error("Unable to mail quick crack result.")
+	}
+}
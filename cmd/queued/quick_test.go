@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/jmmcatee/cracklord/common"
+	"testing"
+	"time"
+)
+
+func TestQuickCrackKeepsCrackingJob(t *testing.T) {
+	a, token := testController(t)
+	c := NewQuickCracker(&a.Q, "tool", nil, "", "", nil)
+	a.Q.AddJobReferrer(c)
+
+	cracked := common.NewJob("tool", "Quick crack", "alice", map[string]string{"hashes": "aaaa"})
+	cracked.CrackedHashes = 1
+	testDraftJob(t, a, cracked)
+	other := testDraft(t, a, map[string]string{"hashes": "aaaa"})
+	c.cracks["qc"] = &QuickCrack{ID: "qc", Owner: "alice", Status: QUICK_RUNNING, Jobs: []string{cracked.UUID, other.UUID}, Created: time.Now()}
+
+	if reason := c.JobInUse(cracked); reason == "" {
+		t.Error("Job that cracked the hash can be deleted before the quick crack read it")
+	}
+	if reason := c.JobInUse(other); reason != "" {
+		t.Errorf("Job that did not crack the hash is in use: %s", reason)
+	}
+
+	if rw := apiRequest(a, "DELETE", "/api/jobs/"+cracked.UUID, token, nil); rw.Code != RESP_CODE_CONFLICT {
+		t.Errorf("Deleting the job that cracked the hash gave %d: %s", rw.Code, rw.Body.String())
+	}
+
+	// The rest of the pipeline can be deleted and is dropped from the quick
+	// crack
+	if rw := apiRequest(a, "DELETE", "/api/jobs/"+other.UUID, token, nil); rw.Code != RESP_CODE_OK {
+		t.Fatalf("Deleting a job of the pipeline gave %d: %s", rw.Code, rw.Body.String())
+	}
+	if qc, _ := c.Get("qc"); len(qc.Jobs) != 1 || qc.Jobs[0] != cracked.UUID {
+		t.Errorf("Deleted job was not dropped from the quick crack: %v", qc.Jobs)
+	}
+
+	// Once the quick crack has the plaintext the job is no longer needed
+	c.cracks["qc"].Status = QUICK_CRACKED
+	if reason := c.JobInUse(cracked); reason != "" {
+		t.Errorf("Job of a finished quick crack is in use: %s", reason)
+	}
+}
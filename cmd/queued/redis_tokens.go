@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/redis"
+	"time"
+)
+
+/*
+ * Tokens kept in Redis so sessions survive restarts of the queue server and
+ * are shared by every queue server using the same Redis. The hash of each
+ * token is a key holding the user that expires with the session, and the
+ * hashes of each user are kept in a set so all of their sessions can be found.
+ */
+type RedisTokenStore struct {
+	R      *redis.Client
+	Prefix string // Added to every key so one Redis can be shared
+}
+
+func NewRedisTokenStore(c *redis.Client, prefix string) *RedisTokenStore {
+	if prefix == "" {
+		prefix = "cracklord:"
+	}
+
+	return &RedisTokenStore{R: c, Prefix: prefix}
+}
+
+func (t *RedisTokenStore) tokenKey(token string) string {
+	return t.hashKey(hashToken(token))
+}
+
+func (t *RedisTokenStore) hashKey(hash string) string {
+	return t.Prefix + "token:" + hash
+}
+
+func (t *RedisTokenStore) userKey(username string) string {
+	return t.Prefix + "user:" + username
+}
+
+func (t *RedisTokenStore) AddToken(token string, user User) error {
+	user.Timeout = time.Now().Add(SessionExpiration)
+	user.SessionID = sessionID(hashToken(token))
+
+	b, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	err = t.R.Set(t.tokenKey(token), string(b), SessionExpiration)
+	if err != nil {
+		return err
+	}
+
+	// Sets of users with no sessions left are cleaned up when the password
+	// of the user changes
+	if err := t.R.SAdd(t.userKey(user.Username), hashToken(token)); err != nil {
+		log.WithFields(log.Fields{
+			"user":  user.Username,
+			"error": err.Error(),
+		}).Warn("Unable to add token to the sessions of the user.")
+	}
+
+	log.WithField("user", user.Username).Debug("Token added to user store.")
+
+	return nil
+}
+
+func (t *RedisTokenStore) RemoveToken(token string) {
+	user, err := t.GetUser(token)
+	if err == nil {
+		t.R.SRem(t.userKey(user.Username), hashToken(token))
+	}
+
+	if err := t.R.Del(t.tokenKey(token)); err != nil {
+		log.WithField("error", err.Error()).Error("Unable to remove token from Redis.")
+	}
+}
+
+// Tokens are refused when Redis can not be reached so a session is never
+// used after it was removed
+func (t *RedisTokenStore) CheckToken(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	ok, err := t.R.Expire(t.tokenKey(token), SessionExpiration)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to check token in Redis.")
+		return false
+	}
+
+	return ok
+}
+
+func (t *RedisTokenStore) PasswordChanged(username string) {
+	hashes, err := t.R.SMembers(t.userKey(username))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user":  username,
+			"error": err.Error(),
+		}).Error("Unable to read the sessions of the user from Redis.")
+		return
+	}
+
+	for _, hash := range hashes {
+		user, err := t.getUser(t.hashKey(hash))
+		if err != nil {
+			t.R.SRem(t.userKey(username), hash)
+			continue
+		}
+
+		ttl, err := t.R.TTL(t.hashKey(hash))
+		if err != nil || ttl <= 0 {
+			continue
+		}
+
+		user.MustChangePassword = false
+		b, _ := json.Marshal(user)
+		t.R.Set(t.hashKey(hash), string(b), ttl)
+	}
+}
+
+func (t *RedisTokenStore) Sessions(username string) []User {
+	hashes, err := t.R.SMembers(t.userKey(username))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"user":  username,
+			"error": err.Error(),
+		}).Error("Unable to read the sessions of the user from Redis.")
+		return []User{}
+	}
+
+	users := []User{}
+	for _, hash := range hashes {
+		user, err := t.getUser(t.hashKey(hash))
+		if err != nil {
+			t.R.SRem(t.userKey(username), hash)
+			continue
+		}
+
+		// The expiry is renewed in Redis and not in the stored user
+		if ttl, err := t.R.TTL(t.hashKey(hash)); err == nil {
+			user.Timeout = time.Now().Add(ttl)
+		}
+		users = append(users, user)
+	}
+
+	return users
+}
+
+func (t *RedisTokenStore) RevokeSession(username, id string) bool {
+	hashes, err := t.R.SMembers(t.userKey(username))
+	if err != nil {
+		return false
+	}
+
+	for _, hash := range hashes {
+		if sessionID(hash) != id {
+			continue
+		}
+
+		t.R.SRem(t.userKey(username), hash)
+		if err := t.R.Del(t.hashKey(hash)); err != nil {
+			log.WithField("error", err.Error()).Error("Unable to remove token from Redis.")
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+func (t *RedisTokenStore) GetUser(token string) (User, error) {
+	return t.getUser(t.tokenKey(token))
+}
+
+func (t *RedisTokenStore) getUser(key string) (User, error) {
+	v, err := t.R.Get(key)
+	if err != nil {
+		if err != redis.ErrNil {
+			log.WithField("error", err.Error()).Error("Unable to read token from Redis.")
+		}
+		return User{}, errors.New("Invalid Token")
+	}
+
+	var user User
+	if err := json.Unmarshal([]byte(v), &user); err != nil {
+		return User{}, errors.New("Invalid Token")
+	}
+
+	return user, nil
+}
+
+// Report Redis as unreachable in the readiness check
+func (t *RedisTokenStore) Healthy() error {
+	return t.R.Ping()
+}
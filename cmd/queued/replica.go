@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+type replicaKey struct{}
+
+// Serve a heavy read endpoint, such as reports, statistics and analysis of
+// results, from a worker of the read replica so it does not hold the queue
+// lock. Requests wait for a free worker. While the replica is behind by more
+// than its maximum lag they are read from the queue instead.
+func (a *AppController) offload(h http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if a.Replica == nil {
+			h(rw, r)
+			return
+		}
+
+		defer a.Replica.Acquire()()
+
+		if err := a.Replica.Refresh(); err != nil {
+			log.WithFields(log.Fields{
+				"path":  r.URL.Path,
+				"error": err.Error(),
+			}).Warn("Unable to use the read replica, reading from the queue.")
+			h(rw, r)
+			return
+		}
+
+		h(rw, r.WithContext(context.WithValue(r.Context(), replicaKey{}, true)))
+	}
+}
+
+// Get what a request reads jobs and statistics from, the replica for requests
+// offloaded to it
+func (a *AppController) reader(r *http.Request) queue.Reader {
+	if replica, _ := r.Context().Value(replicaKey{}).(bool); replica && a.Replica != nil {
+		return a.Replica
+	}
+	return &a.Q
+}
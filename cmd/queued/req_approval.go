@@ -0,0 +1,101 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// The user a job is submitted by, the Administrator when they are
+// impersonating its owner so they cannot approve it themselves
+func submittedBy(user User) string {
+	if user.ImpersonatedBy != "" {
+		return user.ImpersonatedBy
+	}
+	return user.Username
+}
+
+// Let a sensitive job waiting for approval be dispatched. Only an
+// Administrator other than the one that submitted or owns the job may do this
+// and they cannot do it while impersonating another user.
+// (POST - /api/jobs/{id}/approve)
+func (a *AppController) ApproveJob(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobUpdateResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to approve a job.")
+		return
+	}
+
+	// Check for administrator level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) || r.Header.Get(ImpersonateHeader) != "" {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to approve a job.")
+		return
+	}
+
+	jobid := mux.Vars(r)["id"]
+
+	j, err := a.Q.ApproveJob(jobid, user.Username)
+	switch err {
+	case nil:
+	case queue.ErrJobNotFound:
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	case queue.ErrApprovalSelf:
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_APPROVAL_DENIED)
+
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"uuid": jobid,
+			"user": user.Username,
+		}).Warn("An Administrator attempted to approve a sensitive job they submitted.")
+
+		return
+	default:
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_APPROVAL_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Job = newAPIJob(j)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":  j.UUID,
+		"owner": j.Owner,
+		"user":  user.Username,
+	}).Info("Sensitive job approved.")
+}
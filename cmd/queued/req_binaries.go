@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Fill a response with the builds in the binary repository
+func listBinaries(resp *BinaryListResp) {
+	builds, current := queue.Binaries.Builds()
+
+	resp.Builds = []APIToolBinary{}
+	for _, b := range builds {
+		resp.Builds = append(resp.Builds, APIToolBinary{
+			Tool:    b.Tool,
+			Version: b.Version,
+			OS:      b.OS,
+			Arch:    b.Arch,
+			SHA256:  b.SHA256,
+			Size:    b.Size,
+		})
+	}
+	resp.Current = current
+}
+
+// List the tool binaries in the repository (GET - /api/binaries)
+func (a *AppController) ListBinaries(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp BinaryListResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to list tool binaries.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to list tool binaries.")
+
+		return
+	}
+
+	if queue.Binaries == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARIES_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	listBinaries(&resp)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Upload a signed build of a tool for a platform (POST - /api/binaries)
+func (a *AppController) UploadBinary(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req BinaryUploadReq
+	var resp BinaryListResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxUpdateRequestSize))
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to upload a tool binary.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to upload a tool binary.")
+
+		return
+	}
+
+	if queue.Binaries == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARIES_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a tool binary upload.")
+
+		return
+	}
+
+	build := common.ToolBinary{
+		Tool:      req.Tool,
+		Version:   req.Version,
+		OS:        req.OS,
+		Arch:      req.Arch,
+		Signature: req.Signature,
+	}
+	if err := build.Validate(); err != nil || len(req.Binary) == 0 || len(req.Signature) == 0 {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UPDATE_BINARYREQUIRED)
+		if err != nil {
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_INVALID, err.Error())
+		}
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	build, err = queue.Binaries.Add(build, req.Binary)
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithField("error", err.Error()).Error("Unable to store tool binary.")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"tool":     build.Tool,
+		"version":  build.Version,
+		"platform": build.OS + "/" + build.Arch,
+		"sha256":   build.SHA256,
+	}).Info("Tool binary uploaded.")
+
+	listBinaries(&resp)
+
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+}
+
+// Set the version of a tool every resource is kept at (PUT - /api/binaries/{tool})
+func (a *AppController) SetCurrentBinary(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req BinaryCurrentReq
+	var resp BinaryListResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to set a tool binary version.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to set a tool binary version.")
+
+		return
+	}
+
+	if queue.Binaries == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARIES_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a tool binary version.")
+
+		return
+	}
+
+	tool := mux.Vars(r)["tool"]
+	err = queue.Binaries.SetCurrent(tool, req.Version)
+	if err == queue.ErrBinaryNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"tool":     tool,
+		"version":  req.Version,
+	}).Info("Tool binary version set.")
+
+	listBinaries(&resp)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Delete a build from the repository (DELETE - /api/binaries/{tool}/{version}/{os}/{arch})
+func (a *AppController) DeleteBinary(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp BinaryListResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to delete a tool binary.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to delete a tool binary.")
+
+		return
+	}
+
+	if queue.Binaries == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARIES_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	vars := mux.Vars(r)
+	build := common.ToolBinary{
+		Tool:    vars["tool"],
+		Version: vars["version"],
+		OS:      vars["os"],
+		Arch:    vars["arch"],
+	}
+
+	err := queue.Binaries.Delete(build)
+	if err == queue.ErrBinaryNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrBinaryCurrent {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BINARY_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithField("error", err.Error()).Error("Unable to delete tool binary.")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"tool":     build.Tool,
+		"version":  build.Version,
+		"platform": build.OS + "/" + build.Arch,
+	}).Info("Tool binary deleted.")
+
+	listBinaries(&resp)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
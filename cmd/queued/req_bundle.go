@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Export the state of the queue as a bundle to move it to another queue
+// server or stage a standby server (GET - /api/queue/export)
+func (a *AppController) ExportQueue(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ErrorResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to export the queue.")
+		return
+	}
+
+	// The bundle holds the hashes and results of every job
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to export the queue.")
+		return
+	}
+
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	b := a.Q.Export(by)
+
+	name := "cracklord-queue-" + b.Exported.Format("20060102-150405") + ".json"
+	h := rw.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	rw.WriteHeader(RESP_CODE_OK)
+	json.NewEncoder(rw).Encode(b)
+
+	log.WithFields(log.Fields{
+		"jobs":      len(b.Stack),
+		"resources": len(b.Pool),
+		"user":      by,
+	}).Info("Queue exported.")
+}
+
+// Import a bundle exported by another queue server, adding what this queue
+// does not have (POST - /api/queue/import)
+func (a *AppController) ImportQueue(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var b queue.Bundle
+	var resp QueueImportResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to import a queue bundle.")
+		return
+	}
+
+	// Only Administrators can add jobs and resources of another server
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to import a queue bundle.")
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&b)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	sum, err := a.Q.Import(b, by)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_IMPORT_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Imported = APIQueueImport{
+		Jobs:             sum.Jobs,
+		JobsSkipped:      sum.JobsSkipped,
+		Resources:        sum.Resources,
+		ResourcesSkipped: sum.ResourcesSkipped,
+		Defaults:         sum.Defaults,
+		Queues:           sum.Queues,
+		Reservations:     sum.Reservations,
+	}
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
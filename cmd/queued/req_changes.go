@@ -0,0 +1,96 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"net/http"
+	"strconv"
+)
+
+// Build the listing structure of a job
+func newAPIJob(j common.Job) APIJob {
+	api := APIJob{
+		ID:            j.UUID,
+		Name:          j.Name,
+		Status:        j.Status,
+		ResourceID:    j.ResAssigned,
+		Owner:         j.Owner,
+		StartTime:     j.StartTime,
+		ETC:           j.ETC,
+		CrackedHashes: j.CrackedHashes,
+		TotalHashes:   j.TotalHashes,
+		Progress:      j.Progress,
+		ToolID:        j.ToolUUID,
+		Project:       j.Project,
+		Queue:         j.Queue,
+		Cost:          j.Cost,
+	}
+	if !j.Stalled.IsZero() {
+		stalled := j.Stalled
+		api.Stalled = &stalled
+	}
+	return api
+}
+
+// Get the jobs changed since a cursor (GET - /api/jobs/changes?since=<cursor>)
+func (a *AppController) GetJobChanges(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobChangesResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to get job changes")
+		return
+	}
+
+	// Without a cursor every job is returned
+	var since uint64
+	if s := r.URL.Query().Get("since"); s != "" {
+		var err error
+		since, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_CURSOR_INVALID)
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+	}
+
+	changes := a.Q.JobChanges(since)
+
+	resp.Cursor = changes.Cursor
+	resp.Reset = changes.Reset
+	resp.Jobs = []APIJob{}
+	for _, j := range changes.Jobs {
+		resp.Jobs = append(resp.Jobs, newAPIJob(j))
+	}
+	resp.Removed = []string{}
+	if changes.Removed != nil {
+		resp.Removed = changes.Removed
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"since":   since,
+		"cursor":  changes.Cursor,
+		"changed": len(changes.Jobs),
+		"removed": len(changes.Removed),
+	}).Debug("Job changes provided to API.")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Continue a quit or failed job from its last checkpoint (POST - /api/jobs/{id}/restore)
+func (a *AppController) RestoreJob(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobUpdateResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to restore a job.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to restore a job.")
+
+		return
+	}
+
+	// Administrators may restore any job, so check before any impersonation
+	admin := user.Allowed(Administrator)
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "restore a job")
+	if !ok {
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	j, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	if !admin && j.Owner != user.Username {
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESTORE_DENIED)
+
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"uuid":  j.UUID,
+			"user":  user.Username,
+			"owner": j.Owner,
+		}).Warn("A user attempted to restore a job they do not own.")
+
+		return
+	}
+
+	// Record who really made the change when impersonating
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	j, err = a.Q.RestoreJob(jobid, by)
+	if err != nil {
+		code := RESP_CODE_BADREQ
+		if err == queue.ErrJobNotFound {
+			code = RESP_CODE_NOTFOUND
+		}
+
+		resp.Status = code
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESTORE_FAILED, err.Error())
+
+		rw.WriteHeader(code)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Job.ID = j.UUID
+	resp.Job.Name = j.Name
+	resp.Job.Status = j.Status
+	resp.Job.ResourceID = j.ResAssigned
+	resp.Job.Owner = j.Owner
+	resp.Job.StartTime = j.StartTime
+	resp.Job.ETC = j.ETC
+	resp.Job.CrackedHashes = j.CrackedHashes
+	resp.Job.TotalHashes = j.TotalHashes
+	resp.Job.Progress = j.Progress
+	resp.Job.ToolID = j.ToolUUID
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":           j.UUID,
+		"name":           j.Name,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+	}).Info("Job restored from checkpoint.")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"sort"
+)
+
+// List the budgets of projects and what they have spent on resources with a
+// cost per hour (GET - /api/budgets)
+func (a *AppController) GetBudgets(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp BudgetsResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to list project budgets.")
+		return
+	}
+
+	// Users can see why their jobs are waiting
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to list project budgets.")
+		return
+	}
+
+	// Projects with a budget or spending, which spend without a limit if
+	// they have none
+	spend := a.Q.ProjectSpend()
+	projects := []string{}
+	for project := range spend {
+		projects = append(projects, project)
+	}
+	for project := range queue.ProjectBudgets {
+		if _, ok := spend[project]; !ok {
+			projects = append(projects, project)
+		}
+	}
+	sort.Strings(projects)
+
+	resp.Budgets = []APIBudget{}
+	for _, project := range projects {
+		budget, ok := queue.ProjectBudgets[project]
+		if !ok {
+			budget = queue.DefaultBudget
+		}
+		resp.Budgets = append(resp.Budgets, APIBudget{
+			Project: project,
+			Budget:  budget,
+			Spent:   spend[project],
+		})
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Action = queue.BudgetAction
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Let a job held back for going over the budget of its project run on
+// resources with a cost, only the owner or an Administrator may do this
+// (POST - /api/jobs/{id}/cost)
+func (a *AppController) ApproveJobCost(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobUpdateResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to approve the cost of a job.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to approve the cost of a job.")
+		return
+	}
+
+	// Administrators may approve any job, so check before any impersonation
+	admin := user.Allowed(Administrator)
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "approve the cost of a job")
+	if !ok {
+		return
+	}
+
+	jobid := mux.Vars(r)["id"]
+
+	j, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	if !admin && j.Owner != user.Username {
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_COST_DENIED)
+
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"uuid":  j.UUID,
+			"user":  user.Username,
+			"owner": j.Owner,
+		}).Warn("A user attempted to approve the cost of a job they do not own.")
+
+		return
+	}
+
+	// Record who really made the change when impersonating
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	j, err = a.Q.ApproveJobCost(jobid, by)
+	switch err {
+	case nil:
+	case queue.ErrJobNotFound:
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	default:
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_COST_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Job = newAPIJob(j)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":           j.UUID,
+		"project":        j.Project,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+	}).Info("Job approved to run over budget.")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+func newAPIAccountDiffs(accounts []common.AccountDiff) []APIAccountDiff {
+	out := []APIAccountDiff{}
+	for _, a := range accounts {
+		out = append(out, APIAccountDiff{
+			Account:    a.Account,
+			OldHash:    a.OldHash,
+			NewHash:    a.NewHash,
+			OldCracked: a.OldCracked,
+			NewCracked: a.NewCracked,
+			Plaintext:  a.Plaintext,
+		})
+	}
+	return out
+}
+
+// Compare the cracked accounts of an older job a with a newer job b over the
+// same hash list, such as for year over year reporting (GET - /api/jobs/{a}/diff/{b})
+func (a *AppController) DiffJobs(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobDiffResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to compare jobs.")
+
+		return
+	}
+
+	older := mux.Vars(r)["a"]
+	newer := mux.Vars(r)["b"]
+
+	d, err := a.reader(r).DiffJobs(older, newer)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrResultsEncrypted {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_ENCRYPTED)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_DIFF_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"older": older,
+			"newer": newer,
+			"error": err.Error(),
+		}).Error("Unable to compare job results.")
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.ByUser = d.ByUser
+	resp.NewlyCracked = newAPIAccountDiffs(d.NewlyCracked)
+	resp.StillCracked = newAPIAccountDiffs(d.StillCracked)
+	resp.PasswordChanged = newAPIAccountDiffs(d.PasswordChanged)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"older":   older,
+		"newer":   newer,
+		"new":     len(d.NewlyCracked),
+		"still":   len(d.StillCracked),
+		"changed": len(d.PasswordChanged),
+	}).Info("Job results compared.")
+}
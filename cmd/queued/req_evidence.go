@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/evidence"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Export the evidence bundle of a job for chain of custody. The metadata,
+// parameters, digests of the inputs and results and the audit trail of the
+// job are signed with the evidence key of the queue along with when the
+// bundle was made. The bundle is JSON unless the format is zip, which also
+// holds the results the digests are of.
+// (GET - /api/jobs/{id}/evidence)
+func (a *AppController) JobEvidence(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ErrorResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to export an evidence bundle.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to export an evidence bundle.")
+		return
+	}
+
+	if a.Evidence == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_EVIDENCE_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	jobid := mux.Vars(r)["id"]
+	zipped := r.URL.Query().Get("format") == "zip"
+
+	job, err := a.Q.JobOutput(jobid)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	var body bytes.Buffer
+	var signed evidence.Signed
+	if err == nil {
+		b := evidence.New(job, a.Q.AllTools()[job.ToolUUID], by, time.Now())
+		signed, err = evidence.Sign(b, a.Evidence)
+		if err == nil && zipped {
+			err = evidence.WriteZip(&body, signed, b.Generated, evidence.ResultsCSV(job))
+		} else if err == nil {
+			err = json.NewEncoder(&body).Encode(signed)
+		}
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_EVIDENCE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"job":   jobid,
+			"error": err.Error(),
+		}).Error("Unable to export the evidence bundle of a job.")
+		return
+	}
+
+	// The export is part of the chain of custody of the next bundle
+	a.Q.RecordEvidence(jobid, by, signed.KeyID)
+
+	name := jobid + "-evidence.json"
+	ctype := "application/json"
+	if zipped {
+		name = jobid + "-evidence.zip"
+		ctype = "application/zip"
+	}
+
+	h := rw.Header()
+	h.Set("Content-Type", ctype)
+	h.Set("Content-Length", strconv.Itoa(body.Len()))
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	rw.WriteHeader(RESP_CODE_OK)
+	rw.Write(body.Bytes())
+
+	log.WithFields(log.Fields{
+		"job":  jobid,
+		"key":  signed.KeyID,
+		"zip":  zipped,
+		"user": by,
+	}).Info("Evidence bundle of a job exported.")
+}
@@ -2,17 +2,22 @@ package main
 
 import (
 	"bytes"
-	"crypto/rand"
-	"crypto/sha256"
+	"crypto/ed25519"
 	"crypto/tls"
-	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/geoip"
+	"github.com/jmmcatee/cracklord/common/pwned"
+	"github.com/jmmcatee/cracklord/common/ntds"
 	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/jmmcatee/cracklord/common/report"
+	"github.com/jmmcatee/cracklord/common/wordlist"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // All handler functions are created as part of the base AppController. This is done to
@@ -20,22 +25,72 @@ import (
 // expandablility related to adding a database or other dependencies much easier
 // for future development.
 type AppController struct {
-	T    TokenStore
-	Auth Authenticator
-	Q    queue.Queue
-	TLS  *tls.Config
+	T           TokenStore
+	Auth        Authenticator
+	Q           queue.Queue
+	TLS         *tls.Config
+	W           wordlist.Processor
+	WordlistDir string
+	HcstatBin   string
+	M           *MessageCatalog
+	Geo         *geoip.DB             // Locates the address logins come from, nil when not configured
+	D           *Digester             // Mails digests of the queue, nil when not configured
+	Policy      common.PasswordPolicy // Default policy of password compliance reports
+	Pwned       pwned.Source          // Breach corpus cracked passwords are looked up in, nil when not configured
+	Quick       *QuickCracker         // Runs single hashes through a canned pipeline, nil when not configured
+	Cookies     *SessionCookies       // Delivers session tokens in a cookie on login, nil to return them in the response
+	Metrics     *RouteMetrics         // Latency of requests to each route
+	Reports     report.Generator      // Template and methodology notes of engagement reports
+	Evidence    ed25519.PrivateKey    // Key evidence bundles of jobs are signed with, nil when not configured
+	Replica     *queue.Replica        // Serves heavy reads from the state file, nil to read them from the queue
 }
 
+// Lines of resource logs returned by default and the most that can be requested
+const (
+	defaultLogLines = 100
+	maxLogLines     = 5000
+)
+
 func (a *AppController) Router() *mux.Router {
 	r := mux.NewRouter().StrictSlash(false)
 
+	// Health checks for load balancers, these do not require a token
+	r.Path("/healthz").Methods("GET").HandlerFunc(a.Healthz)
+	r.Path("/readyz").Methods("GET").HandlerFunc(a.Readyz)
+
+	// Messages for the user interface in the language of the user
+	r.Path("/api/messages").Methods("GET").HandlerFunc(a.ListMessages)
+
+	// Description of the API clients are generated from
+	r.Path("/api/openapi.json").Methods("GET").HandlerFunc(a.ReadOpenAPI)
+
 	// Login and Logout
 	r.Path("/api/login").Methods("POST").HandlerFunc(a.Login)
 	r.Path("/api/logout").Methods("GET").HandlerFunc(a.Logout)
 
+	// Current user endpoints
+	r.Path("/api/users/me").Methods("GET").HandlerFunc(a.ReadUserMe)
+	r.Path("/api/users/me").Methods("PUT").HandlerFunc(a.UpdateUserMe)
+	r.Path("/api/users/me/sessions").Methods("GET").HandlerFunc(a.ListUserSessions)
+	r.Path("/api/users/me/sessions/{id}").Methods("DELETE").HandlerFunc(a.RevokeUserSession)
+	r.Path("/api/users/me/notifications").Methods("GET").HandlerFunc(a.GetUserNotifications)
+	r.Path("/api/users/me/notifications").Methods("PUT").HandlerFunc(a.UpdateUserNotifications)
+
 	// Tools endpoints
 	r.Path("/api/tools").Methods("GET").HandlerFunc(a.ListTools)
 	r.Path("/api/tools/{id}").Methods("GET").HandlerFunc(a.GetTool)
+	r.Path("/api/tools/{id}/preview").Methods("POST").HandlerFunc(a.PreviewTool)
+	r.Path("/api/tools/{id}/estimate").Methods("POST").HandlerFunc(a.EstimateTool)
+	r.Path("/api/tools/{id}/defaults").Methods("GET").HandlerFunc(a.ReadToolDefaults)
+	r.Path("/api/tools/{id}/defaults").Methods("PUT").HandlerFunc(a.UpdateToolDefaults)
+
+	// Ingestion endpoints
+	r.Path("/api/ingest/ntds").Methods("POST").HandlerFunc(a.IngestNTDS)
+	r.Path("/api/ingest/kerberos").Methods("POST").HandlerFunc(a.IngestKerberos)
+
+	// Statistics endpoints
+	r.Path("/api/stats/tools").Methods("GET").HandlerFunc(a.offload(a.ReadToolStats))
+	r.Path("/api/stats/routes").Methods("GET").HandlerFunc(a.ReadRouteStats)
 
 	// Resource Manager endpoints
 	r.Path("/api/resourcemanagers").Methods("GET").HandlerFunc(a.ListResourceManagers)
@@ -44,6 +99,17 @@ func (a *AppController) Router() *mux.Router {
 	// Resource endpoints
 	r.Path("/api/resources").Methods("GET").HandlerFunc(a.ListResource)
 	r.Path("/api/resources").Methods("POST").HandlerFunc(a.CreateResource)
+	r.Path("/api/resources/update").Methods("GET").HandlerFunc(a.ReadResourceUpdate)
+	r.Path("/api/resources/update").Methods("POST").HandlerFunc(a.StartResourceUpdate)
+	r.Path("/api/binaries").Methods("GET").HandlerFunc(a.ListBinaries)
+	r.Path("/api/binaries").Methods("POST").HandlerFunc(a.UploadBinary)
+	r.Path("/api/binaries/{tool}").Methods("PUT").HandlerFunc(a.SetCurrentBinary)
+	r.Path("/api/binaries/{tool}/{version}/{os}/{arch}").Methods("DELETE").HandlerFunc(a.DeleteBinary)
+	r.Path("/api/resources/named/{name}").Methods("GET").HandlerFunc(a.ReadResourceConfig)
+	r.Path("/api/resources/named/{name}").Methods("PUT").HandlerFunc(a.RegisterResource)
+	r.Path("/api/resources/{id}/logs").Methods("GET").HandlerFunc(a.ReadResourceLogs)
+	r.Path("/api/resources/{id}/profiles").Methods("PUT").HandlerFunc(a.UpdateResourceProfiles)
+	r.Path("/api/resources/{id}/rescan").Methods("POST").HandlerFunc(a.RescanResource)
 	r.Path("/api/resources/{manager}/{id}").Methods("GET").HandlerFunc(a.ReadResource)
 	r.Path("/api/resources/{id}").Methods("PUT").HandlerFunc(a.UpdateResource)
 	r.Path("/api/resources/{id}").Methods("DELETE").HandlerFunc(a.DeleteResources)
@@ -51,23 +117,117 @@ func (a *AppController) Router() *mux.Router {
 	// Jobs endpoints
 	r.Path("/api/jobs").Methods("GET").HandlerFunc(a.GetJobs)
 	r.Path("/api/jobs").Methods("POST").HandlerFunc(a.CreateJob)
+	r.Path("/api/jobs/batch").Methods("POST").HandlerFunc(a.CreateJobBatch)
+	r.Path("/api/jobs/changes").Methods("GET").HandlerFunc(a.GetJobChanges)
+	r.Path("/api/jobs/quick").Methods("POST").HandlerFunc(a.CreateQuickCrack)
+	r.Path("/api/jobs/quick/{id}").Methods("GET").HandlerFunc(a.ReadQuickCrack)
 	r.Path("/api/jobs/{id}").Methods("GET").HandlerFunc(a.ReadJob)
 	r.Path("/api/jobs/{id}").Methods("PUT").HandlerFunc(a.UpdateJob)
 	r.Path("/api/jobs/{id}").Methods("DELETE").HandlerFunc(a.DeleteJob)
+	r.Path("/api/jobs/{id}/start").Methods("POST").HandlerFunc(a.StartJob)
+	r.Path("/api/jobs/{id}/restore").Methods("POST").HandlerFunc(a.RestoreJob)
+	r.Path("/api/jobs/{id}/output").Methods("GET").HandlerFunc(a.ReadJobOutput)
+	r.Path("/api/jobs/{id}/results").Methods("GET").HandlerFunc(a.JobResults)
+	r.Path("/api/jobs/{id}/log").Methods("GET").HandlerFunc(a.ReadJobLog)
+	r.Path("/api/jobs/{id}/queue").Methods("PUT").HandlerFunc(a.MoveJob)
+	r.Path("/api/jobs/{a}/diff/{b}").Methods("GET").HandlerFunc(a.offload(a.DiffJobs))
+	r.Path("/api/jobs/{id}/policy").Methods("POST").HandlerFunc(a.offload(a.JobPolicyReport))
+	r.Path("/api/jobs/{id}/pwned").Methods("GET").HandlerFunc(a.offload(a.JobPwnedReport))
+	r.Path("/api/jobs/{id}/owner").Methods("PUT").HandlerFunc(a.TransferJob)
+	r.Path("/api/jobs/{id}/cost").Methods("POST").HandlerFunc(a.ApproveJobCost)
+	r.Path("/api/jobs/{id}/approve").Methods("POST").HandlerFunc(a.ApproveJob)
+	r.Path("/api/jobs/{id}/evidence").Methods("GET").HandlerFunc(a.JobEvidence)
 
 	// Queue endpoints
 	r.Path("/api/queue").Methods("PUT").HandlerFunc(a.ReorderQueue)
+	r.Path("/api/queue/simulate").Methods("POST").HandlerFunc(a.SimulateQueue)
+	r.Path("/api/queue/export").Methods("GET").HandlerFunc(a.ExportQueue)
+	r.Path("/api/queue/import").Methods("POST").HandlerFunc(a.ImportQueue)
+
+	// Reservation endpoints
+	r.Path("/api/reservations").Methods("GET").HandlerFunc(a.ListReservations)
+	r.Path("/api/reservations").Methods("POST").HandlerFunc(a.CreateReservation)
+	r.Path("/api/reservations/{id}").Methods("DELETE").HandlerFunc(a.DeleteReservation)
+	r.Path("/api/queues").Methods("GET").HandlerFunc(a.ListQueues)
+	r.Path("/api/queues/{name}").Methods("PUT").HandlerFunc(a.SetQueue)
+	r.Path("/api/queues/{name}").Methods("DELETE").HandlerFunc(a.DeleteQueue)
+	r.Path("/api/budgets").Methods("GET").HandlerFunc(a.GetBudgets)
+	r.Path("/api/projects/{id}/report").Methods("GET").HandlerFunc(a.offload(a.ProjectReport))
+
+	// Wordlist processing endpoints
+	r.Path("/api/wordlists/processing").Methods("GET").HandlerFunc(a.ListWordlistTasks)
+	r.Path("/api/wordlists/processing").Methods("POST").HandlerFunc(a.CreateWordlistTask)
+	r.Path("/api/wordlists/processing/{id}").Methods("GET").HandlerFunc(a.ReadWordlistTask)
+	r.Path("/api/wordlists/crawl").Methods("POST").HandlerFunc(a.CreateWordlistCrawl)
+	r.Path("/api/wordlists/models").Methods("GET").HandlerFunc(a.ListMarkovModels)
+	r.Path("/api/wordlists/models/{name}").Methods("PUT").HandlerFunc(a.UploadMarkovModel)
+	r.Path("/api/wordlists/generated").Methods("GET").HandlerFunc(a.ListGeneratedWordlists)
+	r.Path("/api/wordlists/generated/{name}").Methods("PUT").HandlerFunc(a.GenerateWordlist)
 
 	log.Debug("Application router handlers configured.")
 
 	return r
 }
 
+// Get the user a request should be performed as. This is the user who owns the
+// token unless an Administrator has requested to impersonate another user. A
+// user who may not impersonate is refused with a 401 written to rw, and false
+// is returned so the handler stops.
+func (a *AppController) actingUser(rw http.ResponseWriter, r *http.Request, user User, action string) (User, bool) {
+	target := r.Header.Get(ImpersonateHeader)
+	if target == "" || target == user.Username {
+		return user, true
+	}
+
+	acting, err := user.Impersonate(target)
+	if err != nil {
+		var resp ErrorResp
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		newRespEncoder(rw).Encode(resp)
+
+		log.WithFields(log.Fields{
+			"user":   user.Username,
+			"target": target,
+		}).Warn("A non-administrator attempted to impersonate a user to " + action + ".")
+
+		return user, false
+	}
+
+	return acting, true
+}
+
+// Tool parameters from the API might not all be strings, so convert them into
+// the string map used by jobs
+func stringParams(input map[string]interface{}) map[string]string {
+	params := map[string]string{}
+	for key, value := range input {
+		switch v := value.(type) {
+		case string:
+			params[key] = v
+		case bool:
+			params[key] = strconv.FormatBool(v)
+		case int:
+			params[key] = strconv.Itoa(v)
+		case float64:
+			params[key] = strconv.FormatFloat(v, 'g', -1, 64)
+		case float32:
+			params[key] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+
+		}
+	}
+
+	return params
+}
+
 // Login Hander (POST - /api/login)
 func (a *AppController) Login(rw http.ResponseWriter, r *http.Request) {
 	// Decode the request and see if it is valid
 	reqJSON := json.NewDecoder(r.Body)
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	var req = LoginReq{}
 	var resp = LoginResp{}
@@ -76,7 +236,7 @@ func (a *AppController) Login(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// We had an error decoding the request to return an error
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 		resp.Token = ""
 
 		log.Error("Unable to decode login information provided.")
@@ -86,15 +246,27 @@ func (a *AppController) Login(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Where the login came from is recorded for every attempt
+	remote := clientIP(r.RemoteAddr, r.Header.Get("X-Forwarded-For"))
+	audit := log.Fields{
+		"username":  req.Username,
+		"remote":    remote,
+		"useragent": r.UserAgent(),
+	}
+	location := a.Geo.Locate(remote)
+	if location != "" {
+		audit["location"] = location
+	}
+
 	// Verify the login
 	user, err := a.Auth.Login(req.Username, req.Password)
 	if err != nil {
 		// Login failed so return error
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 		resp.Token = ""
 
-		log.WithField("username", req.Username).Warn("Login failed.")
+		log.WithFields(audit).Warn("Login failed.")
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -103,25 +275,61 @@ func (a *AppController) Login(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate token
-	seed := make([]byte, 256)
-	bToken := sha256.New()
+	token, err := NewSessionToken()
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_ERROR)
+		resp.Token = ""
+
+		log.WithField("error", err.Error()).Error("Unable to generate a session token.")
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
 
-	rand.Read(seed)
+		return
+	}
 
-	token := hex.EncodeToString(bToken.Sum(seed))
+	user.RemoteAddr = remote
+	user.UserAgent = r.UserAgent()
+	user.Location = location
 
 	// Add to the token store
-	a.T.AddToken(token, user)
+	err = a.T.AddToken(token, user)
+	if err != nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAVAILABLE)
+		resp.Token = ""
+
+		log.WithFields(log.Fields{
+			"username": req.Username,
+			"error":    err.Error(),
+		}).Error("Unable to save the session token.")
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+
+		return
+	}
 
 	// Return new information
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 	resp.Token = token
 	resp.Role = user.EffectiveRole()
 
+	// The token is only given in the cookie so scripts on the page never see it
+	if a.Cookies != nil {
+		a.Cookies.set(rw, token)
+		if _, err := setCSRFCookie(rw, r); err != nil {
+			log.WithField("error", err.Error()).Error("Unable to generate a CSRF token.")
+		}
+		resp.Token = ""
+	}
+	resp.PasswordChange = user.MustChangePassword
+
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
-	log.WithField("username", req.Username).Info("User successfully logged in")
+	log.WithFields(audit).Info("User successfully logged in")
 }
 
 // Logout endpoint (POST - /api/logout)
@@ -130,7 +338,7 @@ func (a *AppController) Logout(rw http.ResponseWriter, r *http.Request) {
 	var resp = LogoutResp{}
 
 	// Build the JSON Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
@@ -138,28 +346,150 @@ func (a *AppController) Logout(rw http.ResponseWriter, r *http.Request) {
 	u, _ := a.T.GetUser(token)
 	a.T.RemoveToken(token)
 
+	if a.Cookies != nil {
+		a.Cookies.clear(rw)
+	}
+
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
 	log.WithField("username", u.Username).Info("User successfully logged out.")
 }
 
+// Read the current user profile (GET - /api/users/me)
+func (a *AppController) ReadUserMe(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp UserMeResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read a user profile.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.User.Username = user.Username
+	resp.User.Role = user.EffectiveRole()
+	resp.User.Groups = user.Groups
+	resp.User.LogOnTime = user.LogOnTime
+	resp.User.PasswordChange = user.MustChangePassword
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithField("username", user.Username).Debug("User profile read.")
+}
+
+// Change the password of the current user (PUT - /api/users/me)
+func (a *AppController) UpdateUserMe(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req UserPasswordReq
+	var resp UserPasswordResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to change a password.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a password change.")
+
+		return
+	}
+
+	// Only some authenticators allow users to manage their own password
+	pc, ok := a.Auth.(PasswordChanger)
+	if !ok {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_PASSWORD_UNSUPPORTED)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("A password change was attempted with an authenticator that does not support it.")
+
+		return
+	}
+
+	err = pc.ChangePassword(user.Username, req.OldPassword, req.NewPassword)
+	if err != nil {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_PASSWORD_CHANGE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("A password change failed.")
+
+		return
+	}
+
+	a.T.PasswordChanged(user.Username)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithField("username", user.Username).Info("User changed their password.")
+}
+
 // List Tools endpoint (GET - /api/tools)
 func (a *AppController) ListTools(rw http.ResponseWriter, r *http.Request) {
 	// Resposne and Request structures
 	var resp ToolsResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -171,7 +501,7 @@ func (a *AppController) ListTools(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -179,9 +509,9 @@ func (a *AppController) ListTools(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the tools list from the Queue
-	for uuid, t := range a.Q.ActiveTools() {
-		resp.Tools = append(resp.Tools, APITool{uuid, t.Name, t.Version})
+	// Get the tools list from the Queue snapshot
+	for uuid, t := range a.Q.Snapshot().Tools {
+		resp.Tools = append(resp.Tools, APITool{ID: uuid, Name: t.Name, Version: t.Version})
 		log.WithFields(log.Fields{
 			"uuid": t.UUID,
 			"name": t.Name,
@@ -191,7 +521,7 @@ func (a *AppController) ListTools(rw http.ResponseWriter, r *http.Request) {
 
 	// Build response
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
@@ -204,14 +534,14 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 	var resp ToolsGetResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -223,7 +553,7 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -233,11 +563,11 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 
 	// Get the tool ID
 	uuid := mux.Vars(r)["id"]
-	tool, ok := a.Q.ActiveTools()[uuid]
+	tool, ok := a.Q.Snapshot().Tools[uuid]
 	if !ok {
 		// No tool found, return error
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = RESP_CODE_NOTFOUND_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
@@ -252,7 +582,7 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.WithField("error", err.Error()).Error("There was a problem parsing tool form schema JSON.")
 		resp.Status = RESP_CODE_ERROR
-		resp.Message = "There was an error parsing the tool form information: " + err.Error()
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_TOOL_FORM_INVALID, err.Error())
 
 		rw.WriteHeader(RESP_CODE_ERROR)
 		respJSON.Encode(resp)
@@ -261,13 +591,18 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 
 	// We found the tools so return it in the resp structure
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 	resp.Tool.ID = tool.UUID
 	resp.Tool.Name = tool.Name
 	resp.Tool.Version = tool.Version
 	resp.Tool.Form = &form.Form
 	resp.Tool.Schema = &form.Schema
 
+	defaults := a.Q.ToolDefaults(tool.Name)
+	resp.Tool.Defaults = defaults.Defaults
+	resp.Tool.Locked = defaults.Locked
+	resp.Tool.FormVersion = tool.FormVersion
+
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
 
@@ -277,196 +612,452 @@ func (a *AppController) GetTool(rw http.ResponseWriter, r *http.Request) {
 	}).Info("Detailed information on tool sent to API")
 }
 
-// List Resource Managers endpoint (GET - /api/resourcemanagers)
-// This function will provide a list of all resource managers and their IDs to the API
-// in the form of a javascript array of objects.
-func (a *AppController) ListResourceManagers(rw http.ResponseWriter, r *http.Request) {
-	// Resposne and Request structures
-	var resp ResourceManagersResp
+// Get the parameters applied to every job of a tool (GET - /api/tools/{id}/defaults)
+func (a *AppController) ReadToolDefaults(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ToolDefaultsResp
 
-	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
-	//Check to make sure our token is valid, and if not return an error to the API
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
-		log.WithField("token", token).Warn("An unknown user token attempted to list resource managers.")
+		log.WithField("token", token).Warn("An unknown user token attempted to get tool defaults.")
 		return
 	}
 
-	// Check for the read only user level as this is just data gathering.
-	user, _ := a.T.GetUser(token)
-	if !user.Allowed(ReadOnly) {
-		// If the user isn't allowed or the token isn't valid return an HTTP
-		// Unauthorized to the user.
-		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+	tool, ok := a.Q.ActiveTools()[mux.Vars(r)["id"]]
+	if !ok {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_NOTFOUND)
 
-		//Write out the unauthorized response
-		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
-		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to list resource managers.")
 		return
 	}
 
-	// Get the map of all resource managers from the Queue
-	for resmgrid, resmgrdata := range a.Q.AllResourceManagers() {
-		resp.ResourceManagers = append(resp.ResourceManagers,
-			APIResourceManager{
-				ID:   resmgrid,
-				Name: resmgrdata.DisplayName(),
-			})
-		log.WithFields(log.Fields{
-			"id":   resmgrid,
-			"name": resmgrdata.DisplayName(),
-		}).Debug("Added resource manager to list")
-	}
+	defaults := a.Q.ToolDefaults(tool.Name)
 
-	// Build response of 200 for the API Status and Message portions
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Defaults = defaults.Defaults
+	resp.Locked = defaults.Locked
 
-	//Write out the HTTP 200 header
 	rw.WriteHeader(RESP_CODE_OK)
-	// Write out our response to the response writer in JSON format
 	respJSON.Encode(resp)
-
-	//Log it for the end user
-	log.Info("Provided a resource manager listing to API")
 }
 
-// Get the details on a single resource manager (GET /api/resourcemanagers/{id})
-func (a *AppController) GetResourceManager(rw http.ResponseWriter, r *http.Request) {
+// Set the parameters applied to every job of a tool (PUT - /api/tools/{id}/defaults)
+func (a *AppController) UpdateToolDefaults(rw http.ResponseWriter, r *http.Request) {
 	// Response and Request structures
-	var resp ResourceManagerGetResp
+	var req ToolDefaultsReq
+	var resp ToolDefaultsResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
-	// Check to make sure our user token is valid
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
-		log.WithField("token", token).Warn("An unknown user token attempted to get tool details.")
+		log.WithField("token", token).Warn("An unknown user token attempted to set tool defaults.")
 		return
 	}
 
-	// Check for the read only level as this is just information we're returning
+	// Check for administrator access
 	user, _ := a.T.GetUser(token)
-	if !user.Allowed(ReadOnly) {
+	if !user.Allowed(Administrator) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
-		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to get tool details.")
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to set tool defaults.")
 		return
 	}
 
-	// Get the resource manager ID from the URL
-	systemname := mux.Vars(r)["id"]
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
-	// Get the resource manager object itself
-	resmgr, ok := a.Q.GetResourceManager(systemname)
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	tool, ok := a.Q.ActiveTools()[mux.Vars(r)["id"]]
 	if !ok {
-		// The resource manager was not found, let's return that in proper HTTP
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "That resource manager could not be found."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
 		return
 	}
 
-	form := json.RawMessage(resmgr.ParametersForm())
-	schema := json.RawMessage(resmgr.ParametersSchema())
+	a.Q.SetToolDefaults(tool.Name, common.ToolDefaults{
+		Defaults: stringParams(req.Defaults),
+		Locked:   stringParams(req.Locked),
+	})
+
+	defaults := a.Q.ToolDefaults(tool.Name)
 
-	// Now since everything seems ok, let's build up our response and send it off
-	// to the API.
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
-	//Resp.ResourceManager is of the type APIResourceManagerDetail
-	resp.ResourceManager.ID = resmgr.SystemName()
-	resp.ResourceManager.Name = resmgr.DisplayName()
-	resp.ResourceManager.Description = resmgr.Description()
-	resp.ResourceManager.Form = &form
-	resp.ResourceManager.Schema = &schema
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Defaults = defaults.Defaults
+	resp.Locked = defaults.Locked
 
-	// Write out the HTTP OK header
 	rw.WriteHeader(RESP_CODE_OK)
-	//Encode and write out our response
-	err := respJSON.Encode(resp)
-	if err != nil {
-		log.WithField("error", err.Error()).Error("Unable to encode resource manager details.")
-	}
+	respJSON.Encode(resp)
 
-	log.WithField("id", resmgr.SystemName()).Info("Detailed information on resource manager sent to API")
+	log.WithFields(log.Fields{
+		"tool":     tool.Name,
+		"defaults": resp.Defaults,
+		"locked":   resp.Locked,
+		"user":     user.Username,
+	}).Info("Tool defaults updated.")
 }
 
-// Get Job list (GET - /api/jobs)
-func (a *AppController) GetJobs(rw http.ResponseWriter, r *http.Request) {
+// Preview the candidates of a tool (POST - /api/tools/{id}/preview)
+func (a *AppController) PreviewTool(rw http.ResponseWriter, r *http.Request) {
 	// Response and Request structures
-	var resp GetJobsResp
+	var req ToolPreviewReq
+	var resp ToolPreviewResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
-		log.WithField("token", token).Warn("An unknown user token attempted to get a job listing")
+		log.WithField("token", token).Warn("An unknown user token attempted to preview a tool.")
 		return
 	}
 
-	// Get the list of jobs and populate a return structure
-	for _, j := range a.Q.AllJobs() {
-		var job APIJob
-
-		job.ID = j.UUID
-		job.Name = j.Name
-		job.Status = j.Status
-		job.ResourceID = j.ResAssigned
-		job.Owner = j.Owner
-		job.StartTime = j.StartTime
-		job.ETC = j.ETC
-		job.CrackedHashes = j.CrackedHashes
-		job.TotalHashes = j.TotalHashes
-		job.Progress = j.Progress
-		job.ToolID = j.ToolUUID
-
-		resp.Jobs = append(resp.Jobs, job)
-		log.WithFields(log.Fields{
-			"uuid":   j.UUID,
-			"name":   j.Name,
-			"status": j.Status,
-		}).Debug("Gathered job for query listing.")
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to preview a tool.")
+		return
 	}
 
-	// Return the results
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	uuid := mux.Vars(r)["id"]
+	resp.Candidates, err = a.Q.ToolPreview(uuid, stringParams(req.Params), req.Words)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_TOOL_PREVIEW_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"tool":       uuid,
+		"user":       user.Username,
+		"candidates": len(resp.Candidates),
+	}).Info("Tool preview generated.")
+}
+
+// Estimate the keyspace and run time of a job (POST - /api/tools/{id}/estimate)
+func (a *AppController) EstimateTool(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req ToolEstimateReq
+	var resp ToolEstimateResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to estimate a job.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to estimate a job.")
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	uuid := mux.Vars(r)["id"]
+	estimates, err := a.Q.ToolEstimate(uuid, stringParams(req.Params))
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_TOOL_ESTIMATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Estimates = make([]APIEstimate, 0, len(estimates))
+	for _, e := range estimates {
+		resp.Estimates = append(resp.Estimates, APIEstimate{
+			ResourceID:   e.ResourceUUID,
+			ResourceName: e.ResourceName,
+			Keyspace:     e.Keyspace,
+			Speed:        e.Speed,
+			Seconds:      e.Seconds,
+			Error:        e.Error,
+		})
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"tool": uuid,
+		"user": user.Username,
+	}).Debug("Job estimate generated.")
+}
+
+// List Resource Managers endpoint (GET - /api/resourcemanagers)
+// This function will provide a list of all resource managers and their IDs to the API
+// in the form of a javascript array of objects.
+func (a *AppController) ListResourceManagers(rw http.ResponseWriter, r *http.Request) {
+	// Resposne and Request structures
+	var resp ResourceManagersResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	//Check to make sure our token is valid, and if not return an error to the API
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to list resource managers.")
+		return
+	}
+
+	// Check for the read only user level as this is just data gathering.
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(ReadOnly) {
+		// If the user isn't allowed or the token isn't valid return an HTTP
+		// Unauthorized to the user.
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		//Write out the unauthorized response
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to list resource managers.")
+		return
+	}
+
+	// Get the map of all resource managers from the Queue
+	for resmgrid, resmgrdata := range a.Q.AllResourceManagers() {
+		resp.ResourceManagers = append(resp.ResourceManagers,
+			APIResourceManager{
+				ID:   resmgrid,
+				Name: resmgrdata.DisplayName(),
+			})
+		log.WithFields(log.Fields{
+			"id":   resmgrid,
+			"name": resmgrdata.DisplayName(),
+		}).Debug("Added resource manager to list")
+	}
+
+	// Build response of 200 for the API Status and Message portions
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
+	//Write out the HTTP 200 header
 	rw.WriteHeader(RESP_CODE_OK)
+	// Write out our response to the response writer in JSON format
 	respJSON.Encode(resp)
+
+	//Log it for the end user
+	log.Info("Provided a resource manager listing to API")
+}
+
+// Get the details on a single resource manager (GET /api/resourcemanagers/{id})
+func (a *AppController) GetResourceManager(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ResourceManagerGetResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	// Check to make sure our user token is valid
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to get tool details.")
+		return
+	}
+
+	// Check for the read only level as this is just information we're returning
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(ReadOnly) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user token attempted to get tool details.")
+		return
+	}
+
+	// Get the resource manager ID from the URL
+	systemname := mux.Vars(r)["id"]
+
+	// Get the resource manager object itself
+	resmgr, ok := a.Q.GetResourceManager(systemname)
+	if !ok {
+		// The resource manager was not found, let's return that in proper HTTP
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	form := json.RawMessage(resmgr.ParametersForm())
+	schema := json.RawMessage(resmgr.ParametersSchema())
+
+	// Now since everything seems ok, let's build up our response and send it off
+	// to the API.
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	//Resp.ResourceManager is of the type APIResourceManagerDetail
+	resp.ResourceManager.ID = resmgr.SystemName()
+	resp.ResourceManager.Name = resmgr.DisplayName()
+	resp.ResourceManager.Description = resmgr.Description()
+	resp.ResourceManager.Form = &form
+	resp.ResourceManager.Schema = &schema
+
+	// Write out the HTTP OK header
+	rw.WriteHeader(RESP_CODE_OK)
+	//Encode and write out our response
+	err := respJSON.Encode(resp)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to encode resource manager details.")
+	}
+
+	log.WithField("id", resmgr.SystemName()).Info("Detailed information on resource manager sent to API")
+}
+
+// Get Job list (GET - /api/jobs)
+func (a *AppController) GetJobs(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp GetJobsResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to get a job listing")
+		return
+	}
+
+	// Get the list of jobs and populate a return structure
+	for _, j := range a.Q.AllJobs() {
+		resp.Jobs = append(resp.Jobs, newAPIJob(j))
+		log.WithFields(log.Fields{
+			"uuid":   j.UUID,
+			"name":   j.Name,
+			"status": j.Status,
+		}).Debug("Gathered job for query listing.")
+	}
+
+	// Return the results
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	writeWithETag(rw, r, resp)
 }
 
 // Create a new job (POST - /api/job)
@@ -477,14 +1068,14 @@ func (a *AppController) CreateJob(rw http.ResponseWriter, r *http.Request) {
 
 	// JSON Encoder and Decoder
 	reqJSON := json.NewDecoder(r.Body)
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -496,70 +1087,354 @@ func (a *AppController) CreateJob(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
 		log.WithField("user", user.Username).Warn("An unauthorized user attempted to create a job.")
 		return
 	}
+	admin := user.Allowed(Administrator)
+	by := user.Username
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "create a job")
+	if !ok {
+		return
+	}
 
 	// Decode the request
 	err := reqJSON.Decode(&req)
 	if err != nil {
-		log.WithError(err).Error("Error parsing the request.")
+		log.WithField("error", err.Error()).Error("Error parsing the request.")
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
 		return
 	}
 
-	// Some types might not be strings so let's build a map for the params input
-	params := map[string]string{}
-	for key, value := range req.Params {
-		switch v := value.(type) {
-		case string:
-			params[key] = v
-		case bool:
-			params[key] = strconv.FormatBool(v)
-		case int:
-			params[key] = strconv.Itoa(v)
-		case float64:
-			params[key] = strconv.FormatFloat(v, 'g', -1, 64)
-		case float32:
-			params[key] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	// Build a job structure, looking for mistakes in the input before the
+	// tool fails on it
+	found := cleanRequestHashes(&req)
+	job := newRequestJob(req, user.Username)
+	job.SubmittedBy = submittedBy(user)
+
+	err = applyJobOverrides(req, &job, admin, by)
+	if err != nil {
+		code := RESP_CODE_BADREQ
+		if err == errOverridesForbidden {
+			code = RESP_CODE_FORBIDDEN
+		}
+		resp.Status = code
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_OVERRIDES_INVALID, err.Error())
+
+		rw.WriteHeader(code)
+		respJSON.Encode(resp)
+		log.WithField("user", by).Warn("Unable to set tool overrides for a job.")
+		return
+	}
+
+	err = applyResultKey(req, &job, by)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTKEY_INVALID, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		log.WithField("user", by).Warn("Unable to use the result key of a job.")
+		return
+	}
+
+	issues, refuse := a.checkJobInput(job, found, req.Force)
+	resp.Issues = issues
+	if refuse {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_INPUT_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"name":   job.Name,
+			"owner":  job.Owner,
+			"issues": len(issues),
+		}).Info("Job refused because of problems with its input.")
+		return
+	}
+
+	err = a.Q.AddJob(job)
+	if err != nil {
+		log.Println(err.Error())
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_CREATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Job was created so populate the response structure and return
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.JobID = job.UUID
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":           job.UUID,
+		"name":           job.Name,
+		"owner":          job.Owner,
+		"impersonatedby": user.ImpersonatedBy,
+		"status":         job.Status,
+	}).Info("New job created.")
+}
+
+// Build a job from a create request
+func newRequestJob(req JobCreateReq, owner string) common.Job {
+	job := common.NewJob(req.ToolID, req.Name, owner, stringParams(req.Params))
+	job.Project = req.Project
+	job.Debug = req.Debug
+	job.Queue = req.Queue
+	job.Sensitive = req.Sensitive
+	job.FormVersion = req.FormVersion
+
+	// The maximum runtime is provided in minutes, zero will use the queue default
+	if req.MaxRuntime > 0 {
+		job.MaxRuntime = time.Duration(req.MaxRuntime) * time.Minute
+	}
+
+	// Only resources with the hardware given will run the job
+	if req.Constraints != nil {
+		job.Constraints = common.Constraints{
+			MinGPUs:      req.Constraints.MinGPUs,
+			MinGPUMemory: req.Constraints.MinGPUMemory,
+			GPUModel:     req.Constraints.GPUModel,
+			MinCPUCores:  req.Constraints.MinCPUCores,
+			MinMemory:    req.Constraints.MinMemory,
+		}
+	}
+
+	// Jobs that should not be dispatched yet are kept as drafts until started
+	if req.Dispatch != nil && !*req.Dispatch {
+		job.Status = common.STATUS_DRAFT
+	}
+
+	splitRequestHashes(req, &job)
+
+	return job
+}
+
+// Split the hashes of a job into what is given to the tool and what the queue
+// keeps, depending on how the request asked for them to be handled
+func splitRequestHashes(req JobCreateReq, job *common.Job) {
+	if req.LMNT {
+		splitRequestLM(job)
+	} else if req.Usernames {
+		splitRequestUsernames(job)
+	}
+}
+
+// Split the new hashes of a draft the way they were when it was created,
+// unless the request asks for them to be handled another way
+func splitDraftHashes(req JobUpdateReq, current common.Job, job *common.Job) {
+	job.Usernames = nil
+	job.NTHashes = nil
+	if _, ok := job.Parameters["hashes"]; !ok {
+		return
+	}
+
+	switch {
+	case req.LMNT || (!req.Usernames && current.NTHashes != nil):
+		splitRequestLM(job)
+	case req.Usernames || current.Usernames != nil:
+		splitRequestUsernames(job)
+	}
+}
+
+// Only the hashes are given to the tool, the usernames are kept with the job
+// and added back to the results
+func splitRequestUsernames(job *common.Job) {
+	hashes, users := common.SplitUsernames(job.Parameters["hashes"])
+	job.Parameters["hashes"] = hashes
+	job.Usernames = users
+}
+
+// Pwdump hashes with LM hashes are cracked in two phases, the LM hashes are
+// given to the tool and the NT hashes are found by toggling the case of the LM
+// passwords. Without any LM hashes the NT hashes are cracked as normal.
+func splitRequestLM(job *common.Job) {
+	lms, nts := ntds.SplitLM(job.Parameters["hashes"])
+	if len(nts) == 0 {
+		splitRequestUsernames(job)
+		job.Parameters["algorithm"] = ntds.AlgorithmNT
+		return
+	}
+
+	_, job.Usernames = common.SplitUsernames(job.Parameters["hashes"])
+	job.Parameters["hashes"] = lms
+	job.Parameters["algorithm"] = ntds.AlgorithmLM
+	job.NTHashes = nts
+}
+
+// Create several jobs at once (POST - /api/jobs/batch)
+func (a *AppController) CreateJobBatch(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req JobBatchReq
+	var resp JobBatchResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.Warn("An unknown token attempted to create a batch of jobs.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to create a batch of jobs.")
+		return
+	}
+	admin := user.Allowed(Administrator)
+	by := user.Username
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "create a batch of jobs")
+	if !ok {
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Build the list of jobs, a template is copied for every set of hashes
+	var jobs []common.Job
+	var reqs []JobCreateReq
+	var found [][]common.InputIssue
+	var forced []bool
+	for _, j := range req.Jobs {
+		found = append(found, cleanRequestHashes(&j))
+		forced = append(forced, j.Force)
+		reqs = append(reqs, j)
+		job := newRequestJob(j, user.Username)
+		job.SubmittedBy = submittedBy(user)
+		jobs = append(jobs, job)
+	}
+
+	if req.Template != nil {
+		for i, hashes := range req.Hashes {
+			hashes, issues := common.CheckHashes(hashes)
+
+			job := newRequestJob(*req.Template, user.Username)
+			job.SubmittedBy = submittedBy(user)
+			job.Parameters["hashes"] = hashes
+			job.Name = fmt.Sprintf("%s (%d)", req.Template.Name, i+1)
+			splitRequestHashes(*req.Template, &job)
+
+			found = append(found, issues)
+			forced = append(forced, req.Template.Force)
+			reqs = append(reqs, *req.Template)
+			jobs = append(jobs, job)
+		}
+	}
+
+	if len(jobs) == 0 {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_BATCH_EMPTY)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// None of the jobs are created if the input of any would fail
+	resp.Results = make([]JobBatchResult, len(jobs))
+	var refused bool
+	for i := range jobs {
+		var refuse bool
+		resp.Results[i].Index = i
+		resp.Results[i].Issues, refuse = a.checkJobInput(jobs[i], found[i], forced[i])
+		if refuse {
+			resp.Results[i].Error, _ = a.M.Localize(r, MSG_JOB_INPUT_INVALID)
+			refused = true
+		}
+
+		if err := applyJobOverrides(reqs[i], &jobs[i], admin, by); err != nil {
+			resp.Results[i].Error, _ = a.M.Localize(r, MSG_JOB_OVERRIDES_INVALID, err.Error())
+			refused = true
+		}
 
+		if err := applyResultKey(reqs[i], &jobs[i], by); err != nil {
+			resp.Results[i].Error, _ = a.M.Localize(r, MSG_JOB_RESULTKEY_INVALID, err.Error())
+			refused = true
 		}
 	}
 
-	// Build a job structure
-	job := common.NewJob(req.ToolID, req.Name, user.Username, params)
+	if refused {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_INPUT_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	errs, err := a.Q.AddJobs(jobs)
+
+	for i := range jobs {
+		if errs[i] != nil {
+			resp.Results[i].Error = errs[i].Error()
+		} else if err == nil {
+			resp.Results[i].JobID = jobs[i].UUID
+		}
+	}
 
-	err = a.Q.AddJob(job)
 	if err != nil {
-		log.Println(err.Error())
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = "An error occured when trying to create the job: " + err.Error()
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOBS_CREATE_FAILED, err.Error())
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
 		return
 	}
 
-	// Job was created so populate the response structure and return
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
-	resp.JobID = job.UUID
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
 
 	log.WithFields(log.Fields{
-		"uuid": job.UUID,
-		"name": job.Name,
-	}).Info("New job created.")
+		"count":          len(jobs),
+		"owner":          user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+	}).Info("Batch of jobs created.")
 }
 
 // Read an individual Job (GET - /api/jobs/{id})
@@ -568,14 +1443,14 @@ func (a *AppController) ReadJob(rw http.ResponseWriter, r *http.Request) {
 	var resp JobReadResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -588,12 +1463,36 @@ func (a *AppController) ReadJob(rw http.ResponseWriter, r *http.Request) {
 	// Get the ID of the job we want
 	jobid := mux.Vars(r)["id"]
 
+	// Charts of long jobs can ask for performance data averaged over a number
+	// of seconds
+	var resolution int
+	if res := r.URL.Query().Get("resolution"); res != "" {
+		var err error
+		resolution, err = strconv.Atoi(res)
+		if err != nil || resolution < 1 {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESOLUTION_INVALID)
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+	}
+
 	// Pull Job info from the Queue
-	job := a.Q.JobInfo(jobid)
+	job, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_READ_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
 
 	// Build the response structure
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 	resp.Job.ID = job.UUID
 	resp.Job.Name = job.Name
 	resp.Job.Status = job.Status
@@ -608,11 +1507,61 @@ func (a *AppController) ReadJob(rw http.ResponseWriter, r *http.Request) {
 	resp.Job.ToolID = job.ToolUUID
 	resp.Job.PerformanceTitle = job.PerformanceTitle
 	resp.Job.PerformanceData = job.PerformanceData
-	resp.Job.OutputTitles = job.OutputTitles
-	resp.Job.OutputData = job.OutputData
+	if resolution > 0 {
+		resp.Job.PerformanceData = common.ResamplePerformance(job.PerformanceData, time.Duration(resolution)*time.Second)
+	}
+	resp.Job.OutputTitles, resp.Job.OutputData = job.JoinUsernames()
+	resp.Job.OutputMetrics = newAPIMetrics(a.M, r, job, resp.Job.OutputTitles)
+	if m := job.PerformanceDescriptor(); m.Kind != "" {
+		pm := newAPIMetric(a.M, r, m)
+		resp.Job.PerformanceMetric = &pm
+	}
+	resp.Job.OutputSpilled = job.OutputSpilled
+	resp.Job.MaxRuntime = int(job.MaxRuntime / time.Minute)
+	if !job.Constraints.IsZero() {
+		resp.Job.Constraints = &APIConstraints{
+			MinGPUs:      job.Constraints.MinGPUs,
+			MinGPUMemory: job.Constraints.MinGPUMemory,
+			GPUModel:     job.Constraints.GPUModel,
+			MinCPUCores:  job.Constraints.MinCPUCores,
+			MinMemory:    job.Constraints.MinMemory,
+		}
+	}
+	if cp, ok := a.Q.Checkpoint(job.UUID); ok {
+		resp.Job.Checkpoint = &APICheckpoint{
+			Taken:         cp.Taken,
+			Progress:      cp.Progress,
+			CrackedHashes: len(cp.Output),
+		}
+	}
+	resp.Job.Args = job.ExtraArgs
+	resp.Job.Project = job.Project
+	resp.Job.Debug = job.Debug
+	resp.Job.Queue = job.Queue
+	resp.Job.Cost = job.Cost
+	resp.Job.CostApproved = job.CostApproved
+	resp.Job.ResultKey = resultKeyFingerprint(job)
+	resp.Job.Sensitive = job.Sensitive
+	resp.Job.ApprovedBy = job.ApprovedBy
+	resp.Job.FormVersion = job.FormVersion
+	if !job.Stalled.IsZero() {
+		resp.Job.Stalled = &job.Stalled
+	}
+	if !job.Purged.IsZero() {
+		resp.Job.Purged = &job.Purged
+	}
+	resp.Job.Env = job.Env
+	resp.Job.History = []APIJobEvent{}
+	for _, e := range job.History {
+		resp.Job.History = append(resp.Job.History, APIJobEvent{
+			Time:   e.Time,
+			User:   e.User,
+			Action: e.Action,
+			Detail: e.Detail,
+		})
+	}
 
-	rw.WriteHeader(RESP_CODE_OK)
-	respJSON.Encode(resp)
+	writeWithETag(rw, r, resp)
 
 	log.WithFields(log.Fields{
 		"uuid": job.UUID,
@@ -628,14 +1577,14 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 
 	// JSON Encoder and Decoder
 	reqJSON := json.NewDecoder(r.Body)
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -649,7 +1598,7 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -659,11 +1608,20 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only Administrators can force a job to be released
+	admin := user.Allowed(Administrator)
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "update a job")
+	if !ok {
+		return
+	}
+
 	// Decode the request
 	err := reqJSON.Decode(&req)
 	if err != nil {
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
@@ -676,25 +1634,124 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 	// Get the ID of the job we want
 	jobid := mux.Vars(r)["id"]
 
+	current, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_UPDATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Draft jobs can have their details changed before they are started
+	draft := current.Status == common.STATUS_DRAFT
+	if draft && (req.Name != "" || req.Params != nil || req.MaxRuntime > 0) {
+		edit := queue.DraftEdit{
+			Name:       req.Name,
+			MaxRuntime: time.Duration(req.MaxRuntime) * time.Minute,
+		}
+		if req.Params != nil {
+			// New parameters are checked the same way as those of a new job
+			found := cleanHashParams(req.Params)
+
+			edited := current
+			edited.Parameters = stringParams(req.Params)
+			splitDraftHashes(req, current, &edited)
+			edit.Parameters = edited.Parameters
+			edit.Usernames = edited.Usernames
+			edit.NTHashes = edited.NTHashes
+
+			issues, refuse := a.checkJobInput(edited, found, req.Force)
+			resp.Issues = issues
+			if refuse {
+				resp.Status = RESP_CODE_BADREQ
+				resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_INPUT_INVALID)
+
+				rw.WriteHeader(RESP_CODE_BADREQ)
+				respJSON.Encode(resp)
+
+				log.WithFields(log.Fields{
+					"job":    jobid,
+					"issues": len(issues),
+				}).Info("Draft job edit refused because of problems with its input.")
+				return
+			}
+		}
+
+		err = a.Q.UpdateDraftJob(jobid, edit)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+	}
+
 	// Get the action requested
 	switch req.Status {
+	case common.STATUS_CREATED:
+		// Launch the job if it is a draft, otherwise paused jobs resume on their own
+		if draft {
+			err = a.Q.StartJob(jobid)
+			if err != nil {
+				resp.Status = RESP_CODE_ERROR
+				resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_START_FAILED, err.Error())
+
+				rw.WriteHeader(RESP_CODE_ERROR)
+				respJSON.Encode(resp)
+				return
+			}
+		}
 	case "pause":
 		// Pause the job
 		err = a.Q.PauseJob(jobid)
 		if err != nil {
 			resp.Status = RESP_CODE_ERROR
-			resp.Message = "Unable to pause the job: " + err.Error()
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_PAUSE_FAILED, err.Error())
 
 			rw.WriteHeader(RESP_CODE_ERROR)
 			respJSON.Encode(resp)
 			return
 		}
 	case "quit":
+		if req.Force {
+			if !admin {
+				resp.Status = RESP_CODE_UNAUTHORIZED
+				resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+				rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+				respJSON.Encode(resp)
+
+				log.WithField("user", user.Username).Warn("A non-administrator attempted to force quit a job.")
+				return
+			}
+
+			// Release the job even if the resource can't be reached
+			err = a.Q.ForceQuitJob(jobid)
+			if err != nil {
+				resp.Status = RESP_CODE_ERROR
+				resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_FORCESTOP_FAILED, err.Error())
+
+				rw.WriteHeader(RESP_CODE_ERROR)
+				respJSON.Encode(resp)
+				return
+			}
+
+			log.WithFields(log.Fields{
+				"job":  jobid,
+				"user": user.Username,
+			}).Warn("Job was force quit by an administrator.")
+			break
+		}
+
 		// Stop the job
 		err = a.Q.QuitJob(jobid)
 		if err != nil {
 			resp.Status = RESP_CODE_ERROR
-			resp.Message = "Unable to stop the job: " + err.Error()
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_STOP_FAILED, err.Error())
 
 			rw.WriteHeader(RESP_CODE_ERROR)
 			respJSON.Encode(resp)
@@ -703,10 +1760,10 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	// Now return everything is good and the job info
-	j := a.Q.JobInfo(jobid)
+	j, _ := a.Q.JobInfo(jobid)
 
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 	resp.Job.ID = j.UUID
 	resp.Job.Name = j.Name
 	resp.Job.Status = j.Status
@@ -723,25 +1780,245 @@ func (a *AppController) UpdateJob(rw http.ResponseWriter, r *http.Request) {
 	respJSON.Encode(resp)
 
 	log.WithFields(log.Fields{
-		"uuid":   j.UUID,
-		"name":   j.Name,
-		"status": j.Status,
+		"uuid":           j.UUID,
+		"name":           j.Name,
+		"status":         j.Status,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
 	}).Info("Job information updated.")
 }
 
+// Launch a draft job (POST - /api/jobs/{id}/start)
+func (a *AppController) StartJob(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobUpdateResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to start a job.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to start a job.")
+
+		return
+	}
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "start a job")
+	if !ok {
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	err := a.Q.StartJob(jobid)
+	if err != nil {
+		code := RESP_CODE_BADREQ
+		if err == queue.ErrJobNotFound {
+			code = RESP_CODE_NOTFOUND
+		}
+
+		resp.Status = code
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_START_FAILED, err.Error())
+
+		rw.WriteHeader(code)
+		respJSON.Encode(resp)
+		return
+	}
+
+	j, _ := a.Q.JobInfo(jobid)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Job.ID = j.UUID
+	resp.Job.Name = j.Name
+	resp.Job.Status = j.Status
+	resp.Job.ResourceID = j.ResAssigned
+	resp.Job.Owner = j.Owner
+	resp.Job.StartTime = j.StartTime
+	resp.Job.ETC = j.ETC
+	resp.Job.CrackedHashes = j.CrackedHashes
+	resp.Job.TotalHashes = j.TotalHashes
+	resp.Job.Progress = j.Progress
+	resp.Job.ToolID = j.ToolUUID
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":           j.UUID,
+		"name":           j.Name,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+	}).Info("Draft job started.")
+}
+
+// Hand a job to another user, only the owner or an Administrator may do this
+func (a *AppController) TransferJob(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req JobOwnerReq
+	var resp JobUpdateResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to transfer a job.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to transfer a job.")
+
+		return
+	}
+
+	// Administrators may transfer any job, so check before any impersonation
+	admin := user.Allowed(Administrator)
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "transfer a job")
+	if !ok {
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil || req.Owner == "" {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_OWNER_REQUIRED)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	j, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	if !admin && j.Owner != user.Username {
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_TRANSFER_DENIED)
+
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"uuid":  j.UUID,
+			"user":  user.Username,
+			"owner": j.Owner,
+		}).Warn("A user attempted to transfer a job they do not own.")
+
+		return
+	}
+
+	// Record who really made the change when impersonating
+	by := user.Username
+	if user.ImpersonatedBy != "" {
+		by = user.ImpersonatedBy + " as " + user.Username
+	}
+
+	j, err = a.Q.TransferJob(jobid, req.Owner, by)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Job.ID = j.UUID
+	resp.Job.Name = j.Name
+	resp.Job.Status = j.Status
+	resp.Job.ResourceID = j.ResAssigned
+	resp.Job.Owner = j.Owner
+	resp.Job.StartTime = j.StartTime
+	resp.Job.ETC = j.ETC
+	resp.Job.CrackedHashes = j.CrackedHashes
+	resp.Job.TotalHashes = j.TotalHashes
+	resp.Job.Progress = j.Progress
+	resp.Job.ToolID = j.ToolUUID
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"uuid":           j.UUID,
+		"name":           j.Name,
+		"owner":          j.Owner,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+	}).Info("Job ownership transferred.")
+}
+
 func (a *AppController) DeleteJob(rw http.ResponseWriter, r *http.Request) {
 	// Response and Request structures
 	var resp JobDeleteResp
 
 	// JSON Encoders and Decoders
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -755,24 +2032,66 @@ func (a *AppController) DeleteJob(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to delete a job.")
+
+		return
+	}
+
+	// Only Administrators can force a job to be removed
+	admin := user.Allowed(Administrator)
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "delete a job")
+	if !ok {
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	// Remove the job, forcing removal even if the resource can't be reached if asked
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	if force && !admin {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
 
-		log.WithField("username", user.Username).Warn("An unauthorized user attempted to delete a job.")
-
+		log.WithField("user", user.Username).Warn("A non-administrator attempted to force delete a job.")
 		return
 	}
 
-	// Get the ID of the job we want
-	jobid := mux.Vars(r)["id"]
+	var err error
+	if force {
+		err = a.Q.ForceRemoveJob(jobid)
+	} else {
+		err = a.Q.RemoveJob(jobid)
+	}
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_DELETE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if _, ok := err.(*queue.JobInUseError); ok {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_DELETE_FAILED, err.Error())
 
-	// Remove the job
-	err := a.Q.RemoveJob(jobid)
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
 	if err != nil {
 		resp.Status = RESP_CODE_ERROR
-		resp.Message = "An error occured while trying to delete a job: " + err.Error()
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_DELETE_FAILED, err.Error())
 
 		rw.WriteHeader(RESP_CODE_ERROR)
 		respJSON.Encode(resp)
@@ -787,13 +2106,16 @@ func (a *AppController) DeleteJob(rw http.ResponseWriter, r *http.Request) {
 
 	// Job should now be removed, so return all OK
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
 
 	log.WithFields(log.Fields{
-		"jobid": jobid,
+		"jobid":          jobid,
+		"user":           user.Username,
+		"impersonatedby": user.ImpersonatedBy,
+		"force":          force,
 	}).Info("Job deleted.")
 }
 
@@ -803,14 +2125,14 @@ func (a *AppController) ListResource(rw http.ResponseWriter, r *http.Request) {
 	var resp ResListResp
 
 	// JSON Encoders and Decoders
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -824,7 +2146,7 @@ func (a *AppController) ListResource(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -834,46 +2156,42 @@ func (a *AppController) ListResource(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// First we need to loop through all resource managers
-	for managerid, manager := range a.Q.AllResourceManagers() {
-		//Then  we need to loop through all resources controlled by the manager
-		for _, resourceid := range manager.GetManagedResources() {
-			resource, params, err := manager.GetResource(resourceid)
-
-			if err != nil {
-				log.WithField("resourceid", resourceid).Error("Unable to find resource in queue when provided by manager while gathering API resource list.")
-				continue
-			}
-
-			var outresource APIResource
-			outresource.Manager = managerid
-			outresource.ID = resourceid
-			outresource.Name = resource.Name
-			outresource.Status = resource.Status
-			outresource.Address = resource.Address
-			outresource.Params = params
-
-			for _, t := range resource.Tools {
-				outresource.Tools = append(outresource.Tools, APITool{t.UUID, t.Name, t.Version})
-			}
+	// Resources of every manager are read from the queue snapshot
+	now := time.Now()
+	for _, resource := range a.Q.Snapshot().Resources {
+		var outresource APIResource
+		outresource.Manager = resource.Manager
+		outresource.ID = resource.ID
+		outresource.Name = resource.Name
+		outresource.Status = resource.Status
+		outresource.Unresponsive = resource.Client.Tripped()
+		outresource.Exclusive = resource.Exclusive
+		outresource.CostPerHour = resource.CostPerHour
+	outresource.ClockOffset, outresource.ClockRead = newAPIClock(resource.ClockOffset, resource.ClockRead)
+		outresource.Profiles = newAPIProfiles(resource.Profiles)
+		outresource.Profile = resource.ActiveProfile(now)
+		outresource.Address = resource.Address
+		outresource.Params = resource.Params
+
+		for _, t := range resource.Tools {
+			outresource.Tools = append(outresource.Tools, APITool{ID: t.UUID, Name: t.Name, Version: t.Version})
+		}
 
-			resp.Resources = append(resp.Resources, outresource)
+		resp.Resources = append(resp.Resources, outresource)
 
-			log.WithFields(log.Fields{
-				"id":      resourceid,
-				"name":    resource.Name,
-				"addr":    resource.Address,
-				"manager": managerid,
-			}).Debug("Gathered resource information.")
-		}
+		log.WithFields(log.Fields{
+			"id":      resource.ID,
+			"name":    resource.Name,
+			"addr":    resource.Address,
+			"manager": resource.Manager,
+		}).Debug("Gathered resource information.")
 	}
 
 	// Job should now be removed, so return all OK
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
-	rw.WriteHeader(RESP_CODE_OK)
-	respJSON.Encode(resp)
+	writeWithETag(rw, r, resp)
 
 	log.Info("Listing of resources provided to API.")
 }
@@ -885,14 +2203,14 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 
 	// JSON Encoders and Decoders
 	reqJSON := json.NewDecoder(r.Body)
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -906,7 +2224,7 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(Administrator) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -920,7 +2238,7 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 	err := reqJSON.Decode(&req)
 	if err != nil {
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
@@ -937,7 +2255,7 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 	//If that resource manager doesn't exist, return a not found error
 	if !ok {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "That resource manager does not exist."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
@@ -951,11 +2269,12 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 
 	// Now let's try and add the resource itself.
 	err = manager.AddResource(req.Params)
+	a.Q.InvalidateSnapshot() // Managers keep their own parameters
 
 	// If there was an error returned by the resource manager, let's go ahead and return an error to the user.
 	if err != nil {
 		resp.Status = RESP_CODE_ERROR
-		resp.Message = "An error occured when trying to add the resource: " + err.Error()
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_ADD_FAILED, err.Error())
 
 		rw.WriteHeader(RESP_CODE_ERROR)
 		respJSON.Encode(resp)
@@ -970,7 +2289,7 @@ func (a *AppController) CreateResource(rw http.ResponseWriter, r *http.Request)
 
 	// At this point, the resource should be added, we can return success.
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
@@ -985,14 +2304,14 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	var resp ResReadResp
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1006,7 +2325,7 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(StandardUser) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1024,24 +2343,26 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	manager, ok := a.Q.GetResourceManager(managerName)
 	if !ok {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "The requested resource manager was not found."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
 
 		log.WithField("resource", resID).Warn("Resource manager details could not be found.")
+		return
 	}
 
 	// Get the resource
 	resource, params, err := manager.GetResource(resID)
 	if err != nil {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "The requested resource was not found."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
 
 		log.WithField("resource", resID).Warn("Resource details were requested and could not be found.")
+		return
 	}
 
 	// Found the resource so set it to the response
@@ -1049,9 +2370,31 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	resp.Resource.Name = resource.Name
 	resp.Resource.Address = resource.Address
 	resp.Resource.Status = resource.Status
+	resp.Resource.Unresponsive = resource.Client.Tripped()
+	resp.Resource.Exclusive = resource.Exclusive
+	resp.Resource.CostPerHour = resource.CostPerHour
+	resp.Resource.ClockOffset, resp.Resource.ClockRead = newAPIClock(resource.ClockOffset, resource.ClockRead)
+	resp.Resource.Profiles = newAPIProfiles(resource.Profiles)
+	resp.Resource.Profile = resource.ActiveProfile(time.Now())
 	resp.Resource.Params = params
 	resp.Resource.Manager = manager.SystemName()
 
+	inv := APIInventory{
+		GPUs:     []APIGPU{},
+		CUDA:     resource.Inventory.CUDA,
+		CPUModel: resource.Inventory.CPUModel,
+		CPUCores: resource.Inventory.CPUCores,
+		Memory:   resource.Inventory.Memory,
+		Binaries: resource.Inventory.Binaries,
+	}
+	if resource.Inventory.OS != "" {
+		inv.Platform = resource.Inventory.OS + "/" + resource.Inventory.Arch
+	}
+	for _, g := range resource.Inventory.GPUs {
+		inv.GPUs = append(inv.GPUs, APIGPU{Model: g.Model, Memory: g.Memory, Driver: g.Driver})
+	}
+	resp.Resource.Inventory = &inv
+
 	log.WithFields(log.Fields{
 		"uuid":    resID,
 		"name":    resource.Name,
@@ -1060,7 +2403,7 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	}).Debug("Gathered resource information.")
 
 	for _, t := range resource.Tools {
-		resp.Resource.Tools = append(resp.Resource.Tools, APITool{t.UUID, t.Name, t.Version})
+		resp.Resource.Tools = append(resp.Resource.Tools, APITool{ID: t.UUID, Name: t.Name, Version: t.Version})
 		log.WithFields(log.Fields{
 			"uuid": t.UUID,
 			"name": t.Name,
@@ -1068,11 +2411,9 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 		}).Debug("Tool configured on resource gathered.")
 	}
 
-	// TODO (mcatee): Add a check for no found resource and return correct status codes
-
 	// Build good response
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
@@ -1080,6 +2421,219 @@ func (a *AppController) ReadResource(rw http.ResponseWriter, r *http.Request) {
 	log.WithField("name", resp.Resource.Name).Info("Information gathered on resource.")
 }
 
+// Read the recent logs of a resource (GET - /api/resources/{id}/logs?lines=N&task=ID)
+func (a *AppController) ReadResourceLogs(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ResLogsResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read resource logs.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to read resource logs.")
+
+		return
+	}
+
+	resID := mux.Vars(r)["id"]
+	task := r.URL.Query().Get("task")
+
+	// Default to a reasonable number of lines and cap what can be requested
+	lines := defaultLogLines
+	if l := r.URL.Query().Get("lines"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_LOGLINES_INVALID)
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		lines = n
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	out, err := a.Q.ResourceLogs(resID, task, lines)
+	if err == queue.ErrResourceNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_LOGS_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"resource": resID,
+			"task":     task,
+			"error":    err.Error(),
+		}).Error("An error occured while trying to read resource logs.")
+
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Lines = out
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"resource": resID,
+		"task":     task,
+		"user":     user.Username,
+	}).Debug("Resource logs gathered.")
+}
+
+// Have a resource load its tools again and report its inventory (POST - /api/resources/{id}/rescan)
+func (a *AppController) RescanResource(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ResRescanResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to rescan a resource.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to rescan a resource.")
+
+		return
+	}
+
+	resID := mux.Vars(r)["id"]
+	resource, err := a.Q.RescanResource(resID)
+	if err == queue.ErrResourceNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrResourceOffline {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_RESCAN_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Tools that did load are reported even when others failed
+	resp.Resource.ID = resID
+	resp.Resource.Name = resource.Name
+	resp.Resource.Address = resource.Address
+	resp.Resource.Status = resource.Status
+	resp.Resource.Unresponsive = resource.Client.Tripped()
+	resp.Resource.Exclusive = resource.Exclusive
+	resp.Resource.CostPerHour = resource.CostPerHour
+	resp.Resource.ClockOffset, resp.Resource.ClockRead = newAPIClock(resource.ClockOffset, resource.ClockRead)
+	resp.Resource.Profiles = newAPIProfiles(resource.Profiles)
+	resp.Resource.Profile = resource.ActiveProfile(time.Now())
+	resp.Resource.Tools = []APITool{}
+	for _, t := range resource.Tools {
+		resp.Resource.Tools = append(resp.Resource.Tools, APITool{ID: t.UUID, Name: t.Name, Version: t.Version})
+	}
+
+	inv := APIInventory{
+		GPUs:     []APIGPU{},
+		CUDA:     resource.Inventory.CUDA,
+		CPUModel: resource.Inventory.CPUModel,
+		CPUCores: resource.Inventory.CPUCores,
+		Memory:   resource.Inventory.Memory,
+		Binaries: resource.Inventory.Binaries,
+	}
+	if resource.Inventory.OS != "" {
+		inv.Platform = resource.Inventory.OS + "/" + resource.Inventory.Arch
+	}
+	for _, g := range resource.Inventory.GPUs {
+		inv.GPUs = append(inv.GPUs, APIGPU{Model: g.Model, Memory: g.Memory, Driver: g.Driver})
+	}
+	resp.Resource.Inventory = &inv
+	resp.Files = len(resource.Inventory.Files)
+
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_RESCAN_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"resource": resID,
+			"error":    err.Error(),
+		}).Error("Resource tools could not all be rescanned.")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"resource": resID,
+		"tools":    len(resp.Resource.Tools),
+	}).Info("Resource tools rescanned.")
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
 func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request) {
 	// Response and Request structures
 	var req ResUpdateReq
@@ -1087,14 +2641,14 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 
 	// JSON Encoder and Decoder
 	reqJSON := json.NewDecoder(r.Body)
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// Get the authorization header
 	token := r.Header.Get("AuthorizationToken")
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1108,7 +2662,7 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(Administrator) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1122,7 +2676,7 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 	err := reqJSON.Decode(&req)
 	if err != nil {
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
@@ -1142,7 +2696,7 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 	//If that resource manager doesn't exist, return a not found error
 	if !manok {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "That resource manager does not exist."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
@@ -1155,6 +2709,22 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Make sure the resource exists before trying to change it
+	if _, err := a.Q.GetResource(resID); err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"manager":  manager.SystemName(),
+			"resource": resID,
+		}).Warn("Unable to find requested resource to update.")
+
+		return
+	}
+
 	switch req.Status {
 	case common.STATUS_QUIT:
 		log.WithFields(log.Fields{
@@ -1165,9 +2735,10 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 
 		// Quit the resource
 		err = manager.DeleteResource(resID)
+		a.Q.InvalidateSnapshot() // Managers keep their own parameters
 		if err != nil {
 			resp.Status = RESP_CODE_ERROR
-			resp.Message = "An error occured while trying to quit that resource: " + err.Error()
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_QUIT_FAILED, err.Error())
 
 			rw.WriteHeader(RESP_CODE_ERROR)
 			respJSON.Encode(resp)
@@ -1189,9 +2760,10 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 
 		// Pause or resume the resource
 		err = manager.UpdateResource(resID, req.Status, req.Params)
+		a.Q.InvalidateSnapshot() // Managers keep their own parameters
 		if err != nil {
 			resp.Status = RESP_CODE_ERROR
-			resp.Message = "An error occured while trying to update that resource: " + err.Error()
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
 
 			rw.WriteHeader(RESP_CODE_ERROR)
 			respJSON.Encode(resp)
@@ -1206,9 +2778,35 @@ func (a *AppController) UpdateResource(rw http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// Exclusive mode is kept by the queue whatever manager the resource has
+	if req.Exclusive != nil {
+		err = a.Q.SetResourceExclusive(resID, *req.Exclusive)
+		if err != nil {
+			resp.Status = RESP_CODE_ERROR
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_ERROR)
+			respJSON.Encode(resp)
+			return
+		}
+	}
+
+	// The cost is used by the queue for scheduling whatever manager the resource has
+	if req.CostPerHour != nil {
+		err = a.Q.SetResourceCost(resID, *req.CostPerHour)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+	}
+
 	// Build good response because we were able to get here
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
@@ -1225,7 +2823,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 	var req ResDeleteReq
 
 	// JSON Encoder and Decoder
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 	reqJSON := json.NewDecoder(r.Body)
 
 	// Get the authorization header
@@ -1233,7 +2831,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 
 	if !a.T.CheckToken(token) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1247,7 +2845,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 	user, _ := a.T.GetUser(token)
 	if !user.Allowed(Administrator) {
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1261,7 +2859,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 	err := reqJSON.Decode(&req)
 	if err != nil {
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
@@ -1281,7 +2879,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 	//If that resource manager doesn't exist, return a not found error
 	if !manok {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "That resource manager does not exist."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
@@ -1295,30 +2893,31 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 	}
 
 	// Get the resource
-	resource, _, err := manager.GetResource(resID)
+	_, _, err = manager.GetResource(resID)
 
 	// If that resource doesn't exist, let's throw an error
 	if err != nil {
 		resp.Status = RESP_CODE_NOTFOUND
-		resp.Message = "That resource does not exist."
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
 
 		rw.WriteHeader(RESP_CODE_NOTFOUND)
 		respJSON.Encode(resp)
 
 		log.WithFields(log.Fields{
-			"manager": manager.SystemName(),
-			"addr":    resource.Address,
-			"name":    resource.Name,
-		}).Warn("Unable to find requested resource to update.")
+			"manager":  manager.SystemName(),
+			"resource": resID,
+			"error":    err.Error(),
+		}).Warn("Unable to find requested resource to delete.")
 
 		return
 	}
 
 	// Remove the resource
 	err = manager.DeleteResource(resID)
+	a.Q.InvalidateSnapshot() // Managers keep their own parameters
 	if err != nil {
 		resp.Status = RESP_CODE_ERROR
-		resp.Message = "An error occured while trying to delete that resource: " + err.Error()
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_DELETE_FAILED, err.Error())
 
 		rw.WriteHeader(RESP_CODE_ERROR)
 		respJSON.Encode(resp)
@@ -1333,7 +2932,7 @@ func (a *AppController) DeleteResources(rw http.ResponseWriter, r *http.Request)
 
 	// Build good response
 	resp.Status = RESP_CODE_OK
-	resp.Message = RESP_CODE_OK_T
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
 
 	rw.WriteHeader(RESP_CODE_OK)
 	respJSON.Encode(resp)
@@ -1353,14 +2952,14 @@ func (a *AppController) ReorderQueue(rw http.ResponseWriter, r *http.Request) {
 	// A decoder to take the JSON information passed by the API and return it
 	reqJSON := json.NewDecoder(r.Body)
 	// An encoder to take our response and give it back to the user
-	respJSON := json.NewEncoder(rw)
+	respJSON := newRespEncoder(rw)
 
 	// First, we handle authentication through the header
 	token := r.Header.Get("AuthorizationToken")
 	if !a.T.CheckToken(token) {
 		//If the token is unknown, send back an unauthenticated message
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1370,12 +2969,13 @@ func (a *AppController) ReorderQueue(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Let's then check to make sure the user has the right group, in this case standard
+	// Let's then check to make sure the user has the right group, in this case an administrator as
+	// the order is that of the jobs of every user
 	user, _ := a.T.GetUser(token)
-	if !user.Allowed(StandardUser) {
+	if !user.Allowed(Administrator) {
 		//If not, send back the proper response.
 		resp.Status = RESP_CODE_UNAUTHORIZED
-		resp.Message = RESP_CODE_UNAUTHORIZED_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
 
 		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
 		respJSON.Encode(resp)
@@ -1390,7 +2990,7 @@ func (a *AppController) ReorderQueue(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// If there is an error, let the API know via HTTP
 		resp.Status = RESP_CODE_BADREQ
-		resp.Message = RESP_CODE_BADREQ_T
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
 
 		rw.WriteHeader(RESP_CODE_BADREQ)
 		respJSON.Encode(resp)
@@ -1405,7 +3005,7 @@ func (a *AppController) ReorderQueue(rw http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		//If there was an error, send the code to the API
 		resp.Status = RESP_CODE_ERROR
-		resp.Message = err.Error()
+		resp.Message, resp.MessageKey = err.Error(), MSG_ERROR
 
 		rw.WriteHeader(RESP_CODE_ERROR)
 		respJSON.Encode(resp)
@@ -1418,3 +3018,86 @@ func (a *AppController) ReorderQueue(rw http.ResponseWriter, r *http.Request) {
 	// Finally, we did it successfully!
 	log.Info("Queue reodered successfully")
 }
+
+// Plan where the queue would run a set of hypothetical jobs and when they
+// would finish without creating them (POST - /api/queue/simulate)
+func (a *AppController) SimulateQueue(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req QueueSimulateReq
+	var resp QueueSimulateResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to simulate the queue.")
+		return
+	}
+
+	// Only Administrators plan the capacity of the queue
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to simulate the queue.")
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	var jobs []common.Job
+	for _, j := range req.Jobs {
+		jobs = append(jobs, newRequestJob(j, user.Username))
+	}
+
+	plan := a.Q.Simulate(jobs)
+
+	resp.Jobs = make([]APIPlannedJob, 0, len(plan.Jobs))
+	for _, p := range plan.Jobs {
+		resp.Jobs = append(resp.Jobs, APIPlannedJob{
+			JobID:        p.JobUUID,
+			Name:         p.Name,
+			Hypothetical: p.Hypothetical,
+			ResourceID:   p.ResourceUUID,
+			ResourceName: p.ResourceName,
+			Hardware:     p.Hardware,
+			Start:        p.Start,
+			Finish:       p.Finish,
+			Estimated:    p.Estimated,
+			Error:        p.Error,
+		})
+	}
+	resp.Finish = plan.Finish
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"user": user.Username,
+		"jobs": len(jobs),
+	}).Info("Queue simulation planned.")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// An API with an empty queue and a logged in Standard User whose token is
+// returned
+func testController(t *testing.T) (*AppController, string) {
+	a := &AppController{
+		T: NewTokenStore(),
+		M: NewMessageCatalog(),
+		Q: queue.NewQueue(filepath.Join(t.TempDir(), "state.json"), 60, 5, 0),
+	}
+
+	token := "test-token"
+	a.T.AddToken(token, User{Username: "alice", Groups: []string{StandardUser}})
+	return a, token
+}
+
+// Send a request with a token through the router of the API
+func apiRequest(a *AppController, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	r := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		r.Header.Set("AuthorizationToken", token)
+	}
+
+	rw := httptest.NewRecorder()
+	a.Router().ServeHTTP(rw, r)
+	return rw
+}
+
+// Put a draft job in the queue without a resource for its tool
+func testDraft(t *testing.T, a *AppController, params map[string]string) common.Job {
+	j := common.NewJob("tool", "Draft", "alice", params)
+	testDraftJob(t, a, j)
+	return j
+}
+
+func testDraftJob(t *testing.T, a *AppController, j common.Job) {
+	j.Status = common.STATUS_DRAFT
+
+	_, err := a.Q.Import(queue.Bundle{
+		Format:    queue.BUNDLE_FORMAT,
+		Exported:  time.Now(),
+		StateFile: queue.StateFile{Stack: []common.Job{j}},
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateDraftChecksInput(t *testing.T) {
+	a, token := testController(t)
+	j := testDraft(t, a, map[string]string{"hashes": "aaaa"})
+
+	// Hashes the tool would fail on are refused like they are when a job is
+	// created
+	rw := apiRequest(a, "PUT", "/api/jobs/"+j.UUID, token, JobUpdateReq{
+		Params: map[string]interface{}{"hashes": "\n\n"},
+	})
+	if rw.Code != RESP_CODE_BADREQ {
+		t.Fatalf("Draft without hashes gave %d: %s", rw.Code, rw.Body.String())
+	}
+	var resp JobUpdateResp
+	json.NewDecoder(rw.Body).Decode(&resp)
+	if len(resp.Issues) != 1 || resp.Issues[0].Code != "hashes.empty" {
+		t.Errorf("Unexpected issues %+v", resp.Issues)
+	}
+	if got, _ := a.Q.JobInfo(j.UUID); got.Parameters["hashes"] != "aaaa" {
+		t.Errorf("Refused edit changed the hashes to %q", got.Parameters["hashes"])
+	}
+
+	// Hashes that can be fixed are cleaned before they are saved
+	rw = apiRequest(a, "PUT", "/api/jobs/"+j.UUID, token, JobUpdateReq{
+		Params: map[string]interface{}{"hashes": "bbbb\r\ncccc\r\n"},
+	})
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Draft edit gave %d: %s", rw.Code, rw.Body.String())
+	}
+	if got, _ := a.Q.JobInfo(j.UUID); got.Parameters["hashes"] != "bbbb\ncccc\n" {
+		t.Errorf("Edited hashes were not cleaned: %q", got.Parameters["hashes"])
+	}
+}
+
+func TestUpdateDraftSplitsHashes(t *testing.T) {
+	a, token := testController(t)
+	j := common.NewJob("tool", "Draft", "alice", map[string]string{})
+	j.Parameters["hashes"], j.Usernames = common.SplitUsernames("bob:aaaa")
+	testDraftJob(t, a, j)
+
+	// A draft made with usernames splits its new hashes the same way
+	rw := apiRequest(a, "PUT", "/api/jobs/"+j.UUID, token, JobUpdateReq{
+		Params: map[string]interface{}{"hashes": "carol:bbbb\ndave:cccc"},
+	})
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Draft edit gave %d: %s", rw.Code, rw.Body.String())
+	}
+	got, _ := a.Q.JobInfo(j.UUID)
+	if got.Parameters["hashes"] != "bbbb\ncccc" {
+		t.Errorf("Tool was given %q", got.Parameters["hashes"])
+	}
+	if len(got.Usernames) != 2 || got.Usernames["bbbb"][0] != "carol" || got.Usernames["aaaa"] != nil {
+		t.Errorf("Usernames were not split again: %v", got.Usernames)
+	}
+
+	// Pwdump lines with LM hashes are cracked LM first when asked
+	line := "erin:1001:e52cac67419a9a224a3b108f3fa6cb6d:8846f7eaee8fb117ad06bdd830b7586c:::"
+	rw = apiRequest(a, "PUT", "/api/jobs/"+j.UUID, token, JobUpdateReq{
+		Params: map[string]interface{}{"hashes": line},
+		LMNT:   true,
+	})
+	if rw.Code != http.StatusOK {
+		t.Fatalf("Draft edit gave %d: %s", rw.Code, rw.Body.String())
+	}
+	got, _ = a.Q.JobInfo(j.UUID)
+	if got.Parameters["hashes"] != "e52cac67419a9a224a3b108f3fa6cb6d" || len(got.NTHashes) != 1 {
+		t.Errorf("LM hashes were not split: %q %v", got.Parameters["hashes"], got.NTHashes)
+	}
+}
+
+func TestImpersonationNeedsAdministrator(t *testing.T) {
+	a, token := testController(t)
+	j := testDraft(t, a, map[string]string{"hashes": "aaaa"})
+
+	r := httptest.NewRequest("DELETE", "/api/jobs/"+j.UUID, nil)
+	r.Header.Set("AuthorizationToken", token)
+	r.Header.Set(ImpersonateHeader, "bob")
+	rw := httptest.NewRecorder()
+	a.Router().ServeHTTP(rw, r)
+
+	var resp ErrorResp
+	json.NewDecoder(rw.Body).Decode(&resp)
+	if rw.Code != RESP_CODE_UNAUTHORIZED || resp.Status != RESP_CODE_UNAUTHORIZED || resp.MessageKey != MSG_UNAUTHORIZED {
+		t.Errorf("Standard User impersonating gave %d %+v", rw.Code, resp)
+	}
+	if _, err := a.Q.JobInfo(j.UUID); err != nil {
+		t.Errorf("Job was deleted by a refused impersonation: %v", err)
+	}
+}
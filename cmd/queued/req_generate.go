@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/jmmcatee/cracklord/common/shared"
+	"github.com/jmmcatee/cracklord/common/wordlist"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Folder within the wordlist directory and the shared bucket where generated
+// wordlists are stored
+const generatedWordlistDir = "generated"
+
+// Copy a generated wordlist to the shared bucket so resources can use it,
+// returning the path they use for it or empty if there is no bucket
+func publishWordlist(full string) (string, error) {
+	if queue.Shared == nil {
+		return "", nil
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	key := path.Join(generatedWordlistDir, filepath.Base(full))
+	if err := queue.Shared.Put(key, f, info.Size()); err != nil {
+		return "", err
+	}
+
+	return shared.Scheme + key, nil
+}
+
+// List the wordlists generated from terms or crawls (GET - /api/wordlists/generated)
+func (a *AppController) ListGeneratedWordlists(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp GeneratedWordlistsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to list generated wordlists.")
+
+		return
+	}
+
+	resp.Wordlists = []APIGeneratedWordlist{}
+	if a.WordlistDir != "" {
+		files, _ := ioutil.ReadDir(filepath.Join(a.WordlistDir, generatedWordlistDir))
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".txt" {
+				continue
+			}
+
+			resp.Wordlists = append(resp.Wordlists, APIGeneratedWordlist{
+				Name:     strings.TrimSuffix(f.Name(), ".txt"),
+				Path:     path.Join(generatedWordlistDir, f.Name()),
+				Size:     f.Size(),
+				Modified: f.ModTime(),
+			})
+		}
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Generate a targeted wordlist from company names, seasons, years and keywords
+// with leetspeak and prefix and suffix mutations. The list is kept in the
+// wordlist directory and, when there is a shared bucket, stored in it so
+// resources can offer it to jobs. (PUT - /api/wordlists/generated/{name})
+func (a *AppController) GenerateWordlist(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req WordlistGenerateReq
+	var resp WordlistGenerateResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to generate a wordlist.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to generate a wordlist.")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if a.WordlistDir == "" || !regModelName.MatchString(name) {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_MODEL_NAME_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a wordlist generation request.")
+
+		return
+	}
+
+	terms := wordlist.Terms{
+		Companies: req.Companies,
+		Keywords:  req.Keywords,
+		Seasons:   req.Seasons,
+		Years:     req.Years,
+		Leet:      req.Leet,
+		Appends:   req.Appends,
+		Prepends:  req.Prepends,
+	}
+
+	dir := filepath.Join(a.WordlistDir, generatedWordlistDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Write to a temporary file first so a failed generation can't replace a list
+	tmp, err := ioutil.TempFile(dir, ".generate-")
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	count, err := wordlist.Generate(terms, tmp)
+	tmp.Close()
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_INVALID, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	full := filepath.Join(dir, name+".txt")
+	key := path.Join(generatedWordlistDir, name+".txt")
+
+	err = os.Rename(tmp.Name(), full)
+	if err == nil {
+		resp.Wordlist.Shared, err = publishWordlist(full)
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"name":  name,
+			"error": err.Error(),
+		}).Error("Unable to store generated wordlist.")
+
+		return
+	}
+
+	resp.Wordlist.Name = name
+	resp.Wordlist.Path = key
+	resp.Wordlist.Candidates = count
+	if info, err := os.Stat(full); err == nil {
+		resp.Wordlist.Size = info.Size()
+		resp.Wordlist.Modified = info.ModTime()
+	}
+
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"username":   user.Username,
+		"name":       name,
+		"candidates": count,
+		"shared":     resp.Wordlist.Shared != "",
+	}).Info("Wordlist generated.")
+}
+
+// Crawl sites for candidate words in the background, in the way of CeWL. The
+// wordlist is kept with the generated ones and the task is checked on with
+// the other wordlist tasks. (POST - /api/wordlists/crawl)
+func (a *AppController) CreateWordlistCrawl(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req WordlistCrawlReq
+	var resp WordlistProcessResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to crawl for a wordlist.")
+
+		return
+	}
+
+	// Crawls are made from the queue server so only administrators can start them
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to crawl for a wordlist.")
+
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a wordlist crawl request.")
+
+		return
+	}
+
+	if a.WordlistDir == "" || !regModelName.MatchString(req.Name) {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_MODEL_NAME_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		return
+	}
+
+	opts := wordlist.CrawlOptions{
+		URLs:      req.URLs,
+		Depth:     req.Depth,
+		MaxPages:  req.MaxPages,
+		MinLength: req.MinLength,
+		MaxLength: req.MaxLength,
+		Lower:     req.Lower,
+		Publish:   publishWordlist,
+	}
+	if err := opts.Validate(); err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_INVALID, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	dir := filepath.Join(a.WordlistDir, generatedWordlistDir)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_GENERATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.ID = a.W.StartCrawl(opts, filepath.Join(dir, req.Name+".txt"))
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"id":       resp.ID,
+		"username": user.Username,
+		"urls":     len(req.URLs),
+	}).Info("Wordlist crawl requested.")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+)
+
+// Liveness check, if the server can answer at all it is alive (GET - /healthz)
+func (a *AppController) Healthz(rw http.ResponseWriter, r *http.Request) {
+	resp := HealthResp{
+		Status:     RESP_CODE_OK,
+		MessageKey: MSG_OK,
+		Message:    RESP_CODE_OK_T,
+	}
+
+	rw.WriteHeader(RESP_CODE_OK)
+	newRespEncoder(rw).Encode(resp)
+}
+
+// Readiness check for the authentication backend and the queue (GET - /readyz)
+func (a *AppController) Readyz(rw http.ResponseWriter, r *http.Request) {
+	resp := HealthResp{
+		Status:     RESP_CODE_OK,
+		MessageKey: MSG_OK,
+		Message:    RESP_CODE_OK_T,
+		Checks:     map[string]string{},
+	}
+
+	check := func(name string, err error) {
+		if err == nil {
+			resp.Checks[name] = RESP_CODE_OK_T
+			return
+		}
+
+		resp.Checks[name] = err.Error()
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAVAILABLE)
+
+		log.WithFields(log.Fields{
+			"check": name,
+			"error": err.Error(),
+		}).Warn("Readiness check failed.")
+	}
+
+	if hc, ok := a.Auth.(HealthChecker); ok {
+		check("auth", hc.Healthy())
+	} else {
+		check("auth", nil)
+	}
+
+	if hc, ok := a.T.(HealthChecker); ok {
+		check("tokens", hc.Healthy())
+	}
+
+	check("queue", a.Q.Ready())
+
+	rw.WriteHeader(resp.Status)
+	newRespEncoder(rw).Encode(resp)
+}
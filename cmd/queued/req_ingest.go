@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/kerberoast"
+	"github.com/jmmcatee/cracklord/common/ntds"
+	"net/http"
+	"strings"
+)
+
+// Parse secretsdump or NTDS output and optionally create a job from the chosen
+// subsets of it (POST - /api/ingest/ntds)
+func (a *AppController) IngestNTDS(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req NTDSIngestReq
+	var resp NTDSIngestResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.Warn("An unknown token attempted to ingest an NTDS dump.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to ingest an NTDS dump.")
+		return
+	}
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "ingest an NTDS dump")
+	if !ok {
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	accounts, err := ntds.Parse(strings.NewReader(req.Dump))
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_INGEST_DUMP_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	filter := ntds.Filter{
+		Disabled: req.Disabled,
+		History:  req.History,
+		Machine:  req.Machine,
+	}
+
+	s := ntds.Summarize(accounts, filter)
+	resp.Summary = APINTDSSummary{
+		Accounts: s.Accounts,
+		Enabled:  s.Enabled,
+		Disabled: s.Disabled,
+		History:  s.History,
+		Machine:  s.Machine,
+		LM:       s.LM,
+		Selected: s.Selected,
+	}
+
+	// Without a job only the summary is returned so the subsets can be chosen
+	if req.Job != nil {
+		if s.Selected == 0 {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_INGEST_NOHASHES)
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+
+		job := newRequestJob(*req.Job, user.Username)
+		job.SubmittedBy = submittedBy(user)
+		job.Parameters["hashes"] = ntds.Lines(accounts, filter)
+
+		// The dump always has usernames
+		req.Job.Usernames = true
+		splitRequestHashes(*req.Job, &job)
+
+		err = a.Q.AddJob(job)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_CREATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		resp.JobID = job.UUID
+
+		log.WithFields(log.Fields{
+			"uuid":           job.UUID,
+			"name":           job.Name,
+			"owner":          job.Owner,
+			"impersonatedby": user.ImpersonatedBy,
+			"hashes":         s.Selected,
+		}).Info("New job created from an NTDS dump.")
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Parse Rubeus or GetUserSPNs output and optionally create a job for each
+// encryption type found in it (POST - /api/ingest/kerberos)
+func (a *AppController) IngestKerberos(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req KerberosIngestReq
+	var resp KerberosIngestResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.Warn("An unknown token attempted to ingest Kerberos tickets.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to ingest Kerberos tickets.")
+		return
+	}
+
+	// Check if an Administrator is acting on behalf of another user
+	user, ok := a.actingUser(rw, r, user, "ingest Kerberos tickets")
+	if !ok {
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	tickets, err := kerberoast.Parse(strings.NewReader(req.Dump))
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_INGEST_TICKETS_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	groups, unsupported := kerberoast.Split(tickets)
+	resp.Unsupported = len(unsupported)
+	resp.Groups = []APIKerberosGroup{}
+	for _, g := range groups {
+		resp.Groups = append(resp.Groups, APIKerberosGroup{
+			Type:  g.Type,
+			Etype: g.Etype,
+			Mode:  g.Mode,
+			Count: len(g.Tickets),
+		})
+	}
+
+	// Without a job only the summary is returned
+	if req.Job != nil {
+		if len(groups) == 0 {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_INGEST_NOTICKETS)
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+
+		// Each encryption type needs its own hashcat mode and so its own job
+		var jobs []common.Job
+		for _, g := range groups {
+			job := newRequestJob(*req.Job, user.Username)
+			job.SubmittedBy = submittedBy(user)
+			job.Parameters["hashes"] = g.Hashes()
+			job.Parameters["algorithm"] = g.Mode
+			if len(groups) > 1 {
+				job.Name = fmt.Sprintf("%s (%s etype %d)", req.Job.Name, strings.ToUpper(g.Type), g.Etype)
+			}
+
+			jobs = append(jobs, job)
+		}
+
+		errs, err := a.Q.AddJobs(jobs)
+		for i := range jobs {
+			if errs[i] != nil {
+				resp.Groups[i].Error = errs[i].Error()
+			} else if err == nil {
+				resp.Groups[i].JobID = jobs[i].UUID
+			}
+		}
+
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOBS_CREATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+
+		log.WithFields(log.Fields{
+			"owner":          user.Username,
+			"impersonatedby": user.ImpersonatedBy,
+			"jobs":           len(jobs),
+			"tickets":        len(tickets) - len(unsupported),
+		}).Info("New jobs created from Kerberos tickets.")
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Check the hashes of a create request for mistakes, removing byte order
+// marks and Windows line endings so they are fixed before the hashes are
+// split into usernames
+func cleanRequestHashes(req *JobCreateReq) []common.InputIssue {
+	return cleanHashParams(req.Params)
+}
+
+// Check and clean the hashes in the parameters of a request, which are also
+// given when a draft job is edited
+func cleanHashParams(params map[string]interface{}) []common.InputIssue {
+	hashes, ok := params["hashes"].(string)
+	if !ok {
+		return nil
+	}
+
+	hashes, issues := common.CheckHashes(hashes)
+	params["hashes"] = hashes
+
+	return issues
+}
+
+// Add the issues the tool of a job finds to those found in its hashes. The
+// job should be refused if the last value is true.
+func (a *AppController) checkJobInput(job common.Job, found []common.InputIssue, force bool) ([]APIInputIssue, bool) {
+	issues := append(found, a.Q.CheckJobInput(job)...)
+
+	out := []APIInputIssue{}
+	for _, i := range issues {
+		out = append(out, APIInputIssue{
+			Severity: i.Severity,
+			Code:     i.Code,
+			Message:  i.Message,
+			Lines:    i.Lines,
+			Count:    i.Count,
+		})
+	}
+
+	return out, !force && common.HasInputErrors(issues)
+}
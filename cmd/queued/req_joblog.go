@@ -0,0 +1,125 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Read the debug log of a job created with debugging enabled, which is the
+// scheduling decisions of the queue and the resource along with the full
+// output of the tool (GET - /api/jobs/{id}/log)
+func (a *AppController) ReadJobLog(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobLogResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read a job debug log.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to read a job debug log.")
+
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	j, err := a.Q.JobInfo(jobid)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// The output of the tool may show the hashes, so only the owner may read it
+	if !user.Allowed(Administrator) && j.Owner != user.Username {
+		resp.Status = RESP_CODE_FORBIDDEN
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_LOG_DENIED)
+
+		rw.WriteHeader(RESP_CODE_FORBIDDEN)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"uuid":  j.UUID,
+			"user":  user.Username,
+			"owner": j.Owner,
+		}).Warn("A user attempted to read the debug log of a job they do not own.")
+
+		return
+	}
+
+	jl, err := a.Q.JobLog(jobid)
+	switch err {
+	case nil:
+	case queue.ErrJobNotDebugged:
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_LOG_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	default:
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Queue = jl.Events
+	resp.Resource = jl.Resource.Events
+	resp.Output = jl.Resource.Output
+	if resp.Queue == nil {
+		resp.Queue = []string{}
+	}
+	if resp.Resource == nil {
+		resp.Resource = []string{}
+	}
+	if resp.Output == nil {
+		resp.Output = []string{}
+	}
+	if !jl.Fetched.IsZero() {
+		resp.Fetched = &jl.Fetched
+	}
+	resp.ResourceError = jl.ResourceError
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"job":    j.UUID,
+		"user":   user.Username,
+		"output": len(resp.Output),
+	}).Info("Job debug log provided to API.")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"net/mail"
+)
+
+// Get the notification settings of the current user (GET - /api/users/me/notifications)
+func (a *AppController) GetUserNotifications(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp UserNotificationsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.Warn("An unknown user token attempted to read notification settings.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	if a.D == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_DIGEST_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	p := a.D.Prefs(user.Username)
+	resp.Notifications = APINotificationPrefs{
+		Email:     p.Email,
+		Digest:    p.Schedule,
+		Resources: p.Resources,
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Change the notification settings of the current user (PUT - /api/users/me/notifications)
+func (a *AppController) UpdateUserNotifications(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req APINotificationPrefs
+	var resp UserNotificationsResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.Warn("An unknown user token attempted to change notification settings.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	if a.D == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_DIGEST_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode notification settings.")
+
+		return
+	}
+
+	// Only the address itself is kept so a display name cannot add headers
+	if req.Email != "" {
+		addr, err := mail.ParseAddress(req.Email)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_DIGEST_INVALID, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		req.Email = addr.Address
+	}
+
+	// Resources are only managed by administrators
+	p := DigestPrefs{
+		Email:     req.Email,
+		Schedule:  req.Digest,
+		Resources: req.Resources && user.Allowed(Administrator),
+	}
+
+	err = a.D.SetPrefs(user.Username, p)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_DIGEST_INVALID, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"username": user.Username,
+			"error":    err.Error(),
+		}).Warn("Unable to change notification settings.")
+
+		return
+	}
+
+	p = a.D.Prefs(user.Username)
+	resp.Notifications = APINotificationPrefs{
+		Email:     p.Email,
+		Digest:    p.Schedule,
+		Resources: p.Resources,
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"digest":   p.Schedule,
+	}).Info("User changed notification settings.")
+}
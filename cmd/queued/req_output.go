@@ -0,0 +1,101 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Describe a metric for the API with its title in the language of the
+// request. Metrics without a message are given the title the tool gave them.
+func newAPIMetric(c *MessageCatalog, r *http.Request, m common.Metric) APIMetric {
+	title := m.Title
+	if m.Name != "" {
+		if msg := c.Text(c.Negotiate(r.Header.Get("Accept-Language")), "metric."+m.Name); msg != "" {
+			title = msg
+		}
+	}
+
+	return APIMetric{
+		Name:  m.Name,
+		Title: title,
+		Unit:  m.Unit,
+		Kind:  m.Kind,
+		Scale: m.Scale,
+	}
+}
+
+// Describe the columns of output of a job with the titles given
+func newAPIMetrics(c *MessageCatalog, r *http.Request, j common.Job, titles []string) []APIMetric {
+	metrics := []APIMetric{}
+	for _, m := range j.OutputDescriptors(titles) {
+		metrics = append(metrics, newAPIMetric(c, r, m))
+	}
+	return metrics
+}
+
+// Read all of the output of a job, including rows the queue no longer keeps in
+// memory (GET - /api/jobs/{id}/output)
+func (a *AppController) ReadJobOutput(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp JobOutputResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read job output.")
+
+		return
+	}
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	job, err := a.Q.JobOutput(jobid)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_OUTPUT_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"job":   jobid,
+			"error": err.Error(),
+		}).Error("Unable to read spilled job output.")
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.OutputTitles, resp.OutputData = job.JoinUsernames()
+	resp.OutputMetrics = newAPIMetrics(a.M, r, job, resp.OutputTitles)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"job":  job.UUID,
+		"rows": len(resp.OutputData),
+	}).Info("Full job output provided to API.")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"github.com/jmmcatee/cracklord/common"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var errOverridesForbidden = errors.New("Only administrators can set tool arguments or environment variables.")
+
+// Names a tool environment variable can have
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Add the tool arguments and environment variables of a create request to
+// the job. They are recorded in the job history as they change how the tool
+// runs on the resource without going through the tool's own checks.
+func applyJobOverrides(req JobCreateReq, job *common.Job, admin bool, by string) error {
+	if len(req.Args) == 0 && len(req.Env) == 0 {
+		return nil
+	}
+	if !admin {
+		return errOverridesForbidden
+	}
+
+	var env []string
+	for k, v := range req.Env {
+		if !envName.MatchString(k) {
+			return errors.New("The environment variable name " + k + " is not valid.")
+		}
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	if len(req.Args) > 0 {
+		job.ExtraArgs = append([]string(nil), req.Args...)
+		job.Record(by, "arguments", "Tool arguments added: "+strings.Join(req.Args, " "))
+	}
+	if len(req.Env) > 0 {
+		job.Env = make(map[string]string, len(req.Env))
+		for k, v := range req.Env {
+			job.Env[k] = v
+		}
+		job.Record(by, "environment", "Tool environment set: "+strings.Join(env, " "))
+	}
+
+	return nil
+}
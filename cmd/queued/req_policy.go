@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"io"
+	"net/http"
+)
+
+// Check the passwords a job cracked against the configured password policy,
+// or parts of it given in the request, for a compliance summary that can go
+// into a client report. Plaintexts are not returned. (POST - /api/jobs/{id}/policy)
+func (a *AppController) JobPolicyReport(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req PolicyReportReq
+	var resp PolicyReportResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to check a job against the password policy.")
+
+		return
+	}
+
+	// The configured policy is used when no body is sent
+	err := reqJSON.Decode(&req)
+	if err != nil && err != io.EOF {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	policy := a.Policy
+	if req.MinLength != nil {
+		policy.MinLength = *req.MinLength
+	}
+	if req.MinClasses != nil {
+		policy.MinClasses = *req.MinClasses
+	}
+	if req.BannedWords != nil {
+		policy.BannedWords = req.BannedWords
+	}
+	if req.BreachList != nil && !*req.BreachList {
+		policy.Breached = nil
+	}
+	if policy.MinLength < 0 || policy.MinClasses < 0 {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_POLICY_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	jobid := mux.Vars(r)["id"]
+
+	job, err := a.reader(r).JobOutput(jobid)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == nil && job.ResultKey != "" {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_ENCRYPTED)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_POLICY_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"job":   jobid,
+			"error": err.Error(),
+		}).Error("Unable to read job output for the password policy report.")
+		return
+	}
+
+	report := policy.Report(job)
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Policy = APIPasswordPolicy{
+		MinLength:   policy.MinLength,
+		MinClasses:  policy.MinClasses,
+		BannedWords: policy.BannedWords,
+		BreachList:  policy.Breached != nil,
+	}
+	if resp.Policy.BannedWords == nil {
+		resp.Policy.BannedWords = []string{}
+	}
+	resp.ByUser = report.ByUser
+	resp.Accounts = report.Accounts
+	resp.Cracked = report.Cracked
+	resp.Compliant = report.Compliant
+	resp.NonCompliant = report.NonCompliant
+	resp.Violations = report.Violations
+	resp.Lengths = report.Lengths
+	resp.Failures = []APIPolicyAccount{}
+	for _, f := range report.Failures {
+		resp.Failures = append(resp.Failures, APIPolicyAccount{Account: f.Account, Violations: f.Violations})
+	}
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"job":          job.UUID,
+		"cracked":      report.Cracked,
+		"noncompliant": report.NonCompliant,
+	}).Info("Password policy report provided to API.")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Short names of the days profiles are given in
+var profileDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func newAPIProfiles(profiles []common.Profile) []APIProfile {
+	out := []APIProfile{}
+	for _, p := range profiles {
+		ap := APIProfile{
+			Name:     p.Name,
+			Days:     []string{},
+			Start:    formatClock(p.Start),
+			End:      formatClock(p.End),
+			Workload: p.Workload,
+			MaxTasks: p.MaxTasks,
+		}
+		for _, d := range p.Days {
+			ap.Days = append(ap.Days, profileDays[d])
+		}
+		out = append(out, ap)
+	}
+	return out
+}
+
+func parseAPIProfile(ap APIProfile) (common.Profile, error) {
+	p := common.Profile{
+		Name:     strings.TrimSpace(ap.Name),
+		Workload: ap.Workload,
+		MaxTasks: ap.MaxTasks,
+	}
+
+	for _, day := range ap.Days {
+		found := false
+		for i, name := range profileDays {
+			if strings.EqualFold(day, name) {
+				p.Days = append(p.Days, time.Weekday(i))
+				found = true
+			}
+		}
+		if !found {
+			return p, errors.New("unknown day " + day)
+		}
+	}
+
+	var err error
+	if p.Start, err = parseClock(ap.Start); err != nil {
+		return p, err
+	}
+	if p.End, err = parseClock(ap.End); err != nil {
+		return p, err
+	}
+
+	return p, p.Validate()
+}
+
+// Parse an HH:MM time of day into the time after midnight, 24:00 is the end of the day
+func parseClock(s string) (time.Duration, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "24:00":
+		return 24 * time.Hour, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New("times must be HH:MM, not " + s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return "24:00"
+	}
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(d).Format("15:04")
+}
+
+// Set the time of day profiles of a resource, replacing the ones it had (PUT - /api/resources/{id}/profiles)
+func (a *AppController) UpdateResourceProfiles(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req ResProfilesReq
+	var resp ResProfilesResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to set resource profiles.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to set resource profiles.")
+
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode resource profiles.")
+
+		return
+	}
+
+	var profiles []common.Profile
+	for _, ap := range req.Profiles {
+		p, err := parseAPIProfile(ap)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_PROFILES_INVALID, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		profiles = append(profiles, p)
+	}
+
+	resID := mux.Vars(r)["id"]
+	err = a.Q.SetResourceProfiles(resID, profiles)
+	if err == queue.ErrResourceNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_PROFILES_INVALID, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"resource": resID,
+		"profiles": len(profiles),
+	}).Info("Resource profiles updated.")
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Profiles = newAPIProfiles(profiles)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/pwned"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+// Look up the cracked passwords of a job in Pwned Passwords and return how
+// often each account's password was seen in breaches. With hashes=true the NT
+// hashes of accounts not cracked yet are looked up as well. (GET - /api/jobs/{id}/pwned)
+func (a *AppController) JobPwnedReport(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp PwnedReportResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to look up a job in Pwned Passwords.")
+
+		return
+	}
+
+	if a.Pwned == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_PWNED_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	jobid := mux.Vars(r)["id"]
+
+	job, err := a.reader(r).JobOutput(jobid)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == nil && job.ResultKey != "" {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_ENCRYPTED)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+
+	var report pwned.Report
+	if err == nil {
+		report, err = pwned.CheckJob(a.Pwned, job, r.URL.Query().Get("hashes") == "true")
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_PWNED_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"job":   jobid,
+			"error": err.Error(),
+		}).Error("Unable to look up job in Pwned Passwords.")
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Hashes = report.Kind
+	resp.ByUser = report.ByUser
+	resp.Checked = report.Checked
+	resp.Pwned = report.Pwned
+	resp.Accounts = []APIPwnedAccount{}
+	for _, acct := range report.Accounts {
+		resp.Accounts = append(resp.Accounts, APIPwnedAccount{
+			Account: acct.Account,
+			Hash:    acct.Hash,
+			Cracked: acct.Cracked,
+			Count:   acct.Count,
+		})
+	}
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"job":     job.UUID,
+		"checked": report.Checked,
+		"pwned":   report.Pwned,
+	}).Info("Pwned Passwords report provided to API.")
+}
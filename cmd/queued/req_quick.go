@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/notify"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func newAPIQuickCrack(qc QuickCrack) APIQuickCrack {
+	return APIQuickCrack{
+		ID:        qc.ID,
+		Owner:     qc.Owner,
+		Hash:      qc.Hash,
+		Mode:      qc.Mode,
+		ModeName:  qc.ModeName,
+		Jobs:      qc.Jobs,
+		Status:    qc.Status,
+		Plaintext: qc.Plaintext,
+		Created:   qc.Created,
+		Finished:  qc.Finished,
+	}
+}
+
+// Run a single hash through the quick pipeline of top wordlists and a fast rule
+// set. The mode is detected from the hash when it is not given and the other
+// modes it may be in are returned. (POST - /api/jobs/quick)
+func (a *AppController) CreateQuickCrack(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req QuickCrackReq
+	var resp QuickCrackResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to quick crack a hash.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to quick crack a hash.")
+
+		return
+	}
+
+	if a.Quick == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_QUICK_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a quick crack request.")
+
+		return
+	}
+
+	invalid := func(reason string) {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_QUICK_INVALID, reason)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+	}
+
+	hash := strings.TrimSpace(req.Hash)
+	if hash == "" || strings.ContainsAny(hash, "\r\n") {
+		invalid("give a single hash")
+		return
+	}
+
+	detected := common.DetectHashModes(hash)
+
+	var mode common.HashMode
+	if req.Mode == "" {
+		if len(detected) == 0 {
+			invalid("the hash type could not be detected, give the mode")
+			return
+		}
+		mode, detected = detected[0], detected[1:]
+	} else {
+		if _, err := strconv.Atoi(req.Mode); err != nil {
+			invalid("the mode must be a hashcat mode number")
+			return
+		}
+
+		mode = common.HashMode{Mode: req.Mode, Name: req.Mode}
+		var others []common.HashMode
+		for _, m := range detected {
+			if m.Mode == req.Mode {
+				mode.Name = m.Name
+			} else {
+				others = append(others, m)
+			}
+		}
+		detected = others
+	}
+
+	var hook *notify.Webhook
+	if req.Webhook != "" {
+		hook, err = notify.NewWebhook(req.Webhook)
+		if err != nil {
+			invalid(err.Error())
+			return
+		}
+	}
+
+	qc, err := a.Quick.Submit(user.Username, hash, mode, hook)
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_QUICK_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"username": user.Username,
+			"error":    err.Error(),
+		}).Error("Unable to queue a quick crack.")
+
+		return
+	}
+
+	resp.Quick = newAPIQuickCrack(qc)
+	for _, m := range detected {
+		resp.Quick.Modes = append(resp.Quick.Modes, APIHashMode{Mode: m.Mode, Name: m.Name})
+	}
+
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+}
+
+// Read the status of a quick crack, the plaintext is only shown to its owner
+// and administrators (GET - /api/jobs/quick/{id})
+func (a *AppController) ReadQuickCrack(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp QuickCrackResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read a quick crack.")
+
+		return
+	}
+
+	if a.Quick == nil {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_QUICK_DISABLED)
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	qc, ok := a.Quick.Get(mux.Vars(r)["id"])
+	if !ok {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_QUICK_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+	resp.Quick = newAPIQuickCrack(qc)
+	if qc.Owner != user.Username && !user.Allowed(Administrator) {
+		resp.Quick.Plaintext = ""
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"reflect"
+)
+
+// Find the resources with a name. Resources that quit are left out, as they
+// are only kept until they are removed and a registration adds them again.
+func namedResources(snap *queue.Snapshot, name string) []queue.ResourceSnapshot {
+	var found []queue.ResourceSnapshot
+	for _, res := range snap.Resources {
+		if res.Name == name && res.Status != common.STATUS_QUIT {
+			found = append(found, res)
+		}
+	}
+	return found
+}
+
+func newAPIResourceConfig(res queue.ResourceSnapshot) APIResourceConfig {
+	return APIResourceConfig{
+		ID:          res.ID,
+		Name:        res.Name,
+		Manager:     res.Manager,
+		Address:     res.Address,
+		Params:      res.Params,
+		Status:      res.Status,
+		Exclusive:   res.Exclusive,
+		Profiles:    newAPIProfiles(res.Profiles),
+		CostPerHour: res.CostPerHour,
+	}
+}
+
+// Check if the manager parameters of a resource differ from those registered.
+// Parameters the manager added on its own are not compared.
+func paramsChanged(current, desired map[string]string) bool {
+	for k, v := range desired {
+		if current[k] != v {
+			return true
+		}
+	}
+	return false
+}
+
+// Read the state of the resource registered under a name
+// (GET - /api/resources/named/{name})
+func (a *AppController) ReadResourceConfig(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ResConfigResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read a resource registration.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to read a resource registration.")
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	found := namedResources(a.Q.Snapshot(), name)
+	if len(found) == 0 {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if len(found) > 1 {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_REGISTER_CONFLICT, fmt.Sprintf("%d resources are named %s", len(found), name))
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Resource = newAPIResourceConfig(found[0])
+
+	writeWithETag(rw, r, resp)
+}
+
+// Add or change a resource to match the state registered for its name. The
+// same registration can be sent any number of times, so tools managing the
+// resources declaratively can send it on every run. The address and manager
+// of a resource can not be changed, it must be deleted and registered again.
+// (PUT - /api/resources/named/{name})
+func (a *AppController) RegisterResource(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req ResRegisterReq
+	var resp ResConfigResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to register a resource.")
+
+		return
+	}
+
+	// Check for Administrator user level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to register a resource.")
+
+		return
+	}
+
+	if err := reqJSON.Decode(&req); err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.WithField("error", err.Error()).Error("An error occured while trying to decode a resource registration.")
+
+		return
+	}
+
+	status := req.Status
+	if status == "" {
+		status = common.STATUS_RUNNING
+	}
+	if status != common.STATUS_RUNNING && status != common.STATUS_PAUSED {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	profiles := []common.Profile{}
+	for _, ap := range req.Profiles {
+		p, err := parseAPIProfile(ap)
+		if err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_PROFILES_INVALID, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		profiles = append(profiles, p)
+	}
+
+	manager, ok := a.Q.GetResourceManager(req.Manager)
+	if !ok {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESMGR_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	// The name in the path is the one the resource is registered under
+	name := mux.Vars(r)["name"]
+	params := map[string]string{}
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	params["name"] = name
+
+	conflict := func(detail string) {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_REGISTER_CONFLICT, detail)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"name":   name,
+			"reason": detail,
+		}).Warn("Resource registration conflicts with the resource.")
+	}
+
+	var changes []string
+	found := namedResources(a.Q.Snapshot(), name)
+	if len(found) > 1 {
+		conflict(fmt.Sprintf("%d resources are named %s", len(found), name))
+		return
+	}
+
+	if len(found) == 0 {
+		err := manager.AddResource(params)
+		a.Q.InvalidateSnapshot() // Managers keep their own parameters
+		if err == nil {
+			if found = namedResources(a.Q.Snapshot(), name); len(found) != 1 {
+				err = fmt.Errorf("the resource was not found after it was added")
+			}
+		}
+		if err != nil {
+			resp.Status = RESP_CODE_ERROR
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_ADD_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_ERROR)
+			respJSON.Encode(resp)
+
+			log.WithFields(log.Fields{
+				"error":   err.Error(),
+				"manager": req.Manager,
+				"name":    name,
+			}).Error("An error occured adding a registered resource.")
+
+			return
+		}
+
+		resp.Created = true
+		changes = append(changes, "added")
+	}
+
+	cur := found[0]
+	if cur.Manager != manager.SystemName() {
+		conflict(fmt.Sprintf("it is managed by %s", cur.Manager))
+		return
+	}
+	if addr, ok := params["address"]; ok && addr != cur.Address {
+		conflict(fmt.Sprintf("its address is %s", cur.Address))
+		return
+	}
+
+	if cur.Status != status || paramsChanged(cur.Params, params) {
+		err := manager.UpdateResource(cur.ID, status, params)
+		a.Q.InvalidateSnapshot() // Managers keep their own parameters
+		if err != nil {
+			resp.Status = RESP_CODE_ERROR
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_ERROR)
+			respJSON.Encode(resp)
+
+			log.WithFields(log.Fields{
+				"error":    err.Error(),
+				"resource": cur.ID,
+			}).Error("An error occured updating a registered resource.")
+
+			return
+		}
+		changes = append(changes, "params", "status")
+	}
+
+	if cur.Exclusive != req.Exclusive {
+		if err := a.Q.SetResourceExclusive(cur.ID, req.Exclusive); err != nil {
+			resp.Status = RESP_CODE_ERROR
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_ERROR)
+			respJSON.Encode(resp)
+			return
+		}
+		changes = append(changes, "exclusive")
+	}
+
+	if cur.CostPerHour != req.CostPerHour {
+		if err := a.Q.SetResourceCost(cur.ID, req.CostPerHour); err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_UPDATE_FAILED, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		changes = append(changes, "cost")
+	}
+
+	if !reflect.DeepEqual(newAPIProfiles(cur.Profiles), newAPIProfiles(profiles)) {
+		if err := a.Q.SetResourceProfiles(cur.ID, profiles); err != nil {
+			resp.Status = RESP_CODE_BADREQ
+			resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_PROFILES_INVALID, err.Error())
+
+			rw.WriteHeader(RESP_CODE_BADREQ)
+			respJSON.Encode(resp)
+			return
+		}
+		changes = append(changes, "profiles")
+	}
+
+	// Return the state the resource has now
+	if len(changes) > 0 {
+		a.Q.InvalidateSnapshot()
+	}
+	for _, res := range namedResources(a.Q.Snapshot(), name) {
+		if res.ID == cur.ID {
+			cur = res
+		}
+	}
+
+	code := RESP_CODE_OK
+	if resp.Created {
+		code = RESP_CODE_CREATED
+	}
+	resp.Status = code
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Resource = newAPIResourceConfig(cur)
+
+	rw.WriteHeader(code)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"resource": cur.ID,
+		"name":     name,
+		"changes":  changes,
+	}).Info("Resource registration applied.")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/report"
+	"net/http"
+	"strconv"
+)
+
+// Assemble the engagement report of a project from its jobs, as HTML that
+// prints to PDF from a browser. The jobs run, their timeline and crack rates,
+// an analysis of the cracked passwords against the password policy and the
+// methodology are included but never the plaintexts.
+// (GET - /api/projects/{id}/report)
+func (a *AppController) ProjectReport(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ErrorResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to generate a project report.")
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to generate a project report.")
+		return
+	}
+
+	project := mux.Vars(r)["id"]
+
+	// All of the output of each job is needed for the password analysis,
+	// including rows spilled to storage
+	reads := a.reader(r)
+	var jobs []common.Job
+	for _, j := range reads.AllJobs() {
+		if j.Project != project {
+			continue
+		}
+
+		full, err := reads.JobOutput(j.UUID)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"job":   j.UUID,
+				"error": err.Error(),
+			}).Warn("Unable to read all of the output of a job for a project report.")
+			full = j
+		}
+		jobs = append(jobs, full)
+	}
+
+	if len(jobs) == 0 {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_REPORT_NOJOBS)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	gen := a.Reports
+	if gen.Template == nil {
+		gen.Template, _ = report.ParseTemplate("")
+	}
+
+	// The report is rendered before anything is sent so a template error is
+	// not returned as half a page
+	var page bytes.Buffer
+	err := gen.Generate(&page, project, jobs, reads.AllTools(), a.Policy)
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_REPORT_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"project": project,
+			"error":   err.Error(),
+		}).Error("Unable to generate a project report.")
+		return
+	}
+
+	h := rw.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(page.Len()))
+	if r.URL.Query().Get("download") == "true" {
+		h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", project+"-report.html"))
+	}
+	rw.WriteHeader(RESP_CODE_OK)
+	rw.Write(page.Bytes())
+
+	log.WithFields(log.Fields{
+		"project": project,
+		"jobs":    len(jobs),
+		"user":    user.Username,
+	}).Info("Project report generated.")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+)
+
+func newAPIReservation(r queue.Reservation) APIReservation {
+	return APIReservation{
+		ID:        r.UUID,
+		Project:   r.Project,
+		Resources: r.Resources,
+		Start:     r.Start,
+		End:       r.End,
+		CreatedBy: r.CreatedBy,
+		Note:      r.Note,
+	}
+}
+
+// List reservations that have not ended (GET - /api/reservations)
+func (a *AppController) ListReservations(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ReservationListResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to list reservations.")
+		return
+	}
+
+	// Any user can see when resources are reserved
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(ReadOnly) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to list reservations.")
+		return
+	}
+
+	resp.Reservations = []APIReservation{}
+	for _, resv := range a.Q.Reservations() {
+		resp.Reservations = append(resp.Reservations, newAPIReservation(resv))
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Reserve resources for a project during a window (POST - /api/reservations)
+func (a *AppController) CreateReservation(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req ReservationCreateReq
+	var resp ReservationCreateResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to reserve resources.")
+		return
+	}
+
+	// Only Administrators can block out resources
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to reserve resources.")
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resv, err := a.Q.AddReservation(queue.Reservation{
+		Project:   req.Project,
+		Resources: req.Resources,
+		Start:     req.Start,
+		End:       req.End,
+		CreatedBy: user.Username,
+		Note:      req.Note,
+	})
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESV_CREATE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Reservation = newAPIReservation(resv)
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"user":        user.Username,
+		"reservation": resv.UUID,
+		"project":     resv.Project,
+	}).Info("Reservation created.")
+}
+
+// Remove a reservation (DELETE - /api/reservations/{id})
+func (a *AppController) DeleteReservation(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ReservationDeleteResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("token", token).Warn("An unknown user token attempted to remove a reservation.")
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+		log.WithField("user", user.Username).Warn("An unauthorized user attempted to remove a reservation.")
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	err := a.Q.RemoveReservation(id)
+	if err != nil {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RESV_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"user":        user.Username,
+		"reservation": id,
+	}).Info("Reservation removed.")
+}
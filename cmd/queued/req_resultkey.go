@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/pgp"
+)
+
+// Add the key the results of a create request are encrypted for to the job.
+// The resource encrypts each row as the tool reports it, so only the holder
+// of the private key can read the plaintexts, not the queue or its
+// administrators.
+func applyResultKey(req JobCreateReq, job *common.Job, by string) error {
+	if req.ResultKey == "" {
+		return nil
+	}
+
+	// NT hashes are found from the LM passwords on the queue
+	if req.LMNT {
+		return errors.New("LM and NT hashes cannot be cracked together when the results are encrypted.")
+	}
+
+	key, err := pgp.ReadArmoredKey(req.ResultKey)
+	if err != nil {
+		return err
+	}
+
+	job.ResultKey = req.ResultKey
+	job.Record(by, "resultkey", "Results encrypted for the OpenPGP key "+key.FingerprintString())
+
+	return nil
+}
+
+// The fingerprint of the key the results of a job are encrypted for, empty
+// when they are not
+func resultKeyFingerprint(job common.Job) string {
+	if job.ResultKey == "" {
+		return ""
+	}
+
+	key, err := pgp.ReadArmoredKey(job.ResultKey)
+	if err != nil {
+		return ""
+	}
+	return key.FingerprintString()
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse a Range header of a single byte range against a file of the given
+// size. The start and end of the range are returned with end exclusive. Ranges
+// that are not understood, or list more than one range, are ignored so the
+// whole file is sent. False is returned when the range is outside of the file.
+func parseByteRange(header string, size int64) (start, end int64, ranged, ok bool) {
+	if !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return 0, size, false, true
+	}
+
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	dash := strings.Index(spec, "-")
+	if dash < 0 {
+		return 0, size, false, true
+	}
+	first, last := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+
+	if first == "" {
+		// A suffix range of the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, false, true
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, true, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, false, true
+	}
+	end = size
+	if last != "" {
+		l, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || l < start {
+			return 0, size, false, true
+		}
+		if l+1 < end {
+			end = l + 1
+		}
+	}
+	if start >= size {
+		return 0, 0, true, false
+	}
+
+	return start, end, true, true
+}
+
+// Stream the result file of a job from the resource running it. The file is
+// read from the resource in parts as it is written to the client so neither
+// the queue nor the API hold all of it. A single byte range can be asked for
+// with a Range header to resume a download. (GET - /api/jobs/{id}/results)
+func (a *AppController) JobResults(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ErrorResp
+
+	// JSON Encoder and Decoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to download job results.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	// Get the ID of the job we want
+	jobid := mux.Vars(r)["id"]
+
+	// The first part of the file also tells us how large it is, only a byte
+	// is read when a range may start later in the file
+	var probe int64
+	if r.Header.Get("Range") != "" {
+		probe = 1
+	}
+	chunk, err := a.Q.JobResults(jobid, 0, probe)
+	if err == queue.ErrJobNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrResultsEncrypted {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_ENCRYPTED)
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrJobPurged || err == queue.ErrJobNotAssigned {
+		resp.Status = RESP_CODE_CONFLICT
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_CONFLICT)
+		respJSON.Encode(resp)
+		return
+	}
+	if err == queue.ErrResourceOffline {
+		resp.Status = RESP_CODE_UNAVAILABLE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_UNAVAILABLE)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"job":   jobid,
+			"error": err.Error(),
+		}).Error("Unable to read the result file of a job.")
+		return
+	}
+
+	size := chunk.Size
+	start, end, ranged, ok := parseByteRange(r.Header.Get("Range"), size)
+	if !ok {
+		rw.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+
+		resp.Status = RESP_CODE_BADRANGE
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_JOB_RESULTS_RANGE)
+
+		rw.WriteHeader(RESP_CODE_BADRANGE)
+		respJSON.Encode(resp)
+		return
+	}
+
+	h := rw.Header()
+	h.Set("Accept-Ranges", "bytes")
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Length", strconv.FormatInt(end-start, 10))
+	h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-results.txt"`, jobid))
+	if !chunk.Modified.IsZero() {
+		h.Set("Last-Modified", chunk.Modified.UTC().Format(http.TimeFormat))
+	}
+
+	code := RESP_CODE_OK
+	if ranged {
+		h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+		code = RESP_CODE_PARTIAL
+	}
+	rw.WriteHeader(code)
+
+	flusher, _ := rw.(http.Flusher)
+	began := time.Now()
+	offset := start
+	for offset < end {
+		// The first part is reused when the range starts at the beginning
+		if offset != 0 || chunk.Data == nil {
+			chunk, err = a.Q.JobResults(jobid, offset, end-offset)
+			if err != nil {
+				break
+			}
+		}
+
+		data := chunk.Data
+		if int64(len(data)) > end-offset {
+			data = data[:end-offset]
+		}
+		if len(data) == 0 {
+			// The file was made shorter while it was being sent
+			break
+		}
+
+		if _, err = rw.Write(data); err != nil {
+			break
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		offset += int64(len(data))
+		chunk.Data = nil
+	}
+
+	// Headers are sent so a failure can only be logged and the client sees
+	// a body shorter than its length
+	if err != nil || offset < end {
+		log.WithFields(log.Fields{
+			"job":    jobid,
+			"sent":   offset - start,
+			"length": end - start,
+			"error":  fmt.Sprint(err),
+		}).Error("Result file of a job was not completely sent.")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"job":      jobid,
+		"username": user.Username,
+		"start":    start,
+		"length":   end - start,
+		"duration": time.Since(began),
+	}).Info("Job result file streamed to API.")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"net/http"
+	"sort"
+)
+
+// List the sessions of the current user (GET - /api/users/me/sessions)
+func (a *AppController) ListUserSessions(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp UserSessionsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.Warn("An unknown user token attempted to list sessions.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	sessions := a.T.Sessions(user.Username)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LogOnTime.After(sessions[j].LogOnTime)
+	})
+
+	resp.Sessions = []APISession{}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, APISession{
+			ID:         s.SessionID,
+			RemoteAddr: s.RemoteAddr,
+			UserAgent:  s.UserAgent,
+			Location:   s.Location,
+			LogOnTime:  s.LogOnTime,
+			Expires:    s.Timeout,
+			Current:    s.SessionID == user.SessionID,
+		})
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// End one of the sessions of the current user (DELETE - /api/users/me/sessions/{id})
+func (a *AppController) RevokeUserSession(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp UserSessionRevokeResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.Warn("An unknown user token attempted to revoke a session.")
+
+		return
+	}
+
+	user, _ := a.T.GetUser(token)
+
+	id := mux.Vars(r)["id"]
+	if !a.T.RevokeSession(user.Username, id) {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_SESSION_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"username": user.Username,
+		"session":  id,
+		"remote":   clientIP(r.RemoteAddr, r.Header.Get("X-Forwarded-For")),
+	}).Info("User revoked a session.")
+}
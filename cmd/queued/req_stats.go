@@ -0,0 +1,155 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Percentage of hashes cracked, 0 when there were no hashes
+func crackRate(cracked, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(cracked) / float64(total) * 100
+}
+
+// Get usage statistics for each tool and its parameters (GET - /api/stats/tools)
+func (a *AppController) ReadToolStats(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ToolStatsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read tool statistics.")
+
+		return
+	}
+
+	resp.Tools = []APIToolStats{}
+	for name, ts := range a.reader(r).ToolStats() {
+		tool := APIToolStats{
+			Name:      name,
+			Jobs:      ts.Jobs,
+			Cracked:   ts.Cracked,
+			Total:     ts.Total,
+			CrackRate: crackRate(ts.Cracked, ts.Total),
+			Runtime:   int64(ts.Runtime / time.Second),
+			Params:    []APIParamStats{},
+		}
+
+		for param, values := range ts.Params {
+			for value, ps := range values {
+				tool.Params = append(tool.Params, APIParamStats{
+					Name:      param,
+					Value:     value,
+					Jobs:      ps.Jobs,
+					Cracked:   ps.Cracked,
+					Total:     ps.Total,
+					CrackRate: crackRate(ps.Cracked, ps.Total),
+				})
+			}
+		}
+
+		// The most effective values of each parameter come first
+		sort.Slice(tool.Params, func(i, j int) bool {
+			if tool.Params[i].Name != tool.Params[j].Name {
+				return tool.Params[i].Name < tool.Params[j].Name
+			}
+			return tool.Params[i].Cracked > tool.Params[j].Cracked
+		})
+
+		resp.Tools = append(resp.Tools, tool)
+	}
+
+	sort.Slice(resp.Tools, func(i, j int) bool {
+		return resp.Tools[i].Name < resp.Tools[j].Name
+	})
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Get the request latency of each API route, slowest first (GET - /api/stats/routes)
+func (a *AppController) ReadRouteStats(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp RouteStatsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read route metrics.")
+
+		return
+	}
+
+	// Check for administrator level
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to read route metrics.")
+
+		return
+	}
+
+	resp.Routes = []APIRouteStats{}
+	if a.Metrics != nil {
+		for _, rs := range a.Metrics.Routes() {
+			route := APIRouteStats{
+				Method:  rs.Method,
+				Path:    rs.Path,
+				Count:   rs.Count,
+				Errors:  rs.Errors,
+				Mean:    millis(rs.Total) / float64(rs.Count),
+				Max:     millis(rs.Max),
+				Buckets: []APILatencyBucket{},
+			}
+			for i, b := range LatencyBuckets {
+				route.Buckets = append(route.Buckets, APILatencyBucket{Le: millis(b), Count: rs.Buckets[i]})
+			}
+
+			resp.Routes = append(resp.Routes, route)
+		}
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
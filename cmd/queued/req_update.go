@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net/http"
+	"time"
+)
+
+// The largest update request accepted, the binary is base64 encoded in JSON
+const maxUpdateRequestSize = 512 * 1024 * 1024
+
+// How long to wait for jobs on a resource to finish when no drain timeout is given
+const defaultDrainTimeout = time.Hour
+
+// Convert a rolling update into the API structure
+func apiUpdate(u queue.UpdateRollout) APIUpdate {
+	out := APIUpdate{
+		ID:        u.ID,
+		Build:     u.Build,
+		Status:    u.Status,
+		Error:     u.Error,
+		StartTime: u.StartTime,
+		EndTime:   u.EndTime,
+		Resources: []APIResourceUpdate{},
+	}
+
+	for _, r := range u.Resources {
+		out.Resources = append(out.Resources, APIResourceUpdate{
+			ID:     r.UUID,
+			Name:   r.Name,
+			Status: r.Status,
+			Error:  r.Error,
+		})
+	}
+
+	return out
+}
+
+// Start a rolling update of resourceservers (POST - /api/resources/update)
+func (a *AppController) StartResourceUpdate(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req ResUpdateRolloutReq
+	var resp ResUpdateRolloutResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxUpdateRequestSize))
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to update resources.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to update resources.")
+
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a resource update request.")
+
+		return
+	}
+
+	if len(req.Binary) == 0 || len(req.Signature) == 0 {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UPDATE_BINARYREQUIRED)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	drain := defaultDrainTimeout
+	if req.DrainTimeout > 0 {
+		drain = time.Duration(req.DrainTimeout) * time.Second
+	}
+
+	pkg := common.UpdatePackage{
+		Binary:    req.Binary,
+		Signature: req.Signature,
+	}
+
+	rollout, err := a.Q.StartUpdate(pkg, req.Resources, drain)
+	if err == queue.ErrResourceNotFound {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_RES_LIST_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = err.Error(), MSG_BADREQ
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+	resp.Update = apiUpdate(rollout)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"id":        rollout.ID,
+		"build":     rollout.Build,
+		"resources": len(rollout.Resources),
+		"username":  user.Username,
+	}).Info("Rolling resource update started.")
+}
+
+// Get the status of the current or last rolling update (GET - /api/resources/update)
+func (a *AppController) ReadResourceUpdate(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp ResUpdateRolloutResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read the resource update status.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to read the resource update status.")
+
+		return
+	}
+
+	rollout, ok := a.Q.UpdateStatus()
+	if !ok {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UPDATE_NOTSTARTED)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Update = apiUpdate(rollout)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/wordlist"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Folder within the wordlist directory where markov models are stored
+const markovModelDir = "models"
+
+// The largest markov model that can be uploaded
+const maxMarkovModelSize = 512 * 1024 * 1024
+
+var regModelName = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// Extensions of markov models, .hcstat2 for those of newer hashcat versions
+var markovModelExts = []string{".hcstat", ".hcstat2"}
+
+// Split the name a model is uploaded as into its name and extension. Names
+// without an extension are older .hcstat models.
+func splitModelName(name string) (string, string) {
+	for _, ext := range markovModelExts {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext), ext
+		}
+	}
+	return name, ".hcstat"
+}
+
+// Resolve a path provided through the API so that it must stay within the
+// configured wordlist directory
+func (a *AppController) wordlistPath(p string) (string, error) {
+	if a.WordlistDir == "" {
+		return "", errors.New("Wordlist processing is not enabled on this server.")
+	}
+
+	root := filepath.Clean(a.WordlistDir)
+	full := filepath.Join(root, filepath.Clean("/"+p))
+
+	if full == root || !strings.HasPrefix(full, root+string(filepath.Separator)) {
+		return "", errors.New("The path must be a file within the wordlist directory.")
+	}
+
+	return full, nil
+}
+
+// Convert a processing task into the API structure
+func apiWordlistTask(t wordlist.Task, root string) APIWordlistTask {
+	rel := func(p string) string {
+		r, err := filepath.Rel(filepath.Clean(root), p)
+		if err != nil || p == "" {
+			return p
+		}
+		return r
+	}
+
+	return APIWordlistTask{
+		ID:          t.ID,
+		Kind:        t.Kind,
+		Source:      rel(t.Source),
+		Destination: rel(t.Destination),
+		Status:      t.Status,
+		Error:       t.Error,
+		InputLines:  t.Result.InputLines,
+		OutputLines: t.Result.OutputLines,
+		Output:      rel(t.Result.Output),
+		Hcstat:      rel(t.Result.Hcstat),
+		Pages:       t.Result.Pages,
+		Shared:      t.Result.Shared,
+		StartTime:   t.StartTime,
+		EndTime:     t.EndTime,
+	}
+}
+
+// List wordlist processing tasks (GET - /api/wordlists/processing)
+func (a *AppController) ListWordlistTasks(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp WordlistTasksResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to list wordlist processing.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to list wordlist processing.")
+
+		return
+	}
+
+	resp.Tasks = []APIWordlistTask{}
+	for _, t := range a.W.All() {
+		resp.Tasks = append(resp.Tasks, apiWordlistTask(t, a.WordlistDir))
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Start processing a wordlist (POST - /api/wordlists/processing)
+func (a *AppController) CreateWordlistTask(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var req WordlistProcessReq
+	var resp WordlistProcessResp
+
+	// JSON Encoder and Decoder
+	reqJSON := json.NewDecoder(r.Body)
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to process a wordlist.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to process a wordlist.")
+
+		return
+	}
+
+	// Decode the request
+	err := reqJSON.Decode(&req)
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_BADREQ)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.Error("An error occured while trying to decode a wordlist processing request.")
+
+		return
+	}
+
+	src, err := a.wordlistPath(req.Source)
+	if err == nil {
+		var dst string
+		dst, err = a.wordlistPath(req.Destination)
+		if err == nil && dst == src {
+			err = errors.New("The destination must be different from the source.")
+		}
+		req.Destination = dst
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = err.Error(), MSG_BADREQ
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"username":    user.Username,
+			"source":      req.Source,
+			"destination": req.Destination,
+		}).Warn("An invalid wordlist processing path was provided.")
+
+		return
+	}
+
+	opts := wordlist.Options{
+		Compress: req.Compress,
+	}
+	if req.Hcstat {
+		opts.HcstatBin = a.HcstatBin
+	}
+
+	resp.ID = a.W.Start(src, req.Destination, opts)
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"id":       resp.ID,
+		"username": user.Username,
+	}).Info("Wordlist processing requested.")
+}
+
+// Read the status of wordlist processing (GET - /api/wordlists/processing/{id})
+func (a *AppController) ReadWordlistTask(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp WordlistTaskResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to read wordlist processing.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to read wordlist processing.")
+
+		return
+	}
+
+	t, ok := a.W.Get(mux.Vars(r)["id"])
+	if !ok {
+		resp.Status = RESP_CODE_NOTFOUND
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_NOTFOUND)
+
+		rw.WriteHeader(RESP_CODE_NOTFOUND)
+		respJSON.Encode(resp)
+
+		return
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+	resp.Task = apiWordlistTask(t, a.WordlistDir)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// List uploaded markov models (GET - /api/wordlists/models)
+func (a *AppController) ListMarkovModels(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp MarkovModelsResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to list markov models.")
+
+		return
+	}
+
+	// Check for standard user level at least
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(StandardUser) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to list markov models.")
+
+		return
+	}
+
+	resp.Models = []APIMarkovModel{}
+	if a.WordlistDir != "" {
+		files, _ := ioutil.ReadDir(filepath.Join(a.WordlistDir, markovModelDir))
+		for _, f := range files {
+			// Models generated by newer hashcat versions use .hcstat2
+			name, ext := splitModelName(f.Name())
+			if f.IsDir() || ext != filepath.Ext(f.Name()) {
+				continue
+			}
+
+			resp.Models = append(resp.Models, APIMarkovModel{
+				Name:     name,
+				Format:   strings.TrimPrefix(ext, "."),
+				Size:     f.Size(),
+				Modified: f.ModTime(),
+			})
+		}
+	}
+
+	resp.Status = RESP_CODE_OK
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_OK)
+
+	rw.WriteHeader(RESP_CODE_OK)
+	respJSON.Encode(resp)
+}
+
+// Upload a markov model with the raw file as the body (PUT - /api/wordlists/models/{name})
+func (a *AppController) UploadMarkovModel(rw http.ResponseWriter, r *http.Request) {
+	// Response and Request structures
+	var resp MarkovModelUploadResp
+
+	// JSON Encoder
+	respJSON := newRespEncoder(rw)
+
+	// Get the authorization header
+	token := r.Header.Get("AuthorizationToken")
+
+	if !a.T.CheckToken(token) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("token", token).Warn("An unknown user token attempted to upload a markov model.")
+
+		return
+	}
+
+	// Check for administrator access
+	user, _ := a.T.GetUser(token)
+	if !user.Allowed(Administrator) {
+		resp.Status = RESP_CODE_UNAUTHORIZED
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_UNAUTHORIZED)
+
+		rw.WriteHeader(RESP_CODE_UNAUTHORIZED)
+		respJSON.Encode(resp)
+
+		log.WithField("username", user.Username).Warn("An unauthorized user attempted to upload a markov model.")
+
+		return
+	}
+
+	// The extension is kept so hashcat is given the model in its format
+	name, ext := splitModelName(mux.Vars(r)["name"])
+	if a.WordlistDir == "" || !regModelName.MatchString(name) {
+		resp.Status = RESP_CODE_BADREQ
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_MODEL_NAME_INVALID)
+
+		rw.WriteHeader(RESP_CODE_BADREQ)
+		respJSON.Encode(resp)
+
+		return
+	}
+
+	dir := filepath.Join(a.WordlistDir, markovModelDir)
+	err := os.MkdirAll(dir, 0750)
+	if err == nil {
+		// Write to a temporary file first so a failed upload can't replace a model
+		var tmp *os.File
+		tmp, err = ioutil.TempFile(dir, ".upload-")
+		if err == nil {
+			_, err = io.Copy(tmp, http.MaxBytesReader(rw, r.Body, maxMarkovModelSize))
+			tmp.Close()
+
+			if err == nil {
+				err = os.Rename(tmp.Name(), filepath.Join(dir, name+ext))
+			}
+			if err != nil {
+				os.Remove(tmp.Name())
+			}
+		}
+	}
+	if err == nil {
+		// A model uploaded again in the other format replaces the old one
+		for _, other := range markovModelExts {
+			if other != ext {
+				os.Remove(filepath.Join(dir, name+other))
+			}
+		}
+	}
+	if err != nil {
+		resp.Status = RESP_CODE_ERROR
+		resp.Message, resp.MessageKey = a.M.Localize(r, MSG_MODEL_STORE_FAILED, err.Error())
+
+		rw.WriteHeader(RESP_CODE_ERROR)
+		respJSON.Encode(resp)
+
+		log.WithFields(log.Fields{
+			"name":  name,
+			"error": err.Error(),
+		}).Error("Unable to store uploaded markov model.")
+
+		return
+	}
+
+	info, _ := os.Stat(filepath.Join(dir, name+ext))
+
+	// Resources with the models folder mounted offer the model in the job
+	// forms of hashcat once they load their tools again
+	go a.rescanResources()
+
+	resp.Status = RESP_CODE_CREATED
+	resp.Message, resp.MessageKey = a.M.Localize(r, MSG_CREATED)
+	resp.Model.Name = name
+	resp.Model.Format = strings.TrimPrefix(ext, ".")
+	if info != nil {
+		resp.Model.Size = info.Size()
+		resp.Model.Modified = info.ModTime()
+	}
+
+	rw.WriteHeader(RESP_CODE_CREATED)
+	respJSON.Encode(resp)
+
+	log.WithFields(log.Fields{
+		"name":     name,
+		"username": user.Username,
+	}).Info("Markov model uploaded.")
+}
+
+// Ask every connected resource to load its tools again
+func (a *AppController) rescanResources() {
+	for _, res := range a.Q.Snapshot().Resources {
+		if res.Status != common.STATUS_RUNNING && res.Status != common.STATUS_PAUSED {
+			continue
+		}
+
+		if _, err := a.Q.RescanResource(res.ID); err != nil {
+			log.WithFields(log.Fields{
+				"resource": res.Name,
+				"error":    err.Error(),
+			}).Warn("Unable to rescan resource for the uploaded markov model.")
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Upload a markov model as an Administrator
+func uploadModel(t *testing.T, a *AppController, name, body string) MarkovModelUploadResp {
+	r := httptest.NewRequest("PUT", "/api/wordlists/models/"+name, bytes.NewBufferString(body))
+	r.Header.Set("AuthorizationToken", "admin-token")
+	rw := httptest.NewRecorder()
+	a.Router().ServeHTTP(rw, r)
+
+	var resp MarkovModelUploadResp
+	json.NewDecoder(rw.Body).Decode(&resp)
+	if rw.Code != RESP_CODE_CREATED {
+		t.Fatalf("Uploading %s gave %d: %s", name, rw.Code, resp.Message)
+	}
+	return resp
+}
+
+func TestUploadMarkovModelKeepsFormat(t *testing.T) {
+	a, token := testController(t)
+	a.WordlistDir = t.TempDir()
+	a.T.AddToken("admin-token", User{Username: "admin", Groups: []string{Administrator}})
+	dir := filepath.Join(a.WordlistDir, markovModelDir)
+
+	// Models of newer hashcat versions keep their extension
+	if resp := uploadModel(t, a, "corporate.hcstat2", "v2"); resp.Model.Name != "corporate" || resp.Model.Format != "hcstat2" {
+		t.Errorf("Unexpected model %+v", resp.Model)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "corporate.hcstat2")); err != nil {
+		t.Error(err)
+	}
+
+	// Names without an extension are older models
+	if resp := uploadModel(t, a, "legacy", "v1"); resp.Model.Format != "hcstat" {
+		t.Errorf("Unexpected model %+v", resp.Model)
+	}
+
+	// Uploading a model again in the other format replaces it
+	uploadModel(t, a, "corporate.hcstat", "v1")
+	if _, err := os.Stat(filepath.Join(dir, "corporate.hcstat2")); !os.IsNotExist(err) {
+		t.Error("Model in the old format was kept")
+	}
+
+	rw := apiRequest(a, "GET", "/api/wordlists/models", token, nil)
+	var list MarkovModelsResp
+	json.NewDecoder(rw.Body).Decode(&list)
+	if len(list.Models) != 2 {
+		t.Fatalf("Unexpected models %+v", list.Models)
+	}
+	for _, m := range list.Models {
+		if m.Format != "hcstat" {
+			t.Errorf("Model %s is %s", m.Name, m.Format)
+		}
+	}
+}
@@ -7,25 +7,31 @@ const (
 	RESP_CODE_OK           = 200
 	RESP_CODE_CREATED      = 201
 	RESP_CODE_NOCONTENT    = 204
+	RESP_CODE_PARTIAL      = 206
 	RESP_CODE_NOTMODIFIED  = 304
 	RESP_CODE_BADREQ       = 400
 	RESP_CODE_UNAUTHORIZED = 401
 	RESP_CODE_FORBIDDEN    = 403
 	RESP_CODE_NOTFOUND     = 404
 	RESP_CODE_CONFLICT     = 409
+	RESP_CODE_BADRANGE     = 416
 	RESP_CODE_ERROR        = 500
+	RESP_CODE_UNAVAILABLE  = 503
 
 	// Text Status Codes
 	RESP_CODE_OK_T           = "OK"
 	RESP_CODE_CREATED_T      = "Created"
 	RESP_CODE_NOCONTENT_T    = "No Content"
+	RESP_CODE_PARTIAL_T      = "Partial Content"
 	RESP_CODE_NOTMODIFIED_T  = "Not Modified"
 	RESP_CODE_BADREQ_T       = "The system could not process your request, the expected data was incorrect."
 	RESP_CODE_UNAUTHORIZED_T = "You are not authorized to perform that action."
 	RESP_CODE_FORBIDDEN_T    = "You are not authorized to perform that action."
 	RESP_CODE_NOTFOUND_T     = "Not Found"
 	RESP_CODE_CONFLICT_T     = "Conflict"
+	RESP_CODE_BADRANGE_T     = "Range Not Satisfiable"
 	RESP_CODE_ERROR_T        = "An internal server error occured, please refer to the server log."
+	RESP_CODE_UNAVAILABLE_T  = "Service Unavailable"
 )
 
 // // Response Code Interface
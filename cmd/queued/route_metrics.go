@@ -0,0 +1,153 @@
+package main
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/negroni"
+	"github.com/gorilla/mux"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the latency buckets requests are counted in, slower
+// requests are only counted in the total
+var LatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Latency of the requests to one route
+type RouteStats struct {
+	Method  string
+	Path    string // Template of the route, such as /api/jobs/{id}
+	Count   int64
+	Errors  int64 // Responses with a 5xx status
+	Total   time.Duration
+	Max     time.Duration
+	Buckets []int64 // Requests no slower than each of LatencyBuckets
+}
+
+/*
+ * Negroni middleware recording the latency of requests to each API route, so
+ * slow paths can be found under load, and logging requests slower than Slow
+ * with their user and parameters. Requests are counted by route template so
+ * job IDs do not give each job its own route. Requests that are not to a route
+ * of the router, such as web files, are not counted.
+ */
+type RouteMetrics struct {
+	Router *mux.Router
+	T      TokenStore
+	Slow   time.Duration // 0 does not log slow requests
+
+	routes map[string]*RouteStats
+	sync.Mutex
+}
+
+func NewRouteMetrics(router *mux.Router, t TokenStore, slow time.Duration) *RouteMetrics {
+	return &RouteMetrics{
+		Router: router,
+		T:      t,
+		Slow:   slow,
+		routes: map[string]*RouteStats{},
+	}
+}
+
+func (m *RouteMetrics) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var match mux.RouteMatch
+	if !m.Router.Match(r, &match) {
+		next(rw, r)
+		return
+	}
+	tpl, err := match.Route.GetPathTemplate()
+	if err != nil {
+		next(rw, r)
+		return
+	}
+
+	start := time.Now()
+	next(rw, r)
+	latency := time.Since(start)
+
+	status := rw.(negroni.ResponseWriter).Status()
+	m.record(r.Method, tpl, status, latency)
+
+	if m.Slow <= 0 || latency < m.Slow {
+		return
+	}
+
+	fields := log.Fields{
+		"method":  r.Method,
+		"route":   tpl,
+		"path":    r.URL.Path,
+		"status":  status,
+		"latency": latency,
+	}
+	if u, err := m.T.GetUser(r.Header.Get("AuthorizationToken")); err == nil {
+		fields["username"] = u.Username
+	}
+	for k, v := range match.Vars {
+		fields["var_"+k] = v
+	}
+	if q := r.URL.Query(); len(q) > 0 {
+		fields["query"] = q.Encode()
+	}
+	log.WithFields(fields).Warn("Slow API request.")
+}
+
+func (m *RouteMetrics) record(method, tpl string, status int, latency time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	key := method + " " + tpl
+	rs, ok := m.routes[key]
+	if !ok {
+		rs = &RouteStats{
+			Method:  method,
+			Path:    tpl,
+			Buckets: make([]int64, len(LatencyBuckets)),
+		}
+		m.routes[key] = rs
+	}
+
+	rs.Count++
+	if status >= 500 {
+		rs.Errors++
+	}
+	rs.Total += latency
+	if latency > rs.Max {
+		rs.Max = latency
+	}
+	for i, b := range LatencyBuckets {
+		if latency <= b {
+			rs.Buckets[i]++
+		}
+	}
+}
+
+// Get a copy of the stats of every route requested, slowest on average first
+func (m *RouteMetrics) Routes() []RouteStats {
+	m.Lock()
+	defer m.Unlock()
+
+	routes := make([]RouteStats, 0, len(m.routes))
+	for _, rs := range m.routes {
+		c := *rs
+		c.Buckets = append([]int64(nil), rs.Buckets...)
+		routes = append(routes, c)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Total/time.Duration(routes[i].Count) > routes[j].Total/time.Duration(routes[j].Count)
+	})
+	return routes
+}
@@ -4,31 +4,49 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"flag"
+	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmmcatee/cracklord/common"
 	"github.com/jmmcatee/cracklord/common/log"
 	"github.com/jmmcatee/cracklord/common/resource"
+	"github.com/jmmcatee/cracklord/common/shared"
+	"github.com/jmmcatee/cracklord/common/vault"
 	"github.com/jmmcatee/cracklord/plugins/tools/hashcat"
 	"github.com/jmmcatee/cracklord/plugins/tools/johndict"
 	"github.com/jmmcatee/cracklord/plugins/tools/nmap"
 	"github.com/jmmcatee/cracklord/plugins/tools/testtimercpu"
 	"github.com/jmmcatee/cracklord/plugins/tools/testtimergpu"
 	"github.com/vaughan0/go-ini"
-	"io/ioutil"
 	"net/rpc"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Time to wait before trying to connect to the queueserver again
+const reconnectDelay = 10 * time.Second
+
 func main() {
 	//Set our logger to STDERR and level
 	log.SetOutput(os.Stderr)
 
 	// Define the flags
 	var confPath = flag.String("conf", "", "Configuration file to use")
+	var newKey = flag.Bool("newkey", false, "Print a new master key for encrypting configuration values and exit")
+	var encrypt = flag.Bool("encrypt", false, "Encrypt standard input with the master key for use in the configuration file and exit")
 
 	// Parse the flags
 	flag.Parse()
 
+	if *newKey || *encrypt {
+		if err := common.EncryptConfigFile(*confPath, *newKey, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR: "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Read the configuration file
 	var confFile ini.File
 	var confError error
@@ -117,6 +135,36 @@ func main() {
 		}
 	}
 
+	// Encrypted values in the config are decrypted with the master key
+	if key, err := common.MasterKeyFromConfig(resConf); err != nil {
+		log.Error("Unable to load the master key: " + err.Error())
+		return
+	} else if key != nil {
+		common.MasterKey = key
+		log.Info("Encrypted configuration values are decrypted with the master key.")
+	}
+
+	// The key and certificate can be kept in Vault instead of files
+	if vc, err := vault.Setup(confFile.Section("Vault")); err != nil {
+		log.Error("Unable to setup Vault: " + err.Error())
+		return
+	} else if vc != nil {
+		common.Secrets = vc
+		common.SecretRefresh = vc.Refresh
+		log.WithField("address", vc.Addr).Info("Secrets are read from Vault.")
+	}
+
+	// Keep recent log lines in memory so the queue can retrieve them
+	tailLines := 1000
+	if tl := common.StripQuotes(resConf["LogTailLines"]); tl != "" {
+		n, err := strconv.Atoi(tl)
+		if err != nil {
+			log.Error("Unable to parse LogTailLines: " + err.Error())
+		} else {
+			tailLines = n
+		}
+	}
+
 	log.WithFields(log.Fields{
 		"conf": *confPath,
 		"ip":   runIP,
@@ -126,6 +174,51 @@ func main() {
 	// Create a resource queue
 	resQueue := resource.NewResourceQueue()
 
+	if tailLines > 0 {
+		tail := cracklog.NewTailHook(tailLines)
+		log.AddHook(tail)
+		resQueue.SetLogSource(tail)
+	}
+
+	// Allow signed updates to be pushed from the queue
+	if uk := common.StripQuotes(resConf["UpdatePublicKey"]); uk != "" {
+		key, err := common.ParseUpdateKey(uk)
+		if err != nil {
+			log.Error("Unable to load the update public key: " + err.Error())
+			return
+		}
+		resQueue.SetUpdateKey(key)
+	}
+
+	// Wordlists and rules from the queue's shared bucket are downloaded here
+	shared.CacheDir = common.StripQuotes(resConf["SharedCacheDir"])
+
+	// Wordlists and rules in these directories are hashed and reported to the
+	// queue so jobs go to resources that have the files they need
+	var fileDirs []string
+	for _, d := range strings.Split(common.StripQuotes(resConf["WordlistDirs"]), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			fileDirs = append(fileDirs, d)
+		}
+	}
+	if len(fileDirs) > 0 {
+		resQueue.SetFileDirs(fileDirs)
+	}
+
+	// Running tasks are checkpointed when the cloud provider gives notice that
+	// it is taking back a spot or preemptible instance, so the queue can move
+	// them to other resources
+	switch notices := common.StripQuotes(resConf["InterruptionNotices"]); notices {
+	case "off":
+	case "", "auto":
+		resQueue.WatchInterruptions("auto")
+	case common.CLOUD_AWS, common.CLOUD_GCP, common.CLOUD_AZURE:
+		resQueue.WatchInterruptions(notices)
+	default:
+		log.Error("Unknown cloud provider for InterruptionNotices: " + notices)
+		return
+	}
+
 	//Get the configuration section for plugins
 	pluginConf := confFile.Section("Plugins")
 	if len(pluginConf) == 0 {
@@ -152,13 +245,22 @@ func main() {
 		resQueue.AddTool(testtimercpu.NewTooler())
 	}
 
+	// Tool binaries from the repository of the queue are installed here once
+	// they are verified with the update key
+	if bd := common.StripQuotes(resConf["BinaryDir"]); bd != "" {
+		if err := resQueue.SetBinaryDir(bd); err != nil {
+			log.Error("Unable to setup the tool binary directory: " + err.Error())
+			return
+		}
+	}
+
 	// Get an RPC server
 	res := rpc.NewServer()
 
 	// Register the RPC endpoints
 	res.Register(&resQueue)
 
-	caBytes, err := ioutil.ReadFile(caCertPath)
+	caBytes, err := common.ReadSecretFile(caCertPath)
 	if err != nil {
 		log.Error("Unable to read CA certificate: " + err.Error())
 		return
@@ -169,16 +271,17 @@ func main() {
 	caPool.AppendCertsFromPEM(caBytes)
 
 	// Load the cert and key files
-	tlscert, err := tls.LoadX509KeyPair(resCertPath, resKeyPath)
+	tlscert, err := common.LoadKeyPair(resCertPath, resKeyPath)
 	if err != nil {
 		log.Error("There was an error loading the resource key or certificate files: " + err.Error())
 		return
 	}
+	tlscert.Watch()
 
-	// Setup TLS connection
+	// Setup TLS connection, the key pair is replaced when it is rotated
 	tlsconfig := &tls.Config{}
-	tlsconfig.Certificates = make([]tls.Certificate, 1)
-	tlsconfig.Certificates[0] = tlscert
+	tlsconfig.GetCertificate = tlscert.GetCertificate
+	tlsconfig.GetClientCertificate = tlscert.GetClientCertificate
 	tlsconfig.RootCAs = caPool
 	tlsconfig.ClientCAs = caPool
 	tlsconfig.ClientAuth = tls.RequireAndVerifyClientCert
@@ -193,6 +296,19 @@ func main() {
 	tlsconfig.MinVersion = tls.VersionTLS12
 	tlsconfig.SessionTicketsDisabled = true
 
+	// If a queue address is configured we dial out to the queue instead of
+	// listening for it to connect to us
+	queueAddr := common.StripQuotes(resConf["QueueAddress"])
+	if queueAddr != "" {
+		name := common.StripQuotes(resConf["ResourceName"])
+		if name == "" {
+			name, _ = os.Hostname()
+		}
+
+		reverseConnect(res, queueAddr, name, common.StripQuotes(resConf["QueueProxy"]), tlsconfig)
+		return
+	}
+
 	listen, err := tls.Listen("tcp", runIP+":"+runPort, tlsconfig)
 	if err != nil {
 		log.Error("Unable to bind to '" + runIP + ":" + runPort + "':" + err.Error())
@@ -219,3 +335,37 @@ func main() {
 
 	listen.Close()
 }
+
+// Connect out to the queue server and serve RPC requests over the connection,
+// reconnecting whenever it is lost
+func reverseConnect(res *rpc.Server, addr, name, proxy string, tlsconfig *tls.Config) {
+	for {
+		log.WithFields(log.Fields{
+			"addr":  addr,
+			"name":  name,
+			"proxy": proxy != "",
+		}).Info("Connecting to queueserver.")
+
+		conn, err := common.DialTLS(proxy, addr, 15*time.Second, tlsconfig)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to connect to the queueserver.")
+			time.Sleep(reconnectDelay)
+			continue
+		}
+
+		// Identify ourselves and then serve the queue over this connection
+		_, err = conn.Write([]byte(name + "\n"))
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to register with the queueserver.")
+			conn.Close()
+			time.Sleep(reconnectDelay)
+			continue
+		}
+
+		res.ServeConn(conn)
+
+		log.Warn("Connection to the queueserver was lost.")
+		time.Sleep(reconnectDelay)
+	}
+}
+
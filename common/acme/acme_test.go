@@ -0,0 +1,264 @@
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A minimal ACME server that issues certificates for http-01 challenges
+// answered by the solver handler
+type fakeCA struct {
+	t       *testing.T
+	srv     *httptest.Server
+	solver  http.Handler
+	account *ecdsa.PublicKey
+	token   string
+	status  string
+	cert    []byte
+	sync.Mutex
+}
+
+func (f *fakeCA) url(p string) string {
+	return f.srv.URL + p
+}
+
+// Check the JWS signature and return the decoded payload
+func (f *fakeCA) verify(r *http.Request) []byte {
+	var msg map[string]string
+	json.NewDecoder(r.Body).Decode(&msg)
+
+	ph, _ := base64.RawURLEncoding.DecodeString(msg["protected"])
+	var protected struct {
+		Alg   string            `json:"alg"`
+		Nonce string            `json:"nonce"`
+		URL   string            `json:"url"`
+		Kid   string            `json:"kid"`
+		JWK   map[string]string `json:"jwk"`
+	}
+	json.Unmarshal(ph, &protected)
+
+	if protected.URL != f.url(r.URL.Path) || protected.Nonce == "" {
+		f.t.Errorf("Bad protected header for %s: %s", r.URL.Path, ph)
+	}
+
+	key := f.account
+	if protected.Kid == "" {
+		x, _ := base64.RawURLEncoding.DecodeString(protected.JWK["x"])
+		y, _ := base64.RawURLEncoding.DecodeString(protected.JWK["y"])
+		key = &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		f.account = key
+	} else if protected.Kid != f.url("/account") {
+		f.t.Errorf("Unexpected kid %s", protected.Kid)
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(msg["signature"])
+	hash := sha256.Sum256([]byte(msg["protected"] + "." + msg["payload"]))
+	r1 := new(big.Int).SetBytes(sig[:32])
+	s1 := new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(key, hash[:], r1, s1) {
+		f.t.Errorf("Invalid signature on request to %s", r.URL.Path)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(msg["payload"])
+	return payload
+}
+
+func (f *fakeCA) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	rw.Header().Set("Replay-Nonce", "nonce")
+
+	if r.URL.Path == "/directory" {
+		json.NewEncoder(rw).Encode(map[string]string{
+			"newNonce":   f.url("/nonce"),
+			"newAccount": f.url("/new-account"),
+			"newOrder":   f.url("/new-order"),
+		})
+		return
+	}
+	if r.URL.Path == "/nonce" {
+		return
+	}
+
+	payload := f.verify(r)
+
+	switch r.URL.Path {
+	case "/new-account":
+		rw.Header().Set("Location", f.url("/account"))
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte("{}"))
+	case "/new-order":
+		rw.Header().Set("Location", f.url("/order"))
+		rw.WriteHeader(http.StatusCreated)
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"status":         "pending",
+			"authorizations": []string{f.url("/authz")},
+			"finalize":       f.url("/finalize"),
+		})
+	case "/authz":
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"status":     f.status,
+			"identifier": map[string]string{"type": "dns", "value": "queue.example.com"},
+			"challenges": []map[string]string{
+				{"type": "dns-01", "url": f.url("/chal-dns"), "token": "other"},
+				{"type": "http-01", "url": f.url("/chal"), "token": f.token},
+			},
+		})
+	case "/chal":
+		// Fetch the key authorization through the solver like a real CA would
+		rec := httptest.NewRecorder()
+		f.solver.ServeHTTP(rec, httptest.NewRequest("GET", challengePath+f.token, nil))
+
+		thumb, _ := thumbprint(f.account)
+		if rec.Body.String() == f.token+"."+thumb {
+			f.status = "valid"
+		} else {
+			f.t.Errorf("Unexpected key authorization %q", rec.Body.String())
+			f.status = "invalid"
+		}
+		rw.Write([]byte("{}"))
+	case "/finalize":
+		var req map[string]string
+		json.Unmarshal(payload, &req)
+		der, _ := base64.RawURLEncoding.DecodeString(req["csr"])
+		csr, err := x509.ParseCertificateRequest(der)
+		if err != nil {
+			f.t.Fatal(err)
+		}
+		f.cert = issue(f.t, csr)
+
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"status":      "valid",
+			"certificate": f.url("/cert"),
+		})
+	case "/cert":
+		rw.Write(f.cert)
+	default:
+		http.NotFound(rw, r)
+	}
+}
+
+// Sign the CSR with a throwaway CA
+func issue(t *testing.T, csr *x509.CertificateRequest) []byte {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(90 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, csr.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestManagerObtain(t *testing.T) {
+	PollInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	solver := NewHTTPSolver()
+	ca := &fakeCA{t: t, token: "token123", status: "pending", solver: solver.Handler(http.NotFoundHandler())}
+	ca.srv = httptest.NewServer(ca)
+	defer ca.srv.Close()
+
+	m, err := NewManager(ca.url("/directory"), dir, "admin@example.com", []string{"queue.example.com"}, solver)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "queue.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf.DNSNames[0] != "queue.example.com" {
+		t.Errorf("Unexpected certificate names %v", cert.Leaf.DNSNames)
+	}
+
+	// The challenge token should no longer be served
+	rec := httptest.NewRecorder()
+	solver.Handler(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest("GET", challengePath+"token123", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Error("Challenge response was not cleaned up")
+	}
+
+	// A new manager should pick up the cached certificate and account key
+	again, err := NewManager(ca.url("/directory"), dir, "", []string{"queue.example.com"}, solver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.needsRenewal() {
+		t.Error("Cached certificate was not loaded")
+	}
+	if again.Client.Key.X.Cmp(m.Client.Key.X) != 0 {
+		t.Error("Cached account key was not loaded")
+	}
+
+	// A cached certificate for other domains must not be used
+	other, err := NewManager(ca.url("/directory"), dir, "", []string{"other.example.com"}, solver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !other.needsRenewal() {
+		t.Error("Cached certificate for the wrong domain was loaded")
+	}
+}
+
+func TestDNSValue(t *testing.T) {
+	ch := Challenge{KeyAuth: "token.thumb"}
+
+	sum := sha256.Sum256([]byte("token.thumb"))
+	want := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	if ch.DNSValue() != want {
+		t.Errorf("Expected %s but got %s", want, ch.DNSValue())
+	}
+	if strings.ContainsAny(ch.DNSValue(), "=+/") {
+		t.Error("DNS value must be unpadded base64url")
+	}
+}
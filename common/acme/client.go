@@ -0,0 +1,491 @@
+// Package acme is a small ACME (RFC 8555) client used to get certificates for
+// the queue server API from Let's Encrypt or any other ACME certificate
+// authority. It supports the http-01 and dns-01 challenges.
+package acme
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The Let's Encrypt production directory
+const LetsEncryptURL = "https://acme-v02.api.letsencrypt.org/directory"
+
+// The ACME server directory listing the URLs of each operation
+type directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+}
+
+// An ACME error document
+type problem struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail"`
+}
+
+func (p problem) Error() string {
+	return "ACME error " + p.Type + ": " + p.Detail
+}
+
+type order struct {
+	Status         string   `json:"status"`
+	Authorizations []string `json:"authorizations"`
+	Finalize       string   `json:"finalize"`
+	Certificate    string   `json:"certificate"`
+	Error          *problem `json:"error"`
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type challenge struct {
+	Type   string   `json:"type"`
+	URL    string   `json:"url"`
+	Token  string   `json:"token"`
+	Status string   `json:"status"`
+	Error  *problem `json:"error"`
+}
+
+type authorization struct {
+	Status     string      `json:"status"`
+	Identifier identifier  `json:"identifier"`
+	Challenges []challenge `json:"challenges"`
+}
+
+// Client talks to an ACME server using a single account key. It is safe for
+// use from multiple goroutines.
+type Client struct {
+	DirectoryURL string
+	Key          *ecdsa.PrivateKey
+	HTTP         *http.Client
+
+	dir    *directory
+	kid    string
+	nonces []string
+	sync.Mutex
+}
+
+// Load the directory on first use
+func (c *Client) discover() error {
+	if c.dir != nil {
+		return nil
+	}
+
+	if c.HTTP == nil {
+		c.HTTP = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := c.HTTP.Get(c.DirectoryURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	c.saveNonce(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unable to read the ACME directory, server returned %d.", resp.StatusCode)
+	}
+
+	var d directory
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	if err != nil {
+		return err
+	}
+	if d.NewNonce == "" || d.NewAccount == "" || d.NewOrder == "" {
+		return errors.New("ACME directory is missing required URLs.")
+	}
+
+	c.dir = &d
+	return nil
+}
+
+func (c *Client) saveNonce(resp *http.Response) {
+	if n := resp.Header.Get("Replay-Nonce"); n != "" {
+		c.nonces = append(c.nonces, n)
+	}
+}
+
+func (c *Client) nonce() (string, error) {
+	if len(c.nonces) > 0 {
+		n := c.nonces[len(c.nonces)-1]
+		c.nonces = c.nonces[:len(c.nonces)-1]
+		return n, nil
+	}
+
+	resp, err := c.HTTP.Head(c.dir.NewNonce)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	n := resp.Header.Get("Replay-Nonce")
+	if n == "" {
+		return "", errors.New("ACME server did not return a nonce.")
+	}
+
+	return n, nil
+}
+
+// Make a signed POST request. A nil payload makes a POST-as-GET request. The
+// body is returned once the response has been checked for errors.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (c *Client) post(url string, payload interface{}) (*http.Response, []byte, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// A bad nonce is retried once with the fresh nonce from the error response
+	for attempt := 0; ; attempt++ {
+		n, err := c.nonce()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		msg, err := signJWS(c.Key, c.kid, n, url, body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := c.HTTP.Post(url, "application/jose+json", bytes.NewReader(msg))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c.saveNonce(resp)
+
+		if resp.StatusCode >= 400 {
+			var p problem
+			json.Unmarshal(data, &p)
+			if p.Type == "urn:ietf:params:acme:error:badNonce" && attempt == 0 {
+				continue
+			}
+			if p.Type == "" {
+				return resp, data, fmt.Errorf("ACME request failed with status %d.", resp.StatusCode)
+			}
+			return resp, data, p
+		}
+
+		return resp, data, nil
+	}
+}
+
+// Register the account key with the server, or look up the existing account
+// for it
+func (c *Client) Register(email string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	err := c.discover()
+	if err != nil {
+		return err
+	}
+
+	req := map[string]interface{}{
+		"termsOfServiceAgreed": true,
+	}
+	if email != "" {
+		req["contact"] = []string{"mailto:" + email}
+	}
+
+	resp, _, err := c.post(c.dir.NewAccount, req)
+	if err != nil {
+		return err
+	}
+
+	c.kid = resp.Header.Get("Location")
+	if c.kid == "" {
+		return errors.New("ACME server did not return an account URL.")
+	}
+
+	return nil
+}
+
+// A challenge that has to be answered before an order can be finalized
+type Challenge struct {
+	Type    string
+	Domain  string
+	Token   string
+	KeyAuth string // Served at /.well-known/acme-challenge/<Token> for http-01
+}
+
+// The TXT record value expected at _acme-challenge.<Domain> for dns-01
+func (ch Challenge) DNSValue() string {
+	sum := sha256.Sum256([]byte(ch.KeyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Solver makes a challenge answerable and removes it again afterwards
+type Solver interface {
+	Type() string
+	Present(ch Challenge) error
+	CleanUp(ch Challenge) error
+}
+
+// Get a certificate for the domains, answering challenges with the solver.
+// The PEM encoded certificate chain is returned.
+func (c *Client) Obtain(domains []string, csr []byte, solver Solver) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.kid == "" {
+		return nil, errors.New("ACME account has not been registered.")
+	}
+
+	req := map[string]interface{}{}
+	var ids []identifier
+	for _, d := range domains {
+		ids = append(ids, identifier{Type: "dns", Value: d})
+	}
+	req["identifiers"] = ids
+
+	resp, data, err := c.post(c.dir.NewOrder, req)
+	if err != nil {
+		return nil, err
+	}
+
+	orderURL := resp.Header.Get("Location")
+	var o order
+	err = json.Unmarshal(data, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, authzURL := range o.Authorizations {
+		err = c.authorize(authzURL, solver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csrReq := map[string]string{
+		"csr": base64.RawURLEncoding.EncodeToString(csr),
+	}
+	_, data, err = c.post(o.Finalize, csrReq)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for the certificate to be issued
+	for i := 0; o.Status != "valid"; i++ {
+		if o.Status == "invalid" {
+			if o.Error != nil {
+				return nil, *o.Error
+			}
+			return nil, errors.New("ACME order became invalid.")
+		}
+		if i >= pollAttempts {
+			return nil, errors.New("Timed out waiting for the certificate to be issued.")
+		}
+
+		time.Sleep(PollInterval)
+
+		_, data, err = c.post(orderURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		o = order{}
+		err = json.Unmarshal(data, &o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, cert, err := c.post(o.Certificate, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// How often and how many times pending authorizations and orders are checked
+var PollInterval = 2 * time.Second
+
+const pollAttempts = 60
+
+// Answer a single authorization with the solver
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (c *Client) authorize(url string, solver Solver) error {
+	_, data, err := c.post(url, nil)
+	if err != nil {
+		return err
+	}
+
+	var authz authorization
+	err = json.Unmarshal(data, &authz)
+	if err != nil {
+		return err
+	}
+	if authz.Status == "valid" {
+		return nil
+	}
+
+	var chal *challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].Type == solver.Type() {
+			chal = &authz.Challenges[i]
+		}
+	}
+	if chal == nil {
+		return errors.New("ACME server did not offer a " + solver.Type() + " challenge for " + authz.Identifier.Value + ".")
+	}
+
+	thumb, err := thumbprint(&c.Key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	ch := Challenge{
+		Type:    chal.Type,
+		Domain:  authz.Identifier.Value,
+		Token:   chal.Token,
+		KeyAuth: chal.Token + "." + thumb,
+	}
+
+	err = solver.Present(ch)
+	if err != nil {
+		return err
+	}
+	defer solver.CleanUp(ch)
+
+	// Tell the server the challenge is ready to be checked
+	_, _, err = c.post(chal.URL, struct{}{})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < pollAttempts; i++ {
+		time.Sleep(PollInterval)
+
+		_, data, err = c.post(url, nil)
+		if err != nil {
+			return err
+		}
+
+		authz = authorization{}
+		err = json.Unmarshal(data, &authz)
+		if err != nil {
+			return err
+		}
+
+		switch authz.Status {
+		case "valid":
+			return nil
+		case "pending", "processing":
+			continue
+		default:
+			for _, c := range authz.Challenges {
+				if c.Error != nil {
+					return *c.Error
+				}
+			}
+			return errors.New("Authorization for " + ch.Domain + " failed.")
+		}
+	}
+
+	return errors.New("Timed out waiting for authorization of " + ch.Domain + ".")
+}
+
+// The JSON web key of a P-256 public key
+func jwk(pub *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"crv": "P-256",
+		"kty": "EC",
+		"x":   base64.RawURLEncoding.EncodeToString(pad(pub.X, 32)),
+		"y":   base64.RawURLEncoding.EncodeToString(pad(pub.Y, 32)),
+	}
+}
+
+// The RFC 7638 thumbprint of the account key used in key authorizations
+func thumbprint(pub *ecdsa.PublicKey) (string, error) {
+	if pub.Curve != elliptic.P256() {
+		return "", errors.New("ACME account key must use the P-256 curve.")
+	}
+
+	// The members must be in lexicographic order with no whitespace
+	k := jwk(pub)
+	s := `{"crv":"` + k["crv"] + `","kty":"` + k["kty"] + `","x":"` + k["x"] + `","y":"` + k["y"] + `"}`
+	sum := sha256.Sum256([]byte(s))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Build a flattened JWS signed with ES256. The key is identified by its
+// account URL once registered, and by the key itself before that.
+func signJWS(key *ecdsa.PrivateKey, kid, nonce, url string, payload []byte) ([]byte, error) {
+	protected := map[string]interface{}{
+		"alg":   "ES256",
+		"nonce": nonce,
+		"url":   url,
+	}
+	if kid != "" {
+		protected["kid"] = kid
+	} else {
+		protected["jwk"] = jwk(&key.PublicKey)
+	}
+
+	ph, err := json.Marshal(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	p64 := base64.RawURLEncoding.EncodeToString(ph)
+	b64 := base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := sha256.Sum256([]byte(p64 + "." + b64))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sig := append(pad(r, 32), pad(s, 32)...)
+
+	return json.Marshal(map[string]string{
+		"protected": p64,
+		"payload":   b64,
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+	})
+}
+
+// Left pad the big endian bytes of n to size
+func pad(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,264 @@
+package acme
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Certificates are renewed once they are this close to expiring
+var RenewBefore = 30 * 24 * time.Hour
+
+// How often the certificate expiry is checked
+var RenewCheckInterval = 12 * time.Hour
+
+// How long to wait before trying again when getting a certificate failed
+var RetryInterval = time.Hour
+
+// Manager keeps a certificate for a set of domains, getting it from the ACME
+// server when needed and renewing it in the background. Certificates and the
+// account key are cached in a directory so restarts do not request new ones.
+type Manager struct {
+	Domains  []string
+	Email    string
+	CacheDir string
+	Client   *Client
+	Solver   Solver
+
+	cert       *tls.Certificate
+	expires    time.Time
+	registered bool
+	sync.RWMutex
+}
+
+// Create a manager using the account key from the cache directory, generating
+// one if it does not exist yet
+func NewManager(directoryURL, cacheDir, email string, domains []string, solver Solver) (*Manager, error) {
+	if len(domains) == 0 {
+		return nil, errors.New("At least one domain is required for ACME.")
+	}
+
+	err := os.MkdirAll(cacheDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := loadOrCreateKey(filepath.Join(cacheDir, "account.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		Domains:  domains,
+		Email:    email,
+		CacheDir: cacheDir,
+		Client:   &Client{DirectoryURL: directoryURL, Key: key},
+		Solver:   solver,
+	}
+
+	// A cached certificate is fine to use until it is renewed
+	err = m.loadCached()
+	if err != nil && !os.IsNotExist(err) {
+		log.WithField("error", err.Error()).Warn("Unable to load the cached ACME certificate.")
+	}
+
+	return m, nil
+}
+
+// Make sure a valid certificate is available and keep it renewed
+func (m *Manager) Start() error {
+	if m.needsRenewal() {
+		err := m.renew()
+		if err != nil {
+			m.RLock()
+			cached := m.cert != nil
+			m.RUnlock()
+
+			// An old certificate is better than refusing to start
+			if !cached {
+				return err
+			}
+			log.WithField("error", err.Error()).Error("Unable to renew the ACME certificate, using the cached certificate.")
+		}
+	}
+
+	go func() {
+		for {
+			wait := RenewCheckInterval
+			if m.needsRenewal() {
+				err := m.renew()
+				if err != nil {
+					log.WithField("error", err.Error()).Error("Unable to renew the ACME certificate.")
+					wait = RetryInterval
+				}
+			}
+
+			time.Sleep(wait)
+		}
+	}()
+
+	return nil
+}
+
+// Provide the current certificate to a TLS listener, use as tls.Config.GetCertificate
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if m.cert == nil {
+		return nil, errors.New("No ACME certificate is available yet.")
+	}
+
+	return m.cert, nil
+}
+
+func (m *Manager) needsRenewal() bool {
+	m.RLock()
+	defer m.RUnlock()
+
+	return m.cert == nil || time.Now().Add(RenewBefore).After(m.expires)
+}
+
+// Get a new certificate from the ACME server and cache it
+func (m *Manager) renew() error {
+	log.WithField("domains", m.Domains).Info("Requesting certificate from the ACME server.")
+
+	if !m.registered {
+		err := m.Client.Register(m.Email)
+		if err != nil {
+			return err
+		}
+		m.registered = true
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.Domains[0]},
+		DNSNames: m.Domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+
+	chain, err := m.Client.Obtain(m.Domains, csr, m.Solver)
+	if err != nil {
+		return err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	err = m.setCertificate(chain, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	// Write the key before the certificate so a partial write is never loaded
+	err = ioutil.WriteFile(filepath.Join(m.CacheDir, "key.pem"), keyPEM, 0600)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(m.CacheDir, "cert.pem"), chain, 0600)
+	if err != nil {
+		return err
+	}
+
+	m.RLock()
+	log.WithFields(log.Fields{
+		"domains": m.Domains,
+		"expires": m.expires,
+	}).Info("ACME certificate obtained.")
+	m.RUnlock()
+
+	return nil
+}
+
+func (m *Manager) loadCached() error {
+	chain, err := ioutil.ReadFile(filepath.Join(m.CacheDir, "cert.pem"))
+	if err != nil {
+		return err
+	}
+	keyPEM, err := ioutil.ReadFile(filepath.Join(m.CacheDir, "key.pem"))
+	if err != nil {
+		return err
+	}
+
+	return m.setCertificate(chain, keyPEM)
+}
+
+// Parse a certificate and key and start serving them
+func (m *Manager) setCertificate(chain, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(chain, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	cert.Leaf = leaf
+
+	// Do not keep serving a cached certificate for a different set of domains
+	for _, d := range m.Domains {
+		if leaf.VerifyHostname(d) != nil {
+			return errors.New("Certificate does not cover " + d + ".")
+		}
+	}
+
+	m.Lock()
+	m.cert = &cert
+	m.expires = leaf.NotAfter
+	m.Unlock()
+
+	return nil
+}
+
+func loadOrCreateKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("ACME account key is not PEM encoded.")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
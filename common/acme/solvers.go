@@ -0,0 +1,116 @@
+package acme
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The path http-01 challenges are requested from
+const challengePath = "/.well-known/acme-challenge/"
+
+// HTTPSolver answers http-01 challenges. Its handler has to be reachable on
+// port 80 of every domain.
+type HTTPSolver struct {
+	tokens map[string]string
+	sync.RWMutex
+}
+
+func NewHTTPSolver() *HTTPSolver {
+	return &HTTPSolver{
+		tokens: map[string]string{},
+	}
+}
+
+func (s *HTTPSolver) Type() string {
+	return "http-01"
+}
+
+func (s *HTTPSolver) Present(ch Challenge) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.tokens[ch.Token] = ch.KeyAuth
+	return nil
+}
+
+func (s *HTTPSolver) CleanUp(ch Challenge) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.tokens, ch.Token)
+	return nil
+}
+
+// Serve challenge responses and pass everything else to the fallback handler
+func (s *HTTPSolver) Handler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, challengePath) {
+			fallback.ServeHTTP(rw, r)
+			return
+		}
+
+		s.RLock()
+		keyAuth, ok := s.tokens[strings.TrimPrefix(r.URL.Path, challengePath)]
+		s.RUnlock()
+
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte(keyAuth))
+	})
+}
+
+// DNSHookSolver answers dns-01 challenges by running a command that creates
+// and removes the TXT record with the DNS provider. The command is given the
+// action (present or cleanup), the record name and the record value.
+type DNSHookSolver struct {
+	Command string
+	Wait    time.Duration // Time for the record to propagate after it is created
+}
+
+func (s *DNSHookSolver) Type() string {
+	return "dns-01"
+}
+
+func (s *DNSHookSolver) Present(ch Challenge) error {
+	err := s.run("present", ch)
+	if err != nil {
+		return err
+	}
+
+	time.Sleep(s.Wait)
+	return nil
+}
+
+func (s *DNSHookSolver) CleanUp(ch Challenge) error {
+	return s.run("cleanup", ch)
+}
+
+func (s *DNSHookSolver) run(action string, ch Challenge) error {
+	record := "_acme-challenge." + ch.Domain
+
+	out, err := exec.Command(s.Command, action, record, ch.DNSValue()).CombinedOutput()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"action": action,
+			"record": record,
+			"output": string(out),
+		}).Error("ACME DNS hook failed.")
+		return errors.New("ACME DNS hook failed to " + action + " " + record + ": " + err.Error())
+	}
+
+	log.WithFields(log.Fields{
+		"action": action,
+		"record": record,
+	}).Debug("ACME DNS hook completed.")
+
+	return nil
+}
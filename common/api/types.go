@@ -0,0 +1,1206 @@
+// Types of the JSON requests and responses of the queue server API, shared by
+// the server and the cracklordclient package so both are always in sync.
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Login Request Structure
+type LoginReq struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Login Response Structure
+type LoginResp struct {
+	Status         int    `json:"status"`
+	Message        string `json:"message"`
+	MessageKey     string `json:"messagekey"`
+	Token          string `json:"token"`
+	Role           string `json:"role"`
+	PasswordChange bool   `json:"passwordchange"`
+}
+
+// Error Response Structure, sent by middleware that refuses a request before
+// it reaches a handler
+type ErrorResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Logout Response Structure
+type LogoutResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// User profile API structure
+type APIUser struct {
+	Username       string    `json:"username"`
+	Role           string    `json:"role"`
+	Groups         []string  `json:"groups"`
+	LogOnTime      time.Time `json:"logontime"`
+	PasswordChange bool      `json:"passwordchange"`
+}
+
+// Current user read response structure
+type UserMeResp struct {
+	Status     int     `json:"status"`
+	Message    string  `json:"message"`
+	MessageKey string  `json:"messagekey"`
+	User       APIUser `json:"user"`
+}
+
+// A session of the current user
+type APISession struct {
+	ID         string    `json:"id"`
+	RemoteAddr string    `json:"remoteaddr"`
+	UserAgent  string    `json:"useragent"`
+	Location   string    `json:"location,omitempty"`
+	LogOnTime  time.Time `json:"logontime"`
+	Expires    time.Time `json:"expires"`
+	Current    bool      `json:"current"` // The session the request was made with
+}
+
+type UserSessionsResp struct {
+	Status     int          `json:"status"`
+	Message    string       `json:"message"`
+	MessageKey string       `json:"messagekey"`
+	Sessions   []APISession `json:"sessions"`
+}
+
+type UserSessionRevokeResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Notification settings of the current user
+type APINotificationPrefs struct {
+	Email     string `json:"email"`
+	Digest    string `json:"digest"`    // off, daily or shift
+	Resources bool   `json:"resources"` // Include resources that went offline, Administrators only
+}
+
+type UserNotificationsResp struct {
+	Status        int                  `json:"status"`
+	Message       string               `json:"message"`
+	MessageKey    string               `json:"messagekey"`
+	Notifications APINotificationPrefs `json:"notifications"`
+}
+
+// Current user password change request structure
+type UserPasswordReq struct {
+	OldPassword string `json:"oldpassword"`
+	NewPassword string `json:"newpassword"`
+}
+
+// Current user password change response structure
+type UserPasswordResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Tool API structure
+type APITool struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type APIToolDetail struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Form        *json.RawMessage  `json:"form"`
+	Schema      *json.RawMessage  `json:"schema"`
+	Defaults    map[string]string `json:"defaults"`
+	Locked      map[string]string `json:"locked"`
+	FormVersion int               `json:"formversion"` // Version of the form to send with jobs so parameters from older forms are translated
+}
+
+// Tools List Response Structure
+type ToolsResp struct {
+	Status     int       `json:"status"`
+	Message    string    `json:"message"`
+	MessageKey string    `json:"messagekey"`
+	Tools      []APITool `json:"tools"`
+}
+
+// Get Tools structures
+type ToolsGetResp struct {
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	MessageKey string        `json:"messagekey"`
+	Tool       APIToolDetail `json:"tool"`
+}
+
+// Resource Manager API structure
+type APIResourceManager struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type APIResourceManagerDetail struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Form        *json.RawMessage `json:"form"`
+	Schema      *json.RawMessage `json:"schema"`
+}
+
+// Tools List Response Structure
+type ResourceManagersResp struct {
+	Status           int                  `json:"status"`
+	Message          string               `json:"message"`
+	MessageKey       string               `json:"messagekey"`
+	ResourceManagers []APIResourceManager `json:"resourcemanagers"`
+}
+
+type ResourceManagerGetResp struct {
+	Status          int                      `json:"status"`
+	Message         string                   `json:"message"`
+	MessageKey      string                   `json:"messagekey"`
+	ResourceManager APIResourceManagerDetail `json:"resourcemanager"`
+}
+
+// API Jobs structure
+type APIJob struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	ResourceID    string     `json:"resourceid"`
+	Owner         string     `json:"owner"`
+	StartTime     time.Time  `json:"starttime"`
+	ETC           string     `json:"etc"`
+	CrackedHashes int64      `json:"crackedhashes"`
+	TotalHashes   int64      `json:"totalhashes"`
+	Progress      float64    `json:"progress"`
+	ToolID        string     `json:"toolid"`
+	Project       string     `json:"project,omitempty"`
+	Queue         string     `json:"queue,omitempty"`
+	Stalled       *time.Time `json:"stalled,omitempty"` // When the job was found to not be making progress
+	Cost          float64    `json:"cost,omitempty"`    // Dollars spent on resources with a cost per hour
+}
+
+type APIJobDetail struct {
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Status            string            `json:"status"`
+	ResourceID        string            `json:"resourceid"`
+	Owner             string            `json:"owner"`
+	StartTime         time.Time         `json:"starttime"`
+	ETC               string            `json:"etc"`
+	CrackedHashes     int64             `json:"crackedhashes"`
+	TotalHashes       int64             `json:"totalhashes"`
+	Progress          float64           `json:"progress"`
+	Params            map[string]string `json:"params"`
+	ToolID            string            `json:"toolid"`
+	PerformanceTitle  string            `json:"performancetitle"`
+	PerformanceData   map[string]string `json:"performancedata"`
+	OutputTitles      []string          `json:"outputtitles"`
+	OutputData        [][]string        `json:"outputdata"`
+	OutputSpilled     int               `json:"outputspilled"`
+	MaxRuntime        int               `json:"maxruntime"`
+	History           []APIJobEvent     `json:"history"`
+	Constraints       *APIConstraints   `json:"constraints,omitempty"`
+	Checkpoint        *APICheckpoint    `json:"checkpoint,omitempty"`
+	Args              []string          `json:"args,omitempty"`
+	Env               map[string]string `json:"env,omitempty"`
+	Project           string            `json:"project,omitempty"`
+	Stalled           *time.Time        `json:"stalled,omitempty"`
+	Purged            *time.Time        `json:"purged,omitempty"` // When the hashes and results were removed
+	Debug             bool              `json:"debug"`
+	Queue             string            `json:"queue,omitempty"`
+	Cost              float64           `json:"cost,omitempty"`         // Dollars spent on resources with a cost per hour
+	CostApproved      bool              `json:"costapproved,omitempty"` // May run over the budget of its project
+	ResultKey         string            `json:"resultkey,omitempty"`    // Fingerprint of the OpenPGP key the results are encrypted for
+	Sensitive         bool              `json:"sensitive,omitempty"`    // Needs a second Administrator to approve it
+	ApprovedBy        string            `json:"approvedby,omitempty"`
+	FormVersion       int               `json:"formversion,omitempty"`       // Version of the tool form the parameters are for
+	PerformanceMetric *APIMetric        `json:"performancemetric,omitempty"` // What the performance data measures
+	OutputMetrics     []APIMetric       `json:"outputmetrics"`               // What each of the output titles holds
+}
+
+// The last restore point saved for a job
+type APICheckpoint struct {
+	Taken         time.Time `json:"taken"`
+	Progress      float64   `json:"progress"`
+	CrackedHashes int       `json:"crackedhashes"`
+}
+
+// An entry in the audit trail of a job
+type APIJobEvent struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail"`
+}
+
+// What the performance data or a column of output of a job holds. Values of
+// a given scale are multiplied by it for the unit.
+type APIMetric struct {
+	Name  string  `json:"name"`  // Same across tools, such as speed or plaintext
+	Title string  `json:"title"` // In the language of the request
+	Unit  string  `json:"unit,omitempty"`
+	Kind  string  `json:"kind"` // rate, percent, count, text, hash, plaintext or account
+	Scale float64 `json:"scale,omitempty"`
+}
+
+// Get Jobs structure
+type GetJobsResp struct {
+	Status     int      `json:"status"`
+	Message    string   `json:"message"`
+	MessageKey string   `json:"messagekey"`
+	Jobs       []APIJob `json:"jobs"`
+}
+
+type JobOutputResp struct {
+	Status        int         `json:"status"`
+	Message       string      `json:"message"`
+	MessageKey    string      `json:"messagekey"`
+	OutputTitles  []string    `json:"outputtitles"`
+	OutputMetrics []APIMetric `json:"outputmetrics"`
+	OutputData    [][]string  `json:"outputdata"`
+}
+
+// An account compared between two jobs, either a user or a hash
+type APIAccountDiff struct {
+	Account    string `json:"account"`
+	OldHash    string `json:"oldhash"`
+	NewHash    string `json:"newhash"`
+	OldCracked bool   `json:"oldcracked"`
+	NewCracked bool   `json:"newcracked"`
+	Plaintext  string `json:"plaintext"`
+}
+
+type JobDiffResp struct {
+	Status          int              `json:"status"`
+	Message         string           `json:"message"`
+	MessageKey      string           `json:"messagekey"`
+	ByUser          bool             `json:"byuser"`
+	NewlyCracked    []APIAccountDiff `json:"newlycracked"`
+	StillCracked    []APIAccountDiff `json:"stillcracked"`
+	PasswordChanged []APIAccountDiff `json:"passwordchanged"`
+}
+
+// Parts of the configured password policy to replace for a compliance report
+type PolicyReportReq struct {
+	MinLength   *int     `json:"minlength"`
+	MinClasses  *int     `json:"minclasses"`
+	BannedWords []string `json:"bannedwords"` // Replaces the configured words when given
+	BreachList  *bool    `json:"breachlist"`  // False skips the configured breach list
+}
+
+// The policy a compliance report was made with
+type APIPasswordPolicy struct {
+	MinLength   int      `json:"minlength"`
+	MinClasses  int      `json:"minclasses"`
+	BannedWords []string `json:"bannedwords"`
+	BreachList  bool     `json:"breachlist"`
+}
+
+// A cracked account that fails the policy and why
+type APIPolicyAccount struct {
+	Account    string   `json:"account"`
+	Violations []string `json:"violations"`
+}
+
+type PolicyReportResp struct {
+	Status       int                `json:"status"`
+	Message      string             `json:"message"`
+	MessageKey   string             `json:"messagekey"`
+	Policy       APIPasswordPolicy  `json:"policy"`
+	ByUser       bool               `json:"byuser"`
+	Accounts     int                `json:"accounts"`
+	Cracked      int                `json:"cracked"`
+	Compliant    int                `json:"compliant"`
+	NonCompliant int                `json:"noncompliant"`
+	Violations   map[string]int     `json:"violations"`
+	Lengths      map[int]int        `json:"lengths"`
+	Failures     []APIPolicyAccount `json:"failures"`
+}
+
+// A checked account of a job and how often its password was seen in breaches
+type APIPwnedAccount struct {
+	Account string `json:"account"`
+	Hash    string `json:"hash"`
+	Cracked bool   `json:"cracked"`
+	Count   int    `json:"count"`
+}
+
+type PwnedReportResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Hashes     string            `json:"hashes"`
+	ByUser     bool              `json:"byuser"`
+	Checked    int               `json:"checked"`
+	Pwned      int               `json:"pwned"`
+	Accounts   []APIPwnedAccount `json:"accounts"`
+}
+
+// A hashcat mode a quick crack hash may be in
+type APIHashMode struct {
+	Mode string `json:"mode"`
+	Name string `json:"name"`
+}
+
+// Quick crack request structure
+type QuickCrackReq struct {
+	Hash    string `json:"hash"`
+	Mode    string `json:"mode"`    // Detected from the hash when missing
+	Webhook string `json:"webhook"` // URL posted to when the quick crack finishes
+}
+
+// Quick crack API structure
+type APIQuickCrack struct {
+	ID        string        `json:"id"`
+	Owner     string        `json:"owner"`
+	Hash      string        `json:"hash"`
+	Mode      string        `json:"mode"`
+	ModeName  string        `json:"modename"`
+	Modes     []APIHashMode `json:"modes,omitempty"` // Other modes the hash may be in
+	Jobs      []string      `json:"jobs"`
+	Status    string        `json:"status"`
+	Plaintext string        `json:"plaintext,omitempty"`
+	Created   time.Time     `json:"created"`
+	Finished  time.Time     `json:"finished"`
+}
+
+type QuickCrackResp struct {
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	MessageKey string        `json:"messagekey"`
+	Quick      APIQuickCrack `json:"quick"`
+}
+
+type JobLogResp struct {
+	Status        int        `json:"status"`
+	Message       string     `json:"message"`
+	MessageKey    string     `json:"messagekey"`
+	Queue         []string   `json:"queue"`    // Scheduling decisions of the queue
+	Resource      []string   `json:"resource"` // Scheduling decisions of the resource
+	Output        []string   `json:"output"`   // Full output of the tool
+	Fetched       *time.Time `json:"fetched,omitempty"`
+	ResourceError string     `json:"resourceerror,omitempty"`
+}
+
+type JobChangesResp struct {
+	Status     int      `json:"status"`
+	Message    string   `json:"message"`
+	MessageKey string   `json:"messagekey"`
+	Cursor     uint64   `json:"cursor"`
+	Reset      bool     `json:"reset"`
+	Jobs       []APIJob `json:"jobs"`
+	Removed    []string `json:"removed"`
+}
+
+// Create Jobs request
+type JobCreateReq struct {
+	ToolID      string                 `json:"toolid"`
+	Name        string                 `json:"name"`
+	Params      map[string]interface{} `json:"params"`
+	MaxRuntime  int                    `json:"maxruntime"`
+	Dispatch    *bool                  `json:"dispatch"`  // False creates a draft job
+	Usernames   bool                   `json:"usernames"` // Hashes are given as user:hash or pwdump lines
+	LMNT        bool                   `json:"lmnt"`      // Crack LM hashes first then toggle case for the NT hashes
+	Constraints *APIConstraints        `json:"constraints"`
+	Force       bool                   `json:"force"` // Create the job even when the input checks find errors
+	Args        []string               `json:"args"`  // Extra tool arguments, Administrators only
+	Env         map[string]string      `json:"env"`   // Tool environment variables, Administrators only
+	Project     string                 `json:"project"`
+	Debug       bool                   `json:"debug"`       // Keep the full tool output and scheduling decisions for GET /api/jobs/{id}/log
+	Queue       string                 `json:"queue"`       // Named queue to run the job in, empty for the default queue
+	ResultKey   string                 `json:"resultkey"`   // Armored OpenPGP public key to encrypt each row of results for, the queue never sees the plaintexts
+	Sensitive   bool                   `json:"sensitive"`   // Wait for a second Administrator to approve the job before it runs
+	FormVersion int                    `json:"formversion"` // Version of the tool form the parameters were made with, such as from a cloned job, 0 for the current one
+}
+
+// Hardware a job needs from a resource, memory is in megabytes
+type APIConstraints struct {
+	MinGPUs      int    `json:"mingpus"`
+	MinGPUMemory int64  `json:"mingpumemory"`
+	GPUModel     string `json:"gpumodel"`
+	MinCPUCores  int    `json:"mincpucores"`
+	MinMemory    int64  `json:"minmemory"`
+}
+
+// Create Job response
+type JobCreateResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	JobID      string          `json:"jobid"`
+	Issues     []APIInputIssue `json:"issues"`
+}
+
+// A problem found in the input of a job, errors stop it from being created
+type APIInputIssue struct {
+	Severity string `json:"severity"` // error or warning
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Lines    []int  `json:"lines,omitempty"`
+	Count    int    `json:"count,omitempty"`
+}
+
+// Batch Job request, either a list of jobs or a single template job that is
+// created once for each set of hashes provided
+type JobBatchReq struct {
+	Jobs     []JobCreateReq `json:"jobs"`
+	Template *JobCreateReq  `json:"template"`
+	Hashes   []string       `json:"hashes"`
+}
+
+// The result of creating a single job from a batch
+type JobBatchResult struct {
+	Index  int             `json:"index"`
+	JobID  string          `json:"jobid,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Issues []APIInputIssue `json:"issues,omitempty"`
+}
+
+// Batch Job response
+type JobBatchResp struct {
+	Status     int              `json:"status"`
+	Message    string           `json:"message"`
+	MessageKey string           `json:"messagekey"`
+	Results    []JobBatchResult `json:"results"`
+}
+
+// Read Job resposne
+type JobReadResp struct {
+	Status     int          `json:"status"`
+	Message    string       `json:"message"`
+	MessageKey string       `json:"messagekey"`
+	Job        APIJobDetail `json:"job"`
+}
+
+// Update Job Request
+type JobUpdateReq struct {
+	APIJob
+	Params     map[string]interface{} `json:"params"`     // Only used for draft jobs
+	Usernames  bool                   `json:"usernames"`  // New hashes of a draft are user:hash or pwdump lines, drafts made that way keep it
+	LMNT       bool                   `json:"lmnt"`       // New hashes of a draft are cracked LM first, drafts made that way keep it
+	MaxRuntime int                    `json:"maxruntime"` // Only used for draft jobs
+	Force      bool                   `json:"force"`      // Administrators can quit jobs whose resource is unreachable, drafts are saved despite errors in their input
+}
+
+// Transfer Job ownership request
+type JobOwnerReq struct {
+	Owner string `json:"owner"`
+}
+
+// Update Job Response
+type JobUpdateResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	Job        APIJob          `json:"job"`
+	Issues     []APIInputIssue `json:"issues,omitempty"` // Problems found in the new parameters of a draft
+}
+
+// Delete Job response
+type JobDeleteResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Resource API structure
+type APIResource struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Address      string            `json:"address"`
+	Manager      string            `json:"manager"`
+	Params       map[string]string `json:"params"`
+	Status       string            `json:"status"`
+	Unresponsive bool              `json:"unresponsive"`        // Calls are stopped by the circuit breaker
+	Exclusive    bool              `json:"exclusive"`           // Only runs one job at a time
+	CostPerHour  float64           `json:"costperhour"`         // Dollars an hour while running jobs, 0 for free capacity
+	ClockOffset  float64           `json:"clockoffset"`         // Seconds the clock of the resource is ahead of the queue, times from it are corrected by this
+	ClockRead    *time.Time        `json:"clockread,omitempty"` // When the clock of the resource was last measured
+	Profiles     []APIProfile      `json:"profiles"`
+	Profile      string            `json:"profile"` // Name of the profile in effect now, empty for none
+	Tools        []APITool         `json:"tools"`
+	Inventory    *APIInventory     `json:"inventory,omitempty"`
+}
+
+// Workload a resource is allowed by time of day. Days are short names such as
+// mon and times are HH:MM in the local time of the queue.
+type APIProfile struct {
+	Name     string   `json:"name"`
+	Days     []string `json:"days"` // Every day when empty
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Workload int      `json:"workload"` // 0 does not limit it
+	MaxTasks int      `json:"maxtasks"` // 0 does not limit them
+}
+
+// A GPU of a resource, memory is in megabytes
+type APIGPU struct {
+	Model  string `json:"model"`
+	Memory int64  `json:"memory"`
+	Driver string `json:"driver"`
+}
+
+// Hardware inventory of a resource
+type APIInventory struct {
+	GPUs     []APIGPU          `json:"gpus"`
+	CUDA     string            `json:"cuda"`
+	CPUModel string            `json:"cpumodel"`
+	CPUCores int               `json:"cpucores"`
+	Memory   int64             `json:"memory"`
+	Platform string            `json:"platform"` // OS and architecture, such as linux/amd64
+	Binaries map[string]string `json:"binaries"` // Version of each tool binary installed from the queue
+}
+
+// List resource structs
+type ResListResp struct {
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	MessageKey string        `json:"messagekey"`
+	Resources  []APIResource `json:"resources"`
+}
+
+// Create resource structs
+type ResCreateReq struct {
+	Manager string            `json:"manager"`
+	Params  map[string]string `json:"params"`
+}
+
+type ResCreateResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Read a resource struct
+type ResReadResp struct {
+	Status     int         `json:"status"`
+	Message    string      `json:"message"`
+	MessageKey string      `json:"messagekey"`
+	Resource   APIResource `json:"resource"`
+}
+
+// Resource logs response
+type ResLogsResp struct {
+	Status     int      `json:"status"`
+	Message    string   `json:"message"`
+	MessageKey string   `json:"messagekey"`
+	Lines      []string `json:"lines"`
+}
+
+// Update a resource struct
+type ResUpdateReq struct {
+	ID      string            `json:"id"`
+	Manager string            `json:"manager"`
+	Params  map[string]string `json:"params"`
+	Status  string            `json:"status"`
+	Tools   []APITool         `json:"tools"`
+
+	// Run only one job at a time, left as it is when not given
+	Exclusive *bool `json:"exclusive,omitempty"`
+
+	// Dollars an hour the resource costs, left as it is when not given
+	CostPerHour *float64 `json:"costperhour,omitempty"`
+}
+
+type ResUpdateResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Rescan resource response, the inventory of the resource is included
+type ResRescanResp struct {
+	Status     int         `json:"status"`
+	Message    string      `json:"message"`
+	MessageKey string      `json:"messagekey"`
+	Resource   APIResource `json:"resource"`
+	Files      int         `json:"files"` // Wordlists and rules found on the resource
+}
+
+// A build of a tool in the binary repository
+type APIToolBinary struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+// Upload a tool binary, the binary and its Ed25519 signature are base64 encoded
+type BinaryUploadReq struct {
+	Tool      string `json:"tool"`
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Binary    []byte `json:"binary"`
+	Signature []byte `json:"signature"`
+}
+
+// Set the version of a tool resources are kept at, empty to stop managing it
+type BinaryCurrentReq struct {
+	Version string `json:"version"`
+}
+
+type BinaryListResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Builds     []APIToolBinary   `json:"builds"`
+	Current    map[string]string `json:"current"` // Version of each tool resources are kept at
+}
+
+// Set resource profiles structs
+type ResProfilesReq struct {
+	Profiles []APIProfile `json:"profiles"`
+}
+
+type ResProfilesResp struct {
+	Status     int          `json:"status"`
+	Message    string       `json:"message"`
+	MessageKey string       `json:"messagekey"`
+	Profiles   []APIProfile `json:"profiles"`
+}
+
+// Desired state of a resource registered by name, such as from infrastructure
+// as code. Registering the same state again changes nothing.
+type ResRegisterReq struct {
+	Manager     string            `json:"manager"`
+	Params      map[string]string `json:"params"`
+	Status      string            `json:"status"` // running or paused, running when empty
+	Exclusive   bool              `json:"exclusive"`
+	Profiles    []APIProfile      `json:"profiles"`
+	CostPerHour float64           `json:"costperhour"`
+}
+
+// State of a resource registered by name
+type APIResourceConfig struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Manager     string            `json:"manager"`
+	Address     string            `json:"address"`
+	Params      map[string]string `json:"params"`
+	Status      string            `json:"status"`
+	Exclusive   bool              `json:"exclusive"`
+	Profiles    []APIProfile      `json:"profiles"`
+	CostPerHour float64           `json:"costperhour"`
+}
+
+type ResConfigResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Created    bool              `json:"created"` // The registration added the resource
+	Resource   APIResourceConfig `json:"resource"`
+}
+
+// Delete a resource struct
+type ResDeleteReq struct {
+	ID      string            `json:"id"`
+	Manager string            `json:"manager"`
+	Params  map[string]string `json:"params"`
+	Status  string            `json:"status"`
+	Tools   []APITool         `json:"tools"`
+}
+
+// Delete a resource struct
+type ResDeleteResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+type QueueUpdateReq struct {
+	JobOrder []string `json:"joborder"`
+}
+
+type QueueUpdateResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Resources blocked out for the jobs of a project
+type APIReservation struct {
+	ID        string    `json:"id"`
+	Project   string    `json:"project"`
+	Resources []string  `json:"resources"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	CreatedBy string    `json:"createdby"`
+	Note      string    `json:"note"`
+}
+
+type ReservationListResp struct {
+	Status       int              `json:"status"`
+	Message      string           `json:"message"`
+	MessageKey   string           `json:"messagekey"`
+	Reservations []APIReservation `json:"reservations"`
+}
+
+type ReservationCreateReq struct {
+	Project   string    `json:"project"`
+	Resources []string  `json:"resources"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Note      string    `json:"note"`
+}
+
+type ReservationCreateResp struct {
+	Status      int            `json:"status"`
+	Message     string         `json:"message"`
+	MessageKey  string         `json:"messagekey"`
+	Reservation APIReservation `json:"reservation"`
+}
+
+type ReservationDeleteResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// A named queue and the resources bound to it
+type APINamedQueue struct {
+	Name       string   `json:"name"`
+	Resources  []string `json:"resources"`
+	Policy     string   `json:"policy"` // fifo, lifo or fair
+	MaxRunning int      `json:"maxrunning"`
+}
+
+type QueueListResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	Queues     []APINamedQueue `json:"queues"`
+}
+
+type QueueSetReq struct {
+	Resources  []string `json:"resources"`
+	Policy     string   `json:"policy"`
+	MaxRunning int      `json:"maxrunning"`
+}
+
+type QueueSetResp struct {
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	MessageKey string        `json:"messagekey"`
+	Queue      APINamedQueue `json:"queue"`
+}
+
+type QueueDeleteResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+}
+
+// Move a job to another queue, an empty queue is the default queue
+type JobMoveReq struct {
+	Queue string `json:"queue"`
+}
+
+// Queue simulation request structure, the jobs are never created
+type QueueSimulateReq struct {
+	Jobs []JobCreateReq `json:"jobs"`
+}
+
+// Where and when a job would run in a queue simulation
+type APIPlannedJob struct {
+	JobID        string    `json:"jobid"`
+	Name         string    `json:"name"`
+	Hypothetical bool      `json:"hypothetical"` // The job was only submitted to the simulation
+	ResourceID   string    `json:"resourceid,omitempty"`
+	ResourceName string    `json:"resourcename,omitempty"`
+	Hardware     string    `json:"hardware,omitempty"`
+	Start        time.Time `json:"start"`
+	Finish       time.Time `json:"finish"`
+	Estimated    bool      `json:"estimated"` // False when the maximum runtime was used as the tool had no estimate
+	Error        string    `json:"error,omitempty"`
+}
+
+// Queue simulation response structure
+type QueueSimulateResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	Jobs       []APIPlannedJob `json:"jobs"`
+	Finish     time.Time       `json:"finish"`
+}
+
+// What importing a queue bundle added, and the jobs and resources that were
+// already on the queue and left alone
+type APIQueueImport struct {
+	Jobs             int `json:"jobs"`
+	JobsSkipped      int `json:"jobsskipped"`
+	Resources        int `json:"resources"`
+	ResourcesSkipped int `json:"resourcesskipped"`
+	Defaults         int `json:"defaults"`
+	Queues           int `json:"queues"`
+	Reservations     int `json:"reservations"`
+}
+
+// Queue import response structure
+type QueueImportResp struct {
+	Status     int            `json:"status"`
+	Message    string         `json:"message"`
+	MessageKey string         `json:"messagekey"`
+	Imported   APIQueueImport `json:"imported"`
+}
+
+// Wordlist processing API structure
+type APIWordlistTask struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	InputLines  int64     `json:"inputlines"`
+	OutputLines int64     `json:"outputlines"`
+	Output      string    `json:"output"`
+	Hcstat      string    `json:"hcstat"`
+	Pages       int       `json:"pages"`  // Pages fetched by a crawl
+	Shared      string    `json:"shared"` // Path resources use for the wordlist of a crawl
+	StartTime   time.Time `json:"starttime"`
+	EndTime     time.Time `json:"endtime"`
+}
+
+// Wordlist processing list response structure
+type WordlistTasksResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Tasks      []APIWordlistTask `json:"tasks"`
+}
+
+// Wordlist processing create request structure
+type WordlistProcessReq struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Compress    bool   `json:"compress"`
+	Hcstat      bool   `json:"hcstat"`
+}
+
+// Wordlist crawl request structure
+type WordlistCrawlReq struct {
+	Name      string   `json:"name"`
+	URLs      []string `json:"urls"`
+	Depth     int      `json:"depth"`
+	MaxPages  int      `json:"maxpages"`
+	MinLength int      `json:"minlength"`
+	MaxLength int      `json:"maxlength"`
+	Lower     bool     `json:"lower"`
+}
+
+// Wordlist processing create response structure
+type WordlistProcessResp struct {
+	Status     int    `json:"status"`
+	Message    string `json:"message"`
+	MessageKey string `json:"messagekey"`
+	ID         string `json:"id"`
+}
+
+// Wordlist processing read response structure
+type WordlistTaskResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	Task       APIWordlistTask `json:"task"`
+}
+
+// Markov model API structure
+type APIMarkovModel struct {
+	Name     string    `json:"name"`
+	Format   string    `json:"format"` // hcstat, or hcstat2 for newer hashcat versions
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Markov model list response structure
+type MarkovModelsResp struct {
+	Status     int              `json:"status"`
+	Message    string           `json:"message"`
+	MessageKey string           `json:"messagekey"`
+	Models     []APIMarkovModel `json:"models"`
+}
+
+// Markov model upload response structure
+type MarkovModelUploadResp struct {
+	Status     int            `json:"status"`
+	Message    string         `json:"message"`
+	MessageKey string         `json:"messagekey"`
+	Model      APIMarkovModel `json:"model"`
+}
+
+// Generated wordlist API structure
+type APIGeneratedWordlist struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`             // Within the wordlist directory
+	Shared     string    `json:"shared,omitempty"` // Path resources use for the copy in the shared bucket
+	Candidates int64     `json:"candidates,omitempty"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+}
+
+// Generated wordlist list response structure
+type GeneratedWordlistsResp struct {
+	Status     int                    `json:"status"`
+	Message    string                 `json:"message"`
+	MessageKey string                 `json:"messagekey"`
+	Wordlists  []APIGeneratedWordlist `json:"wordlists"`
+}
+
+// Wordlist generation request structure
+type WordlistGenerateReq struct {
+	Companies []string `json:"companies"`
+	Keywords  []string `json:"keywords"`
+	Seasons   bool     `json:"seasons"`
+	Years     []int    `json:"years"`
+	Leet      bool     `json:"leet"`
+	Appends   []string `json:"appends"` // Default suffixes are used when missing
+	Prepends  []string `json:"prepends"`
+}
+
+// Wordlist generation response structure
+type WordlistGenerateResp struct {
+	Status     int                  `json:"status"`
+	Message    string               `json:"message"`
+	MessageKey string               `json:"messagekey"`
+	Wordlist   APIGeneratedWordlist `json:"wordlist"`
+}
+
+// Tool preview request structure
+type ToolPreviewReq struct {
+	Params map[string]interface{} `json:"params"`
+	Words  []string               `json:"words"`
+}
+
+// Tool preview response structure
+type ToolPreviewResp struct {
+	Status     int      `json:"status"`
+	Message    string   `json:"message"`
+	MessageKey string   `json:"messagekey"`
+	Candidates []string `json:"candidates"`
+}
+
+// Tool estimate request structure
+type ToolEstimateReq struct {
+	Params map[string]interface{} `json:"params"`
+}
+
+// The estimate of a job from a single resource
+type APIEstimate struct {
+	ResourceID   string  `json:"resourceid"`
+	ResourceName string  `json:"resourcename"`
+	Keyspace     uint64  `json:"keyspace"`
+	Speed        float64 `json:"speed"`
+	Seconds      float64 `json:"seconds"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// Tool estimate response structure
+type ToolEstimateResp struct {
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	MessageKey string        `json:"messagekey"`
+	Estimates  []APIEstimate `json:"estimates"`
+}
+
+// Rolling resource update request structure, binary and signature are base64
+type ResUpdateRolloutReq struct {
+	Binary       []byte   `json:"binary"`
+	Signature    []byte   `json:"signature"`
+	Resources    []string `json:"resources"`    // Empty updates every connected resource
+	DrainTimeout int      `json:"draintimeout"` // Seconds to wait for jobs on each resource to finish
+}
+
+// Progress of a single resource in a rolling update
+type APIResourceUpdate struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// A rolling resource update
+type APIUpdate struct {
+	ID        string              `json:"id"`
+	Build     string              `json:"build"`
+	Status    string              `json:"status"`
+	Error     string              `json:"error,omitempty"`
+	StartTime time.Time           `json:"starttime"`
+	EndTime   time.Time           `json:"endtime"`
+	Resources []APIResourceUpdate `json:"resources"`
+}
+
+// Rolling resource update response structure
+type ResUpdateRolloutResp struct {
+	Status     int       `json:"status"`
+	Message    string    `json:"message"`
+	MessageKey string    `json:"messagekey"`
+	Update     APIUpdate `json:"update"`
+}
+
+// Health and readiness check response structure
+type HealthResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Checks     map[string]string `json:"checks,omitempty"`
+}
+
+// Tool defaults request structure
+type ToolDefaultsReq struct {
+	Defaults map[string]interface{} `json:"defaults"`
+	Locked   map[string]interface{} `json:"locked"`
+}
+
+// Tool defaults response structure
+type ToolDefaultsResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Defaults   map[string]string `json:"defaults"`
+	Locked     map[string]string `json:"locked"`
+}
+
+// Usage of a single tool parameter value
+type APIParamStats struct {
+	Name      string  `json:"name"`
+	Value     string  `json:"value"`
+	Jobs      int64   `json:"jobs"`
+	Cracked   int64   `json:"cracked"`
+	Total     int64   `json:"total"`
+	CrackRate float64 `json:"crackrate"` // Percentage of hashes cracked
+}
+
+// Usage of a tool across all finished jobs
+type APIToolStats struct {
+	Name      string          `json:"name"`
+	Jobs      int64           `json:"jobs"`
+	Cracked   int64           `json:"cracked"`
+	Total     int64           `json:"total"`
+	CrackRate float64         `json:"crackrate"`
+	Runtime   int64           `json:"runtime"` // Seconds spent running
+	Params    []APIParamStats `json:"params"`
+}
+
+// Tool usage statistics response structure
+type ToolStatsResp struct {
+	Status     int            `json:"status"`
+	Message    string         `json:"message"`
+	MessageKey string         `json:"messagekey"`
+	Tools      []APIToolStats `json:"tools"`
+}
+
+// Requests to a route no slower than a latency
+type APILatencyBucket struct {
+	Le    float64 `json:"le"` // Milliseconds
+	Count int64   `json:"count"`
+}
+
+// Latency of the requests to one API route
+type APIRouteStats struct {
+	Method  string             `json:"method"`
+	Path    string             `json:"path"`
+	Count   int64              `json:"count"`
+	Errors  int64              `json:"errors"`
+	Mean    float64            `json:"mean"` // Milliseconds
+	Max     float64            `json:"max"`  // Milliseconds
+	Buckets []APILatencyBucket `json:"buckets"`
+}
+
+// Route metrics response structure
+type RouteStatsResp struct {
+	Status     int             `json:"status"`
+	Message    string          `json:"message"`
+	MessageKey string          `json:"messagekey"`
+	Routes     []APIRouteStats `json:"routes"`
+}
+
+// NTDS ingestion request, the dump is parsed and the chosen subsets are used
+// as the hashes of the job if one is given
+type NTDSIngestReq struct {
+	Dump     string        `json:"dump"`
+	Disabled bool          `json:"disabled"` // Include disabled accounts
+	History  bool          `json:"history"`  // Include password history hashes
+	Machine  bool          `json:"machine"`  // Include computer accounts
+	Job      *JobCreateReq `json:"job"`
+}
+
+// Counts of each subset of an NTDS dump
+type APINTDSSummary struct {
+	Accounts int `json:"accounts"`
+	Enabled  int `json:"enabled"`
+	Disabled int `json:"disabled"`
+	History  int `json:"history"`
+	Machine  int `json:"machine"`
+	LM       int `json:"lm"`
+	Selected int `json:"selected"`
+}
+
+// NTDS ingestion response
+type NTDSIngestResp struct {
+	Status     int            `json:"status"`
+	Message    string         `json:"message"`
+	MessageKey string         `json:"messagekey"`
+	Summary    APINTDSSummary `json:"summary"`
+	JobID      string         `json:"jobid,omitempty"`
+}
+
+// Kerberoast ingestion request, a job is created for each encryption type
+// found using the job given as a template
+type KerberosIngestReq struct {
+	Dump string        `json:"dump"`
+	Job  *JobCreateReq `json:"job"`
+}
+
+// Tickets of one type and encryption type found in the output
+type APIKerberosGroup struct {
+	Type  string `json:"type"`
+	Etype int    `json:"etype"`
+	Mode  string `json:"mode"`
+	Count int    `json:"count"`
+	JobID string `json:"jobid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Kerberoast ingestion response
+type KerberosIngestResp struct {
+	Status      int                `json:"status"`
+	Message     string             `json:"message"`
+	MessageKey  string             `json:"messagekey"`
+	Groups      []APIKerberosGroup `json:"groups"`
+	Unsupported int                `json:"unsupported"` // Tickets with an encryption type hashcat cannot crack
+}
+
+type MessagesResp struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	MessageKey string            `json:"messagekey"`
+	Locale     string            `json:"locale"`
+	Messages   map[string]string `json:"messages"`
+}
+
+// Budget of a project for resources with a cost per hour
+type APIBudget struct {
+	Project string  `json:"project"`
+	Budget  float64 `json:"budget"` // 0 when the project is not limited
+	Spent   float64 `json:"spent"`
+}
+
+type BudgetsResp struct {
+	Status     int         `json:"status"`
+	Message    string      `json:"message"`
+	MessageKey string      `json:"messagekey"`
+	Action     string      `json:"action"` // refuse or confirm for jobs that would go over budget
+	Budgets    []APIBudget `json:"budgets"`
+}
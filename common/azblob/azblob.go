@@ -0,0 +1,278 @@
+// Package azblob is a small client for a container in Azure Blob Storage. It
+// signs requests with the storage account Shared Key.
+package azblob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The version of the Blob service REST API used
+const apiVersion = "2020-04-08"
+
+var ErrNotFound = errors.New("The blob does not exist.")
+
+// A container in an Azure storage account
+type Client struct {
+	Account   string
+	Container string
+	Endpoint  string // Defaults to https://<account>.blob.core.windows.net
+	key       []byte
+	HTTP      *http.Client
+}
+
+// Create a client with the base64 encoded account key
+func New(account, key, container, endpoint string) (*Client, error) {
+	k, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.New("The account key must be base64 encoded.")
+	}
+
+	if endpoint == "" {
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+
+	return &Client{
+		Account:   account,
+		Container: container,
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		key:       k,
+		HTTP:      &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// An error document returned by the Blob service
+type apiError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	status  string
+}
+
+func (e apiError) Error() string {
+	if e.Code == "" {
+		return "Azure Blob Storage returned " + e.status
+	}
+	return "Azure Blob Storage error " + e.Code + ": " + e.Message
+}
+
+// Store a blob of the given size
+func (c *Client) Put(name string, body io.Reader, size int64) error {
+	req, err := c.newRequest("PUT", name, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get a blob, the caller must close it
+func (c *Client) Get(name string) (io.ReadCloser, error) {
+	req, err := c.newRequest("GET", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Delete a blob. Deleting a blob that does not exist is not an error.
+func (c *Client) Delete(name string) error {
+	req, err := c.newRequest("DELETE", name, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+type listResult struct {
+	Blobs struct {
+		Blob []struct {
+			Name string `xml:"Name"`
+		} `xml:"Blob"`
+	} `xml:"Blobs"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// List the names of every blob starting with the prefix
+func (c *Client) List(prefix string) ([]string, error) {
+	var names []string
+	var marker string
+
+	for {
+		query := url.Values{}
+		query.Set("restype", "container")
+		query.Set("comp", "list")
+		query.Set("prefix", prefix)
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		req, err := c.newRequest("GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range result.Blobs.Blob {
+			names = append(names, b.Name)
+		}
+
+		if result.NextMarker == "" {
+			return names, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+func (c *Client) newRequest(method, name string, query url.Values, body io.Reader) (*http.Request, error) {
+	path := "/" + c.Container
+	if name != "" {
+		path += "/" + name
+	}
+
+	u, err := url.Parse(c.Endpoint + (&url.URL{Path: path}).EscapedPath())
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", apiVersion)
+
+	return req, nil
+}
+
+// The string signed with the account key for a request
+func (c *Client) stringToSign(req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	h := req.Header
+	lines := []string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	}
+
+	// Every x-ms header in order
+	var names []string
+	for k := range h {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			names = append(names, lk)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		lines = append(lines, k+":"+strings.TrimSpace(h.Get(k)))
+	}
+
+	// Followed by the resource and its query parameters in order
+	resource := "/" + c.Account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var params []string
+	for k := range query {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+	lines = append(lines, resource)
+
+	return strings.Join(lines, "\n")
+}
+
+func (c *Client) sign(req *http.Request) {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(c.stringToSign(req)))
+
+	req.Header.Set("Authorization", "SharedKey "+c.Account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	// Headers set after the request was made are signed as well
+	c.sign(req)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	e := apiError{status: resp.Status}
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	xml.Unmarshal(data, &e)
+
+	return nil, e
+}
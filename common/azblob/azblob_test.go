@@ -0,0 +1,42 @@
+package azblob
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStringToSign(t *testing.T) {
+	c, err := New("myaccount", "c2VjcmV0", "jobs", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{}
+	query.Set("restype", "container")
+	query.Set("comp", "list")
+	query.Set("prefix", "output/")
+
+	req, _ := c.newRequest("GET", "", query, nil)
+	req.Header.Set("x-ms-date", "Fri, 26 Jun 2015 23:39:12 GMT")
+
+	want := strings.Join([]string{
+		"GET", "", "", "", "", "", "", "", "", "", "", "",
+		"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT",
+		"x-ms-version:" + apiVersion,
+		"/myaccount/jobs\ncomp:list\nprefix:output/\nrestype:container",
+	}, "\n")
+	if got := c.stringToSign(req); got != want {
+		t.Errorf("Unexpected string to sign\n got: %q\nwant: %q", got, want)
+	}
+
+	req, _ = c.newRequest("PUT", "output/a b.csv", nil, strings.NewReader("hello"))
+	req.ContentLength = 5
+	if got := c.stringToSign(req); !strings.HasPrefix(got, "PUT\n\n\n5\n") || !strings.HasSuffix(got, "\n/myaccount/jobs/output/a%20b.csv") {
+		t.Errorf("Unexpected string to sign for a blob %q", got)
+	}
+
+	if _, err := New("myaccount", "not base64!", "jobs", ""); err == nil {
+		t.Error("Expected an invalid key to be refused")
+	}
+}
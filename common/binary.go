@@ -0,0 +1,50 @@
+package common
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"regexp"
+)
+
+// Names and versions of tool binaries are used in paths so they are kept simple
+var binaryName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// A build of a tool for one platform in the binary repository of the queue
+type ToolBinary struct {
+	Tool      string // Binary name the plugin asks for, such as hashcat
+	Version   string
+	OS        string // Platform the build runs on, as in runtime.GOOS
+	Arch      string // As in runtime.GOARCH
+	SHA256    string // Hex SHA-256 of the binary
+	Signature []byte // Ed25519 signature of the binary by the update key
+	Size      int64
+}
+
+// Check the names of the build are safe to use in paths
+func (b ToolBinary) Validate() error {
+	for _, s := range []string{b.Tool, b.Version, b.OS, b.Arch} {
+		if !binaryName.MatchString(s) {
+			return errors.New("Tool binaries need a tool, version, OS and architecture made of letters, numbers, dots, dashes and underscores.")
+		}
+	}
+	return nil
+}
+
+// A tool binary sent to a resource to install
+type BinaryPackage struct {
+	ToolBinary
+	Binary []byte
+}
+
+// Check the binary is the build it claims to be and signed by the holder of
+// the update key
+func (p BinaryPackage) Verify(key ed25519.PublicKey) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if BuildHash(p.Binary) != p.SHA256 {
+		return errors.New("Tool binary does not match its checksum.")
+	}
+
+	return UpdatePackage{Binary: p.Binary, Signature: p.Signature}.Verify(key)
+}
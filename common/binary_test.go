@@ -0,0 +1,43 @@
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestBinaryPackageVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bin := []byte("hashcat binary")
+	p := BinaryPackage{
+		ToolBinary: ToolBinary{
+			Tool:      "hashcat",
+			Version:   "6.2.6",
+			OS:        "linux",
+			Arch:      "amd64",
+			SHA256:    BuildHash(bin),
+			Signature: ed25519.Sign(priv, bin),
+		},
+		Binary: bin,
+	}
+
+	if err := p.Verify(pub); err != nil {
+		t.Errorf("Valid tool binary was rejected: %s", err.Error())
+	}
+
+	tampered := p
+	tampered.Binary = []byte("tampered binary")
+	if err := tampered.Verify(pub); err == nil {
+		t.Error("Tool binary that does not match its checksum was accepted")
+	}
+
+	unsafe := p
+	unsafe.Version = "../../bin"
+	if err := unsafe.Verify(pub); err == nil {
+		t.Error("Tool binary with a path in its version was accepted")
+	}
+}
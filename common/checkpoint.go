@@ -0,0 +1,14 @@
+package common
+
+import (
+	"time"
+)
+
+// A restore point of a running task, so the work done up to it is not lost if
+// the resource running the task fails
+type Checkpoint struct {
+	Taken    time.Time  // When the checkpoint was taken on the resource
+	Progress float64    // Progress of the task at the checkpoint
+	Point    uint64     // Position in the keyspace the tool can continue from
+	Output   [][]string // Results of the task up to the checkpoint
+}
@@ -0,0 +1,44 @@
+package common
+
+import (
+	"strconv"
+	"time"
+)
+
+// How far a remote clock is ahead of the local one, from asking for the remote
+// time between sent and received. The remote time is taken to be from halfway
+// through the call, so the offset is right to within half the round trip.
+func ClockOffset(sent, remote, received time.Time) time.Duration {
+	return remote.Sub(sent.Add(received.Sub(sent) / 2))
+}
+
+// A time read from a clock that is offset ahead of the local one, as the local
+// time in UTC. Zero times are left zero.
+func LocalTime(t time.Time, offset time.Duration) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.Add(-offset).UTC()
+}
+
+// Move the times the resource sets on a job, its start time and the
+// timestamps of its performance data, from the clock of the resource to the
+// clock of the queue in UTC
+func (j *Job) NormalizeClock(offset time.Duration) {
+	j.StartTime = LocalTime(j.StartTime, offset)
+
+	if offset/time.Second == 0 || len(j.PerformanceData) == 0 {
+		return
+	}
+
+	perf := make(map[string]string, len(j.PerformanceData))
+	for k, v := range j.PerformanceData {
+		ts, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			perf[k] = v
+			continue
+		}
+		perf[strconv.FormatInt(time.Unix(ts, 0).Add(-offset).Unix(), 10)] = v
+	}
+	j.PerformanceData = perf
+}
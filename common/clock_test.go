@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockOffset(t *testing.T) {
+	sent := time.Unix(1000, 0)
+	received := sent.Add(2 * time.Second)
+
+	// The resource read its clock halfway through the call
+	if got := ClockOffset(sent, time.Unix(1031, 0), received); got != 30*time.Second {
+		t.Errorf("Offset is %v not 30s", got)
+	}
+	if got := ClockOffset(sent, time.Unix(991, 0), received); got != -10*time.Second {
+		t.Errorf("Offset is %v not -10s", got)
+	}
+}
+
+func TestNormalizeClock(t *testing.T) {
+	zone := time.FixedZone("resource", 5*60*60)
+	start := time.Date(2026, 10, 14, 17, 0, 30, 0, zone)
+
+	j := Job{
+		StartTime:       start,
+		PerformanceData: map[string]string{"1800000030": "100", "1800000040": "200"},
+	}
+	j.NormalizeClock(30 * time.Second)
+
+	want := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	if !j.StartTime.Equal(want) || j.StartTime.Location() != time.UTC {
+		t.Errorf("Start time is %v not %v", j.StartTime, want)
+	}
+	if len(j.PerformanceData) != 2 || j.PerformanceData["1800000000"] != "100" || j.PerformanceData["1800000010"] != "200" {
+		t.Errorf("Unexpected performance data %v", j.PerformanceData)
+	}
+
+	// Jobs that have not started stay that way
+	j = Job{}
+	j.NormalizeClock(time.Minute)
+	if !j.StartTime.IsZero() {
+		t.Errorf("Start time of a job that has not started is %v", j.StartTime)
+	}
+}
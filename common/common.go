@@ -3,16 +3,20 @@ package common
 import (
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 const (
-	STATUS_CREATED = "created"
-	STATUS_RUNNING = "running"
-	STATUS_PENDING = "pending"
-	STATUS_PAUSED  = "paused"
-	STATUS_DONE    = "done"
-	STATUS_FAILED  = "failed"
-	STATUS_QUIT    = "quit"
+	STATUS_CREATED  = "created"
+	STATUS_RUNNING  = "running"
+	STATUS_PENDING  = "pending"
+	STATUS_PAUSED   = "paused"
+	STATUS_DONE     = "done"
+	STATUS_FAILED   = "failed"
+	STATUS_QUIT     = "quit"
+	STATUS_EXPIRED  = "expired"
+	STATUS_DRAFT    = "draft"
+	STATUS_APPROVAL = "approval" // Sensitive jobs wait for a second Administrator to approve them
 
 	RES_CPU = "cpu"
 	RES_GPU = "gpu"
@@ -20,7 +24,30 @@ const (
 )
 
 type RPCCall struct {
-	Job Job
+	Job        Job
+	Words      []string          // Sample input used when previewing a tool
+	Lines      int               // Number of log lines requested
+	Update     *UpdatePackage    // Binary pushed when updating the resource
+	Checkpoint *Checkpoint       // Restore point a new task continues from
+	Files      map[string]string // Presigned URLs of files in the shared bucket by key
+	Profile    *Profile          // Workload the resource is allowed now, nil lifts the limits
+	Binary     *BinaryPackage    // Tool binary from the repository of the queue to install
+	Offset     int64             // Start of the part of the result file requested
+	Length     int64             // Bytes of the result file requested
+}
+
+// Part of the result file of a task
+type ResultChunk struct {
+	Size     int64 // Size of the whole file when the part was read
+	Modified time.Time
+	Data     []byte
+}
+
+// Estimate of the work needed to run a job on a resource
+type Estimate struct {
+	Keyspace uint64  `json:"keyspace"` // Number of candidates that will be tried
+	Speed    float64 `json:"speed"`    // Candidates per second from benchmark data
+	Seconds  float64 `json:"seconds"`  // Estimated run time or 0 if the speed is unknown
 }
 
 type JSONSchemaForm struct {
@@ -31,7 +58,7 @@ type JSONSchemaForm struct {
 // Function to determine if a status shows something is completed
 func IsDone(status string) bool {
 	switch status {
-	case STATUS_DONE, STATUS_FAILED, STATUS_QUIT:
+	case STATUS_DONE, STATUS_FAILED, STATUS_QUIT, STATUS_EXPIRED:
 		return true
 	default:
 		return false
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"sort"
+	"strings"
+)
+
+// An account compared between the results of two jobs. Accounts are users when
+// both jobs were submitted with usernames and hashes otherwise.
+type AccountDiff struct {
+	Account    string
+	OldHash    string
+	NewHash    string
+	OldCracked bool
+	NewCracked bool
+	Plaintext  string // Plaintext found by the newer job, or by the older one when only it cracked the account
+}
+
+// How the cracked accounts of a newer job, such as this year's dump of a
+// domain, compare to those of an older job over the same accounts
+type ResultDiff struct {
+	ByUser          bool          // Accounts are users rather than hashes
+	NewlyCracked    []AccountDiff // Cracked by the newer job only
+	StillCracked    []AccountDiff // Cracked by both with the same hash, so the password was not changed
+	PasswordChanged []AccountDiff // The hash of the user changed between the jobs
+}
+
+// The hashes cracked by a job and their plaintexts, keyed by the hash in lower
+// case as tools may change it in the output
+func (j Job) Plaintexts() map[string]string {
+	plainCol, hashCol := -1, -1
+	for i, t := range j.OutputTitles {
+		switch {
+		case t == "Plaintext":
+			plainCol = i
+		case hashCol < 0 && strings.Contains(t, "Hash"):
+			hashCol = i
+		}
+	}
+
+	cracked := map[string]string{}
+	if plainCol < 0 || hashCol < 0 {
+		return cracked
+	}
+
+	for _, row := range j.OutputRows() {
+		if len(row) != len(j.OutputTitles) {
+			continue
+		}
+		cracked[strings.ToLower(row[hashCol])] = row[plainCol]
+	}
+
+	return cracked
+}
+
+// The hash of every user of a job submitted with usernames
+func (j Job) userHashes() map[string]string {
+	users := map[string]string{}
+	for hash, names := range j.Usernames {
+		for _, u := range names {
+			users[strings.ToLower(u)] = hash
+		}
+	}
+	return users
+}
+
+// Compare the cracked results of an older and a newer job. When both jobs were
+// submitted with usernames users are followed from one to the other, so users
+// whose hash changed are found. Otherwise only the hashes are compared.
+func DiffResults(older, newer Job) ResultDiff {
+	oldCracked := older.Plaintexts()
+	newCracked := newer.Plaintexts()
+
+	var d ResultDiff
+	if len(older.Usernames) > 0 && len(newer.Usernames) > 0 {
+		d.ByUser = true
+
+		oldUsers := older.userHashes()
+		for user, newHash := range newer.userHashes() {
+			a := AccountDiff{Account: user, NewHash: newHash}
+			a.Plaintext, a.NewCracked = newCracked[newHash]
+
+			oldHash, existed := oldUsers[user]
+			if existed {
+				a.OldHash = oldHash
+				var oldPlain string
+				oldPlain, a.OldCracked = oldCracked[oldHash]
+				if !a.NewCracked {
+					a.Plaintext = oldPlain
+				}
+			}
+
+			switch {
+			case existed && oldHash != newHash:
+				d.PasswordChanged = append(d.PasswordChanged, a)
+			case a.NewCracked && a.OldCracked:
+				d.StillCracked = append(d.StillCracked, a)
+			case a.NewCracked:
+				d.NewlyCracked = append(d.NewlyCracked, a)
+			}
+		}
+	} else {
+		for hash, plain := range newCracked {
+			a := AccountDiff{Account: hash, OldHash: hash, NewHash: hash, NewCracked: true, Plaintext: plain}
+			if _, ok := oldCracked[hash]; ok {
+				a.OldCracked = true
+				d.StillCracked = append(d.StillCracked, a)
+			} else {
+				d.NewlyCracked = append(d.NewlyCracked, a)
+			}
+		}
+	}
+
+	for _, list := range [][]AccountDiff{d.NewlyCracked, d.StillCracked, d.PasswordChanged} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Account < list[j].Account })
+	}
+
+	return d
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	older := Job{
+		OutputTitles: []string{"Plaintext", "Hash"},
+		OutputData:   [][]string{{"Summer2023", "aaaa"}, {"Password1", "bbbb"}},
+		Usernames: map[string][]string{
+			"aaaa": {"alice"},
+			"bbbb": {"bob"},
+			"cccc": {"carol"},
+			"dddd": {"dave"},
+		},
+	}
+	newer := Job{
+		OutputTitles: []string{"Plaintext", "Hash"},
+		OutputData:   [][]string{{"Summer2023", "AAAA"}, {"Winter2024", "eeee"}, {"letmein", "cccc"}},
+		Usernames: map[string][]string{
+			"aaaa": {"alice"},
+			"eeee": {"bob"},
+			"cccc": {"carol"},
+			"dddd": {"dave"},
+		},
+	}
+
+	d := DiffResults(older, newer)
+	if !d.ByUser {
+		t.Fatal("Expected accounts to be compared by user")
+	}
+
+	if len(d.NewlyCracked) != 1 || d.NewlyCracked[0].Account != "carol" || d.NewlyCracked[0].Plaintext != "letmein" {
+		t.Errorf("Expected carol to be newly cracked, got %v", d.NewlyCracked)
+	}
+	if len(d.StillCracked) != 1 || d.StillCracked[0].Account != "alice" {
+		t.Errorf("Expected alice to still be cracked, got %v", d.StillCracked)
+	}
+	if len(d.PasswordChanged) != 1 || d.PasswordChanged[0].Account != "bob" || !d.PasswordChanged[0].OldCracked || !d.PasswordChanged[0].NewCracked {
+		t.Errorf("Expected bob to have changed their password and been cracked again, got %v", d.PasswordChanged)
+	}
+}
+
+func TestDiffResultsByHash(t *testing.T) {
+	older := Job{
+		OutputTitles: []string{"Plaintext", "Hash"},
+		OutputData:   [][]string{{"one", "1111"}},
+	}
+	newer := Job{
+		OutputTitles: []string{"Plaintext", "Hash"},
+		OutputData:   [][]string{{"one", "1111"}, {"two", "2222"}},
+	}
+
+	d := DiffResults(older, newer)
+	if d.ByUser {
+		t.Error("Expected accounts to be compared by hash")
+	}
+	if len(d.NewlyCracked) != 1 || d.NewlyCracked[0].Account != "2222" {
+		t.Errorf("Expected 2222 to be newly cracked, got %v", d.NewlyCracked)
+	}
+	if len(d.StillCracked) != 1 || d.StillCracked[0].Account != "1111" {
+		t.Errorf("Expected 1111 to still be cracked, got %v", d.StillCracked)
+	}
+}
@@ -0,0 +1,314 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Format of the bundles made by this version, checked by anyone verifying one
+const FORMAT = "cracklord-evidence/1"
+
+// Names of the files in a zip bundle
+const (
+	ZIP_BUNDLE    = "bundle.json"
+	ZIP_SIGNATURE = "bundle.sig"
+	ZIP_KEY       = "signer.pem"
+	ZIP_RESULTS   = "results.csv"
+)
+
+// Parameters holding the data a job was given rather than how it was run.
+// Only their digests are part of a bundle.
+var InputParams = map[string]bool{
+	"hashes":        true,
+	"customdictadd": true,
+}
+
+var ErrBadSignature = errors.New("Evidence bundle signature is not valid.")
+var ErrBadFormat = errors.New("Evidence bundle is not in a known format.")
+
+// Everything recorded about a job for chain of custody. The plaintexts are
+// never part of it, only the digests of the results.
+type Bundle struct {
+	Format     string
+	Generated  time.Time // When the bundle was made, covered by the signature
+	ExportedBy string
+	Job        Job
+	Parameters map[string]string // Parameters of the tool other than the inputs
+	Inputs     []Digest
+	Results    Results
+	Audit      []common.JobEvent
+}
+
+// The metadata of the job
+type Job struct {
+	UUID          string
+	Name          string
+	Tool          string
+	ToolVersion   string
+	Owner         string
+	SubmittedBy   string
+	ApprovedBy    string
+	Project       string
+	Queue         string
+	Resource      string
+	Status        string
+	Started       time.Time
+	Finished      time.Time
+	Purged        time.Time
+	CrackedHashes int64
+	TotalHashes   int64
+	Sensitive     bool
+	Encrypted     bool // Results are encrypted for a recipient key, the digests are of the ciphertext
+	ExtraArgs     []string
+}
+
+// The SHA-256 of an input of the job
+type Digest struct {
+	Name   string
+	SHA256 string
+	Lines  int
+	Bytes  int
+}
+
+// The digests of the output of the job. SHA256 is of the results as CSV with
+// the titles as the first row and each row is also digested on its own so a
+// single result can be shown to be part of the bundle without the others.
+type Results struct {
+	Titles []string
+	Rows   int
+	SHA256 string
+	RowSHA []string
+}
+
+// A bundle with the signature of the queue over its exact bytes
+type Signed struct {
+	Bundle    json.RawMessage
+	Signature string // Base64 Ed25519 signature of Bundle
+	KeyID     string // Hex SHA-256 of the public key in PKIX form
+	PublicKey string // PEM encoded public key of the signer
+}
+
+func sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// Encode rows as CSV
+func csvBytes(rows ...[]string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll(rows)
+	return buf.Bytes()
+}
+
+// The results of a job as CSV with the titles first
+func ResultsCSV(j common.Job) []byte {
+	return csvBytes(append([][]string{j.OutputTitles}, j.OutputRows()...)...)
+}
+
+// Make the bundle of a job with all of its output
+func New(j common.Job, tool common.Tool, by string, now time.Time) Bundle {
+	b := Bundle{
+		Format:     FORMAT,
+		Generated:  now.UTC(),
+		ExportedBy: by,
+		Job: Job{
+			UUID:          j.UUID,
+			Name:          j.Name,
+			Tool:          tool.Name,
+			ToolVersion:   tool.Version,
+			Owner:         j.Owner,
+			SubmittedBy:   j.SubmittedBy,
+			ApprovedBy:    j.ApprovedBy,
+			Project:       j.Project,
+			Queue:         j.Queue,
+			Resource:      j.ResAssigned,
+			Status:        j.Status,
+			Started:       j.StartTime,
+			Finished:      j.Finished,
+			Purged:        j.Purged,
+			CrackedHashes: j.CrackedHashes,
+			TotalHashes:   j.TotalHashes,
+			Sensitive:     j.Sensitive,
+			Encrypted:     j.ResultKey != "",
+			ExtraArgs:     j.ExtraArgs,
+		},
+		Parameters: map[string]string{},
+		Audit:      j.History,
+	}
+	if b.Job.Tool == "" {
+		b.Job.Tool = j.ToolUUID
+	}
+
+	var inputs []string
+	for k, v := range j.Parameters {
+		if InputParams[k] {
+			inputs = append(inputs, k)
+			continue
+		}
+		b.Parameters[k] = v
+	}
+	sort.Strings(inputs)
+	for _, k := range inputs {
+		v := j.Parameters[k]
+		b.Inputs = append(b.Inputs, Digest{
+			Name:   k,
+			SHA256: sum([]byte(v)),
+			Lines:  countLines(v),
+			Bytes:  len(v),
+		})
+	}
+
+	// The users each hash was submitted for, one user:hash line each
+	if len(j.Usernames) > 0 {
+		var lines []string
+		for hash, users := range j.Usernames {
+			for _, u := range users {
+				lines = append(lines, u+":"+hash)
+			}
+		}
+		sort.Strings(lines)
+		v := strings.Join(lines, "\n")
+		b.Inputs = append(b.Inputs, Digest{Name: "usernames", SHA256: sum([]byte(v)), Lines: len(lines), Bytes: len(v)})
+	}
+
+	rows := j.OutputRows()
+	b.Results = Results{
+		Titles: j.OutputTitles,
+		Rows:   len(rows),
+		SHA256: sum(ResultsCSV(j)),
+	}
+	for _, row := range rows {
+		b.Results.RowSHA = append(b.Results.RowSHA, sum(csvBytes(row)))
+	}
+
+	return b
+}
+
+func countLines(v string) int {
+	n := 0
+	for _, l := range strings.Split(v, "\n") {
+		if strings.TrimSpace(l) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Load the PEM encoded PKCS #8 Ed25519 private key bundles are signed with, as
+// produced by `openssl genpkey -algorithm ed25519`
+func ParseSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("Evidence signing key is not PEM encoded.")
+	}
+
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := priv.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("Evidence signing key is not an Ed25519 private key.")
+	}
+
+	return key, nil
+}
+
+// The PEM encoded public key and its ID of a signing key
+func PublicKey(key ed25519.PrivateKey) (string, string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	p := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return string(p), sum(der), nil
+}
+
+// Sign a bundle with the key of the queue. The bundle is signed as compact
+// JSON as that is how it is kept when the signed bundle is encoded.
+func Sign(b Bundle, key ed25519.PrivateKey) (Signed, error) {
+	var s Signed
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return s, err
+	}
+
+	s.PublicKey, s.KeyID, err = PublicKey(key)
+	if err != nil {
+		return s, err
+	}
+
+	s.Bundle = data
+	s.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return s, nil
+}
+
+// Check the bundle was signed by the holder of the key and read it
+func (s Signed) Verify(pub ed25519.PublicKey) (Bundle, error) {
+	var b Bundle
+
+	sig, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil || len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, s.Bundle, sig) {
+		return b, ErrBadSignature
+	}
+
+	if err := json.Unmarshal(s.Bundle, &b); err != nil {
+		return b, err
+	}
+	if b.Format != FORMAT {
+		return b, ErrBadFormat
+	}
+
+	return b, nil
+}
+
+// Write a signed bundle as a zip of the bundle, its detached signature, the
+// public key of the signer and the results the digests are of. Each file is
+// stamped with when the bundle was made.
+func WriteZip(w io.Writer, s Signed, generated time.Time, results []byte) error {
+	z := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{ZIP_BUNDLE, s.Bundle},
+		{ZIP_SIGNATURE, []byte(s.Signature + "\n")},
+		{ZIP_KEY, []byte(s.PublicKey)},
+		{ZIP_RESULTS, results},
+	}
+	for _, f := range files {
+		fw, err := z.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: generated,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
@@ -0,0 +1,145 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+func testJob() common.Job {
+	j := common.NewJob("tool", "Domain", "alice", map[string]string{
+		"hashes":    "aaaa\nbbbb\n",
+		"algorithm": "1000",
+	})
+	j.OutputTitles = []string{"Hash", "Plaintext"}
+	j.OutputData = [][]string{{"aaaa", "Summer2026!"}}
+	j.Usernames = map[string][]string{"aaaa": {"bob"}, "bbbb": {"carol"}}
+	j.Record("alice", "created", "Job created.")
+	return j
+}
+
+func TestBundle(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := testJob()
+	b := New(j, common.Tool{Name: "Hashcat", Version: "6.2.6"}, "admin", time.Now())
+
+	if _, ok := b.Parameters["hashes"]; ok || b.Parameters["algorithm"] != "1000" {
+		t.Errorf("Unexpected parameters %v", b.Parameters)
+	}
+	if len(b.Inputs) != 2 || b.Inputs[0].Name != "hashes" || b.Inputs[0].Lines != 2 || b.Inputs[1].Name != "usernames" {
+		t.Errorf("Unexpected inputs %+v", b.Inputs)
+	}
+	if b.Results.Rows != 1 || len(b.Results.RowSHA) != 1 || b.Results.SHA256 != sum(ResultsCSV(j)) {
+		t.Errorf("Unexpected results %+v", b.Results)
+	}
+	if len(b.Audit) != 1 {
+		t.Errorf("Unexpected audit trail %+v", b.Audit)
+	}
+
+	s, err := Sign(b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(s.Bundle), "Summer2026!") {
+		t.Error("Bundle contains a plaintext")
+	}
+
+	// The signature must still hold once the signed bundle is sent as JSON
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Signed
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode([]byte(got.PublicKey))
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vb, err := got.Verify(pub.(ed25519.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vb.Job.UUID != j.UUID || vb.Job.Tool != "Hashcat" || vb.ExportedBy != "admin" {
+		t.Errorf("Unexpected verified bundle %+v", vb.Job)
+	}
+
+	got.Bundle = bytes.Replace(got.Bundle, []byte("admin"), []byte("mallory"), 1)
+	if _, err := got.Verify(pub.(ed25519.PublicKey)); err != ErrBadSignature {
+		t.Errorf("Changed bundle verified with %v", err)
+	}
+}
+
+func TestZip(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+
+	j := testJob()
+	b := New(j, common.Tool{}, "admin", time.Now())
+	s, err := Sign(b, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, s, b.Generated, ResultsCSV(j)); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range z.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = ioutil.ReadAll(rc)
+		rc.Close()
+	}
+
+	if !bytes.Equal(files[ZIP_BUNDLE], s.Bundle) || strings.TrimSpace(string(files[ZIP_SIGNATURE])) != s.Signature {
+		t.Error("Zip does not hold the signed bundle")
+	}
+	if sum(files[ZIP_RESULTS]) != b.Results.SHA256 {
+		t.Error("Results in the zip do not match their digest")
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(key) {
+		t.Error("Parsed key does not match")
+	}
+
+	if _, err := ParseSigningKey([]byte("not a key")); err == nil {
+		t.Error("Parsed a key that is not PEM encoded")
+	}
+}
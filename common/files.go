@@ -0,0 +1,140 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A wordlist, rule or other file found on a resource
+type InventoryFile struct {
+	Name   string // Path relative to the directory it was found in
+	Size   int64
+	SHA256 string
+}
+
+// Check if the inventory has a file with the hash
+func (i Inventory) HasFile(sum string) bool {
+	for _, f := range i.Files {
+		if f.SHA256 == sum {
+			return true
+		}
+	}
+	return false
+}
+
+// A hash kept until the file it was taken from changes
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+var (
+	fileHashes   = map[string]fileHash{}
+	fileHashLock sync.Mutex
+)
+
+// Get the SHA-256 of a file. Hashes are kept in memory so a file is only read
+// again when its size or modification time changes.
+func HashFile(path string) (string, error) {
+	if sum, ok := CachedFileHash(path); ok {
+		return sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	fileHashLock.Lock()
+	fileHashes[path] = fileHash{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	fileHashLock.Unlock()
+
+	return sum, nil
+}
+
+// Get the hash of a file only if it has already been taken, so callers that
+// must be quick never read a whole wordlist
+func CachedFileHash(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+
+	fileHashLock.Lock()
+	defer fileHashLock.Unlock()
+
+	fh, ok := fileHashes[path]
+	if !ok || fh.size != info.Size() || !fh.modTime.Equal(info.ModTime()) {
+		return "", false
+	}
+
+	return fh.sum, true
+}
+
+// Find and hash every file in the directories. Hidden files, such as partial
+// downloads, are skipped.
+func ScanFiles(dirs []string) []InventoryFile {
+	files := []InventoryFile{}
+
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				log.WithFields(log.Fields{
+					"path":  p,
+					"error": err.Error(),
+				}).Warn("Unable to scan for wordlists and rules.")
+				return nil
+			}
+
+			if strings.HasPrefix(info.Name(), ".") && p != dir {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			sum, err := HashFile(p)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"path":  p,
+					"error": err.Error(),
+				}).Warn("Unable to hash file.")
+				return nil
+			}
+
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, InventoryFile{
+				Name:   filepath.ToSlash(rel),
+				Size:   info.Size(),
+				SHA256: sum,
+			})
+			return nil
+		})
+	}
+
+	return files
+}
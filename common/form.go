@@ -0,0 +1,76 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrFormVersion = errors.New("Parameters were made with a newer form than the tool has.")
+
+// A change to the form of a tool that the parameters of jobs made with the
+// form before it are translated through, from version From to the next. The
+// parts are applied in the order of the fields.
+type FormMigration struct {
+	From     int                          // Version of the form this migrates from
+	Rename   map[string]string            // Old names of parameters to their new names
+	Values   map[string]map[string]string // Old values of a parameter, by its new name, to the new values
+	Defaults map[string]string            // Parameters the new form added, set when they are not given
+	Remove   []string                     // Parameters the new form no longer has
+}
+
+// Apply the migration to a copy of the parameters
+func (m FormMigration) Apply(params map[string]string) map[string]string {
+	out := make(map[string]string, len(params)+len(m.Defaults))
+	for k, v := range params {
+		if to, ok := m.Rename[k]; ok {
+			k = to
+		}
+		out[k] = v
+	}
+
+	for k, values := range m.Values {
+		if v, ok := values[out[k]]; ok {
+			out[k] = v
+		}
+	}
+
+	for k, v := range m.Defaults {
+		if _, ok := out[k]; !ok {
+			out[k] = v
+		}
+	}
+
+	for _, k := range m.Remove {
+		delete(out, k)
+	}
+
+	return out
+}
+
+// Translate parameters made with a version of the form of the tool to its
+// current form. Version 0 is taken to be the current form, as it is what jobs
+// made before forms were versioned and clients that do not know of versions
+// give.
+func (t Tool) MigrateParameters(version int, params map[string]string) (map[string]string, error) {
+	if version == 0 || version == t.FormVersion {
+		return params, nil
+	}
+	if version > t.FormVersion {
+		return nil, ErrFormVersion
+	}
+
+	steps := make(map[int]FormMigration, len(t.Migrations))
+	for _, m := range t.Migrations {
+		steps[m.From] = m
+	}
+
+	for v := version; v < t.FormVersion; v++ {
+		m, ok := steps[v]
+		if !ok {
+			return nil, fmt.Errorf("The tool cannot migrate parameters from version %d of its form.", v)
+		}
+		params = m.Apply(params)
+	}
+
+	return params, nil
+}
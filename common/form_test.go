@@ -0,0 +1,64 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestMigrateParameters(t *testing.T) {
+	tool := Tool{
+		FormVersion: 3,
+		Migrations: []FormMigration{
+			{
+				From:   1,
+				Rename: map[string]string{"dict": "dictionaries"},
+				Values: map[string]map[string]string{"mode": {"fast": "3"}},
+			},
+			{
+				From:     2,
+				Defaults: map[string]string{"workload": "2"},
+				Remove:   []string{"legacy"},
+			},
+		},
+	}
+
+	old := map[string]string{"dict": "rockyou", "mode": "fast", "legacy": "x", "hashes": "aaaa"}
+	got, err := tool.MigrateParameters(1, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"dictionaries": "rockyou", "mode": "3", "workload": "2", "hashes": "aaaa"}
+	if len(got) != len(want) {
+		t.Errorf("Unexpected parameters %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Parameter %s is %q not %q", k, got[k], v)
+		}
+	}
+	if old["dict"] != "rockyou" || old["legacy"] != "x" {
+		t.Error("Parameters given were changed")
+	}
+
+	// Defaults do not replace what a job set
+	got, _ = tool.MigrateParameters(2, map[string]string{"workload": "4"})
+	if got["workload"] != "4" {
+		t.Errorf("Default replaced the workload %v", got)
+	}
+
+	// The current form and version 0 are left as they are
+	for _, v := range []int{0, 3} {
+		if got, _ := tool.MigrateParameters(v, old); got["dict"] != "rockyou" {
+			t.Errorf("Version %d was migrated %v", v, got)
+		}
+	}
+
+	if _, err := tool.MigrateParameters(4, old); err != ErrFormVersion {
+		t.Errorf("Newer form gave %v", err)
+	}
+
+	tool.Migrations = tool.Migrations[1:]
+	if _, err := tool.MigrateParameters(1, old); err == nil {
+		t.Error("Migrated without a step from version 1")
+	}
+}
@@ -0,0 +1,124 @@
+// Package geoip finds the location of an IP address from a CSV file of
+// networks. Each line is a network in CIDR notation followed by a location,
+// such as "203.0.113.0/24,Sydney, Australia". Lines starting with # are
+// skipped, so databases such as GeoLite2 can be converted with a short script.
+package geoip
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A network and where it is
+type block struct {
+	first    net.IP // 16 byte form
+	last     net.IP
+	location string
+}
+
+// The networks of a database sorted by their first address
+type DB struct {
+	blocks []block
+	reach  []net.IP // Highest last address of the blocks up to each index
+}
+
+// Load a database from a file
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Load a database, lines that are not a valid network are skipped
+func Read(r io.Reader) (*DB, error) {
+	db := &DB{}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, ",")
+		if i < 0 {
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(strings.TrimSpace(line[:i]))
+		if err != nil {
+			continue
+		}
+
+		first := network.IP.To16()
+		last := make(net.IP, len(first))
+		mask := network.Mask
+		if len(mask) == net.IPv4len {
+			mask = append(net.CIDRMask(96, 128)[:12], mask...)
+		}
+		for b := range first {
+			last[b] = first[b] | ^mask[b]
+		}
+
+		db.blocks = append(db.blocks, block{
+			first:    first,
+			last:     last,
+			location: strings.Trim(strings.TrimSpace(line[i+1:]), `"`),
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(db.blocks, func(i, j int) bool {
+		return bytes.Compare(db.blocks[i].first, db.blocks[j].first) < 0
+	})
+
+	db.reach = make([]net.IP, len(db.blocks))
+	for i, b := range db.blocks {
+		db.reach[i] = b.last
+		if i > 0 && bytes.Compare(db.reach[i-1], b.last) > 0 {
+			db.reach[i] = db.reach[i-1]
+		}
+	}
+
+	return db, nil
+}
+
+// Get the location of an address, empty if it is not in the database. When
+// networks overlap the one starting closest to the address is used.
+func (db *DB) Locate(addr string) string {
+	ip := net.ParseIP(addr).To16()
+	if db == nil || ip == nil {
+		return ""
+	}
+
+	// The last network starting at or before the address
+	i := sort.Search(len(db.blocks), func(i int) bool {
+		return bytes.Compare(db.blocks[i].first, ip) > 0
+	}) - 1
+
+	// Earlier networks are only checked while one of them could still hold
+	// the address
+	for ; i >= 0 && bytes.Compare(ip, db.reach[i]) <= 0; i-- {
+		if bytes.Compare(ip, db.blocks[i].last) <= 0 {
+			return db.blocks[i].location
+		}
+	}
+
+	return ""
+}
+
+// Number of networks in the database
+func (db *DB) Len() int {
+	return len(db.blocks)
+}
@@ -0,0 +1,45 @@
+package geoip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	db, err := Read(strings.NewReader(`# network,location
+10.0.0.0/8,Internal
+10.1.2.0/24,"Lab, Building 2"
+203.0.113.0/24,Sydney, Australia
+2001:db8::/32,Documentation
+not a network,Skipped
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if db.Len() != 4 {
+		t.Errorf("Expected 4 networks, got %d", db.Len())
+	}
+
+	tests := map[string]string{
+		"10.200.0.1":    "Internal",
+		"10.1.2.3":      "Lab, Building 2",
+		"10.1.3.1":      "Internal",
+		"203.0.113.255": "Sydney, Australia",
+		"203.0.114.0":   "",
+		"2001:db8::1":   "Documentation",
+		"2001:db9::1":   "",
+		"192.0.2.1":     "",
+		"not an ip":     "",
+	}
+	for ip, want := range tests {
+		if got := db.Locate(ip); got != want {
+			t.Errorf("Locate(%s) = %q, expected %q", ip, got, want)
+		}
+	}
+
+	var none *DB
+	if none.Locate("10.0.0.1") != "" {
+		t.Error("A nil database must not locate anything")
+	}
+}
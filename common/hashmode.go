@@ -0,0 +1,56 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A hashcat mode a hash may be in
+type HashMode struct {
+	Mode string
+	Name string
+}
+
+// Formats of common hashes and the modes they may be, most likely first
+var hashPatterns = []struct {
+	re    *regexp.Regexp
+	modes []HashMode
+}{
+	{regexp.MustCompile(`^\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}$`), []HashMode{{"3200", "bcrypt"}}},
+	{regexp.MustCompile(`^\$1\$`), []HashMode{{"500", "md5crypt"}}},
+	{regexp.MustCompile(`^\$apr1\$`), []HashMode{{"1600", "Apache apr1"}}},
+	{regexp.MustCompile(`^\$5\$`), []HashMode{{"7400", "sha256crypt"}}},
+	{regexp.MustCompile(`^\$6\$`), []HashMode{{"1800", "sha512crypt"}}},
+	{regexp.MustCompile(`^\$[PH]\$`), []HashMode{{"400", "phpass"}}},
+	{regexp.MustCompile(`^(?i)\$DCC2\$`), []HashMode{{"2100", "Domain Cached Credentials 2"}}},
+	{regexp.MustCompile(`^\$krb5tgs\$23\$`), []HashMode{{"13100", "Kerberos 5 TGS-REP etype 23"}}},
+	{regexp.MustCompile(`^\$krb5tgs\$17\$`), []HashMode{{"19600", "Kerberos 5 TGS-REP etype 17"}}},
+	{regexp.MustCompile(`^\$krb5tgs\$18\$`), []HashMode{{"19700", "Kerberos 5 TGS-REP etype 18"}}},
+	{regexp.MustCompile(`^\$krb5asrep\$23\$`), []HashMode{{"18200", "Kerberos 5 AS-REP etype 23"}}},
+	{regexp.MustCompile(`^\$krb5pa\$23\$`), []HashMode{{"7500", "Kerberos 5 AS-REQ Pre-Auth etype 23"}}},
+	{regexp.MustCompile(`^pbkdf2_sha256\$`), []HashMode{{"10000", "Django PBKDF2-SHA256"}}},
+	{regexp.MustCompile(`^(?i)\*[0-9a-f]{40}$`), []HashMode{{"300", "MySQL4.1/MySQL5"}}},
+	{regexp.MustCompile(`^(?i)[^:]+::[^:]*:[0-9a-f]{16}:[0-9a-f]{32}:[0-9a-f]+$`), []HashMode{{"5600", "NetNTLMv2"}}},
+	{regexp.MustCompile(`^(?i)[^:]+::[^:]*:[0-9a-f]{48}:[0-9a-f]{48}:[0-9a-f]{16}$`), []HashMode{{"5500", "NetNTLMv1"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{16}$`), []HashMode{{"200", "MySQL323"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{32}$`), []HashMode{{"0", "MD5"}, {"1000", "NTLM"}, {"900", "MD4"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{40}$`), []HashMode{{"100", "SHA1"}, {"6000", "RipeMD160"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{64}$`), []HashMode{{"1400", "SHA-256"}, {"17400", "SHA3-256"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{96}$`), []HashMode{{"10800", "SHA-384"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{128}$`), []HashMode{{"1700", "SHA-512"}, {"6100", "Whirlpool"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{32}:.+$`), []HashMode{{"10", "md5($pass.$salt)"}, {"20", "md5($salt.$pass)"}}},
+	{regexp.MustCompile(`^(?i)[0-9a-f]{40}:.+$`), []HashMode{{"110", "sha1($pass.$salt)"}, {"120", "sha1($salt.$pass)"}}},
+}
+
+// Guess the hashcat modes a single hash may be in from its format, the most
+// likely first. Plain hex digests are ambiguous so every mode of their length
+// is returned. Nothing is returned for formats that are not known.
+func DetectHashModes(hash string) []HashMode {
+	hash = strings.TrimSpace(hash)
+	for _, p := range hashPatterns {
+		if p.re.MatchString(hash) {
+			return append([]HashMode(nil), p.modes...)
+		}
+	}
+	return nil
+}
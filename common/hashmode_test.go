@@ -0,0 +1,31 @@
+package common
+
+import "testing"
+
+func TestDetectHashModes(t *testing.T) {
+	tests := map[string]string{
+		"5f4dcc3b5aa765d61d8327deb882cf99":                                                     "0",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8":                                             "100",
+		"5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8":                     "1400",
+		"$2b$12$GhvMmNVjRW29ulnudl.LbuAnUtN/LRfe1JsBm1Xu6LE3059z5Tr8m":                         "3200",
+		"$6$rounds=5000$salt$abc":                                                              "1800",
+		"$krb5tgs$23$*svc_sql$CORP.LOCAL$MSSQLSvc/db.corp.local*$abc$def":                      "13100",
+		"admin::CORP:1122334455667788:0123456789abcdef0123456789abcdef:0101000000000000abcdef": "5600",
+		"*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19":                                            "300",
+		`  5f4dcc3b5aa765d61d8327deb882cf99:saltvalue  `:                                       "10",
+	}
+
+	for hash, want := range tests {
+		modes := DetectHashModes(hash)
+		if len(modes) == 0 || modes[0].Mode != want {
+			t.Errorf("Expected %s to be mode %s first, got %v", hash, want, modes)
+		}
+	}
+
+	if modes := DetectHashModes("8846f7eaee8fb117ad06bdd830b7586c"); len(modes) < 2 || modes[1].Mode != "1000" {
+		t.Errorf("Expected NTLM to be offered for a 32 character hex hash, got %v", modes)
+	}
+	if modes := DetectHashModes("not a hash"); modes != nil {
+		t.Errorf("Expected no modes for unknown input, got %v", modes)
+	}
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ISSUE_ERROR   = "error"   // The job is refused, the tool would fail on the input
+	ISSUE_WARNING = "warning" // The job is created but the input may not be what was meant
+)
+
+// The most line numbers given with an issue
+const maxIssueLines = 10
+
+// A problem found in the input of a job before it is dispatched
+type InputIssue struct {
+	Severity string
+	Code     string // Such as hashes.duplicate, for clients to recognize the issue
+	Message  string // What is wrong and how to fix it
+	Lines    []int  // The first few lines with the problem, starting at 1
+	Count    int    // Number of lines with the problem
+}
+
+// Check if any of the issues should stop the job from being created
+func HasInputErrors(issues []InputIssue) bool {
+	for _, i := range issues {
+		if i.Severity == ISSUE_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+// Collects the lines that have one problem
+type issueLines struct {
+	lines []int
+	count int
+}
+
+func (l *issueLines) add(line int) {
+	if len(l.lines) < maxIssueLines {
+		l.lines = append(l.lines, line)
+	}
+	l.count++
+}
+
+// Build an issue from the lines found, nil if there were none
+func (l issueLines) issue(severity, code, message string) *InputIssue {
+	if l.count == 0 {
+		return nil
+	}
+
+	return &InputIssue{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(message, l.count),
+		Lines:    l.lines,
+		Count:    l.count,
+	}
+}
+
+// Each non-empty line of the hashes with its line number
+func HashLines(hashes string, f func(n int, line string)) {
+	for i, line := range strings.Split(hashes, "\n") {
+		if line != "" {
+			f(i+1, line)
+		}
+	}
+}
+
+// Check a list of hashes for mistakes that would make any tool fail. Byte
+// order marks and Windows line endings are removed from the hashes returned.
+func CheckHashes(hashes string) (string, []InputIssue) {
+	var issues []InputIssue
+
+	switch {
+	case strings.HasPrefix(hashes, "\xef\xbb\xbf"):
+		hashes = strings.TrimPrefix(hashes, "\xef\xbb\xbf")
+		issues = append(issues, InputIssue{
+			Severity: ISSUE_WARNING,
+			Code:     "hashes.bom",
+			Message:  "The hashes started with a UTF-8 byte order mark, it was removed.",
+			Lines:    []int{1},
+			Count:    1,
+		})
+	case strings.HasPrefix(hashes, "\xff\xfe"), strings.HasPrefix(hashes, "\xfe\xff"):
+		return hashes, []InputIssue{{
+			Severity: ISSUE_ERROR,
+			Code:     "hashes.utf16",
+			Message:  "The hashes are UTF-16 encoded, save the file as UTF-8 or ASCII.",
+			Lines:    []int{1},
+			Count:    1,
+		}}
+	}
+
+	if strings.Contains(hashes, "\r") {
+		hashes = strings.Replace(hashes, "\r\n", "\n", -1)
+		hashes = strings.Replace(hashes, "\r", "\n", -1)
+		issues = append(issues, InputIssue{
+			Severity: ISSUE_WARNING,
+			Code:     "hashes.crlf",
+			Message:  "The hashes had Windows line endings, they were changed to Unix line endings.",
+		})
+	}
+
+	var total int
+	var space, dups issueLines
+	seen := map[string]bool{}
+	HashLines(hashes, func(n int, line string) {
+		total++
+
+		if strings.TrimSpace(line) != line {
+			space.add(n)
+		}
+
+		if seen[line] {
+			dups.add(n)
+		}
+		seen[line] = true
+	})
+
+	if total == 0 {
+		return hashes, append(issues, InputIssue{
+			Severity: ISSUE_ERROR,
+			Code:     "hashes.empty",
+			Message:  "No hashes were provided.",
+		})
+	}
+
+	if i := space.issue(ISSUE_WARNING, "hashes.whitespace", "%d lines start or end with spaces or tabs, which tools read as part of the hash."); i != nil {
+		issues = append(issues, *i)
+	}
+	if i := dups.issue(ISSUE_WARNING, "hashes.duplicate", "%d lines are duplicates of earlier lines."); i != nil {
+		issues = append(issues, *i)
+	}
+
+	return hashes, issues
+}
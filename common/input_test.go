@@ -0,0 +1,35 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestCheckHashes(t *testing.T) {
+	hashes, issues := CheckHashes("\xef\xbb\xbfaaaa\r\nbbbb \r\naaaa\r\n")
+	if hashes != "aaaa\nbbbb \naaaa\n" {
+		t.Errorf("Unexpected cleaned hashes %q", hashes)
+	}
+
+	codes := map[string]InputIssue{}
+	for _, i := range issues {
+		codes[i.Code] = i
+	}
+	for _, code := range []string{"hashes.bom", "hashes.crlf", "hashes.whitespace", "hashes.duplicate"} {
+		if _, ok := codes[code]; !ok {
+			t.Errorf("Expected a %s issue in %+v", code, issues)
+		}
+	}
+	if d := codes["hashes.duplicate"]; d.Count != 1 || len(d.Lines) != 1 || d.Lines[0] != 3 {
+		t.Errorf("Unexpected duplicate issue %+v", d)
+	}
+	if HasInputErrors(issues) {
+		t.Error("Problems that were fixed should only be warnings")
+	}
+
+	if _, issues := CheckHashes("\xff\xfea\x00"); !HasInputErrors(issues) {
+		t.Error("UTF-16 hashes should be refused")
+	}
+	if _, issues := CheckHashes("\n\n"); !HasInputErrors(issues) {
+		t.Error("Empty hashes should be refused")
+	}
+}
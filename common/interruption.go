@@ -0,0 +1,193 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cloud providers that give notice before taking back spot and preemptible
+// instances
+const (
+	CLOUD_AWS   = "aws"
+	CLOUD_GCP   = "gcp"
+	CLOUD_AZURE = "azure"
+)
+
+// Address of the instance metadata service, which is the same for all of the
+// cloud providers
+var MetadataURL = "http://169.254.169.254"
+
+// How long GCP and Azure wait after giving notice before an instance is
+// stopped when they do not say
+const defaultInterruptionWarning = 30 * time.Second
+
+// A notice from the cloud provider that the instance a resource runs on is
+// about to be taken back, with restore points of the tasks running on it so
+// they can continue on another resource
+type Interruption struct {
+	Provider    string
+	Noticed     time.Time             // When the notice was seen, zero if there is none
+	Deadline    time.Time             // When the provider will stop the instance
+	Checkpoints map[string]Checkpoint // Restore points of running tasks by job UUID
+}
+
+// Make a request to the instance metadata service
+func metadataRequest(client *http.Client, method, path string, headers map[string]string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, MetadataURL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
+
+// Find which cloud provider the machine runs in from its metadata service, an
+// empty string is returned if it does not run in any of them
+func DetectCloud(client *http.Client) string {
+	resp, _, err := metadataRequest(client, "GET", "/computeMetadata/v1/instance/id", map[string]string{"Metadata-Flavor": "Google"})
+	if err == nil && resp.StatusCode == http.StatusOK && resp.Header.Get("Metadata-Flavor") == "Google" {
+		return CLOUD_GCP
+	}
+
+	resp, _, err = metadataRequest(client, "GET", "/metadata/instance?api-version=2021-02-01", map[string]string{"Metadata": "true"})
+	if err == nil && resp.StatusCode == http.StatusOK {
+		return CLOUD_AZURE
+	}
+
+	if _, err := awsMetadataToken(client); err == nil {
+		return CLOUD_AWS
+	}
+
+	return ""
+}
+
+// Check the metadata service of the provider for notice that the instance is
+// being taken back, returning when it will be stopped if there is one
+func CheckInterruption(client *http.Client, provider string) (time.Time, bool, error) {
+	switch provider {
+	case CLOUD_AWS:
+		return awsInterruption(client)
+	case CLOUD_GCP:
+		return gcpInterruption(client)
+	case CLOUD_AZURE:
+		return azureInterruption(client)
+	}
+
+	return time.Time{}, false, errors.New("Unknown cloud provider " + provider + ".")
+}
+
+// Instance metadata version 2 needs a session token for every request
+func awsMetadataToken(client *http.Client) (string, error) {
+	resp, body, err := metadataRequest(client, "PUT", "/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "300"})
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("Unable to get a metadata token, status " + strconv.Itoa(resp.StatusCode) + ".")
+	}
+
+	return string(body), nil
+}
+
+// Spot instances get an instance action two minutes before they are stopped
+func awsInterruption(client *http.Client) (time.Time, bool, error) {
+	token, err := awsMetadataToken(client)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	resp, body, err := metadataRequest(client, "GET", "/latest/meta-data/spot/instance-action", map[string]string{"X-aws-ec2-metadata-token": token})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return time.Time{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, errors.New("Unable to read the spot instance action, status " + strconv.Itoa(resp.StatusCode) + ".")
+	}
+
+	var action struct {
+		Action string `json:"action"`
+		Time   string `json:"time"`
+	}
+	if err := json.Unmarshal(body, &action); err != nil {
+		return time.Time{}, false, err
+	}
+
+	deadline, err := time.Parse(time.RFC3339, action.Time)
+	if err != nil {
+		deadline = time.Now().Add(2 * time.Minute)
+	}
+
+	return deadline, true, nil
+}
+
+// Preemptible and spot instances are marked as preempted when they are being
+// stopped
+func gcpInterruption(client *http.Client) (time.Time, bool, error) {
+	resp, body, err := metadataRequest(client, "GET", "/computeMetadata/v1/instance/preempted", map[string]string{"Metadata-Flavor": "Google"})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, errors.New("Unable to read the preempted state, status " + strconv.Itoa(resp.StatusCode) + ".")
+	}
+
+	if strings.TrimSpace(string(body)) != "TRUE" {
+		return time.Time{}, false, nil
+	}
+
+	return time.Now().Add(defaultInterruptionWarning), true, nil
+}
+
+// Spot virtual machines get a scheduled event of the Preempt type before they
+// are evicted
+func azureInterruption(client *http.Client) (time.Time, bool, error) {
+	resp, body, err := metadataRequest(client, "GET", "/metadata/scheduledevents?api-version=2020-07-01", map[string]string{"Metadata": "true"})
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, false, errors.New("Unable to read the scheduled events, status " + strconv.Itoa(resp.StatusCode) + ".")
+	}
+
+	var events struct {
+		Events []struct {
+			EventType string
+			NotBefore string
+		}
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return time.Time{}, false, err
+	}
+
+	for _, e := range events.Events {
+		if e.EventType != "Preempt" {
+			continue
+		}
+
+		deadline, err := time.Parse(time.RFC1123, e.NotBefore)
+		if err != nil {
+			deadline = time.Now().Add(defaultInterruptionWarning)
+		}
+		return deadline, true, nil
+	}
+
+	return time.Time{}, false, nil
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Point the metadata service at a test server for the duration of a test
+func testMetadata(t *testing.T, handler http.HandlerFunc) *http.Client {
+	srv := httptest.NewServer(handler)
+	old := MetadataURL
+	MetadataURL = srv.URL
+	t.Cleanup(func() {
+		MetadataURL = old
+		srv.Close()
+	})
+
+	return srv.Client()
+}
+
+func TestAWSInterruption(t *testing.T) {
+	var notice bool
+	client := testMetadata(t, func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "PUT /latest/api/token":
+			rw.Write([]byte("token"))
+		case "GET /latest/meta-data/spot/instance-action":
+			if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !notice {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			rw.Write([]byte(`{"action": "terminate", "time": "2026-10-14T08:22:00Z"}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if cloud := DetectCloud(client); cloud != CLOUD_AWS {
+		t.Errorf("Expected the cloud to be %s, got %q", CLOUD_AWS, cloud)
+	}
+
+	if _, ok, err := CheckInterruption(client, CLOUD_AWS); ok || err != nil {
+		t.Errorf("Expected no notice, got %v %v", ok, err)
+	}
+
+	notice = true
+	deadline, ok, err := CheckInterruption(client, CLOUD_AWS)
+	if !ok || err != nil {
+		t.Fatalf("Expected a notice, got %v %v", ok, err)
+	}
+	if want := time.Date(2026, 10, 14, 8, 22, 0, 0, time.UTC); !deadline.Equal(want) {
+		t.Errorf("Expected the instance to be stopped at %s, got %s", want, deadline)
+	}
+}
+
+func TestGCPInterruption(t *testing.T) {
+	preempted := "FALSE"
+	client := testMetadata(t, func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+		rw.Header().Set("Metadata-Flavor", "Google")
+
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/id":
+			rw.Write([]byte("1234"))
+		case "/computeMetadata/v1/instance/preempted":
+			rw.Write([]byte(preempted))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if cloud := DetectCloud(client); cloud != CLOUD_GCP {
+		t.Errorf("Expected the cloud to be %s, got %q", CLOUD_GCP, cloud)
+	}
+
+	if _, ok, err := CheckInterruption(client, CLOUD_GCP); ok || err != nil {
+		t.Errorf("Expected no notice, got %v %v", ok, err)
+	}
+
+	preempted = "TRUE"
+	deadline, ok, err := CheckInterruption(client, CLOUD_GCP)
+	if !ok || err != nil {
+		t.Fatalf("Expected a notice, got %v %v", ok, err)
+	}
+	if deadline.Before(time.Now()) {
+		t.Errorf("Expected the deadline to be in the future, got %s", deadline)
+	}
+}
+
+func TestAzureInterruption(t *testing.T) {
+	events := `{"DocumentIncarnation": 1, "Events": [{"EventType": "Freeze", "NotBefore": ""}]}`
+	client := testMetadata(t, func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/metadata/instance":
+			rw.Write([]byte(`{"compute": {}}`))
+		case "/metadata/scheduledevents":
+			rw.Write([]byte(events))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	if cloud := DetectCloud(client); cloud != CLOUD_AZURE {
+		t.Errorf("Expected the cloud to be %s, got %q", CLOUD_AZURE, cloud)
+	}
+
+	// Only evictions of spot machines are interruptions
+	if _, ok, err := CheckInterruption(client, CLOUD_AZURE); ok || err != nil {
+		t.Errorf("Expected no notice, got %v %v", ok, err)
+	}
+
+	events = `{"DocumentIncarnation": 2, "Events": [{"EventType": "Preempt", "NotBefore": "Wed, 14 Oct 2026 08:22:30 GMT"}]}`
+	deadline, ok, err := CheckInterruption(client, CLOUD_AZURE)
+	if !ok || err != nil {
+		t.Fatalf("Expected a notice, got %v %v", ok, err)
+	}
+	if want := time.Date(2026, 10, 14, 8, 22, 30, 0, time.UTC); !deadline.Equal(want) {
+		t.Errorf("Expected the machine to be evicted at %s, got %s", want, deadline)
+	}
+}
+
+func TestDetectNoCloud(t *testing.T) {
+	client := testMetadata(t, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	})
+
+	if cloud := DetectCloud(client); cloud != "" {
+		t.Errorf("Expected no cloud to be found, got %q", cloud)
+	}
+}
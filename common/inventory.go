@@ -0,0 +1,209 @@
+package common
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A GPU installed in a resource
+type GPU struct {
+	Model  string
+	Memory int64 // VRAM in megabytes
+	Driver string
+}
+
+// The hardware inventory of a resource reported when it is connected
+type Inventory struct {
+	GPUs     []GPU
+	CUDA     string // CUDA version supported by the driver
+	CPUModel string
+	CPUCores int
+	Memory   int64             // RAM in megabytes
+	Files    []InventoryFile   // Wordlists and rules found in the directories the resource scans
+	OS       string            // Platform tool binaries are picked for, from runtime.GOOS
+	Arch     string            // From runtime.GOARCH
+	Binaries map[string]string // Version of each tool binary installed from the queue, by tool
+}
+
+// Hardware a job needs from the resource it runs on. Zero values are not
+// checked.
+type Constraints struct {
+	MinGPUs      int
+	MinGPUMemory int64  // Megabytes of VRAM every GPU must have
+	GPUModel     string // Text the model of a GPU must contain, without case
+	MinCPUCores  int
+	MinMemory    int64  // Megabytes of RAM
+	Files        string // SHA-256 of files the resource must have, separated by commas
+}
+
+func (c Constraints) IsZero() bool {
+	return c == Constraints{}
+}
+
+// Combine two sets of constraints, keeping the larger of each requirement
+func (c Constraints) Merge(o Constraints) Constraints {
+	if o.MinGPUs > c.MinGPUs {
+		c.MinGPUs = o.MinGPUs
+	}
+	if o.MinGPUMemory > c.MinGPUMemory {
+		c.MinGPUMemory = o.MinGPUMemory
+	}
+	if c.GPUModel == "" {
+		c.GPUModel = o.GPUModel
+	}
+	if o.MinCPUCores > c.MinCPUCores {
+		c.MinCPUCores = o.MinCPUCores
+	}
+	if o.MinMemory > c.MinMemory {
+		c.MinMemory = o.MinMemory
+	}
+	c.Files = joinFileHashes(append(c.FileHashes(), o.FileHashes()...))
+
+	return c
+}
+
+// The hashes of the files the constraints need
+func (c Constraints) FileHashes() []string {
+	if c.Files == "" {
+		return nil
+	}
+	return strings.Split(c.Files, ",")
+}
+
+// Join file hashes for constraints in order without duplicates
+func joinFileHashes(hashes []string) string {
+	sort.Strings(hashes)
+
+	var out []string
+	for i, h := range hashes {
+		if h != "" && (i == 0 || h != hashes[i-1]) {
+			out = append(out, h)
+		}
+	}
+
+	return strings.Join(out, ",")
+}
+
+// Check if the hardware meets the constraints of a job
+func (i Inventory) Satisfies(c Constraints) bool {
+	if c.IsZero() {
+		return true
+	}
+
+	gpus := 0
+	for _, g := range i.GPUs {
+		if c.MinGPUMemory > 0 && g.Memory < c.MinGPUMemory {
+			continue
+		}
+		if c.GPUModel != "" && !strings.Contains(strings.ToLower(g.Model), strings.ToLower(c.GPUModel)) {
+			continue
+		}
+		gpus++
+	}
+
+	// A GPU requirement without a count needs at least one matching GPU
+	needGPUs := c.MinGPUs
+	if needGPUs == 0 && (c.MinGPUMemory > 0 || c.GPUModel != "") {
+		needGPUs = 1
+	}
+	if gpus < needGPUs {
+		return false
+	}
+
+	if i.CPUCores < c.MinCPUCores || i.Memory < c.MinMemory {
+		return false
+	}
+
+	for _, h := range c.FileHashes() {
+		if !i.HasFile(h) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var cudaVersion = regexp.MustCompile(`CUDA Version:\s*([0-9.]+)`)
+
+// Gather the hardware inventory of this machine. GPUs are found with
+// nvidia-smi when it is installed.
+func GatherInventory() Inventory {
+	inv := Inventory{
+		CPUCores: runtime.NumCPU(),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+	}
+
+	if data, err := ioutil.ReadFile("/proc/meminfo"); err == nil {
+		inv.Memory = ParseMemInfo(string(data))
+	}
+	if data, err := ioutil.ReadFile("/proc/cpuinfo"); err == nil {
+		inv.CPUModel = ParseCPUInfo(string(data))
+	}
+
+	if out, err := exec.Command("nvidia-smi", "--query-gpu=name,memory.total,driver_version", "--format=csv,noheader,nounits").Output(); err == nil {
+		inv.GPUs = ParseNvidiaSMI(string(out))
+	}
+	if out, err := exec.Command("nvidia-smi").Output(); err == nil {
+		if m := cudaVersion.FindStringSubmatch(string(out)); m != nil {
+			inv.CUDA = m[1]
+		}
+	}
+
+	return inv
+}
+
+// Parse the CSV output of nvidia-smi --query-gpu=name,memory.total,driver_version
+func ParseNvidiaSMI(out string) []GPU {
+	var gpus []GPU
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 3 {
+			continue
+		}
+
+		mem, _ := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		gpus = append(gpus, GPU{
+			Model:  strings.TrimSpace(fields[0]),
+			Memory: mem,
+			Driver: strings.TrimSpace(fields[2]),
+		})
+	}
+
+	return gpus
+}
+
+// Get the total memory in megabytes from /proc/meminfo
+func ParseMemInfo(data string) int64 {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb / 1024
+		}
+	}
+
+	return 0
+}
+
+// Get the CPU model name from /proc/cpuinfo
+func ParseCPUInfo(data string) string {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "model name" {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseInventory(t *testing.T) {
+	gpus := ParseNvidiaSMI("NVIDIA GeForce RTX 4090, 24564, 535.129.03\nTesla T4, 15360, 535.129.03\n")
+	if len(gpus) != 2 {
+		t.Fatalf("Expected 2 GPUs but got %d", len(gpus))
+	}
+	if gpus[0].Model != "NVIDIA GeForce RTX 4090" || gpus[0].Memory != 24564 || gpus[0].Driver != "535.129.03" {
+		t.Errorf("Unexpected GPU %+v", gpus[0])
+	}
+
+	if mem := ParseMemInfo("MemTotal:       65849480 kB\nMemFree:         1234 kB\n"); mem != 64306 {
+		t.Errorf("Expected 64306 MB but got %d", mem)
+	}
+
+	if cpu := ParseCPUInfo("processor\t: 0\nmodel name\t: AMD EPYC 7763 64-Core Processor\n"); cpu != "AMD EPYC 7763 64-Core Processor" {
+		t.Errorf("Unexpected CPU model %q", cpu)
+	}
+}
+
+func TestInventorySatisfies(t *testing.T) {
+	inv := Inventory{
+		GPUs: []GPU{
+			{Model: "NVIDIA GeForce RTX 4090", Memory: 24564},
+			{Model: "Tesla T4", Memory: 15360},
+		},
+		CPUCores: 16,
+		Memory:   65536,
+	}
+
+	tests := []struct {
+		c    Constraints
+		want bool
+	}{
+		{Constraints{}, true},
+		{Constraints{MinGPUMemory: 16384}, true},
+		{Constraints{MinGPUMemory: 16384, MinGPUs: 2}, false},
+		{Constraints{GPUModel: "t4"}, true},
+		{Constraints{GPUModel: "a100"}, false},
+		{Constraints{MinCPUCores: 32}, false},
+		{Constraints{MinMemory: 32768, MinGPUs: 2}, true},
+	}
+
+	for _, test := range tests {
+		if got := inv.Satisfies(test.c); got != test.want {
+			t.Errorf("Constraints %+v: expected %v but got %v", test.c, test.want, got)
+		}
+	}
+
+	// Nothing is known about resources that did not report an inventory
+	if (Inventory{}).Satisfies(Constraints{MinGPUs: 1}) {
+		t.Error("An empty inventory should not satisfy a GPU constraint")
+	}
+}
+
+func TestConstraintsMerge(t *testing.T) {
+	c := Constraints{MinGPUs: 2, GPUModel: "4090"}.Merge(Constraints{MinGPUs: 1, MinGPUMemory: 8192, GPUModel: "t4"})
+	if c != (Constraints{MinGPUs: 2, MinGPUMemory: 8192, GPUModel: "4090"}) {
+		t.Errorf("Unexpected merged constraints %+v", c)
+	}
+}
+
+func TestInventoryFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "rules"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "rockyou.txt"), []byte("password\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "rules", "best64.rule"), []byte(":\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, ".fetch-123"), []byte("partial"), 0600)
+
+	inv := Inventory{Files: ScanFiles([]string{dir})}
+	if len(inv.Files) != 2 || inv.Files[0].Name != "rockyou.txt" || inv.Files[1].Name != "rules/best64.rule" {
+		t.Fatalf("Unexpected files %+v", inv.Files)
+	}
+
+	// echo password | sha256sum
+	sum := "6b3a55e0261b0304143f805a24924d0c1c44524821305f31d9277843b8a10f4e"
+	if inv.Files[0].SHA256 != sum {
+		t.Errorf("Unexpected hash %s", inv.Files[0].SHA256)
+	}
+	if cached, ok := CachedFileHash(filepath.Join(dir, "rockyou.txt")); !ok || cached != sum {
+		t.Error("The hash of a scanned file was not cached")
+	}
+
+	c := Constraints{Files: sum}.Merge(Constraints{Files: inv.Files[1].SHA256 + "," + sum})
+	if len(c.FileHashes()) != 2 {
+		t.Errorf("Unexpected merged files %q", c.Files)
+	}
+	if !inv.Satisfies(c) {
+		t.Error("Inventory with the files did not satisfy the constraints")
+	}
+	if inv.Satisfies(Constraints{Files: "0000"}) {
+		t.Error("Inventory without the file satisfied the constraints")
+	}
+}
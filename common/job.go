@@ -2,27 +2,68 @@ package common
 
 import (
 	"github.com/pborman/uuid"
+	"os"
+	"sort"
 	"time"
 )
 
 type Job struct {
-	UUID             string            // UUID generated by the Queue
-	ToolUUID         string            // ID of the tool to use with this job
-	Name             string            // Name of the job
-	Status           string            // Status of the job
-	Error            string            // Last returned error from the tool
-	StartTime        time.Time         // Start time of the job
-	ETC              string            // The estimated time of completion
-	Owner            string            // Owner provided by the web frontend
-	ResAssigned      string            // Resource this job is assinged to if any
-	CrackedHashes    int64             // # of hashes cracked
-	TotalHashes      int64             // # of hashes provided
-	Progress         float64           // # % of cracked/provided
-	Parameters       map[string]string // Parameters returned to the tool
-	PerformanceData  map[string]string // Some performance status map[timestamp]perf#
-	PerformanceTitle string            // Title of the perf #
-	OutputData       [][]string        // A 2D array of rows for output values
-	OutputTitles     []string          // The headers for the 2D array of rows above
+	UUID              string              // UUID generated by the Queue
+	ToolUUID          string              // ID of the tool to use with this job
+	Name              string              // Name of the job
+	Status            string              // Status of the job
+	Error             string              // Last returned error from the tool
+	StartTime         time.Time           // Start time of the job
+	RunTime           time.Duration       // How long the job has been running, added up by the queue across pauses
+	ETC               string              // The estimated time of completion
+	Owner             string              // Owner provided by the web frontend
+	ResAssigned       string              // Resource this job is assinged to if any
+	CrackedHashes     int64               // # of hashes cracked
+	TotalHashes       int64               // # of hashes provided
+	Progress          float64             // # % of cracked/provided
+	Parameters        map[string]string   // Parameters returned to the tool
+	PerformanceData   map[string]string   // Some performance status map[timestamp]perf#
+	PerformanceTitle  string              // Title of the perf #
+	PerformanceMetric Metric              // What the performance data measures, described from the title when the tool does not set it
+	OutputData        [][]string          // A 2D array of rows for output values
+	Output            *OutputTable        // Rows the queue moved out of OutputData to hold them compactly, they came before OutputData
+	OutputTitles      []string            // The headers for the 2D array of rows above
+	OutputMetrics     []Metric            // What the columns of output hold by title, those not described are from their titles
+	OutputSpilled     int                 // Rows of output the queue moved out of memory, they came before Output
+	MaxRuntime        time.Duration       // Maximum time the job may run before it is expired (0 uses the queue default)
+	History           []JobEvent          // Changes made to the job through the queue such as ownership transfers
+	Usernames         map[string][]string // Users of each submitted hash, kept by the queue and never sent to resources
+	NTHashes          map[string][]string // NT hashes of each LM hash, cracked by case toggling once the LM job is done
+	Constraints       Constraints         // Hardware the resource running the job must have
+	ExtraArgs         []string            // Arguments an Administrator added to the tool command line
+	Env               map[string]string   // Environment variables an Administrator set for the tool
+	Project           string              // Engagement the job is for, resources can be reserved for a project
+	Stalled           time.Time           // When the watchdog found the job was not making progress, zero while it is
+	Debug             bool                // Keep all of the tool output and the scheduling decisions made for the job
+	Queue             string              // Named queue the job is scheduled in, empty for the default queue
+	Finished          time.Time           // When the queue found the job was done, zero while it is not
+	Purged            time.Time           // When the hashes and results were removed after the job expired
+	Cost              float64             // Dollars the job has cost on resources with a cost per hour
+	CostApproved      bool                // The job may run over the budget of its project
+	ResultKey         string              // Armored OpenPGP public key the resource encrypts each row of output for, empty for plaintext
+	Sensitive         bool                // Must be approved by an Administrator other than the one that submitted it before it is dispatched
+	SubmittedBy       string              // User that created the job, the Administrator when they were impersonating the owner
+	ApprovedBy        string              // Administrator that approved the sensitive job, empty until it is
+	FormVersion       int                 // Version of the tool form the parameters are for, 0 for the current one
+}
+
+// The debug log a resource keeps for a task of a job with Debug set
+type TaskLog struct {
+	Events []string // Decisions the resource made about the task
+	Output []string // Output of the tool
+}
+
+// A change made to a job, kept as an audit trail
+type JobEvent struct {
+	Time   time.Time
+	User   string // User that made the change
+	Action string
+	Detail string
 }
 
 func NewJob(tooluuid string, name string, owner string, params map[string]string) Job {
@@ -36,3 +77,143 @@ func NewJob(tooluuid string, name string, owner string, params map[string]string
 		PerformanceData: make(map[string]string),
 	}
 }
+
+// Make a deep copy of the job so it can be read without holding the queue lock
+func (j Job) Clone() Job {
+	c := j
+
+	if j.Parameters != nil {
+		c.Parameters = make(map[string]string, len(j.Parameters))
+		for k, v := range j.Parameters {
+			c.Parameters[k] = v
+		}
+	}
+
+	if j.PerformanceData != nil {
+		c.PerformanceData = make(map[string]string, len(j.PerformanceData))
+		for k, v := range j.PerformanceData {
+			c.PerformanceData[k] = v
+		}
+	}
+
+	if j.OutputData != nil {
+		c.OutputData = make([][]string, len(j.OutputData))
+		for i := range j.OutputData {
+			c.OutputData[i] = append([]string(nil), j.OutputData[i]...)
+		}
+	}
+
+	// The table is only appended to, so a view of it is enough
+	c.Output = j.Output.View()
+
+	if j.OutputTitles != nil {
+		c.OutputTitles = append([]string(nil), j.OutputTitles...)
+	}
+
+	if j.OutputMetrics != nil {
+		c.OutputMetrics = append([]Metric(nil), j.OutputMetrics...)
+	}
+
+	if j.History != nil {
+		c.History = append([]JobEvent(nil), j.History...)
+	}
+
+	if j.Usernames != nil {
+		c.Usernames = make(map[string][]string, len(j.Usernames))
+		for k, v := range j.Usernames {
+			c.Usernames[k] = append([]string(nil), v...)
+		}
+	}
+
+	if j.NTHashes != nil {
+		c.NTHashes = make(map[string][]string, len(j.NTHashes))
+		for k, v := range j.NTHashes {
+			c.NTHashes[k] = append([]string(nil), v...)
+		}
+	}
+
+	if j.ExtraArgs != nil {
+		c.ExtraArgs = append([]string(nil), j.ExtraArgs...)
+	}
+
+	if j.Env != nil {
+		c.Env = make(map[string]string, len(j.Env))
+		for k, v := range j.Env {
+			c.Env[k] = v
+		}
+	}
+
+	return c
+}
+
+// The environment a tool runs the job with, nil when no variables were set
+// so the resource's own environment is used
+func (j Job) CommandEnv() []string {
+	if len(j.Env) == 0 {
+		return nil
+	}
+
+	env := os.Environ()
+	keys := make([]string, 0, len(j.Env))
+	for k := range j.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+j.Env[k])
+	}
+
+	return env
+}
+
+// Make a copy of the job to send to a resource, leaving out what only the
+// queue needs to know. Resources build the output of their tasks themselves,
+// so it is not sent back to them.
+func (j Job) ForResource() Job {
+	j.OutputData = nil
+	j.Output = nil
+
+	c := j.Clone()
+	c.Usernames = nil
+	c.NTHashes = nil
+
+	return c
+}
+
+// The number of rows of output held in memory
+func (j Job) OutputLen() int {
+	return j.Output.Len() + len(j.OutputData)
+}
+
+// Get the rows of output held in memory, in order
+func (j Job) OutputRows() [][]string {
+	if j.Output.Len() == 0 {
+		return j.OutputData
+	}
+
+	return append(j.Output.Rows(), j.OutputData...)
+}
+
+// Move the rows of OutputData into the compact table
+func (j *Job) CompactOutput() {
+	if len(j.OutputData) == 0 {
+		return
+	}
+
+	if j.Output == nil {
+		j.Output = NewOutputTable(j.OutputData)
+	} else {
+		j.Output.Append(j.OutputData)
+	}
+	j.OutputData = nil
+}
+
+// Add an event to the audit trail of the job
+func (j *Job) Record(user, action, detail string) {
+	j.History = append(j.History, JobEvent{
+		Time:   time.Now(),
+		User:   user,
+		Action: action,
+		Detail: detail,
+	})
+}
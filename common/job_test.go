@@ -0,0 +1,51 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestJobClone(t *testing.T) {
+	j := NewJob("tool", "name", "owner", map[string]string{"algorithm": "1000"})
+	j.PerformanceData["1"] = "100"
+	j.OutputTitles = []string{"Plaintext", "Hash"}
+	j.OutputData = [][]string{{"password", "hash"}}
+	j.Record("admin", "owner", "Ownership transferred")
+
+	c := j.Clone()
+
+	// Changing the copy must not change the original
+	c.Parameters["algorithm"] = "0"
+	c.PerformanceData["1"] = "200"
+	c.OutputTitles[0] = "Changed"
+	c.OutputData[0][0] = "changed"
+	c.History[0].User = "changed"
+
+	if j.Parameters["algorithm"] != "1000" {
+		t.Error("Parameters of the original job were changed by the clone")
+	}
+	if j.PerformanceData["1"] != "100" {
+		t.Error("Performance data of the original job was changed by the clone")
+	}
+	if j.OutputTitles[0] != "Plaintext" {
+		t.Error("Output titles of the original job were changed by the clone")
+	}
+	if j.OutputData[0][0] != "password" {
+		t.Error("Output data of the original job was changed by the clone")
+	}
+	if j.History[0].User != "admin" {
+		t.Error("History of the original job was changed by the clone")
+	}
+}
+
+func TestJobCommandEnv(t *testing.T) {
+	j := NewJob("tool", "name", "owner", map[string]string{})
+	if env := j.CommandEnv(); env != nil {
+		t.Errorf("A job without variables must use the resource environment, got %d variables", len(env))
+	}
+
+	j.Env = map[string]string{"CUDA_VISIBLE_DEVICES": "1", "A": "2"}
+	env := j.CommandEnv()
+	if n := len(env); n < 2 || env[n-2] != "A=2" || env[n-1] != "CUDA_VISIBLE_DEVICES=1" {
+		t.Errorf("The job variables must follow the resource environment in order, got %v", env[len(env)-2:])
+	}
+}
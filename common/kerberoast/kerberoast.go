@@ -0,0 +1,191 @@
+package kerberoast
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ticket types
+const (
+	TYPE_TGS   = "tgs"   // Kerberoasted service tickets
+	TYPE_ASREP = "asrep" // AS-REP roasted accounts without pre-authentication
+)
+
+// Hashcat modes for each ticket type and encryption type
+var modes = map[string]map[int]string{
+	TYPE_TGS:   {23: "13100", 17: "19600", 18: "19700"},
+	TYPE_ASREP: {23: "18200", 17: "32100", 18: "32200"},
+}
+
+// The start of a ticket hash and its fields
+var ticketStart = regexp.MustCompile(`\$krb5(tgs|asrep)\$(\d+)\$`)
+
+// Rubeus wraps long hashes onto indented lines made only of hash characters
+var continuation = regexp.MustCompile(`^\s+[0-9A-Fa-f$]+\s*$`)
+
+// A single roasted ticket
+type Ticket struct {
+	Type  string
+	Etype int
+	User  string
+	Realm string
+	SPN   string
+	Hash  string
+}
+
+// The hashcat mode used to crack the ticket, empty if it is not supported
+func (t Ticket) Mode() string {
+	return modes[t.Type][t.Etype]
+}
+
+// Parse Rubeus or GetUserSPNs output and return every ticket hash found in it.
+// Hashes wrapped onto several lines are joined back together and duplicate
+// hashes are only returned once.
+func Parse(r io.Reader) ([]Ticket, error) {
+	var tickets []Ticket
+	seen := map[string]bool{}
+
+	var current string
+	flush := func() {
+		if current == "" {
+			return
+		}
+		if t, ok := parseHash(current); ok && !seen[t.Hash] {
+			seen[t.Hash] = true
+			tickets = append(tickets, t)
+		}
+		current = ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if loc := ticketStart.FindStringIndex(line); loc != nil {
+			flush()
+			current = strings.TrimSpace(line[loc[0]:])
+			continue
+		}
+
+		if current != "" && continuation.MatchString(line) {
+			current += strings.TrimSpace(line)
+			continue
+		}
+
+		flush()
+	}
+	flush()
+
+	return tickets, scanner.Err()
+}
+
+func parseHash(hash string) (Ticket, bool) {
+	m := ticketStart.FindStringSubmatch(hash)
+	if m == nil {
+		return Ticket{}, false
+	}
+
+	etype, _ := strconv.Atoi(m[2])
+	t := Ticket{
+		Type:  m[1],
+		Etype: etype,
+		Hash:  hash,
+	}
+
+	rest := strings.TrimPrefix(hash, m[0])
+	switch {
+	case strings.HasPrefix(rest, "*"):
+		// $krb5tgs$23$*user$realm$spn*$checksum$data
+		if end := strings.Index(rest[1:], "*"); end >= 0 {
+			fields := strings.SplitN(rest[1:end+1], "$", 3)
+			t.User = fields[0]
+			if len(fields) > 1 {
+				t.Realm = fields[1]
+			}
+			if len(fields) > 2 {
+				t.SPN = fields[2]
+			}
+		}
+	case t.Type == TYPE_ASREP:
+		// $krb5asrep$23$user@realm:checksum$data
+		if end := strings.Index(rest, ":"); end >= 0 {
+			t.User = rest[:end]
+			if at := strings.LastIndex(t.User, "@"); at >= 0 {
+				t.Realm = t.User[at+1:]
+				t.User = t.User[:at]
+			}
+		}
+	default:
+		// $krb5tgs$18$user$realm$*spn*$checksum$data
+		fields := strings.SplitN(rest, "$", 3)
+		if len(fields) == 3 {
+			t.User = fields[0]
+			t.Realm = fields[1]
+			if strings.HasPrefix(fields[2], "*") {
+				if end := strings.Index(fields[2][1:], "*"); end >= 0 {
+					t.SPN = fields[2][1 : end+1]
+				}
+			}
+		}
+	}
+
+	return t, true
+}
+
+// Tickets that can be cracked with the same hashcat mode
+type Group struct {
+	Type    string
+	Etype   int
+	Mode    string
+	Tickets []Ticket
+}
+
+// Split tickets by the hashcat mode needed to crack them. Tickets with an
+// unsupported encryption type are returned separately.
+func Split(tickets []Ticket) ([]Group, []Ticket) {
+	var unsupported []Ticket
+	groups := map[string]*Group{}
+
+	for _, t := range tickets {
+		mode := t.Mode()
+		if mode == "" {
+			unsupported = append(unsupported, t)
+			continue
+		}
+
+		g, ok := groups[mode]
+		if !ok {
+			g = &Group{Type: t.Type, Etype: t.Etype, Mode: mode}
+			groups[mode] = g
+		}
+		g.Tickets = append(g.Tickets, t)
+	}
+
+	var out []Group
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Type != out[j].Type {
+			return out[i].Type > out[j].Type
+		}
+		return out[i].Etype > out[j].Etype
+	})
+
+	return out, unsupported
+}
+
+// The hashes of the group, one per line
+func (g Group) Hashes() string {
+	var hashes []string
+	for _, t := range g.Tickets {
+		hashes = append(hashes, t.Hash)
+	}
+
+	return strings.Join(hashes, "\n")
+}
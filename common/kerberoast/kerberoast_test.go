@@ -0,0 +1,72 @@
+package kerberoast
+
+import (
+	"strings"
+	"testing"
+)
+
+const rubeus = `
+[*] Action: Kerberoasting
+
+[*] SamAccountName         : sqlsvc
+[*] ServicePrincipalName   : MSSQLSvc/sql01.corp.local:1433
+[*] Hash                   : $krb5tgs$23$*sqlsvc$CORP.LOCAL$MSSQLSvc/sql01.corp.local:1433*$9D1B3C
+                             A03B4E5F
+                             77AA
+
+[*] SamAccountName         : websvc
+[*] Hash                   : $krb5tgs$18$websvc$CORP.LOCAL$*HTTP/web01*$1234$ABCD
+`
+
+const getuserspns = `ServicePrincipalName  Name    MemberOf  PasswordLastSet
+--------------------  ------  --------  ---------------
+$krb5tgs$23$*backup$CORP.LOCAL$corp.local/backup*$AAAA$BBBB
+$krb5tgs$23$*backup$CORP.LOCAL$corp.local/backup*$AAAA$BBBB
+$krb5asrep$23$nopreauth@CORP.LOCAL:CCCC$DDDD
+$krb5tgs$99$*odd$CORP.LOCAL$spn*$EEEE
+`
+
+func TestParseRubeus(t *testing.T) {
+	tickets, err := Parse(strings.NewReader(rubeus))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tickets) != 2 {
+		t.Fatalf("Expected 2 tickets but got %d", len(tickets))
+	}
+
+	tgs := tickets[0]
+	if tgs.Hash != "$krb5tgs$23$*sqlsvc$CORP.LOCAL$MSSQLSvc/sql01.corp.local:1433*$9D1B3CA03B4E5F77AA" {
+		t.Errorf("Wrapped hash was not joined: %s", tgs.Hash)
+	}
+	if tgs.User != "sqlsvc" || tgs.Realm != "CORP.LOCAL" || tgs.SPN != "MSSQLSvc/sql01.corp.local:1433" || tgs.Mode() != "13100" {
+		t.Errorf("Unexpected ticket %+v", tgs)
+	}
+
+	aes := tickets[1]
+	if aes.User != "websvc" || aes.SPN != "HTTP/web01" || aes.Mode() != "19700" {
+		t.Errorf("Unexpected AES ticket %+v", aes)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tickets, _ := Parse(strings.NewReader(getuserspns))
+	if len(tickets) != 3 {
+		t.Fatalf("Expected duplicates to be removed but got %d tickets", len(tickets))
+	}
+
+	groups, unsupported := Split(tickets)
+	if len(unsupported) != 1 || unsupported[0].Etype != 99 {
+		t.Errorf("Unexpected unsupported tickets %v", unsupported)
+	}
+	if len(groups) != 2 || groups[0].Mode != "13100" || groups[1].Mode != "18200" {
+		t.Fatalf("Unexpected groups %+v", groups)
+	}
+	if groups[1].Tickets[0].User != "nopreauth" || groups[1].Tickets[0].Realm != "CORP.LOCAL" {
+		t.Errorf("Unexpected AS-REP ticket %+v", groups[1].Tickets[0])
+	}
+	if groups[0].Hashes() != "$krb5tgs$23$*backup$CORP.LOCAL$corp.local/backup*$AAAA$BBBB" {
+		t.Errorf("Unexpected hashes %q", groups[0].Hashes())
+	}
+}
@@ -29,14 +29,22 @@ func NewFileHook(location string) (*fileHook, error) {
 
 //Function that will be called every time we have an item to log to the file
 func (hook *fileHook) Fire(entry *logrus.Entry) error {
+	//Write it to the file and then return
+	_, err := hook.Writer.WriteString(formatEntry(entry))
+	return err
+}
+
+//Format a log entry as a single line, this should be called directly from the
+// Fire function of a hook so we can find the caller of the log function
+func formatEntry(entry *logrus.Entry) string {
 	//Prep a buffer to hold the output line
 	b := &bytes.Buffer{}
 
 	//First, let's put the time and level of the event
 	fmt.Fprintf(b, "[%-39s %5s]", entry.Time.String(), entry.Level.String())
 
-	//Now let's get the calling function, it should be 4 function calls (this fire, 2 in entry, and then debug/info/error/etc.)
-	_, file, line, ok := runtime.Caller(4)
+	//Now let's get the calling function, it should be 5 function calls (this, fire, 2 in entry, and then debug/info/error/etc.)
+	_, file, line, ok := runtime.Caller(5)
 	if ok {
 		fmt.Fprintf(b, " (%s:%d)", path.Base(file), line)
 	}
@@ -65,9 +73,7 @@ func (hook *fileHook) Fire(entry *logrus.Entry) error {
 	//Newlines are awesome
 	b.WriteByte('\n')
 
-	//Write it to the file and then return
-	_, err := hook.Writer.WriteString(b.String())
-	return err
+	return b.String()
 }
 
 //We want our hook to log all levels.  Note, this will still be changed by 
@@ -0,0 +1,42 @@
+package cracklog
+
+import (
+	"github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Hook that keeps the most recent log lines in memory so they can be pulled
+// remotely without needing access to the log file
+type TailHook struct {
+	tail *common.LineTail
+}
+
+// Create a hook that keeps the given number of lines
+func NewTailHook(lines int) *TailHook {
+	return &TailHook{
+		tail: common.NewLineTail(lines),
+	}
+}
+
+// Function that will be called every time we have an item to log
+func (hook *TailHook) Fire(entry *logrus.Entry) error {
+	_, err := hook.tail.Write([]byte(formatEntry(entry)))
+	return err
+}
+
+// Log all levels, the level set on the global log object still applies
+func (hook *TailHook) Levels() []logrus.Level {
+	return []logrus.Level{
+		logrus.PanicLevel,
+		logrus.FatalLevel,
+		logrus.ErrorLevel,
+		logrus.WarnLevel,
+		logrus.InfoLevel,
+		logrus.DebugLevel,
+	}
+}
+
+// Get up to the last n lines that were logged
+func (hook *TailHook) Lines(n int) []string {
+	return hook.tail.Lines(n)
+}
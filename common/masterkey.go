@@ -0,0 +1,178 @@
+package common
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/vaughan0/go-ini"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Prefix of configuration values, and the contents of key files, encrypted
+// with the master key
+const ENCRYPTED_PREFIX = "enc:"
+
+// Environment variable the master key can be given in, base64 encoded
+const MASTER_KEY_ENV = "CRACKLORD_MASTER_KEY"
+
+// Key encrypted configuration values are decrypted with, nil when none is set
+var MasterKey []byte
+
+var ErrNoMasterKey = errors.New("A value is encrypted but no master key is configured.")
+
+// Check if a configuration value is encrypted with the master key
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, ENCRYPTED_PREFIX)
+}
+
+// Get the master key from the environment, or the MasterKeyFile or the output
+// of the MasterKeyCommand of the General section, nil when none is given. The
+// command can ask a KMS to decrypt the key so it is never kept on disk. The
+// environment variable is cleared so the programs of tools do not inherit it.
+func MasterKeyFromConfig(conf map[string]string) ([]byte, error) {
+	var encoded []byte
+
+	if v := os.Getenv(MASTER_KEY_ENV); v != "" {
+		encoded = []byte(v)
+		os.Unsetenv(MASTER_KEY_ENV)
+	} else if path := StripQuotes(conf["MasterKeyFile"]); path != "" {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded = b
+	} else if command := StripQuotes(conf["MasterKeyCommand"]); command != "" {
+		var stderr bytes.Buffer
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stderr = &stderr
+
+		b, err := cmd.Output()
+		if err != nil {
+			return nil, errors.New("The MasterKeyCommand failed: " + strings.TrimSpace(stderr.String()))
+		}
+		encoded = b
+	} else {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, errors.New("The master key is not base64 encoded.")
+	}
+	if len(key) != 32 {
+		return nil, errors.New("The master key must be 32 bytes.")
+	}
+
+	return key, nil
+}
+
+// Create a new random master key, base64 encoded
+func NewMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Encrypt a value with AES-256-GCM for use in a configuration file
+func EncryptValue(key, plaintext []byte) (string, error) {
+	gcm, err := masterGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return ENCRYPTED_PREFIX + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt a value from a configuration file
+func DecryptValue(key []byte, v string) ([]byte, error) {
+	if key == nil {
+		return nil, ErrNoMasterKey
+	}
+
+	gcm, err := masterGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(v, ENCRYPTED_PREFIX)))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("The encrypted value is malformed.")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("Unable to decrypt the value, it was encrypted with another master key.")
+	}
+
+	return plaintext, nil
+}
+
+// Write a new master key to out, or encrypt in with the master key from the
+// environment or the General section of the config file at confPath, for use in
+// that file. This is what the -newkey and -encrypt flags of the servers do.
+func EncryptConfigFile(confPath string, newKey bool, in io.Reader, out io.Writer) error {
+	if newKey {
+		key, err := NewMasterKey()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, key)
+		return nil
+	}
+
+	genConf := ini.Section{}
+	if confPath != "" {
+		confFile, err := ini.LoadFile(confPath)
+		if err != nil {
+			return errors.New("Unable to " + err.Error())
+		}
+		genConf = confFile.Section("General")
+	}
+
+	key, err := MasterKeyFromConfig(genConf)
+	if err == nil && key == nil {
+		err = ErrNoMasterKey
+	}
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	// Values are usually piped in with a trailing newline
+	v, err := EncryptValue(key, bytes.TrimRight(plaintext, "\r\n"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, v)
+	return nil
+}
+
+func masterGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"strings"
+)
+
+// Kinds of values a metric holds, which is how clients format them
+const (
+	METRIC_RATE      = "rate"    // Per second in Unit, such as H/s
+	METRIC_PERCENT   = "percent" // 0 to 100
+	METRIC_COUNT     = "count"   // A whole number of Unit
+	METRIC_TEXT      = "text"
+	METRIC_HASH      = "hash"
+	METRIC_PLAINTEXT = "plaintext"
+	METRIC_ACCOUNT   = "account"
+)
+
+// What the performance data or a column of output of a job holds, so clients
+// can format and chart it without guessing from a title
+type Metric struct {
+	Name  string  // Identifies the metric across tools, such as speed or plaintext, for clients to translate
+	Title string  // Shown when a client has no translation of the name
+	Unit  string  // Base unit of the values, such as H/s, without a prefix
+	Kind  string  // One of the METRIC_ kinds
+	Scale float64 // Values are multiplied by this for the base unit, such as 1000000 for MH/s, 0 is 1
+}
+
+// Output columns most cracking tools have
+var (
+	PlaintextColumn = Metric{Name: "plaintext", Title: "Plaintext", Kind: METRIC_PLAINTEXT}
+	HashColumn      = Metric{Name: "hash", Title: "Hash", Kind: METRIC_HASH}
+	UsernameColumn  = Metric{Name: "username", Title: UsernameTitle, Kind: METRIC_ACCOUNT}
+)
+
+// Prefixes of the units speeds are given in by tools
+var ratePrefixes = map[string]float64{
+	"":  1,
+	"k": 1e3,
+	"K": 1e3,
+	"M": 1e6,
+	"G": 1e9,
+	"T": 1e12,
+	"P": 1e15,
+}
+
+// Base units of the speeds of tools, hashes for hashcat and the guesses,
+// passwords and candidates of John
+var rateUnits = []string{"H/s", "C/s", "c/s", "p/s", "g/s"}
+
+// The metric of a speed given in a unit such as kH/s or MC/s. Units that are
+// not known are taken as a plain number with the unit as the title.
+func RateMetric(unit string) Metric {
+	unit = strings.TrimSpace(unit)
+	for _, base := range rateUnits {
+		if !strings.HasSuffix(unit, base) {
+			continue
+		}
+
+		if scale, ok := ratePrefixes[strings.TrimSuffix(unit, base)]; ok {
+			return Metric{Name: "speed", Title: unit, Unit: base, Kind: METRIC_RATE, Scale: scale}
+		}
+	}
+
+	return Metric{Name: "performance", Title: unit, Kind: METRIC_COUNT}
+}
+
+// The metric of an output column with only a title, from what the columns of
+// the tools are called
+func ColumnMetric(title string) Metric {
+	switch {
+	case title == PlaintextColumn.Title:
+		return PlaintextColumn
+	case title == UsernameTitle:
+		return UsernameColumn
+	case strings.Contains(title, "Password"):
+		return Metric{Name: "password", Title: title, Kind: METRIC_PLAINTEXT}
+	case strings.Contains(title, "Hash"):
+		return Metric{Name: "hash", Title: title, Kind: METRIC_HASH}
+	}
+
+	return Metric{Name: strings.ToLower(strings.Replace(title, " ", "", -1)), Title: title, Kind: METRIC_TEXT}
+}
+
+// What the performance data of the job holds, from the title when the tool
+// did not say
+func (j Job) PerformanceDescriptor() Metric {
+	if j.PerformanceMetric.Kind != "" {
+		return j.PerformanceMetric
+	}
+	if j.PerformanceTitle == "" {
+		return Metric{}
+	}
+	return RateMetric(j.PerformanceTitle)
+}
+
+// What each of a list of output titles of the job holds, such as those of
+// JoinUsernames. Columns the tool did not describe are described from their
+// titles.
+func (j Job) OutputDescriptors(titles []string) []Metric {
+	out := make([]Metric, len(titles))
+	for i, t := range titles {
+		out[i] = ColumnMetric(t)
+		for _, m := range j.OutputMetrics {
+			if m.Title == t {
+				out[i] = m
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRateMetric(t *testing.T) {
+	for unit, scale := range map[string]float64{"H/s": 1, "kH/s": 1e3, "MH/s": 1e6, "GH/s": 1e9, "KC/s": 1e3} {
+		m := RateMetric(unit)
+		if m.Kind != METRIC_RATE || m.Scale != scale || m.Title != unit {
+			t.Errorf("Unexpected metric of %s %+v", unit, m)
+		}
+	}
+
+	if m := RateMetric("MH/s"); m.Unit != "H/s" {
+		t.Errorf("Unexpected base unit %s", m.Unit)
+	}
+	if m := RateMetric("Time data"); m.Kind != METRIC_COUNT || m.Title != "Time data" {
+		t.Errorf("Unknown unit described as %+v", m)
+	}
+}
+
+func TestOutputDescriptors(t *testing.T) {
+	j := Job{
+		OutputTitles:  []string{"Plaintext", "NT Hash", "Port"},
+		OutputMetrics: []Metric{{Name: "port", Title: "Port", Kind: METRIC_COUNT}},
+	}
+
+	got := j.OutputDescriptors(append([]string{UsernameTitle}, j.OutputTitles...))
+	want := []string{METRIC_ACCOUNT, METRIC_PLAINTEXT, METRIC_HASH, METRIC_COUNT}
+	for i, kind := range want {
+		if got[i].Kind != kind {
+			t.Errorf("Column %d is %+v not %s", i, got[i], kind)
+		}
+	}
+
+	if m := (Job{PerformanceTitle: "kH/s"}).PerformanceDescriptor(); m.Scale != 1e3 {
+		t.Errorf("Performance described from the title as %+v", m)
+	}
+	if m := (Job{}).PerformanceDescriptor(); m.Kind != "" {
+		t.Errorf("Job without performance described as %+v", m)
+	}
+}
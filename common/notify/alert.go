@@ -0,0 +1,203 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	SEVERITY_CRITICAL = "critical"
+	SEVERITY_ERROR    = "error"
+	SEVERITY_WARNING  = "warning"
+)
+
+// A problem operators are paged about. Alerts with the same key are the same
+// problem, so triggering one again does not page again and resolving the key
+// closes it.
+type Alert struct {
+	Key      string
+	Summary  string
+	Details  map[string]string
+	Severity string
+}
+
+// An on call service alerts are sent to
+type Pager interface {
+	Name() string
+	Trigger(a Alert) error
+	Resolve(key string) error
+}
+
+// Send a JSON request and fail on any response that is not a success
+func postJSON(client *http.Client, u string, headers map[string]string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", u, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Sends alerts to a PagerDuty service with the Events API v2
+type PagerDuty struct {
+	RoutingKey string // Integration key of the service
+	Source     string // The host the alerts are about
+	URL        string
+	Client     *http.Client
+}
+
+func NewPagerDuty(routingKey, source string) *PagerDuty {
+	return &PagerDuty{
+		RoutingKey: routingKey,
+		Source:     source,
+		URL:        "https://events.pagerduty.com/v2/enqueue",
+		Client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *PagerDuty) Name() string {
+	return "pagerduty"
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Component     string            `json:"component"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (p *PagerDuty) Trigger(a Alert) error {
+	severity := a.Severity
+	if severity == "" {
+		severity = SEVERITY_ERROR
+	}
+
+	return postJSON(p.Client, p.URL, nil, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    a.Key,
+		Payload: &pagerDutyPayload{
+			Summary:       a.Summary,
+			Source:        p.Source,
+			Severity:      severity,
+			Component:     "cracklord",
+			CustomDetails: a.Details,
+		},
+	})
+}
+
+func (p *PagerDuty) Resolve(key string) error {
+	return postJSON(p.Client, p.URL, nil, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    key,
+	})
+}
+
+// Sends alerts to Opsgenie with the Alert API, the key of an alert is used as
+// its alias
+type Opsgenie struct {
+	APIKey string
+	Source string
+	URL    string // Use https://api.eu.opsgenie.com for accounts in the EU
+	Client *http.Client
+}
+
+func NewOpsgenie(apiKey, source string) *Opsgenie {
+	return &Opsgenie{
+		APIKey: apiKey,
+		Source: source,
+		URL:    "https://api.opsgenie.com",
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (o *Opsgenie) Name() string {
+	return "opsgenie"
+}
+
+type opsgenieAlert struct {
+	Message  string            `json:"message"`
+	Alias    string            `json:"alias"`
+	Source   string            `json:"source"`
+	Priority string            `json:"priority"`
+	Details  map[string]string `json:"details,omitempty"`
+	Tags     []string          `json:"tags"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+}
+
+func (o *Opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
+
+func (o *Opsgenie) Trigger(a Alert) error {
+	priority := "P2"
+	switch a.Severity {
+	case SEVERITY_CRITICAL:
+		priority = "P1"
+	case SEVERITY_WARNING:
+		priority = "P3"
+	}
+
+	// Messages longer than this are refused
+	msg := a.Summary
+	if len(msg) > 130 {
+		msg = msg[:130]
+	}
+
+	return postJSON(o.Client, strings.TrimRight(o.URL, "/")+"/v2/alerts", o.headers(), opsgenieAlert{
+		Message:  msg,
+		Alias:    a.Key,
+		Source:   o.Source,
+		Priority: priority,
+		Details:  a.Details,
+		Tags:     []string{"cracklord"},
+	})
+}
+
+func (o *Opsgenie) Resolve(key string) error {
+	if key == "" {
+		return errors.New("An alert key is needed to close an alert.")
+	}
+
+	u := strings.TrimRight(o.URL, "/") + "/v2/alerts/" + url.PathEscape(key) + "/close?identifierType=alias"
+	return postJSON(o.Client, u, o.headers(), opsgenieClose{Source: o.Source})
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recorded struct {
+	path   string
+	query  string
+	auth   string
+	fields map[string]interface{}
+}
+
+func recordServer(t *testing.T, status int) (*httptest.Server, *[]recorded) {
+	var reqs []recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rec := recorded{path: r.URL.Path, query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		if err := json.NewDecoder(r.Body).Decode(&rec.fields); err != nil {
+			t.Errorf("Unable to decode request: %v", err)
+		}
+		reqs = append(reqs, rec)
+		rw.WriteHeader(status)
+	}))
+	return srv, &reqs
+}
+
+func TestPagerDuty(t *testing.T) {
+	srv, reqs := recordServer(t, http.StatusAccepted)
+	defer srv.Close()
+
+	p := NewPagerDuty("routing", "queue-01")
+	p.URL = srv.URL
+
+	err := p.Trigger(Alert{Key: "resource-1", Summary: "gpu-01 offline", Severity: SEVERITY_CRITICAL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Resolve("resource-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*reqs) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(*reqs))
+	}
+
+	trigger := (*reqs)[0].fields
+	if trigger["event_action"] != "trigger" || trigger["dedup_key"] != "resource-1" || trigger["routing_key"] != "routing" {
+		t.Errorf("Unexpected trigger event %v", trigger)
+	}
+	payload, _ := trigger["payload"].(map[string]interface{})
+	if payload["severity"] != SEVERITY_CRITICAL || payload["source"] != "queue-01" {
+		t.Errorf("Unexpected trigger payload %v", payload)
+	}
+
+	resolve := (*reqs)[1].fields
+	if resolve["event_action"] != "resolve" || resolve["dedup_key"] != "resource-1" {
+		t.Errorf("Unexpected resolve event %v", resolve)
+	}
+}
+
+func TestOpsgenie(t *testing.T) {
+	srv, reqs := recordServer(t, http.StatusAccepted)
+	defer srv.Close()
+
+	o := NewOpsgenie("key", "queue-01")
+	o.URL = srv.URL
+
+	if err := o.Trigger(Alert{Key: "job/1", Summary: "Job stuck", Severity: SEVERITY_WARNING}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Resolve("job/1"); err != nil {
+		t.Fatal(err)
+	}
+
+	create := (*reqs)[0]
+	if create.path != "/v2/alerts" || create.auth != "GenieKey key" {
+		t.Errorf("Unexpected create request %+v", create)
+	}
+	if create.fields["alias"] != "job/1" || create.fields["priority"] != "P3" {
+		t.Errorf("Unexpected alert %v", create.fields)
+	}
+
+	close := (*reqs)[1]
+	if close.path != "/v2/alerts/job/1/close" || close.query != "identifierType=alias" {
+		t.Errorf("Unexpected close request %+v", close)
+	}
+}
+
+func TestPagerError(t *testing.T) {
+	srv, _ := recordServer(t, http.StatusBadRequest)
+	defer srv.Close()
+
+	p := NewPagerDuty("routing", "queue-01")
+	p.URL = srv.URL
+
+	if err := p.Trigger(Alert{Key: "k", Summary: "s"}); err == nil {
+		t.Error("Expected an error when the service refuses the event")
+	}
+}
@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// A job that finished while a digest was collected
+type FinishedJob struct {
+	UUID          string
+	Name          string
+	Status        string
+	CrackedHashes int64
+	TotalHashes   int64
+}
+
+// Hashes a job cracked while a digest was collected
+type CrackedJob struct {
+	UUID    string
+	Name    string
+	Cracked int64
+}
+
+// Everything a user is told about in one digest mail
+type Digest struct {
+	Since    time.Time
+	Finished []FinishedJob
+	Cracked  []CrackedJob
+	Offline  []string // Names of resources that went offline
+}
+
+func (d *Digest) Empty() bool {
+	return len(d.Finished) == 0 && len(d.Cracked) == 0 && len(d.Offline) == 0
+}
+
+// Add the events of a job, cracks of a job seen before are added together
+func (d *Digest) addCracked(c CrackedJob) {
+	for i := range d.Cracked {
+		if d.Cracked[i].UUID == c.UUID {
+			d.Cracked[i].Cracked += c.Cracked
+			d.Cracked[i].Name = c.Name
+			return
+		}
+	}
+	d.Cracked = append(d.Cracked, c)
+}
+
+// Add the events of another digest
+func (d *Digest) Merge(o Digest) {
+	d.Finished = append(d.Finished, o.Finished...)
+	for _, c := range o.Cracked {
+		d.addCracked(c)
+	}
+	d.Offline = append(d.Offline, o.Offline...)
+}
+
+// The mail subject and body of the digest
+func (d *Digest) Mail(username string, now time.Time) (string, string) {
+	subject := "CrackLord digest: " + fmt.Sprintf("%d jobs finished, %d hashes cracked", len(d.Finished), d.totalCracked())
+	if len(d.Offline) > 0 {
+		subject += fmt.Sprintf(", %d resources offline", len(d.Offline))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CrackLord digest for %s from %s to %s\n", username, d.Since.Format("2006-01-02 15:04 MST"), now.Format("2006-01-02 15:04 MST"))
+
+	if len(d.Finished) > 0 {
+		fmt.Fprintf(&b, "\nJobs finished (%d):\n", len(d.Finished))
+		for _, j := range d.Finished {
+			fmt.Fprintf(&b, "  - %s (%s): %d of %d hashes cracked\n", j.Name, j.Status, j.CrackedHashes, j.TotalHashes)
+		}
+	}
+
+	if len(d.Cracked) > 0 {
+		cracked := append([]CrackedJob(nil), d.Cracked...)
+		sort.Slice(cracked, func(i, j int) bool {
+			return cracked[i].Cracked > cracked[j].Cracked
+		})
+
+		fmt.Fprintf(&b, "\nNew cracks (%d):\n", d.totalCracked())
+		for _, c := range cracked {
+			fmt.Fprintf(&b, "  - %s: %d\n", c.Name, c.Cracked)
+		}
+	}
+
+	if len(d.Offline) > 0 {
+		fmt.Fprintf(&b, "\nResources that went offline (%d):\n", len(d.Offline))
+		for _, r := range d.Offline {
+			fmt.Fprintf(&b, "  - %s\n", r)
+		}
+	}
+
+	return subject, b.String()
+}
+
+func (d *Digest) totalCracked() int64 {
+	var n int64
+	for _, c := range d.Cracked {
+		n += c.Cracked
+	}
+	return n
+}
+
+// What was last seen of a job
+type jobState struct {
+	status  string
+	cracked int64
+}
+
+// Finds what changed in the queue between two looks at it. The first look
+// only records the state so nothing from before it is reported.
+type Tracker struct {
+	jobs      map[string]jobState
+	resources map[string]string // Status of each resource by name
+	seeded    bool
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		jobs:      map[string]jobState{},
+		resources: map[string]string{},
+	}
+}
+
+// Compare the jobs and the status of each resource by name to the last look.
+// The events of each job are returned for its owner and the resources that
+// went offline separately.
+func (t *Tracker) Update(jobs []common.Job, resources map[string]string) (map[string]*Digest, []string) {
+	events := map[string]*Digest{}
+	var offline []string
+
+	seen := map[string]bool{}
+	for _, j := range jobs {
+		seen[j.UUID] = true
+		prev, known := t.jobs[j.UUID]
+		t.jobs[j.UUID] = jobState{status: j.Status, cracked: j.CrackedHashes}
+
+		if !t.seeded {
+			continue
+		}
+
+		d := events[j.Owner]
+		if d == nil {
+			d = &Digest{}
+		}
+
+		if j.CrackedHashes > prev.cracked {
+			d.addCracked(CrackedJob{UUID: j.UUID, Name: j.Name, Cracked: j.CrackedHashes - prev.cracked})
+		}
+
+		if common.IsDone(j.Status) && (!known || !common.IsDone(prev.status)) {
+			d.Finished = append(d.Finished, FinishedJob{
+				UUID:          j.UUID,
+				Name:          j.Name,
+				Status:        j.Status,
+				CrackedHashes: j.CrackedHashes,
+				TotalHashes:   j.TotalHashes,
+			})
+		}
+
+		if !d.Empty() {
+			events[j.Owner] = d
+		}
+	}
+
+	// Forget jobs that were removed
+	for id := range t.jobs {
+		if !seen[id] {
+			delete(t.jobs, id)
+		}
+	}
+
+	for name, status := range resources {
+		prev, known := t.resources[name]
+		if t.seeded && known && prev != common.STATUS_QUIT && status == common.STATUS_QUIT {
+			offline = append(offline, name)
+		}
+	}
+	t.resources = resources
+	sort.Strings(offline)
+
+	t.seeded = true
+	return events, offline
+}
+
+// The first time after last that a digest is due. Digests are sent every
+// period starting at the offset from midnight, so a period of a day sends one
+// each day at that time.
+func NextDigest(last time.Time, offset, period time.Duration) time.Time {
+	if period <= 0 {
+		period = 24 * time.Hour
+	}
+
+	y, m, d := last.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, last.Location()).Add(offset % period)
+	for !next.After(last) {
+		next = next.Add(period)
+	}
+	return next
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+
+	job := common.Job{UUID: "1", Name: "NTDS", Owner: "alice", Status: common.STATUS_RUNNING, CrackedHashes: 5, TotalHashes: 10}
+	res := map[string]string{"gpu-01": common.STATUS_RUNNING}
+
+	// Nothing from before the first look is reported
+	events, offline := tr.Update([]common.Job{job}, res)
+	if len(events) != 0 || len(offline) != 0 {
+		t.Fatalf("The first update must not report events, got %v %v", events, offline)
+	}
+
+	job.CrackedHashes = 8
+	job.Status = common.STATUS_DONE
+	events, offline = tr.Update([]common.Job{job}, map[string]string{"gpu-01": common.STATUS_QUIT})
+
+	d := events["alice"]
+	if d == nil || len(d.Cracked) != 1 || d.Cracked[0].Cracked != 3 {
+		t.Fatalf("Expected 3 new cracks for alice, got %+v", d)
+	}
+	if len(d.Finished) != 1 || d.Finished[0].Status != common.STATUS_DONE {
+		t.Errorf("Expected the job to be reported as finished, got %+v", d.Finished)
+	}
+	if len(offline) != 1 || offline[0] != "gpu-01" {
+		t.Errorf("Expected gpu-01 to be reported offline, got %v", offline)
+	}
+
+	// A finished job is only reported once
+	events, offline = tr.Update([]common.Job{job}, map[string]string{"gpu-01": common.STATUS_QUIT})
+	if len(events) != 0 || len(offline) != 0 {
+		t.Errorf("Events must only be reported once, got %v %v", events, offline)
+	}
+}
+
+func TestDigestMail(t *testing.T) {
+	var d Digest
+	d.Merge(Digest{Cracked: []CrackedJob{{UUID: "1", Name: "NTDS", Cracked: 2}}})
+	d.Merge(Digest{Cracked: []CrackedJob{{UUID: "1", Name: "NTDS", Cracked: 3}}, Offline: []string{"gpu-01"}})
+
+	subject, body := d.Mail("alice", time.Now())
+	if !strings.Contains(subject, "5 hashes cracked") || !strings.Contains(subject, "1 resources offline") {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(body, "NTDS: 5") || !strings.Contains(body, "gpu-01") {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	loc := time.UTC
+	last := time.Date(2026, 10, 14, 9, 30, 0, 0, loc)
+
+	if next := NextDigest(last, 8*time.Hour, 24*time.Hour); !next.Equal(time.Date(2026, 10, 15, 8, 0, 0, 0, loc)) {
+		t.Errorf("Expected the daily digest the next morning, got %s", next)
+	}
+	if next := NextDigest(last, 6*time.Hour, 8*time.Hour); !next.Equal(time.Date(2026, 10, 14, 14, 0, 0, 0, loc)) {
+		t.Errorf("Expected the shift digest at 14:00, got %s", next)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	s := NewSMTP("localhost:25", "", "", "cracklord@example.com")
+	msg := string(s.message([]string{"alice@example.com"}, "Digest", "line\n.dot"))
+
+	if !strings.Contains(msg, "\r\n\r\nline\r\n..dot\r\n") {
+		t.Errorf("Body lines must end in CRLF and leading dots be doubled, got %q", msg)
+	}
+}
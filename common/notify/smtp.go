@@ -0,0 +1,131 @@
+// Package notify sends notifications about the queue to users and operators.
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A mail server and the address mail is sent from
+type SMTP struct {
+	Addr     string // host:port of the server
+	Username string // Empty sends mail without authenticating
+	Password string
+	From     string
+	Timeout  time.Duration
+	mux      sync.Mutex
+}
+
+func NewSMTP(addr, username, password, from string) *SMTP {
+	return &SMTP{
+		Addr:     addr,
+		Username: username,
+		Password: password,
+		From:     from,
+		Timeout:  30 * time.Second,
+	}
+}
+
+// Change the password mail is sent with, such as when it is rotated
+func (s *SMTP) SetPassword(password string) {
+	s.mux.Lock()
+	s.Password = password
+	s.mux.Unlock()
+}
+
+// Build the message with the headers mail clients need
+func (s *SMTP) message(to []string, subject, body string) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("From: " + s.From + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+
+	// Lines must end with CRLF and a line of a single dot ends the message
+	for _, line := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n") {
+		if strings.HasPrefix(line, ".") {
+			line = "." + line
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	return b.Bytes()
+}
+
+// Send a plain text mail. STARTTLS is used whenever the server offers it and
+// credentials are never sent without it.
+func (s *SMTP) Send(to []string, subject, body string) error {
+	if len(to) == 0 {
+		return errors.New("The mail has no recipients.")
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Addr, s.Timeout)
+	if err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	s.mux.Lock()
+	password := s.Password
+	s.mux.Unlock()
+
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(to, subject, body)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
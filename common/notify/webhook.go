@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Posts events as JSON to a URL a user gave, such as a chat webhook
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Check the URL of a webhook, only http and https are allowed
+func NewWebhook(u string) (*Webhook, error) {
+	p, err := url.Parse(u)
+	if err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+		return nil, errors.New("Webhooks must be http or https URLs.")
+	}
+
+	return &Webhook{
+		URL:    u,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (w *Webhook) Send(event interface{}) error {
+	return postJSON(w.Client, w.URL, nil, event)
+}
@@ -0,0 +1,135 @@
+package ntds
+
+import (
+	"encoding/hex"
+	"golang.org/x/crypto/md4"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Hashcat algorithm numbers for the two phases
+const (
+	AlgorithmLM = "3000"
+	AlgorithmNT = "1000"
+)
+
+// The half of an LM hash used when a password is seven characters or less
+const emptyLMHalf = "aad3b435b51404ee"
+
+// Find the NT hashes belonging to each real LM hash in a list of pwdump lines.
+// The unique LM hashes are returned along with the NT hashes of each one.
+func SplitLM(input string) (string, map[string][]string) {
+	var lms []string
+	nts := map[string][]string{}
+
+	for _, line := range strings.Split(input, "\n") {
+		m := hashLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		lm := strings.ToLower(m[3])
+		nt := strings.ToLower(m[4])
+		if lm == EmptyLM {
+			continue
+		}
+
+		if _, ok := nts[lm]; !ok {
+			lms = append(lms, lm)
+		}
+
+		found := false
+		for _, n := range nts[lm] {
+			if n == nt {
+				found = true
+			}
+		}
+		if !found {
+			nts[lm] = append(nts[lm], nt)
+		}
+	}
+
+	return strings.Join(lms, "\n"), nts
+}
+
+// Get the cracked LM passwords from tool output rows of plaintext and hash.
+// Tools may report each half of an LM hash on its own, so halves are joined
+// back together for every full hash given.
+func CrackedLM(rows [][]string, lms []string) map[string]string {
+	found := map[string]string{}
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		found[strings.ToLower(row[1])] = row[0]
+	}
+
+	out := map[string]string{}
+	for _, lm := range lms {
+		lm = strings.ToLower(lm)
+
+		if plain, ok := found[lm]; ok {
+			out[lm] = plain
+			continue
+		}
+
+		first, ok1 := lmHalf(found, lm[:16])
+		second, ok2 := lmHalf(found, lm[16:])
+		if ok1 && ok2 {
+			out[lm] = first + second
+		}
+	}
+
+	return out
+}
+
+func lmHalf(found map[string]string, half string) (string, bool) {
+	if half == emptyLMHalf {
+		return "", true
+	}
+
+	plain, ok := found[half]
+	return plain, ok
+}
+
+// Compute the NT hash of a password
+func NTHash(password string) string {
+	h := md4.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c), byte(c >> 8)})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Find the case of an LM password that matches the NT hash by trying every
+// combination of upper and lower case letters
+func ToggleCase(lmPassword, nt string) (string, bool) {
+	nt = strings.ToLower(nt)
+	runes := []rune(strings.ToLower(lmPassword))
+
+	var letters []int
+	for i, r := range runes {
+		if unicode.ToUpper(r) != r {
+			letters = append(letters, i)
+		}
+	}
+
+	// LM passwords are at most 14 characters so this is at most 16384 tries
+	for mask := 0; mask < 1<<uint(len(letters)); mask++ {
+		try := make([]rune, len(runes))
+		copy(try, runes)
+		for bit, i := range letters {
+			if mask&(1<<uint(bit)) != 0 {
+				try[i] = unicode.ToUpper(try[i])
+			}
+		}
+
+		if NTHash(string(try)) == nt {
+			return string(try), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,173 @@
+package ntds
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Account statuses reported by secretsdump -user-status
+const (
+	STATUS_ENABLED  = "Enabled"
+	STATUS_DISABLED = "Disabled"
+)
+
+// The LM hash stored when an account has no LM hash
+const EmptyLM = "aad3b435b51404eeaad3b435b51404ee"
+
+// A hash line from secretsdump looks like
+// DOMAIN\user:rid:lmhash:nthash::: (status=Enabled)
+var hashLine = regexp.MustCompile(`^([^:]+):(\d+):([0-9A-Fa-f]{32}):([0-9A-Fa-f]{32}):::\s*(?:\(status=(\w+)\))?`)
+
+// Password history entries have the history number added to the username
+var historySuffix = regexp.MustCompile(`_history(\d+)$`)
+
+// A single hash of an account parsed from an NTDS dump
+type Account struct {
+	Domain   string
+	User     string // Username without the domain or history suffix
+	RID      int64
+	LM       string
+	NT       string
+	Status   string // Empty when the dump did not include account status
+	History  int    // Password history number, -1 for the current password
+	Machine  bool   // Computer accounts end with a $
+	username string // Username as given in the dump
+}
+
+// Username as it appeared in the dump, including domain and history suffix
+func (a Account) Username() string {
+	return a.username
+}
+
+// Accounts are treated as enabled unless the dump says otherwise
+func (a Account) Enabled() bool {
+	return a.Status != STATUS_DISABLED
+}
+
+// The account has a real LM hash rather than the empty placeholder
+func (a Account) HasLM() bool {
+	return strings.ToLower(a.LM) != EmptyLM
+}
+
+// Parse the hash lines of secretsdump or other pwdump style output. Any other
+// lines, such as Kerberos keys, cleartext passwords and progress messages,
+// are skipped.
+func Parse(r io.Reader) ([]Account, error) {
+	var accounts []Account
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := hashLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+
+		rid, _ := strconv.ParseInt(m[2], 10, 64)
+		a := Account{
+			RID:      rid,
+			LM:       m[3],
+			NT:       m[4],
+			Status:   m[5],
+			History:  -1,
+			username: m[1],
+		}
+
+		a.User = m[1]
+		if i := strings.LastIndex(a.User, "\\"); i >= 0 {
+			a.Domain = a.User[:i]
+			a.User = a.User[i+1:]
+		}
+
+		if h := historySuffix.FindStringSubmatch(a.User); h != nil {
+			a.History, _ = strconv.Atoi(h[1])
+			a.User = strings.TrimSuffix(a.User, h[0])
+		}
+
+		a.Machine = strings.HasSuffix(a.User, "$")
+
+		accounts = append(accounts, a)
+	}
+
+	return accounts, scanner.Err()
+}
+
+// The subsets of a dump to include in a job. Current hashes of enabled user
+// accounts are always included.
+type Filter struct {
+	Disabled bool // Include disabled accounts
+	History  bool // Include password history hashes
+	Machine  bool // Include computer accounts
+}
+
+func (f Filter) Match(a Account) bool {
+	if !a.Enabled() && !f.Disabled {
+		return false
+	}
+	if a.History >= 0 && !f.History {
+		return false
+	}
+	if a.Machine && !f.Machine {
+		return false
+	}
+
+	return true
+}
+
+// Counts of each subset in a dump
+type Summary struct {
+	Accounts int // Current hashes
+	Enabled  int
+	Disabled int
+	History  int
+	Machine  int
+	LM       int // Hashes with a real LM hash
+	Selected int // Hashes matching the filter
+}
+
+func Summarize(accounts []Account, f Filter) Summary {
+	var s Summary
+
+	for _, a := range accounts {
+		if a.History >= 0 {
+			s.History++
+		} else {
+			s.Accounts++
+			if a.Enabled() {
+				s.Enabled++
+			} else {
+				s.Disabled++
+			}
+		}
+		if a.Machine {
+			s.Machine++
+		}
+		if a.HasLM() {
+			s.LM++
+		}
+		if f.Match(a) {
+			s.Selected++
+		}
+	}
+
+	return s
+}
+
+// Get the accounts matching the filter as pwdump lines, ready to be used as
+// the hashes of a job with usernames
+func Lines(accounts []Account, f Filter) string {
+	var lines []string
+
+	for _, a := range accounts {
+		if !f.Match(a) {
+			continue
+		}
+
+		lines = append(lines, a.username+":"+strconv.FormatInt(a.RID, 10)+":"+a.LM+":"+a.NT+":::")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,102 @@
+package ntds
+
+import (
+	"strings"
+	"testing"
+)
+
+const dump = `Impacket v0.9.22 - Copyright 2020 SecureAuth Corporation
+
+[*] Dumping Domain Credentials (domain\uid:rid:lmhash:nthash)
+[*] Using the DRSUAPI method to get NTDS.DIT secrets
+CORP.LOCAL\Administrator:500:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c::: (status=Enabled)
+CORP.LOCAL\Administrator_history0:500:aad3b435b51404eeaad3b435b51404ee:5f4dcc3b5aa765d61d8327deb882cf99:::
+Guest:501:aad3b435b51404eeaad3b435b51404ee:31d6cfe0d16ae931b73c59d7e0c089c0::: (status=Disabled)
+CORP.LOCAL\alice:1104:e52cac67419a9a224a3b108f3fa6cb6d:8846f7eaee8fb117ad06bdd830b7586c::: (status=Enabled)
+DC01$:1000:aad3b435b51404eeaad3b435b51404ee:b4b9b02e6f09a9bd760f388b67351e2b::: (status=Enabled)
+[*] Kerberos keys grabbed
+CORP.LOCAL\alice:aes256-cts-hmac-sha1-96:0f3d8a4c5d9b6e7a1c2b3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3f4a
+[*] Cleaning up...
+`
+
+func TestParse(t *testing.T) {
+	accounts, err := Parse(strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(accounts) != 5 {
+		t.Fatalf("Expected 5 hashes but got %d", len(accounts))
+	}
+
+	admin := accounts[0]
+	if admin.Domain != "CORP.LOCAL" || admin.User != "Administrator" || admin.RID != 500 || admin.History != -1 {
+		t.Errorf("Unexpected administrator account %+v", admin)
+	}
+
+	history := accounts[1]
+	if history.User != "Administrator" || history.History != 0 || history.Username() != "CORP.LOCAL\\Administrator_history0" {
+		t.Errorf("Unexpected history entry %+v", history)
+	}
+
+	if accounts[2].Enabled() || accounts[2].Domain != "" {
+		t.Errorf("Guest should be a disabled account without a domain: %+v", accounts[2])
+	}
+	if !accounts[3].HasLM() || accounts[0].HasLM() {
+		t.Error("LM hashes were not detected correctly")
+	}
+	if !accounts[4].Machine {
+		t.Error("DC01$ should be a machine account")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	accounts, _ := Parse(strings.NewReader(dump))
+
+	s := Summarize(accounts, Filter{})
+	want := Summary{Accounts: 4, Enabled: 3, Disabled: 1, History: 1, Machine: 1, LM: 1, Selected: 2}
+	if s != want {
+		t.Errorf("Expected summary %+v but got %+v", want, s)
+	}
+
+	lines := Lines(accounts, Filter{})
+	if lines != "CORP.LOCAL\\Administrator:500:aad3b435b51404eeaad3b435b51404ee:8846f7eaee8fb117ad06bdd830b7586c:::\n"+
+		"CORP.LOCAL\\alice:1104:e52cac67419a9a224a3b108f3fa6cb6d:8846f7eaee8fb117ad06bdd830b7586c:::" {
+		t.Errorf("Unexpected lines for the default filter:\n%s", lines)
+	}
+
+	all := Summarize(accounts, Filter{Disabled: true, History: true, Machine: true})
+	if all.Selected != 5 {
+		t.Errorf("Expected every hash to be selected but got %d", all.Selected)
+	}
+}
+
+func TestLMToNT(t *testing.T) {
+	if NTHash("password") != "8846f7eaee8fb117ad06bdd830b7586c" {
+		t.Fatalf("Unexpected NT hash %s", NTHash("password"))
+	}
+
+	lms, nts := SplitLM(dump)
+	if lms != "e52cac67419a9a224a3b108f3fa6cb6d" {
+		t.Errorf("Expected only the real LM hash but got %q", lms)
+	}
+	if len(nts["e52cac67419a9a224a3b108f3fa6cb6d"]) != 1 {
+		t.Errorf("Unexpected NT hashes %v", nts)
+	}
+
+	// Hashcat reports each half of an LM hash separately
+	rows := [][]string{{"PASSWOR", "e52cac67419a9a22"}, {"D", "4a3b108f3fa6cb6d"}}
+	cracked := CrackedLM(rows, []string{"E52CAC67419A9A224A3B108F3FA6CB6D"})
+	if cracked["e52cac67419a9a224a3b108f3fa6cb6d"] != "PASSWORD" {
+		t.Fatalf("LM halves were not joined: %v", cracked)
+	}
+
+	plain, ok := ToggleCase("PASSWORD", NTHash("PassWord"))
+	if !ok || plain != "PassWord" {
+		t.Errorf("Expected PassWord but got %q", plain)
+	}
+
+	if _, ok := ToggleCase("PASSWORD", NTHash("different")); ok {
+		t.Error("A different password should not match")
+	}
+}
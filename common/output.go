@@ -0,0 +1,254 @@
+package common
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Columns are dictionary encoded until this many rows show most of their
+// values are distinct, such as hashes, then each value is stored once
+const outputDictRows = 256
+
+/*
+ * Output rows of a job stored by column to keep large results small in memory.
+ * Columns whose values repeat, such as hash types or status, keep each
+ * distinct value once and a code for every row. Other columns keep their
+ * values in one buffer. Rows are only turned back into strings when they are
+ * read, and the table is written to JSON and gob as plain rows.
+ *
+ * Tables are only ever appended to. A view shares the storage of its table
+ * but does not see rows appended after it was taken, so views can be read
+ * without a lock while the table grows.
+ */
+type OutputTable struct {
+	cols   []outputColumn
+	widths []uint16 // Cells in each row, rows do not all have every column
+}
+
+type outputColumn struct {
+	plain bool
+
+	// Dictionary encoding
+	dict  []string
+	index map[string]uint32 // Rebuilt when a view is appended to
+	codes []uint32
+
+	// Plain encoding
+	data []byte
+	ends []uint32 // End of each value in data
+}
+
+func (c *outputColumn) len() int {
+	if c.plain {
+		return len(c.ends)
+	}
+	return len(c.codes)
+}
+
+func (c *outputColumn) value(i int) string {
+	if !c.plain {
+		return c.dict[c.codes[i]]
+	}
+
+	var start uint32
+	if i > 0 {
+		start = c.ends[i-1]
+	}
+	return string(c.data[start:c.ends[i]])
+}
+
+func (c *outputColumn) add(v string) {
+	if c.plain {
+		c.data = append(c.data, v...)
+		c.ends = append(c.ends, uint32(len(c.data)))
+		return
+	}
+
+	if c.index == nil {
+		c.index = make(map[string]uint32, len(c.dict))
+		for code, d := range c.dict {
+			c.index[d] = uint32(code)
+		}
+	}
+
+	code, ok := c.index[v]
+	if !ok {
+		code = uint32(len(c.dict))
+		c.dict = append(c.dict, v)
+		c.index[v] = code
+	}
+	c.codes = append(c.codes, code)
+
+	if len(c.codes) >= outputDictRows && len(c.dict)*2 > len(c.codes) {
+		c.toPlain()
+	}
+}
+
+// Store the values of a column once each, into new slices so views of the
+// dictionary are not changed
+func (c *outputColumn) toPlain() {
+	var size int
+	for _, code := range c.codes {
+		size += len(c.dict[code])
+	}
+
+	data := make([]byte, 0, size)
+	ends := make([]uint32, 0, len(c.codes))
+	for _, code := range c.codes {
+		data = append(data, c.dict[code]...)
+		ends = append(ends, uint32(len(data)))
+	}
+
+	*c = outputColumn{plain: true, data: data, ends: ends}
+}
+
+// Bytes of memory the column uses, not counting slice headers
+func (c *outputColumn) size() int {
+	if c.plain {
+		return cap(c.data) + 4*cap(c.ends)
+	}
+
+	n := 4 * cap(c.codes)
+	for _, d := range c.dict {
+		n += len(d) + 16
+	}
+	return n
+}
+
+// Build a table from rows of output
+func NewOutputTable(rows [][]string) *OutputTable {
+	t := &OutputTable{}
+	t.Append(rows)
+	return t
+}
+
+// The number of rows in the table, a nil table has none
+func (t *OutputTable) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.widths)
+}
+
+// Add rows to the end of the table
+func (t *OutputTable) Append(rows [][]string) {
+	for _, row := range rows {
+		// Columns first seen in this row are empty in the rows before it
+		for len(t.cols) < len(row) {
+			var c outputColumn
+			for range t.widths {
+				c.add("")
+			}
+			t.cols = append(t.cols, c)
+		}
+
+		for i := range t.cols {
+			v := ""
+			if i < len(row) {
+				v = row[i]
+			}
+			t.cols[i].add(v)
+		}
+		t.widths = append(t.widths, uint16(len(row)))
+	}
+}
+
+// Get a row of the table
+func (t *OutputTable) Row(i int) []string {
+	row := make([]string, t.widths[i])
+	for c := range row {
+		row[c] = t.cols[c].value(i)
+	}
+	return row
+}
+
+// Get every row of the table
+func (t *OutputTable) Rows() [][]string {
+	if t == nil || len(t.widths) == 0 {
+		return nil
+	}
+
+	rows := make([][]string, len(t.widths))
+	for i := range rows {
+		rows[i] = t.Row(i)
+	}
+	return rows
+}
+
+// Get a copy of the table holding the rows from i on
+func (t *OutputTable) From(i int) *OutputTable {
+	n := &OutputTable{}
+	for ; i < t.Len(); i++ {
+		n.Append([][]string{t.Row(i)})
+	}
+	return n
+}
+
+// Get a view of the rows in the table now. Appending to the view copies its
+// storage first so the table is not changed.
+func (t *OutputTable) View() *OutputTable {
+	if t == nil {
+		return nil
+	}
+
+	v := &OutputTable{
+		cols:   make([]outputColumn, len(t.cols)),
+		widths: t.widths[:len(t.widths):len(t.widths)],
+	}
+	for i, c := range t.cols {
+		v.cols[i] = outputColumn{
+			plain: c.plain,
+			dict:  c.dict[:len(c.dict):len(c.dict)],
+			codes: c.codes[:len(c.codes):len(c.codes)],
+			data:  c.data[:len(c.data):len(c.data)],
+			ends:  c.ends[:len(c.ends):len(c.ends)],
+		}
+	}
+	return v
+}
+
+// Bytes of memory the table uses
+func (t *OutputTable) Size() int {
+	if t == nil {
+		return 0
+	}
+
+	n := 2 * cap(t.widths)
+	for i := range t.cols {
+		n += t.cols[i].size()
+	}
+	return n
+}
+
+func (t *OutputTable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Rows())
+}
+
+func (t *OutputTable) UnmarshalJSON(b []byte) error {
+	var rows [][]string
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return err
+	}
+
+	*t = OutputTable{}
+	t.Append(rows)
+	return nil
+}
+
+func (t *OutputTable) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(t.Rows())
+	return buf.Bytes(), err
+}
+
+func (t *OutputTable) GobDecode(b []byte) error {
+	var rows [][]string
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&rows); err != nil {
+		return err
+	}
+
+	*t = OutputTable{}
+	t.Append(rows)
+	return nil
+}
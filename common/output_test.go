@@ -0,0 +1,116 @@
+package common
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func testRows(n int) [][]string {
+	rows := make([][]string, n)
+	for i := range rows {
+		rows[i] = []string{fmt.Sprintf("hash%d", i), fmt.Sprintf("pw%d", i), "NTLM"}
+	}
+	return rows
+}
+
+func TestOutputTableRows(t *testing.T) {
+	rows := testRows(1000)
+	rows = append(rows, []string{"short"}, []string{"a", "b", "c", "wide"})
+
+	table := NewOutputTable(rows)
+	if table.Len() != len(rows) {
+		t.Fatalf("Expected %d rows, got %d", len(rows), table.Len())
+	}
+	if !reflect.DeepEqual(table.Rows(), rows) {
+		t.Error("Expected the rows read back to match the rows added")
+	}
+
+	// Distinct columns are stored plain and repeated ones keep a dictionary
+	if !table.cols[0].plain || table.cols[2].plain {
+		t.Errorf("Expected only distinct columns to be plain, got %v %v", table.cols[0].plain, table.cols[2].plain)
+	}
+	if len(table.cols[2].dict) != 3 {
+		t.Errorf("Expected the repeated column to hold 3 distinct values, got %d", len(table.cols[2].dict))
+	}
+
+	if (*OutputTable)(nil).Len() != 0 || (*OutputTable)(nil).Rows() != nil {
+		t.Error("Expected a nil table to have no rows")
+	}
+}
+
+func TestOutputTableView(t *testing.T) {
+	table := NewOutputTable(testRows(10))
+	view := table.View()
+
+	table.Append(testRows(500))
+	if view.Len() != 10 {
+		t.Errorf("Expected the view to keep 10 rows, got %d", view.Len())
+	}
+
+	// Appending to a view must not change the rows of the table
+	view.Append([][]string{{"other", "other", "other"}})
+	if got := table.Row(10); got[0] != "hash0" {
+		t.Errorf("Expected the table to be unchanged by the view, got %v", got)
+	}
+	if got := view.Row(10); got[0] != "other" {
+		t.Errorf("Expected the row appended to the view, got %v", got)
+	}
+
+	from := table.From(505)
+	if !reflect.DeepEqual(from.Rows(), testRows(500)[495:]) {
+		t.Errorf("Expected the last rows of the table, got %v", from.Rows())
+	}
+}
+
+func TestOutputTableEncoding(t *testing.T) {
+	j := Job{Output: NewOutputTable(testRows(300))}
+
+	b, err := json.Marshal(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Job
+	if err := json.Unmarshal(b, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON.OutputRows(), testRows(300)) {
+		t.Error("Expected the output to survive JSON")
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(j); err != nil {
+		t.Fatal(err)
+	}
+	var fromGob Job
+	if err := gob.NewDecoder(&buf).Decode(&fromGob); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromGob.OutputRows(), testRows(300)) {
+		t.Error("Expected the output to survive gob")
+	}
+}
+
+func TestJobCompactOutput(t *testing.T) {
+	j := Job{OutputData: testRows(5)}
+	j.CompactOutput()
+	j.OutputData = testRows(8)[5:]
+
+	if j.OutputLen() != 8 || !reflect.DeepEqual(j.OutputRows(), testRows(8)) {
+		t.Errorf("Expected the table and newer rows in order, got %v", j.OutputRows())
+	}
+
+	c := j.Clone()
+	j.CompactOutput()
+	j.Output.Append(testRows(1))
+	if c.OutputLen() != 8 {
+		t.Errorf("Expected the clone to keep its rows, got %d", c.OutputLen())
+	}
+
+	if r := j.ForResource(); r.OutputLen() != 0 {
+		t.Errorf("Expected no output to be sent to resources, got %d rows", r.OutputLen())
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Performance samples older than After are averaged into buckets of Interval
+type PerformanceTier struct {
+	After    time.Duration
+	Interval time.Duration
+}
+
+// Per minute averages after an hour and per hour averages after a day
+var DefaultPerformanceTiers = []PerformanceTier{
+	{After: time.Hour, Interval: time.Minute},
+	{After: 24 * time.Hour, Interval: time.Hour},
+}
+
+type perfBucket struct {
+	sum   float64
+	count int
+}
+
+// Average the performance data of a job, which is keyed by unix timestamp, into
+// the bucket of the tier matching the age of each sample. Samples newer than
+// every tier and samples that are not numbers are kept as is. Each bucket is
+// keyed by the time it starts.
+func DownsamplePerformance(data map[string]string, now time.Time, tiers []PerformanceTier) map[string]string {
+	if len(tiers) == 0 || len(data) == 0 {
+		return data
+	}
+
+	out := make(map[string]string, len(data))
+	buckets := map[int64]*perfBucket{}
+
+	for k, v := range data {
+		ts, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			out[k] = v
+			continue
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			out[k] = v
+			continue
+		}
+
+		// The oldest tier the sample has reached decides its interval
+		age := now.Sub(time.Unix(ts, 0))
+		var interval int64
+		for _, t := range tiers {
+			if age >= t.After && t.Interval >= time.Second {
+				interval = int64(t.Interval / time.Second)
+			}
+		}
+		if interval == 0 {
+			out[k] = v
+			continue
+		}
+
+		start := ts - ts%interval
+		b, ok := buckets[start]
+		if !ok {
+			b = &perfBucket{}
+			buckets[start] = b
+		}
+		b.sum += value
+		b.count++
+	}
+
+	for start, b := range buckets {
+		out[strconv.FormatInt(start, 10)] = fmt.Sprintf("%f", b.sum/float64(b.count))
+	}
+
+	return out
+}
+
+// Average all of the performance data of a job into buckets of the interval
+func ResamplePerformance(data map[string]string, interval time.Duration) map[string]string {
+	return DownsamplePerformance(data, time.Now(), []PerformanceTier{{After: 0, Interval: interval}})
+}
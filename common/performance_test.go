@@ -0,0 +1,53 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownsamplePerformance(t *testing.T) {
+	now := time.Unix(200000, 0)
+
+	data := map[string]string{
+		"199990": "7",     // Newer than every tier
+		"196210": "10",    // Over an hour old, averaged per minute
+		"196230": "20",    //
+		"196290": "30",    // The next minute
+		"100000": "1",     // Over a day old, averaged per hour
+		"100500": "3",     //
+		"notime": "5",     // Kept as is
+		"199995": "error", // Kept as is
+	}
+
+	out := DownsamplePerformance(data, now, DefaultPerformanceTiers)
+
+	want := map[string]string{
+		"199990": "7",
+		"196200": "15.000000",
+		"196260": "30.000000",
+		"97200":  "2.000000",
+		"notime": "5",
+		"199995": "error",
+	}
+	if len(out) != len(want) {
+		t.Fatalf("Expected %d points but got %v", len(want), out)
+	}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("Point %s: expected %s but got %s", k, v, out[k])
+		}
+	}
+
+	// Downsampled data stays the same when it is downsampled again
+	again := DownsamplePerformance(out, now, DefaultPerformanceTiers)
+	for k, v := range want {
+		if again[k] != v {
+			t.Errorf("Point %s changed to %s when downsampled again", k, again[k])
+		}
+	}
+
+	res := ResamplePerformance(map[string]string{"100": "1", "150": "3", "200": "5"}, 100*time.Second)
+	if len(res) != 2 || res["100"] != "2.000000" || res["200"] != "5.000000" {
+		t.Errorf("Unexpected resampled points %v", res)
+	}
+}
@@ -0,0 +1,241 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Encrypt data for the key as an ASCII armored OpenPGP message. The data is
+// encrypted with a random AES-256 session key and integrity protected, only
+// the holder of the private key can decrypt it.
+func (k *PublicKey) Encrypt(plaintext []byte) (string, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, sessionKey); err != nil {
+		return "", err
+	}
+
+	pkesk, err := k.encryptSessionKey(sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	seipd, err := encryptData(sessionKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	var msg bytes.Buffer
+	writePacket(&msg, tagPKESK, pkesk)
+	writePacket(&msg, tagSEIPD, seipd)
+
+	return Armor(msg.Bytes(), ARMOR_MESSAGE), nil
+}
+
+// The body of the packet with the session key encrypted for the key
+func (k *PublicKey) encryptSessionKey(sessionKey []byte) ([]byte, error) {
+	// The cipher, then the key and its checksum
+	m := append([]byte{cipherAES256}, sessionKey...)
+	var sum uint16
+	for _, b := range sessionKey {
+		sum += uint16(b)
+	}
+	m = append(m, byte(sum>>8), byte(sum))
+
+	var body bytes.Buffer
+	body.WriteByte(3)
+	binary.Write(&body, binary.BigEndian, k.KeyID)
+	body.WriteByte(k.algo)
+
+	switch {
+	case k.rsa != nil:
+		c, err := rsa.EncryptPKCS1v15(rand.Reader, k.rsa, m)
+		if err != nil {
+			return nil, err
+		}
+		writeMPI(&body, c)
+
+	case k.x25519 != nil:
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := ephemeral.ECDH(k.x25519)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped, err := keyWrap(k.kek(shared), pkcs5Pad(m, 8))
+		if err != nil {
+			return nil, err
+		}
+
+		writeMPI(&body, append([]byte{0x40}, ephemeral.PublicKey().Bytes()...))
+		body.WriteByte(byte(len(wrapped)))
+		body.Write(wrapped)
+
+	default:
+		return nil, ErrNoEncryptionKey
+	}
+
+	return body.Bytes(), nil
+}
+
+// Derive the key encryption key from the ECDH shared secret as in RFC 6637
+func (k *PublicKey) kek(shared []byte) []byte {
+	h := newHash(k.kdfHash)
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(shared)
+
+	h.Write([]byte{byte(len(oidCurve25519))})
+	h.Write(oidCurve25519)
+	h.Write([]byte{algoECDH, 3, 1, k.kdfHash, k.kdfCipher})
+	h.Write([]byte("Anonymous Sender    "))
+	h.Write(k.Fingerprint[:])
+
+	return h.Sum(nil)[:kekSize(k.kdfCipher)]
+}
+
+// The body of the integrity protected packet with a literal data packet of
+// the plaintext encrypted with the session key
+func encryptData(sessionKey, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// A random block with its last two bytes repeated comes first
+	prefix := make([]byte, block.BlockSize()+2)
+	if _, err := io.ReadFull(rand.Reader, prefix[:block.BlockSize()]); err != nil {
+		return nil, err
+	}
+	copy(prefix[block.BlockSize():], prefix[block.BlockSize()-2:block.BlockSize()])
+
+	// Binary data without a file name or date
+	var literal bytes.Buffer
+	literal.Write([]byte{'b', 0, 0, 0, 0, 0})
+	literal.Write(plaintext)
+
+	var data bytes.Buffer
+	data.Write(prefix)
+	writePacket(&data, tagLiteral, literal.Bytes())
+
+	// The modification detection code covers everything before it and its
+	// own header
+	data.Write([]byte{0xC0 | tagMDC, sha1.Size})
+	mdc := sha1.Sum(data.Bytes())
+	data.Write(mdc[:])
+
+	out := make([]byte, 1+data.Len())
+	out[0] = 1
+	cipher.NewCFBEncrypter(block, make([]byte, block.BlockSize())).XORKeyStream(out[1:], data.Bytes())
+
+	return out, nil
+}
+
+// Write a packet with a new format header
+func writePacket(w *bytes.Buffer, tag byte, body []byte) {
+	w.WriteByte(0xC0 | tag)
+
+	switch n := len(body); {
+	case n < 192:
+		w.WriteByte(byte(n))
+	case n < 8384:
+		n -= 192
+		w.WriteByte(byte(n>>8) + 192)
+		w.WriteByte(byte(n))
+	default:
+		w.WriteByte(255)
+		binary.Write(w, binary.BigEndian, uint32(n))
+	}
+
+	w.Write(body)
+}
+
+// Write a multiprecision integer
+func writeMPI(w *bytes.Buffer, v []byte) {
+	v = bytes.TrimLeft(v, "\x00")
+
+	bits := len(v) * 8
+	if len(v) > 0 {
+		for b := v[0]; b&0x80 == 0; b <<= 1 {
+			bits--
+		}
+	}
+
+	w.WriteByte(byte(bits >> 8))
+	w.WriteByte(byte(bits))
+	w.Write(v)
+}
+
+func pkcs5Pad(data []byte, size int) []byte {
+	n := size - len(data)%size
+	return append(data, bytes.Repeat([]byte{byte(n)}, n)...)
+}
+
+// Wrap a key with AES as in RFC 3394
+func keyWrap(kek, key []byte) ([]byte, error) {
+	if len(key)%8 != 0 {
+		return nil, errors.New("The key to wrap must be a multiple of 8 bytes.")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(key) / 8
+	a := []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+	r := make([]byte, len(key))
+	copy(r, key)
+
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(b, a)
+			copy(b[8:], r[i*8:i*8+8])
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r[i*8:], b[8:])
+		}
+	}
+
+	return append(a, r...), nil
+}
+
+// Bytes of the key encryption key for an ECDH cipher, 0 when not supported
+func kekSize(id byte) int {
+	switch id {
+	case cipherAES128:
+		return 16
+	case cipherAES192:
+		return 24
+	case cipherAES256:
+		return 32
+	}
+	return 0
+}
+
+// The hash of an ECDH key derivation function, nil when not supported
+func newHash(id byte) hash.Hash {
+	switch id {
+	case hashSHA256:
+		return sha256.New()
+	case hashSHA384:
+		return sha512.New384()
+	case hashSHA512:
+		return sha512.New()
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Armor headers of the public keys read and the messages written
+const (
+	ARMOR_PUBLIC_KEY = "PGP PUBLIC KEY BLOCK"
+	ARMOR_MESSAGE    = "PGP MESSAGE"
+)
+
+// Packet tags
+const (
+	tagPKESK     = 1  // Public-key encrypted session key
+	tagPublicKey = 6  // Primary public key
+	tagLiteral   = 11 // Literal data
+	tagSubkey    = 14 // Public subkey
+	tagSEIPD     = 18 // Symmetrically encrypted and integrity protected data
+	tagMDC       = 19 // Modification detection code
+)
+
+// Public key algorithms
+const (
+	algoRSA        = 1
+	algoRSAEncrypt = 2
+	algoECDH       = 18
+)
+
+// Symmetric ciphers and hashes used by ECDH keys
+const (
+	cipherAES128 = 7
+	cipherAES192 = 8
+	cipherAES256 = 9
+
+	hashSHA256 = 8
+	hashSHA384 = 9
+	hashSHA512 = 10
+)
+
+// OID of Curve25519 for ECDH, the curve GnuPG creates encryption subkeys on
+var oidCurve25519 = []byte{0x2B, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+
+var (
+	ErrNoArmor         = errors.New("The key is not an ASCII armored OpenPGP public key.")
+	ErrBadChecksum     = errors.New("The checksum of the armored data does not match.")
+	ErrNoEncryptionKey = errors.New("The OpenPGP key has no RSA or Curve25519 key that can encrypt.")
+	ErrMalformed       = errors.New("The OpenPGP key is malformed.")
+)
+
+// The key of an OpenPGP certificate that messages are encrypted for
+type PublicKey struct {
+	KeyID       uint64   // Last 8 bytes of the fingerprint
+	Fingerprint [20]byte // V4 fingerprint of the encryption key
+
+	algo      byte
+	rsa       *rsa.PublicKey
+	x25519    *ecdh.PublicKey
+	kdfHash   byte
+	kdfCipher byte
+}
+
+// Read the key messages are encrypted for from an ASCII armored OpenPGP
+// public key. The first subkey that can encrypt is used, or the primary key
+// when it is an RSA key and there is no such subkey.
+func ReadArmoredKey(armored string) (*PublicKey, error) {
+	data, err := Unarmor(armored, ARMOR_PUBLIC_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary *PublicKey
+	for len(data) > 0 {
+		tag, body, rest, err := readPacket(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+
+		if tag != tagPublicKey && tag != tagSubkey {
+			continue
+		}
+
+		key, err := parsePublicKey(body)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			continue
+		}
+		if tag == tagSubkey {
+			return key, nil
+		}
+		if primary == nil {
+			primary = key
+		}
+	}
+
+	if primary == nil || primary.rsa == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	return primary, nil
+}
+
+// The fingerprint as GnuPG shows it
+func (k *PublicKey) FingerprintString() string {
+	return strings.ToUpper(hex.EncodeToString(k.Fingerprint[:]))
+}
+
+// Parse the body of a public key packet, nil when it is a version or algorithm
+// that cannot be encrypted for
+func parsePublicKey(body []byte) (*PublicKey, error) {
+	if len(body) < 6 {
+		return nil, ErrMalformed
+	}
+	if body[0] != 4 {
+		return nil, nil
+	}
+
+	key := &PublicKey{algo: body[5]}
+
+	h := sha1.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	copy(key.Fingerprint[:], h.Sum(nil))
+	key.KeyID = binary.BigEndian.Uint64(key.Fingerprint[12:])
+
+	fields := body[6:]
+	switch key.algo {
+	case algoRSA, algoRSAEncrypt:
+		n, fields, err := readMPI(fields)
+		if err != nil {
+			return nil, err
+		}
+		e, _, err := readMPI(fields)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+			return nil, ErrMalformed
+		}
+		key.rsa = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+
+	case algoECDH:
+		if len(fields) < 1 || len(fields) < 1+int(fields[0]) {
+			return nil, ErrMalformed
+		}
+		oid := fields[1 : 1+int(fields[0])]
+		if !bytes.Equal(oid, oidCurve25519) {
+			return nil, nil
+		}
+
+		point, fields, err := readMPI(fields[1+len(oid):])
+		if err != nil {
+			return nil, err
+		}
+		if len(point) != 33 || point[0] != 0x40 {
+			return nil, ErrMalformed
+		}
+		key.x25519, err = ecdh.X25519().NewPublicKey(point[1:])
+		if err != nil {
+			return nil, ErrMalformed
+		}
+
+		// KDF parameters are the length, a reserved 1, the hash and the cipher
+		if len(fields) < 4 || fields[0] != 3 {
+			return nil, ErrMalformed
+		}
+		key.kdfHash, key.kdfCipher = fields[2], fields[3]
+		if kekSize(key.kdfCipher) == 0 || newHash(key.kdfHash) == nil {
+			return nil, nil
+		}
+
+	default:
+		return nil, nil
+	}
+
+	return key, nil
+}
+
+// Read the tag and body of the packet at the start of the data
+func readPacket(data []byte) (tag byte, body, rest []byte, err error) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return 0, nil, nil, ErrMalformed
+	}
+
+	var length, header int
+	if data[0]&0x40 != 0 {
+		// New format lengths, partial lengths are only used for data
+		tag = data[0] & 0x3F
+		switch o := int(data[1]); {
+		case o < 192:
+			length, header = o, 2
+		case o < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, ErrMalformed
+			}
+			length, header = (o-192)<<8+int(data[2])+192, 3
+		case o == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, ErrMalformed
+			}
+			length, header = int(binary.BigEndian.Uint32(data[2:6])), 6
+		default:
+			return 0, nil, nil, ErrMalformed
+		}
+	} else {
+		tag = (data[0] >> 2) & 0x0F
+		switch data[0] & 0x03 {
+		case 0:
+			length, header = int(data[1]), 2
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, ErrMalformed
+			}
+			length, header = int(binary.BigEndian.Uint16(data[1:3])), 3
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, ErrMalformed
+			}
+			length, header = int(binary.BigEndian.Uint32(data[1:5])), 5
+		default:
+			length, header = len(data)-1, 1
+		}
+	}
+
+	if length < 0 || len(data)-header < length {
+		return 0, nil, nil, ErrMalformed
+	}
+
+	return tag, data[header : header+length], data[header+length:], nil
+}
+
+// Read a multiprecision integer, returning its bytes and what follows it
+func readMPI(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, ErrMalformed
+	}
+	n := (int(binary.BigEndian.Uint16(data)) + 7) / 8
+	if len(data)-2 < n {
+		return nil, nil, ErrMalformed
+	}
+	return data[2 : 2+n], data[2+n:], nil
+}
+
+// Decode the ASCII armored data of the kind given, ignoring any text around it
+func Unarmor(armored, kind string) ([]byte, error) {
+	begin := "-----BEGIN " + kind + "-----"
+	end := "-----END " + kind + "-----"
+
+	start := strings.Index(armored, begin)
+	if start < 0 {
+		return nil, ErrNoArmor
+	}
+	lines := strings.Split(armored[start+len(begin):], "\n")
+
+	// Armor headers come first and end with a blank line
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if !strings.Contains(line, ": ") {
+			break
+		}
+	}
+
+	var body strings.Builder
+	var checksum string
+	found := false
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == end {
+			found = true
+			break
+		}
+		if strings.HasPrefix(line, "=") {
+			checksum = line[1:]
+			continue
+		}
+		body.WriteString(line)
+	}
+	if !found {
+		return nil, ErrNoArmor
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.String())
+	if err != nil {
+		return nil, ErrNoArmor
+	}
+
+	// The checksum is optional
+	if checksum != "" {
+		sum, err := base64.StdEncoding.DecodeString(checksum)
+		if err != nil || len(sum) != 3 {
+			return nil, ErrBadChecksum
+		}
+		crc := crc24(data)
+		if sum[0] != byte(crc>>16) || sum[1] != byte(crc>>8) || sum[2] != byte(crc) {
+			return nil, ErrBadChecksum
+		}
+	}
+
+	return data, nil
+}
+
+// ASCII armor data as the kind given
+func Armor(data []byte, kind string) string {
+	var b strings.Builder
+
+	b.WriteString("-----BEGIN " + kind + "-----\n\n")
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 64 {
+		b.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	if encoded != "" {
+		b.WriteString(encoded + "\n")
+	}
+
+	crc := crc24(data)
+	b.WriteString("=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) + "\n")
+	b.WriteString("-----END " + kind + "-----\n")
+
+	return b.String()
+}
+
+// Check if a value is an ASCII armored OpenPGP message
+func IsArmoredMessage(v string) bool {
+	return strings.HasPrefix(v, "-----BEGIN "+ARMOR_MESSAGE+"-----")
+}
+
+// The CRC-24 of armored data from RFC 4880
+func crc24(data []byte) uint32 {
+	crc := uint32(0xB704CE)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
@@ -0,0 +1,92 @@
+package pgp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyWrap(t *testing.T) {
+	// Test vector 4.6 of RFC 3394
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	want := "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"
+
+	wrapped, err := keyWrap(kek, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.ToUpper(hex.EncodeToString(wrapped)); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestArmor(t *testing.T) {
+	data := []byte("a shared secret")
+
+	armored := Armor(data, ARMOR_MESSAGE)
+	if !IsArmoredMessage(armored) {
+		t.Fatalf("Expected an armored message, got %s", armored)
+	}
+
+	got, err := Unarmor("text before\n"+armored, ARMOR_MESSAGE)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Expected the data back, got %q %v", got, err)
+	}
+
+	broken := strings.Replace(armored, "YSBzaGFyZWQgc2VjcmV0", "YSBzaGFyZWQgc2VjcmV1", 1)
+	if _, err := Unarmor(broken, ARMOR_MESSAGE); err != ErrBadChecksum {
+		t.Errorf("Expected a checksum error, got %v", err)
+	}
+
+	if _, err := ReadArmoredKey("not a key"); err != ErrNoArmor {
+		t.Errorf("Expected an armor error, got %v", err)
+	}
+}
+
+// Messages must decrypt with GnuPG for keys it creates
+func TestEncryptGnuPG(t *testing.T) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	for _, algo := range []string{"future-default", "rsa2048"} {
+		home := t.TempDir()
+		run := func(stdin string, args ...string) string {
+			cmd := exec.Command(gpg, append([]string{"--homedir", home, "--batch", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...)
+			cmd.Stdin = strings.NewReader(stdin)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("gpg %v failed for %s: %v", args, algo, err)
+			}
+			return string(out)
+		}
+
+		run("", "--quick-gen-key", "results@example.com", algo, "default", "never")
+		armored := run("", "--armor", "--export", "results@example.com")
+
+		key, err := ReadArmoredKey(armored)
+		if err != nil {
+			t.Fatalf("Unable to read the %s key: %v", algo, err)
+		}
+		if !strings.Contains(run("", "--with-colons", "--with-subkey-fingerprint", "--list-keys"), key.FingerprintString()) {
+			t.Errorf("GnuPG does not list the fingerprint %s of the %s key", key.FingerprintString(), algo)
+		}
+
+		msg, err := key.Encrypt([]byte("5f4dcc3b5aa765d61d8327deb882cf99,password"))
+		if err != nil {
+			t.Fatalf("Unable to encrypt for the %s key: %v", algo, err)
+		}
+
+		path := filepath.Join(home, "msg.asc")
+		os.WriteFile(path, []byte(msg), 0600)
+		if got := run("", "--decrypt", path); got != "5f4dcc3b5aa765d61d8327deb882cf99,password" {
+			t.Errorf("Expected GnuPG to decrypt the message for the %s key, got %q", algo, got)
+		}
+	}
+}
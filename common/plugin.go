@@ -22,3 +22,82 @@ type Tooler interface {
 	Requirements() string
 	NewTask(Job) (Tasker, error)
 }
+
+// Toolers can also implement Previewer to show the candidates a job with the
+// given parameters would generate from a small list of sample words, without
+// having to submit the job.
+type Previewer interface {
+	Preview(params map[string]string, words []string) ([]string, error)
+}
+
+// Toolers can implement Estimator to calculate the keyspace of a job and how long
+// it would take to run on the resource before it is submitted.
+type Estimator interface {
+	Estimate(params map[string]string) (Estimate, error)
+}
+
+// Toolers can implement Requirer to declare the memory a job with the given
+// parameters needs, so it is only scheduled on resources that have enough.
+type Requirer interface {
+	JobRequirements(params map[string]string) (Constraints, error)
+}
+
+// Toolers can implement InputChecker to find mistakes in the parameters of a
+// job, such as hashes that do not match the algorithm, before it is
+// dispatched.
+type InputChecker interface {
+	CheckInput(params map[string]string) []InputIssue
+}
+
+// Taskers can implement Checkpointer so the queue can save where a long task is
+// and continue it from that point on another resource after a failure.
+type Checkpointer interface {
+	Checkpoint() (Checkpoint, error)
+	Restore(Checkpoint) error
+}
+
+// Toolers can implement Throttler to limit the workload of the tasks they start
+// while a profile is applied to the resource, 0 lifts the limit. Tasks already
+// running are left as they are.
+type Throttler interface {
+	SetWorkload(level int)
+}
+
+// Toolers can implement Rescanner to load their binaries, versions, wordlists
+// and rules again when asked, so an upgrade does not need a restart of the
+// resource.
+type Rescanner interface {
+	Rescan() error
+}
+
+// Toolers can implement BinaryUser to run a binary installed from the
+// repository of the queue instead of the one in their config file. Tasks
+// already running keep the binary they were started with.
+type BinaryUser interface {
+	BinaryName() string
+	SetBinary(path, version string) error
+}
+
+// Toolers can implement FormMigrator when their form changes in a way that
+// breaks the parameters of jobs made with an older one, such as a renamed
+// field. FormVersion is the version of the form Parameters returns and
+// FormMigrations the steps from each older version to the next. The queue
+// uses them to translate the parameters of templates and cloned jobs, so they
+// are data rather than code.
+type FormMigrator interface {
+	FormVersion() int
+	FormMigrations() []FormMigration
+}
+
+// Taskers can implement ResultFiler to give the path of the file the tool
+// writes its results to, so it can be downloaded as it is without the queue
+// holding all of it in memory.
+type ResultFiler interface {
+	ResultFile() string
+}
+
+// Taskers can implement OutputLogger to provide the recent output of the tool
+// so failures can be debugged remotely.
+type OutputLogger interface {
+	Logs(lines int) []string
+}
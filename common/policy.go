@@ -0,0 +1,157 @@
+package common
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Reasons a password fails a policy
+const (
+	POLICY_LENGTH     = "length"
+	POLICY_COMPLEXITY = "complexity"
+	POLICY_BANNED     = "banned"
+	POLICY_BREACHED   = "breached"
+)
+
+// A password policy, such as the one a client requires of its users. Zero
+// values do not check that part of the policy.
+type PasswordPolicy struct {
+	MinLength   int
+	MinClasses  int             // Character classes needed of lower, upper, digits and symbols
+	BannedWords []string        // Words passwords may not contain, such as the company name or seasons
+	Breached    map[string]bool // Passwords known from breaches
+}
+
+// A cracked account that fails the policy
+type PolicyAccount struct {
+	Account    string
+	Violations []string
+}
+
+// How the cracked passwords of a job hold up against a policy. Plaintexts are
+// left out so the report can be dropped into a client report as it is.
+type PolicyReport struct {
+	ByUser       bool           // Accounts are users rather than hashes
+	Accounts     int            // Accounts in the hash list of the job
+	Cracked      int            // Accounts whose password was cracked
+	Compliant    int            // Cracked accounts that meet the policy
+	NonCompliant int            // Cracked accounts that fail it
+	Violations   map[string]int // Cracked accounts failing each part of the policy
+	Lengths      map[int]int    // Cracked passwords of each length
+	Failures     []PolicyAccount
+}
+
+// Count the character classes of lower case, upper case, digits and symbols a
+// password uses
+func passwordClasses(pw string) int {
+	var lower, upper, digit, symbol int
+	for _, c := range pw {
+		switch {
+		case unicode.IsLower(c):
+			lower = 1
+		case unicode.IsUpper(c):
+			upper = 1
+		case unicode.IsDigit(c):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// Lower case a password and undo common substitutions so Pa$$w0rd is found
+// to contain password
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+func normalizeWord(s string) string {
+	return leetReplacer.Replace(strings.ToLower(s))
+}
+
+// Get the parts of the policy a password fails, none when it meets it
+func (p PasswordPolicy) Check(pw string) []string {
+	var out []string
+
+	if p.MinLength > 0 && len([]rune(pw)) < p.MinLength {
+		out = append(out, POLICY_LENGTH)
+	}
+	if p.MinClasses > 0 && passwordClasses(pw) < p.MinClasses {
+		out = append(out, POLICY_COMPLEXITY)
+	}
+
+	norm := normalizeWord(pw)
+	for _, w := range p.BannedWords {
+		if w = normalizeWord(strings.TrimSpace(w)); w != "" && strings.Contains(norm, w) {
+			out = append(out, POLICY_BANNED)
+			break
+		}
+	}
+
+	if p.Breached[pw] {
+		out = append(out, POLICY_BREACHED)
+	}
+
+	return out
+}
+
+// Check every password a job cracked against a policy. When the job was
+// submitted with usernames each user is an account, otherwise each hash is.
+func (p PasswordPolicy) Report(j Job) PolicyReport {
+	r := PolicyReport{
+		Violations: map[string]int{},
+		Lengths:    map[int]int{},
+		Failures:   []PolicyAccount{},
+	}
+
+	cracked := j.Plaintexts()
+
+	check := func(account, pw string) {
+		r.Cracked++
+		r.Lengths[len([]rune(pw))]++
+
+		v := p.Check(pw)
+		if len(v) == 0 {
+			r.Compliant++
+			return
+		}
+
+		r.NonCompliant++
+		for _, reason := range v {
+			r.Violations[reason]++
+		}
+		r.Failures = append(r.Failures, PolicyAccount{Account: account, Violations: v})
+	}
+
+	if len(j.Usernames) > 0 {
+		r.ByUser = true
+		for hash, users := range j.Usernames {
+			names := users
+			if len(names) == 0 {
+				// A bare hash in a list of users is still an account
+				names = []string{hash}
+			}
+			r.Accounts += len(names)
+
+			pw, ok := cracked[hash]
+			if !ok {
+				continue
+			}
+			for _, u := range names {
+				check(u, pw)
+			}
+		}
+	} else {
+		r.Accounts = int(j.TotalHashes)
+		for hash, pw := range cracked {
+			check(hash, pw)
+		}
+		if r.Accounts < r.Cracked {
+			r.Accounts = r.Cracked
+		}
+	}
+
+	sort.Slice(r.Failures, func(a, b int) bool { return r.Failures[a].Account < r.Failures[b].Account })
+
+	return r
+}
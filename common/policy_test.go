@@ -0,0 +1,57 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := PasswordPolicy{
+		MinLength:   10,
+		MinClasses:  3,
+		BannedWords: []string{"password", "acme"},
+		Breached:    map[string]bool{"Tr0ub4dor&3x": true},
+	}
+
+	cases := []struct {
+		pw       string
+		expected []string
+	}{
+		{"correct-Horse-7battery", nil},
+		{"Sh0rt!", []string{POLICY_LENGTH}},
+		{"alllowercaseletters", []string{POLICY_COMPLEXITY}},
+		{"Pa$$w0rd2024!", []string{POLICY_BANNED}},
+		{"Welcome@ACME1", []string{POLICY_BANNED}},
+		{"Tr0ub4dor&3x", []string{POLICY_BREACHED}},
+		{"acme", []string{POLICY_LENGTH, POLICY_COMPLEXITY, POLICY_BANNED}},
+	}
+
+	for _, c := range cases {
+		if got := p.Check(c.pw); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Expected %v for %s, got %v", c.expected, c.pw, got)
+		}
+	}
+}
+
+func TestPasswordPolicyReport(t *testing.T) {
+	j := Job{
+		OutputTitles: []string{"Plaintext", "Hash"},
+		OutputData:   [][]string{{"Summer2024", "aaaa"}, {"L0ng-Enough-Passphrase", "bbbb"}},
+		Usernames: map[string][]string{
+			"aaaa": {"alice", "bob"},
+			"bbbb": {"carol"},
+			"cccc": {"dave"},
+		},
+	}
+
+	r := PasswordPolicy{MinLength: 12}.Report(j)
+	if !r.ByUser || r.Accounts != 4 || r.Cracked != 3 || r.Compliant != 1 || r.NonCompliant != 2 {
+		t.Errorf("Unexpected report totals %+v", r)
+	}
+	if r.Violations[POLICY_LENGTH] != 2 {
+		t.Errorf("Expected 2 accounts too short, got %d", r.Violations[POLICY_LENGTH])
+	}
+	if len(r.Failures) != 2 || r.Failures[0].Account != "alice" || r.Failures[1].Account != "bob" {
+		t.Errorf("Expected alice and bob to fail the policy, got %v", r.Failures)
+	}
+}
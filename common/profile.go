@@ -0,0 +1,72 @@
+package common
+
+import (
+	"errors"
+	"time"
+)
+
+// The workload a resource is allowed during part of the week, such as a light
+// load during business hours and everything it has at night
+type Profile struct {
+	Name     string
+	Days     []time.Weekday // Days the profile starts on, every day when empty
+	Start    time.Duration  // Time after midnight the profile starts
+	End      time.Duration  // Time after midnight it ends, before Start when it runs past midnight
+	Workload int            // Highest workload level tools run at, such as hashcat -w, 0 does not limit it
+	MaxTasks int            // Jobs the resource runs at once, 0 does not limit them
+}
+
+func (p Profile) Validate() error {
+	if p.Name == "" {
+		return errors.New("Profiles must have a name.")
+	}
+	if p.Start < 0 || p.Start >= 24*time.Hour || p.End < 0 || p.End > 24*time.Hour {
+		return errors.New("Profile times must be within a day.")
+	}
+	if p.Workload < 0 || p.MaxTasks < 0 {
+		return errors.New("Profile limits cannot be negative.")
+	}
+	return nil
+}
+
+func (p Profile) onDay(d time.Weekday) bool {
+	if len(p.Days) == 0 {
+		return true
+	}
+	for _, day := range p.Days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the profile applies at a time. A profile with the same start and
+// end lasts all day and one ending before it starts runs into the next day.
+func (p Profile) Active(t time.Time) bool {
+	y, m, d := t.Date()
+	since := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+
+	switch {
+	case p.Start == p.End:
+		return p.onDay(t.Weekday())
+	case p.Start < p.End:
+		return p.onDay(t.Weekday()) && since >= p.Start && since < p.End
+	default:
+		// Past midnight the profile started the day before
+		if since >= p.Start {
+			return p.onDay(t.Weekday())
+		}
+		return since < p.End && p.onDay((t.Weekday()+6)%7)
+	}
+}
+
+// Get the first of the profiles that applies at a time, nil when none do
+func ActiveProfile(profiles []Profile, t time.Time) *Profile {
+	for i := range profiles {
+		if profiles[i].Active(t) {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProfileActive(t *testing.T) {
+	business := Profile{
+		Name:  "business",
+		Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start: 8 * time.Hour,
+		End:   18 * time.Hour,
+	}
+	night := Profile{Name: "night", Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 6 * time.Hour}
+
+	at := func(day, hour int) time.Time {
+		// 2024-01-01 was a Monday
+		return time.Date(2024, 1, day, hour, 30, 0, 0, time.UTC)
+	}
+
+	cases := []struct {
+		p      Profile
+		t      time.Time
+		active bool
+	}{
+		{business, at(1, 9), true},
+		{business, at(1, 18), false},
+		{business, at(1, 7), false},
+		{business, at(6, 12), false}, // Saturday
+		{night, at(5, 23), true},     // Friday night
+		{night, at(6, 3), true},      // Saturday morning is still Friday night
+		{night, at(6, 7), false},
+		{night, at(5, 3), false}, // Thursday night was not included
+		{Profile{Name: "always"}, at(3, 0), true},
+	}
+
+	for _, c := range cases {
+		if got := c.p.Active(c.t); got != c.active {
+			t.Errorf("Expected %s at %s to be %v", c.p.Name, c.t.Format(time.ANSIC), c.active)
+		}
+	}
+
+	if p := ActiveProfile([]Profile{business, night}, at(6, 12)); p != nil {
+		t.Errorf("Expected no profile on Saturday noon, got %s", p.Name)
+	}
+	if p := ActiveProfile([]Profile{business, night}, at(2, 12)); p == nil || p.Name != "business" {
+		t.Errorf("Expected the business profile on Tuesday noon, got %v", p)
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	if err := (Profile{Name: "ok", Start: time.Hour, End: 2 * time.Hour, Workload: 1}).Validate(); err != nil {
+		t.Errorf("Expected a valid profile, got %v", err)
+	}
+	if err := (Profile{Start: time.Hour}).Validate(); err == nil {
+		t.Error("Expected an error for a profile without a name")
+	}
+	if err := (Profile{Name: "late", Start: 25 * time.Hour}).Validate(); err == nil {
+		t.Error("Expected an error for a start past the end of the day")
+	}
+}
@@ -0,0 +1,180 @@
+package common
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Start a listener that echoes back whatever is written to it
+func echoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+
+	return l
+}
+
+// Join two connections together until either closes
+func pipe(a, b net.Conn) {
+	go io.Copy(a, b)
+	io.Copy(b, a)
+}
+
+func checkEcho(t *testing.T, conn net.Conn) {
+	_, err := conn.Write([]byte("cracklord"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 9)
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "cracklord" {
+		t.Errorf("Expected cracklord through the proxy but got %s", string(buf))
+	}
+}
+
+func TestDialProxyHTTP(t *testing.T) {
+	echo := echoServer(t)
+	defer echo.Close()
+
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	go func() {
+		c, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(c))
+		if err != nil || req.Method != "CONNECT" {
+			c.Close()
+			return
+		}
+
+		if req.Header.Get("Proxy-Authorization") != "Basic dXNlcjpwYXNz" {
+			c.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+			c.Close()
+			return
+		}
+
+		target, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			c.Close()
+			return
+		}
+
+		c.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		pipe(c, target)
+	}()
+
+	conn, err := DialProxy("http://user:pass@"+proxy.Addr().String(), echo.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unable to connect through HTTP proxy: %s", err.Error())
+	}
+	defer conn.Close()
+
+	checkEcho(t, conn)
+}
+
+func TestDialProxySOCKS5(t *testing.T) {
+	echo := echoServer(t)
+	defer echo.Close()
+
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	go func() {
+		c, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+
+		// Greeting, we only accept username and password authentication
+		buf := make([]byte, 3)
+		io.ReadFull(c, buf)
+		c.Write([]byte{0x05, 0x02})
+
+		// Username and password
+		hdr := make([]byte, 2)
+		io.ReadFull(c, hdr)
+		user := make([]byte, hdr[1])
+		io.ReadFull(c, user)
+		plen := make([]byte, 1)
+		io.ReadFull(c, plen)
+		pass := make([]byte, plen[0])
+		io.ReadFull(c, pass)
+
+		if string(user) != "user" || string(pass) != "pass" {
+			c.Write([]byte{0x01, 0x01})
+			c.Close()
+			return
+		}
+		c.Write([]byte{0x01, 0x00})
+
+		// Connect request with a domain name address
+		req := make([]byte, 5)
+		io.ReadFull(c, req)
+		host := make([]byte, req[4])
+		io.ReadFull(c, host)
+		port := make([]byte, 2)
+		io.ReadFull(c, port)
+
+		target, err := net.Dial("tcp", net.JoinHostPort(string(host), strconv.Itoa(int(port[0])<<8|int(port[1]))))
+		if err != nil {
+			c.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+			c.Close()
+			return
+		}
+
+		c.Write([]byte{0x05, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0})
+		pipe(c, target)
+	}()
+
+	conn, err := DialProxy("socks5://user:pass@"+proxy.Addr().String(), echo.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Unable to connect through SOCKS5 proxy: %s", err.Error())
+	}
+	defer conn.Close()
+
+	checkEcho(t, conn)
+}
+
+func TestDialProxyUnknownScheme(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	_, err = DialProxy("ftp://"+l.Addr().String(), "127.0.0.1:9443", time.Second)
+	if err == nil {
+		t.Error("Expected an error for an unsupported proxy type")
+	}
+}
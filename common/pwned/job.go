@@ -0,0 +1,157 @@
+package pwned
+
+import (
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Lookups made at once against a source
+var lookupWorkers = 8
+
+// An account of a job and how often its password was seen in breaches
+type Account struct {
+	Account string
+	Hash    string // Hash the account was submitted with
+	Cracked bool   // False when the submitted NT hash itself was looked up
+	Count   int
+}
+
+// The breach prevalence of the passwords of a job
+type Report struct {
+	Kind     string
+	ByUser   bool // Accounts are users rather than hashes
+	Checked  int  // Accounts looked up
+	Pwned    int  // Accounts whose password was seen in breaches
+	Accounts []Account
+}
+
+func isNTHash(hash string) bool {
+	if len(hash) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Look up the cracked passwords of a job in a source. With hashes set the
+// submitted hashes of accounts that were not cracked are looked up as they
+// are, which only makes sense for jobs of NT hashes checked against an NTLM
+// source and finds breached passwords the job has not cracked yet.
+func CheckJob(src Source, j common.Job, hashes bool) (Report, error) {
+	r := Report{Kind: src.Kind(), Accounts: []Account{}}
+	raw := hashes && src.Kind() == NTLM
+
+	cracked := j.Plaintexts()
+
+	// The account and the hash looked up for it
+	var accounts []Account
+	var lookups []string
+	add := func(account, hash string) {
+		a := Account{Account: account, Hash: hash}
+
+		var lookup string
+		if pw, ok := cracked[strings.ToLower(hash)]; ok {
+			a.Cracked = true
+			lookup = Hash(src.Kind(), pw)
+		} else if raw && isNTHash(hash) {
+			lookup = strings.ToUpper(hash)
+		} else {
+			return
+		}
+
+		accounts = append(accounts, a)
+		lookups = append(lookups, lookup)
+	}
+
+	if len(j.Usernames) > 0 {
+		r.ByUser = true
+		for hash, users := range j.Usernames {
+			if len(users) == 0 {
+				add(hash, hash)
+			}
+			for _, u := range users {
+				add(u, hash)
+			}
+		}
+	} else {
+		for hash := range cracked {
+			add(hash, hash)
+		}
+	}
+
+	counts, err := lookupAll(src, lookups)
+	if err != nil {
+		return r, err
+	}
+
+	for i, a := range accounts {
+		a.Count = counts[lookups[i]]
+		if a.Count > 0 {
+			r.Pwned++
+		}
+		r.Accounts = append(r.Accounts, a)
+	}
+	r.Checked = len(r.Accounts)
+
+	// The most common passwords first
+	sort.Slice(r.Accounts, func(x, y int) bool {
+		if r.Accounts[x].Count != r.Accounts[y].Count {
+			return r.Accounts[x].Count > r.Accounts[y].Count
+		}
+		return r.Accounts[x].Account < r.Accounts[y].Account
+	})
+
+	return r, nil
+}
+
+// Look up each hash once, stopping at the first error
+func lookupAll(src Source, hashes []string) (map[string]int, error) {
+	counts := map[string]int{}
+	var unique []string
+	for _, h := range hashes {
+		if _, ok := counts[h]; !ok {
+			counts[h] = 0
+			unique = append(unique, h)
+		}
+	}
+
+	work := make(chan string)
+	var firstErr error
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < lookupWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range work {
+				n, err := src.Count(h)
+
+				mux.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				counts[h] = n
+				mux.Unlock()
+			}
+		}()
+	}
+
+	for _, h := range unique {
+		mux.Lock()
+		failed := firstErr != nil
+		mux.Unlock()
+		if failed {
+			break
+		}
+		work <- h
+	}
+	close(work)
+	wg.Wait()
+
+	return counts, firstErr
+}
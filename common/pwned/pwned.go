@@ -0,0 +1,256 @@
+// Package pwned checks passwords against the Pwned Passwords corpus of Have I
+// Been Pwned, which counts how often each password was seen in breaches. The
+// corpus can be a local copy, downloaded as SHA-1 or NTLM hashes sorted by
+// hash with lines such as "7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577",
+// or the range API, which is only sent the first five characters of each hash
+// so the passwords themselves never leave the queue.
+package pwned
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common/ntds"
+)
+
+// The hashes a corpus is kept in
+const (
+	SHA1 = "sha1"
+	NTLM = "ntlm"
+)
+
+// The range API of Have I Been Pwned
+const DefaultURL = "https://api.pwnedpasswords.com"
+
+// Lookups of the range API remembered before the cache is cleared
+var apiCacheSize = 100000
+
+// A copy of the corpus or a way to reach it
+type Source interface {
+	// The hashes the source is kept in, SHA1 or NTLM
+	Kind() string
+
+	// Times a hash, in upper case hex, was seen in breaches, 0 if it never was
+	Count(hash string) (int, error)
+}
+
+// Check that a kind of hash is known
+func ValidKind(kind string) bool {
+	return kind == SHA1 || kind == NTLM
+}
+
+// Hash a password the way a kind of corpus keeps it
+func Hash(kind, password string) string {
+	if kind == NTLM {
+		return strings.ToUpper(ntds.NTHash(password))
+	}
+
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// A local copy of the corpus, which is searched in place as it is far too big
+// to be held in memory
+type Corpus struct {
+	kind string
+	f    *os.File
+	size int64
+}
+
+// Open a local copy of the corpus kept in a kind of hash
+func OpenCorpus(path, kind string) (*Corpus, error) {
+	if !ValidKind(kind) {
+		return nil, errors.New("Pwned Passwords hashes must be sha1 or ntlm.")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Corpus{kind: kind, f: f, size: info.Size()}, nil
+}
+
+func (c *Corpus) Kind() string {
+	return c.kind
+}
+
+func (c *Corpus) Close() error {
+	return c.f.Close()
+}
+
+// Get the first line starting at or after an offset and where it starts. The
+// line is empty at the end of the file.
+func (c *Corpus) lineAt(off int64) (int64, string, error) {
+	start := off
+	if off > 0 {
+		start = off - 1
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(c.f, start, c.size-start))
+	if off > 0 {
+		// Skip the rest of the line the offset is in
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return c.size, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	return start, line, nil
+}
+
+// Binary search the corpus for a hash
+func (c *Corpus) Count(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+
+	// The line of the hash, if there is one, starts between lo and hi
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := c.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi || line == "" {
+			hi = mid
+			continue
+		}
+
+		key, count := splitLine(line)
+		switch {
+		case key == hash:
+			return count, nil
+		case key < hash:
+			lo = start + int64(len(line))
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+// Split a line of the corpus or the range API into its hash and count
+func splitLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.ToUpper(line), 0
+	}
+
+	count, _ := strconv.Atoi(line[i+1:])
+	return strings.ToUpper(line[:i]), count
+}
+
+// The range API, which is sent the first five characters of a hash and
+// returns the rest of every hash starting with them
+type API struct {
+	URL    string
+	Client *http.Client
+
+	kind  string
+	cache map[string]int
+	mux   sync.Mutex
+}
+
+// Use the range API with a kind of hash
+func NewAPI(kind string) (*API, error) {
+	if !ValidKind(kind) {
+		return nil, errors.New("Pwned Passwords hashes must be sha1 or ntlm.")
+	}
+
+	return &API{
+		URL:    DefaultURL,
+		Client: &http.Client{Timeout: 30 * time.Second},
+		kind:   kind,
+		cache:  map[string]int{},
+	}, nil
+}
+
+func (a *API) Kind() string {
+	return a.kind
+}
+
+func (a *API) Count(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	if len(hash) < 6 {
+		return 0, errors.New("Hash is too short for the range API.")
+	}
+
+	a.mux.Lock()
+	count, ok := a.cache[hash]
+	a.mux.Unlock()
+	if ok {
+		return count, nil
+	}
+
+	u := strings.TrimRight(a.URL, "/") + "/range/" + hash[:5]
+	if a.kind == NTLM {
+		u += "?mode=ntlm"
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "cracklord")
+	// Padded responses hide how many hashes share the prefix
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Pwned Passwords returned %s", resp.Status)
+	}
+
+	count = 0
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		if suffix, n := splitLine(s.Text()); hash[:5]+suffix == hash {
+			count = n
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, err
+	}
+
+	a.mux.Lock()
+	if len(a.cache) >= apiCacheSize {
+		a.cache = map[string]int{}
+	}
+	a.cache[hash] = count
+	a.mux.Unlock()
+
+	return count, nil
+}
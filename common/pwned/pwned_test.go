@@ -0,0 +1,147 @@
+package pwned
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+func TestHash(t *testing.T) {
+	if h := Hash(SHA1, "password"); h != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Errorf("Unexpected SHA-1 of password %s", h)
+	}
+	if h := Hash(NTLM, "password"); h != "8846F7EAEE8FB117AD06BDD830B7586C" {
+		t.Errorf("Unexpected NT hash of password %s", h)
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	var lines []string
+	for _, pw := range []string{"password", "123456", "letmein", "Summer2024", "qwerty"} {
+		lines = append(lines, fmt.Sprintf("%s:%d", Hash(SHA1, pw), len(pw)))
+	}
+	sort.Strings(lines)
+
+	f, err := ioutil.TempFile("", "pwned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(strings.Join(lines, "\r\n") + "\r\n")
+	f.Close()
+
+	c, err := OpenCorpus(f.Name(), SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for _, pw := range []string{"password", "123456", "letmein", "Summer2024", "qwerty"} {
+		n, err := c.Count(strings.ToLower(Hash(SHA1, pw)))
+		if err != nil || n != len(pw) {
+			t.Errorf("Expected %s to be seen %d times, got %d (%v)", pw, len(pw), n, err)
+		}
+	}
+
+	if n, _ := c.Count(Hash(SHA1, "correct horse battery staple")); n != 0 {
+		t.Errorf("Expected an unknown password to not be found, got %d", n)
+	}
+	if n, _ := c.Count("0000000000000000000000000000000000000000"); n != 0 {
+		t.Errorf("Expected a hash before the first line to not be found, got %d", n)
+	}
+	if n, _ := c.Count("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"); n != 0 {
+		t.Errorf("Expected a hash after the last line to not be found, got %d", n)
+	}
+}
+
+func TestAPICheckJob(t *testing.T) {
+	known := map[string]int{
+		Hash(NTLM, "password"):  100,
+		Hash(NTLM, "Winter1!"):  3,
+		Hash(NTLM, "uncracked"): 7,
+	}
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Query().Get("mode") != "ntlm" {
+			t.Errorf("Expected an NTLM range request, got %s", r.URL)
+		}
+
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		if len(prefix) != 5 {
+			t.Errorf("Expected only five characters of the hash to be sent, got %s", prefix)
+		}
+		for h, n := range known {
+			if strings.HasPrefix(h, prefix) {
+				fmt.Fprintf(w, "%s:%d\r\n", h[5:], n)
+			}
+		}
+		fmt.Fprintf(w, "%s:0\r\n", strings.Repeat("0", 27))
+	}))
+	defer srv.Close()
+
+	api, err := NewAPI(NTLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.URL = srv.URL
+
+	pw := strings.ToLower(Hash(NTLM, "password"))
+	winter := strings.ToLower(Hash(NTLM, "Winter1!"))
+	strong := strings.ToLower(Hash(NTLM, "a long and unusual passphrase"))
+	uncracked := strings.ToLower(Hash(NTLM, "uncracked"))
+
+	job := common.Job{
+		OutputTitles: []string{"Hash", "Plaintext"},
+		OutputData: [][]string{
+			{pw, "password"},
+			{winter, "Winter1!"},
+			{strong, "a long and unusual passphrase"},
+		},
+		Usernames: map[string][]string{
+			pw:        {"alice", "bob"},
+			winter:    {"carol"},
+			strong:    {"dave"},
+			uncracked: {"erin"},
+		},
+	}
+
+	r, err := CheckJob(api, job, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.ByUser || r.Checked != 4 || r.Pwned != 3 {
+		t.Errorf("Expected 3 of 4 users to be pwned, got %d of %d", r.Pwned, r.Checked)
+	}
+	if r.Accounts[0].Account != "alice" || r.Accounts[0].Count != 100 || r.Accounts[3].Account != "dave" {
+		t.Errorf("Expected accounts sorted by count, got %v", r.Accounts)
+	}
+	if atomic.LoadInt32(&requests) != 3 {
+		t.Errorf("Expected one request for each password, got %d", requests)
+	}
+
+	r, err = CheckJob(api, job, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Checked != 5 || r.Pwned != 4 {
+		t.Errorf("Expected the uncracked hash to be looked up, got %d of %d", r.Pwned, r.Checked)
+	}
+	for _, a := range r.Accounts {
+		if a.Account == "erin" && (a.Cracked || a.Count != 7) {
+			t.Errorf("Expected erin to be found by hash, got %+v", a)
+		}
+	}
+	if atomic.LoadInt32(&requests) != 4 {
+		t.Errorf("Expected earlier lookups to be cached, got %d requests", requests)
+	}
+}
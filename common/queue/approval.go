@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"errors"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Hash modes, by the algorithm parameter of a job, whose jobs are always
+// sensitive such as the NT hashes of a domain
+var ApprovalAlgorithms = map[string]bool{}
+
+// Projects whose jobs are always sensitive
+var ApprovalProjects = map[string]bool{}
+
+// Returned when approving a job that is not waiting for approval
+var ErrApprovalNotHeld = errors.New("Job is not waiting for approval.")
+
+// Returned when the Administrator that submitted or owns a job approves it
+var ErrApprovalSelf = errors.New("A sensitive job must be approved by an Administrator other than the one that submitted it.")
+
+// Check if a job is sensitive, because it was tagged as one or because of the
+// hashes it cracks or the project it is for
+func isSensitive(j common.Job) bool {
+	return j.Sensitive || ApprovalAlgorithms[j.Parameters["algorithm"]] || ApprovalProjects[j.Project]
+}
+
+// Hold a sensitive job that is ready to be dispatched until a second
+// Administrator approves it. Drafts are held once they are started.
+func holdForApproval(j *common.Job) {
+	if !isSensitive(*j) {
+		return
+	}
+	j.Sensitive = true
+
+	if j.Status != common.STATUS_CREATED || j.ApprovedBy != "" {
+		return
+	}
+	j.Status = common.STATUS_APPROVAL
+
+	log.WithFields(log.Fields{
+		"job":   j.UUID,
+		"owner": j.Owner,
+	}).Info("Sensitive job is waiting for approval.")
+}
+
+// Let a sensitive job be dispatched. The Administrator approving it must not
+// be its owner or the one that submitted it.
+func (q *Queue) ApproveJob(jobuuid, by string) (common.Job, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := range q.stack {
+		if q.stack[i].UUID != jobuuid {
+			continue
+		}
+
+		if q.stack[i].Status != common.STATUS_APPROVAL {
+			return common.Job{}, ErrApprovalNotHeld
+		}
+		if by == q.stack[i].Owner || by == q.stack[i].SubmittedBy {
+			return common.Job{}, ErrApprovalSelf
+		}
+
+		q.stack[i].ApprovedBy = by
+		q.stack[i].Status = common.STATUS_CREATED
+		q.stack[i].Record(by, "approved", "Approved to run as a sensitive job.")
+
+		log.WithFields(log.Fields{
+			"job":   jobuuid,
+			"owner": q.stack[i].Owner,
+			"by":    by,
+		}).Info("Sensitive job approved.")
+
+		// If only held jobs have been added the keeper was never started
+		if q.status == STATUS_EMPTY {
+			log.Debug("Keeper started")
+			q.qk = make(chan bool)
+			go q.keeper()
+
+			q.status = STATUS_RUNNING
+		}
+		q.wakeDispatch()
+
+		return q.stack[i].Clone(), nil
+	}
+
+	return common.Job{}, ErrJobNotFound
+}
@@ -0,0 +1,267 @@
+package queue
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Hold back jobs that would push back the projected start of the job at the
+// head of their queue. Jobs further back still start on idle hardware ahead
+// of it, but only where they are estimated to finish before it could start.
+// When this is off every job takes whatever free hardware it can use.
+var Backfill = false
+
+// How long to wait before asking resources again for the run time of a job
+// none of them could estimate
+var EstimateRetryInterval = 10 * time.Minute
+
+// Run times of a waiting job reported by the resources
+type jobEstimate struct {
+	seconds map[string]float64 // Estimated run time on each resource
+	at      time.Time
+}
+
+// A job holding hardware on a resource until it is expected to finish
+type busySlot struct {
+	hardware string
+	finish   time.Time
+}
+
+// Ask the resources for the run times of waiting jobs that have not been
+// estimated so the dispatcher can tell which jobs are short enough to start
+// ahead of the head of their queue. The estimates are made in the background
+// so the queue is not held up by them.
+// The queue should NOT be locked.
+func (q *Queue) refreshEstimates() {
+	if !Backfill {
+		return
+	}
+
+	q.Lock()
+	if q.estimating {
+		q.Unlock()
+		return
+	}
+
+	now := time.Now()
+	present := map[string]bool{}
+	var planned []*planJob
+	for i := range q.stack {
+		j := q.stack[i]
+		present[j.UUID] = true
+		if j.Status != common.STATUS_CREATED && j.Status != common.STATUS_PAUSED && j.Status != common.STATUS_RUNNING {
+			continue
+		}
+		if e, ok := q.estimates[j.UUID]; ok && (len(e.seconds) > 0 || now.Sub(e.at) < EstimateRetryInterval) {
+			continue
+		}
+		planned = append(planned, &planJob{job: j.Clone()})
+	}
+
+	// Forget the estimates of removed jobs
+	for id := range q.estimates {
+		if !present[id] {
+			delete(q.estimates, id)
+		}
+	}
+
+	if len(planned) == 0 {
+		q.Unlock()
+		return
+	}
+	q.estimating = true
+	q.Unlock()
+
+	go func() {
+		q.estimatePlan(planned)
+
+		q.Lock()
+		if q.estimates == nil {
+			q.estimates = map[string]jobEstimate{}
+		}
+		for _, p := range planned {
+			q.estimates[p.job.UUID] = jobEstimate{seconds: p.seconds, at: time.Now()}
+		}
+		q.estimating = false
+		q.Unlock()
+
+		log.WithField("jobs", len(planned)).Debug("Job run times estimated for backfill.")
+
+		// Jobs held back for lack of an estimate may now be sent
+		q.wakeDispatch()
+	}()
+}
+
+// How long a job will keep running on a resource. Running jobs are projected
+// from their progress so far, other jobs use the estimate of the resource and
+// the maximum runtime of the job caps both. False when there is no way to tell.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) remainingOn(j common.Job, resUUID string, now time.Time) (time.Duration, bool) {
+	var elapsed time.Duration
+	if j.Status == common.STATUS_RUNNING && !j.StartTime.IsZero() {
+		elapsed = now.Sub(j.StartTime)
+	}
+
+	var rem time.Duration
+	var known bool
+	switch {
+	case j.Status == common.STATUS_RUNNING && j.Progress > 0 && j.Progress < 100 && elapsed > 0:
+		rem = time.Duration(float64(elapsed) * (100 - j.Progress) / j.Progress)
+		known = true
+	default:
+		if seconds, ok := q.estimates[j.UUID].seconds[resUUID]; ok {
+			rem = time.Duration(seconds*float64(time.Second)) - elapsed
+			known = true
+		}
+	}
+
+	maxRuntime := j.MaxRuntime
+	if maxRuntime <= 0 {
+		maxRuntime = MaxJobRuntime
+	}
+	if maxRuntime > 0 && (!known || maxRuntime-elapsed < rem) {
+		rem = maxRuntime - elapsed
+		known = true
+	}
+
+	if rem < 0 {
+		rem = 0
+	}
+	return rem, known
+}
+
+// Backfill decisions for one pass of the dispatcher
+type backfillPass struct {
+	q     *Queue
+	now   time.Time
+	never time.Time // Stands in for when a job with no estimate finishes
+	bound map[string][]string
+}
+
+func (q *Queue) newBackfillPass(now time.Time, bound map[string][]string) *backfillPass {
+	return &backfillPass{
+		q:     q,
+		now:   now,
+		never: now.Add(100 * 365 * 24 * time.Hour),
+		bound: bound,
+	}
+}
+
+// The hardware held on a resource by the jobs running or being sent to it
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (b *backfillPass) busy(resUUID string) []busySlot {
+	q := b.q
+	var out []busySlot
+	for i := range q.stack {
+		j := q.stack[i]
+		if j.ResAssigned != resUUID || (j.Status != common.STATUS_RUNNING && !q.dispatching[j.UUID]) {
+			continue
+		}
+
+		finish := b.never
+		if rem, ok := q.remainingOn(j, resUUID, b.now); ok {
+			finish = b.now.Add(rem)
+		}
+		out = append(out, busySlot{resourceHardware(q.pool[resUUID], j.ToolUUID), finish})
+	}
+	return out
+}
+
+// When a job waiting to start could start at the earliest on any resource,
+// with an extra job holding hardware on one of them when one is given
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (b *backfillPass) startOf(h common.Job, extraRes string, extra *busySlot) time.Time {
+	q := b.q
+	best := b.never
+
+	for resUUID, res := range q.pool {
+		if res.Status != common.STATUS_RUNNING || res.Draining {
+			continue
+		}
+		tool, ok := res.Tools[h.ToolUUID]
+		if !ok || !res.Inventory.Satisfies(h.Constraints) || !queueAllows(b.bound[resUUID], h) {
+			continue
+		}
+		if !reservationAllows(q.reservations, resUUID, h, b.now) {
+			continue
+		}
+
+		busy := b.busy(resUUID)
+		if extra != nil && resUUID == extraRes {
+			busy = append(busy, *extra)
+		}
+
+		// The hardware the job needs must be released
+		start := b.now
+		for _, s := range busy {
+			if s.hardware == tool.Requirements && s.finish.After(start) {
+				start = s.finish
+			}
+		}
+
+		// And the resource must be under the number of jobs it may run
+		limit := 0
+		if p := common.ActiveProfile(res.Profiles, b.now); p != nil {
+			limit = p.MaxTasks
+		}
+		if res.Exclusive {
+			limit = 1
+		}
+		if limit > 0 && len(busy) >= limit {
+			finishes := make([]time.Time, len(busy))
+			for i, s := range busy {
+				finishes[i] = s.finish
+			}
+			sort.Slice(finishes, func(i, j int) bool { return finishes[i].Before(finishes[j]) })
+
+			if room := finishes[len(busy)-limit]; room.After(start) {
+				start = room
+			}
+		}
+
+		if start.Before(best) {
+			best = start
+		}
+	}
+
+	return best
+}
+
+// Check if a job can be sent to the hardware of a resource without pushing
+// back the projected start of the job at the head of its queue
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (b *backfillPass) allows(jobKey int, resUUID, hardware string) bool {
+	q := b.q
+	j := q.stack[jobKey]
+
+	// The head is the first job of the queue waiting to start
+	waiting, _, _ := q.policyOrder(j.Queue)
+	head := -1
+	for _, i := range waiting {
+		if q.stack[i].Status == common.STATUS_CREATED {
+			head = i
+			break
+		}
+	}
+	if head < 0 || head == jobKey {
+		return true
+	}
+	h := q.stack[head]
+
+	finish := b.never
+	if rem, ok := q.remainingOn(j, resUUID, b.now); ok {
+		finish = b.now.Add(rem)
+	}
+
+	before := b.startOf(h, "", nil)
+	after := b.startOf(h, resUUID, &busySlot{hardware, finish})
+	if !after.After(before) {
+		return true
+	}
+
+	q.debugOncef(j, "Held back from resource %s so job %s at the head of the queue can start by %s", q.pool[resUUID].Name, h.UUID, before.Format(time.RFC3339))
+	return false
+}
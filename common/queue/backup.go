@@ -0,0 +1,395 @@
+package queue
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Number of backups kept in the backup storage, the oldest are deleted once
+// there are more. 0 keeps every backup. Backups hold the hash lists of jobs, so
+// when hash lists expire backups are also deleted once they are older than the
+// shortest expiry, whatever BackupKeep is.
+var BackupKeep = 7
+
+var ErrBackupNotFound = errors.New("The backup was not found in the backup storage.")
+
+// Keys of a backup in the backup storage
+const (
+	backupPrefix   = "backups/"
+	backupManifest = "manifest.json"
+	backupState    = "state.json"
+	backupStorage  = "storage/"
+	backupIDFormat = "20060102T150405Z"
+)
+
+// A file of a backup and the digest it is verified with
+type BackupFile struct {
+	Key    string // Key in the backup, state.json or storage/ and the key in job storage
+	Size   int64
+	SHA256 string
+}
+
+// What a backup holds. The manifest is written last, so only backups that
+// were finished have one.
+type BackupManifest struct {
+	ID    string
+	Taken time.Time
+	Files []BackupFile
+}
+
+func backupKey(id string, parts ...string) string {
+	return backupPrefix + id + "/" + strings.Join(parts, "")
+}
+
+// Copy an object to the backup storage and get its size and digest
+func putBackupFile(target Storage, key string, r io.Reader) (BackupFile, error) {
+	h := sha256.New()
+	cr := &countingReader{r: io.TeeReader(r, h)}
+	if err := target.Put(key, cr); err != nil {
+		return BackupFile{}, err
+	}
+
+	return BackupFile{Size: cr.n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Back up the queue to the backup storage every interval until the queue is
+// stopped, the first backup is taken after one interval
+func (q *Queue) StartBackups(target Storage, every time.Duration) {
+	go func() {
+		t := time.NewTicker(every)
+		defer t.Stop()
+
+		for range t.C {
+			m, err := q.Backup(target)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"storage": target.Name(),
+					"error":   err.Error(),
+				}).Error("Unable to back up the queue.")
+				continue
+			}
+
+			log.WithFields(log.Fields{
+				"backup": m.ID,
+				"files":  len(m.Files),
+			}).Info("Queue backed up and verified.")
+		}
+	}()
+}
+
+// Back up the state of the queue and every object in job storage to the
+// backup storage. Each file is read back and checked against its digest before
+// the manifest is written, then the oldest backups over BackupKeep are
+// deleted. Backups kept in the job storage itself are not backed up again.
+func (q *Queue) Backup(target Storage) (BackupManifest, error) {
+	m, err := q.backup(target)
+
+	q.Lock()
+	if err != nil && q.backupErr == nil {
+		q.backupErrAt = time.Now()
+	}
+	q.backupErr = err
+	q.Unlock()
+
+	return m, err
+}
+
+// Get since when and why backups are failing, a nil error when the last
+// backup worked
+func (q *Queue) BackupError() (time.Time, error) {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.backupErrAt, q.backupErr
+}
+
+func (q *Queue) backup(target Storage) (BackupManifest, error) {
+	m := BackupManifest{Taken: time.Now().UTC()}
+	m.ID = m.Taken.Format(backupIDFormat)
+
+	// The state is encoded under the lock so it matches what the queue had at
+	// one time, the state file itself may be half written
+	q.RLock()
+	state, err := json.Marshal(q.state())
+	q.RUnlock()
+	if err != nil {
+		return m, err
+	}
+
+	f, err := putBackupFile(target, backupKey(m.ID, backupState), bytes.NewReader(state))
+	if err != nil {
+		return m, err
+	}
+	f.Key = backupState
+	m.Files = append(m.Files, f)
+
+	if JobStorage != nil {
+		keys, err := JobStorage.List("")
+		if err != nil {
+			return m, err
+		}
+
+		for _, k := range keys {
+			if target == JobStorage && strings.HasPrefix(k, backupPrefix) {
+				continue
+			}
+
+			r, err := JobStorage.Get(k)
+			if err == ErrStorageNotFound {
+				// Removed since it was listed
+				continue
+			}
+			if err != nil {
+				return m, err
+			}
+			f, err := putBackupFile(target, backupKey(m.ID, backupStorage, k), r)
+			r.Close()
+			if err != nil {
+				return m, err
+			}
+			f.Key = backupStorage + k
+			m.Files = append(m.Files, f)
+		}
+	}
+
+	if err := verifyBackupFiles(target, m); err != nil {
+		return m, err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return m, err
+	}
+	if err := target.Put(backupKey(m.ID, backupManifest), bytes.NewReader(data)); err != nil {
+		return m, err
+	}
+
+	if err := pruneBackups(target); err != nil {
+		log.WithField("error", err.Error()).Warn("Unable to delete old backups.")
+	}
+
+	return m, nil
+}
+
+// Get the IDs of the finished backups in the backup storage, oldest first
+func ListBackups(target Storage) ([]string, error) {
+	keys, err := target.List(backupPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, k := range keys {
+		parts := strings.Split(strings.TrimPrefix(k, backupPrefix), "/")
+		if len(parts) == 2 && parts[1] == backupManifest {
+			ids = append(ids, parts[0])
+		}
+	}
+
+	// IDs are times, so they sort in the order they were taken
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// How long a backup is kept before the hash lists in it could have been
+// purged from the queue, 0 when hash lists are kept until jobs are removed
+func backupMaxAge() time.Duration {
+	max := DefaultExpiry
+	for _, d := range ProjectExpiry {
+		if d > 0 && (max <= 0 || d < max) {
+			max = d
+		}
+	}
+	return max
+}
+
+// Delete the oldest backups, and any that were never finished, so only
+// BackupKeep are left and none is older than the shortest expiry of hash
+// lists. A job purged from the queue is still in the backups taken before it
+// was, for up to the expiry again.
+func pruneBackups(target Storage) error {
+	maxAge := backupMaxAge()
+	if BackupKeep <= 0 && maxAge <= 0 {
+		return nil
+	}
+
+	ids, err := ListBackups(target)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	newest := ids[len(ids)-1]
+
+	keep := map[string]bool{}
+	if BackupKeep > 0 && len(ids) > BackupKeep {
+		ids = ids[len(ids)-BackupKeep:]
+	}
+	for _, id := range ids {
+		if maxAge > 0 {
+			taken, err := time.Parse(backupIDFormat, id)
+			if err == nil && time.Since(taken) > maxAge {
+				continue
+			}
+		}
+		keep[id] = true
+	}
+
+	keys, err := target.List(backupPrefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		id := strings.SplitN(strings.TrimPrefix(k, backupPrefix), "/", 2)[0]
+		// Backups newer than the last finished one may still be running
+		if keep[id] || id > newest {
+			continue
+		}
+		if err := target.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read the manifest of a backup, the newest one for an ID of latest
+func readBackupManifest(target Storage, id string) (BackupManifest, error) {
+	var m BackupManifest
+
+	if id == "latest" {
+		ids, err := ListBackups(target)
+		if err != nil {
+			return m, err
+		}
+		if len(ids) == 0 {
+			return m, ErrBackupNotFound
+		}
+		id = ids[len(ids)-1]
+	}
+
+	r, err := target.Get(backupKey(id, backupManifest))
+	if err == ErrStorageNotFound {
+		return m, ErrBackupNotFound
+	}
+	if err != nil {
+		return m, err
+	}
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(&m)
+	return m, err
+}
+
+// Check every file of a backup against the digest in its manifest
+func verifyBackupFiles(target Storage, m BackupManifest) error {
+	for _, f := range m.Files {
+		r, err := target.Get(backupKey(m.ID, f.Key))
+		if err != nil {
+			return fmt.Errorf("Unable to read %s of backup %s: %s", f.Key, m.ID, err.Error())
+		}
+
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("Unable to read %s of backup %s: %s", f.Key, m.ID, err.Error())
+		}
+
+		if n != f.Size || hex.EncodeToString(h.Sum(nil)) != f.SHA256 {
+			return fmt.Errorf("The file %s of backup %s does not match its checksum.", f.Key, m.ID)
+		}
+	}
+
+	return nil
+}
+
+// Check that every file of a backup is in the backup storage and matches its
+// checksum
+func VerifyBackup(target Storage, id string) (BackupManifest, error) {
+	m, err := readBackupManifest(target, id)
+	if err != nil {
+		return m, err
+	}
+
+	return m, verifyBackupFiles(target, m)
+}
+
+// Restore a backup to the state file and job storage. The backup is verified
+// first so nothing is restored from one that is damaged. The queue server
+// must not be running, as it would write its own state over the state file.
+func RestoreBackup(target Storage, id, statefile string, store Storage) (BackupManifest, error) {
+	if statefile == "" {
+		return BackupManifest{}, errors.New("No state file is configured to restore the backup to.")
+	}
+
+	m, err := VerifyBackup(target, id)
+	if err != nil {
+		return m, err
+	}
+
+	for _, f := range m.Files {
+		if !strings.HasPrefix(f.Key, backupStorage) {
+			continue
+		}
+		if store == nil {
+			return m, errors.New("The backup holds job data but no job storage is configured.")
+		}
+
+		r, err := target.Get(backupKey(m.ID, f.Key))
+		if err != nil {
+			return m, err
+		}
+		err = store.Put(strings.TrimPrefix(f.Key, backupStorage), r)
+		r.Close()
+		if err != nil {
+			return m, err
+		}
+	}
+
+	// The state file is written last and replaced at once, so a restore that
+	// failed part way leaves the state of the queue as it was
+	r, err := target.Get(backupKey(m.ID, backupState))
+	if err != nil {
+		return m, err
+	}
+	defer r.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(statefile), ".restore-")
+	if err != nil {
+		return m, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return m, err
+	}
+
+	return m, os.Rename(tmp.Name(), statefile)
+}
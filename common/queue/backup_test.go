@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"encoding/json"
+	"github.com/jmmcatee/cracklord/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Job storage holding one object for a job and a queue with that job, both
+// backed up to a local backup storage
+func testBackup(t *testing.T) (*Queue, Storage, BackupManifest, common.Job) {
+	store, err := NewLocalStorage(filepath.Join(t.TempDir(), "storage"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	JobStorage = store
+	t.Cleanup(func() { JobStorage = nil })
+
+	q := testQueue(t)
+	j := common.NewJob("tool", "Backed up", "GoTestSuite", map[string]string{"hashes": "aaaa"})
+	j.Status = common.STATUS_DRAFT
+	if _, err := q.Import(Bundle{Format: BUNDLE_FORMAT, StateFile: StateFile{Stack: []common.Job{j}}}, "GoTestSuite"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(jobKey(j.UUID, "output"), strings.NewReader("aaaa:password")); err != nil {
+		t.Fatal(err)
+	}
+
+	target, err := NewLocalStorage(filepath.Join(t.TempDir(), "backups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := q.Backup(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q, target, m, j
+}
+
+func readStorage(t *testing.T, s Storage, key string) string {
+	r, err := s.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	_, target, m, j := testBackup(t)
+
+	if ids, err := ListBackups(target); err != nil || !reflect.DeepEqual(ids, []string{m.ID}) {
+		t.Fatalf("Expected backup %s, got %v %v", m.ID, ids, err)
+	}
+	if _, err := VerifyBackup(target, m.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	statefile := filepath.Join(t.TempDir(), "restored.json")
+	restored, err := NewLocalStorage(filepath.Join(t.TempDir(), "restored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreBackup(target, "latest", statefile, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readStorage(t, restored, jobKey(j.UUID, "output")); got != "aaaa:password" {
+		t.Errorf("Restored job data is %q", got)
+	}
+
+	data, err := ioutil.ReadFile(statefile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s StateFile
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Stack) != 1 || s.Stack[0].UUID != j.UUID || s.Stack[0].Parameters["hashes"] != "aaaa" {
+		t.Errorf("Restored state has jobs %+v", s.Stack)
+	}
+}
+
+func TestBackupDamagedNotRestored(t *testing.T) {
+	for name, content := range map[string]string{
+		"truncated": "aaaa:pass",
+		"corrupted": "bbbb:password",
+	} {
+		_, target, m, j := testBackup(t)
+		if err := target.Put(backupKey(m.ID, backupStorage, jobKey(j.UUID, "output")), strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := VerifyBackup(target, m.ID); err == nil {
+			t.Errorf("Backup with a %s file was verified", name)
+		}
+
+		// Nothing is restored from the damaged backup
+		statefile := filepath.Join(t.TempDir(), "restored.json")
+		restored, err := NewLocalStorage(filepath.Join(t.TempDir(), "restored"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RestoreBackup(target, m.ID, statefile, restored); err == nil {
+			t.Errorf("Backup with a %s file was restored", name)
+		}
+		if keys, _ := restored.List(""); len(keys) != 0 {
+			t.Errorf("Backup with a %s file restored %v", name, keys)
+		}
+		if _, err := os.Stat(statefile); !os.IsNotExist(err) {
+			t.Errorf("Backup with a %s file restored the state file", name)
+		}
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	keep, expiry := BackupKeep, DefaultExpiry
+	defer func() { BackupKeep, DefaultExpiry = keep, expiry }()
+
+	target, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Four finished backups an hour apart, one left unfinished before them and
+	// one still being taken
+	now := time.Now().UTC()
+	var ids []string
+	for h := 5; h >= 0; h-- {
+		id := now.Add(-time.Duration(h) * time.Hour).Format(backupIDFormat)
+		ids = append(ids, id)
+		target.Put(backupKey(id, backupState), strings.NewReader("{}"))
+		if h != 5 && h != 0 {
+			target.Put(backupKey(id, backupManifest), strings.NewReader("{}"))
+		}
+	}
+	backups := func() []string {
+		keys, _ := target.List(backupPrefix)
+		var found []string
+		for _, k := range keys {
+			if strings.HasSuffix(k, backupState) {
+				found = append(found, strings.Split(strings.TrimPrefix(k, backupPrefix), "/")[0])
+			}
+		}
+		return found
+	}
+
+	BackupKeep, DefaultExpiry = 2, 0
+	if err := pruneBackups(target); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := backups(), ids[3:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected backups %v to be kept, got %v", want, got)
+	}
+
+	// Backups are not kept longer than hash lists, even when every one is kept
+	BackupKeep, DefaultExpiry = 0, 90*time.Minute
+	if err := pruneBackups(target); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := backups(), ids[4:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected backups %v to be kept, got %v", want, got)
+	}
+}
@@ -0,0 +1,338 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How long to wait before sending a tool binary to a resource again after it
+// was refused or the call failed
+var BinaryRetryInterval = 15 * time.Minute
+
+// Returned when a build is not in the binary repository
+var ErrBinaryNotFound = errors.New("The tool binary does not exist.")
+
+// Returned when deleting a build resources are kept at
+var ErrBinaryCurrent = errors.New("Builds of the current version of a tool cannot be deleted.")
+
+// The key of the list of builds and current versions in storage
+const binaryIndexKey = "binaries/index.json"
+
+// The key a build is kept under in storage
+func binaryKey(b common.ToolBinary) string {
+	return strings.Join([]string{"binaries", b.Tool, b.Version, b.OS + "-" + b.Arch}, "/")
+}
+
+type binaryIndex struct {
+	Builds  []common.ToolBinary
+	Current map[string]string // Version resources are kept at, by tool
+}
+
+/*
+ * Builds of tools such as hashcat for each platform resources run on. Admins
+ * upload signed builds and pick the current version of each tool, which the
+ * queue then installs on every resource that runs the tool so the whole farm
+ * runs the same version. Resources check the checksum and signature before
+ * they use a build.
+ */
+type BinaryRepo struct {
+	store   Storage
+	index   binaryIndex
+	failed  map[string]time.Time // When sending a build to a resource last failed
+	syncing bool
+	mux     sync.Mutex
+}
+
+// The binary repository, nil if there is no storage for it
+var Binaries *BinaryRepo
+
+// Open the repository kept in a storage, the list of builds is read from it
+func NewBinaryRepo(store Storage) (*BinaryRepo, error) {
+	b := &BinaryRepo{
+		store:  store,
+		index:  binaryIndex{Current: map[string]string{}},
+		failed: map[string]time.Time{},
+	}
+
+	r, err := store.Get(binaryIndexKey)
+	if err == ErrStorageNotFound {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := json.NewDecoder(r).Decode(&b.index); err != nil {
+		return nil, err
+	}
+	if b.index.Current == nil {
+		b.index.Current = map[string]string{}
+	}
+
+	return b, nil
+}
+
+// Get every build and the current version of each tool
+func (b *BinaryRepo) Builds() ([]common.ToolBinary, map[string]string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	current := make(map[string]string, len(b.index.Current))
+	for k, v := range b.index.Current {
+		current[k] = v
+	}
+
+	return append([]common.ToolBinary(nil), b.index.Builds...), current
+}
+
+// Add a build, replacing one for the same version and platform. The checksum
+// and size are worked out from the binary, the signature is only checked by
+// the resources that hold the update key.
+func (b *BinaryRepo) Add(build common.ToolBinary, bin []byte) (common.ToolBinary, error) {
+	if err := build.Validate(); err != nil {
+		return build, err
+	}
+	if len(bin) == 0 || len(build.Signature) == 0 {
+		return build, errors.New("Tool binaries need a binary and its signature.")
+	}
+
+	build.SHA256 = common.BuildHash(bin)
+	build.Size = int64(len(bin))
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if err := b.store.Put(binaryKey(build), bytes.NewReader(bin)); err != nil {
+		return build, err
+	}
+
+	builds := []common.ToolBinary{build}
+	for _, v := range b.index.Builds {
+		if binaryKey(v) != binaryKey(build) {
+			builds = append(builds, v)
+		}
+	}
+	b.index.Builds = builds
+
+	// A replaced build is sent to resources again
+	b.failed = map[string]time.Time{}
+
+	return build, b.save()
+}
+
+// Delete a build. Builds of the current version of a tool are kept.
+func (b *BinaryRepo) Delete(build common.ToolBinary) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.index.Current[build.Tool] == build.Version {
+		return ErrBinaryCurrent
+	}
+
+	var builds []common.ToolBinary
+	var found bool
+	for _, v := range b.index.Builds {
+		if binaryKey(v) == binaryKey(build) {
+			found = true
+			continue
+		}
+		builds = append(builds, v)
+	}
+	if !found {
+		return ErrBinaryNotFound
+	}
+
+	if err := b.store.Delete(binaryKey(build)); err != nil {
+		return err
+	}
+	b.index.Builds = builds
+
+	return b.save()
+}
+
+// Set the version of a tool resources are kept at, an empty version leaves
+// resources with whatever they have
+func (b *BinaryRepo) SetCurrent(tool, version string) error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if version == "" {
+		delete(b.index.Current, tool)
+		return b.save()
+	}
+
+	var found bool
+	for _, v := range b.index.Builds {
+		if v.Tool == tool && v.Version == version {
+			found = true
+		}
+	}
+	if !found {
+		return ErrBinaryNotFound
+	}
+
+	b.index.Current[tool] = version
+	b.failed = map[string]time.Time{}
+
+	log.WithFields(log.Fields{
+		"tool":    tool,
+		"version": version,
+	}).Info("Current tool binary version set.")
+
+	return b.save()
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (b *BinaryRepo) save() error {
+	data, err := json.Marshal(b.index)
+	if err != nil {
+		return err
+	}
+	return b.store.Put(binaryIndexKey, bytes.NewReader(data))
+}
+
+// The builds a resource should be sent, which are the current versions of the
+// tools it runs for its platform that it does not have already
+func (b *BinaryRepo) pending(resUUID string, inv common.Inventory) []common.ToolBinary {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	var out []common.ToolBinary
+	for tool, installed := range inv.Binaries {
+		version, ok := b.index.Current[tool]
+		if !ok || installed == version {
+			continue
+		}
+
+		for _, v := range b.index.Builds {
+			if v.Tool != tool || v.Version != version || v.OS != inv.OS || v.Arch != inv.Arch {
+				continue
+			}
+			if time.Since(b.failed[resUUID+"/"+binaryKey(v)]) < BinaryRetryInterval {
+				continue
+			}
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// Read a build from storage to send it to a resource
+func (b *BinaryRepo) Package(build common.ToolBinary) (common.BinaryPackage, error) {
+	r, err := b.store.Get(binaryKey(build))
+	if err != nil {
+		return common.BinaryPackage{}, err
+	}
+	defer r.Close()
+
+	bin, err := ioutil.ReadAll(r)
+	if err != nil {
+		return common.BinaryPackage{}, err
+	}
+
+	return common.BinaryPackage{ToolBinary: build, Binary: bin}, nil
+}
+
+// Send the current tool binaries to the resources that do not have them. The
+// builds are sent in the background so the queue is not held up by them.
+// The queue should NOT be locked.
+func (q *Queue) syncBinaries() {
+	repo := Binaries
+	if repo == nil {
+		return
+	}
+
+	type target struct {
+		client *ResourceClient
+		builds []common.ToolBinary
+	}
+
+	q.RLock()
+	targets := map[string]target{}
+	for resUUID, res := range q.pool {
+		if res.Status != common.STATUS_RUNNING || res.Client == nil {
+			continue
+		}
+		if builds := repo.pending(resUUID, res.Inventory); len(builds) > 0 {
+			targets[resUUID] = target{res.Client, builds}
+		}
+	}
+	q.RUnlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	repo.mux.Lock()
+	if repo.syncing {
+		repo.mux.Unlock()
+		return
+	}
+	repo.syncing = true
+	repo.mux.Unlock()
+
+	go func() {
+		defer func() {
+			repo.mux.Lock()
+			repo.syncing = false
+			repo.mux.Unlock()
+		}()
+
+		for resUUID, t := range targets {
+			for _, build := range t.builds {
+				q.sendBinary(repo, resUUID, t.client, build)
+			}
+		}
+	}()
+}
+
+func (q *Queue) sendBinary(repo *BinaryRepo, resUUID string, client *ResourceClient, build common.ToolBinary) {
+	logger := log.WithFields(log.Fields{
+		"resource": resUUID,
+		"tool":     build.Tool,
+		"version":  build.Version,
+		"platform": build.OS + "/" + build.Arch,
+	})
+
+	pkg, err := repo.Package(build)
+	if err == nil {
+		var installed string
+		err = client.Call("Queue.InstallBinary", common.RPCCall{Binary: &pkg}, &installed)
+	}
+	if err != nil {
+		repo.mux.Lock()
+		repo.failed[resUUID+"/"+binaryKey(build)] = time.Now()
+		repo.mux.Unlock()
+
+		logger.WithField("error", err.Error()).Error("Unable to install tool binary on resource.")
+		return
+	}
+
+	q.Lock()
+	if res, ok := q.pool[resUUID]; ok {
+		binaries := make(map[string]string, len(res.Inventory.Binaries))
+		for k, v := range res.Inventory.Binaries {
+			binaries[k] = v
+		}
+		binaries[build.Tool] = build.Version
+		res.Inventory.Binaries = binaries
+		q.pool[resUUID] = res
+	}
+	q.Unlock()
+	q.InvalidateSnapshot()
+
+	logger.Info("Tool binary installed on resource.")
+
+	// The resource reports the new version of the tool
+	q.LoadRemoteResourceTools(resUUID)
+}
@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Format of the queue bundles this version exports and imports
+const BUNDLE_FORMAT = "cracklord-queue/1"
+
+var ErrBundleFormat = errors.New("The bundle is not a queue bundle this server can import.")
+
+// The state of a queue exported to move it to another queue server or to
+// stage a standby server. It holds what the state file does. Output spilled to
+// job storage is not in the bundle, so the other server needs the same storage
+// for the spilled rows of the jobs.
+type Bundle struct {
+	Format     string    `json:"format"`
+	Exported   time.Time `json:"exported"`
+	ExportedBy string    `json:"exportedby"`
+	StateFile
+}
+
+// What an import added and what was already on the queue and left alone
+type ImportSummary struct {
+	Jobs             int
+	JobsSkipped      int
+	Resources        int
+	ResourcesSkipped int
+	Defaults         int
+	Queues           int
+	Reservations     int
+}
+
+// Export the jobs, resources, tool defaults, named queues, reservations, tool
+// statistics and spend of projects of the queue
+func (q *Queue) Export(by string) Bundle {
+	q.RLock()
+	defer q.RUnlock()
+
+	s := q.state()
+	for i := range s.Stack {
+		s.Stack[i] = s.Stack[i].Clone()
+	}
+	for k, res := range s.Pool {
+		s.Pool[k] = res.clone()
+	}
+	// Released tasks are on the resources of this server so it quits them
+	s.Released = nil
+
+	return Bundle{
+		Format:     BUNDLE_FORMAT,
+		Exported:   time.Now().UTC(),
+		ExportedBy: by,
+		StateFile:  s,
+	}
+}
+
+// Import a bundle exported by another queue server. Only what this queue does
+// not have is added, so a bundle can be imported again to catch a standby up.
+// Resources are added disconnected like they are from the state file, until
+// their managers connect them, and jobs that were running on the other server
+// are quit as their tasks stay on its resources. Jobs waiting to run are
+// queued here with the tool defaults and approval of jobs added here.
+func (q *Queue) Import(b Bundle, by string) (ImportSummary, error) {
+	var sum ImportSummary
+
+	if b.Format != BUNDLE_FORMAT {
+		return sum, ErrBundleFormat
+	}
+	for _, j := range b.Stack {
+		if j.UUID == "" {
+			return sum, errors.New("A job in the bundle does not have an ID.")
+		}
+	}
+
+	defer q.InvalidateSnapshot()
+	q.Lock()
+	defer q.Unlock()
+
+	for id, res := range b.Pool {
+		if _, ok := q.pool[id]; ok {
+			sum.ResourcesSkipped++
+			continue
+		}
+
+		res.Client = nil
+		res.Address = "(disconnected)"
+		res.Status = common.STATUS_QUIT
+		res.Tools = map[string]common.Tool{}
+		if res.Hardware == nil {
+			res.Hardware = map[string]bool{}
+		}
+		res.Throttle = common.NewThrottle(res.bandwidth())
+		q.pool[id] = res
+		sum.Resources++
+	}
+
+	// Defaults first so queued jobs get those of the bundle too
+	for name, d := range b.Defaults {
+		if _, ok := q.defaults[name]; !ok {
+			q.defaults[name] = d
+			sum.Defaults++
+		}
+	}
+
+	jobs := map[string]bool{}
+	for i := range q.stack {
+		jobs[q.stack[i].UUID] = true
+	}
+
+	var queued bool
+	detail := "Job imported from a queue bundle exported at " + b.Exported.UTC().Format(time.RFC3339) + "."
+	for _, j := range b.Stack {
+		if jobs[j.UUID] {
+			sum.JobsSkipped++
+			continue
+		}
+
+		msg := detail
+		switch j.Status {
+		case common.STATUS_RUNNING, common.STATUS_PAUSED:
+			msg += " It was " + j.Status + " on the other server and was quit."
+			j.Status = common.STATUS_QUIT
+		}
+		j.Record(by, "imported", msg)
+		j.CompactOutput()
+
+		// Jobs waiting to run are queued here like they are when they are added
+		if j.Status == common.STATUS_CREATED {
+			q.applyToolDefaults(&j)
+			holdForApproval(&j)
+			queued = queued || j.Status == common.STATUS_CREATED
+		}
+
+		if cp, ok := b.Checkpoints[j.UUID]; ok {
+			q.checkpoints[j.UUID] = cp
+		}
+		q.stats.Skip(j.UUID)
+		q.newCredentials(j) // Exported by the other server
+
+		q.stack = append(q.stack, j)
+		jobs[j.UUID] = true
+		sum.Jobs++
+	}
+
+	for name, ts := range b.Stats {
+		if _, ok := q.stats.Tools[name]; !ok {
+			q.stats.Tools[name] = ts
+		}
+	}
+	for project, spent := range b.Spend {
+		if _, ok := q.spend[project]; !ok {
+			q.spend[project] = spent
+		}
+	}
+
+	queues := map[string]bool{}
+	for _, n := range q.queues {
+		queues[n.Name] = true
+	}
+	for _, n := range b.Queues {
+		if !queues[n.Name] {
+			q.queues = append(q.queues, n)
+			sum.Queues++
+		}
+	}
+
+	reservations := map[string]bool{}
+	for _, r := range q.reservations {
+		reservations[r.UUID] = true
+	}
+	for _, r := range b.Reservations {
+		if !reservations[r.UUID] {
+			q.reservations = append(q.reservations, r)
+			sum.Reservations++
+		}
+	}
+
+	if StateFileLocation != "" {
+		q.writeState()
+	}
+
+	// The keeper will start the queued jobs as resources become free
+	if queued {
+		if q.status == STATUS_EMPTY {
+			log.Debug("Keeper started")
+			q.qk = make(chan bool)
+			go q.keeper()
+
+			q.status = STATUS_RUNNING
+		}
+		q.wakeDispatch()
+	}
+
+	log.WithFields(log.Fields{
+		"exported":  b.Exported,
+		"jobs":      sum.Jobs,
+		"skipped":   sum.JobsSkipped,
+		"resources": sum.Resources,
+		"user":      by,
+	}).Info("Queue bundle imported.")
+
+	return sum, nil
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// The number of removed jobs remembered for clients that are catching up
+var MaxRemovedChanges = 1000
+
+// The parts of a job shown in listings. A job whose version differs from the
+// last one seen is given a new sequence number.
+type jobVersion struct {
+	Name          string
+	Status        string
+	Error         string
+	Owner         string
+	Queue         string
+	ResAssigned   string
+	StartTime     time.Time
+	ETC           string
+	CrackedHashes int64
+	TotalHashes   int64
+	Progress      float64
+	History       int
+	Output        int
+}
+
+func versionOf(j *common.Job) jobVersion {
+	return jobVersion{
+		Name:          j.Name,
+		Status:        j.Status,
+		Error:         j.Error,
+		Owner:         j.Owner,
+		Queue:         j.Queue,
+		ResAssigned:   j.ResAssigned,
+		StartTime:     j.StartTime,
+		ETC:           j.ETC,
+		CrackedHashes: j.CrackedHashes,
+		TotalHashes:   j.TotalHashes,
+		Progress:      j.Progress,
+		History:       len(j.History),
+		Output:        j.OutputSpilled + j.OutputLen(),
+	}
+}
+
+type trackedJob struct {
+	version jobVersion
+	seq     uint64
+}
+
+// Sequence numbers of the last change to every job. Changes are found by
+// comparing jobs to the version last seen whenever changes are asked for, so
+// no code that changes a job has to record it.
+type changeTracker struct {
+	seq     uint64
+	jobs    map[string]trackedJob
+	removed map[string]uint64 // Removed jobs and the sequence they were removed at
+	pruned  uint64            // Removals at or before this sequence were forgotten
+	mux     sync.Mutex
+}
+
+func newChangeTracker() *changeTracker {
+	return &changeTracker{
+		jobs:    map[string]trackedJob{},
+		removed: map[string]uint64{},
+	}
+}
+
+// Jobs changed since a cursor returned by an earlier call
+type JobChanges struct {
+	Cursor  uint64       // Pass this back to get the next changes
+	Reset   bool         // The changes could not be worked out so Jobs has every job
+	Jobs    []common.Job // Jobs added or changed, in queue order
+	Removed []string     // UUIDs of jobs that were removed
+}
+
+// Get the jobs that changed after the given cursor. A cursor of 0, or one that
+// is too old or from before the queue was restarted, returns every job with
+// Reset set.
+func (q *Queue) JobChanges(since uint64) JobChanges {
+	q.RLock()
+	defer q.RUnlock()
+
+	t := q.changes
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Bring the sequences up to date with the queue
+	present := make(map[string]bool, len(q.stack))
+	for i := range q.stack {
+		j := &q.stack[i]
+		present[j.UUID] = true
+
+		v := versionOf(j)
+		if tj, ok := t.jobs[j.UUID]; !ok || tj.version != v {
+			t.seq++
+			t.jobs[j.UUID] = trackedJob{version: v, seq: t.seq}
+		}
+	}
+	for id := range t.jobs {
+		if !present[id] {
+			t.seq++
+			t.removed[id] = t.seq
+			delete(t.jobs, id)
+		}
+	}
+	t.prune()
+
+	c := JobChanges{
+		Cursor: t.seq,
+		Reset:  since == 0 || since > t.seq || since < t.pruned,
+	}
+
+	for i := range q.stack {
+		if c.Reset || t.jobs[q.stack[i].UUID].seq > since {
+			c.Jobs = append(c.Jobs, q.stack[i].Clone())
+		}
+	}
+	if !c.Reset {
+		for id, seq := range t.removed {
+			if seq > since {
+				c.Removed = append(c.Removed, id)
+			}
+		}
+	}
+
+	return c
+}
+
+// Forget the oldest removals once there are too many. The tracker lock should
+// already be held.
+func (t *changeTracker) prune() {
+	for len(t.removed) > MaxRemovedChanges {
+		var oldest string
+		for id, seq := range t.removed {
+			if oldest == "" || seq < t.removed[oldest] {
+				oldest = id
+			}
+		}
+
+		if t.removed[oldest] > t.pruned {
+			t.pruned = t.removed[oldest]
+		}
+		delete(t.removed, oldest)
+	}
+}
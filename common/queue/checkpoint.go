@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How often running jobs are checkpointed, 0 disables checkpoints
+var CheckpointInterval time.Duration
+
+// Ask resources for the restore point of running jobs that have not been
+// checkpointed within the interval. Checkpoints are saved with the state file
+// so at most one interval of work is lost if a resource fails.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) syncCheckpoints() {
+	// Finished and removed jobs will never be restored
+	keep := map[string]bool{}
+	for i := range q.stack {
+		if q.stack[i].Status != common.STATUS_DONE {
+			keep[q.stack[i].UUID] = true
+		}
+	}
+	for jobuuid := range q.checkpoints {
+		if !keep[jobuuid] {
+			delete(q.checkpoints, jobuuid)
+		}
+	}
+	for jobuuid := range q.checkpointed {
+		if !keep[jobuuid] {
+			delete(q.checkpointed, jobuuid)
+		}
+	}
+
+	if CheckpointInterval <= 0 {
+		return
+	}
+
+	for i := range q.stack {
+		if q.stack[i].Status != common.STATUS_RUNNING {
+			continue
+		}
+
+		jobuuid := q.stack[i].UUID
+		last, ok := q.checkpointed[jobuuid]
+		if !ok {
+			// Nothing worth saving has been done until the job has run an interval
+			q.checkpointed[jobuuid] = time.Now()
+			continue
+		}
+		if time.Since(last) < CheckpointInterval {
+			continue
+		}
+		q.checkpointed[jobuuid] = time.Now()
+
+		res, ok := q.pool[q.stack[i].ResAssigned]
+		if !ok || res.Client == nil {
+			continue
+		}
+
+		var cp common.Checkpoint
+		err := res.Client.Call("Queue.TaskCheckpoint", common.RPCCall{Job: q.stack[i].ForResource()}, &cp)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"job":   jobuuid,
+				"error": err.Error(),
+			}).Debug("Unable to checkpoint job.")
+			continue
+		}
+
+		cp.Taken = common.LocalTime(cp.Taken, res.ClockOffset)
+		q.checkpoints[jobuuid] = cp
+
+		log.WithFields(log.Fields{
+			"job":      jobuuid,
+			"progress": cp.Progress,
+			"point":    cp.Point,
+		}).Debug("Job checkpoint saved.")
+	}
+}
+
+// Get the last checkpoint saved for a job
+func (q *Queue) Checkpoint(jobuuid string) (common.Checkpoint, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	cp, ok := q.checkpoints[jobuuid]
+	return cp, ok
+}
+
+// Queue a quit or failed job again so it continues from its last checkpoint on
+// the next free resource
+func (q *Queue) RestoreJob(jobuuid, by string) (common.Job, error) {
+	log.WithField("job", jobuuid).Info("Attempting to restore job from checkpoint.")
+
+	q.Lock()
+	defer q.Unlock()
+
+	for i, _ := range q.stack {
+		if q.stack[i].UUID != jobuuid {
+			continue
+		}
+
+		s := q.stack[i].Status
+		if s != common.STATUS_QUIT && s != common.STATUS_FAILED {
+			return common.Job{}, errors.New("Only quit or failed jobs can be restored. Current status is " + s)
+		}
+
+		cp, ok := q.checkpoints[jobuuid]
+		if !ok {
+			return common.Job{}, errors.New("Job does not have a checkpoint to restore from.")
+		}
+
+		q.stack[i].Status = common.STATUS_CREATED
+		q.stack[i].Error = ""
+		q.stack[i].Progress = cp.Progress
+		q.stack[i].Record(by, "restore", "Restored from the checkpoint taken at "+cp.Taken.Format(time.RFC3339))
+
+		// The keeper may have stopped if every other job was finished
+		if q.status == STATUS_EMPTY {
+			log.Debug("Keeper started")
+			q.qk = make(chan bool)
+			go q.keeper()
+
+			q.status = STATUS_RUNNING
+		}
+		q.wakeDispatch()
+
+		return q.stack[i].Clone(), nil
+	}
+
+	return common.Job{}, ErrJobNotFound
+}
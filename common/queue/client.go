@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"errors"
+	"net/rpc"
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Maximum time a call to a resource may take before it is abandoned
+var RPCTimeout = 30 * time.Second
+
+// Maximum time for calls that wait on the resource, such as starting or
+// stopping a tool or running a benchmark
+var SlowRPCTimeout = 10 * time.Minute
+
+// Number of times calls that only read from a resource are retried after a
+// timeout or connection error
+var RPCRetries = 2
+
+// Number of failed calls in a row before calls to a resource are stopped, 0
+// disables the circuit breaker
+var BreakerFailures = 3
+
+// How long calls to a resource are stopped for once the breaker is open
+var BreakerCooldown = time.Minute
+
+// Returned when a call to a resource takes longer than its timeout
+var ErrRPCTimeout = errors.New("Call to resource timed out.")
+
+// Returned without calling the resource while its circuit breaker is open
+var ErrCircuitOpen = errors.New("Resource is not responding, calls are stopped until it recovers.")
+
+// Methods that only read from the resource and can be sent again safely
+var retryMethods = map[string]bool{
+	"Queue.Ping":                 true,
+	"Queue.ResourceClock":        true,
+	"Queue.ResourceHardware":     true,
+	"Queue.ResourceInventory":    true,
+	"Queue.ResourceTools":        true,
+	"Queue.ResourceLogs":         true,
+	"Queue.ResourceBuild":        true,
+	"Queue.ResourceInterruption": true,
+	"Queue.TaskStatus":           true,
+	"Queue.TaskCheckpoint":       true,
+	"Queue.TaskDebugLog":         true,
+	"Queue.TaskResults":          true,
+	"Queue.ToolPreview":          true,
+	"Queue.ToolRequirements":     true,
+	"Queue.ToolCheckInput":       true,
+}
+
+// Methods that use the slow timeout
+var slowMethods = map[string]bool{
+	"Queue.AddTask":        true,
+	"Queue.TaskRun":        true,
+	"Queue.TaskPause":      true,
+	"Queue.TaskQuit":       true,
+	"Queue.ToolEstimate":   true,
+	"Queue.ResourceUpdate": true,
+	"Queue.RescanTools":    true,
+	"Queue.InstallBinary":  true,
+}
+
+// The RPC connection to a resource. Every call has a deadline and failed calls
+// trip a circuit breaker, so a resource that stops responding can not hold up
+// the queue.
+type ResourceClient struct {
+	client    *rpc.Client
+	name      string
+	failures  int       // Failed calls in a row
+	openUntil time.Time // Calls are stopped until this time once the breaker is open
+	mux       sync.Mutex
+}
+
+func NewResourceClient(name string, c *rpc.Client) *ResourceClient {
+	return &ResourceClient{
+		client: c,
+		name:   name,
+	}
+}
+
+// Call a method on the resource. Only errors returned by the resource itself
+// are passed back without retrying or counting towards the breaker.
+func (c *ResourceClient) Call(method string, args, reply interface{}) error {
+	if c == nil || c.client == nil {
+		return rpc.ErrShutdown
+	}
+
+	attempts := 1
+	if retryMethods[method] {
+		attempts += RPCRetries
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		// Pings check if the resource has recovered so they skip the breaker
+		if method != "Queue.Ping" && !c.allow() {
+			return ErrCircuitOpen
+		}
+
+		err = c.call(method, args, reply)
+		if _, ok := err.(rpc.ServerError); ok || err == nil {
+			c.success()
+			return err
+		}
+
+		c.failure(method, err)
+
+		// A closed connection will not come back by itself
+		if err == rpc.ErrShutdown {
+			break
+		}
+	}
+
+	return err
+}
+
+// Make a single call with the timeout for the method. The reply is decoded into
+// a new value so a call that is abandoned can not write to the reply later.
+func (c *ResourceClient) call(method string, args, reply interface{}) error {
+	timeout := RPCTimeout
+	if slowMethods[method] {
+		timeout = SlowRPCTimeout
+	}
+
+	fresh := reflect.New(reflect.TypeOf(reply).Elem())
+	call := c.client.Go(method, args, fresh.Interface(), make(chan *rpc.Call, 1))
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			return call.Error
+		}
+		reflect.ValueOf(reply).Elem().Set(fresh.Elem())
+		return nil
+	case <-expired:
+		return ErrRPCTimeout
+	}
+}
+
+func (c *ResourceClient) allow() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if BreakerFailures <= 0 || c.failures < BreakerFailures {
+		return true
+	}
+
+	// Once the cooldown is over calls are let through again, a failure will
+	// open the breaker straight away
+	return !time.Now().Before(c.openUntil)
+}
+
+func (c *ResourceClient) success() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if BreakerFailures > 0 && c.failures >= BreakerFailures {
+		log.WithField("resource", c.name).Info("Resource is responding again, circuit breaker closed.")
+	}
+	c.failures = 0
+}
+
+func (c *ResourceClient) failure(method string, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.failures++
+
+	log.WithFields(log.Fields{
+		"resource": c.name,
+		"method":   method,
+		"failures": c.failures,
+		"error":    err.Error(),
+	}).Debug("Call to resource failed.")
+
+	if BreakerFailures > 0 && c.failures >= BreakerFailures {
+		if c.failures == BreakerFailures {
+			log.WithFields(log.Fields{
+				"resource": c.name,
+				"cooldown": BreakerCooldown,
+			}).Warn("Resource is not responding, circuit breaker opened.")
+		}
+		c.openUntil = time.Now().Add(BreakerCooldown)
+	}
+}
+
+// Check if calls to the resource are currently stopped by the breaker
+func (c *ResourceClient) Tripped() bool {
+	if c == nil {
+		return false
+	}
+
+	return !c.allow()
+}
+
+func (c *ResourceClient) Close() error {
+	if c == nil || c.client == nil {
+		return rpc.ErrShutdown
+	}
+
+	return c.client.Close()
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How far the clock of a resource may be off before a warning is logged, times
+// from the resource are corrected by the offset whatever it is
+var ClockSkewWarning = 5 * time.Second
+
+// Ask a resource for its clock and record how far it is ahead of the queue, so
+// times the resource sets on jobs can be moved to the clock of the queue.
+// Resources from before clocks were measured keep an offset of 0.
+func (q *Queue) measureClock(client *ResourceClient) {
+	if client == nil {
+		return
+	}
+
+	sent := time.Now()
+	var remote time.Time
+	err := client.Call("Queue.ResourceClock", common.RPCCall{}, &remote)
+	received := time.Now()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"resource": client.name,
+			"error":    err.Error(),
+		}).Debug("Unable to read the clock of the resource.")
+		return
+	}
+
+	offset := common.ClockOffset(sent, remote, received)
+
+	q.Lock()
+	var previous time.Duration
+	var found, first bool
+	for resUUID, res := range q.pool {
+		if res.Client != client {
+			continue
+		}
+
+		previous, first = res.ClockOffset, res.ClockRead.IsZero()
+		res.ClockOffset = offset
+		res.ClockRead = received.UTC()
+		q.pool[resUUID] = res
+		found = true
+		break
+	}
+	q.Unlock()
+
+	if !found {
+		return
+	}
+
+	// Offsets move a little every time, the API only needs to see real changes
+	if first || absDuration(offset-previous) >= time.Second {
+		q.InvalidateSnapshot()
+	}
+
+	if absDuration(offset) >= ClockSkewWarning && (first || absDuration(previous) < ClockSkewWarning) {
+		log.WithFields(log.Fields{
+			"resource": client.name,
+			"offset":   offset.String(),
+		}).Warn("The clock of the resource is off from the queue, times from it will be corrected.")
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
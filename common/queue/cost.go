@@ -0,0 +1,259 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// What happens to a job that would take its project over budget
+const (
+	BUDGET_REFUSE  = "refuse"  // The job only runs on resources without a cost
+	BUDGET_CONFIRM = "confirm" // The job waits until its owner or an Administrator approves the cost
+)
+
+// Send jobs to resources without a cost per hour, such as on-prem rigs, before
+// those with one, such as cloud instances
+var CostAware bool
+
+// Dollars the jobs of each project may spend on resources with a cost per
+// hour by project name, projects not listed use the default
+var ProjectBudgets = map[string]float64{}
+
+// Budget of projects without their own, 0 does not limit them
+var DefaultBudget float64
+
+// What is done with jobs that would go over the budget of their project
+var BudgetAction = BUDGET_REFUSE
+
+// The user cost decisions are recorded as in the job history
+const COST_USER = "cost"
+
+// Returned when approving the cost of a job that does not need it
+var ErrCostNotHeld = errors.New("Job is not waiting for its cost to be approved.")
+
+// Get the budget of a project, false if it can spend without limit. Jobs
+// without a project are not limited.
+func budgetFor(project string) (float64, bool) {
+	if project == "" {
+		return 0, false
+	}
+	if b, ok := ProjectBudgets[project]; ok {
+		return b, true
+	}
+
+	return DefaultBudget, DefaultBudget > 0
+}
+
+// Get the dollars spent by each project on resources with a cost
+func (q *Queue) ProjectSpend() map[string]float64 {
+	q.RLock()
+	defer q.RUnlock()
+
+	out := make(map[string]float64, len(q.spend))
+	for project, spent := range q.spend {
+		out[project] = spent
+	}
+	return out
+}
+
+// Set the dollars an hour a resource costs while it runs jobs, 0 for free
+// capacity such as on-prem hardware
+func (q *Queue) SetResourceCost(resUUID string, perHour float64) error {
+	if perHour < 0 {
+		return errors.New("The cost of a resource cannot be negative.")
+	}
+
+	q.Lock()
+	res, ok := q.pool[resUUID]
+	if !ok {
+		q.Unlock()
+		return ErrResourceNotFound
+	}
+
+	res.CostPerHour = perHour
+	q.pool[resUUID] = res
+	q.Unlock()
+
+	q.InvalidateSnapshot()
+	q.wakeDispatch()
+
+	log.WithFields(log.Fields{
+		"resource": resUUID,
+		"cost":     perHour,
+	}).Info("Resource cost set.")
+
+	return nil
+}
+
+// Let a job held back for going over the budget of its project run anyway
+func (q *Queue) ApproveJobCost(jobuuid, by string) (common.Job, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := range q.stack {
+		if q.stack[i].UUID != jobuuid {
+			continue
+		}
+
+		if BudgetAction != BUDGET_CONFIRM || !q.overBudget[jobuuid] || q.stack[i].CostApproved {
+			return common.Job{}, ErrCostNotHeld
+		}
+
+		q.stack[i].CostApproved = true
+		q.stack[i].Record(by, "cost-approved", "Approved running over the budget of project "+q.stack[i].Project+".")
+		delete(q.overBudget, jobuuid)
+
+		log.WithFields(log.Fields{
+			"job":     jobuuid,
+			"project": q.stack[i].Project,
+			"by":      by,
+		}).Info("Job approved to run over budget.")
+
+		q.wakeDispatch()
+
+		return q.stack[i].Clone(), nil
+	}
+
+	return common.Job{}, ErrJobNotFound
+}
+
+// Order the resources jobs are sent to, those without a cost first when the
+// queue is cost aware
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) dispatchOrder() []string {
+	order := make([]string, 0, len(q.pool))
+	for resKey := range q.pool {
+		order = append(order, resKey)
+	}
+
+	if CostAware {
+		sort.SliceStable(order, func(a, b int) bool {
+			return q.pool[order[a]].CostPerHour < q.pool[order[b]].CostPerHour
+		})
+	}
+
+	return order
+}
+
+// Estimate what a job would cost to finish on a resource from its progress or
+// the run time estimated for backfill, 0 when neither is known
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) estimatedCost(j common.Job, resUUID string, perHour float64) float64 {
+	if j.Status == common.STATUS_RUNNING && j.Progress > 0 && j.Progress < 100 && !j.StartTime.IsZero() {
+		elapsed := time.Since(j.StartTime)
+		return perHour * elapsed.Hours() * (100 - j.Progress) / j.Progress
+	}
+
+	if seconds, ok := q.estimates[j.UUID].seconds[resUUID]; ok {
+		return perHour * seconds / 3600
+	}
+
+	return 0
+}
+
+// Check that starting a job on a resource keeps its project within budget.
+// Jobs that would go over are noted in their history the first time.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) budgetAllows(jobKey int, resKey string) bool {
+	perHour := q.pool[resKey].CostPerHour
+	if perHour <= 0 {
+		return true
+	}
+
+	j := q.stack[jobKey]
+	budget, ok := budgetFor(j.Project)
+	if !ok {
+		return true
+	}
+
+	spent := q.spend[j.Project]
+	cost := q.estimatedCost(j, resKey, perHour)
+	if spent+cost <= budget && spent < budget {
+		return true
+	}
+	if BudgetAction == BUDGET_CONFIRM && j.CostApproved {
+		return true
+	}
+
+	if !q.overBudget[j.UUID] {
+		q.overBudget[j.UUID] = true
+
+		detail := fmt.Sprintf("Held back from resources with a cost as project %s has spent $%.2f of its $%.2f budget.", j.Project, spent, budget)
+		if cost > 0 {
+			detail = fmt.Sprintf("Held back from resources with a cost as it is expected to cost $%.2f and project %s has spent $%.2f of its $%.2f budget.", cost, j.Project, spent, budget)
+		}
+		if BudgetAction == BUDGET_CONFIRM {
+			detail += " It waits until the cost is approved."
+		}
+		q.stack[jobKey].Record(COST_USER, "over-budget", detail)
+
+		log.WithFields(log.Fields{
+			"job":     j.UUID,
+			"project": j.Project,
+			"spent":   spent,
+			"budget":  budget,
+		}).Warn("Job would go over the budget of its project.")
+	}
+	q.debugOncef(q.stack[jobKey], "Skipped resource %s, it costs $%.2f an hour and project %s is out of budget", q.pool[resKey].Name, perHour, j.Project)
+
+	return false
+}
+
+// Add what running jobs have cost since the keeper last ran to them and their
+// project. The cost of a resource is shared by the jobs running on it.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) accrueCosts() {
+	now := time.Now()
+	running := map[string]bool{}
+	waiting := map[string]bool{}
+
+	for i := range q.stack {
+		if q.stack[i].Status == common.STATUS_CREATED {
+			waiting[q.stack[i].UUID] = true
+		}
+		if q.stack[i].Status != common.STATUS_RUNNING {
+			continue
+		}
+		res, ok := q.pool[q.stack[i].ResAssigned]
+		if !ok || res.CostPerHour <= 0 {
+			continue
+		}
+
+		jobuuid := q.stack[i].UUID
+		running[jobuuid] = true
+
+		last, ok := q.costed[jobuuid]
+		q.costed[jobuuid] = now
+		if !ok {
+			continue
+		}
+
+		tasks := q.resourceTasks(q.stack[i].ResAssigned)
+		if tasks < 1 {
+			tasks = 1
+		}
+		cost := res.CostPerHour / float64(tasks) * now.Sub(last).Hours()
+
+		q.stack[i].Cost += cost
+		if q.stack[i].Project != "" {
+			q.spend[q.stack[i].Project] += cost
+		}
+	}
+
+	// Forget jobs that are no longer running on a resource with a cost
+	for jobuuid := range q.costed {
+		if !running[jobuuid] {
+			delete(q.costed, jobuuid)
+		}
+	}
+	for jobuuid := range q.overBudget {
+		if !waiting[jobuuid] {
+			delete(q.overBudget, jobuuid)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Number of scheduling events kept for each job being debugged
+const debugEventLines = 1000
+
+// Returned when the log of a job that was not created with debugging is asked for
+var ErrJobNotDebugged = errors.New("Debugging is not enabled for the job.")
+
+// What the queue knows about a job being debugged. The events are kept in
+// memory and are lost when the queue restarts.
+type jobDebug struct {
+	events   *common.LineTail
+	seen     map[string]bool // Events already recorded since the job was last sent to a resource
+	resource common.TaskLog  // Last debug log fetched from the resource of the job
+	fetched  time.Time
+}
+
+// The debug log of a job, the decisions of the queue and, from the resource it
+// was sent to, those of the resource and the full output of the tool
+type JobLog struct {
+	Events        []string
+	Resource      common.TaskLog
+	Fetched       time.Time // When the resource log was fetched, zero if it never was
+	ResourceError string    // Why the resource log could not be fetched, the last copy is returned
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) jobDebug(jobUUID string) *jobDebug {
+	if q.debugs == nil {
+		q.debugs = map[string]*jobDebug{}
+	}
+
+	d, ok := q.debugs[jobUUID]
+	if !ok {
+		d = &jobDebug{events: common.NewLineTail(debugEventLines), seen: map[string]bool{}}
+		q.debugs[jobUUID] = d
+	}
+	return d
+}
+
+// Record a scheduling event for a job if it is being debugged
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugf(j common.Job, format string, args ...interface{}) {
+	if !j.Debug {
+		return
+	}
+
+	line := time.Now().Format(time.RFC3339) + " " + fmt.Sprintf(format, args...) + "\n"
+	q.jobDebug(j.UUID).events.Write([]byte(line))
+}
+
+// Record an event that is found on every pass of the dispatcher, such as why a
+// resource was skipped, only once until the job is sent to a resource
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugOncef(j common.Job, format string, args ...interface{}) {
+	if !j.Debug {
+		return
+	}
+
+	msg := fmt.Sprintf(format, args...)
+	d := q.jobDebug(j.UUID)
+	if d.seen[msg] {
+		return
+	}
+	d.seen[msg] = true
+
+	d.events.Write([]byte(time.Now().Format(time.RFC3339) + " " + msg + "\n"))
+}
+
+// Record that a job was handed to a resource so skipped resources are recorded
+// again if it comes back to the queue
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugDispatched(j common.Job, resUUID, hardware string) {
+	if !j.Debug {
+		return
+	}
+
+	q.jobDebug(j.UUID).seen = map[string]bool{}
+	q.debugf(j, "Sending to resource %s (%s) on %s", q.pool[resUUID].Name, resUUID, hardware)
+}
+
+// Get the debug log of a job. The log kept by the resource is fetched from it
+// each time, if that fails the copy from the last fetch is returned along with
+// the reason.
+func (q *Queue) JobLog(jobUUID string) (JobLog, error) {
+	var out JobLog
+
+	q.RLock()
+	var job common.Job
+	var found bool
+	for i := range q.stack {
+		if q.stack[i].UUID == jobUUID {
+			job = q.stack[i]
+			found = true
+		}
+	}
+	if !found {
+		q.RUnlock()
+		return out, ErrJobNotFound
+	}
+	if !job.Debug {
+		q.RUnlock()
+		return out, ErrJobNotDebugged
+	}
+
+	if d, ok := q.debugs[jobUUID]; ok {
+		out.Events = d.events.Lines(0)
+		out.Resource = d.resource
+		out.Fetched = d.fetched
+	}
+	res, ok := q.pool[job.ResAssigned]
+	q.RUnlock()
+
+	if job.ResAssigned == "" {
+		return out, nil
+	}
+	if !ok || res.Client == nil || res.Status == common.STATUS_QUIT {
+		out.ResourceError = ErrResourceOffline.Error()
+		return out, nil
+	}
+
+	var tl common.TaskLog
+	err := res.Client.Call("Queue.TaskDebugLog", common.RPCCall{Job: common.Job{UUID: jobUUID}}, &tl)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"job":      jobUUID,
+			"resource": job.ResAssigned,
+			"error":    err.Error(),
+		}).Warn("Unable to fetch job debug log from resource.")
+
+		out.ResourceError = err.Error()
+		return out, nil
+	}
+
+	out.Resource = tl
+	out.Fetched = time.Now()
+
+	q.Lock()
+	d := q.jobDebug(jobUUID)
+	d.resource = tl
+	d.fetched = out.Fetched
+	q.Unlock()
+
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package queue
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Set the defaults and locked parameters for every job of a tool. Defaults are
+// kept by tool name so they apply to the tool on every resource and survive
+// restarts of the queue. Empty defaults remove them.
+func (q *Queue) SetToolDefaults(toolName string, d common.ToolDefaults) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(d.Defaults) == 0 && len(d.Locked) == 0 {
+		delete(q.defaults, toolName)
+	} else {
+		q.defaults[toolName] = d
+	}
+
+	log.WithFields(log.Fields{
+		"tool":     toolName,
+		"defaults": len(d.Defaults),
+		"locked":   len(d.Locked),
+	}).Info("Tool defaults updated.")
+
+	if StateFileLocation != "" {
+		q.writeState()
+	}
+}
+
+// Get the defaults and locked parameters for a tool
+func (q *Queue) ToolDefaults(toolName string) common.ToolDefaults {
+	q.RLock()
+	defer q.RUnlock()
+
+	d := q.defaults[toolName]
+
+	c := common.ToolDefaults{
+		Defaults: map[string]string{},
+		Locked:   map[string]string{},
+	}
+	for k, v := range d.Defaults {
+		c.Defaults[k] = v
+	}
+	for k, v := range d.Locked {
+		c.Locked[k] = v
+	}
+
+	return c
+}
+
+// Merge the defaults for the tool of a job into its parameters
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) applyToolDefaults(j *common.Job) {
+	for _, res := range q.pool {
+		if tool, ok := res.Tools[j.ToolUUID]; ok {
+			if d, ok := q.defaults[tool.Name]; ok {
+				j.Parameters = d.Apply(j.Parameters)
+			}
+			return
+		}
+	}
+}
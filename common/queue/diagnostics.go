@@ -0,0 +1,67 @@
+package queue
+
+// Sizes of what the queue holds in memory, to find what grows on a server
+// that has been up for a long time
+type Diagnostics struct {
+	Status            string
+	Jobs              int
+	JobsByStatus      map[string]int
+	OutputRows        int // Rows of output held in memory for every job
+	OutputBytes       int // Memory used by the output tables of every job
+	PerformancePoints int
+	HistoryEvents     int
+	Resources         int
+	Released          int // Jobs force released waiting for their resource
+	Exported          int // Credentials remembered as pushed to the exporters
+	Checkpoints       int
+	Checkpointed      int
+	Outbound          int // Resources with a dispatch channel
+	Dispatching       int
+	Debugs            int
+	Estimates         int
+	Progressed        int // Running jobs watched for stalls
+	Reservations      int
+	Queues            int
+	TrackedChanges    int
+	RemovedChanges    int
+}
+
+// Count what the queue holds in memory
+func (q *Queue) Diagnostics() Diagnostics {
+	q.RLock()
+	d := Diagnostics{
+		Status:       q.status,
+		Jobs:         len(q.stack),
+		JobsByStatus: map[string]int{},
+		Resources:    len(q.pool),
+		Released:     len(q.released),
+		Checkpoints:  len(q.checkpoints),
+		Checkpointed: len(q.checkpointed),
+		Outbound:     len(q.outbound),
+		Dispatching:  len(q.dispatching),
+		Debugs:       len(q.debugs),
+		Estimates:    len(q.estimates),
+		Progressed:   len(q.progressed),
+		Reservations: len(q.reservations),
+		Queues:       len(q.queues),
+	}
+
+	for i := range q.stack {
+		d.JobsByStatus[q.stack[i].Status]++
+		d.OutputRows += q.stack[i].OutputLen()
+		d.OutputBytes += q.stack[i].Output.Size()
+		d.PerformancePoints += len(q.stack[i].PerformanceData)
+		d.HistoryEvents += len(q.stack[i].History)
+	}
+	for _, seen := range q.exported {
+		d.Exported += len(seen)
+	}
+	q.RUnlock()
+
+	q.changes.mux.Lock()
+	d.TrackedChanges = len(q.changes.jobs)
+	d.RemovedChanges = len(q.changes.removed)
+	q.changes.mux.Unlock()
+
+	return d
+}
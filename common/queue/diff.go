@@ -0,0 +1,30 @@
+package queue
+
+import (
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Compare the cracked accounts of two jobs over the same hash list, such as
+// last year's and this year's dump of a domain. All of the output of both jobs
+// is compared, including rows spilled to storage.
+func (q *Queue) DiffJobs(olderUUID, newerUUID string) (common.ResultDiff, error) {
+	return diffJobs(q, olderUUID, newerUUID)
+}
+
+func diffJobs(r Reader, olderUUID, newerUUID string) (common.ResultDiff, error) {
+	older, err := r.JobOutput(olderUUID)
+	if err != nil {
+		return common.ResultDiff{}, err
+	}
+
+	newer, err := r.JobOutput(newerUUID)
+	if err != nil {
+		return common.ResultDiff{}, err
+	}
+
+	if older.ResultKey != "" || newer.ResultKey != "" {
+		return common.ResultDiff{}, ErrResultsEncrypted
+	}
+
+	return common.DiffResults(older, newer), nil
+}
@@ -0,0 +1,325 @@
+package queue
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Number of jobs that can be sent to resources at the same time
+var DispatchWorkers = 8
+
+// Number of jobs that can wait to be sent to a single resource
+const dispatchBacklog = 64
+
+// A job waiting to be started or resumed on a resource
+type dispatchReq struct {
+	jobUUID  string
+	resUUID  string
+	hardware string // Hardware reserved on the resource for the job
+	method   string // Queue.AddTask to start the job or Queue.TaskRun to resume it
+	status   string // Status the job must still have when the call returns
+}
+
+// Let the keeper know there may be jobs to send without waiting for its timer
+func (q *Queue) wakeDispatch() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Find free hardware for jobs waiting to start or resume and hand them to the
+// outbound queue of the resource. The calls to the resources are made by the
+// dispatch workers so the lock is not held while waiting on them.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) dispatchJobs() {
+	now := time.Now()
+
+	// Resources bound to named queues only run the jobs of those queues
+	bound := q.boundQueues()
+
+	var backfill *backfillPass
+	if Backfill {
+		backfill = q.newBackfillPass(now, bound)
+	}
+
+	// Look for open resources, free ones first when the queue is cost aware
+	for _, resKey := range q.dispatchOrder() {
+		// Check that the resource is running and not being drained for an update
+		if q.pool[resKey].Status != common.STATUS_RUNNING || q.pool[resKey].Draining {
+			continue
+		}
+
+		// Exclusive resources take nothing more while they have a job and the
+		// profile in effect may limit how many jobs the resource runs at once
+		limit := 0
+		if p := common.ActiveProfile(q.pool[resKey].Profiles, now); p != nil {
+			limit = p.MaxTasks
+		}
+		if q.pool[resKey].Exclusive {
+			limit = 1
+		}
+		if limit > 0 && q.resourceTasks(resKey) >= limit {
+			continue
+		}
+
+		// Loop through hardware the resouce offers (CPU, GPU, etc.)
+	HardwareLoop:
+		for hardwareKey, hardwareFree := range q.pool[resKey].Hardware {
+			// If the hardware is free
+			if !hardwareFree {
+				continue
+			}
+
+			log.WithFields(log.Fields{
+				"resource": q.pool[resKey].Name,
+				"hardware": hardwareKey,
+			}).Debug("Found empty resource hardware")
+
+			// This resource is free, so lets find a job for it from its queues in
+			// the order their policies give
+			for _, jobKey := range q.queueOrder(bound[resKey]) {
+				if q.dispatching[q.stack[jobKey].UUID] {
+					continue
+				}
+
+				// Reserved resources only run jobs of the project they are reserved for
+				if !reservationAllows(q.reservations, resKey, q.stack[jobKey], now) {
+					q.debugOncef(q.stack[jobKey], "Skipped resource %s, it is reserved for another project", q.pool[resKey].Name)
+					continue
+				}
+
+				logger := log.WithFields(log.Fields{
+					"resource": q.pool[resKey].Name,
+					"job":      q.stack[jobKey].UUID,
+				})
+
+				// Are we looking to start or resume the job?
+				switch q.stack[jobKey].Status {
+				case common.STATUS_CREATED: // We are going to start the job fresh
+					// We first need to check if this tool exists on this resource and it has the hardware the job needs
+					tool, ok := q.pool[resKey].Tools[q.stack[jobKey].ToolUUID]
+					if !ok {
+						q.debugOncef(q.stack[jobKey], "Skipped resource %s, it does not have the tool", q.pool[resKey].Name)
+						continue
+					}
+					if tool.Requirements != hardwareKey {
+						continue
+					}
+					if !q.pool[resKey].Inventory.Satisfies(q.stack[jobKey].Constraints) {
+						q.debugOncef(q.stack[jobKey], "Skipped resource %s, it does not meet the job constraints", q.pool[resKey].Name)
+						continue
+					}
+
+					// Resources with a cost only take jobs their project can afford
+					if !q.budgetAllows(jobKey, resKey) {
+						continue
+					}
+
+					// Jobs behind the head of their queue only start if they do not delay it
+					if backfill != nil && !backfill.allows(jobKey, resKey, hardwareKey) {
+						continue
+					}
+
+					// We now know we have an open resource and a job that needs that resource
+					logger.Debug("Sending new job to resource")
+
+					// It is now possible that the Tool UUID on the resource does not match
+					// the Tool UUID the job and Queue have. This is because the Queue changes
+					// all UUIDs (keys in the pool map) to make identical Tools across multiple
+					// resources show up as one Tool available to the system. We now need to set
+					// the Job's ToolUUID field to the correct UUID for the resource if this has
+					// happened.
+					if tool.UUID != q.stack[jobKey].ToolUUID {
+						q.stack[jobKey].ToolUUID = tool.UUID
+					}
+
+					if q.dispatch(dispatchReq{q.stack[jobKey].UUID, resKey, hardwareKey, "Queue.AddTask", common.STATUS_CREATED}) {
+						q.stack[jobKey].ResAssigned = resKey
+						q.debugDispatched(q.stack[jobKey], resKey, hardwareKey)
+						break HardwareLoop
+					}
+				case common.STATUS_PAUSED: // We are going to resume the job were it is
+					// We are resuming a job so we first need to check if the job was assigned to this resource
+					if q.stack[jobKey].ResAssigned != resKey {
+						continue
+					}
+
+					// This job was assigned to this resource so we need to find the correct local UUID of the tool
+					for _, resTool := range q.pool[resKey].Tools {
+						// We have found the correct UUID, so check if this is the available hardware
+						if resTool.UUID == q.stack[jobKey].ToolUUID && resTool.Requirements == hardwareKey {
+							logger.Debug("Sending job to resource to resume.")
+
+							if q.dispatch(dispatchReq{q.stack[jobKey].UUID, resKey, hardwareKey, "Queue.TaskRun", common.STATUS_PAUSED}) {
+								q.debugDispatched(q.stack[jobKey], resKey, hardwareKey)
+								break HardwareLoop
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Count the jobs a resource is running or has being sent to it.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) resourceTasks(resUUID string) int {
+	var n int
+	for i := range q.stack {
+		if q.stack[i].ResAssigned != resUUID {
+			continue
+		}
+		if q.stack[i].Status == common.STATUS_RUNNING || q.dispatching[q.stack[i].UUID] {
+			n++
+		}
+	}
+	return n
+}
+
+// Add a job to the outbound queue of its resource and reserve the hardware for
+// it. False is returned if the resource already has a full backlog.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) dispatch(req dispatchReq) bool {
+	if q.workers == nil {
+		workers := DispatchWorkers
+		if workers < 1 {
+			workers = 1
+		}
+		q.workers = make(chan struct{}, workers)
+	}
+
+	out, ok := q.outbound[req.resUUID]
+	if !ok {
+		out = make(chan dispatchReq, dispatchBacklog)
+		q.outbound[req.resUUID] = out
+		go q.dispatchWorker(out)
+	}
+
+	select {
+	case out <- req:
+		q.dispatching[req.jobUUID] = true
+		q.pool[req.resUUID].Hardware[req.hardware] = false
+		return true
+	default:
+		log.WithField("resource", req.resUUID).Warn("Resource dispatch backlog is full.")
+		return false
+	}
+}
+
+// Send the jobs of a single resource in order, sharing the workers with every
+// other resource. The queue should NOT be locked.
+func (q *Queue) dispatchWorker(out chan dispatchReq) {
+	for req := range out {
+		q.workers <- struct{}{}
+		q.sendJob(req)
+		<-q.workers
+	}
+}
+
+// Make the call that starts or resumes a job and put the job the resource
+// returns on the stack. The queue should NOT be locked.
+func (q *Queue) sendJob(req dispatchReq) {
+	logger := log.WithFields(log.Fields{
+		"job":      req.jobUUID,
+		"resource": req.resUUID,
+		"method":   req.method,
+	})
+
+	q.RLock()
+	var call common.RPCCall
+	var found bool
+	for i := range q.stack {
+		if q.stack[i].UUID == req.jobUUID {
+			call.Job = q.stack[i].ForResource()
+			found = true
+		}
+	}
+	if cp, ok := q.checkpoints[req.jobUUID]; ok && req.method == "Queue.AddTask" {
+		call.Checkpoint = &cp
+	}
+	client := q.pool[req.resUUID].Client
+	q.RUnlock()
+
+	// Listing the shared files may take a moment so the queue is not locked
+	if req.method == "Queue.AddTask" {
+		call.Files = sharedFileURLs()
+	}
+
+	var reply common.Job
+	err := ErrJobNotFound
+	if found {
+		logger.Debug("Sending job to resource.")
+		err = client.Call(req.method, call, &reply)
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	delete(q.dispatching, req.jobUUID)
+
+	i := -1
+	for k := range q.stack {
+		if q.stack[k].UUID == req.jobUUID {
+			i = k
+		}
+	}
+
+	if err != nil {
+		// Something failed so let's mark the job as failed and let another job use the hardware
+		logger.WithField("error", err.Error()).Error("Error while attempting to send job to remote resource.")
+		q.freeHardware(req)
+		if i >= 0 && q.stack[i].Status == req.status {
+			q.debugf(q.stack[i], "Resource %s refused %s: %s", req.resUUID, req.method, err.Error())
+			q.stack[i].Status = common.STATUS_FAILED
+			q.stack[i].Error = err.Error()
+		}
+		q.wakeDispatch()
+		return
+	}
+
+	// The job was stopped or removed while it was being sent, so quit the task
+	// on the resource once the keeper reaches it
+	if i < 0 || q.stack[i].Status != req.status {
+		logger.Info("Job was stopped while being sent to resource, it will be quit.")
+		reply.ResAssigned = req.resUUID
+		q.released[req.jobUUID] = reply
+		q.freeHardware(req)
+		q.wakeDispatch()
+		return
+	}
+
+	keepQueueData(&reply, q.stack[i])
+	reply.NormalizeClock(q.pool[req.resUUID].ClockOffset)
+	reply.ResAssigned = req.resUUID
+	q.stack[i] = reply
+	q.debugf(reply, "Resource %s accepted %s, the job is %s", req.resUUID, req.method, reply.Status)
+
+	logger.Debug("Job sent to resource.")
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) freeHardware(req dispatchReq) {
+	if res, ok := q.pool[req.resUUID]; ok && res.Hardware != nil {
+		res.Hardware[req.hardware] = true
+	}
+}
+
+// Check that at least one resource that has not quit provides the tool
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) hasTool(toolUUID string) bool {
+	for r := range q.pool {
+		if q.pool[r].Status == common.STATUS_QUIT {
+			continue
+		}
+
+		if _, ok := q.pool[r].Tools[toolUUID]; ok {
+			return true
+		}
+	}
+
+	return false
+}
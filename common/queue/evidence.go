@@ -0,0 +1,19 @@
+package queue
+
+// Record in the audit trail of a job that an evidence bundle of it was
+// exported, so every bundle shows the ones exported before it
+func (q *Queue) RecordEvidence(jobuuid, by, keyID string) error {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := range q.stack {
+		if q.stack[i].UUID != jobuuid {
+			continue
+		}
+
+		q.stack[i].Record(by, "evidence", "Evidence bundle exported and signed with key "+keyID+".")
+		return nil
+	}
+
+	return ErrJobNotFound
+}
@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How long the hashes and results of a finished job are kept before they are
+// purged, 0 keeps them until the job is removed
+var DefaultExpiry time.Duration
+
+// Expiry for the jobs of each project by project name, such as when an
+// engagement requires client data to be deleted within a set time
+var ProjectExpiry = map[string]time.Duration{}
+
+// Parameters of jobs holding the inputs purged when a job expires
+var SensitiveParameters = []string{"hashes"}
+
+// The user purges are recorded as in the job history
+const EXPIRY_USER = "expiry"
+
+// Get the expiry of a project
+func expiryFor(project string) time.Duration {
+	if d, ok := ProjectExpiry[project]; ok {
+		return d
+	}
+
+	return DefaultExpiry
+}
+
+// Note when jobs finish and purge the sensitive inputs of those finished
+// longer than the expiry of their project. Only the statistics of a purged job
+// are kept, such as how many hashes were cracked.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) expireInputs() {
+	now := time.Now()
+	for i := range q.stack {
+		j := &q.stack[i]
+
+		if !common.IsDone(j.Status) {
+			// Jobs started again are timed from when they finish again
+			j.Finished = time.Time{}
+			continue
+		}
+		if j.Finished.IsZero() {
+			// Jobs are seen on the first keeper pass after they finish
+			j.Finished = now
+		}
+
+		expiry := expiryFor(j.Project)
+		if !j.Purged.IsZero() || expiry <= 0 || now.Sub(j.Finished) < expiry {
+			continue
+		}
+
+		q.purgeJob(j, expiry)
+	}
+}
+
+// Remove the hash list, results and everything kept from them for a job
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) purgeJob(j *common.Job, expiry time.Duration) {
+	for _, p := range SensitiveParameters {
+		delete(j.Parameters, p)
+	}
+
+	rows := j.OutputSpilled + j.OutputLen()
+	j.OutputData = nil
+	j.Output = nil
+	j.OutputSpilled = 0
+	j.Usernames = nil
+	j.NTHashes = nil
+
+	delete(q.checkpoints, j.UUID)
+	delete(q.checkpointed, j.UUID)
+	delete(q.exported, j.UUID)
+	delete(q.debugs, j.UUID)
+
+	// Storage may be remote so the keeper does not wait on it
+	go removeJobData(j.UUID)
+
+	j.Purged = time.Now()
+	j.Record(EXPIRY_USER, "purged", fmt.Sprintf("Hash list and %d rows of results removed %s after the job finished.", rows, expiry))
+
+	log.WithFields(log.Fields{
+		"job":      j.UUID,
+		"owner":    j.Owner,
+		"project":  j.Project,
+		"finished": j.Finished,
+		"expiry":   expiry,
+		"rows":     rows,
+	}).Info("Sensitive inputs of job purged.")
+}
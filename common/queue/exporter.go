@@ -0,0 +1,149 @@
+package queue
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"strings"
+	"time"
+)
+
+// A cracked credential pushed to exporters
+type Credential struct {
+	JobID     string
+	JobName   string
+	Owner     string
+	Username  string // Empty when the job was not submitted with usernames
+	Hash      string
+	Plaintext string
+	Cracked   time.Time // When the queue first saw the credential
+}
+
+/* The Exporter interface is used to push newly cracked credentials to other
+ * tools for analysis. Exporters are given the credentials found each time the
+ * queue keeper runs, outside of the queue lock, so they may take their time.
+ */
+type Exporter interface {
+	//SystemName returns the string used in logs and the configuration
+	SystemName() string
+	//Export pushes the credentials to the external tool
+	Export(creds []Credential) error
+}
+
+// Add an exporter to receive cracked credentials
+func (q *Queue) AddExporter(e Exporter) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.exporters = append(q.exporters, e)
+}
+
+// Find the credentials cracked since the last keeper run and push them to
+// every exporter in the background
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) exportCracked() {
+	if len(q.exporters) == 0 {
+		return
+	}
+
+	var creds []Credential
+	current := map[string]bool{}
+	for i := range q.stack {
+		creds = append(creds, q.newCredentials(q.stack[i])...)
+		current[q.stack[i].UUID] = true
+	}
+
+	// Forget about deleted jobs
+	for id := range q.exported {
+		if !current[id] {
+			delete(q.exported, id)
+		}
+	}
+
+	if len(creds) == 0 {
+		return
+	}
+
+	for _, e := range q.exporters {
+		go func(e Exporter) {
+			err := e.Export(creds)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"exporter": e.SystemName(),
+					"count":    len(creds),
+					"error":    err.Error(),
+				}).Error("Unable to export cracked credentials.")
+				return
+			}
+
+			log.WithFields(log.Fields{
+				"exporter": e.SystemName(),
+				"count":    len(creds),
+			}).Debug("Exported cracked credentials.")
+		}(e)
+	}
+}
+
+// Get the credentials of a job that have not been exported yet and mark them
+// as exported
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) newCredentials(j common.Job) []Credential {
+	// The output of the LM phase is only halves of LM passwords, and the
+	// queue cannot read results encrypted for the key of the job
+	if len(j.NTHashes) > 0 || j.ResultKey != "" {
+		return nil
+	}
+
+	titles, rows := j.JoinUsernames()
+
+	userCol, plainCol, hashCol := -1, -1, -1
+	for i, t := range titles {
+		switch {
+		case t == common.UsernameTitle:
+			userCol = i
+		case t == "Plaintext":
+			plainCol = i
+		case hashCol < 0 && strings.Contains(t, "Hash"):
+			hashCol = i
+		}
+	}
+
+	// Only jobs that crack hashes have anything to export
+	if plainCol < 0 || hashCol < 0 {
+		return nil
+	}
+
+	seen, ok := q.exported[j.UUID]
+	if !ok {
+		seen = map[string]bool{}
+		q.exported[j.UUID] = seen
+	}
+
+	var creds []Credential
+	for _, row := range rows {
+		if len(row) != len(titles) {
+			continue
+		}
+
+		c := Credential{
+			JobID:     j.UUID,
+			JobName:   j.Name,
+			Owner:     j.Owner,
+			Hash:      row[hashCol],
+			Plaintext: row[plainCol],
+			Cracked:   time.Now(),
+		}
+		if userCol >= 0 {
+			c.Username = row[userCol]
+		}
+
+		key := c.Username + ":" + c.Hash
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		creds = append(creds, c)
+	}
+
+	return creds
+}
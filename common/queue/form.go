@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// The user parameter migrations are recorded as in the job history
+const FORM_USER = "form"
+
+// Translate the parameters of a job made with an older form of its tool, such
+// as from a template or a cloned job, to the form the tool has now
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) migrateParameters(j *common.Job) error {
+	for _, res := range q.pool {
+		tool, ok := res.Tools[j.ToolUUID]
+		if !ok {
+			continue
+		}
+
+		from := j.FormVersion
+		params, err := tool.MigrateParameters(from, j.Parameters)
+		if err != nil {
+			return err
+		}
+
+		j.Parameters = params
+		j.FormVersion = tool.FormVersion
+		if from != 0 && from != tool.FormVersion {
+			j.Record(FORM_USER, "migrated", fmt.Sprintf("Parameters translated from version %d to version %d of the %s form.", from, tool.FormVersion, tool.Name))
+
+			log.WithFields(log.Fields{
+				"job":  j.UUID,
+				"tool": tool.Name,
+				"from": from,
+				"to":   tool.FormVersion,
+			}).Info("Job parameters migrated to the current tool form.")
+		}
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How often resources are asked if their cloud provider is taking them back,
+// 0 disables it. Providers give between 30 seconds and two minutes of notice.
+var InterruptionInterval = 10 * time.Second
+
+// The user interruptions are recorded as in the job history
+const INTERRUPTION_USER = "interruption"
+
+// Ask resources for notice that their spot or preemptible instance is being
+// taken back and move the jobs of those that are to other resources
+func (q *Queue) checkInterruptions() {
+	q.RLock()
+	clients := map[string]*ResourceClient{}
+	for resUUID, res := range q.pool {
+		if res.Status != common.STATUS_QUIT && res.Client != nil {
+			clients[resUUID] = res.Client
+		}
+	}
+	q.RUnlock()
+
+	for resUUID, client := range clients {
+		var notice common.Interruption
+		err := client.Call("Queue.ResourceInterruption", common.RPCCall{}, &notice)
+		if err != nil {
+			// Resources from before interruptions were watched do not have the call
+			log.WithFields(log.Fields{
+				"resource": resUUID,
+				"error":    err.Error(),
+			}).Debug("Unable to check resource for an interruption notice.")
+			continue
+		}
+
+		if notice.Noticed.IsZero() {
+			continue
+		}
+
+		q.evacuateResource(resUUID, notice)
+	}
+}
+
+// Save the restore points sent with an interruption notice, queue the jobs on
+// the resource again so they continue from them elsewhere, and remove the
+// resource before its instance is gone
+func (q *Queue) evacuateResource(resUUID string, notice common.Interruption) {
+	q.Lock()
+	res, ok := q.pool[resUUID]
+	if !ok || res.Status == common.STATUS_QUIT {
+		q.Unlock()
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"resource": res.Name,
+		"provider": notice.Provider,
+		"deadline": notice.Deadline,
+	}).Warn("Cloud provider is taking back resource, moving its jobs to other resources.")
+
+	for i := range q.stack {
+		if q.stack[i].ResAssigned != resUUID {
+			continue
+		}
+		if q.stack[i].Status != common.STATUS_RUNNING && q.stack[i].Status != common.STATUS_PAUSED {
+			continue
+		}
+		jobuuid := q.stack[i].UUID
+
+		if cp, ok := notice.Checkpoints[jobuuid]; ok {
+			q.checkpoints[jobuuid] = cp
+		}
+
+		err := q.callJob(res.Client, "Queue.TaskQuit", i)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"job":   jobuuid,
+				"error": err.Error(),
+			}).Warn("Unable to quit interrupted job on resource.")
+			q.released[jobuuid] = q.stack[i].Clone()
+		}
+
+		q.stack[i].Status = common.STATUS_CREATED
+		q.stack[i].Error = ""
+		q.stack[i].Stalled = time.Time{}
+
+		detail := "Queued again as the " + notice.Provider + " instance of resource " + res.Name + " is being taken back."
+		if cp, ok := q.checkpoints[jobuuid]; ok {
+			q.stack[i].Progress = cp.Progress
+			detail += " It continues from the checkpoint taken at " + cp.Taken.Format(time.RFC3339) + "."
+		}
+		q.stack[i].Record(INTERRUPTION_USER, "requeue", detail)
+		q.debugf(q.stack[i], "%s", detail)
+
+		log.WithField("job", jobuuid).Info("Interrupted job queued again.")
+	}
+	q.Unlock()
+
+	// The manager of the resource forgets it and cleans up its instance
+	var err error
+	if mgr := q.managerOf(resUUID); mgr != nil {
+		err = mgr.DeleteResource(resUUID)
+	} else {
+		err = q.RemoveResource(resUUID)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"resource": res.Name,
+			"error":    err.Error(),
+		}).Error("Unable to remove interrupted resource.")
+	}
+
+	q.wakeDispatch()
+}
+
+// Find the resource manager a resource was added by
+func (q *Queue) managerOf(resUUID string) ResourceManager {
+	for _, mgr := range q.AllResourceManagers() {
+		for _, id := range mgr.GetManagedResources() {
+			if id == resUUID {
+				return mgr
+			}
+		}
+	}
+
+	return nil
+}
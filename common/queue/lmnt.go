@@ -0,0 +1,61 @@
+package queue
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/ntds"
+)
+
+// Output columns of a job once its NT hashes have been cracked
+var lmntTitles = []string{"Plaintext", "NT Hash", "LM Password"}
+
+var lmntMetrics = []common.Metric{
+	common.PlaintextColumn,
+	{Name: "nthash", Title: "NT Hash", Kind: common.METRIC_HASH},
+	{Name: "lmpassword", Title: "LM Password", Kind: common.METRIC_PLAINTEXT},
+}
+
+// Use the passwords of finished LM jobs to crack the NT hashes of the same
+// accounts. LM passwords are upper case so every combination of case is
+// tried against the NT hash to find the real password.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) finishLMJobs() {
+	for i := range q.stack {
+		j := &q.stack[i]
+		if len(j.NTHashes) == 0 || !common.IsDone(j.Status) {
+			continue
+		}
+
+		var lms []string
+		var total int64
+		for lm, nts := range j.NTHashes {
+			lms = append(lms, lm)
+			total += int64(len(nts))
+		}
+
+		var rows [][]string
+		for lm, plain := range ntds.CrackedLM(j.OutputRows(), lms) {
+			for _, nt := range j.NTHashes[lm] {
+				if password, ok := ntds.ToggleCase(plain, nt); ok {
+					rows = append(rows, []string{password, nt, plain})
+				}
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"job":     j.UUID,
+			"lm":      len(lms),
+			"cracked": len(rows),
+		}).Info("Cracked NT hashes from the LM results.")
+
+		j.OutputTitles = lmntTitles
+		j.OutputMetrics = lmntMetrics
+		j.OutputData = nil
+		j.Output = common.NewOutputTable(rows)
+		j.CrackedHashes = int64(len(rows))
+		j.TotalHashes = total
+
+		// The workflow is complete so the job is only done once
+		j.NTHashes = nil
+	}
+}
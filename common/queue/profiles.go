@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Set the time of day profiles of a resource. The first profile that applies
+// is used, with none the resource runs without limits. Tasks already running
+// keep the workload they were started with.
+func (q *Queue) SetResourceProfiles(resUUID string, profiles []common.Profile) error {
+	for _, p := range profiles {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+
+	q.Lock()
+	res, ok := q.pool[resUUID]
+	if !ok {
+		q.Unlock()
+		return ErrResourceNotFound
+	}
+
+	res.Profiles = append([]common.Profile(nil), profiles...)
+	// A profile may have changed without changing its name
+	res.profileSynced = false
+	q.pool[resUUID] = res
+	q.Unlock()
+
+	q.InvalidateSnapshot()
+	q.wakeDispatch()
+
+	log.WithFields(log.Fields{
+		"resource": resUUID,
+		"profiles": len(profiles),
+	}).Info("Resource profiles set.")
+
+	return nil
+}
+
+// Get the name of the profile that applies to a resource now, empty for none
+func (r Resource) ActiveProfile(t time.Time) string {
+	if p := common.ActiveProfile(r.Profiles, t); p != nil {
+		return p.Name
+	}
+	return ""
+}
+
+// Give each connected resource the profile that applies now when it is not the
+// one it was last given. Resources that do not know about profiles are only
+// asked once per connection.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) applyProfiles() {
+	now := time.Now()
+
+	for resKey, res := range q.pool {
+		if res.Client == nil || (res.Status != common.STATUS_RUNNING && res.Status != common.STATUS_PAUSED) {
+			continue
+		}
+
+		p := common.ActiveProfile(res.Profiles, now)
+		var name string
+		if p != nil {
+			name = p.Name
+		}
+		if res.profileSynced && res.profile == name {
+			continue
+		}
+
+		var applied bool
+		err := res.Client.Call("Queue.SetProfile", common.RPCCall{Profile: p}, &applied)
+		if err != nil && !strings.Contains(err.Error(), "can't find method") {
+			log.WithFields(log.Fields{
+				"resource": resKey,
+				"profile":  name,
+				"error":    err.Error(),
+			}).Warn("Unable to give the resource its profile, it will be tried again.")
+			continue
+		}
+
+		if err != nil {
+			log.WithField("resource", resKey).Debug("Resource does not support profiles.")
+		} else if res.profile != name {
+			log.WithFields(log.Fields{
+				"resource": resKey,
+				"profile":  name,
+			}).Info("Resource profile changed.")
+		}
+
+		res.profile = name
+		res.profileSynced = true
+		q.pool[resKey] = res
+	}
+}
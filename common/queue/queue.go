@@ -7,11 +7,15 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/emperorcow/protectedmap"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/resource"
 	"github.com/pborman/uuid"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/rpc"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -24,38 +28,110 @@ const (
 	STATUS_EXHAUSTED = "Exhausted"
 )
 
+// Returned when a job UUID is not on the stack
+var ErrJobNotFound = errors.New("Job does not exist!")
+
+// Returned when a resource UUID is not in the pool
+var ErrResourceNotFound = errors.New("Resource with UUID provided does not exist!")
+
+// Returned when no resource provides the tool for a job
+var ErrNoTool = errors.New("Tool did not exist for jobs provided.")
+
 var KeeperDuration time.Duration
 var NetworkTimeout time.Duration
 var StateFileLocation string
+var MaxJobRuntime time.Duration
+
+// Optional SOCKS5 or HTTP proxy URL used when connecting to resources
+var ResourceProxy string
+
+// Default bandwidth limit in bytes per second for each resource, 0 is unlimited
+var ResourceBandwidth int64
+
+// How often the inventory of each resource is gathered again, so wordlists
+// added after it connected are found. 0 only gathers it on connect.
+var InventoryInterval = 15 * time.Minute
 
 type Queue struct {
-	status   string // Empty, Running, Paused, Exhausted
-	pool     ResourcePool
-	stack    []common.Job
-	managers protectedmap.ProtectedMap
-	stats    Stats
+	status       string // Empty, Running, Paused, Exhausted
+	pool         ResourcePool
+	stack        []common.Job
+	managers     protectedmap.ProtectedMap
+	stats        Stats
+	released     map[string]common.Job          // Jobs force released while their resource was unreachable
+	rollout      *UpdateRollout                 // Current or most recent rolling resource update
+	defaults     map[string]common.ToolDefaults // Parameters applied to jobs by tool name
+	exporters    []Exporter                     // Tools cracked credentials are pushed to
+	exported     map[string]map[string]bool     // Credentials of each job already pushed to the exporters
+	checkpoints  map[string]common.Checkpoint   // Last restore point of each job
+	checkpointed map[string]time.Time           // When each running job was last checkpointed
+	outbound     map[string]chan dispatchReq    // Jobs waiting to be sent to each resource
+	dispatching  map[string]bool                // Jobs waiting on or being sent to a resource
+	workers      chan struct{}                  // Limits the jobs being sent at once
+	wake         chan struct{}                  // Runs the dispatcher without waiting for the keeper timer
+	snapshots    *snapshotCache                 // Tools and resources for API reads
+	changes      *changeTracker                 // Sequence of the last change to each job
+	reservations []Reservation                  // Resources blocked out for the jobs of a project
+	stateErr     error                          // Why the state file could not be written last time
+	stateErrAt   time.Time                      // When writing the state file started failing
+	backupErr    error                          // Why the last backup failed
+	backupErrAt  time.Time                      // When backups started failing
+	progressed   map[string]progressMark        // When the progress of each running job last moved
+	debugs       map[string]*jobDebug           // Scheduling events of jobs being debugged
+	queues       []NamedQueue                   // Named queues and the resources bound to them
+	estimates    map[string]jobEstimate         // Run times of jobs on each resource for backfill
+	estimating   bool                           // Set while the run times of jobs are estimated
+	spend        map[string]float64             // Dollars spent by each project on resources with a cost
+	costed       map[string]time.Time           // When the cost of each running job was last added
+	timed        map[string]time.Time           // When the run time of each running job was last added
+	overBudget   map[string]bool                // Jobs held back for going over the budget of their project
+	referrers    []JobReferrer                  // Asked before jobs are deleted and told after
 	sync.RWMutex
 	qk chan bool
 }
 
 type StateFile struct {
-	Stack []common.Job `json:"stack"`
-	Pool  ResourcePool `json:"pool"`
+	Stack        []common.Job                   `json:"stack"`
+	Pool         ResourcePool                   `json:"pool"`
+	Defaults     map[string]common.ToolDefaults `json:"defaults"`
+	Stats        map[string]*ToolStats          `json:"stats"`
+	Checkpoints  map[string]common.Checkpoint   `json:"checkpoints"`
+	Reservations []Reservation                  `json:"reservations"`
+	Queues       []NamedQueue                   `json:"queues"`
+	Spend        map[string]float64             `json:"spend"`
+	Released     map[string]common.Job          `json:"released,omitempty"`
 }
 
-func NewQueue(statefile string, updatetime int, timeout int) Queue {
+func NewQueue(statefile string, updatetime int, timeout int, maxruntime int) Queue {
 	//Setup the options
 	StateFileLocation = statefile
 	KeeperDuration = time.Duration(updatetime) * time.Second
 	NetworkTimeout = time.Duration(timeout) * time.Second
+	MaxJobRuntime = time.Duration(maxruntime) * time.Minute
 
 	// Build the queue
 	q := Queue{
-		status:   STATUS_EMPTY,
-		pool:     NewResourcePool(),
-		stack:    []common.Job{},
-		managers: protectedmap.New(),
-		stats:    NewStats(),
+		status:       STATUS_EMPTY,
+		pool:         NewResourcePool(),
+		stack:        []common.Job{},
+		managers:     protectedmap.New(),
+		stats:        NewStats(),
+		released:     map[string]common.Job{},
+		defaults:     map[string]common.ToolDefaults{},
+		exported:     map[string]map[string]bool{},
+		checkpoints:  map[string]common.Checkpoint{},
+		checkpointed: map[string]time.Time{},
+		outbound:     map[string]chan dispatchReq{},
+		dispatching:  map[string]bool{},
+		debugs:       map[string]*jobDebug{},
+		estimates:    map[string]jobEstimate{},
+		spend:        map[string]float64{},
+		costed:       map[string]time.Time{},
+		timed:        map[string]time.Time{},
+		overBudget:   map[string]bool{},
+		wake:         make(chan struct{}, 1),
+		snapshots:    &snapshotCache{},
+		changes:      newChangeTracker(),
 	}
 
 	if _, err := os.Stat(StateFileLocation); err == nil {
@@ -66,22 +142,103 @@ func NewQueue(statefile string, updatetime int, timeout int) Queue {
 		"statefile":  StateFileLocation,
 		"keepertime": KeeperDuration,
 		"nettimeout": NetworkTimeout,
+		"maxruntime": MaxJobRuntime,
 	}).Debug("Setup a new queue")
 
 	return q
 }
 
+// Check that the queue is initialized and that its state file can be written
+func (q *Queue) Ready() error {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.pool == nil || q.stack == nil {
+		return errors.New("Queue has not been initialized.")
+	}
+
+	if StateFileLocation == "" {
+		return nil
+	}
+
+	if q.stateErr != nil {
+		return q.stateErr
+	}
+
+	// Open an existing state file without truncating it, otherwise make sure
+	// the directory it will be created in exists
+	if _, err := os.Stat(StateFileLocation); err == nil {
+		f, err := os.OpenFile(StateFileLocation, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	info, err := os.Stat(filepath.Dir(StateFileLocation))
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("State file directory is not a directory.")
+	}
+
+	return nil
+}
+
 func (q *Queue) writeState() error {
+	err := q.encodeState()
+	if err != nil {
+		if q.stateErr == nil {
+			q.stateErrAt = time.Now()
+		}
+		q.stateErr = err
+
+		log.WithField("error", err.Error()).Error("Unable to write to state file")
+		return err
+	}
+
+	if q.stateErr != nil {
+		log.Info("State file is being written again.")
+	}
+	q.stateErr = nil
+
+	log.Debug("State file written successfully.")
+
+	return nil
+}
+
+func (q *Queue) encodeState() error {
 	var s StateFile
 
-	//Create a state fila in case we are rebooted
-	stateFile, err := os.Create(StateFileLocation)
+	//Create a state fila in case we are rebooted. It is written to a
+	//temporary file first so the read replica never sees part of one.
+	stateFile, err := ioutil.TempFile(filepath.Dir(StateFileLocation), ".state-")
 	if err != nil {
-		log.WithField("error", err.Error()).Fatal("Unable to write to state file")
 		return err
 	}
+	defer os.Remove(stateFile.Name())
 	stateEncoder := json.NewEncoder(stateFile)
 
+	s = q.state()
+
+	if err := stateEncoder.Encode(s); err != nil {
+		stateFile.Close()
+		return err
+	}
+
+	if err := stateFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(stateFile.Name(), StateFileLocation)
+}
+
+// Get the state written to the state file
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) state() StateFile {
+	var s StateFile
+
 	s.Stack = make([]common.Job, len(q.stack))
 	copy(s.Stack, q.stack)
 
@@ -90,12 +247,24 @@ func (q *Queue) writeState() error {
 		s.Pool[k] = v
 	}
 
-	stateEncoder.Encode(s)
-	stateFile.Close()
+	s.Defaults = q.defaults
+	s.Stats = q.stats.Tools
+	s.Checkpoints = q.checkpoints
+	s.Reservations = q.reservations
+	s.Queues = q.queues
+	s.Spend = q.spend
+	s.Released = q.released
 
-	log.Debug("State file written successfully.")
+	return s
+}
 
-	return nil
+// Get since when and why the state file cannot be written, a nil error when
+// the last write worked
+func (q *Queue) StateError() (time.Time, error) {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.stateErrAt, q.stateErr
 }
 
 func (q *Queue) parseState() error {
@@ -127,112 +296,281 @@ func (q *Queue) parseState() error {
 		for tool, _ := range v.Tools {
 			delete(v.Tools, tool)
 		}
+		v.Throttle = common.NewThrottle(v.bandwidth())
 
 		q.pool[id] = v
 	}
+	for name, d := range s.Defaults {
+		q.defaults[name] = d
+	}
+	for name, ts := range s.Stats {
+		q.stats.Tools[name] = ts
+	}
+	for jobuuid, cp := range s.Checkpoints {
+		q.checkpoints[jobuuid] = cp
+	}
+	for project, spent := range s.Spend {
+		q.spend[project] = spent
+	}
+	// Tasks force released before the restart are still quit once their
+	// resource is back
+	for jobuuid, j := range s.Released {
+		q.released[jobuuid] = j
+	}
+	q.reservations = s.Reservations
+	q.queues = s.Queues
 	for i, _ := range s.Stack {
 		log.WithFields(log.Fields{
 			"name": s.Stack[i].Name,
 			"id":   s.Stack[i].UUID,
 		}).Debug("Added job from state file.")
 		s.Stack[i].Status = common.STATUS_QUIT
+		s.Stack[i].CompactOutput() // State files from before the table was kept
+		q.stats.Skip(s.Stack[i].UUID)
+		q.newCredentials(s.Stack[i]) // Exported before the restart
 		q.stack = append(q.stack, s.Stack[i])
 	}
 
 	return nil
 }
 
-// Add a job to the queue at the end of the stack
+// Add a job to the queue at the end of the stack. The job is sent to a resource
+// by the dispatcher so this does not wait on any resource.
 func (q *Queue) AddJob(j common.Job) error {
 	logger := log.WithFields(log.Fields{
 		"jobid":   j.UUID,
 		"jobname": j.Name,
 	})
 
+	// The tool may need more hardware than the job asked for
+	q.addToolRequirements(&j)
+
 	// Lock the queue for the adding work
 	q.Lock()
 	defer q.Unlock()
 
 	logger.Debug("Queue locked.")
 
+	// Make sure there is a resource that can run the job
+	if !q.hasTool(j.ToolUUID) {
+		return ErrNoTool
+	}
+	if err := q.checkJobQueue(j); err != nil {
+		return err
+	}
+
+	// Parameters made with an older form of the tool are translated to the
+	// form it has now
+	if err := q.migrateParameters(&j); err != nil {
+		return err
+	}
+
+	// Administrators can set parameters for every job of a tool
+	q.applyToolDefaults(&j)
+
+	// Sensitive jobs wait for a second Administrator
+	holdForApproval(&j)
+
 	// Add job to stack
 	q.stack = append(q.stack, j)
-	jobIndex := len(q.stack) - 1
 	logger.Debug("job added to stack.")
 
 	// Add stats
 	// TODO: Add more stats
 	q.stats.IncJob()
 
-	// Check if the Queue was empty
-	if q.status == STATUS_EMPTY {
-		logger.Debug("Queue is empty, job needs starting.")
-		// The Queue is empty so we need to start this job and the keeper
+	// Drafts wait until they are started and sensitive jobs until they are
+	// approved
+	if j.Status == common.STATUS_DRAFT || j.Status == common.STATUS_APPROVAL {
+		return nil
+	}
 
-		// Start the keeper
+	// Check if the Queue was empty and start the keeper
+	if q.status == STATUS_EMPTY {
 		logger.Debug("Keeper started")
 		q.qk = make(chan bool)
 		go q.keeper()
 
 		// We have started the keeper so change the status
 		q.status = STATUS_RUNNING
+	}
 
-		// Find the first open resource
-		for i, _ := range q.pool {
-			logger.WithField("resource", q.pool[i].Name).Debug("Looking for resource.")
+	q.wakeDispatch()
+
+	return nil
+}
 
-			// Make sure this resource isn't paused
-			if q.pool[i].Status == common.STATUS_PAUSED || q.pool[i].Status == common.STATUS_QUIT {
-				continue
+// Changes to a job that is still a draft, empty fields are left as they are
+type DraftEdit struct {
+	Name       string
+	Parameters map[string]string   // Replace all of the parameters
+	Usernames  map[string][]string // Split from the hashes of the new parameters, replaced along with them
+	NTHashes   map[string][]string // Split from the hashes of the new parameters, replaced along with them
+	MaxRuntime time.Duration
+}
+
+// Update the name, parameters and maximum runtime of a job that is still a draft
+func (q *Queue) UpdateDraftJob(jobuuid string, edit DraftEdit) error {
+	log.WithField("job", jobuuid).Debug("Attempting to update draft job.")
+
+	// New parameters may change the hardware the job needs
+	name, params, maxruntime := edit.Name, edit.Parameters, edit.MaxRuntime
+	var required common.Constraints
+	if params != nil {
+		draft := common.Job{UUID: jobuuid, Parameters: params}
+		q.RLock()
+		for i, _ := range q.stack {
+			if q.stack[i].UUID == jobuuid {
+				draft.ToolUUID = q.stack[i].ToolUUID
 			}
+		}
+		q.RUnlock()
 
-			// See if the tool exist on this resource
-			tool, ok := q.pool[i].Tools[j.ToolUUID]
-			if ok {
-				logger.WithFields(log.Fields{
-					"resource": q.pool[i].Name,
-					"tool":     tool.Name,
-				}).Debug("Tool exists on resource.")
-
-				// It is now possible that the job about to be assigned to the resource
-				// actually has a Tool UUID different than the one the Queue has for this
-				// tool. This is because the Queue changes the UUID if it has two identical
-				// tools. We need to set the Job's ToolUUID to the real UUID of the tool
-				// if this is the case
-				if tool.UUID != j.ToolUUID {
-					log.WithField("toolid", tool.UUID).Debug("Changed tool UUID to match resource.")
-					j.ToolUUID = tool.UUID
-				}
+		q.addToolRequirements(&draft)
+		required = draft.Constraints
+	}
 
-				// Tool exist, lets start the job on this resource and assign the resource to the job
-				j.ResAssigned = i
-				addJob := common.RPCCall{Job: j}
+	q.Lock()
+	defer q.Unlock()
 
-				logger.Debug("Queue.AddTask RPC call started.")
-				err := q.pool[i].Client.Call("Queue.AddTask", addJob, &j)
-				if err != nil {
-					logger.WithField("error", err.Error()).Error("There was a problem making an RPC call.")
-					q.DeleteJobFromStackByIndex(jobIndex)
-					return err
-				}
+	for i, _ := range q.stack {
+		if q.stack[i].UUID == jobuuid {
+			if q.stack[i].Status != common.STATUS_DRAFT {
+				return errors.New("Only draft jobs can be edited. Current status is " + q.stack[i].Status)
+			}
 
-				// Update the job in the stack
-				q.stack[jobIndex] = j
+			if name != "" {
+				q.stack[i].Name = name
+			}
+			if params != nil {
+				q.stack[i].Parameters = params
+				q.stack[i].Usernames = edit.Usernames
+				q.stack[i].NTHashes = edit.NTHashes
+				q.applyToolDefaults(&q.stack[i])
+				q.stack[i].Constraints = q.stack[i].Constraints.Merge(required)
+			}
+			if maxruntime > 0 {
+				q.stack[i].MaxRuntime = maxruntime
+			}
 
-				// Note the resources as being used
-				q.pool[i].Hardware[tool.Requirements] = false
+			return nil
+		}
+	}
 
-				// We should be done so return no errors
-				return nil
+	return ErrJobNotFound
+}
+
+// Hand a job to another owner, recording who made the change
+func (q *Queue) TransferJob(jobuuid, owner, by string) (common.Job, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	for i, _ := range q.stack {
+		if q.stack[i].UUID == jobuuid {
+			previous := q.stack[i].Owner
+			q.stack[i].Owner = owner
+			q.stack[i].Record(by, "owner", "Ownership transferred from "+previous+" to "+owner)
+
+			log.WithFields(log.Fields{
+				"job":  jobuuid,
+				"from": previous,
+				"to":   owner,
+				"by":   by,
+			}).Info("Job ownership transferred.")
+
+			return q.stack[i].Clone(), nil
+		}
+	}
+
+	return common.Job{}, ErrJobNotFound
+}
+
+// Launch a draft job so it will be started by the keeper when a resource is free
+func (q *Queue) StartJob(jobuuid string) error {
+	log.WithField("job", jobuuid).Info("Attempting to start draft job.")
+
+	q.Lock()
+	defer q.Unlock()
+
+	for i, _ := range q.stack {
+		if q.stack[i].UUID == jobuuid {
+			if q.stack[i].Status != common.STATUS_DRAFT {
+				return errors.New("Only draft jobs can be started. Current status is " + q.stack[i].Status)
 			}
 
-			// Tool did not exist... return error
-			return errors.New("Tool did not exist for jobs provided.")
+			q.stack[i].Status = common.STATUS_CREATED
+			holdForApproval(&q.stack[i])
+
+			// If only drafts have been added the keeper was never started
+			if q.status == STATUS_EMPTY {
+				log.Debug("Keeper started")
+				q.qk = make(chan bool)
+				go q.keeper()
+
+				q.status = STATUS_RUNNING
+			}
+			q.wakeDispatch()
+
+			return nil
 		}
 	}
 
-	// If the queue is running or paused all we need to have done is add it to the queue
-	return nil
+	return ErrJobNotFound
+}
+
+// Add several jobs to the end of the stack at once. Either every job is added or
+// none are, the returned slice has an error for each job that could not be added.
+func (q *Queue) AddJobs(jobs []common.Job) ([]error, error) {
+	log.WithField("count", len(jobs)).Debug("Attempting to add a batch of jobs.")
+
+	for i := range jobs {
+		q.addToolRequirements(&jobs[i])
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	// Check that a tool is available for every job before adding any
+	errs := make([]error, len(jobs))
+	var failed bool
+	for i := range jobs {
+		if !q.hasTool(jobs[i].ToolUUID) {
+			errs[i] = ErrNoTool
+			failed = true
+		} else if err := q.checkJobQueue(jobs[i]); err != nil {
+			errs[i] = err
+			failed = true
+		} else if err := q.migrateParameters(&jobs[i]); err != nil {
+			errs[i] = err
+			failed = true
+		}
+	}
+
+	if failed {
+		return errs, errors.New("One or more jobs in the batch were invalid, no jobs were added.")
+	}
+
+	for i := range jobs {
+		q.applyToolDefaults(&jobs[i])
+		holdForApproval(&jobs[i])
+	}
+
+	q.stack = append(q.stack, jobs...)
+	for range jobs {
+		q.stats.IncJob()
+	}
+
+	// The keeper will start the jobs as resources become free
+	if q.status == STATUS_EMPTY {
+		log.Debug("Keeper started")
+		q.qk = make(chan bool)
+		go q.keeper()
+
+		q.status = STATUS_RUNNING
+	}
+	q.wakeDispatch()
+
+	return errs, nil
 }
 
 func (q *Queue) DeleteJobFromStackByIndex(idx int) {
@@ -242,14 +580,20 @@ func (q *Queue) DeleteJobFromStackByIndex(idx int) {
 	q.stack = append(tmp[:idx], tmp[idx+1:]...)
 }
 
-// Get the full queue stack
+// Get a copy of the full queue stack
 func (q *Queue) AllJobs() []common.Job {
 	log.Debug("Gathering all jobs from queue.")
 
-	q.Lock()
+	q.RLock()
+	defer q.RUnlock()
 
-	q.Unlock()
-	return q.stack
+	// The keeper updates jobs in place so callers get their own copies
+	jobs := make([]common.Job, len(q.stack))
+	for i := range q.stack {
+		jobs[i] = q.stack[i].Clone()
+	}
+
+	return jobs
 }
 
 // Get a list of all jobs assigned to a resource
@@ -267,18 +611,32 @@ func (q *Queue) AllJobsByResource(resourceid string) []common.Job {
 }
 
 // Get one specific job
-func (q *Queue) JobInfo(jobUUID string) common.Job {
+func (q *Queue) JobInfo(jobUUID string) (common.Job, error) {
 	log.WithField("job", jobUUID).Debug("Gathering information on job.")
-	q.Lock()
-	defer q.Unlock()
+	q.RLock()
+	defer q.RUnlock()
 
-	for _, job := range q.stack {
-		if job.UUID == jobUUID {
-			return job
+	for i := range q.stack {
+		if q.stack[i].UUID == jobUUID {
+			return q.stack[i].Clone(), nil
 		}
 	}
 
-	return common.Job{}
+	return common.Job{}, ErrJobNotFound
+}
+
+// Check if a job is on the stack
+func (q *Queue) JobExists(jobUUID string) bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	for i := range q.stack {
+		if q.stack[i].UUID == jobUUID {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (q *Queue) PauseJob(jobuuid string) error {
@@ -297,9 +655,7 @@ func (q *Queue) PauseJob(jobuuid string) error {
 			// We have found the job so lets see if it running
 			if q.stack[i].Status == common.STATUS_RUNNING {
 				// Job is running so lets tell it to pause
-				pauseJob := common.RPCCall{Job: q.stack[i]}
-
-				err := q.pool[q.stack[i].ResAssigned].Client.Call("Queue.TaskPause", pauseJob, &q.stack[i])
+				err := q.callJob(q.pool[q.stack[i].ResAssigned].Client, "Queue.TaskPause", i)
 				log.WithField("job", jobuuid).Debug("Calling Queue.TaskPause on remote resource.")
 				if err != nil {
 					log.WithFields(log.Fields{
@@ -330,7 +686,7 @@ func (q *Queue) PauseJob(jobuuid string) error {
 	}
 
 	// We didn't find the job so return an error
-	return errors.New("Job does not exist!")
+	return ErrJobNotFound
 }
 
 func (q *Queue) QuitJob(jobuuid string) error {
@@ -347,13 +703,19 @@ func (q *Queue) QuitJob(jobuuid string) error {
 				"status": q.stack[i].Status,
 			}).Debug("Job found in queue.")
 
-			// We have found the job so lets check that it isn't already done
+			// Drafts and jobs that have not started are not running on a resource
+			// so just mark them as quit. Jobs still being sent are quit once they
+			// start.
 			s := q.stack[i].Status
-			if s != common.STATUS_DONE && s != common.STATUS_FAILED && s != common.STATUS_QUIT {
-				// Lets build the call to stop the job
-				quitJob := common.RPCCall{Job: q.stack[i]}
+			if s == common.STATUS_DRAFT || s == common.STATUS_CREATED || s == common.STATUS_APPROVAL {
+				q.stack[i].Status = common.STATUS_QUIT
+				return nil
+			}
 
-				err := q.pool[q.stack[i].ResAssigned].Client.Call("Queue.TaskQuit", quitJob, &q.stack[i])
+			// We have found the job so lets check that it isn't already done
+			if s != common.STATUS_DONE && s != common.STATUS_FAILED && s != common.STATUS_QUIT && s != common.STATUS_EXPIRED {
+				// Lets tell the resource to stop the job
+				err := q.callJob(q.pool[q.stack[i].ResAssigned].Client, "Queue.TaskQuit", i)
 				log.WithField("job", jobuuid).Debug("Attempting to call Queue.TaskQuit on remote resource.")
 				if err != nil {
 					log.WithFields(log.Fields{
@@ -384,64 +746,219 @@ func (q *Queue) QuitJob(jobuuid string) error {
 	}
 
 	// No job was found so return error
-	return errors.New("Job does not exist!")
+	return ErrJobNotFound
 }
 
-func (q *Queue) RemoveJob(jobuuid string) error {
-	log.WithField("job", jobuuid).Debug("Attempting to remove job")
+// Force a job to be quit on the queue side even if the resource running it can't
+// be reached. Hardware assigned to the job is freed and the task is quit on the
+// resource the next time the keeper can reach it.
+func (q *Queue) ForceQuitJob(jobuuid string) error {
+	log.WithField("job", jobuuid).Warn("Attempting to force quit job.")
+
 	q.Lock()
+	defer q.Unlock()
+
+	return q.forceQuitJob(jobuuid)
+}
 
-	// Loop through and find the job
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) forceQuitJob(jobuuid string) error {
 	for i, _ := range q.stack {
-		if q.stack[i].UUID == jobuuid {
-			log.WithFields(log.Fields{
-				"job":    jobuuid,
-				"status": q.stack[i].Status,
-			}).Debug("Job found in queue.")
+		if q.stack[i].UUID != jobuuid {
+			continue
+		}
 
-			// We have the job so check to make sure it isn't running
-			s := q.stack[i].Status
-			if s == common.STATUS_RUNNING {
-				// Quit the job
-				q.Unlock()
-				err := q.QuitJob(jobuuid)
-				q.Lock()
+		s := q.stack[i].Status
+		if s != common.STATUS_RUNNING && s != common.STATUS_PAUSED {
+			// Nothing is held on a resource so just mark it as quit if it isn't done
+			if !common.IsDone(s) {
+				q.stack[i].Status = common.STATUS_QUIT
+			}
+			return nil
+		}
+
+		res, ok := q.pool[q.stack[i].ResAssigned]
+		if ok {
+			// Try a normal quit first but don't let an error stop the release
+			if res.Status != common.STATUS_QUIT && res.Client != nil {
+				err := q.callJob(res.Client, "Queue.TaskQuit", i)
 				if err != nil {
-					q.Unlock()
-					return err
+					log.WithFields(log.Fields{
+						"job":   jobuuid,
+						"error": err.Error(),
+					}).Warn("Unable to quit job on resource, it will be quit when the resource returns.")
+					q.released[jobuuid] = q.stack[i].Clone()
+				}
+			} else {
+				q.released[jobuuid] = q.stack[i].Clone()
+			}
+
+			// Free the hardware the job was using
+			for _, tool := range res.Tools {
+				if tool.UUID == q.stack[i].ToolUUID {
+					res.Hardware[tool.Requirements] = true
 				}
 			}
+		}
+
+		q.stack[i].Status = common.STATUS_QUIT
+		q.stack[i].Error = "Job was force released by an administrator."
 
-			// Job should now be quit so lets rebuild the stack
-			newStack := []common.Job{}
-			for _, v := range q.stack {
-				if v.UUID != jobuuid {
-					newStack = append(newStack, v)
+		return nil
+	}
+
+	return ErrJobNotFound
+}
+
+// Quit tasks on resources that were force released while the resource was
+// unreachable. A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) reconcileReleased() {
+	for jobuuid, job := range q.released {
+		res, ok := q.pool[job.ResAssigned]
+		if !ok {
+			// The resource was removed so there is nothing left to quit
+			delete(q.released, jobuuid)
+			continue
+		}
+
+		// A resource that reconnected after the queue restarted has a new
+		// UUID so it is found by its name
+		if res.Status == common.STATUS_QUIT || res.Client == nil {
+			for _, v := range q.pool {
+				if v.Name == res.Name && v.Status != common.STATUS_QUIT && v.Client != nil {
+					res = v
+					break
 				}
 			}
+		}
+
+		if res.Status == common.STATUS_QUIT || res.Client == nil {
+			continue
+		}
+
+		var reply common.Job
+		err := res.Client.Call("Queue.TaskQuit", common.RPCCall{Job: job.ForResource()}, &reply)
+		if err != nil && err.Error() != resource.ERROR_NO_TASK {
+			log.WithFields(log.Fields{
+				"job":      jobuuid,
+				"resource": job.ResAssigned,
+				"error":    err.Error(),
+			}).Debug("Unable to quit released job on resource yet.")
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"job":      jobuuid,
+			"resource": job.ResAssigned,
+		}).Info("Released job has been quit on its resource.")
+		delete(q.released, jobuuid)
+	}
+}
+
+// Force a job to be removed from the stack even if the resource running it
+// can't be reached. Referrers are not asked but are still told it was deleted.
+func (q *Queue) ForceRemoveJob(jobuuid string) error {
+	log.WithField("job", jobuuid).Warn("Attempting to force remove job.")
+
+	q.Lock()
+	err := q.forceQuitJob(jobuuid)
+	if err != nil {
+		q.Unlock()
+		return err
+	}
+
+	newStack := []common.Job{}
+	for _, v := range q.stack {
+		if v.UUID != jobuuid {
+			newStack = append(newStack, v)
+		}
+	}
+	q.stack = newStack
+	q.forgetJob(jobuuid)
+	q.Unlock()
+
+	q.notifyJobDeleted(jobuuid)
+	return nil
+}
+
+// Remove a job from the stack, quitting it first if it is on a resource. The
+// job is kept when a referrer still needs it, see JobReferrer.
+func (q *Queue) RemoveJob(jobuuid string) error {
+	log.WithField("job", jobuuid).Debug("Attempting to remove job")
 
-			// Rest stack
-			q.stack = newStack
+	q.Lock()
+
+	// Referrers are asked under the same lock the job is deleted with, so none
+	// of them can start needing it in between
+	i, err := q.removableJob(jobuuid)
+	if err != nil {
+		q.Unlock()
+		return err
+	}
+
+	// We have the job so check to make sure it isn't held on a resource
+	s := q.stack[i].Status
+	if s == common.STATUS_RUNNING || s == common.STATUS_PAUSED {
+		// Quit the job
+		q.Unlock()
+		err := q.QuitJob(jobuuid)
+		if err != nil {
+			return err
+		}
 
-			// Stack has been cleaned so return no errors
+		// The lock was let go to quit the job so it is found and checked again
+		q.Lock()
+		if _, err := q.removableJob(jobuuid); err != nil {
 			q.Unlock()
-			return nil
+			return err
+		}
+	}
+
+	// Job should now be quit so lets rebuild the stack
+	newStack := []common.Job{}
+	for _, v := range q.stack {
+		if v.UUID != jobuuid {
+			newStack = append(newStack, v)
 		}
 	}
 
+	// Rest stack
+	q.stack = newStack
+	q.forgetJob(jobuuid)
+
+	// Stack has been cleaned so return no errors
 	q.Unlock()
-	return errors.New("Job not found.")
+	q.notifyJobDeleted(jobuuid)
+	return nil
+}
+
+// Find a job on the stack that no referrer needs any more
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) removableJob(jobuuid string) (int, error) {
+	for i, _ := range q.stack {
+		if q.stack[i].UUID == jobuuid {
+			log.WithFields(log.Fields{
+				"job":    jobuuid,
+				"status": q.stack[i].Status,
+			}).Debug("Job found in queue.")
+
+			return i, q.checkJobReferences(q.stack[i])
+		}
+	}
+
+	return -1, ErrJobNotFound
 }
 
 func (q *Queue) PauseResource(resUUID string) error {
 	log.WithField("resource", resUUID).Debug("Attempting to pause resource")
+	defer q.InvalidateSnapshot()
 
 	q.Lock()
 	defer q.Unlock()
 
 	// Check for UUID existance
 	if _, ok := q.pool[resUUID]; !ok {
-		return errors.New("Resource with UUID provided does not exist!")
+		return ErrResourceNotFound
 	}
 
 	// Loop through and pause any tasks running on the selected resource
@@ -454,9 +971,7 @@ func (q *Queue) PauseResource(resUUID string) error {
 
 		if q.stack[i].ResAssigned == resUUID && q.stack[i].Status == common.STATUS_RUNNING {
 			// We found a task that is running so lets pause it
-			pauseJob := common.RPCCall{Job: q.stack[i]}
-
-			err := q.pool[resUUID].Client.Call("Queue.TaskPause", pauseJob, &q.stack[i])
+			err := q.callJob(q.pool[resUUID].Client, "Queue.TaskPause", i)
 			if err != nil {
 				return err
 			}
@@ -485,13 +1000,14 @@ func (q *Queue) PauseResource(resUUID string) error {
 
 func (q *Queue) ResumeResource(resUUID string) error {
 	log.WithField("resource", resUUID).Debug("Attempting to resume resource.")
+	defer q.InvalidateSnapshot()
 
 	q.Lock()
 	defer q.Unlock()
 
 	// Check for UUID existance
 	if _, ok := q.pool[resUUID]; !ok {
-		return errors.New("Resource with UUID provided does not exist!")
+		return ErrResourceNotFound
 	}
 
 	if q.pool[resUUID].Status != common.STATUS_PAUSED {
@@ -543,10 +1059,8 @@ func (q *Queue) PauseQueue() []error {
 			resuuid := q.stack[i].ResAssigned
 
 			// This task is running and needs to be paused
-			pauseJob := common.RPCCall{Job: q.stack[i]}
-
 			joblog.Debug("Calling Queue.TaskPause on job")
-			err := q.pool[resuuid].Client.Call("Queue.TaskPause", pauseJob, &q.stack[i])
+			err := q.callJob(q.pool[resuuid].Client, "Queue.TaskPause", i)
 			if err != nil {
 				// Note the error but now mark the job as Failed
 				// This is a definied way of dealing with this to avoid complicated error handling
@@ -581,10 +1095,10 @@ func (q *Queue) PauseQueue() []error {
 func (q *Queue) ResumeQueue() {
 	log.Debug("Attempting to restart the queue")
 
-	if q.status == STATUS_PAUSED {
-		q.Lock()
-		defer q.Unlock()
+	q.Lock()
+	defer q.Unlock()
 
+	if q.status == STATUS_PAUSED {
 		// Change status and start the keeper
 		q.status = STATUS_RUNNING
 		q.qk = make(chan bool)
@@ -685,12 +1199,9 @@ func (q *Queue) Quit() []common.Job {
 		s := q.stack[i].Status
 
 		// If the job is running quit it
-		if s == common.STATUS_RUNNING && s == common.STATUS_PAUSED {
-			// Build the quit call
-			quitJob := common.RPCCall{Job: q.stack[i]}
-
+		if s == common.STATUS_RUNNING || s == common.STATUS_PAUSED {
 			joblog.Debug("Quiting tasks")
-			err := q.pool[q.stack[i].ResAssigned].Client.Call("Queue.TaskQuit", quitJob, &q.stack[i])
+			err := q.callJob(q.pool[q.stack[i].ResAssigned].Client, "Queue.TaskQuit", i)
 			// Log any errors but we don't care from a flow perspective
 			if err != nil {
 				log.Error(err.Error())
@@ -716,24 +1227,82 @@ func (q *Queue) Quit() []common.Job {
 func (q *Queue) keeper() {
 	log.Debug("Starting keeper loop.")
 	go func() {
+		// Setup timer for keeper
+		kTimer := time.After(KeeperDuration)
+
+		// Interruption notices are checked more often than the keeper runs
+		// as providers only give a short warning
+		var iTimer <-chan time.Time
+		if InterruptionInterval > 0 {
+			iTimer = time.After(InterruptionInterval)
+		}
+
 	keeperLoop:
 		for {
-			// Setup timer for keeper
-			kTimer := time.After(KeeperDuration)
-
 			select {
 			case <-kTimer:
+				kTimer = time.After(KeeperDuration)
 				log.Info("Updating queue status and keeping jobs.")
 
 				// Run all resource manager keep routines
 				q.KeepAllResourceManagers()
 
+				// Pick up wordlists and rules added to resources
+				q.refreshInventories()
+
+				// Install the current tool binaries on resources missing them
+				q.syncBinaries()
+
+				// Estimate the run times of waiting jobs for backfill
+				q.refreshEstimates()
+
 				// Get lock
 				q.Lock()
 
 				// Update all running jobs
 				q.updateQueue()
 
+				// Add what running jobs cost to their projects
+				q.accrueCosts()
+
+				// Add up how long jobs have been running
+				q.accrueRunTime()
+
+				// Forget reservations that have ended
+				q.expireReservations()
+
+				// Give resources the workload profile for the time of day
+				q.applyProfiles()
+
+				// Save restore points of long running jobs
+				q.syncCheckpoints()
+
+				// Mark jobs that stopped making progress and queue them again
+				q.watchJobs()
+
+				// Quit any jobs that have been running longer than they are allowed
+				q.expireJobs()
+
+				// Quit tasks that were force released once their resource is reachable
+				q.reconcileReleased()
+
+				// Crack the NT hashes of finished LM jobs
+				q.finishLMJobs()
+
+				// Count finished jobs in the tool usage stats
+				q.recordStats()
+
+				// Push newly cracked credentials to the exporters
+				q.exportCracked()
+
+				// Downsample performance data and cap the output held in
+				// memory once it has been exported
+				q.retainOutput()
+
+				// Purge the hashes and results of jobs past the expiry of
+				// their project
+				q.expireInputs()
+
 				// Quit jobs without a tool in the current resource list
 				for j := range q.stack {
 					var foundTool bool
@@ -781,99 +1350,22 @@ func (q *Queue) keeper() {
 					q.writeState()
 				}
 
-				// Look for open resources
-				// ResourceLoop:
-				for resKey, _ := range q.pool {
-					// Check that the resource is running
-					if q.pool[resKey].Status == common.STATUS_RUNNING {
-						// Loop through hardware the resouce offers (CPU, GPU, etc.)
-					HardwareLoop:
-						for hardwareKey, hardwareFree := range q.pool[resKey].Hardware {
-							// If the hardware is free
-							if hardwareFree {
-								log.WithFields(log.Fields{
-									"resource": q.pool[resKey].Name,
-									"hardware": hardwareKey,
-								}).Debug("Found empty resource hardware")
-
-								// This resource is free, so lets find a job for it
-							JobLoop:
-								for jobKey, _ := range q.stack {
-									logger := log.WithFields(log.Fields{
-										"resource": q.pool[resKey].Name,
-										"job":      q.stack[jobKey].UUID,
-									})
-
-									// Are we looking to start or resume the job?
-									switch q.stack[jobKey].Status {
-									case common.STATUS_CREATED: // We are going to start the job fresh
-										// We first need to check if this tool exists on this resource
-										if tool, ok := q.pool[resKey].Tools[q.stack[jobKey].ToolUUID]; ok {
-											// We now need to get the hardware requirements for this tool
-											if q.pool[resKey].Tools[q.stack[jobKey].ToolUUID].Requirements == hardwareKey {
-												// We now know we have an open resource and a job that needs that resource
-												logger.Debug("Attempting to start new job on resource")
-
-												// It is now possible that the Tool UUID on the resource does not match
-												// the Tool UUID the job and Queue have. This is because the Queue changes
-												// all UUIDs (keys in the pool map) to make identical Tools across multiple
-												// resources show up as one Tool available to the system. We now need to set
-												// the Job's ToolUUID field to the correct UUID for the resource if this has
-												// happened.
-												if tool.UUID != q.stack[jobKey].ToolUUID {
-													q.stack[jobKey].ToolUUID = tool.UUID
-												}
-
-												logger.Debug("Calling Queue.AddTask to start the job.")
-												err := q.pool[resKey].Client.Call("Queue.AddTask", common.RPCCall{Job: q.stack[jobKey]}, &q.stack[jobKey])
-												if err != nil {
-													// Something failed so let's mark the job as failed
-													logger.WithField("error", err.Error()).Error("Error while attempting to start job on remote resource.")
-													q.stack[jobKey].Status = common.STATUS_FAILED
-													continue JobLoop
-												}
-
-												// Job has been started so mark the hardware as in use and assign the resource ID
-												q.stack[jobKey].ResAssigned = resKey
-												q.pool[resKey].Hardware[hardwareKey] = false
-												break HardwareLoop
-											}
-										}
-									case common.STATUS_PAUSED: // We are going to resume the job were it is
-										// We are resuming a job so we first need to check if the job was assigned to this resource
-										if q.stack[jobKey].ResAssigned == resKey {
-											// This job was assigned to this resource so we need to find the correct local UUID of the tool
-											for _, resTool := range q.pool[resKey].Tools {
-												if resTool.UUID == q.stack[jobKey].ToolUUID {
-													// We have found the correct UUID, so check if this is the available hardware
-													if resTool.Requirements == hardwareKey {
-														// The job requires the hardware that is available on this resource to resume
-														logger.Debug("Attempting to resume job.")
-
-														err := q.pool[resKey].Client.Call("Queue.TaskRun", common.RPCCall{Job: q.stack[jobKey]}, &q.stack[jobKey])
-														if err != nil {
-															// Something failed so let's mark the job as failed
-															logger.WithField("error", err.Error()).Error("Error while attempting to resume job on remote resource.")
-															q.stack[jobKey].Status = common.STATUS_FAILED
-															continue JobLoop
-														}
-
-														// Job has been started so mark the hardware as in use
-														q.pool[resKey].Hardware[hardwareKey] = false
-														break HardwareLoop
-													}
-												}
-											}
-										}
-									}
-								}
-							}
-						}
-					}
-				}
+				// Send jobs to resources with free hardware
+				q.dispatchJobs()
 
 				// Release the Lock
 				q.Unlock()
+
+				// Update the tools and resources read by the API
+				q.refreshSnapshot()
+			case <-iTimer:
+				iTimer = time.After(InterruptionInterval)
+				q.checkInterruptions()
+			case <-q.wake:
+				// New jobs or free hardware do not have to wait for the timer
+				q.Lock()
+				q.dispatchJobs()
+				q.Unlock()
 			case <-q.qk:
 				log.Debug("Keeper has been quit.")
 				break keeperLoop
@@ -883,34 +1375,186 @@ func (q *Queue) keeper() {
 	}()
 }
 
+// Make an RPC call for the job at index i of the stack and replace it with the
+// job the resource returns. The reply is decoded into a new job because gob
+// leaves fields that were sent as zero values untouched, which would keep
+// stale data from the old job.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) callJob(client *ResourceClient, method string, i int) error {
+	var j common.Job
+
+	err := client.Call(method, common.RPCCall{Job: q.stack[i].ForResource()}, &j)
+	if err != nil {
+		return err
+	}
+	j.NormalizeClock(q.pool[q.stack[i].ResAssigned].ClockOffset)
+
+	keepQueueData(&j, q.stack[i])
+	keepOutput(&j, q.stack[i])
+
+	q.stack[i] = j
+	return nil
+}
+
+// The owner, history, usernames, NT hashes, spilled output, stall state, debug
+// flag, named queue, expiry and cost are managed by the queue and may have changed since the resource was given the job
+func keepQueueData(j *common.Job, from common.Job) {
+	j.Owner = from.Owner
+	j.History = from.History
+	j.Usernames = from.Usernames
+	j.NTHashes = from.NTHashes
+	j.OutputSpilled = from.OutputSpilled
+	j.ExtraArgs = from.ExtraArgs
+	j.Env = from.Env
+	j.Project = from.Project
+	j.Stalled = from.Stalled
+	j.Debug = from.Debug
+	j.Queue = from.Queue
+	j.Finished = from.Finished
+	j.Purged = from.Purged
+	j.Cost = from.Cost
+	j.CostApproved = from.CostApproved
+	j.ResultKey = from.ResultKey
+	j.Sensitive = from.Sensitive
+	j.SubmittedBy = from.SubmittedBy
+	j.ApprovedBy = from.ApprovedBy
+	j.FormVersion = from.FormVersion
+	j.RunTime = from.RunTime
+}
+
 // This is an internal function used to update the status of all Jobs.
 // A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
 func (q *Queue) updateQueue() {
-	// Loop through jobs and get the status of running jobs
+	// Ask for the status of every running job at once so a slow resource only
+	// delays the keeper by its timeout. Each call only replaces its own job.
+	var running []int
+	var wg sync.WaitGroup
 	for i, _ := range q.stack {
 		if q.stack[i].Status == common.STATUS_RUNNING {
-			// Build status update call
-			jobStatus := common.RPCCall{Job: q.stack[i]}
+			running = append(running, i)
+
+			wg.Add(1)
+			go func(i int, client *ResourceClient) {
+				defer wg.Done()
+
+				// Get the latest status of the job
+				err := q.callJob(client, "Queue.TaskStatus", i)
+				// we care about the errors, but only from a logging perspective
+				if err != nil {
+					log.WithField("rpc error", err.Error()).Error("Error during RPC call.")
+				}
+			}(i, q.pool[q.stack[i].ResAssigned].Client)
+		}
+	}
+	wg.Wait()
+
+	for _, i := range running {
+		// Check if this is now no longer running
+		if q.stack[i].Status != common.STATUS_RUNNING {
+			// Release the resources from this change
+			log.WithField("JobID", q.stack[i].UUID).Debug("Job has finished.")
+			var hw string
+			for _, v := range q.pool[q.stack[i].ResAssigned].Tools {
+				if v.UUID == q.stack[i].ToolUUID {
+					hw = v.Requirements
+				}
+			}
+			q.pool[q.stack[i].ResAssigned].Hardware[hw] = true
+		}
+	}
+}
+
+// This is an internal function used to add the time running jobs have run
+// since they were last seen to their RunTime. Tools start the time of a job
+// again when it is resumed, so time is only counted from when a job started or
+// was last seen running, whichever is later, and time spent paused is not.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) accrueRunTime() {
+	now := time.Now()
+	running := map[string]bool{}
+
+	for i, _ := range q.stack {
+		if q.stack[i].Status != common.STATUS_RUNNING || q.stack[i].StartTime.IsZero() {
+			continue
+		}
+
+		jobuuid := q.stack[i].UUID
+		running[jobuuid] = true
+
+		last, ok := q.timed[jobuuid]
+		if !ok || q.stack[i].StartTime.After(last) {
+			last = q.stack[i].StartTime
+		}
+		q.timed[jobuuid] = now
 
-			err := q.pool[q.stack[i].ResAssigned].Client.Call("Queue.TaskStatus", jobStatus, &q.stack[i])
-			// we care about the errors, but only from a logging perspective
+		if now.After(last) {
+			q.stack[i].RunTime += now.Sub(last)
+		}
+	}
+
+	// Forget jobs that are no longer running
+	for jobuuid := range q.timed {
+		if !running[jobuuid] {
+			delete(q.timed, jobuuid)
+		}
+	}
+}
+
+// This is an internal function used to quit any running job that has exceeded
+// its maximum runtime. The job's own MaxRuntime is used if it was set, otherwise
+// the queue wide MaxJobRuntime applies. A zero value for both means no limit.
+// The time a job has run is its RunTime, so pausing it does not reset it.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) expireJobs() {
+	for i, _ := range q.stack {
+		if q.stack[i].Status != common.STATUS_RUNNING {
+			continue
+		}
+
+		maxRuntime := q.stack[i].MaxRuntime
+		if maxRuntime <= 0 {
+			maxRuntime = MaxJobRuntime
+		}
+
+		if maxRuntime <= 0 || q.stack[i].RunTime < maxRuntime {
+			continue
+		}
+
+		joblog := log.WithFields(log.Fields{
+			"job":        q.stack[i].UUID,
+			"resource":   q.stack[i].ResAssigned,
+			"maxruntime": maxRuntime,
+		})
+		joblog.Info("Job has exceeded its maximum runtime and will be expired.")
+
+		// Stop the task on the remote resource, which may have been removed
+		// since the job started
+		res, ok := q.pool[q.stack[i].ResAssigned]
+		if ok && res.Client != nil {
+			err := q.callJob(res.Client, "Queue.TaskQuit", i)
 			if err != nil {
-				log.WithField("rpc error", err.Error()).Error("Error during RPC call.")
+				joblog.WithField("error", err.Error()).Error("An error occurred while trying to quit an expired job.")
 			}
+		} else {
+			joblog.Warn("Resource of the expired job is gone, it was not quit on the resource.")
+		}
 
-			// Check if this is now no longer running
-			if q.stack[i].Status != common.STATUS_RUNNING {
-				// Release the resources from this change
-				log.WithField("JobID", q.stack[i].UUID).Debug("Job has finished.")
-				var hw string
-				for _, v := range q.pool[q.stack[i].ResAssigned].Tools {
-					if v.UUID == q.stack[i].ToolUUID {
-						hw = v.Requirements
-					}
-				}
-				q.pool[q.stack[i].ResAssigned].Hardware[hw] = true
+		q.stack[i].Status = common.STATUS_EXPIRED
+		q.stack[i].Error = "Job exceeded the maximum runtime of " + maxRuntime.String() + "."
+
+		if !ok {
+			continue
+		}
+
+		// Release the hardware the job was using
+		// Find the real ToolUUID since the Job's might have changed (See AddJob)
+		var hw string
+		for _, v := range res.Tools {
+			if v.UUID == q.stack[i].ToolUUID {
+				hw = v.Requirements
 			}
 		}
+		res.Hardware[hw] = true
 	}
 }
 
@@ -938,6 +1582,11 @@ func (q *Queue) ActiveTools() map[string]common.Tool {
 	q.RLock()
 	defer q.RUnlock()
 
+	return q.activeTools()
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) activeTools() map[string]common.Tool {
 	// Cycle through all the attached resources for unique tools
 	var tools = make(map[string]common.Tool)
 	for _, res := range q.pool {
@@ -958,6 +1607,171 @@ func (q *Queue) ActiveTools() map[string]common.Tool {
 	return tools
 }
 
+// Preview the candidates a tool would generate from sample words using any
+// active resource that provides the tool
+func (q *Queue) ToolPreview(toolUUID string, params map[string]string, words []string) ([]string, error) {
+	var candidates []string
+
+	q.RLock()
+	var client *ResourceClient
+	var resToolUUID string
+	for _, res := range q.pool {
+		if res.Status == common.STATUS_QUIT {
+			continue
+		}
+
+		for key, t := range res.Tools {
+			if key == toolUUID || t.UUID == toolUUID {
+				client = res.Client
+				resToolUUID = t.UUID
+				break
+			}
+		}
+
+		if client != nil {
+			break
+		}
+	}
+	q.RUnlock()
+
+	if client == nil {
+		return candidates, errors.New("Tool did not exist on any active resource.")
+	}
+
+	// The preview is run without the queue locked as it may take some time
+	call := common.RPCCall{
+		Job: common.Job{
+			ToolUUID:   resToolUUID,
+			Parameters: params,
+		},
+		Words: words,
+		Files: sharedFileURLs(),
+	}
+
+	err := client.Call("Queue.ToolPreview", call, &candidates)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"tool":  toolUUID,
+			"error": err.Error(),
+		}).Error("Unable to preview tool.")
+		return candidates, err
+	}
+
+	return candidates, nil
+}
+
+// Get the last lines of the log of a resource or the output of a task on it
+func (q *Queue) ResourceLogs(resUUID, jobUUID string, lines int) ([]string, error) {
+	q.RLock()
+	res, ok := q.pool[resUUID]
+	q.RUnlock()
+
+	if !ok {
+		return []string{}, ErrResourceNotFound
+	}
+
+	if res.Status == common.STATUS_QUIT || res.Client == nil {
+		return []string{}, errors.New("Resource is not connected.")
+	}
+
+	var out []string
+	call := common.RPCCall{
+		Job:   common.Job{UUID: jobUUID},
+		Lines: lines,
+	}
+
+	err := res.Client.Call("Queue.ResourceLogs", call, &out)
+	if err != nil {
+		return []string{}, err
+	}
+
+	return out, nil
+}
+
+// The estimate for a job from a single resource
+type ResourceEstimate struct {
+	ResourceUUID string
+	ResourceName string
+	common.Estimate
+	Error string
+}
+
+// Estimate the keyspace and run time of a job on every active resource that
+// provides the tool
+func (q *Queue) ToolEstimate(toolUUID string, params map[string]string) ([]ResourceEstimate, error) {
+	type target struct {
+		est      ResourceEstimate
+		client   *ResourceClient
+		toolUUID string
+	}
+
+	q.RLock()
+	var targets []target
+	for resUUID, res := range q.pool {
+		if res.Status == common.STATUS_QUIT {
+			continue
+		}
+
+		for key, t := range res.Tools {
+			if key == toolUUID || t.UUID == toolUUID {
+				targets = append(targets, target{
+					est:      ResourceEstimate{ResourceUUID: resUUID, ResourceName: res.Name},
+					client:   res.Client,
+					toolUUID: t.UUID,
+				})
+				break
+			}
+		}
+	}
+	q.RUnlock()
+
+	if len(targets) == 0 {
+		return []ResourceEstimate{}, errors.New("Tool did not exist on any active resource.")
+	}
+
+	// Benchmarks can take some time so ask every resource at once without the
+	// queue locked
+	files := sharedFileURLs()
+
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+
+			call := common.RPCCall{
+				Job: common.Job{
+					ToolUUID:   t.toolUUID,
+					Parameters: params,
+				},
+				Files: files,
+			}
+
+			err := t.client.Call("Queue.ToolEstimate", call, &t.est.Estimate)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"tool":     toolUUID,
+					"resource": t.est.ResourceUUID,
+					"error":    err.Error(),
+				}).Error("Unable to estimate job.")
+				t.est.Error = err.Error()
+			}
+		}(&targets[i])
+	}
+	wg.Wait()
+
+	estimates := make([]ResourceEstimate, 0, len(targets))
+	for _, t := range targets {
+		estimates = append(estimates, t.est)
+	}
+
+	sort.Slice(estimates, func(i, j int) bool {
+		return estimates[i].ResourceName < estimates[j].ResourceName
+	})
+
+	return estimates, nil
+}
+
 // This function is used to get all tools that have ever been available
 func (q *Queue) AllTools() map[string]common.Tool {
 	q.RLock()
@@ -1100,29 +1914,51 @@ func (q *Queue) ConnectResource(resUUID, addr string, tlsconfig *tls.Config) err
 		}
 	*/
 
-	dialer := &net.Dialer{
-		Timeout: 15 * time.Second,
-	}
-
-	conn, err := tls.DialWithDialer(dialer, "tcp", target, tlsconfig)
+	conn, err := common.DialTLS(ResourceProxy, target, 15*time.Second, tlsconfig)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"addr":       target,
 			"servername": localRes.Address,
+			"proxy":      ResourceProxy != "",
 		}).Debug("An error occured while building the TLS connection")
 		return err
 	}
 
-	// Build the RPC client for the resource
-	localRes.Client = rpc.NewClient(conn)
-	if err != nil {
-		log.WithField("addr", target).Debug("An error occured while creating new client")
-		return err
+	return q.ConnectResourceConn(resUUID, addr, conn)
+}
+
+//This function will setup a resource over an already established connection.
+//This is used directly by resource managers where the resource dials out to the
+//queue instead of the queue connecting to the resource.
+func (q *Queue) ConnectResourceConn(resUUID, addr string, conn net.Conn) error {
+	q.RLock()
+	localRes, ok := q.pool[resUUID]
+	q.RUnlock()
+
+	if !ok {
+		return ErrResourceNotFound
+	}
+
+	localRes.Address = addr
+
+	// All traffic with the resource goes through its bandwidth limit
+	if localRes.Throttle == nil {
+		localRes.Throttle = common.NewThrottle(localRes.bandwidth())
 	}
 
+	// The resource may have restarted so it is given its profile again
+	localRes.profileSynced = false
+
+	// Build the RPC client for the resource
+	localRes.Client = NewResourceClient(localRes.Name, rpc.NewClient(common.NewThrottledConn(conn, localRes.Throttle)))
+
 	// Let the user know we connected
 	log.WithField("target", localRes.Address).Info("Successfully connected to resource")
-	localRes.Status = common.STATUS_RUNNING
+
+	// A resource that was paused stays paused when it reconnects
+	if localRes.Status != common.STATUS_PAUSED {
+		localRes.Status = common.STATUS_RUNNING
+	}
 
 	q.Lock()
 	q.pool[resUUID] = localRes
@@ -1135,8 +1971,63 @@ func (q *Queue) ConnectResource(resUUID, addr string, tlsconfig *tls.Config) err
 	return nil
 }
 
+//Sets the bandwidth limit in bytes per second for a resource. A rate of 0
+//goes back to ResourceBandwidth and a negative rate removes the limit. This
+//takes effect immediately even on connected resources, and is kept in the
+//state file.
+func (q *Queue) SetResourceBandwidth(resUUID string, rate int64) error {
+	q.Lock()
+	defer q.Unlock()
+
+	res, ok := q.pool[resUUID]
+	if !ok {
+		return ErrResourceNotFound
+	}
+
+	res.Bandwidth = rate
+	if res.Throttle == nil {
+		res.Throttle = common.NewThrottle(res.bandwidth())
+	} else {
+		res.Throttle.SetRate(res.bandwidth())
+	}
+	q.pool[resUUID] = res
+
+	log.WithFields(log.Fields{
+		"resource": resUUID,
+		"rate":     rate,
+	}).Debug("Resource bandwidth limit set.")
+
+	return nil
+}
+
+// Set whether a resource only runs one job at a time across all of its tools
+// and hardware. Jobs already running are left alone.
+func (q *Queue) SetResourceExclusive(resUUID string, exclusive bool) error {
+	q.Lock()
+	res, ok := q.pool[resUUID]
+	if !ok {
+		q.Unlock()
+		return ErrResourceNotFound
+	}
+
+	res.Exclusive = exclusive
+	q.pool[resUUID] = res
+	q.Unlock()
+
+	q.InvalidateSnapshot()
+	q.wakeDispatch()
+
+	log.WithFields(log.Fields{
+		"resource":  resUUID,
+		"exclusive": exclusive,
+	}).Info("Resource exclusive mode set.")
+
+	return nil
+}
+
 //Checks to see if our RPC connection to a resource is still valid, if not it
-//will return false, otherwise it will return true.
+//will return false, otherwise it will return true. The clock of the resource
+//is measured while it is connected.
 func (q *Queue) CheckResourceConnectionStatus(res *Resource) bool {
 	var reply int64
 	err := res.Client.Call("Queue.Ping", 12345, &reply)
@@ -1144,6 +2035,8 @@ func (q *Queue) CheckResourceConnectionStatus(res *Resource) bool {
 		return false
 	}
 
+	q.measureClock(res.Client)
+
 	return true
 }
 
@@ -1168,6 +2061,18 @@ func (q *Queue) LoadRemoteResourceHardware(resUUID string) {
 		localRes.Hardware[key] = true
 	}
 
+	// Older resources do not report an inventory, so jobs with hardware
+	// constraints will not be scheduled on them
+	err = localRes.Client.Call("Queue.ResourceInventory", common.RPCCall{}, &localRes.Inventory)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error":    err.Error(),
+			"resource": resUUID,
+		}).Warn("Unable to gather resource inventory.")
+	} else {
+		localRes.inventoried = time.Now()
+	}
+
 	q.Lock()
 	q.pool[resUUID] = localRes
 	q.Unlock()
@@ -1175,7 +2080,45 @@ func (q *Queue) LoadRemoteResourceHardware(resUUID string) {
 	log.WithField("resources", resUUID).Debug("Loaded hardware for resource")
 }
 
+// Gather the inventory of every running resource that has not reported one
+// within the InventoryInterval. The queue should NOT be locked.
+func (q *Queue) refreshInventories() {
+	if InventoryInterval <= 0 {
+		return
+	}
+
+	q.RLock()
+	clients := map[string]*ResourceClient{}
+	for resUUID, res := range q.pool {
+		if res.Status == common.STATUS_RUNNING && time.Since(res.inventoried) > InventoryInterval {
+			clients[resUUID] = res.Client
+		}
+	}
+	q.RUnlock()
+
+	for resUUID, client := range clients {
+		var inv common.Inventory
+		err := client.Call("Queue.ResourceInventory", common.RPCCall{}, &inv)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"error":    err.Error(),
+				"resource": resUUID,
+			}).Warn("Unable to gather resource inventory.")
+			continue
+		}
+
+		q.Lock()
+		if res, ok := q.pool[resUUID]; ok {
+			res.Inventory = inv
+			res.inventoried = time.Now()
+			q.pool[resUUID] = res
+		}
+		q.Unlock()
+	}
+}
+
 func (q *Queue) LoadRemoteResourceTools(resUUID string) {
+	defer q.InvalidateSnapshot()
 	q.RLock()
 	localRes := q.pool[resUUID]
 	q.RUnlock()
@@ -1191,6 +2134,8 @@ func (q *Queue) LoadRemoteResourceTools(resUUID string) {
 		return
 	}
 
+	// Tools the resource no longer has are dropped
+	localRes.Tools = make(map[string]common.Tool, len(tools))
 	for _, v := range tools {
 		localRes.Tools[v.UUID] = v
 	}
@@ -1226,6 +2171,7 @@ func (q *Queue) LoadRemoteResourceTools(resUUID string) {
 
 //This function will add a resource to the queue.  Returns the UUID.
 func (q *Queue) AddResource(name string) (string, error) {
+	defer q.InvalidateSnapshot()
 	// Check that the address is already in use
 	for _, v := range q.pool {
 		if v.Name == name && v.Status != common.STATUS_QUIT {
@@ -1241,6 +2187,24 @@ func (q *Queue) AddResource(name string) (string, error) {
 	res.Name = name
 	res.Status = common.STATUS_PENDING
 
+	// A resource added again after it quit, such as when the queue restarts,
+	// keeps running one job at a time, its workload profiles and its bandwidth
+	for _, v := range q.pool {
+		if v.Name != name {
+			continue
+		}
+		if v.Exclusive {
+			res.Exclusive = true
+		}
+		if len(v.Profiles) > 0 {
+			res.Profiles = v.Profiles
+		}
+		if v.Bandwidth != 0 {
+			res.Bandwidth = v.Bandwidth
+			res.Throttle = common.NewThrottle(res.bandwidth())
+		}
+	}
+
 	//Generate a UUID for the resource
 	resourceuuid := uuid.New()
 
@@ -1252,42 +2216,43 @@ func (q *Queue) AddResource(name string) (string, error) {
 	return resourceuuid, nil
 }
 
-func (q *Queue) GetResource(resUUID string) (*Resource, bool) {
+func (q *Queue) GetResource(resUUID string) (*Resource, error) {
 	log.WithField("resourceid", resUUID).Debug("Gathering data on resource.")
-	q.Lock()
-	defer q.Unlock()
+	q.RLock()
+	defer q.RUnlock()
 
 	res, ok := q.pool[resUUID]
 	if !ok {
-		return &Resource{}, false
+		return &Resource{}, ErrResourceNotFound
 	}
 	log.WithField("resourceid", resUUID).Debug("Found resource.")
-	return &res, ok
+
+	res = res.clone()
+	return &res, nil
 }
 
 // RemoveResource closes the resource RPC client, and removes it from service.
 // It does not delete it however, because that information is needed by the API
 // even after it is no longer in service.
 func (q *Queue) RemoveResource(resUUID string) error {
+	defer q.InvalidateSnapshot()
+	// Lock the queue
+	q.Lock()
+	defer q.Unlock()
+
 	// Check for the resource with given UUID
 	_, ok := q.pool[resUUID]
 	if !ok {
-		return errors.New("Given Resource UUID does not exist.")
+		return ErrResourceNotFound
 	}
 
-	// Lock the queue
-	q.Lock()
-	defer q.Unlock()
-
 	// Loop through any jobs assigned to the resource and quit them if they are not completed
 	for i, v := range q.stack {
 		if v.ResAssigned == resUUID {
 			// Check status
 			if v.Status == common.STATUS_RUNNING || v.Status == common.STATUS_PAUSED {
 				// Quit the task
-				quitTask := common.RPCCall{Job: v}
-
-				err := q.pool[resUUID].Client.Call("Queue.TaskQuit", quitTask, &q.stack[i])
+				err := q.callJob(q.pool[resUUID].Client, "Queue.TaskQuit", i)
 				if err != nil {
 					log.Println(err.Error())
 				}
@@ -1298,6 +2263,12 @@ func (q *Queue) RemoveResource(resUUID string) error {
 	// Close the connection to the client
 	q.pool[resUUID].Client.Close()
 
+	// Jobs still waiting to be sent to the resource will fail
+	if out, ok := q.outbound[resUUID]; ok {
+		close(out)
+		delete(q.outbound, resUUID)
+	}
+
 	// Remove information that might affect additional resource adding
 	res, _ := q.pool[resUUID]
 	res.Address = "closed"
@@ -1,731 +1,675 @@
 package queue
 
 import (
-	"fmt"
+	"errors"
 	"github.com/jmmcatee/cracklord/common"
 	"github.com/jmmcatee/cracklord/common/resource"
+	"io"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
 
-func TestMeetQueueInterface(t *testing.T) {
-	queue := NewQueue()
-
-	takeInterface(&queue)
-}
-
-func takeInterface(a common.Queue) {
-	a.Quit()
+// A tool for tests whose tasks finish once their timer runs out
+type timerTooler struct {
+	uuid string
 }
 
-func dumpQueue(q Queue) {
-	fmt.Printf("Queue Dump:\n")
-	fmt.Printf("\tStatus:%s\n", q.status)
-	fmt.Printf("\tResourcePool:\n")
-	for i, v := range q.pool {
-		fmt.Printf("\t\tResource(%s): %v\n", i, v)
-	}
-	fmt.Printf("\tStack:\n")
-	for _, j := range q.stack {
-		fmt.Printf("\t\t%v\n", j)
+func (t *timerTooler) Name() string         { return "Simple Timer Tool" }
+func (t *timerTooler) Type() string         { return "Timer" }
+func (t *timerTooler) Version() string      { return "1.0" }
+func (t *timerTooler) UUID() string         { return t.uuid }
+func (t *timerTooler) SetUUID(s string)     { t.uuid = s }
+func (t *timerTooler) Parameters() string   { return "" }
+func (t *timerTooler) Requirements() string { return common.RES_CPU }
+func (t *timerTooler) NewTask(j common.Job) (common.Tasker, error) {
+	d, err := time.ParseDuration(j.Parameters["timer"])
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("\tStats: %v\n", q.stats)
-	fmt.Printf("\tLock: %v\n", q.RWMutex)
-	fmt.Printf("\tKeeperChannel: %v\n", q.qk)
+	return &timerTask{j: j, left: d}, nil
 }
 
-func TestQueueCreate(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
+type timerTask struct {
+	j     common.Job
+	left  time.Duration // Time the timer has left while it is paused
+	until time.Time     // When the timer runs out while it is running
+	sync.Mutex
+}
 
-	closed := resource.StartResource("localhost:4444", &res)
+func (t *timerTask) Status() common.Job {
+	t.Lock()
+	defer t.Unlock()
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueCreate:" + err.Error())
+	if t.j.Status == common.STATUS_RUNNING && time.Now().After(t.until) {
+		t.j.Status = common.STATUS_DONE
+		t.j.Progress = 100
 	}
+	return t.j
+}
 
-	// Check that there is a tool called Simple Test Timer
-	tools := queue.Tools()
-	for _, v := range tools {
-		if v.Name != "Simple Timer Tool" {
-			t.Fatal("Simple Timer Tool did not return correctly.")
-		}
-	}
+func (t *timerTask) Run() error {
+	t.Lock()
+	defer t.Unlock()
 
-	types := queue.Types()
-	for _, v := range types {
-		if v != "Timer" {
-			t.Fatal("Simple Timer Tool did not give the right type. Given " + v + " but expected Timer.")
-		}
+	if common.IsDone(t.j.Status) {
+		return errors.New("Cannot start task as its status is " + t.j.Status)
 	}
-
-	queue.Quit()
-
-	<-closed
+	t.until = time.Now().Add(t.left)
+	t.j.Status = common.STATUS_RUNNING
+	return nil
 }
 
-func TestQueueStop(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
-
-	closed := resource.StartResource("localhost:4444", &res)
+func (t *timerTask) Pause() error {
+	t.Lock()
+	defer t.Unlock()
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueStop:" + err.Error())
+	if t.j.Status != common.STATUS_RUNNING {
+		return errors.New("Cannot pause task as its status is " + t.j.Status)
 	}
+	t.left = t.until.Sub(time.Now())
+	t.j.Status = common.STATUS_PAUSED
+	return nil
+}
 
-	jobs := queue.Quit()
+func (t *timerTask) Quit() common.Job {
+	t.Lock()
+	defer t.Unlock()
 
-	// Jobs should be empty
-	if len(jobs) != 0 {
-		t.Fatal("Queue returned jobs that shouldn't exist.")
+	if !common.IsDone(t.j.Status) {
+		t.j.Status = common.STATUS_QUIT
+		t.j.Error = "Stopped by user"
 	}
-
-	<-closed
+	return t.j
 }
 
-func TestQueueAddJob(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
+func (t *timerTask) IOE() (io.Writer, io.Reader, io.Reader) {
+	return nil, nil, nil
+}
 
-	closed := resource.StartResource("localhost:4444", &res)
+// Build a queue with its state in a temporary directory and a keeper that
+// runs often so tests do not wait on it
+func testQueue(t *testing.T) *Queue {
+	KeeperDuration = 50 * time.Millisecond
+	q := NewQueue(filepath.Join(t.TempDir(), "state.json"), 0, 5, 0)
+	KeeperDuration = 50 * time.Millisecond
+	return &q
+}
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
-	}
+// Add a resource running the timer tool to the queue over an in memory
+// connection, returning the UUID of the resource
+func testResource(t *testing.T, q *Queue, name string) string {
+	res := resource.NewResourceQueue()
+	res.AddTool(new(timerTooler))
 
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
-		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
-		}
+	server := rpc.NewServer()
+	if err := server.Register(&res); err != nil {
+		t.Fatal(err)
 	}
 
-	// Build a simple jobs to run and add it to the queue
-	params := map[string]string{"timer": "1"}
-	j := common.NewJob(juuid, "Simple Timer Queue Test", "GoTestSuite", params)
+	queueSide, resourceSide := net.Pipe()
+	go server.ServeConn(resourceSide)
 
-	err = queue.AddJob(j)
+	resuuid, err := q.AddResource(name)
 	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+		t.Fatal(err)
 	}
-
-	// Wait for the job to finish
-	<-time.After(2 * time.Second)
-
-	println("MADE IT")
-	jobs := queue.Quit()
-	println("MADE IT 2")
-
-	// Check for done status
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE {
-			t.Fatal("Job was not finished and should have been.")
-		}
+	if err := q.ConnectResourceConn(resuuid, name, queueSide); err != nil {
+		t.Fatal(err)
 	}
-
-	<-closed
+	return resuuid
 }
 
-func TestQueueAddMultipleJob(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 1 * time.Second
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
-
-	closed := resource.StartResource("localhost:4444", &res)
-
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
-	}
-
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
+// The UUID of the timer tool in the queue, which is the UUID of the first
+// resource that had it
+func timerTool(t *testing.T, q *Queue) string {
+	for id, v := range q.AllTools() {
 		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
+			return id
 		}
 	}
+	t.Fatal("Simple Timer Tool was not loaded from the resource.")
+	return ""
+}
 
-	// Build a simple jobs to run and add it to the queue
-	params := map[string]string{"timer": "1"}
-	j := common.NewJob(juuid, "Simple Timer Queue Test 1", "GoTestSuite", params)
-
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
-
-	// Build another job that runs longer
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 2", "GoTestSuite", params)
-
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
-
-	// Build a third and final job that runs longer
-	params = map[string]string{"timer": "3"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 3", "GoTestSuite", params)
-
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
-
-	// Wait for the job to finish
-	<-time.After(10 * time.Second)
-
-	jobs := queue.Quit()
+// Wait for every job to reach one of the statuses
+func waitJobs(t *testing.T, q *Queue, timeout time.Duration, statuses ...string) []common.Job {
+	deadline := time.Now().Add(timeout)
+	for {
+		jobs := q.AllJobs()
+		waiting := false
+		for _, j := range jobs {
+			found := false
+			for _, s := range statuses {
+				if j.Status == s {
+					found = true
+				}
+			}
+			if !found {
+				waiting = true
+			}
+		}
+		if !waiting {
+			return jobs
+		}
 
-	// Check for done status
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE {
-			t.Fatal("Job was not finished and should have been.")
+		if time.Now().After(deadline) {
+			for _, j := range jobs {
+				t.Logf("Job %s is %s", j.Name, j.Status)
+			}
+			t.Fatalf("Jobs were not %v after %s", statuses, timeout)
 		}
+		time.Sleep(20 * time.Millisecond)
 	}
-
-	<-closed
 }
 
-func TestQueueDelayAddMultipleJob(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 1 * time.Second
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
-
-	closed := resource.StartResource("localhost:4444", &res)
+func TestQueueCreate(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
+	tools := q.AllTools()
+	if len(tools) != 1 {
+		t.Fatalf("Expected one tool but got %v", tools)
 	}
-
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
-		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
+	for _, v := range tools {
+		if v.Name != "Simple Timer Tool" || v.Type != "Timer" {
+			t.Errorf("Simple Timer Tool did not return correctly: %+v", v)
 		}
 	}
 
-	// Build a simple jobs to run and add it to the queue
-	params := map[string]string{"timer": "1"}
-	j := common.NewJob(juuid, "Simple Timer Queue Test 1", "GoTestSuite", params)
+	q.Quit()
+}
 
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+func TestQueueStop(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+
+	// Jobs should be empty
+	if jobs := q.Quit(); len(jobs) != 0 {
+		t.Fatal("Queue returned jobs that shouldn't exist.")
 	}
+}
 
-	// Build another job that runs longer
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 2", "GoTestSuite", params)
+func TestQueueAddJob(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
 
-	err = queue.AddJob(j)
-	if err != nil {
+	j := common.NewJob(tool, "Simple Timer Queue Test", "GoTestSuite", map[string]string{"timer": "100ms"})
+	if err := q.AddJob(j); err != nil {
 		t.Fatal("Error adding Job: " + err.Error())
 	}
 
-	// Build a third that runs longer
-	params = map[string]string{"timer": "3"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 3", "GoTestSuite", params)
+	waitJobs(t, q, 5*time.Second, common.STATUS_DONE)
+	q.Quit()
+}
 
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+func TestQueueAddMultipleJob(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
+
+	for _, timer := range []string{"100ms", "200ms", "300ms"} {
+		j := common.NewJob(tool, "Simple Timer Queue Test "+timer, "GoTestSuite", map[string]string{"timer": timer})
+		if err := q.AddJob(j); err != nil {
+			t.Fatal("Error adding Job: " + err.Error())
+		}
 	}
 
-	// Wait for the job to finish
-	<-time.After(8 * time.Second)
-
-	// Build a four and final job much delayed
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 3", "GoTestSuite", params)
+	waitJobs(t, q, 10*time.Second, common.STATUS_DONE)
 
-	err = queue.AddJob(j)
-	if err != nil {
+	// Jobs added once the queue has gone idle are run as well
+	j := common.NewJob(tool, "Simple Timer Queue Test Delayed", "GoTestSuite", map[string]string{"timer": "100ms"})
+	if err := q.AddJob(j); err != nil {
 		t.Fatal("Error adding Job: " + err.Error())
 	}
 
-	<-time.After(3 * time.Second)
-
-	jobs := queue.Quit()
-
-	// Check for done status
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE {
-			t.Fatal("Job was not finished and should have been.")
-		}
+	jobs := waitJobs(t, q, 5*time.Second, common.STATUS_DONE)
+	if len(jobs) != 4 {
+		t.Errorf("Expected 4 jobs but got %d", len(jobs))
 	}
-
-	<-closed
+	q.Quit()
 }
 
 func TestQueuePause(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 1 * time.Second
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
-
-	closed := resource.StartResource("localhost:4444", &res)
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
+
+	for _, name := range []string{"1", "2"} {
+		j := common.NewJob(tool, "Simple Timer Queue Test "+name, "GoTestSuite", map[string]string{"timer": "300ms"})
+		if err := q.AddJob(j); err != nil {
+			t.Fatal("Error adding Job: " + err.Error())
+		}
+	}
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
+	waitJobs(t, q, 5*time.Second, common.STATUS_RUNNING, common.STATUS_CREATED)
+	if errs := q.PauseQueue(); len(errs) != 0 {
+		t.Fatalf("Pausing the queue failed: %v", errs)
 	}
 
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
-		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
+	for _, j := range q.AllJobs() {
+		if j.Status == common.STATUS_RUNNING {
+			t.Errorf("Job %s is still running while the queue is paused", j.Name)
 		}
 	}
 
-	// Add two jobs with 2 second timers
-	params := map[string]string{"timer": "2"}
-	j := common.NewJob(juuid, "Simple Timer Queue Test 1", "GoTestSuite", params)
-
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
+	q.ResumeQueue()
+	waitJobs(t, q, 10*time.Second, common.STATUS_DONE)
+	q.Quit()
+}
 
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 2", "GoTestSuite", params)
+func TestJobPause(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
 
-	err = queue.AddJob(j)
-	if err != nil {
+	j := common.NewJob(tool, "Simple Timer Queue Test", "GoTestSuite", map[string]string{"timer": "300ms"})
+	if err := q.AddJob(j); err != nil {
 		t.Fatal("Error adding Job: " + err.Error())
 	}
+	waitJobs(t, q, 5*time.Second, common.STATUS_RUNNING)
 
-	// Wait 1 second then pause
-	<-time.After(1 * time.Second)
-
-	// Pause the queue
-	errs := queue.PauseQueue()
-	if len(errs) != 0 {
-		t.Fatal(errs)
+	if err := q.PauseJob(j.UUID); err != nil {
+		t.Fatal("Pausing job failed: " + err.Error())
 	}
-	if queue.status != STATUS_PAUSED {
-		t.Fatal("Queue was not paused.")
+	if info, _ := q.JobInfo(j.UUID); info.Status != common.STATUS_PAUSED {
+		t.Fatalf("Job was %s and should have been paused.", info.Status)
 	}
 
-	// Add two more jobs
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 3", "GoTestSuite", params)
+	// Paused jobs are started again by the keeper
+	waitJobs(t, q, 10*time.Second, common.STATUS_DONE)
+	q.Quit()
+}
 
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+func TestJobQuit(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
+
+	j1 := common.NewJob(tool, "Simple Timer Queue Test 1", "GoTestSuite", map[string]string{"timer": "1h"})
+	j2 := common.NewJob(tool, "Simple Timer Queue Test 2", "GoTestSuite", map[string]string{"timer": "100ms"})
+	for _, j := range []common.Job{j1, j2} {
+		if err := q.AddJob(j); err != nil {
+			t.Fatal("Error adding Job: " + err.Error())
+		}
 	}
 
-	params = map[string]string{"timer": "2"}
-	j = common.NewJob(juuid, "Simple Timer Queue Test 4", "GoTestSuite", params)
-
-	err = queue.AddJob(j)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+	waitJobs(t, q, 5*time.Second, common.STATUS_RUNNING, common.STATUS_CREATED)
+	if err := q.QuitJob(j1.UUID); err != nil {
+		t.Fatal("Quiting job failed: " + err.Error())
 	}
-
-	// Restart the Queue
-	queue.ResumeQueue()
-
-	// Wait enough time for jobs to finish
-	<-time.After(10 * time.Second)
-
-	jobs := queue.Quit()
-
-	// Check for done status
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE {
-			t.Fatal("Job was not finished and should have been.")
-		}
+	if info, _ := q.JobInfo(j1.UUID); info.Status != common.STATUS_QUIT {
+		t.Fatalf("Job was %s and should have been quit.", info.Status)
 	}
 
-	<-closed
+	waitJobs(t, q, 5*time.Second, common.STATUS_DONE, common.STATUS_QUIT)
+	if info, _ := q.JobInfo(j2.UUID); info.Status != common.STATUS_DONE {
+		t.Errorf("Second job was %s and should have finished.", info.Status)
+	}
+	q.Quit()
 }
 
-func TestJobPause(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 1 * time.Second
-
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
-
-	closed := resource.StartResource("localhost:4444", &res)
+func TestMultiResourceMultiJobs(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest1")
+	testResource(t, q, "QueueTest2")
+	tool := timerTool(t, q)
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
-	}
-
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
-		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
+	for i := 0; i < 6; i++ {
+		j := common.NewJob(tool, "Simple Timer Queue Test", "GoTestSuite", map[string]string{"timer": "100ms"})
+		if err := q.AddJob(j); err != nil {
+			t.Fatal("Error adding Job: " + err.Error())
 		}
 	}
 
-	// Add two jobs with 2 second timers
-	params := map[string]string{"timer": "4"}
-	j1 := common.NewJob(juuid, "Simple Timer Queue Test 1", "GoTestSuite", params)
-
-	err = queue.AddJob(j1)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
-
-	params = map[string]string{"timer": "2"}
-	j2 := common.NewJob(juuid, "Simple Timer Queue Test 2", "GoTestSuite", params)
-
-	err = queue.AddJob(j2)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+	jobs := waitJobs(t, q, 10*time.Second, common.STATUS_DONE)
+	used := map[string]bool{}
+	for _, j := range jobs {
+		used[j.ResAssigned] = true
 	}
-
-	// Now pause the first job we added so the second finished first
-	err = queue.PauseJob(j1.UUID)
-	if err != nil {
-		t.Fatal("Pausing job failed: " + err.Error())
+	if len(used) != 2 {
+		t.Errorf("Jobs ran on %d resources instead of both", len(used))
 	}
+	q.Quit()
+}
 
-	// The first jobs should now be paused so lets pull all statuses and check
-	jobs := queue.AllJobs()
-	for _, v := range jobs {
-		if v.UUID == j1.UUID {
-			if v.Status != common.STATUS_PAUSED {
-				t.Fatal("Job was not paused and should have been.")
+// Reads and adds from API handlers while the keeper is updating the jobs,
+// run with -race
+func TestQueueConcurrentAccess(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
+
+	var wg sync.WaitGroup
+	added := make(chan string, 20)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				j := common.NewJob(tool, "Simple Timer Queue Test", "GoTestSuite", map[string]string{"timer": "20ms"})
+				if err := q.AddJob(j); err != nil {
+					t.Error("Error adding Job: " + err.Error())
+					return
+				}
+				added <- j.UUID
 			}
-		}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deadline := time.Now().Add(500 * time.Millisecond)
+			for time.Now().Before(deadline) {
+				for _, j := range q.AllJobs() {
+					if _, err := q.JobInfo(j.UUID); err != nil {
+						t.Error(err)
+						return
+					}
+					j.Parameters["timer"] = "changed"
+				}
+			}
+		}()
 	}
+	wg.Wait()
+	close(added)
 
-	// Wait the total runtime to make sure everything eventually finished
-	<-time.After(8 * time.Second)
-
-	jobs = queue.Quit()
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE {
-			t.Fatal("Not all jobs were finished.")
+	jobs := waitJobs(t, q, 10*time.Second, common.STATUS_DONE)
+	if len(jobs) != 20 {
+		t.Errorf("Expected 20 jobs but got %d", len(jobs))
+	}
+	for id := range added {
+		if j, err := q.JobInfo(id); err != nil || j.Parameters["timer"] != "20ms" {
+			t.Errorf("Job %s was changed through a copy: %v %v", id, j.Parameters, err)
 		}
 	}
-
-	<-closed
+	q.Quit()
 }
 
-func TestJobQuit(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 1 * time.Second
+func TestExpireJobsRemovedResource(t *testing.T) {
+	q := testQueue(t)
 
-	// Build the resource
-	res := resource.NewResourceQueue("QueueTest")
-	tool := new(resource.SimpleTimerTooler)
-	res.AddTool(tool)
+	j := common.NewJob("tool", "Expired", "GoTestSuite", map[string]string{})
+	j.Status = common.STATUS_RUNNING
+	j.StartTime = time.Now().Add(-2 * time.Hour)
+	j.MaxRuntime = time.Hour
+	j.ResAssigned = "removed"
+	q.stack = append(q.stack, j)
 
-	closed := resource.StartResource("localhost:4444", &res)
+	q.Lock()
+	q.accrueRunTime()
+	q.expireJobs()
+	q.Unlock()
 
-	err := queue.AddResource("localhost:4444", "test", "QueueTest")
-	if err != nil {
-		t.Fatal("TestQueueAddJob:" + err.Error())
+	if info, _ := q.JobInfo(j.UUID); info.Status != common.STATUS_EXPIRED {
+		t.Errorf("Job on a removed resource was %s and should have expired.", info.Status)
 	}
+}
 
-	// Get the Queue Tools so we can get the UUID
-	var juuid string
-	for _, v := range queue.Tools() {
-		if v.Name == "Simple Timer Tool" {
-			juuid = v.UUID
-		}
+func TestRunTimeSurvivesPause(t *testing.T) {
+	q := testQueue(t)
+
+	// A job that ran for 50 minutes before it was paused
+	j := common.NewJob("tool", "Paused", "GoTestSuite", map[string]string{})
+	j.Status = common.STATUS_RUNNING
+	j.StartTime = time.Now().Add(-50 * time.Minute)
+	j.MaxRuntime = time.Hour
+	j.ResAssigned = "removed"
+	q.stack = append(q.stack, j)
+
+	q.Lock()
+	q.accrueRunTime()
+	q.stack[0].Status = common.STATUS_PAUSED
+	q.accrueRunTime()
+	q.Unlock()
+
+	// Resumed 20 minutes ago, the tool started its time again
+	q.Lock()
+	q.stack[0].Status = common.STATUS_RUNNING
+	q.stack[0].StartTime = time.Now().Add(-20 * time.Minute)
+	q.accrueRunTime()
+	q.expireJobs()
+	q.Unlock()
+
+	info, _ := q.JobInfo(j.UUID)
+	if info.RunTime < 70*time.Minute || info.RunTime > 71*time.Minute {
+		t.Errorf("Expected the job to have run for 70 minutes, got %s", info.RunTime)
+	}
+	if info.Status != common.STATUS_EXPIRED {
+		t.Errorf("Job that ran past its limit across a pause was %s and should have expired.", info.Status)
 	}
+}
 
-	// Add two jobs with 2 second timers
-	params := map[string]string{"timer": "4"}
-	j1 := common.NewJob(juuid, "Simple Timer Queue Test 1", "GoTestSuite", params)
+func TestReleasedSurviveRestart(t *testing.T) {
+	q := testQueue(t)
 
-	err = queue.AddJob(j1)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
-	}
+	// A job running on a resource that cannot be reached
+	res := NewResource()
+	res.Name = "QueueTest"
+	res.Status = common.STATUS_QUIT
+	q.pool["unreachable"] = res
 
-	params = map[string]string{"timer": "2"}
-	j2 := common.NewJob(juuid, "Simple Timer Queue Test 2", "GoTestSuite", params)
+	j := common.NewJob("tool", "Released", "GoTestSuite", map[string]string{})
+	j.Status = common.STATUS_RUNNING
+	j.ResAssigned = "unreachable"
+	q.stack = append(q.stack, j)
 
-	err = queue.AddJob(j2)
-	if err != nil {
-		t.Fatal("Error adding Job: " + err.Error())
+	if err := q.ForceQuitJob(j.UUID); err != nil {
+		t.Fatal(err)
 	}
-
-	// Now pause the first job we added so the second finished first
-	err = queue.QuitJob(j1.UUID)
-	if err != nil {
-		t.Fatal("Quiting job failed: " + err.Error())
+	if err := q.writeState(); err != nil {
+		t.Fatal(err)
 	}
 
-	// The first jobs should now be paused so lets pull all statuses and check
-	jobs := queue.AllJobs()
-	for _, v := range jobs {
-		if v.UUID == j1.UUID {
-			if v.Status != common.STATUS_QUIT {
-				t.Fatal("Job was not quit and should have been.")
-			}
-		}
+	restarted := NewQueue(StateFileLocation, 0, 5, 0)
+	if _, ok := restarted.released[j.UUID]; !ok {
+		t.Fatal("Released job was not kept in the state file.")
 	}
 
-	// Wait the total runtime to make sure everything eventually finished
-	<-time.After(4 * time.Second)
+	// The resource comes back under a new UUID and is told to quit the task
+	testResource(t, &restarted, "QueueTest")
+	restarted.Lock()
+	restarted.reconcileReleased()
+	restarted.Unlock()
 
-	jobs = queue.Quit()
-	for _, v := range jobs {
-		if v.Status != common.STATUS_DONE && v.Status != common.STATUS_QUIT {
-			t.Fatal("Not all jobs were finished. Status:" + v.Status)
-		}
+	if _, ok := restarted.released[j.UUID]; ok {
+		t.Error("Released job was not reconciled with its reconnected resource.")
 	}
-
-	<-closed
 }
 
-func TestMultiResourceMultiJobs1(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 100 * time.Millisecond
-
-	// Build the first resource
-	res1 := resource.NewResourceQueue("Resource 1")
-	tool := new(resource.SimpleTimerTooler)
-	res1.AddTool(tool)
-
-	// Start the first resource
-	close1 := resource.StartResource("localhost:4441", &res1)
-
-	// Add the first resource
-	err := queue.AddResource("localhost:4441", "name", "Resource 1")
-	if err != nil {
-		t.Fatal("Error adding resource: " + err.Error())
+func TestBandwidthSurvivesRestart(t *testing.T) {
+	bandwidth := ResourceBandwidth
+	defer func() { ResourceBandwidth = bandwidth }()
+	ResourceBandwidth = 4096
+
+	q := testQueue(t)
+	limited, _ := q.AddResource("Limited")
+	unlimited, _ := q.AddResource("Unlimited")
+	queueDefault, _ := q.AddResource("Default")
+	q.SetResourceBandwidth(limited, 1024)
+	q.SetResourceBandwidth(unlimited, -1)
+	if err := q.writeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewQueue(StateFileLocation, 0, 5, 0)
+	for id, want := range map[string]int64{limited: 1024, unlimited: -1, queueDefault: 4096} {
+		res, err := restarted.GetResource(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Throttle == nil || res.Throttle.Rate() != want {
+			t.Errorf("Expected %s to be limited to %d after a restart, got %+v", res.Name, want, res.Throttle)
+		}
 	}
 
-	// Build the second resource
-	res2 := resource.NewResourceQueue("Resource 2")
-	tool = new(resource.SimpleTimerTooler)
-	res2.AddTool(tool)
-
-	// Start the second resource
-	close2 := resource.StartResource("localhost:4442", &res2)
-
-	// Add the second resource
-	err = queue.AddResource("localhost:4442", "name", "Resource 2")
+	// The limit is kept when the resource comes back under a new UUID
+	id, err := restarted.AddResource("Limited")
 	if err != nil {
-		t.Fatal("Error adding resource: " + err.Error())
+		t.Fatal(err)
 	}
-
-	// Build several jobs to add
-	param1 := map[string]string{"timer": "1"}
-	param2 := map[string]string{"timer": "2"}
-	param3 := map[string]string{"timer": "3"}
-
-	var tu1 string
-	for _, v := range queue.Tools() {
-		tu1 = v.UUID
+	if res, _ := restarted.GetResource(id); res.Throttle.Rate() != 1024 {
+		t.Errorf("Expected the reconnected resource to be limited to 1024, got %d", res.Throttle.Rate())
 	}
+}
 
-	j1 := common.NewJob(tu1, "Simple Timer Queue Test 1", "GoTestSuite", param1)
-	j2 := common.NewJob(tu1, "Simple Timer Queue Test 2", "GoTestSuite", param3)
-	j3 := common.NewJob(tu1, "Simple Timer Queue Test 3", "GoTestSuite", param3)
-	j4 := common.NewJob(tu1, "Simple Timer Queue Test 4", "GoTestSuite", param2)
-	j5 := common.NewJob(tu1, "Simple Timer Queue Test 5", "GoTestSuite", param1)
-
-	err = queue.AddJob(j1)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+func TestToolCallMigratesWithDefaults(t *testing.T) {
+	q := testQueue(t)
+	res := NewResource()
+	res.Name = "QueueTest"
+	res.Status = common.STATUS_RUNNING
+	res.Tools["tool"] = common.Tool{
+		UUID:        "tool",
+		Name:        "Migrated Tool",
+		FormVersion: 2,
+		Migrations:  []common.FormMigration{{From: 1, Rename: map[string]string{"dict": "dictionaries"}}},
 	}
-	err = queue.AddJob(j2)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	q.pool["resource"] = res
+	q.defaults["Migrated Tool"] = common.ToolDefaults{
+		Defaults: map[string]string{"workload": "2"},
+		Locked:   map[string]string{"optimized": "true"},
 	}
-	err = queue.AddJob(j3)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
-	}
-	err = queue.AddJob(j4)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+
+	// A job made with the older form gets both its migrated parameters and
+	// the defaults of the tool
+	j := common.NewJob("tool", "Old Form", "GoTestSuite", map[string]string{"dict": "rockyou"})
+	j.FormVersion = 1
+
+	client, call := q.toolCall(j)
+	if client != res.Client {
+		t.Error("Expected the client of the resource with the tool")
 	}
-	err = queue.AddJob(j5)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	want := map[string]string{"dictionaries": "rockyou", "workload": "2", "optimized": "true"}
+	if !reflect.DeepEqual(call.Job.Parameters, want) {
+		t.Errorf("Expected the tool to be called with %v, got %v", want, call.Job.Parameters)
 	}
+}
 
-	for {
-		running := false
-
-		select {
-		case <-time.After(1 * time.Second):
-			// Check if all jobs are done
-			for _, v := range queue.AllJobs() {
-				if v.Status != common.STATUS_DONE {
-					running = true
-				}
+func TestImportDispatchesJobs(t *testing.T) {
+	q := testQueue(t)
+	testResource(t, q, "QueueTest")
+	tool := timerTool(t, q)
+
+	// The defaults of the bundle lock the timer so the job is done quickly
+	j := common.NewJob(tool, "Imported Timer", "GoTestSuite", map[string]string{"timer": "1h"})
+	sensitive := common.NewJob(tool, "Imported Sensitive Timer", "GoTestSuite", map[string]string{"timer": "100ms"})
+	sensitive.Sensitive = true
+
+	sum, err := q.Import(Bundle{
+		Format:   BUNDLE_FORMAT,
+		Exported: time.Now(),
+		StateFile: StateFile{
+			Stack: []common.Job{j, sensitive},
+			Defaults: map[string]common.ToolDefaults{
+				"Simple Timer Tool": {Locked: map[string]string{"timer": "100ms"}},
+			},
+		},
+	}, "GoTestSuite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Jobs != 2 || sum.Defaults != 1 {
+		t.Errorf("Unexpected import summary %+v", sum)
+	}
+
+	// Imported jobs are run without another job being added to start the
+	// keeper, and sensitive ones wait for approval like added ones do
+	for _, got := range waitJobs(t, q, 5*time.Second, common.STATUS_DONE, common.STATUS_APPROVAL) {
+		switch got.UUID {
+		case j.UUID:
+			if got.Status != common.STATUS_DONE || got.Parameters["timer"] != "100ms" {
+				t.Errorf("Imported job is %s with timer %s", got.Status, got.Parameters["timer"])
+			}
+		case sensitive.UUID:
+			if got.Status != common.STATUS_APPROVAL {
+				t.Errorf("Imported sensitive job is %s", got.Status)
 			}
-
-		}
-
-		if !running {
-			break
 		}
 	}
-
-	queue.Quit()
-
-	<-close1
-	<-close2
+	q.Quit()
 }
 
-func TestMultiResourceMultiJobs2(t *testing.T) {
-	// Build the main queue
-	queue := NewQueue()
-	KeeperDuration = 100 * time.Millisecond
-
-	// Build the first resource
-	res1 := resource.NewResourceQueue("Resource 1")
-	tool := new(resource.SimpleTimerTooler)
-	res1.AddTool(tool)
+// A referrer for tests that needs the jobs it is given reasons for
+type stubReferrer struct {
+	q       *Queue
+	inUse   map[string]string
+	locked  bool // The queue was locked every time it was asked
+	deleted []string
+	sync.Mutex
+}
 
-	// Start the first resource
-	close1 := resource.StartResource("localhost:4441", &res1)
+func (r *stubReferrer) JobInUse(job common.Job) string {
+	r.Lock()
+	defer r.Unlock()
 
-	// Add the first resource
-	err := queue.AddResource("localhost:4441", "name", "Resource 1")
-	if err != nil {
-		t.Fatal("Error adding resource: " + err.Error())
+	// The queue is locked for writing while jobs are deleted
+	if r.q.TryRLock() {
+		r.q.RUnlock()
+		r.locked = false
 	}
+	return r.inUse[job.UUID]
+}
 
-	// Build the second resource
-	res2 := resource.NewResourceQueue("Resource 2")
-	tool = new(resource.SimpleTimerTooler)
-	res2.AddTool(tool)
-
-	// Start the second resource
-	close2 := resource.StartResource("localhost:4442", &res2)
+func (r *stubReferrer) JobDeleted(jobuuid string) {
+	r.Lock()
+	defer r.Unlock()
 
-	// Add the second resource
-	err = queue.AddResource("localhost:4442", "name", "Resource 2")
-	if err != nil {
-		t.Fatal("Error adding resource: " + err.Error())
-	}
-
-	// Build the second resource
-	res3 := resource.NewResourceQueue("Resource 3")
-	tool3 := new(resource.SimpleTimerTooler)
-	res3.AddTool(tool3)
+	r.deleted = append(r.deleted, jobuuid)
+}
 
-	// Start the second resource
-	close3 := resource.StartResource("localhost:4443", &res3)
+func TestRemoveJobReferences(t *testing.T) {
+	q := testQueue(t)
 
-	// Add the second resource
-	err = queue.AddResource("localhost:4443", "name", "Resource 3")
-	if err != nil {
-		t.Fatal("Error adding resource: " + err.Error())
+	var stack []common.Job
+	for _, name := range []string{"Needed", "Unneeded"} {
+		j := common.NewJob("tool", name, "GoTestSuite", map[string]string{})
+		j.Status = common.STATUS_DRAFT
+		stack = append(stack, j)
 	}
-
-	// Build several jobs to add
-	param := map[string]string{"timer": "3"}
-
-	var tu1 string
-	for _, v := range queue.Tools() {
-		tu1 = v.UUID
+	needed, unneeded := stack[0].UUID, stack[1].UUID
+	if _, err := q.Import(Bundle{Format: BUNDLE_FORMAT, StateFile: StateFile{Stack: stack}}, "GoTestSuite"); err != nil {
+		t.Fatal(err)
 	}
 
-	j1 := common.NewJob(tu1, "Simple Timer Queue Test 1", "GoTestSuite", param)
-	j2 := common.NewJob(tu1, "Simple Timer Queue Test 2", "GoTestSuite", param)
-	j3 := common.NewJob(tu1, "Simple Timer Queue Test 3", "GoTestSuite", param)
-	j4 := common.NewJob(tu1, "Simple Timer Queue Test 4", "GoTestSuite", param)
-	j5 := common.NewJob(tu1, "Simple Timer Queue Test 5", "GoTestSuite", param)
-	j6 := common.NewJob(tu1, "Simple Timer Queue Test 6", "GoTestSuite", param)
+	r := &stubReferrer{q: q, inUse: map[string]string{needed: "it is needed"}, locked: true}
+	q.AddJobReferrer(r)
 
-	err = queue.AddJob(j1)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	// Restrict: a job a referrer needs is kept
+	err := q.RemoveJob(needed)
+	if e, ok := err.(*JobInUseError); !ok || e.Job != needed || e.Reason != "it is needed" {
+		t.Fatalf("Expected the job to be in use, got %v", err)
 	}
-	err = queue.AddJob(j2)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	if _, err := q.JobInfo(needed); err != nil {
+		t.Error("Job in use was deleted")
 	}
-	err = queue.AddJob(j3)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+
+	if err := q.RemoveJob(unneeded); err != nil {
+		t.Fatal(err)
 	}
-	err = queue.AddJob(j4)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	if _, err := q.JobInfo(unneeded); err != ErrJobNotFound {
+		t.Error("Job not in use was not deleted")
 	}
-	err = queue.AddJob(j5)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+
+	// Forced deletes do not ask
+	if err := q.ForceRemoveJob(needed); err != nil {
+		t.Fatal(err)
 	}
-	err = queue.AddJob(j6)
-	if err != nil {
-		t.Fatal("Job was not added successfully: " + err.Error())
+	if _, err := q.JobInfo(needed); err != ErrJobNotFound {
+		t.Error("Job in use was not force deleted")
 	}
 
-	for {
-		running := false
-
-		select {
-		case <-time.After(1 * time.Second):
-			// Check if all jobs are done
-			for _, v := range queue.AllJobs() {
-				if v.Status != common.STATUS_DONE {
-					running = true
-				}
-			}
-
-		}
-
-		if !running {
-			break
-		}
+	// Cascade: the referrer is told of both deletes
+	r.Lock()
+	defer r.Unlock()
+	if len(r.deleted) != 2 || r.deleted[0] != unneeded || r.deleted[1] != needed {
+		t.Errorf("Referrer was told of deletes %v", r.deleted)
+	}
+	if !r.locked {
+		t.Error("Referrer was asked without the queue locked")
 	}
-
-	queue.Quit()
-
-	<-close1
-	<-close2
-	<-close3
 }
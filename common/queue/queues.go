@@ -0,0 +1,311 @@
+package queue
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Returned when a named queue does not exist
+var ErrQueueNotFound = errors.New("Job queue does not exist!")
+
+// Orders jobs waiting in a named queue are sent to resources in
+const (
+	POLICY_FIFO = "fifo" // Oldest job first
+	POLICY_LIFO = "lifo" // Newest job first
+	POLICY_FAIR = "fair" // Jobs of the owner with the fewest running jobs in the queue first
+)
+
+var queueNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+/*
+ * A named queue, such as "fast", "bulk" or "cloud", bound to a set of
+ * resources. Jobs created in a named queue only run on its resources and
+ * resources bound to a named queue only run jobs from the queues they are
+ * bound to, so jobs without a queue run on the resources that are not bound
+ * to any. A resource bound to several queues takes jobs from them in the
+ * order they are listed.
+ */
+type NamedQueue struct {
+	Name       string
+	Resources  []string // UUIDs of the resources that run the jobs of the queue
+	Policy     string   // Order waiting jobs are sent in, FIFO when empty
+	MaxRunning int      // Jobs of the queue running at once, 0 does not limit them
+}
+
+func (n NamedQueue) Validate() error {
+	if !queueNameRegex.MatchString(n.Name) {
+		return errors.New("Queue names must be up to 32 lowercase letters, numbers, dashes and underscores.")
+	}
+	switch n.Policy {
+	case "", POLICY_FIFO, POLICY_LIFO, POLICY_FAIR:
+	default:
+		return errors.New("Unknown queue policy " + n.Policy + ".")
+	}
+	if n.MaxRunning < 0 {
+		return errors.New("The running job limit cannot be negative.")
+	}
+	return nil
+}
+
+func (n NamedQueue) clone() NamedQueue {
+	n.Resources = append([]string(nil), n.Resources...)
+	return n
+}
+
+// Get every named queue in the order resources take jobs from them
+func (q *Queue) Queues() []NamedQueue {
+	q.RLock()
+	defer q.RUnlock()
+
+	out := []NamedQueue{}
+	for _, n := range q.queues {
+		out = append(out, n.clone())
+	}
+	return out
+}
+
+// Create a named queue or replace the resources and policy of one
+func (q *Queue) SetQueue(n NamedQueue) (NamedQueue, error) {
+	if n.Policy == "" {
+		n.Policy = POLICY_FIFO
+	}
+	if err := n.Validate(); err != nil {
+		return n, err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	for _, id := range n.Resources {
+		if _, ok := q.pool[id]; !ok {
+			return n, ErrResourceNotFound
+		}
+	}
+
+	n = n.clone()
+	replaced := false
+	for i := range q.queues {
+		if q.queues[i].Name == n.Name {
+			q.queues[i] = n
+			replaced = true
+		}
+	}
+	if !replaced {
+		q.queues = append(q.queues, n)
+	}
+
+	log.WithFields(log.Fields{
+		"queue":     n.Name,
+		"policy":    n.Policy,
+		"resources": len(n.Resources),
+	}).Info("Job queue set.")
+
+	if StateFileLocation != "" {
+		q.writeState()
+	}
+
+	// The resources may now run jobs they could not before
+	q.wakeDispatch()
+
+	return n.clone(), nil
+}
+
+// Remove a named queue. Queues with jobs that have not finished are kept so
+// those jobs are not stranded.
+func (q *Queue) RemoveQueue(name string) error {
+	q.Lock()
+	defer q.Unlock()
+
+	i := q.findQueue(name)
+	if i < 0 {
+		return ErrQueueNotFound
+	}
+
+	for _, j := range q.stack {
+		if j.Queue == name && !jobFinished(j) {
+			return errors.New("The queue still has jobs that have not finished, they must be moved or stopped first.")
+		}
+	}
+
+	q.queues = append(q.queues[:i], q.queues[i+1:]...)
+	log.WithField("queue", name).Info("Job queue removed.")
+
+	if StateFileLocation != "" {
+		q.writeState()
+	}
+
+	// Its resources now run jobs without a queue
+	q.wakeDispatch()
+
+	return nil
+}
+
+// Move a job that has not started to another queue, an empty name moves it
+// to the default queue
+func (q *Queue) MoveJob(jobUUID, name, by string) (common.Job, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if name != "" && q.findQueue(name) < 0 {
+		return common.Job{}, ErrQueueNotFound
+	}
+
+	for i := range q.stack {
+		if q.stack[i].UUID != jobUUID {
+			continue
+		}
+
+		s := q.stack[i].Status
+		if (s != common.STATUS_CREATED && s != common.STATUS_DRAFT && s != common.STATUS_APPROVAL) || q.dispatching[jobUUID] {
+			return q.stack[i].Clone(), errors.New("Only jobs that have not started can be moved between queues.")
+		}
+
+		from := q.stack[i].Queue
+		q.stack[i].Queue = name
+		q.stack[i].Record(by, "move", "Moved from queue "+queueLabel(from)+" to "+queueLabel(name)+".")
+		q.debugf(q.stack[i], "Moved from queue %s to %s", queueLabel(from), queueLabel(name))
+
+		log.WithFields(log.Fields{
+			"job":  jobUUID,
+			"from": from,
+			"to":   name,
+			"by":   by,
+		}).Info("Job moved between queues.")
+
+		q.wakeDispatch()
+		return q.stack[i].Clone(), nil
+	}
+
+	return common.Job{}, ErrJobNotFound
+}
+
+// The name of a queue for the history of a job
+func queueLabel(name string) string {
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) findQueue(name string) int {
+	for i := range q.queues {
+		if q.queues[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Check that a job can be created in the queue it names
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) checkJobQueue(j common.Job) error {
+	if j.Queue != "" && q.findQueue(j.Queue) < 0 {
+		return ErrQueueNotFound
+	}
+	return nil
+}
+
+func jobFinished(j common.Job) bool {
+	switch j.Status {
+	case common.STATUS_DONE, common.STATUS_FAILED, common.STATUS_QUIT, common.STATUS_EXPIRED:
+		return true
+	}
+	return false
+}
+
+// The queues each resource is bound to, in the order it takes jobs from them,
+// resources missing from the map run jobs without a queue
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) boundQueues() map[string][]string {
+	bound := map[string][]string{}
+	for _, n := range q.queues {
+		for _, id := range n.Resources {
+			bound[id] = append(bound[id], n.Name)
+		}
+	}
+	return bound
+}
+
+// Check if a resource bound to the queues given, or to none when empty, runs
+// the jobs of the queue of a job
+func queueAllows(queues []string, j common.Job) bool {
+	if len(queues) == 0 {
+		return j.Queue == ""
+	}
+	for _, name := range queues {
+		if name == j.Queue {
+			return true
+		}
+	}
+	return false
+}
+
+// Order the jobs of the stack for a resource bound to the queues given, or to
+// none when empty. Each queue keeps to its own policy and queues that are
+// already running as many jobs as they are allowed are left out.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) queueOrder(queues []string) []int {
+	if len(queues) == 0 {
+		queues = []string{""}
+	}
+
+	var order []int
+	for _, name := range queues {
+		jobs, running, limit := q.policyOrder(name)
+		if limit > 0 && running >= limit {
+			for _, i := range jobs {
+				q.debugOncef(q.stack[i], "Waiting, queue %s is running %d of %d jobs", queueLabel(name), running, limit)
+			}
+			continue
+		}
+
+		order = append(order, jobs...)
+	}
+
+	return order
+}
+
+// Order the jobs of a queue that are not running or being sent by its policy,
+// along with the number that are and the limit of the queue
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) policyOrder(name string) ([]int, int, int) {
+	policy := POLICY_FIFO
+	limit := 0
+	if i := q.findQueue(name); i >= 0 {
+		policy = q.queues[i].Policy
+		limit = q.queues[i].MaxRunning
+	}
+
+	var jobs []int
+	running := 0
+	owners := map[string]int{}
+	for i := range q.stack {
+		if q.stack[i].Queue != name {
+			continue
+		}
+		if q.stack[i].Status == common.STATUS_RUNNING || q.dispatching[q.stack[i].UUID] {
+			running++
+			owners[q.stack[i].Owner]++
+			continue
+		}
+		jobs = append(jobs, i)
+	}
+
+	switch policy {
+	case POLICY_LIFO:
+		for l, r := 0, len(jobs)-1; l < r; l, r = l+1, r-1 {
+			jobs[l], jobs[r] = jobs[r], jobs[l]
+		}
+	case POLICY_FAIR:
+		sort.SliceStable(jobs, func(a, b int) bool {
+			return owners[q.stack[jobs[a]].Owner] < owners[q.stack[jobs[b]].Owner]
+		})
+	}
+
+	return jobs, running, limit
+}
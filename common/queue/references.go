@@ -0,0 +1,99 @@
+package queue
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+/* A JobReferrer is anything outside of the queue that holds on to jobs by
+ * their UUID, such as the quick crack pipeline, and decides what happens to it
+ * when one of those jobs is deleted. Every delete of a job goes through the
+ * queue so the rules are kept in one place:
+ *
+ *  - Restrict: RemoveJob asks every referrer first and refuses to delete the
+ *    job when one of them says it is still needed. ForceRemoveJob is for jobs
+ *    that have to go regardless, so it does not ask.
+ *  - Cascade: once the job is gone, by either call, every referrer is told so
+ *    it can drop the UUID. The queue itself forgets the checkpoint, debug log,
+ *    estimates and the rest it kept for the job at the same time.
+ *
+ * Tool defaults are applied to the parameters of a job when it is created, so
+ * they are no reference and changing or removing them never breaks a job.
+ * JobInUse is called with the queue locked so its answer still holds when the
+ * job is deleted, and must not use the queue. JobDeleted is called outside of
+ * the lock and may use it.
+ */
+type JobReferrer interface {
+	// Why the job cannot be deleted yet, empty when it can be
+	JobInUse(job common.Job) string
+	// The job was deleted from the queue
+	JobDeleted(jobuuid string)
+}
+
+// Returned by RemoveJob when a referrer still needs the job
+type JobInUseError struct {
+	Job    string
+	Reason string
+}
+
+func (e *JobInUseError) Error() string {
+	return "The job cannot be deleted: " + e.Reason
+}
+
+// Add a referrer to be asked before jobs are deleted and told after
+func (q *Queue) AddJobReferrer(r JobReferrer) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.referrers = append(q.referrers, r)
+}
+
+// The referrers of the queue, copied so they can be called without the lock
+func (q *Queue) jobReferrers() []JobReferrer {
+	q.RLock()
+	defer q.RUnlock()
+
+	return append([]JobReferrer(nil), q.referrers...)
+}
+
+// Ask every referrer if the job can be deleted
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) checkJobReferences(job common.Job) error {
+	for _, r := range q.referrers {
+		if reason := r.JobInUse(job); reason != "" {
+			log.WithFields(log.Fields{
+				"job":    job.UUID,
+				"reason": reason,
+			}).Info("Job is still in use and was not deleted.")
+			return &JobInUseError{Job: job.UUID, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// Tell every referrer the job was deleted
+func (q *Queue) notifyJobDeleted(jobuuid string) {
+	for _, r := range q.jobReferrers() {
+		r.JobDeleted(jobuuid)
+	}
+}
+
+// Forget everything the queue kept about a job that was removed from the
+// stack. Released jobs are kept until their resource has been told to quit
+// them, and jobs still being sent are quit by the dispatcher once it sees they
+// are gone.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) forgetJob(jobuuid string) {
+	delete(q.checkpoints, jobuuid)
+	delete(q.checkpointed, jobuuid)
+	delete(q.exported, jobuuid)
+	delete(q.debugs, jobuuid)
+	delete(q.estimates, jobuuid)
+	delete(q.progressed, jobuuid)
+	delete(q.costed, jobuuid)
+	delete(q.timed, jobuuid)
+	delete(q.overBudget, jobuuid)
+
+	// Storage may be remote so the caller does not wait on it
+	go removeJobData(jobuuid)
+}
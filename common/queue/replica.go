@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+var ErrReplicaStale = errors.New("The read replica is behind the queue by more than its maximum lag.")
+
+// Reads of jobs and statistics that both the queue and its read replica serve
+type Reader interface {
+	AllJobs() []common.Job
+	JobOutput(jobUUID string) (common.Job, error)
+	AllTools() map[string]common.Tool
+	ToolStats() map[string]ToolStats
+	DiffJobs(olderUUID, newerUUID string) (common.ResultDiff, error)
+}
+
+// How often the replica checks if the state file was written again
+var ReplicaRefresh = time.Second
+
+/*
+ * A read only copy of the queue loaded from its state file, which the keeper
+ * writes every run. Heavy reads such as reports, statistics and analysis of
+ * results are served from it by a pool of workers of their own, so they never
+ * hold the queue lock the keeper and scheduler need. The copy is as old as the
+ * last state file written.
+ */
+type Replica struct {
+	path    string
+	maxLag  time.Duration
+	workers chan struct{}
+
+	loading  sync.Mutex // Held while the state file is read
+	mux      sync.RWMutex
+	state    StateFile
+	modified time.Time // When the state file of the copy was written
+	checked  time.Time // When the state file was last checked
+}
+
+// Build a replica of a state file with the number of workers that read from it
+// at once. Reads are refused once the state file is older than maxLag, 0 never
+// refuses them.
+func NewReplica(statefile string, workers int, maxLag time.Duration) *Replica {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Replica{
+		path:    statefile,
+		maxLag:  maxLag,
+		workers: make(chan struct{}, workers),
+	}
+}
+
+// Wait for a worker of the replica to be free, the returned function frees it
+func (r *Replica) Acquire() func() {
+	r.workers <- struct{}{}
+	return func() { <-r.workers }
+}
+
+// Load the state file again if it was written since it was last loaded and
+// check the copy is not too old
+func (r *Replica) Refresh() error {
+	r.loading.Lock()
+	defer r.loading.Unlock()
+
+	r.mux.RLock()
+	checked, modified := r.checked, r.modified
+	r.mux.RUnlock()
+
+	if time.Since(checked) >= ReplicaRefresh {
+		info, err := os.Stat(r.path)
+		if err != nil {
+			return err
+		}
+
+		if !info.ModTime().Equal(modified) {
+			state, err := readStateFile(r.path)
+			if err != nil {
+				return err
+			}
+
+			r.mux.Lock()
+			r.state = state
+			r.modified = info.ModTime()
+			r.mux.Unlock()
+
+			modified = info.ModTime()
+			log.WithFields(log.Fields{
+				"jobs":    len(state.Stack),
+				"written": modified,
+			}).Debug("Read replica loaded the state file.")
+		}
+
+		r.mux.Lock()
+		r.checked = time.Now()
+		r.mux.Unlock()
+	}
+
+	if r.maxLag > 0 && time.Since(modified) > r.maxLag {
+		return ErrReplicaStale
+	}
+
+	return nil
+}
+
+func readStateFile(path string) (StateFile, error) {
+	var s StateFile
+
+	f, err := os.Open(path)
+	if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&s)
+	return s, err
+}
+
+// Get a copy of every job as of the last state file
+func (r *Replica) AllJobs() []common.Job {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	jobs := make([]common.Job, len(r.state.Stack))
+	for i := range r.state.Stack {
+		jobs[i] = r.state.Stack[i].Clone()
+	}
+
+	return jobs
+}
+
+// Get a job with all of its output, including rows spilled to storage
+func (r *Replica) JobOutput(jobUUID string) (common.Job, error) {
+	r.mux.RLock()
+	var j common.Job
+	found := false
+	for i := range r.state.Stack {
+		if r.state.Stack[i].UUID == jobUUID {
+			j = r.state.Stack[i].Clone()
+			found = true
+			break
+		}
+	}
+	r.mux.RUnlock()
+
+	if !found {
+		return j, ErrJobNotFound
+	}
+
+	return withSpilledOutput(j)
+}
+
+// Get the tools the resources had as of the last state file
+func (r *Replica) AllTools() map[string]common.Tool {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	tools := map[string]common.Tool{}
+	for _, res := range r.state.Pool {
+		for id, t := range res.Tools {
+			if _, ok := tools[id]; !ok {
+				tools[id] = t
+			}
+		}
+	}
+
+	return tools
+}
+
+// Get the usage stats of every tool as of the last state file
+func (r *Replica) ToolStats() map[string]ToolStats {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	s := Stats{Tools: r.state.Stats}
+	return s.ToolStats()
+}
+
+// Compare the cracked accounts of two jobs like the queue does
+func (r *Replica) DiffJobs(olderUUID, newerUUID string) (common.ResultDiff, error) {
+	return diffJobs(r, olderUUID, newerUUID)
+}
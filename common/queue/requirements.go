@@ -0,0 +1,90 @@
+package queue
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Find a resource with the tool of a job and build a call with the
+// parameters the job will actually run with. The client is nil when no
+// resource has the tool.
+func (q *Queue) toolCall(j common.Job) (*ResourceClient, common.RPCCall) {
+	q.RLock()
+	defer q.RUnlock()
+
+	call := common.RPCCall{Job: common.Job{ToolUUID: j.ToolUUID}}
+	for _, res := range q.pool {
+		if res.Status == common.STATUS_QUIT {
+			continue
+		}
+
+		if tool, ok := res.Tools[j.ToolUUID]; ok {
+			call.Job.ToolUUID = tool.UUID
+
+			// The tool sees the parameters the job will actually run with,
+			// in the form it has now
+			call.Job.Parameters = j.Parameters
+			if params, err := tool.MigrateParameters(j.FormVersion, j.Parameters); err == nil {
+				call.Job.Parameters = params
+			}
+			if d, ok := q.defaults[tool.Name]; ok {
+				call.Job.Parameters = d.Apply(call.Job.Parameters)
+			}
+			return res.Client, call
+		}
+	}
+
+	return nil, call
+}
+
+// Ask the tool of a job what hardware its parameters need and add it to the
+// constraints of the job. The queue should NOT be locked as this makes an RPC
+// call to a resource.
+func (q *Queue) addToolRequirements(j *common.Job) {
+	client, call := q.toolCall(*j)
+	if client == nil {
+		return
+	}
+
+	var c common.Constraints
+	err := client.Call("Queue.ToolRequirements", call, &c)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"job":   j.UUID,
+			"tool":  j.ToolUUID,
+			"error": err.Error(),
+		}).Warn("Unable to get job requirements from tool.")
+		return
+	}
+
+	if !c.IsZero() {
+		log.WithFields(log.Fields{
+			"job":         j.UUID,
+			"constraints": c,
+		}).Debug("Tool added requirements to job.")
+		j.Constraints = j.Constraints.Merge(c)
+	}
+}
+
+// Ask the tool of a job to check its parameters for mistakes. Tools that can
+// not be reached return no issues so jobs are not refused because of it. The
+// queue should NOT be locked as this makes an RPC call to a resource.
+func (q *Queue) CheckJobInput(j common.Job) []common.InputIssue {
+	client, call := q.toolCall(j)
+	if client == nil {
+		return nil
+	}
+
+	var issues []common.InputIssue
+	err := client.Call("Queue.ToolCheckInput", call, &issues)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"job":   j.UUID,
+			"tool":  j.ToolUUID,
+			"error": err.Error(),
+		}).Warn("Unable to check job input with tool.")
+		return nil
+	}
+
+	return issues
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"errors"
+	"net/rpc"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Returned when a resource is asked to do something while it is not connected
+var ErrResourceOffline = errors.New("Resource is not connected.")
+
+// Ask a resource to load its tools again, such as after hashcat is upgraded or
+// wordlists are added, and take the tools and inventory it reports. If some of
+// its tools fail to load the rest are still taken and the error of the resource
+// is returned with the updated resource.
+func (q *Queue) RescanResource(resUUID string) (Resource, error) {
+	q.RLock()
+	res, ok := q.pool[resUUID]
+	q.RUnlock()
+
+	if !ok {
+		return Resource{}, ErrResourceNotFound
+	}
+	if res.Client == nil || (res.Status != common.STATUS_RUNNING && res.Status != common.STATUS_PAUSED) {
+		return res.clone(), ErrResourceOffline
+	}
+
+	var inv common.Inventory
+	rescanErr := res.Client.Call("Queue.RescanTools", common.RPCCall{}, &inv)
+	if rescanErr != nil {
+		if _, ok := rescanErr.(rpc.ServerError); !ok {
+			log.WithFields(log.Fields{
+				"resource": resUUID,
+				"error":    rescanErr.Error(),
+			}).Error("Unable to rescan resource tools.")
+			return res.clone(), rescanErr
+		}
+
+		// The inventory is not sent back with an error so it is asked for
+		inv = common.Inventory{}
+		err := res.Client.Call("Queue.ResourceInventory", common.RPCCall{}, &inv)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"resource": resUUID,
+				"error":    err.Error(),
+			}).Warn("Unable to gather resource inventory.")
+		}
+	}
+
+	q.Lock()
+	if res, ok = q.pool[resUUID]; ok {
+		res.Inventory = inv
+		res.inventoried = time.Now()
+		q.pool[resUUID] = res
+	}
+	q.Unlock()
+
+	q.LoadRemoteResourceTools(resUUID)
+
+	q.RLock()
+	res = q.pool[resUUID].clone()
+	q.RUnlock()
+
+	// New tools or files may let waiting jobs start
+	q.wakeDispatch()
+
+	log.WithFields(log.Fields{
+		"resource": resUUID,
+		"tools":    len(res.Tools),
+		"files":    len(res.Inventory.Files),
+	}).Info("Resource tools rescanned.")
+
+	return res, rescanErr
+}
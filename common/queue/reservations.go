@@ -0,0 +1,186 @@
+package queue
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/pborman/uuid"
+)
+
+// Returned when a reservation UUID does not exist
+var ErrReservationNotFound = errors.New("Reservation does not exist!")
+
+// Resources blocked out for the jobs of a project during a window. Jobs of
+// other projects already running when the window starts are left to finish.
+type Reservation struct {
+	UUID      string
+	Project   string
+	Resources []string // UUIDs of the reserved resources
+	Start     time.Time
+	End       time.Time
+	CreatedBy string
+	Note      string
+}
+
+// Check if the reservation covers the resource at the time given
+func (r Reservation) Covers(resUUID string, at time.Time) bool {
+	if at.Before(r.Start) || !at.Before(r.End) {
+		return false
+	}
+
+	for _, id := range r.Resources {
+		if id == resUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the window of two reservations overlap with a shared resource
+func (r Reservation) conflicts(o Reservation) bool {
+	if !r.Start.Before(o.End) || !o.Start.Before(r.End) {
+		return false
+	}
+
+	for _, id := range r.Resources {
+		for _, oid := range o.Resources {
+			if id == oid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reserve resources for a project. Resources can only be reserved by one
+// project at a time; reservations of the same project may overlap.
+func (q *Queue) AddReservation(r Reservation) (Reservation, error) {
+	if r.Project == "" {
+		return Reservation{}, errors.New("A reservation must be for a project.")
+	}
+	if !r.End.After(r.Start) {
+		return Reservation{}, errors.New("A reservation must end after it starts.")
+	}
+	if len(r.Resources) == 0 {
+		return Reservation{}, errors.New("A reservation must include at least one resource.")
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	for _, id := range r.Resources {
+		if _, ok := q.pool[id]; !ok {
+			return Reservation{}, ErrResourceNotFound
+		}
+	}
+
+	for _, o := range q.reservations {
+		if o.Project != r.Project && r.conflicts(o) {
+			return Reservation{}, errors.New("A resource is already reserved for project " + o.Project + " during that time.")
+		}
+	}
+
+	r.UUID = uuid.New()
+	r.Resources = append([]string(nil), r.Resources...)
+	q.reservations = append(q.reservations, r)
+
+	log.WithFields(log.Fields{
+		"reservation": r.UUID,
+		"project":     r.Project,
+		"resources":   len(r.Resources),
+		"start":       r.Start,
+		"end":         r.End,
+	}).Info("Resources reserved for project.")
+
+	if StateFileLocation != "" {
+		q.writeState()
+	}
+
+	return r, nil
+}
+
+// Remove a reservation so its resources run jobs from every project
+func (q *Queue) RemoveReservation(resvUUID string) error {
+	q.Lock()
+	defer q.Unlock()
+
+	for i := range q.reservations {
+		if q.reservations[i].UUID != resvUUID {
+			continue
+		}
+
+		q.reservations = append(q.reservations[:i], q.reservations[i+1:]...)
+		log.WithField("reservation", resvUUID).Info("Reservation removed.")
+
+		if StateFileLocation != "" {
+			q.writeState()
+		}
+
+		// Jobs held back by the reservation can now be sent
+		q.wakeDispatch()
+		return nil
+	}
+
+	return ErrReservationNotFound
+}
+
+// Get every reservation that has not ended, ordered by when they start
+func (q *Queue) Reservations() []Reservation {
+	q.RLock()
+	defer q.RUnlock()
+
+	now := time.Now()
+	out := []Reservation{}
+	for _, r := range q.reservations {
+		if r.End.After(now) {
+			c := r
+			c.Resources = append([]string(nil), r.Resources...)
+			out = append(out, c)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Start.Before(out[j].Start)
+	})
+
+	return out
+}
+
+// Get the project the resource is reserved for at the time given, empty if
+// it is not reserved
+func reservedFor(reservations []Reservation, resUUID string, at time.Time) string {
+	for _, r := range reservations {
+		if r.Covers(resUUID, at) {
+			return r.Project
+		}
+	}
+	return ""
+}
+
+// Check if the job can be given to the resource at the time given
+func reservationAllows(reservations []Reservation, resUUID string, j common.Job, at time.Time) bool {
+	project := reservedFor(reservations, resUUID, at)
+	return project == "" || project == j.Project
+}
+
+// Forget reservations that have ended
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) expireReservations() {
+	now := time.Now()
+
+	kept := q.reservations[:0]
+	for _, r := range q.reservations {
+		if r.End.After(now) {
+			kept = append(kept, r)
+		} else {
+			log.WithFields(log.Fields{
+				"reservation": r.UUID,
+				"project":     r.Project,
+			}).Debug("Reservation ended.")
+		}
+	}
+	q.reservations = kept
+}
@@ -2,18 +2,31 @@ package queue
 
 import (
 	"github.com/jmmcatee/cracklord/common"
-	"net/rpc"
+	"time"
 )
 
 type ResourcePool map[string]Resource
 
 type Resource struct {
-	Client   *rpc.Client
-	Name     string
-	Address  string
-	Hardware map[string]bool
-	Tools    map[string]common.Tool
-	Status   string // Can be running, paused, quit
+	Client      *ResourceClient
+	Name        string
+	Address     string
+	Hardware    map[string]bool
+	Inventory   common.Inventory // GPUs, CPU and memory reported by the resource
+	Tools       map[string]common.Tool
+	Status      string // Can be running, paused, quit
+	Throttle    *common.Throttle `json:"-"`
+	Bandwidth   int64            // Bytes per second all traffic with the resource is limited to, 0 for ResourceBandwidth and negative for no limit
+	Draining    bool             `json:"-"` // Set while a rolling update waits for its jobs to finish
+	Exclusive   bool             // Only run one job at a time whatever hardware is free
+	Profiles    []common.Profile // Workload allowed by time of day, the first that applies is used
+	CostPerHour float64          // Dollars an hour the resource costs while it runs jobs, 0 for free capacity
+	ClockOffset time.Duration    // How far the clock of the resource is ahead of the queue
+	ClockRead   time.Time        // When the clock of the resource was last measured, zero if it never was
+
+	inventoried   time.Time // When the inventory was last gathered
+	profile       string    // Name of the profile the resource was last given, empty for none
+	profileSynced bool      // The resource has been given the profile since it connected
 }
 
 func NewResourcePool() ResourcePool {
@@ -24,5 +37,40 @@ func NewResource() Resource {
 	return Resource{
 		Hardware: make(map[string]bool),
 		Tools:    make(map[string]common.Tool),
+		Throttle: common.NewThrottle(ResourceBandwidth),
+	}
+}
+
+// The rate the throttle of the resource is set to
+func (r Resource) bandwidth() int64 {
+	if r.Bandwidth == 0 {
+		return ResourceBandwidth
+	}
+	return r.Bandwidth
+}
+
+// Make a copy of the resource whose maps are safe to read without the queue lock
+func (r Resource) clone() Resource {
+	c := r
+
+	c.Hardware = make(map[string]bool, len(r.Hardware))
+	for k, v := range r.Hardware {
+		c.Hardware[k] = v
+	}
+
+	c.Inventory.GPUs = append([]common.GPU(nil), r.Inventory.GPUs...)
+	c.Inventory.Files = append([]common.InventoryFile(nil), r.Inventory.Files...)
+	c.Inventory.Binaries = make(map[string]string, len(r.Inventory.Binaries))
+	for k, v := range r.Inventory.Binaries {
+		c.Inventory.Binaries[k] = v
 	}
+
+	c.Profiles = append([]common.Profile(nil), r.Profiles...)
+
+	c.Tools = make(map[string]common.Tool, len(r.Tools))
+	for k, v := range r.Tools {
+		c.Tools[k] = v
+	}
+
+	return c
 }
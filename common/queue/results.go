@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"errors"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Returned when the result file of a job that no resource has run is asked for
+var ErrJobNotAssigned = errors.New("The job has not been run on a resource.")
+
+// Returned when the result file of a job was removed after it expired
+var ErrJobPurged = errors.New("The results of the job were purged.")
+
+// Returned when the plaintexts of a job are needed but its results are
+// encrypted for the result key of the job
+var ErrResultsEncrypted = errors.New("The results of the job are encrypted for its result key.")
+
+// Read part of the result file of a job from the resource running it. Parts
+// are read as they are asked for, so the queue never holds the whole file.
+func (q *Queue) JobResults(jobUUID string, offset, length int64) (common.ResultChunk, error) {
+	var chunk common.ResultChunk
+
+	q.RLock()
+	var job common.Job
+	var found bool
+	for i := range q.stack {
+		if q.stack[i].UUID == jobUUID {
+			job = q.stack[i]
+			found = true
+		}
+	}
+	res, ok := q.pool[job.ResAssigned]
+	q.RUnlock()
+
+	if !found {
+		return chunk, ErrJobNotFound
+	}
+	if !job.Purged.IsZero() {
+		return chunk, ErrJobPurged
+	}
+	if job.ResultKey != "" {
+		return chunk, ErrResultsEncrypted
+	}
+	if job.ResAssigned == "" {
+		return chunk, ErrJobNotAssigned
+	}
+	if !ok || res.Client == nil || res.Status == common.STATUS_QUIT {
+		return chunk, ErrResourceOffline
+	}
+
+	call := common.RPCCall{
+		Job:    common.Job{UUID: jobUUID},
+		Offset: offset,
+		Length: length,
+	}
+	err := res.Client.Call("Queue.TaskResults", call, &chunk)
+
+	return chunk, err
+}
@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How much output and performance data of a job is kept in memory. Zero
+// values keep everything.
+type Retention struct {
+	MaxOutputRows        int // Older rows are moved to the job storage, or dropped without one
+	MaxPerformancePoints int // Older points are dropped
+}
+
+// The retention used for tools without their own
+var DefaultRetention = Retention{}
+
+// Retention for the jobs of each tool by tool name
+var ToolRetention = map[string]Retention{}
+
+// How old performance data of jobs is averaged to keep charts of long jobs small
+var PerformanceTiers = common.DefaultPerformanceTiers
+
+// Get the retention of a tool
+func retentionFor(toolName string) Retention {
+	if r, ok := ToolRetention[toolName]; ok {
+		return r
+	}
+
+	return DefaultRetention
+}
+
+// Downsample the performance data of each job and cap the output and
+// performance data held for it
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) retainOutput() {
+	now := time.Now()
+	for i := range q.stack {
+		j := &q.stack[i]
+
+		j.PerformanceData = common.DownsamplePerformance(j.PerformanceData, now, PerformanceTiers)
+
+		// LM jobs need all of their output to crack the NT hashes
+		if len(j.NTHashes) > 0 {
+			continue
+		}
+
+		r := retentionFor(q.toolName(j.ToolUUID))
+		if r.MaxPerformancePoints > 0 && len(j.PerformanceData) > r.MaxPerformancePoints {
+			trimPerformance(j, r.MaxPerformancePoints)
+		}
+		if r.MaxOutputRows > 0 && j.OutputLen() > r.MaxOutputRows {
+			q.spillOutput(j, r.MaxOutputRows)
+		}
+	}
+}
+
+// Drop the oldest performance points of a job
+func trimPerformance(j *common.Job, max int) {
+	keys := make([]string, 0, len(j.PerformanceData))
+	for k := range j.PerformanceData {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		ta, erra := strconv.ParseInt(keys[a], 10, 64)
+		tb, errb := strconv.ParseInt(keys[b], 10, 64)
+		if erra != nil || errb != nil {
+			return keys[a] < keys[b]
+		}
+		return ta < tb
+	})
+
+	for _, k := range keys[:len(keys)-max] {
+		delete(j.PerformanceData, k)
+	}
+}
+
+// Move the output a resource sent back for a job into the table of the job.
+// Resources send the whole output on every update, so only the rows after
+// those already spilled or in the table are added. When the rows sent do not
+// line up with the table the resource has started over, so the table is built
+// again from what it sent after the spilled rows.
+func keepOutput(j *common.Job, from common.Job) {
+	rows := j.OutputData
+	held := from.OutputSpilled + from.Output.Len()
+
+	if from.Output.Len() > 0 && len(rows) >= held && sameRow(from.Output.Row(from.Output.Len()-1), rows[held-1]) {
+		j.Output = from.Output
+		j.OutputData = rows[held:]
+	} else {
+		j.Output = nil
+		if from.OutputSpilled < len(rows) {
+			j.OutputData = rows[from.OutputSpilled:]
+		} else {
+			j.OutputData = nil
+		}
+	}
+
+	j.CompactOutput()
+}
+
+func sameRow(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Keep only the newest rows of output in memory, the older rows of the table
+// are stored
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) spillOutput(j *common.Job, max int) {
+	j.CompactOutput()
+	cut := j.Output.Len() - max
+
+	if JobStorage != nil {
+		rows := make([][]string, cut)
+		for i := range rows {
+			rows[i] = j.Output.Row(i)
+		}
+
+		// Storage may be remote so the keeper does not wait on it
+		go storeOutput(j.UUID, j.OutputSpilled, rows)
+	}
+	j.OutputSpilled += cut
+	j.Output = j.Output.From(cut)
+
+	log.WithFields(log.Fields{
+		"job":     j.UUID,
+		"spilled": j.OutputSpilled,
+		"kept":    j.Output.Len(),
+	}).Debug("Job output retention applied.")
+}
+
+// Output is stored in chunks named by their first row so they sort in order
+func outputKey(jobUUID string, start int) string {
+	return jobKey(jobUUID, "output", fmt.Sprintf("%012d.csv", start))
+}
+
+func storeOutput(jobUUID string, start int, rows [][]string) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll(rows)
+
+	err := w.Error()
+	if err == nil {
+		err = JobStorage.Put(outputKey(jobUUID, start), &buf)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"job":     jobUUID,
+			"storage": JobStorage.Name(),
+			"error":   err.Error(),
+		}).Error("Unable to spill job output to storage, the rows are dropped.")
+	}
+}
+
+// Remove everything stored for a removed job
+func removeJobData(jobUUID string) {
+	if JobStorage == nil {
+		return
+	}
+
+	err := deleteJobData(JobStorage, jobUUID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"job":     jobUUID,
+			"storage": JobStorage.Name(),
+			"error":   err.Error(),
+		}).Warn("Unable to remove stored job data.")
+	}
+}
+
+// Get a job with all of its output, including rows spilled to storage
+func (q *Queue) JobOutput(jobUUID string) (common.Job, error) {
+	j, err := q.JobInfo(jobUUID)
+	if err != nil {
+		return j, err
+	}
+
+	return withSpilledOutput(j)
+}
+
+// Add the rows of a job spilled to storage back to its output
+func withSpilledOutput(j common.Job) (common.Job, error) {
+	if j.OutputSpilled == 0 || JobStorage == nil {
+		return j, nil
+	}
+
+	keys, err := JobStorage.List(jobKey(j.UUID, "output") + "/")
+	if err != nil {
+		return j, err
+	}
+
+	var rows [][]string
+	for _, k := range keys {
+		rc, err := JobStorage.Get(k)
+		if err != nil {
+			return j, err
+		}
+
+		r := csv.NewReader(rc)
+		r.FieldsPerRecord = -1
+		chunk, err := r.ReadAll()
+		rc.Close()
+		if err != nil {
+			return j, err
+		}
+
+		rows = append(rows, chunk...)
+	}
+
+	j.OutputData = append(rows, j.OutputRows()...)
+	j.Output = nil
+	return j, nil
+}
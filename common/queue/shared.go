@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/s3"
+)
+
+// How long the list of shared files is used before the bucket is listed again
+const sharedListInterval = time.Minute
+
+// Wordlists, rules and other files resources get straight from a bucket with
+// presigned URLs so they are not sent through the queue server
+type SharedFiles struct {
+	Client  *s3.Client
+	Expires time.Duration // How long the URLs given to resources work
+	prefix  string
+	keys    []string
+	listed  time.Time
+	mux     sync.Mutex
+}
+
+// The bucket of shared files, nil if there is none
+var Shared *SharedFiles
+
+func NewSharedFiles(c *s3.Client, prefix string, expires time.Duration) *SharedFiles {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &SharedFiles{Client: c, Expires: expires, prefix: prefix}
+}
+
+// Presign a GET of every shared file. Keys are relative to the prefix.
+func (s *SharedFiles) URLs() (map[string]string, error) {
+	s.mux.Lock()
+	if time.Since(s.listed) > sharedListInterval {
+		keys, err := s.Client.List(s.prefix)
+		if err != nil {
+			s.mux.Unlock()
+			return nil, err
+		}
+		s.keys = keys
+		s.listed = time.Now()
+	}
+	keys := s.keys
+	s.mux.Unlock()
+
+	urls := make(map[string]string, len(keys))
+	for _, k := range keys {
+		u, err := s.Client.Presign("GET", k, s.Expires)
+		if err != nil {
+			return nil, err
+		}
+		urls[strings.TrimPrefix(k, s.prefix)] = u
+	}
+
+	return urls, nil
+}
+
+// Store a file in the bucket so resources can use it as s3: followed by the
+// key. The key is relative to the prefix.
+func (s *SharedFiles) Put(key string, body io.Reader, size int64) error {
+	if err := s.Client.Put(s.prefix+key, body, size); err != nil {
+		return err
+	}
+
+	// The new file is handed out with the next call
+	s.mux.Lock()
+	s.listed = time.Time{}
+	s.mux.Unlock()
+
+	return nil
+}
+
+// The URLs sent to resources with calls that may need shared files
+func sharedFileURLs() map[string]string {
+	if Shared == nil {
+		return nil
+	}
+
+	urls, err := Shared.URLs()
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to list the shared files bucket.")
+		return nil
+	}
+
+	return urls
+}
@@ -0,0 +1,323 @@
+package queue
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Where and when a job would run if the queue was left as it is
+type PlannedJob struct {
+	JobUUID      string
+	Name         string
+	Hypothetical bool // The job was only submitted to the simulation
+	ResourceUUID string
+	ResourceName string
+	Hardware     string
+	Start        time.Time
+	Finish       time.Time
+	Estimated    bool   // False when the tool could not estimate the run time and the maximum runtime was used
+	Error        string // Why the job could not be planned
+}
+
+// The result of simulating the queue with a set of hypothetical jobs
+type Plan struct {
+	Jobs   []PlannedJob
+	Finish time.Time // When the last planned job would finish
+}
+
+// A piece of hardware on a resource and when it is next free
+type planSlot struct {
+	resUUID  string
+	hardware string
+	free     time.Time
+}
+
+// A job of the simulation and the state it is in
+type planJob struct {
+	job          common.Job
+	hypothetical bool
+	seconds      map[string]float64 // Estimated run time on each resource
+	err          error
+}
+
+// Plan where the jobs on the stack and the hypothetical jobs would run and
+// when they would finish, without dispatching anything. The hypothetical jobs
+// are placed after the stack as if they were added to the queue. Each job
+// takes the eligible hardware that is free first, in stack order, which is
+// how the dispatcher hands out hardware as it is released, and reserved
+// resources only take jobs of their project. Run times come from the tool
+// estimates of each resource and are capped at the maximum runtime of the
+// job. The queue should NOT be locked as the resources are asked for
+// estimates.
+func (q *Queue) Simulate(jobs []common.Job) Plan {
+	now := time.Now()
+
+	var planned []*planJob
+	for i := range jobs {
+		j := jobs[i]
+
+		// The tool may need more hardware than the job asked for
+		q.addToolRequirements(&j)
+		planned = append(planned, &planJob{job: j, hypothetical: true})
+	}
+
+	q.RLock()
+	var slots []*planSlot
+	pool := make(map[string]Resource, len(q.pool))
+	for resUUID, res := range q.pool {
+		if res.Status == common.STATUS_QUIT {
+			continue
+		}
+		pool[resUUID] = res.clone()
+
+		for hw := range res.Hardware {
+			slots = append(slots, &planSlot{resUUID: resUUID, hardware: hw, free: now})
+		}
+	}
+
+	reservations := append([]Reservation(nil), q.reservations...)
+	bound := q.boundQueues()
+
+	var stack []*planJob
+	for _, j := range q.stack {
+		switch j.Status {
+		case common.STATUS_RUNNING, common.STATUS_PAUSED, common.STATUS_CREATED:
+			stack = append(stack, &planJob{job: j.Clone()})
+		}
+	}
+
+	for _, p := range planned {
+		if !q.hasTool(p.job.ToolUUID) {
+			p.err = ErrNoTool
+		} else if err := q.checkJobQueue(p.job); err != nil {
+			p.err = err
+		}
+	}
+	q.RUnlock()
+	planned = append(stack, planned...)
+
+	// Slots are listed in a fixed order so the same queue gives the same plan
+	sort.Slice(slots, func(i, j int) bool {
+		if slots[i].resUUID != slots[j].resUUID {
+			return slots[i].resUUID < slots[j].resUUID
+		}
+		return slots[i].hardware < slots[j].hardware
+	})
+
+	q.estimatePlan(planned)
+
+	var plan Plan
+
+	// Running jobs hold their hardware until they finish
+	for _, p := range planned {
+		if p.job.Status != common.STATUS_RUNNING {
+			continue
+		}
+
+		slot := findSlot(slots, p.job.ResAssigned, resourceHardware(pool[p.job.ResAssigned], p.job.ToolUUID))
+		if slot == nil {
+			continue
+		}
+
+		start := p.job.StartTime
+		if start.IsZero() {
+			start = now
+		}
+		finish, estimated := p.finish(slot.resUUID, start)
+		if finish.Before(now) {
+			finish = now
+		}
+		occupy(slots, slot, finish, pool[slot.resUUID].Exclusive)
+
+		plan.add(p, pool[slot.resUUID].Name, slot, start, finish, estimated)
+	}
+
+	for _, p := range planned {
+		if p.job.Status == common.STATUS_RUNNING {
+			continue
+		}
+		if p.err != nil {
+			plan.Jobs = append(plan.Jobs, PlannedJob{
+				JobUUID:      p.job.UUID,
+				Name:         p.job.Name,
+				Hypothetical: p.hypothetical,
+				Error:        p.err.Error(),
+			})
+			continue
+		}
+
+		var best *planSlot
+		for _, s := range slots {
+			res := pool[s.resUUID]
+			if !canRun(res, p.job, s.hardware) {
+				continue
+			}
+			if p.job.Status == common.STATUS_PAUSED && p.job.ResAssigned != s.resUUID {
+				continue
+			}
+			if !reservationAllows(reservations, s.resUUID, p.job, s.free) {
+				continue
+			}
+			if !queueAllows(bound[s.resUUID], p.job) {
+				continue
+			}
+			if best == nil || s.free.Before(best.free) {
+				best = s
+			}
+		}
+
+		if best == nil {
+			plan.Jobs = append(plan.Jobs, PlannedJob{
+				JobUUID:      p.job.UUID,
+				Name:         p.job.Name,
+				Hypothetical: p.hypothetical,
+				Error:        "No running resource can run the job.",
+			})
+			continue
+		}
+
+		start := best.free
+		finish, estimated := p.finish(best.resUUID, start)
+		occupy(slots, best, finish, pool[best.resUUID].Exclusive)
+
+		plan.add(p, pool[best.resUUID].Name, best, start, finish, estimated)
+	}
+
+	return plan
+}
+
+// Mark a slot busy until a job finishes. Every slot of an exclusive resource
+// is busy while any of them is.
+func occupy(slots []*planSlot, slot *planSlot, finish time.Time, exclusive bool) {
+	slot.free = finish
+	if !exclusive {
+		return
+	}
+
+	for _, s := range slots {
+		if s.resUUID == slot.resUUID && s.free.Before(finish) {
+			s.free = finish
+		}
+	}
+}
+
+// Ask the resources for the run time of every job, a few at a time
+func (q *Queue) estimatePlan(planned []*planJob) {
+	workers := DispatchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for _, p := range planned {
+		if p.err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(p *planJob) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			p.seconds = map[string]float64{}
+
+			// Jobs already sent to a resource have the UUID of its tool and
+			// parameters that have had the defaults applied
+			_, call := q.toolCall(p.job)
+			params := call.Job.Parameters
+			if params == nil {
+				params = p.job.Parameters
+			}
+
+			estimates, err := q.ToolEstimate(p.job.ToolUUID, params)
+			if err != nil {
+				return
+			}
+			for _, e := range estimates {
+				if e.Error == "" && e.Seconds > 0 {
+					p.seconds[e.ResourceUUID] = e.Seconds
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// When the job would finish if it started on the resource at the time given.
+// The maximum runtime is used when there is no estimate, as the queue expires
+// jobs once they reach it.
+func (p *planJob) finish(resUUID string, start time.Time) (time.Time, bool) {
+	maxRuntime := p.job.MaxRuntime
+	if maxRuntime <= 0 {
+		maxRuntime = MaxJobRuntime
+	}
+
+	seconds, ok := p.seconds[resUUID]
+	if !ok {
+		return start.Add(maxRuntime), false
+	}
+
+	run := time.Duration(seconds * float64(time.Second))
+	if maxRuntime > 0 && run > maxRuntime {
+		run = maxRuntime
+	}
+
+	return start.Add(run), true
+}
+
+func (plan *Plan) add(p *planJob, resName string, slot *planSlot, start, finish time.Time, estimated bool) {
+	plan.Jobs = append(plan.Jobs, PlannedJob{
+		JobUUID:      p.job.UUID,
+		Name:         p.job.Name,
+		Hypothetical: p.hypothetical,
+		ResourceUUID: slot.resUUID,
+		ResourceName: resName,
+		Hardware:     slot.hardware,
+		Start:        start,
+		Finish:       finish,
+		Estimated:    estimated,
+	})
+
+	if finish.After(plan.Finish) {
+		plan.Finish = finish
+	}
+}
+
+// Check if the dispatcher would send a new job to the hardware of the resource
+func canRun(res Resource, j common.Job, hardware string) bool {
+	if res.Status != common.STATUS_RUNNING || res.Draining {
+		return false
+	}
+
+	if j.Status == common.STATUS_PAUSED {
+		return resourceHardware(res, j.ToolUUID) == hardware
+	}
+
+	tool, ok := res.Tools[j.ToolUUID]
+	return ok && tool.Requirements == hardware && res.Inventory.Satisfies(j.Constraints)
+}
+
+// The hardware the tool of a job uses on the resource it was assigned to,
+// whose tool UUID may differ from the one of the queue (See AddJob)
+func resourceHardware(res Resource, toolUUID string) string {
+	for _, t := range res.Tools {
+		if t.UUID == toolUUID {
+			return t.Requirements
+		}
+	}
+	return ""
+}
+
+func findSlot(slots []*planSlot, resUUID, hardware string) *planSlot {
+	for _, s := range slots {
+		if s.resUUID == resUUID && s.hardware == hardware {
+			return s
+		}
+	}
+	return nil
+}
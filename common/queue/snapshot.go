@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// A copy of the tools and resources of the queue made for API reads, so clients
+// polling the API do not wait on the queue lock while the keeper is scheduling.
+// A snapshot is shared between readers and must not be changed.
+type Snapshot struct {
+	Taken     time.Time
+	Tools     map[string]common.Tool // Tools of every resource that has not quit
+	Resources []ResourceSnapshot     // Resources sorted by manager
+}
+
+// A resource with the parameters its manager keeps for it
+type ResourceSnapshot struct {
+	Resource
+	ID      string
+	Manager string
+	Params  map[string]string
+}
+
+// The current snapshot and a generation that changes each time it is invalidated
+type snapshotCache struct {
+	snap *Snapshot
+	gen  uint64
+	mux  sync.Mutex
+}
+
+// Get the latest snapshot, building a new one if it was invalidated. The queue
+// should NOT be locked.
+func (q *Queue) Snapshot() *Snapshot {
+	q.snapshots.mux.Lock()
+	snap, gen := q.snapshots.snap, q.snapshots.gen
+	q.snapshots.mux.Unlock()
+
+	if snap != nil {
+		return snap
+	}
+
+	snap = q.buildSnapshot()
+
+	// Keep it unless something changed while it was being built
+	q.snapshots.mux.Lock()
+	if q.snapshots.gen == gen {
+		q.snapshots.snap = snap
+	}
+	q.snapshots.mux.Unlock()
+
+	return snap
+}
+
+// Drop the current snapshot so the next read sees changes to the resources
+func (q *Queue) InvalidateSnapshot() {
+	q.snapshots.mux.Lock()
+	q.snapshots.snap = nil
+	q.snapshots.gen++
+	q.snapshots.mux.Unlock()
+}
+
+// Replace the snapshot with the current state. The queue should NOT be locked.
+func (q *Queue) refreshSnapshot() {
+	q.InvalidateSnapshot()
+	q.Snapshot()
+}
+
+func (q *Queue) buildSnapshot() *Snapshot {
+	q.RLock()
+	snap := &Snapshot{
+		Taken: time.Now(),
+		Tools: q.activeTools(),
+	}
+	pool := make(map[string]Resource, len(q.pool))
+	for id, res := range q.pool {
+		pool[id] = res.clone()
+	}
+	q.RUnlock()
+
+	// Managers look resources up in the queue so they are asked without the lock
+	managers := q.AllResourceManagers()
+	ids := make([]string, 0, len(managers))
+	for managerid := range managers {
+		ids = append(ids, managerid)
+	}
+	sort.Strings(ids)
+
+	for _, managerid := range ids {
+		manager := managers[managerid]
+		for _, resourceid := range manager.GetManagedResources() {
+			res, ok := pool[resourceid]
+			if !ok {
+				continue
+			}
+
+			var params map[string]string
+			if _, p, err := manager.GetResource(resourceid); err == nil {
+				params = p
+			}
+
+			snap.Resources = append(snap.Resources, ResourceSnapshot{
+				Resource: res,
+				ID:       resourceid,
+				Manager:  managerid,
+				Params:   params,
+			})
+		}
+	}
+
+	return snap
+}
@@ -1,11 +1,41 @@
 package queue
 
+import (
+	"github.com/jmmcatee/cracklord/common"
+	"strings"
+	"time"
+)
+
+// Parameter values longer than this, such as hash lists, are not tracked
+const maxStatValueLength = 128
+
 type Stats struct {
 	jobsCount int64
+	Tools     map[string]*ToolStats // Usage of each tool keyed by tool name
+	recorded  map[string]bool       // Jobs already counted in the tool stats
+}
+
+// Usage of a tool across every finished job
+type ToolStats struct {
+	Jobs    int64
+	Cracked int64
+	Total   int64
+	Runtime time.Duration
+	Params  map[string]map[string]*ParamStats // Parameter name to value to usage
+}
+
+// Usage of a single value of a tool parameter
+type ParamStats struct {
+	Jobs    int64
+	Cracked int64
+	Total   int64
 }
 
 func NewStats() Stats {
-	return Stats{}
+	return Stats{
+		Tools:    map[string]*ToolStats{},
+		recorded: map[string]bool{},
+	}
 }
 
 func (s *Stats) IncJob() {
@@ -15,3 +45,105 @@ func (s *Stats) IncJob() {
 func (s *Stats) JobCount() int64 {
 	return s.jobsCount
 }
+
+// Mark a job as counted without adding it to the stats, used for jobs loaded
+// from the state file that were counted before the restart
+func (s *Stats) Skip(uuid string) {
+	s.recorded[uuid] = true
+}
+
+// Add a finished job to the usage of its tool. Each job is only counted once
+// and jobs that never started are ignored.
+func (s *Stats) RecordJob(tool string, j common.Job) {
+	if s.recorded[j.UUID] || !common.IsDone(j.Status) || j.StartTime.IsZero() {
+		return
+	}
+	s.recorded[j.UUID] = true
+
+	ts, ok := s.Tools[tool]
+	if !ok {
+		ts = &ToolStats{Params: map[string]map[string]*ParamStats{}}
+		s.Tools[tool] = ts
+	}
+
+	ts.Jobs++
+	ts.Cracked += j.CrackedHashes
+	ts.Total += j.TotalHashes
+	// Jobs are recorded on the first keeper pass after they finish, so the time
+	// since they started is close enough to their runtime
+	ts.Runtime += time.Since(j.StartTime)
+
+	for name, value := range j.Parameters {
+		if value == "" || len(value) > maxStatValueLength || strings.ContainsAny(value, "\r\n") {
+			continue
+		}
+
+		values, ok := ts.Params[name]
+		if !ok {
+			values = map[string]*ParamStats{}
+			ts.Params[name] = values
+		}
+
+		ps, ok := values[value]
+		if !ok {
+			ps = &ParamStats{}
+			values[value] = ps
+		}
+
+		ps.Jobs++
+		ps.Cracked += j.CrackedHashes
+		ps.Total += j.TotalHashes
+	}
+}
+
+// Make a copy of the tool stats that is safe to use without the queue lock
+func (s *Stats) ToolStats() map[string]ToolStats {
+	out := make(map[string]ToolStats, len(s.Tools))
+
+	for name, ts := range s.Tools {
+		c := *ts
+		c.Params = make(map[string]map[string]*ParamStats, len(ts.Params))
+		for p, values := range ts.Params {
+			c.Params[p] = make(map[string]*ParamStats, len(values))
+			for v, ps := range values {
+				cp := *ps
+				c.Params[p][v] = &cp
+			}
+		}
+		out[name] = c
+	}
+
+	return out
+}
+
+// Add any newly finished jobs to the tool usage stats
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) recordStats() {
+	for _, j := range q.stack {
+		if common.IsDone(j.Status) {
+			q.stats.RecordJob(q.toolName(j.ToolUUID), j)
+		}
+	}
+}
+
+// Find the name of a tool from either the queue or the resource UUID of it
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) toolName(toolUUID string) string {
+	for _, res := range q.pool {
+		for id, tool := range res.Tools {
+			if id == toolUUID || tool.UUID == toolUUID {
+				return tool.Name
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+// Get the usage stats of every tool
+func (q *Queue) ToolStats() map[string]ToolStats {
+	q.RLock()
+	defer q.RUnlock()
+
+	return q.stats.ToolStats()
+}
@@ -0,0 +1,296 @@
+package queue
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jmmcatee/cracklord/common/azblob"
+	"github.com/jmmcatee/cracklord/common/s3"
+)
+
+var ErrStorageNotFound = errors.New("The object was not found in storage.")
+
+// Where job data too large for the memory or root volume of the queue server
+// is kept, such as output and uploaded files. Keys are paths separated by
+// slashes.
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error) // ErrStorageNotFound if the key does not exist
+	List(prefix string) ([]string, error)  // Keys starting with the prefix in order
+	Delete(key string) error
+	Name() string
+}
+
+// The storage used for job data, nil if there is none
+var JobStorage Storage
+
+// The key of job data in storage
+func jobKey(jobUUID string, parts ...string) string {
+	return strings.Join(append([]string{"jobs", jobUUID}, parts...), "/")
+}
+
+// Delete everything stored for a job
+func deleteJobData(s Storage, jobUUID string) error {
+	keys, err := s.List(jobKey(jobUUID) + "/")
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := s.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Objects on the local disk of the queue server, or storage mounted on it
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{root: root}, nil
+}
+
+func (l *LocalStorage) Name() string {
+	return "local"
+}
+
+func (l *LocalStorage) path(key string) (string, error) {
+	p := filepath.Join(l.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(l.root)+string(filepath.Separator)) {
+		return "", errors.New("The storage key is outside of the storage directory.")
+	}
+
+	return p, nil
+}
+
+// Write to a temporary file first so readers never see part of an object
+func (l *LocalStorage) Put(key string, r io.Reader) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p), ".put-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+func (l *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrStorageNotFound
+	}
+
+	return f, err
+}
+
+func (l *LocalStorage) List(prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.Walk(l.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".put-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (l *LocalStorage) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Object stores need the size of an object before it is sent, so readers of an
+// unknown size are written to a temporary file first
+func sized(r io.Reader) (io.Reader, int64, func(), error) {
+	if s, ok := r.(io.Seeker); ok {
+		if cur, err := s.Seek(0, io.SeekCurrent); err == nil {
+			if end, err := s.Seek(0, io.SeekEnd); err == nil {
+				if _, err := s.Seek(cur, io.SeekStart); err == nil {
+					return r, end - cur, func() {}, nil
+				}
+			}
+		}
+	}
+
+	f, err := ioutil.TempFile("", "cracklord-storage-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	done := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	size, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		done()
+		return nil, 0, nil, err
+	}
+
+	return f, size, done, nil
+}
+
+// Objects in an S3 bucket or a compatible store such as MinIO
+type S3Storage struct {
+	Client *s3.Client
+	prefix string
+}
+
+func NewS3Storage(c *s3.Client, prefix string) *S3Storage {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &S3Storage{Client: c, prefix: prefix}
+}
+
+func (s *S3Storage) Name() string {
+	return "s3"
+}
+
+func (s *S3Storage) Put(key string, r io.Reader) error {
+	body, size, done, err := sized(r)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return s.Client.Put(s.prefix+key, body, size)
+}
+
+func (s *S3Storage) Get(key string) (io.ReadCloser, error) {
+	rc, err := s.Client.Get(s.prefix + key)
+	if err == s3.ErrNotFound {
+		return nil, ErrStorageNotFound
+	}
+
+	return rc, err
+}
+
+func (s *S3Storage) List(prefix string) ([]string, error) {
+	keys, err := s.Client.List(s.prefix + prefix)
+	for i := range keys {
+		keys[i] = strings.TrimPrefix(keys[i], s.prefix)
+	}
+
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (s *S3Storage) Delete(key string) error {
+	return s.Client.Delete(s.prefix + key)
+}
+
+// Blobs in an Azure Blob Storage container
+type AzureStorage struct {
+	Client *azblob.Client
+	prefix string
+}
+
+func NewAzureStorage(c *azblob.Client, prefix string) *AzureStorage {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &AzureStorage{Client: c, prefix: prefix}
+}
+
+func (a *AzureStorage) Name() string {
+	return "azure"
+}
+
+func (a *AzureStorage) Put(key string, r io.Reader) error {
+	body, size, done, err := sized(r)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return a.Client.Put(a.prefix+key, body, size)
+}
+
+func (a *AzureStorage) Get(key string) (io.ReadCloser, error) {
+	rc, err := a.Client.Get(a.prefix + key)
+	if err == azblob.ErrNotFound {
+		return nil, ErrStorageNotFound
+	}
+
+	return rc, err
+}
+
+func (a *AzureStorage) List(prefix string) ([]string, error) {
+	keys, err := a.Client.List(a.prefix + prefix)
+	for i := range keys {
+		keys[i] = strings.TrimPrefix(keys[i], a.prefix)
+	}
+
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (a *AzureStorage) Delete(key string) error {
+	return a.Client.Delete(a.prefix + key)
+}
@@ -0,0 +1,310 @@
+package queue
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/pborman/uuid"
+	"time"
+)
+
+// Status of each resource in a rolling update
+const (
+	UPDATE_PENDING   = "pending"
+	UPDATE_DRAINING  = "draining"
+	UPDATE_UPDATING  = "updating"
+	UPDATE_VERIFYING = "verifying"
+	UPDATE_DONE      = "done"
+	UPDATE_FAILED    = "failed"
+	UPDATE_SKIPPED   = "skipped"
+)
+
+// How long a resource has to restart and report the new build after an update
+var UpdateVerifyTimeout = 5 * time.Minute
+
+// How often a rolling update checks on the resource it is working on
+var UpdatePollInterval = 5 * time.Second
+
+// Progress of a single resource in a rolling update
+type ResourceUpdate struct {
+	UUID   string // Reverse connected resources get a new UUID when they reconnect
+	Name   string
+	Status string
+	Error  string
+}
+
+// A rolling update of resourceservers, which are drained, updated, verified
+// and returned to service one at a time
+type UpdateRollout struct {
+	ID        string
+	Build     string
+	Status    string
+	Error     string
+	StartTime time.Time
+	EndTime   time.Time
+	Resources []ResourceUpdate
+}
+
+func (u UpdateRollout) clone() UpdateRollout {
+	c := u
+	c.Resources = append([]ResourceUpdate{}, u.Resources...)
+	return c
+}
+
+// Start a rolling update of the given resources, or every connected resource
+// if none are given. Resources verify the signature themselves so an
+// unsigned package fails on the first resource. drain is how long to wait for
+// jobs on each resource to finish before giving up.
+func (q *Queue) StartUpdate(pkg common.UpdatePackage, resources []string, drain time.Duration) (UpdateRollout, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if q.rollout != nil && q.rollout.Status == common.STATUS_RUNNING {
+		return UpdateRollout{}, errors.New("An update is already in progress.")
+	}
+
+	if len(pkg.Binary) == 0 {
+		return UpdateRollout{}, errors.New("Update package does not contain a binary.")
+	}
+
+	if len(resources) == 0 {
+		for id, res := range q.pool {
+			if res.Status != common.STATUS_QUIT {
+				resources = append(resources, id)
+			}
+		}
+	}
+
+	if len(resources) == 0 {
+		return UpdateRollout{}, errors.New("There are no connected resources to update.")
+	}
+
+	rollout := &UpdateRollout{
+		ID:        uuid.New(),
+		Build:     pkg.Build(),
+		Status:    common.STATUS_RUNNING,
+		StartTime: time.Now(),
+	}
+
+	for _, id := range resources {
+		res, ok := q.pool[id]
+		if !ok {
+			return UpdateRollout{}, ErrResourceNotFound
+		}
+		if res.Status == common.STATUS_QUIT {
+			return UpdateRollout{}, errors.New("Resource " + res.Name + " is not connected.")
+		}
+
+		rollout.Resources = append(rollout.Resources, ResourceUpdate{
+			UUID:   id,
+			Name:   res.Name,
+			Status: UPDATE_PENDING,
+		})
+	}
+
+	q.rollout = rollout
+
+	log.WithFields(log.Fields{
+		"id":        rollout.ID,
+		"build":     rollout.Build,
+		"resources": len(rollout.Resources),
+	}).Info("Starting rolling resource update.")
+
+	go q.runUpdate(pkg, drain)
+
+	return rollout.clone(), nil
+}
+
+// Get the current or most recent rolling update
+func (q *Queue) UpdateStatus() (UpdateRollout, bool) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.rollout == nil {
+		return UpdateRollout{}, false
+	}
+
+	return q.rollout.clone(), true
+}
+
+// Work through the resources of the current rollout one at a time, stopping at
+// the first failure so a bad build never reaches the whole farm
+func (q *Queue) runUpdate(pkg common.UpdatePackage, drain time.Duration) {
+	q.RLock()
+	count := len(q.rollout.Resources)
+	q.RUnlock()
+
+	for i := 0; i < count; i++ {
+		err := q.updateResource(i, pkg, drain)
+		if err == nil {
+			q.setUpdateStatus(i, UPDATE_DONE, "")
+			continue
+		}
+
+		q.setUpdateStatus(i, UPDATE_FAILED, err.Error())
+
+		q.Lock()
+		name := q.rollout.Resources[i].Name
+		for j := i + 1; j < count; j++ {
+			q.rollout.Resources[j].Status = UPDATE_SKIPPED
+		}
+		q.rollout.Status = common.STATUS_FAILED
+		q.rollout.Error = "Update of " + name + " failed: " + err.Error()
+		q.rollout.EndTime = time.Now()
+		q.Unlock()
+
+		log.WithFields(log.Fields{
+			"resource": name,
+			"error":    err.Error(),
+		}).Error("Rolling resource update stopped.")
+
+		return
+	}
+
+	q.Lock()
+	q.rollout.Status = common.STATUS_DONE
+	q.rollout.EndTime = time.Now()
+	q.Unlock()
+
+	log.Info("Rolling resource update complete.")
+}
+
+// Drain, update, verify and resume a single resource
+func (q *Queue) updateResource(i int, pkg common.UpdatePackage, drain time.Duration) error {
+	q.RLock()
+	resUUID := q.rollout.Resources[i].UUID
+	name := q.rollout.Resources[i].Name
+	q.RUnlock()
+
+	logger := log.WithFields(log.Fields{
+		"resource": name,
+		"uuid":     resUUID,
+	})
+
+	// Stop new work from being scheduled and wait for what is running to finish
+	q.setUpdateStatus(i, UPDATE_DRAINING, "")
+	q.setDraining(resUUID, true)
+	defer q.setDraining(resUUID, false)
+
+	logger.Info("Draining resource for update.")
+
+	deadline := time.Now().Add(drain)
+	for q.resourceBusy(resUUID) {
+		if time.Now().After(deadline) {
+			return errors.New("Timed out waiting for jobs on the resource to finish.")
+		}
+		time.Sleep(UpdatePollInterval)
+	}
+
+	q.RLock()
+	res, ok := q.pool[resUUID]
+	q.RUnlock()
+
+	if !ok {
+		return ErrResourceNotFound
+	}
+	if res.Status == common.STATUS_QUIT || res.Client == nil {
+		return errors.New("Resource is not connected.")
+	}
+
+	// Push the binary, the resource exits shortly after replying
+	q.setUpdateStatus(i, UPDATE_UPDATING, "")
+	logger.Info("Pushing update to resource.")
+
+	var build string
+	err := res.Client.Call("Queue.ResourceUpdate", common.RPCCall{Update: &pkg}, &build)
+	if err != nil {
+		return err
+	}
+	if build != pkg.Build() {
+		return errors.New("Resource installed a different build than was sent.")
+	}
+
+	// Wait for the resource to reconnect running the new build
+	q.setUpdateStatus(i, UPDATE_VERIFYING, "")
+	logger.Info("Waiting for resource to restart with the new build.")
+
+	deadline = time.Now().Add(UpdateVerifyTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(UpdatePollInterval)
+
+		if id, ok := q.findBuild(resUUID, name, build); ok {
+			q.Lock()
+			q.rollout.Resources[i].UUID = id
+			q.Unlock()
+
+			logger.WithField("newuuid", id).Info("Resource is running the new build.")
+			return nil
+		}
+	}
+
+	return errors.New("Resource did not come back running the new build.")
+}
+
+// Find a connected resource with the given name reporting the expected build,
+// checking the original UUID first
+func (q *Queue) findBuild(resUUID, name, build string) (string, bool) {
+	type candidate struct {
+		id  string
+		res Resource
+	}
+
+	q.RLock()
+	var candidates []candidate
+	if res, ok := q.pool[resUUID]; ok {
+		candidates = append(candidates, candidate{resUUID, res})
+	}
+	for id, res := range q.pool {
+		if id != resUUID && res.Name == name {
+			candidates = append(candidates, candidate{id, res})
+		}
+	}
+	q.RUnlock()
+
+	for _, c := range candidates {
+		if c.res.Status == common.STATUS_QUIT || c.res.Client == nil {
+			continue
+		}
+
+		var running string
+		err := c.res.Client.Call("Queue.ResourceBuild", common.RPCCall{}, &running)
+		if err == nil && running == build {
+			return c.id, true
+		}
+	}
+
+	return "", false
+}
+
+// Check if any job on the resource has not finished
+func (q *Queue) resourceBusy(resUUID string) bool {
+	q.RLock()
+	defer q.RUnlock()
+
+	for _, j := range q.stack {
+		if j.ResAssigned == resUUID && (j.Status == common.STATUS_RUNNING || j.Status == common.STATUS_PAUSED) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Mark a resource so the keeper does not give it new jobs
+func (q *Queue) setDraining(resUUID string, draining bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	if res, ok := q.pool[resUUID]; ok {
+		res.Draining = draining
+		q.pool[resUUID] = res
+	}
+}
+
+func (q *Queue) setUpdateStatus(i int, status, msg string) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.rollout.Resources[i].Status = status
+	q.rollout.Resources[i].Error = msg
+}
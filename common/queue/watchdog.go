@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// How long a running job can go without progress before it is suspect, 0
+// disables the watchdog
+var StallTimeout time.Duration
+
+// How many times a stalled job is queued again before it is left running for
+// an administrator to look at, 0 never queues it again
+var StallRequeues int
+
+// The user watchdog events are recorded as in the job history
+const WATCHDOG_USER = "watchdog"
+
+// The progress of a running job and when it last moved
+type progressMark struct {
+	progress float64
+	cracked  int64
+	at       time.Time
+}
+
+// Mark running jobs whose progress and cracked hashes have not moved within
+// the stall timeout as suspect, such as when a tool hangs or a driver crashes,
+// and queue them again if they have not been already too many times. Jobs that
+// move again are no longer suspect.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) watchJobs() {
+	if StallTimeout <= 0 {
+		q.progressed = nil
+		return
+	}
+	if q.progressed == nil {
+		q.progressed = map[string]progressMark{}
+	}
+
+	now := time.Now()
+	running := map[string]bool{}
+	for i := range q.stack {
+		if q.stack[i].Status != common.STATUS_RUNNING {
+			continue
+		}
+		running[q.stack[i].UUID] = true
+
+		mark, ok := q.progressed[q.stack[i].UUID]
+		if !ok || mark.progress != q.stack[i].Progress || mark.cracked != q.stack[i].CrackedHashes {
+			q.progressed[q.stack[i].UUID] = progressMark{
+				progress: q.stack[i].Progress,
+				cracked:  q.stack[i].CrackedHashes,
+				at:       now,
+			}
+
+			if !q.stack[i].Stalled.IsZero() {
+				q.stack[i].Stalled = time.Time{}
+				q.stack[i].Record(WATCHDOG_USER, "progressing", "Progress resumed.")
+				log.WithField("job", q.stack[i].UUID).Info("Stalled job is making progress again.")
+			}
+			continue
+		}
+
+		if !q.stack[i].Stalled.IsZero() || now.Sub(mark.at) < StallTimeout {
+			continue
+		}
+
+		q.stack[i].Stalled = now
+		q.stack[i].Record(WATCHDOG_USER, "stalled", "No progress since "+mark.at.Format(time.RFC3339)+".")
+		q.debugf(q.stack[i], "Watchdog found no progress since %s", mark.at.Format(time.RFC3339))
+
+		requeues := stallRequeues(q.stack[i])
+		log.WithFields(log.Fields{
+			"job":      q.stack[i].UUID,
+			"resource": q.stack[i].ResAssigned,
+			"since":    mark.at,
+			"requeues": requeues,
+		}).Warn("Job has stopped making progress.")
+
+		if requeues < StallRequeues {
+			q.requeueStalled(i, requeues+1)
+		}
+	}
+
+	// Forget jobs that are no longer running
+	for id := range q.progressed {
+		if !running[id] {
+			delete(q.progressed, id)
+		}
+	}
+}
+
+// Number of times the watchdog has queued a job again
+func stallRequeues(j common.Job) int {
+	var n int
+	for _, e := range j.History {
+		if e.User == WATCHDOG_USER && e.Action == "requeue" {
+			n++
+		}
+	}
+	return n
+}
+
+// Quit the task of a stalled job and put it back on the queue to continue
+// from its last checkpoint, if it has one, on the next free resource.
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) requeueStalled(i int, attempt int) {
+	jobuuid := q.stack[i].UUID
+
+	res, ok := q.pool[q.stack[i].ResAssigned]
+	if ok {
+		// A hung task may not answer, so it is quit once the resource does
+		if res.Status != common.STATUS_QUIT && res.Client != nil {
+			err := q.callJob(res.Client, "Queue.TaskQuit", i)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"job":   jobuuid,
+					"error": err.Error(),
+				}).Warn("Unable to quit stalled job on resource, it will be quit when the resource answers.")
+				q.released[jobuuid] = q.stack[i].Clone()
+			}
+		} else {
+			q.released[jobuuid] = q.stack[i].Clone()
+		}
+
+		// Free the hardware the job was using
+		for _, tool := range res.Tools {
+			if tool.UUID == q.stack[i].ToolUUID {
+				res.Hardware[tool.Requirements] = true
+			}
+		}
+	}
+
+	q.stack[i].Status = common.STATUS_CREATED
+	q.stack[i].Error = ""
+	q.stack[i].Stalled = time.Time{}
+
+	detail := "Queued again after stalling, attempt " + strconv.Itoa(attempt) + " of " + strconv.Itoa(StallRequeues) + "."
+	if cp, ok := q.checkpoints[jobuuid]; ok {
+		q.stack[i].Progress = cp.Progress
+		detail += " It continues from the checkpoint taken at " + cp.Taken.Format(time.RFC3339) + "."
+	}
+	q.stack[i].Record(WATCHDOG_USER, "requeue", detail)
+	q.debugf(q.stack[i], "%s", detail)
+
+	log.WithField("job", jobuuid).Info("Stalled job queued again.")
+
+	q.wakeDispatch()
+}
@@ -0,0 +1,312 @@
+// Package redis is a small Redis client speaking the RESP protocol. It only
+// has the commands the queue server uses to share state between replicas.
+package redis
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Returned when a key does not exist
+var ErrNil = errors.New("The key does not exist.")
+
+// An error reply sent by the server
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Most idle connections kept for reuse
+const maxIdle = 8
+
+// A Redis server and the connections open to it
+type Client struct {
+	Addr     string
+	Password string
+	DB       int
+	TLS      *tls.Config // Connect with TLS when set
+	Timeout  time.Duration
+	idle     chan *conn
+	mux      sync.Mutex
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Create a client for the server at addr, no connection is made until the
+// first command
+func New(addr, password string, db int, tlsConfig *tls.Config) *Client {
+	return &Client{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+		TLS:      tlsConfig,
+		Timeout:  5 * time.Second,
+		idle:     make(chan *conn, maxIdle),
+	}
+}
+
+// Change the password new connections authenticate with, such as when it is
+// rotated. Open connections stay authenticated.
+func (c *Client) SetPassword(password string) {
+	c.mux.Lock()
+	c.Password = password
+	c.mux.Unlock()
+}
+
+// Open a connection, authenticate and select the database
+func (c *Client) dial() (*conn, error) {
+	d := net.Dialer{Timeout: c.Timeout}
+
+	var nc net.Conn
+	var err error
+	if c.TLS != nil {
+		nc, err = tls.DialWithDialer(&d, "tcp", c.Addr, c.TLS)
+	} else {
+		nc, err = d.Dial("tcp", c.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+
+	c.mux.Lock()
+	password := c.Password
+	c.mux.Unlock()
+
+	if password != "" {
+		if _, err := c.do(cn, "AUTH", password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.DB != 0 {
+		if _, err := c.do(cn, "SELECT", strconv.Itoa(c.DB)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// Run a command. Replies are a string, int64, []interface{} or nil for a
+// missing value. Error replies are returned as an Error.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	var cn *conn
+	select {
+	case cn = <-c.idle:
+	default:
+		var err error
+		cn, err = c.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.do(cn, args...)
+	if _, ok := err.(Error); err != nil && !ok {
+		// The connection is in an unknown state after a network error
+		cn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+
+	return reply, err
+}
+
+func (c *Client) do(cn *conn, args ...string) (interface{}, error) {
+	if c.Timeout > 0 {
+		cn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+
+	if _, err := cn.Write(encode(args)); err != nil {
+		return nil, err
+	}
+
+	return readReply(cn.r)
+}
+
+// Encode a command as an array of bulk strings
+func encode(args []string) []byte {
+	b := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		b = append(b, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		b = append(b, a...)
+		b = append(b, "\r\n"...)
+	}
+	return b
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("Invalid reply from the Redis server.")
+	}
+	return line[:len(line)-2], nil
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("Invalid reply from the Redis server.")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readReply(r)
+			if _, ok := err.(Error); err != nil && !ok {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	return nil, errors.New("Invalid reply from the Redis server.")
+}
+
+// Check the server can be reached
+func (c *Client) Ping() error {
+	_, err := c.Do("PING")
+	return err
+}
+
+// Get the value of a key, ErrNil if it does not exist
+func (c *Client) Get(key string) (string, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := reply.(string)
+	if !ok {
+		return "", ErrNil
+	}
+	return s, nil
+}
+
+// Set the value of a key, which expires after ttl unless it is 0
+func (c *Client) Set(key, value string, ttl time.Duration) error {
+	args := []string{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	}
+
+	_, err := c.Do(args...)
+	return err
+}
+
+// Remove keys
+func (c *Client) Del(keys ...string) error {
+	_, err := c.Do(append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Set how long until a key expires. False is returned if it does not exist.
+func (c *Client) Expire(key string, ttl time.Duration) (bool, error) {
+	reply, err := c.Do("PEXPIRE", key, strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	if err != nil {
+		return false, err
+	}
+
+	n, _ := reply.(int64)
+	return n == 1, nil
+}
+
+// Get how long until a key expires, ErrNil if it does not exist
+func (c *Client) TTL(key string) (time.Duration, error) {
+	reply, err := c.Do("PTTL", key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, _ := reply.(int64)
+	if n == -2 {
+		return 0, ErrNil
+	}
+	if n < 0 {
+		return 0, nil
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
+// Add members to a set
+func (c *Client) SAdd(key string, members ...string) error {
+	_, err := c.Do(append([]string{"SADD", key}, members...)...)
+	return err
+}
+
+// Remove members from a set
+func (c *Client) SRem(key string, members ...string) error {
+	_, err := c.Do(append([]string{"SREM", key}, members...)...)
+	return err
+}
+
+// Get every member of a set
+func (c *Client) SMembers(key string) ([]string, error) {
+	reply, err := c.Do("SMEMBERS", key)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := reply.([]interface{})
+	members := make([]string, 0, len(items))
+	for _, i := range items {
+		if s, ok := i.(string); ok {
+			members = append(members, s)
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Serve a few commands from memory, enough to test the client
+func fakeServer(t *testing.T, password string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("Unable to listen: " + err.Error())
+	}
+
+	data := map[string]string{}
+	sets := map[string]map[string]bool{}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(c net.Conn) {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				authed := password == ""
+
+				for {
+					reply, err := readReply(r)
+					if err != nil {
+						return
+					}
+
+					var args []string
+					for _, a := range reply.([]interface{}) {
+						args = append(args, a.(string))
+					}
+
+					var out string
+					switch {
+					case args[0] == "AUTH":
+						authed = args[1] == password
+						out = "+OK\r\n"
+						if !authed {
+							out = "-WRONGPASS invalid password\r\n"
+						}
+					case !authed:
+						out = "-NOAUTH Authentication required.\r\n"
+					case args[0] == "PING":
+						out = "+PONG\r\n"
+					case args[0] == "SET":
+						data[args[1]] = args[2]
+						out = "+OK\r\n"
+					case args[0] == "GET":
+						v, ok := data[args[1]]
+						out = "$-1\r\n"
+						if ok {
+							out = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+						}
+					case args[0] == "DEL":
+						delete(data, args[1])
+						out = ":1\r\n"
+					case args[0] == "SADD":
+						if sets[args[1]] == nil {
+							sets[args[1]] = map[string]bool{}
+						}
+						for _, m := range args[2:] {
+							sets[args[1]][m] = true
+						}
+						out = ":1\r\n"
+					case args[0] == "SMEMBERS":
+						out = "*" + strconv.Itoa(len(sets[args[1]])) + "\r\n"
+						for m := range sets[args[1]] {
+							out += "$" + strconv.Itoa(len(m)) + "\r\n" + m + "\r\n"
+						}
+					default:
+						out = "-ERR unknown command\r\n"
+					}
+
+					if _, err := c.Write([]byte(out)); err != nil {
+						return
+					}
+				}
+			}(c)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestClient(t *testing.T) {
+	c := New(fakeServer(t, "secret"), "secret", 0, nil)
+
+	if err := c.Ping(); err != nil {
+		t.Fatalf("Unable to ping: %s", err.Error())
+	}
+
+	if _, err := c.Get("missing"); err != ErrNil {
+		t.Errorf("A missing key must return ErrNil, got %v", err)
+	}
+
+	if err := c.Set("key", "line one\r\nline two", time.Minute); err != nil {
+		t.Fatalf("Unable to set key: %s", err.Error())
+	}
+	if v, err := c.Get("key"); err != nil || v != "line one\r\nline two" {
+		t.Errorf("Values must be returned as they were set, got %q, %v", v, err)
+	}
+
+	if err := c.Del("key"); err != nil {
+		t.Errorf("Unable to delete key: %s", err.Error())
+	}
+	if _, err := c.Get("key"); err != ErrNil {
+		t.Errorf("A deleted key must return ErrNil, got %v", err)
+	}
+
+	c.SAdd("set", "a", "b")
+	if m, err := c.SMembers("set"); err != nil || len(m) != 2 {
+		t.Errorf("Expected 2 members, got %v, %v", m, err)
+	}
+
+	// Error replies keep the connection usable
+	if _, err := c.Do("UNKNOWN"); err == nil {
+		t.Error("An error reply must be returned as an error")
+	} else if _, ok := err.(Error); !ok {
+		t.Errorf("An error reply must be an Error, got %T", err)
+	}
+	if err := c.Ping(); err != nil {
+		t.Errorf("Unable to ping after an error reply: %s", err.Error())
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	c := New(fakeServer(t, "secret"), "wrong", 0, nil)
+
+	if err := c.Ping(); err == nil {
+		t.Error("A wrong password must fail")
+	}
+}
@@ -0,0 +1,303 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Parameters left out of the attacks listed in the methodology
+var hiddenParams = map[string]bool{
+	"hashes": true,
+}
+
+// An engagement report of the jobs of a project. Plaintexts are never part of
+// it so it can be handed to the client as it is.
+type Report struct {
+	Project     string
+	Generated   time.Time
+	Start       time.Time // When the first job started
+	End         time.Time // When the last job finished, or the report was generated if one is still going
+	Jobs        []Job
+	Accounts    int // Accounts in the hash lists of the jobs, each counted once
+	Cracked     int
+	Encrypted   int // Jobs left out of the password analysis as their results are encrypted
+	Policy      common.PolicyReport
+	Lengths     []Count // Cracked passwords of each length, shortest first
+	Violations  []Count // Cracked accounts failing each part of the policy, most first
+	Methodology []string
+}
+
+// A job run for the engagement
+type Job struct {
+	Name      string
+	Owner     string
+	Tool      string
+	Status    string
+	Started   time.Time
+	Finished  time.Time
+	Cracked   int64
+	Total     int64
+	Cost      float64
+	Attack    []string // Parameters of the tool other than the hashes
+	Encrypted bool
+}
+
+// How long the job ran, until now if it has not finished
+func (j Job) Duration(now time.Time) time.Duration {
+	if j.Started.IsZero() {
+		return 0
+	}
+	if j.Finished.IsZero() {
+		return now.Sub(j.Started)
+	}
+	return j.Finished.Sub(j.Started)
+}
+
+// Percent of the hashes of the job that were cracked
+func (j Job) Rate() float64 {
+	if j.Total == 0 {
+		return 0
+	}
+	return float64(j.Cracked) / float64(j.Total) * 100
+}
+
+// A labelled number in a chart, with its share of the largest for the bar
+type Count struct {
+	Label   string
+	Value   int
+	Percent float64
+}
+
+// Build the report of a project from its jobs with all of their output. The
+// cracked passwords of every job are checked against the policy together so
+// accounts cracked by more than one job are counted once.
+func Build(project string, jobs []common.Job, tools map[string]common.Tool, policy common.PasswordPolicy, notes []string) Report {
+	r := Report{
+		Project:     project,
+		Generated:   time.Now(),
+		Methodology: notes,
+	}
+
+	combined := common.Job{
+		OutputTitles: []string{"Hash", "Plaintext"},
+		Usernames:    map[string][]string{},
+	}
+	hashes := map[string]bool{}
+	users := map[string]map[string]bool{}
+	var purged int64
+
+	sort.SliceStable(jobs, func(a, b int) bool { return jobs[a].StartTime.Before(jobs[b].StartTime) })
+	for _, j := range jobs {
+		job := Job{
+			Name:      j.Name,
+			Owner:     j.Owner,
+			Tool:      tools[j.ToolUUID].Name,
+			Status:    j.Status,
+			Started:   j.StartTime,
+			Finished:  j.Finished,
+			Cracked:   j.CrackedHashes,
+			Total:     j.TotalHashes,
+			Cost:      j.Cost,
+			Encrypted: j.ResultKey != "",
+		}
+		if job.Tool == "" {
+			job.Tool = j.ToolUUID
+		}
+		for k, v := range j.Parameters {
+			if !hiddenParams[k] && v != "" {
+				job.Attack = append(job.Attack, k+": "+v)
+			}
+		}
+		sort.Strings(job.Attack)
+		r.Jobs = append(r.Jobs, job)
+
+		if !j.StartTime.IsZero() && (r.Start.IsZero() || j.StartTime.Before(r.Start)) {
+			r.Start = j.StartTime
+		}
+		end := j.Finished
+		if end.IsZero() && !j.StartTime.IsZero() {
+			end = r.Generated
+		}
+		if end.After(r.End) {
+			r.End = end
+		}
+
+		if job.Encrypted {
+			r.Encrypted++
+			continue
+		}
+
+		// The hash lists of purged jobs are gone so only their totals are known
+		if j.Parameters["hashes"] == "" && len(j.Usernames) == 0 {
+			purged += j.TotalHashes
+		}
+		for _, line := range strings.Split(j.Parameters["hashes"], "\n") {
+			if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+				hashes[line] = true
+			}
+		}
+		for hash, names := range j.Usernames {
+			if users[hash] == nil {
+				users[hash] = map[string]bool{}
+			}
+			for _, u := range names {
+				users[hash][u] = true
+			}
+		}
+		for hash, pw := range j.Plaintexts() {
+			combined.OutputData = append(combined.OutputData, []string{hash, pw})
+		}
+	}
+
+	for hash, names := range users {
+		combined.Usernames[hash] = []string{}
+		for u := range names {
+			combined.Usernames[hash] = append(combined.Usernames[hash], u)
+		}
+	}
+	if len(combined.Usernames) == 0 {
+		combined.Usernames = nil
+	}
+	combined.TotalHashes = int64(len(hashes)) + purged
+
+	r.Policy = policy.Report(combined)
+	r.Policy.Failures = nil
+	r.Accounts = r.Policy.Accounts
+	r.Cracked = r.Policy.Cracked
+
+	var lengths []int
+	for length := range r.Policy.Lengths {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+	for _, length := range lengths {
+		r.Lengths = append(r.Lengths, Count{Label: strconv.Itoa(length), Value: r.Policy.Lengths[length]})
+	}
+	scale(r.Lengths)
+
+	for reason, n := range r.Policy.Violations {
+		r.Violations = append(r.Violations, Count{Label: reason, Value: n})
+	}
+	sort.Slice(r.Violations, func(a, b int) bool {
+		if r.Violations[a].Value != r.Violations[b].Value {
+			return r.Violations[a].Value > r.Violations[b].Value
+		}
+		return r.Violations[a].Label < r.Violations[b].Label
+	})
+	scale(r.Violations)
+
+	return r
+}
+
+// Set the share of the largest count of each count for drawing bars
+func scale(counts []Count) {
+	max := 0
+	for _, c := range counts {
+		if c.Value > max {
+			max = c.Value
+		}
+	}
+	for i := range counts {
+		if max > 0 {
+			counts[i].Percent = float64(counts[i].Value) / float64(max) * 100
+		}
+	}
+}
+
+// Percent of the engagement that had passed at a time, for drawing the
+// timeline
+func (r Report) Offset(t time.Time) float64 {
+	span := r.End.Sub(r.Start)
+	if span <= 0 || t.Before(r.Start) {
+		return 0
+	}
+	return float64(t.Sub(r.Start)) / float64(span) * 100
+}
+
+// Percent of the engagement a job ran for, for drawing the timeline
+func (r Report) Span(j Job) float64 {
+	span := r.End.Sub(r.Start)
+	if span <= 0 || j.Started.IsZero() {
+		return 0
+	}
+	return float64(j.Duration(r.Generated)) / float64(span) * 100
+}
+
+// Percent of the accounts that were cracked
+func (r Report) Rate() float64 {
+	if r.Accounts == 0 {
+		return 0
+	}
+	return float64(r.Cracked) / float64(r.Accounts) * 100
+}
+
+// Read the methodology notes from a file, paragraphs are separated by blank
+// lines
+func ReadNotes(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []string
+	for _, p := range strings.Split(strings.Replace(string(b), "\r\n", "\n", -1), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			notes = append(notes, p)
+		}
+	}
+	return notes, nil
+}
+
+// The template and notes engagement reports are made with
+type Generator struct {
+	Template *template.Template
+	Notes    []string // Methodology notes added to every report
+}
+
+// Load the template and methodology notes of reports, the default template
+// is used when no file is given and the notes are optional
+func NewGenerator(templatePath, notesPath string) (Generator, error) {
+	var g Generator
+	var err error
+
+	g.Template, err = ParseTemplate(templatePath)
+	if err != nil {
+		return g, err
+	}
+
+	if notesPath != "" {
+		g.Notes, err = ReadNotes(notesPath)
+	}
+	return g, err
+}
+
+// Build the report of a project and write it as HTML
+func (g Generator) Generate(w io.Writer, project string, jobs []common.Job, tools map[string]common.Tool, policy common.PasswordPolicy) error {
+	return Build(project, jobs, tools, policy, g.Notes).Render(w, g.Template)
+}
+
+// Parse a report template, the default one when no file is given
+func ParseTemplate(path string) (*template.Template, error) {
+	t := template.New("report").Funcs(funcs)
+	if path == "" {
+		return t.Parse(DefaultTemplate)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return t.Parse(string(b))
+}
+
+// Write the report as HTML with the template
+func (r Report) Render(w io.Writer, t *template.Template) error {
+	return t.Execute(w, r)
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+)
+
+func TestBuild(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	jobs := []common.Job{
+		{
+			Name:          "Second",
+			ToolUUID:      "tool",
+			Project:       "acme",
+			Status:        common.STATUS_DONE,
+			StartTime:     start.Add(time.Hour),
+			Finished:      start.Add(3 * time.Hour),
+			Parameters:    map[string]string{"hashes": "AAAA\nbbbb\ncccc", "algorithm": "1000"},
+			OutputTitles:  []string{"Hash", "Plaintext"},
+			OutputData:    [][]string{{"aaaa", "Summer2026!"}, {"bbbb", "acme"}},
+			CrackedHashes: 2,
+			TotalHashes:   3,
+		},
+		{
+			Name:          "First",
+			ToolUUID:      "tool",
+			Project:       "acme",
+			Status:        common.STATUS_DONE,
+			StartTime:     start,
+			Finished:      start.Add(time.Hour),
+			Parameters:    map[string]string{"hashes": "aaaa\ndddd"},
+			OutputTitles:  []string{"Hash", "Plaintext"},
+			OutputData:    [][]string{{"aaaa", "Summer2026!"}},
+			CrackedHashes: 1,
+			TotalHashes:   2,
+		},
+		{
+			Name:       "Sealed",
+			ToolUUID:   "tool",
+			Project:    "acme",
+			Status:     common.STATUS_DONE,
+			StartTime:  start,
+			Finished:   start.Add(time.Hour),
+			Parameters: map[string]string{"hashes": "eeee"},
+			ResultKey:  "key",
+		},
+	}
+	tools := map[string]common.Tool{"tool": {Name: "Hashcat"}}
+	policy := common.PasswordPolicy{MinLength: 8}
+
+	r := Build("acme", jobs, tools, policy, []string{"Dictionary attacks first."})
+
+	if r.Jobs[0].Name != "First" || r.Jobs[0].Tool != "Hashcat" {
+		t.Errorf("Jobs not ordered by start %+v", r.Jobs[0])
+	}
+	if len(r.Jobs[2].Attack) != 1 || r.Jobs[2].Attack[0] != "algorithm: 1000" {
+		t.Errorf("Unexpected attack %v", r.Jobs[2].Attack)
+	}
+	if !r.Start.Equal(start) || !r.End.Equal(start.Add(3*time.Hour)) {
+		t.Errorf("Unexpected engagement %v to %v", r.Start, r.End)
+	}
+	if r.Accounts != 4 || r.Cracked != 2 || r.Encrypted != 1 {
+		t.Errorf("Unexpected totals %d accounts %d cracked %d encrypted", r.Accounts, r.Cracked, r.Encrypted)
+	}
+	if len(r.Violations) != 1 || r.Violations[0].Label != common.POLICY_LENGTH || r.Violations[0].Percent != 100 {
+		t.Errorf("Unexpected violations %+v", r.Violations)
+	}
+	if r.Policy.Failures != nil {
+		t.Error("Failing accounts are part of the report")
+	}
+	if o := r.Offset(start.Add(time.Hour)); o < 33 || o > 34 {
+		t.Errorf("Unexpected timeline offset %f", o)
+	}
+
+	var page bytes.Buffer
+	tmpl, err := ParseTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Render(&page, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Password cracking report: acme", "Dictionary attacks first.", "Hashcat", "width: 100.00%"} {
+		if !strings.Contains(page.String(), want) {
+			t.Errorf("Report is missing %q", want)
+		}
+	}
+	if strings.Contains(page.String(), "Summer2026!") {
+		t.Error("Report contains a plaintext")
+	}
+}
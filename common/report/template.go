@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Functions report templates can use
+var funcs = template.FuncMap{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Minute).String()
+	},
+	"pct": func(f float64) string {
+		return fmt.Sprintf("%.1f%%", f)
+	},
+	"money": func(f float64) string {
+		return fmt.Sprintf("$%.2f", f)
+	},
+	// Percentages for the CSS of bars, which html/template would otherwise
+	// refuse as unsafe
+	"css": func(f float64) template.CSS {
+		return template.CSS(fmt.Sprintf("%.2f%%", f))
+	},
+}
+
+// The report used when no template is configured. It prints to PDF from a
+// browser as the charts are plain HTML and CSS.
+const DefaultTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password cracking report: {{.Project}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 2em; }
+h1 { border-bottom: 2px solid #337ab7; padding-bottom: .3em; }
+h2 { color: #337ab7; margin-top: 1.5em; page-break-after: avoid; }
+table { border-collapse: collapse; width: 100%; font-size: .9em; }
+th, td { text-align: left; padding: .3em .5em; border-bottom: 1px solid #ddd; vertical-align: top; }
+th { background: #f5f5f5; }
+.summary td { font-size: 1.1em; }
+.chart { width: 100%; }
+.chart .row { display: flex; align-items: center; margin: .2em 0; }
+.chart .label { width: 12em; font-size: .85em; overflow: hidden; white-space: nowrap; }
+.chart .track { flex: 1; position: relative; height: 1em; background: #f5f5f5; }
+.chart .bar { position: absolute; top: 0; height: 100%; background: #337ab7; }
+.chart .value { width: 5em; text-align: right; font-size: .85em; }
+.attack { font-family: monospace; font-size: .85em; }
+.muted { color: #888; }
+@media print { body { margin: 0; } a { color: #222; } }
+</style>
+</head>
+<body>
+<h1>Password cracking report: {{.Project}}</h1>
+<p class="muted">Generated {{date .Generated}}</p>
+
+<h2>Summary</h2>
+<table class="summary">
+<tr><th>Engagement</th><td>{{date .Start}} to {{date .End}}</td></tr>
+<tr><th>Jobs run</th><td>{{len .Jobs}}</td></tr>
+<tr><th>Accounts</th><td>{{.Accounts}}</td></tr>
+<tr><th>Cracked</th><td>{{.Cracked}} ({{pct .Rate}})</td></tr>
+{{if .Policy.Cracked}}<tr><th>Compliant with the password policy</th><td>{{.Policy.Compliant}} of {{.Policy.Cracked}} cracked</td></tr>{{end}}
+</table>
+{{if .Encrypted}}<p class="muted">{{.Encrypted}} jobs encrypted their results for a recipient key and are left out of the password analysis.</p>{{end}}
+
+<h2>Jobs</h2>
+<table>
+<tr><th>Job</th><th>Tool</th><th>Owner</th><th>Status</th><th>Started</th><th>Ran for</th><th>Cracked</th><th>Cost</th></tr>
+{{range .Jobs}}<tr>
+<td>{{.Name}}</td><td>{{.Tool}}</td><td>{{.Owner}}</td><td>{{.Status}}</td>
+<td>{{date .Started}}</td><td>{{duration (.Duration $.Generated)}}</td>
+<td>{{.Cracked}} of {{.Total}} ({{pct .Rate}})</td><td>{{if .Cost}}{{money .Cost}}{{else}}-{{end}}</td>
+</tr>{{end}}
+</table>
+
+<h2>Timeline</h2>
+<div class="chart">
+{{range .Jobs}}<div class="row">
+<div class="label">{{.Name}}</div>
+<div class="track"><div class="bar" style="left: {{css ($.Offset .Started)}}; width: {{css ($.Span .)}}"></div></div>
+<div class="value">{{duration (.Duration $.Generated)}}</div>
+</div>{{end}}
+</div>
+
+<h2>Crack rates</h2>
+<div class="chart">
+{{range .Jobs}}<div class="row">
+<div class="label">{{.Name}}</div>
+<div class="track"><div class="bar" style="width: {{css .Rate}}"></div></div>
+<div class="value">{{pct .Rate}}</div>
+</div>{{end}}
+</div>
+
+{{if .Lengths}}<h2>Password analysis</h2>
+<h3>Lengths of cracked passwords</h3>
+<div class="chart">
+{{range .Lengths}}<div class="row">
+<div class="label">{{.Label}} characters</div>
+<div class="track"><div class="bar" style="width: {{css .Percent}}"></div></div>
+<div class="value">{{.Value}}</div>
+</div>{{end}}
+</div>
+{{if .Violations}}<h3>Password policy failures</h3>
+<div class="chart">
+{{range .Violations}}<div class="row">
+<div class="label">{{.Label}}</div>
+<div class="track"><div class="bar" style="width: {{css .Percent}}"></div></div>
+<div class="value">{{.Value}}</div>
+</div>{{end}}
+</div>{{end}}{{end}}
+
+<h2>Methodology</h2>
+{{range .Methodology}}<p>{{.}}</p>
+{{end}}<table>
+<tr><th>Job</th><th>Tool</th><th>Attack</th></tr>
+{{range .Jobs}}<tr><td>{{.Name}}</td><td>{{.Tool}}</td><td class="attack">{{range .Attack}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
@@ -9,7 +9,7 @@ import (
 
 func TestRunFailure(t *testing.T) {
 	// Create a queue & start the resource
-	q := Queue{authToken: "FailureTest"}
+	q := Queue{}
 	l := startRPCOnce("tcp", addr, &q)
 	defer l.Close()
 
@@ -29,7 +29,7 @@ func TestRunFailure(t *testing.T) {
 	job := common.NewJob(tool.UUID(), "Failure Test", "GoTestSuite", params)
 
 	// Try and create the job... we should get a failure
-	call := common.RPCCall{Auth: "FailureTest", Job: job}
+	call := common.RPCCall{Job: job}
 	err = client.Call("Queue.AddTask", call, nil)
 	if err == nil {
 		t.Fatal("Failure task's error was not returned.")
@@ -38,7 +38,7 @@ func TestRunFailure(t *testing.T) {
 
 func TestPauseFailure(t *testing.T) {
 	// Create a queue & start the resource
-	q := Queue{authToken: "FailureTest"}
+	q := Queue{}
 	l := startRPCOnce("tcp", addr, &q)
 	defer l.Close()
 
@@ -58,14 +58,14 @@ func TestPauseFailure(t *testing.T) {
 	job := common.NewJob(tool.UUID(), "Failure Test", "GoTestSuite", params)
 
 	// Create the failure job
-	call := common.RPCCall{Auth: "FailureTest", Job: job}
+	call := common.RPCCall{Job: job}
 	err = client.Call("Queue.AddTask", call, nil)
 	if err != nil {
 		t.Fatal("Failure task failed on the wrong call.")
 	}
 
 	// Try to pause the job... we should get an error
-	call = common.RPCCall{Auth: "FailureTest", Job: job}
+	call = common.RPCCall{Job: job}
 	err = client.Call("Queue.TaskPause", call, nil)
 	if err == nil {
 		t.Fatal("Failure task's error was not returned.")
@@ -74,7 +74,7 @@ func TestPauseFailure(t *testing.T) {
 
 func TestRunAfterPauseFailure(t *testing.T) {
 	// Create a queue & start the resource
-	q := Queue{authToken: "FailureTest"}
+	q := Queue{}
 	l := startRPCOnce("tcp", addr, &q)
 	defer l.Close()
 
@@ -94,21 +94,21 @@ func TestRunAfterPauseFailure(t *testing.T) {
 	job := common.NewJob(tool.UUID(), "Failure Test", "GoTestSuite", params)
 
 	// Create the failure job
-	call := common.RPCCall{Auth: "FailureTest", Job: job}
+	call := common.RPCCall{Job: job}
 	err = client.Call("Queue.AddTask", call, nil)
 	if err != nil {
 		t.Fatal("Failure task failed on the wrong call.")
 	}
 
 	// Try to pause the job... we should get an error
-	call = common.RPCCall{Auth: "FailureTest", Job: job}
+	call = common.RPCCall{Job: job}
 	err = client.Call("Queue.TaskPause", call, nil)
 	if err != nil {
 		println("TEST::" + err.Error())
 		t.Fatal("Failure task failed on the wrong call.")
 	}
 
-	call = common.RPCCall{Auth: "FailureTest", Job: job}
+	call = common.RPCCall{Job: job}
 	err = client.Call("Queue.TaskRun", call, nil)
 	if err == nil {
 		t.Fatal("Failure task did not fail on Resume.")
@@ -117,7 +117,7 @@ func TestRunAfterPauseFailure(t *testing.T) {
 
 func TestQuitFailure(t *testing.T) {
 	// Create a queue & start the resource
-	q := Queue{authToken: "FailureTest"}
+	q := Queue{}
 	l := startRPCOnce("tcp", addr, &q)
 	defer l.Close()
 
@@ -137,14 +137,14 @@ func TestQuitFailure(t *testing.T) {
 	job := common.NewJob(tool.UUID(), "Failure Test", "GoTestSuite", params)
 
 	// Create the failure job
-	call := common.RPCCall{Auth: "FailureTest", Job: job}
+	call := common.RPCCall{Job: job}
 	err = client.Call("Queue.AddTask", call, nil)
 	if err != nil {
 		t.Fatal("Failure task failed on the wrong call.")
 	}
 
 	// Try to quit the job... we should get an error
-	call = common.RPCCall{Auth: "FailureTest", Job: job}
+	call = common.RPCCall{Job: job}
 	err = client.Call("Queue.TaskQuit", call, &job)
 	if job.Error == "" {
 		t.Fatal("Failure task's error was not returned.")
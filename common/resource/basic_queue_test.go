@@ -13,7 +13,7 @@ var addr = "localhost:4000"
 
 func TestCreateResource(t *testing.T) {
 	// Create the RPC resource
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 	l := startRPCOnce("tcp", addr, &q)
 	defer l.Close()
 
@@ -25,7 +25,7 @@ func TestCreateResource(t *testing.T) {
 	defer client.Close()
 
 	// Do a call but ignore the out so that the RPC connection closes properly
-	rpccall := common.RPCCall{Auth: "ResourceTest"}
+	rpccall := common.RPCCall{}
 	j := []common.Job{}
 	err = client.Call("Queue.AllTaskStatus", rpccall, &j)
 	if err != nil {
@@ -33,75 +33,9 @@ func TestCreateResource(t *testing.T) {
 	}
 }
 
-func TestResourceAuth(t *testing.T) {
-	// Create the RPC Server
-	q := Queue{authToken: "ResourceTest"}
-	l := startRPCOnce("tcp", addr, &q)
-	defer l.Close()
-
-	// Just connect to the RPC service and check for errors
-	client, err := rpc.Dial("tcp", addr)
-	if err != nil {
-		t.Fatal("dialing:", err)
-	}
-	defer client.Close()
-
-	// Do a call to get tools
-	rpccall := common.RPCCall{Auth: "ResourceTest"}
-	err = client.Call("Queue.AllTaskStatus", rpccall, nil)
-	if err != nil {
-		log.Fatal("Failed call: ", err)
-	}
-
-	// Now make a call and look for the error
-	badCall := common.RPCCall{Auth: "WRONG"}
-
-	// Resource hardware
-	err = client.Call("Queue.ResourceHardware", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// AddTask
-	err = client.Call("Queue.AddTask", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// Task Status
-	err = client.Call("Queue.TaskStatus", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// Task Pause
-	err = client.Call("Queue.TaskPause", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// Task Run
-	err = client.Call("Queue.TaskRun", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// Task Quit
-	err = client.Call("Queue.TaskQuit", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-
-	// AllTaskStatus
-	err = client.Call("Queue.AllTaskStatus", badCall, nil)
-	if err == nil || err.Error() != ERROR_AUTH {
-		t.Fatal("Error was not returned for bad authentication.")
-	}
-}
-
 func TestListResourceTools(t *testing.T) {
 	// Build the queue to pass to the RPC server
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 	b := new(SimpleTimerTooler)
 	b.SetUUID(uuid.New())
 	q.tools = append(q.tools, b)
@@ -117,7 +51,7 @@ func TestListResourceTools(t *testing.T) {
 	}
 	defer client.Close()
 
-	rpccall := common.RPCCall{Auth: "ResourceTest"}
+	rpccall := common.RPCCall{}
 
 	var tools []common.Tool
 	client.Call("Queue.ResourceTools", rpccall, &tools)
@@ -143,7 +77,7 @@ func TestListResourceTools(t *testing.T) {
 
 func TestSimpleStartTask(t *testing.T) {
 	// Build the Queue with the SimpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -166,7 +100,7 @@ func TestSimpleStartTask(t *testing.T) {
 	j := common.NewJob(st.UUID(), "Testing Job", "GoTestSuite", params)
 
 	// Create RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Make call
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -190,7 +124,7 @@ func TestSimpleStartTask(t *testing.T) {
 
 func TestSimplePauseTask(t *testing.T) {
 	// Build the Queue with the simpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -213,7 +147,7 @@ func TestSimplePauseTask(t *testing.T) {
 	j := common.NewJob(st.UUID(), "Testing Job", "GoTestSuite", params)
 
 	// Create the RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Make the call to create the task
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -225,14 +159,14 @@ func TestSimplePauseTask(t *testing.T) {
 	<-time.After(2 * time.Second)
 
 	// Pause the job
-	pauseJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	pauseJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskPause", pauseJob, &j)
 	if err != nil {
 		t.Fatal("Error pausing simpleTimer status.", err)
 	}
 
 	// Get the status of the job
-	statusJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	statusJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskStatus", statusJob, &j)
 	if err != nil {
 		t.Fatal("Error getting simpleTimer status.", err)
@@ -245,7 +179,7 @@ func TestSimplePauseTask(t *testing.T) {
 
 func TestSimpleRunTask(t *testing.T) {
 	// Build the Queue with the simpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -267,7 +201,7 @@ func TestSimpleRunTask(t *testing.T) {
 	j := common.NewJob(st.UUID(), "Testing Job", "GoTestSuite", params)
 
 	// Create the RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Make the call to create the task
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -279,14 +213,14 @@ func TestSimpleRunTask(t *testing.T) {
 	<-time.After(2 * time.Second)
 
 	// Pause the job
-	pauseJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	pauseJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskPause", pauseJob, &j)
 	if err != nil {
 		t.Fatal("Error pausing simpleTimer status.", err)
 	}
 
 	// Get the status of the job
-	statusJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	statusJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskStatus", statusJob, &j)
 	if err != nil {
 		t.Fatal("Error getting simpleTimer status.", err)
@@ -297,7 +231,7 @@ func TestSimpleRunTask(t *testing.T) {
 	}
 
 	// Restart the job and wait to see if it finishes
-	runJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	runJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskRun", runJob, &j)
 	if err != nil {
 		t.Fatal("Error resuming simpleTimer task.", err)
@@ -311,7 +245,7 @@ func TestSimpleRunTask(t *testing.T) {
 	<-time.After(5 * time.Second)
 
 	// Get the final status
-	finalJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	finalJob := common.RPCCall{Job: j}
 	err = client.Call("Queue.TaskStatus", finalJob, &j)
 	if err != nil {
 		t.Fatal("Error getting simpleTimer status.", err)
@@ -324,7 +258,7 @@ func TestSimpleRunTask(t *testing.T) {
 
 func TestSimpleQuitTask(t *testing.T) {
 	// Build the Queue with the SimpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -346,7 +280,7 @@ func TestSimpleQuitTask(t *testing.T) {
 	j := common.NewJob(st.UUID(), "Testing Job", "GoTestSuite", params)
 
 	// Create RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Make call
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -371,7 +305,7 @@ func TestSimpleQuitTask(t *testing.T) {
 func TestResourceHardware(t *testing.T) {
 	// Build the Queue with the SimpleTool timer
 	hw := map[string]bool{common.RES_CPU: true, common.RES_GPU: true}
-	q := Queue{authToken: "ResourceTest", hardware: hw}
+	q := Queue{hardware: hw}
 
 	// Create the RPC server and bind the Queue
 	listen := startRPCOnce("tcp", addr, &q)
@@ -385,7 +319,7 @@ func TestResourceHardware(t *testing.T) {
 	defer client.Close()
 
 	// Create RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest"}
+	startJob := common.RPCCall{}
 
 	// Make call
 	ahw := make(map[string]bool)
@@ -401,7 +335,7 @@ func TestResourceHardware(t *testing.T) {
 
 func TestToolDoesNotExist(t *testing.T) {
 	// Build the Queue with the SimpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -424,7 +358,7 @@ func TestToolDoesNotExist(t *testing.T) {
 	j := common.NewJob(uuid.New(), "Testing Job", "GoTestSuite", params)
 
 	// Create RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Make call and expect an error of bad job
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -435,7 +369,7 @@ func TestToolDoesNotExist(t *testing.T) {
 
 func TestMultiToolStatus(t *testing.T) {
 	// Build the Queue with the SimpleTool timer
-	q := Queue{authToken: "ResourceTest"}
+	q := Queue{}
 
 	// Add the tool
 	st := new(SimpleTimerTooler)
@@ -458,7 +392,7 @@ func TestMultiToolStatus(t *testing.T) {
 	j := common.NewJob(st.UUID(), "Testing Job", "GoTestSuite", params)
 
 	// Create RPC call for starting a job
-	startJob := common.RPCCall{Auth: "ResourceTest", Job: j}
+	startJob := common.RPCCall{Job: j}
 
 	// Add our first job
 	err = client.Call("Queue.AddTask", startJob, &j)
@@ -474,7 +408,7 @@ func TestMultiToolStatus(t *testing.T) {
 	}
 
 	// Get the status of both and check that the Job UUIDs aren't the same
-	getStatus := common.RPCCall{Auth: "ResourceTest"}
+	getStatus := common.RPCCall{}
 	jobs := []common.Job{}
 	err = client.Call("Queue.AllTaskStatus", getStatus, &jobs)
 	if err != nil {
@@ -0,0 +1,227 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// The file in the binary directory that lists the tool binaries installed
+const binaryIndex = "binaries.json"
+
+// Set the directory tool binaries from the queue are installed in and switch
+// the tools over to the ones already installed there. The tools must be added
+// first.
+func (q *Queue) SetBinaryDir(dir string) error {
+	q.Lock()
+	defer q.Unlock()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	q.binDir = dir
+	q.binaries = map[string]common.ToolBinary{}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, binaryIndex))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var installed map[string]common.ToolBinary
+	if err := json.Unmarshal(data, &installed); err != nil {
+		return err
+	}
+
+	for tool, b := range installed {
+		if err := q.useBinary(b); err != nil {
+			log.WithFields(log.Fields{
+				"tool":    tool,
+				"version": b.Version,
+				"error":   err.Error(),
+			}).Warn("Unable to use the installed tool binary, it will be installed again.")
+			continue
+		}
+		q.binaries[tool] = b
+	}
+
+	return nil
+}
+
+// Where a build is installed inside the binary directory
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) binaryPath(b common.ToolBinary) string {
+	name := b.Tool
+	if b.OS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(q.binDir, b.Tool, b.Version+"-"+b.OS+"-"+b.Arch, name)
+}
+
+// Switch the tools that run a binary over to an installed build
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) useBinary(b common.ToolBinary) error {
+	var found bool
+	for _, tool := range q.tools {
+		u, ok := tool.(common.BinaryUser)
+		if !ok || u.BinaryName() != b.Tool {
+			continue
+		}
+		if err := u.SetBinary(q.binaryPath(b), b.Version); err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return errors.New("No tool on this resource runs " + b.Tool + ".")
+	}
+	return nil
+}
+
+// Version of the binary installed for each tool that can be given one, empty
+// for tools still running the binary in their config file
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) binaryVersions() map[string]string {
+	versions := map[string]string{}
+	for _, tool := range q.tools {
+		if u, ok := tool.(common.BinaryUser); ok {
+			versions[u.BinaryName()] = q.binaries[u.BinaryName()].Version
+		}
+	}
+	return versions
+}
+
+// Install a tool binary pushed from the repository of the queue once its
+// checksum and signature are verified. The tool runs it for new tasks and the
+// build it ran before is kept for tasks still using it. The version installed
+// is returned.
+func (q *Queue) InstallBinary(rpc common.RPCCall, installed *string) error {
+	// Add a defered catch for panic from within the install
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.InstallBinary: %v", err)
+		}
+	}()
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.update == nil {
+		return errors.New("Tool binaries are refused as no update key is set on this resource.")
+	}
+	if q.binDir == "" {
+		return errors.New("No directory for tool binaries is set on this resource.")
+	}
+	if rpc.Binary == nil {
+		return errors.New("No tool binary was provided.")
+	}
+
+	pkg := *rpc.Binary
+	if pkg.OS != runtime.GOOS || pkg.Arch != runtime.GOARCH {
+		return errors.New("The tool binary is built for " + pkg.OS + "/" + pkg.Arch + " and this resource runs " + runtime.GOOS + "/" + runtime.GOARCH + ".")
+	}
+
+	err := pkg.Verify(q.update)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"tool":    pkg.Tool,
+			"version": pkg.Version,
+			"error":   err.Error(),
+		}).Error("Rejected tool binary.")
+		return err
+	}
+
+	path := q.binaryPath(pkg.ToolBinary)
+	err = writeBinary(path, pkg.Binary)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to write tool binary.")
+		return err
+	}
+
+	err = q.useBinary(pkg.ToolBinary)
+	if err != nil {
+		os.RemoveAll(filepath.Dir(path))
+		return err
+	}
+
+	prev, had := q.binaries[pkg.Tool]
+	q.binaries[pkg.Tool] = pkg.ToolBinary
+
+	// Keep only the new build and the one before it
+	keep := map[string]bool{filepath.Dir(path): true}
+	if had {
+		keep[filepath.Dir(q.binaryPath(prev))] = true
+	}
+	dirs, _ := ioutil.ReadDir(filepath.Join(q.binDir, pkg.Tool))
+	for _, d := range dirs {
+		if p := filepath.Join(q.binDir, pkg.Tool, d.Name()); !keep[p] {
+			os.RemoveAll(p)
+		}
+	}
+
+	if err := q.saveBinaries(); err != nil {
+		log.WithField("error", err.Error()).Error("Unable to save the list of installed tool binaries.")
+	}
+
+	*installed = pkg.Version
+
+	log.WithFields(log.Fields{
+		"tool":    pkg.Tool,
+		"version": pkg.Version,
+		"path":    path,
+	}).Info("Tool binary installed.")
+
+	return nil
+}
+
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) saveBinaries() error {
+	data, err := json.Marshal(q.binaries)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(q.binDir, binaryIndex+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(q.binDir, binaryIndex))
+}
+
+// Write an executable next to where it goes and move it into place so a
+// partly written binary is never run
+func writeBinary(path string, bin []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".install-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(bin)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0700)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
@@ -0,0 +1,117 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"sort"
+	"time"
+)
+
+// Number of events kept for each task of a debug job
+const debugEventLines = 1000
+
+// Number of debug logs kept for tasks the queue has quit so they can still be
+// read once the job is over
+const debugKeep = 20
+
+// What the resource did with a task of a debug job
+type taskDebug struct {
+	events  *common.LineTail
+	status  string
+	output  []string  // Output of the tool when the task was removed
+	sealed  bool      // The results are encrypted so the tool output that has them is not kept
+	removed time.Time // When the task was quit, zero while it is on the stack
+}
+
+// Record an event for a task if its job is being debugged
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugf(jobUUID, format string, args ...interface{}) {
+	d, ok := q.debug[jobUUID]
+	if !ok {
+		return
+	}
+
+	d.events.Write([]byte(time.Now().Format(time.RFC3339) + " " + fmt.Sprintf(format, args...) + "\n"))
+}
+
+// Record a change in the status reported for a task of a debug job
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugStatus(j common.Job) {
+	d, ok := q.debug[j.UUID]
+	if !ok || d.status == j.Status {
+		return
+	}
+
+	d.status = j.Status
+	if j.Error != "" {
+		q.debugf(j.UUID, "Task is %s with the error: %s", j.Status, j.Error)
+		return
+	}
+	q.debugf(j.UUID, "Task is %s at %.2f%%", j.Status, j.Progress)
+}
+
+// Keep the output of a debug task that is being removed and forget the oldest
+// logs of removed tasks beyond the number kept
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) debugRemoved(jobUUID string, task common.Tasker) {
+	d, ok := q.debug[jobUUID]
+	if !ok {
+		return
+	}
+
+	if logger, ok := task.(common.OutputLogger); ok && !d.sealed {
+		d.output = logger.Logs(0)
+	}
+	d.removed = time.Now()
+
+	var removed []string
+	for id, d := range q.debug {
+		if !d.removed.IsZero() {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) <= debugKeep {
+		return
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return q.debug[removed[i]].removed.Before(q.debug[removed[j]].removed)
+	})
+	for _, id := range removed[:len(removed)-debugKeep] {
+		delete(q.debug, id)
+	}
+}
+
+// Return the events and the full tool output kept for a task of a debug job,
+// including tasks the queue has already quit. The output of tasks with
+// encrypted results is left out as it has the plaintexts.
+func (q *Queue) TaskDebugLog(rpc common.RPCCall, l *common.TaskLog) error {
+	log.WithField("task", rpc.Job.UUID).Debug("Gathering task debug log")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.TaskDebugLog: %v", err)
+		}
+	}()
+
+	q.RLock()
+	defer q.RUnlock()
+
+	d, ok := q.debug[rpc.Job.UUID]
+	if !ok {
+		return errors.New("No debug log is kept for that task.")
+	}
+
+	l.Events = d.events.Lines(0)
+	l.Output = d.output
+	if task, ok := q.stack[rpc.Job.UUID]; ok {
+		if logger, ok := task.(common.OutputLogger); ok && !d.sealed {
+			l.Output = logger.Logs(0)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package resource
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"net/http"
+	"time"
+)
+
+const ERROR_INTERRUPTED = "Resource is being taken back by its cloud provider."
+
+// How often the metadata service is checked for an interruption notice
+var InterruptionPoll = 5 * time.Second
+
+// Watch the metadata service of the cloud the resource runs in for notice that
+// its spot or preemptible instance is being taken back. The provider is aws,
+// gcp or azure, or auto to find which one the resource runs in.
+func (q *Queue) WatchInterruptions(provider string) {
+	go func() {
+		client := &http.Client{Timeout: 2 * time.Second}
+
+		if provider == "auto" {
+			provider = common.DetectCloud(client)
+			if provider == "" {
+				log.Debug("Resource is not running in a known cloud, interruption notices are not watched.")
+				return
+			}
+		}
+		log.WithField("provider", provider).Info("Watching for interruption notices.")
+
+		for {
+			deadline, ok, err := common.CheckInterruption(client, provider)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"provider": provider,
+					"error":    err.Error(),
+				}).Debug("Unable to check for an interruption notice.")
+			} else if ok {
+				q.interrupt(provider, deadline)
+				return
+			}
+
+			time.Sleep(InterruptionPoll)
+		}
+	}()
+}
+
+// Checkpoint the running tasks straight away as the instance may be gone
+// before the queue asks, and refuse new tasks
+func (q *Queue) interrupt(provider string, deadline time.Time) {
+	log.WithFields(log.Fields{
+		"provider": provider,
+		"deadline": deadline,
+	}).Warn("Cloud provider is taking back the instance, checkpointing tasks for the queue.")
+
+	notice := &common.Interruption{
+		Provider:    provider,
+		Noticed:     time.Now(),
+		Deadline:    deadline,
+		Checkpoints: q.checkpointTasks(),
+	}
+
+	q.Lock()
+	q.interruption = notice
+	for jobUUID := range q.stack {
+		q.debugf(jobUUID, "Cloud provider is taking back the instance at %s", deadline.Format(time.RFC3339))
+	}
+	q.Unlock()
+}
+
+// Take a restore point of every task that supports them
+func (q *Queue) checkpointTasks() map[string]common.Checkpoint {
+	q.RLock()
+	tasks := map[string]common.Checkpointer{}
+	for jobUUID, task := range q.stack {
+		if cp, ok := task.(common.Checkpointer); ok {
+			tasks[jobUUID] = cp
+		}
+	}
+	q.RUnlock()
+
+	// Tools are not called with the lock held as checkpoints can take a while
+	points := map[string]common.Checkpoint{}
+	for jobUUID, task := range tasks {
+		cp, err := task.Checkpoint()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"task":  jobUUID,
+				"error": err.Error(),
+			}).Warn("Unable to checkpoint task for interruption.")
+			continue
+		}
+		q.sealCheckpoint(jobUUID, &cp)
+		points[jobUUID] = cp
+	}
+
+	return points
+}
+
+// Return the interruption notice of the resource, which has no time noticed
+// if there is none. Tasks still running are checkpointed again so the queue
+// loses as little work as possible when it moves them.
+func (q *Queue) ResourceInterruption(rpc common.RPCCall, i *common.Interruption) error {
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ResourceInterruption: %v", err)
+		}
+	}()
+
+	q.RLock()
+	notice := q.interruption
+	q.RUnlock()
+
+	if notice == nil {
+		*i = common.Interruption{}
+		return nil
+	}
+
+	*i = *notice
+	i.Checkpoints = map[string]common.Checkpoint{}
+	for jobUUID, cp := range notice.Checkpoints {
+		i.Checkpoints[jobUUID] = cp
+	}
+	for jobUUID, cp := range q.checkpointTasks() {
+		i.Checkpoints[jobUUID] = cp
+	}
+
+	return nil
+}
+
+// New tasks would only be lost with the instance
+func (q *Queue) refuseInterrupted() error {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.interruption != nil {
+		return errors.New(ERROR_INTERRUPTED)
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package resource
 
 import (
+	"crypto/ed25519"
 	"errors"
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/shared"
 	"github.com/pborman/uuid"
+	"strings"
 	"sync"
+	"time"
 )
 
 // TODO: Add function for adding tools and assign a UUID
@@ -13,20 +17,41 @@ import (
 const (
 	ERROR_AUTH    = "Call to resource did not have the proper authentication token."
 	ERROR_NO_TOOL = "Tool specified does not exit."
+	ERROR_NO_TASK = "Task with UUID provided does not exist."
 )
 
 type Queue struct {
 	stack map[string]common.Tasker
 	tools []common.Tooler
 	sync.RWMutex
-	hardware map[string]bool
+	hardware     map[string]bool
+	inventory    common.Inventory // Hardware details reported to the queue
+	logs         LogSource
+	update       ed25519.PublicKey            // Key used to verify pushed updates, nil disables them
+	build        string                       // SHA-256 of the running executable
+	fileDirs     []string                     // Directories of wordlists and rules reported in the inventory
+	scanning     bool                         // Set while the file directories are scanned
+	binDir       string                       // Where tool binaries from the queue are installed, empty refuses them
+	binaries     map[string]common.ToolBinary // Installed tool binaries by tool
+	debug        map[string]*taskDebug        // Debug logs of tasks by job UUID
+	interruption *common.Interruption         // Notice that the cloud instance is being taken back
+	sealers      map[string]*resultSealer     // Encrypt the results of tasks by job UUID, for jobs with a result key
+}
+
+// Somewhere the recent log lines of the resource can be read from
+type LogSource interface {
+	Lines(n int) []string
 }
 
 func NewResourceQueue() Queue {
 	return Queue{
-		stack:    map[string]common.Tasker{},
-		tools:    []common.Tooler{},
-		hardware: map[string]bool{},
+		stack:     map[string]common.Tasker{},
+		debug:     map[string]*taskDebug{},
+		sealers:   map[string]*resultSealer{},
+		tools:     []common.Tooler{},
+		hardware:  map[string]bool{},
+		inventory: common.GatherInventory(),
+		build:     executableBuild(),
 	}
 }
 
@@ -43,7 +68,54 @@ func (q *Queue) AddTool(tooler common.Tooler) {
 	}).Debug("Tool added")
 }
 
+// Set the directories of wordlists and rules to report to the queue. They are
+// hashed in the background and reported once the scan finishes.
+func (q *Queue) SetFileDirs(dirs []string) {
+	q.Lock()
+	q.fileDirs = dirs
+	q.Unlock()
+
+	q.rescanFiles()
+}
+
+// Start scanning the file directories again unless a scan is running. Only
+// new or changed files are hashed again.
+func (q *Queue) rescanFiles() {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.fileDirs) == 0 || q.scanning {
+		return
+	}
+	q.scanning = true
+
+	go q.scanFiles(q.fileDirs)
+}
+
+func (q *Queue) scanFiles(dirs []string) {
+	start := time.Now()
+	files := common.ScanFiles(dirs)
+
+	q.Lock()
+	q.inventory.Files = files
+	q.scanning = false
+	q.Unlock()
+
+	log.WithFields(log.Fields{
+		"files":    len(files),
+		"duration": time.Since(start),
+	}).Debug("Wordlists and rules scanned.")
+}
+
 // Task RPC functions
+// Set where the log lines returned by ResourceLogs come from
+func (q *Queue) SetLogSource(l LogSource) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.logs = l
+}
+
 func (q *Queue) Ping(ping int, pong *int) error {
 	q.Lock()
 	defer q.Unlock()
@@ -53,6 +125,13 @@ func (q *Queue) Ping(ping int, pong *int) error {
 	return nil
 }
 
+// Return the clock of the resource for the queue to measure how far it is off
+func (q *Queue) ResourceClock(rpc common.RPCCall, now *time.Time) error {
+	*now = time.Now().UTC()
+
+	return nil
+}
+
 func (q *Queue) ResourceHardware(rpc common.RPCCall, hw *map[string]bool) error {
 	q.RLock()
 	defer q.RUnlock()
@@ -62,6 +141,20 @@ func (q *Queue) ResourceHardware(rpc common.RPCCall, hw *map[string]bool) error
 	return nil
 }
 
+// Return the GPUs, CPU, memory and files of the resource for the queue to schedule with
+func (q *Queue) ResourceInventory(rpc common.RPCCall, inv *common.Inventory) error {
+	// Files added since the last scan are reported the next time
+	defer q.rescanFiles()
+
+	q.RLock()
+	defer q.RUnlock()
+
+	*inv = q.inventory
+	inv.Binaries = q.binaryVersions()
+
+	return nil
+}
+
 func (q *Queue) AddTask(rpc common.RPCCall, rj *common.Job) error {
 	log.WithFields(log.Fields{
 		"name": rpc.Job.Name,
@@ -80,24 +173,64 @@ func (q *Queue) AddTask(rpc common.RPCCall, rj *common.Job) error {
 		}
 	}()
 
+	if err := q.refuseInterrupted(); err != nil {
+		return err
+	}
+
+	// Results are never reported in the clear when the job has a key for them
+	var sealer *resultSealer
+	if rpc.Job.ResultKey != "" {
+		var err error
+		sealer, err = newResultSealer(rpc.Job.ResultKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep the URLs of shared files the task may need to download
+	shared.SetURLs(rpc.Files)
+
 	// variable to hold the tasker
 	var tasker common.Tasker
 	var err error
 	// loop through common.Toolers for matching tool
 	q.Lock()
-	defer q.Unlock()
+	if rpc.Job.Debug {
+		if q.debug == nil {
+			q.debug = make(map[string]*taskDebug)
+		}
+		q.debug[rpc.Job.UUID] = &taskDebug{events: common.NewLineTail(debugEventLines), sealed: sealer != nil}
+	}
+	var tool common.Tooler
 	for i, _ := range q.tools {
 		if q.tools[i].UUID() == rpc.Job.ToolUUID {
-			tasker, err = q.tools[i].NewTask(rpc.Job)
-			if err != nil {
-				return err
-			}
+			tool = q.tools[i]
 		}
 	}
+	if tool != nil {
+		q.debugf(rpc.Job.UUID, "Task received for %s %s", tool.Name(), tool.Version())
+	}
+	q.Unlock()
+
+	// The queue is not locked while the task is created as it may download
+	// shared files first
+	if tool != nil {
+		tasker, err = tool.NewTask(rpc.Job)
+		if err != nil {
+			q.Lock()
+			q.debugf(rpc.Job.UUID, "Unable to create the task: %s", err.Error())
+			q.Unlock()
+			return err
+		}
+	}
+
+	q.Lock()
+	defer q.Unlock()
 
 	// Check if no tool was found and return error
 	if tasker == nil {
 		log.Warn("An error occured, we could not find the tool requested")
+		q.debugf(rpc.Job.UUID, "No tool with the UUID %s is loaded", rpc.Job.ToolUUID)
 		return errors.New(ERROR_NO_TOOL)
 	}
 	log.WithFields(log.Fields{
@@ -110,16 +243,45 @@ func (q *Queue) AddTask(rpc common.RPCCall, rj *common.Job) error {
 	}
 
 	q.stack[rpc.Job.UUID] = tasker
+	if sealer != nil {
+		if q.sealers == nil {
+			q.sealers = make(map[string]*resultSealer)
+		}
+		q.sealers[rpc.Job.UUID] = sealer
+	}
+
+	// Continue from where the task was when it last ran on another resource
+	if rpc.Checkpoint != nil {
+		if cp, ok := tasker.(common.Checkpointer); ok {
+			err = cp.Restore(*rpc.Checkpoint)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"task":  rpc.Job.UUID,
+					"error": err.Error(),
+				}).Warn("Unable to restore task from checkpoint, starting from the beginning.")
+				q.debugf(rpc.Job.UUID, "Unable to restore the checkpoint, starting from the beginning: %s", err.Error())
+			} else {
+				q.debugf(rpc.Job.UUID, "Restored the checkpoint taken at %s", rpc.Checkpoint.Taken.Format(time.RFC3339))
+			}
+		} else {
+			log.WithField("task", rpc.Job.UUID).Warn("Task does not support checkpoints, starting from the beginning.")
+			q.debugf(rpc.Job.UUID, "The tool does not support checkpoints, starting from the beginning")
+		}
+	}
 
 	// Everything should be paused by the control queue so start this job
 	err = q.stack[rpc.Job.UUID].Run()
 	if err != nil {
 		log.Debug("Error starting task on resource")
+		q.debugf(rpc.Job.UUID, "Unable to start the task: %s", err.Error())
 		return errors.New("Error starting task on the resource: " + err.Error())
 	}
+	q.debugf(rpc.Job.UUID, "Task started")
 
 	// Grab the status and return that job to the control queue
 	*rj = q.stack[rpc.Job.UUID].Status()
+	q.sealStatus(rj)
+	q.debugStatus(*rj)
 
 	return nil
 }
@@ -142,10 +304,47 @@ func (q *Queue) TaskStatus(rpc common.RPCCall, j *common.Job) error {
 	// Check for a bad UUID
 	if !ok {
 		log.WithField("task", rpc.Job.UUID).Error("Task with UUID provided does not exist.")
-		return errors.New("Task with UUID provided does not exist.")
+		return errors.New(ERROR_NO_TASK)
 	}
 
 	*j = q.stack[rpc.Job.UUID].Status()
+	q.sealStatus(j)
+	q.debugStatus(*j)
+
+	return nil
+}
+
+// Get a restore point for a running task so the queue can save it
+func (q *Queue) TaskCheckpoint(rpc common.RPCCall, c *common.Checkpoint) error {
+	log.WithField("task", rpc.Job.UUID).Debug("Attempting to checkpoint task")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.TaskCheckpoint: %v", err)
+		}
+	}()
+
+	q.RLock()
+	task, ok := q.stack[rpc.Job.UUID]
+	q.RUnlock()
+
+	if !ok {
+		return errors.New(ERROR_NO_TASK)
+	}
+
+	cp, ok := task.(common.Checkpointer)
+	if !ok {
+		return errors.New("Task does not support checkpoints.")
+	}
+
+	out, err := cp.Checkpoint()
+	if err != nil {
+		return err
+	}
+	q.sealCheckpoint(rpc.Job.UUID, &out)
+
+	*c = out
 
 	return nil
 }
@@ -168,7 +367,7 @@ func (q *Queue) TaskPause(rpc common.RPCCall, j *common.Job) error {
 	// Check for a bad UUID
 	if !ok {
 		log.WithField("task", rpc.Job.UUID).Debug("Task with UUID provided does not exist.")
-		return errors.New("Task with UUID provided does not exist.")
+		return errors.New(ERROR_NO_TASK)
 	}
 
 	// Pause the task
@@ -177,10 +376,14 @@ func (q *Queue) TaskPause(rpc common.RPCCall, j *common.Job) error {
 		// return the error but quit the job with status Failed
 		// This is a definied behavior that we will not for all tools
 		q.stack[rpc.Job.UUID].Quit()
+		q.debugf(rpc.Job.UUID, "Unable to pause the task, it was quit: %s", err.Error())
 		return err
 	}
 
 	*j = q.stack[rpc.Job.UUID].Status()
+	q.sealStatus(j)
+	q.debugf(rpc.Job.UUID, "Task paused by the queue")
+	q.debugStatus(*j)
 
 	log.WithField("task", j.UUID).Debug("Task paused successfully")
 
@@ -206,16 +409,20 @@ func (q *Queue) TaskRun(rpc common.RPCCall, j *common.Job) error {
 	// Check for a bad UUID
 	if !ok {
 		log.WithField("task", rpc.Job.UUID).Debug("Task with UUID provided does not exist.")
-		return errors.New("Task with UUID provided does not exist.")
+		return errors.New(ERROR_NO_TASK)
 	}
 
 	// Start or resume the task
 	err := q.stack[rpc.Job.UUID].Run()
 	if err != nil {
+		q.debugf(rpc.Job.UUID, "Unable to resume the task: %s", err.Error())
 		return err
 	}
 
 	*j = q.stack[rpc.Job.UUID].Status()
+	q.sealStatus(j)
+	q.debugf(rpc.Job.UUID, "Task resumed by the queue")
+	q.debugStatus(*j)
 
 	log.WithField("task", j.UUID).Debug("Task ran successfully")
 
@@ -243,20 +450,310 @@ func (q *Queue) TaskQuit(rpc common.RPCCall, j *common.Job) error {
 	// Check for a bad UUID
 	if !ok {
 		log.WithField("task", rpc.Job.UUID).Debug("Task with UUID provided does not exist.")
-		return errors.New("Task with UUID provided does not exist.")
+		return errors.New(ERROR_NO_TASK)
 	}
 
 	// Quit the task and return the final result
 	*j = q.stack[rpc.Job.UUID].Quit()
+	q.sealStatus(j)
+	q.debugf(rpc.Job.UUID, "Task quit by the queue")
+	q.debugStatus(*j)
 
-	// Remove quit job from stack
+	// Remove quit job from stack, the output of a debug task is kept
+	q.debugRemoved(rpc.Job.UUID, q.stack[rpc.Job.UUID])
 	delete(q.stack, rpc.Job.UUID)
+	delete(q.sealers, rpc.Job.UUID)
 
 	log.WithField("task", rpc.Job.UUID).Debug("Task quit and removed successfully")
 
 	return nil
 }
 
+// Apply the workload profile the queue picked for the time of day to the tools
+// that can be throttled. New tasks start with it and running tasks keep going.
+func (q *Queue) SetProfile(rpc common.RPCCall, applied *bool) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	workload := 0
+	name := "none"
+	if rpc.Profile != nil {
+		workload = rpc.Profile.Workload
+		name = rpc.Profile.Name
+	}
+
+	for _, tool := range q.tools {
+		if t, ok := tool.(common.Throttler); ok {
+			t.SetWorkload(workload)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"profile":  name,
+		"workload": workload,
+	}).Info("Workload profile applied.")
+
+	*applied = true
+	return nil
+}
+
+// Load the tools again and gather a fresh inventory, waiting for the file
+// directories to be scanned. Tools that fail to load keep what they had and
+// are named in the error returned after the inventory is updated.
+func (q *Queue) RescanTools(rpc common.RPCCall, inv *common.Inventory) error {
+	log.Debug("Rescanning tools")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.RescanTools: %v", err)
+		}
+	}()
+
+	// The lock keeps new tasks from starting while a tool loads
+	q.Lock()
+	var failed []string
+	for _, tool := range q.tools {
+		r, ok := tool.(common.Rescanner)
+		if !ok {
+			continue
+		}
+
+		if err := r.Rescan(); err != nil {
+			log.WithFields(log.Fields{
+				"tool":  tool.Name(),
+				"error": err.Error(),
+			}).Error("Unable to rescan tool.")
+			failed = append(failed, tool.Name()+": "+err.Error())
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"toolid":  tool.UUID(),
+			"name":    tool.Name(),
+			"version": tool.Version(),
+		}).Info("Tool rescanned.")
+	}
+	dirs := q.fileDirs
+	q.Unlock()
+
+	fresh := common.GatherInventory()
+	if len(dirs) > 0 {
+		fresh.Files = common.ScanFiles(dirs)
+	}
+
+	q.Lock()
+	q.inventory = fresh
+	fresh.Binaries = q.binaryVersions()
+	q.Unlock()
+
+	*inv = fresh
+
+	if len(failed) > 0 {
+		return errors.New("Unable to rescan " + strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Get the last lines of the resource log, or of the output of a task if a job
+// UUID is provided
+func (q *Queue) ResourceLogs(rpc common.RPCCall, lines *[]string) error {
+	log.WithField("task", rpc.Job.UUID).Debug("Gathering resource logs")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ResourceLogs: %v", err)
+		}
+	}()
+
+	q.RLock()
+	defer q.RUnlock()
+
+	if rpc.Job.UUID == "" {
+		if q.logs == nil {
+			return errors.New("Logging to memory is not enabled on this resource.")
+		}
+
+		*lines = q.logs.Lines(rpc.Lines)
+		return nil
+	}
+
+	task, ok := q.stack[rpc.Job.UUID]
+	if !ok {
+		return errors.New(ERROR_NO_TASK)
+	}
+
+	logger, ok := task.(common.OutputLogger)
+	if !ok {
+		return errors.New("Task does not support retrieving its output.")
+	}
+
+	*lines = logger.Logs(rpc.Lines)
+	return nil
+}
+
+func (q *Queue) ToolPreview(rpc common.RPCCall, candidates *[]string) error {
+	log.WithField("tool", rpc.Job.ToolUUID).Debug("Attempting to preview tool")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ToolPreview: %v", err)
+		}
+	}()
+
+	q.RLock()
+	var tool common.Tooler
+	for i, _ := range q.tools {
+		if q.tools[i].UUID() == rpc.Job.ToolUUID {
+			tool = q.tools[i]
+		}
+	}
+	q.RUnlock()
+
+	if tool == nil {
+		log.Warn("An error occured, we could not find the tool requested")
+		return errors.New(ERROR_NO_TOOL)
+	}
+
+	shared.SetURLs(rpc.Files)
+
+	previewer, ok := tool.(common.Previewer)
+	if !ok {
+		return errors.New("Tool does not support previews.")
+	}
+
+	out, err := previewer.Preview(rpc.Job.Parameters, rpc.Words)
+	if err != nil {
+		return err
+	}
+
+	*candidates = out
+
+	return nil
+}
+
+func (q *Queue) ToolEstimate(rpc common.RPCCall, est *common.Estimate) error {
+	log.WithField("tool", rpc.Job.ToolUUID).Debug("Attempting to estimate job")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ToolEstimate: %v", err)
+		}
+	}()
+
+	q.RLock()
+	var tool common.Tooler
+	for i, _ := range q.tools {
+		if q.tools[i].UUID() == rpc.Job.ToolUUID {
+			tool = q.tools[i]
+		}
+	}
+	q.RUnlock()
+
+	if tool == nil {
+		log.Warn("An error occured, we could not find the tool requested")
+		return errors.New(ERROR_NO_TOOL)
+	}
+
+	shared.SetURLs(rpc.Files)
+
+	estimator, ok := tool.(common.Estimator)
+	if !ok {
+		return errors.New("Tool does not support estimates.")
+	}
+
+	out, err := estimator.Estimate(rpc.Job.Parameters)
+	if err != nil {
+		return err
+	}
+
+	*est = out
+
+	return nil
+}
+
+// Get the hardware a job needs from the tool. Tools that do not declare any
+// requirements return empty constraints.
+func (q *Queue) ToolRequirements(rpc common.RPCCall, c *common.Constraints) error {
+	log.WithField("tool", rpc.Job.ToolUUID).Debug("Attempting to get job requirements")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ToolRequirements: %v", err)
+		}
+	}()
+
+	q.RLock()
+	var tool common.Tooler
+	for i, _ := range q.tools {
+		if q.tools[i].UUID() == rpc.Job.ToolUUID {
+			tool = q.tools[i]
+		}
+	}
+	q.RUnlock()
+
+	if tool == nil {
+		log.Warn("An error occured, we could not find the tool requested")
+		return errors.New(ERROR_NO_TOOL)
+	}
+
+	requirer, ok := tool.(common.Requirer)
+	if !ok {
+		*c = common.Constraints{}
+		return nil
+	}
+
+	out, err := requirer.JobRequirements(rpc.Job.Parameters)
+	if err != nil {
+		return err
+	}
+
+	*c = out
+
+	return nil
+}
+
+// Check the parameters of a job for mistakes before it is dispatched. Tools
+// that do not check their input return no issues.
+func (q *Queue) ToolCheckInput(rpc common.RPCCall, issues *[]common.InputIssue) error {
+	log.WithField("tool", rpc.Job.ToolUUID).Debug("Attempting to check job input")
+
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ToolCheckInput: %v", err)
+		}
+	}()
+
+	q.RLock()
+	var tool common.Tooler
+	for i, _ := range q.tools {
+		if q.tools[i].UUID() == rpc.Job.ToolUUID {
+			tool = q.tools[i]
+		}
+	}
+	q.RUnlock()
+
+	if tool == nil {
+		log.Warn("An error occured, we could not find the tool requested")
+		return errors.New(ERROR_NO_TOOL)
+	}
+
+	checker, ok := tool.(common.InputChecker)
+	if !ok {
+		*issues = []common.InputIssue{}
+		return nil
+	}
+
+	*issues = checker.CheckInput(rpc.Job.Parameters)
+
+	return nil
+}
+
 // Queue Tasks
 
 func (q *Queue) ResourceTools(rpc common.RPCCall, tools *[]common.Tool) error {
@@ -282,6 +779,10 @@ func (q *Queue) ResourceTools(rpc common.RPCCall, tools *[]common.Tool) error {
 		tool.UUID = q.tools[i].UUID()
 		tool.Parameters = q.tools[i].Parameters()
 		tool.Requirements = q.tools[i].Requirements()
+		if m, ok := q.tools[i].(common.FormMigrator); ok {
+			tool.FormVersion = m.FormVersion()
+			tool.Migrations = m.FormMigrations()
+		}
 
 		log.WithFields(log.Fields{
 			"UUID": tool.UUID,
@@ -317,7 +818,10 @@ func (q *Queue) AllTaskStatus(rpc common.RPCCall, j *[]common.Job) error {
 	q.Lock()
 
 	for i, _ := range q.stack {
-		jobs = append(jobs, q.stack[i].Status())
+		status := q.stack[i].Status()
+		q.sealStatus(&status)
+		q.debugStatus(status)
+		jobs = append(jobs, status)
 	}
 
 	*j = jobs
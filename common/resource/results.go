@@ -0,0 +1,77 @@
+package resource
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"io"
+	"os"
+)
+
+// The most of a result file sent in one call, so a large file is sent in
+// parts and never held in memory
+const MaxResultChunk = 1024 * 1024
+
+// Read part of the result file of a task
+func (q *Queue) TaskResults(rpc common.RPCCall, c *common.ResultChunk) error {
+	// Add a defered catch for panic from within the tools
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.TaskResults: %v", err)
+		}
+	}()
+
+	q.RLock()
+	task, ok := q.stack[rpc.Job.UUID]
+	sealed := q.sealed(rpc.Job.UUID)
+	q.RUnlock()
+
+	if !ok {
+		return errors.New(ERROR_NO_TASK)
+	}
+	if sealed {
+		return ErrResultsSealed
+	}
+
+	filer, ok := task.(common.ResultFiler)
+	if !ok {
+		return errors.New("Task does not support downloading its result file.")
+	}
+
+	f, err := os.Open(filer.ResultFile())
+	if os.IsNotExist(err) {
+		// Nothing has been cracked yet
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	c.Size = info.Size()
+	c.Modified = info.ModTime()
+
+	length := rpc.Length
+	if length <= 0 || length > MaxResultChunk {
+		length = MaxResultChunk
+	}
+	if rpc.Offset < 0 || rpc.Offset >= c.Size {
+		return nil
+	}
+	if rest := c.Size - rpc.Offset; length > rest {
+		length = rest
+	}
+
+	c.Data = make([]byte, length)
+	n, err := f.ReadAt(c.Data, rpc.Offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	c.Data = c.Data[:n]
+
+	return nil
+}
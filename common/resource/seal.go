@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/pgp"
+	"strings"
+	"sync"
+)
+
+// Prefix of the single output title of a task whose results are encrypted
+const SEALED_TITLE = "Encrypted "
+
+var ErrResultsSealed = errors.New("The results of the task are encrypted for the key of its job.")
+
+// Encrypts each row of the output of a task for the key of its job as it is
+// reported, so neither the queue nor its administrators see the plaintexts
+type resultSealer struct {
+	key  *pgp.PublicKey
+	rows map[int]sealedRow // Rows already encrypted by their position
+	sync.Mutex
+}
+
+type sealedRow struct {
+	sum    [sha256.Size]byte // Of the row the tool reported
+	sealed []string
+}
+
+func newResultSealer(armored string) (*resultSealer, error) {
+	key, err := pgp.ReadArmoredKey(armored)
+	if err != nil {
+		return nil, errors.New("Unable to read the key to encrypt results for: " + err.Error())
+	}
+
+	return &resultSealer{key: key, rows: map[int]sealedRow{}}, nil
+}
+
+// Replace the rows of output with a single column of the OpenPGP messages of
+// each row. Rows are only encrypted once and rows that come from a checkpoint
+// are already encrypted.
+func (s *resultSealer) seal(titles []string, rows [][]string) ([]string, [][]string) {
+	s.Lock()
+	defer s.Unlock()
+
+	sealed := make([][]string, 0, len(rows))
+	for i, row := range rows {
+		if len(row) == 1 && pgp.IsArmoredMessage(row[0]) {
+			sealed = append(sealed, row)
+			continue
+		}
+
+		line := csvRow(row)
+		sum := sha256.Sum256(line)
+		if cached, ok := s.rows[i]; ok && cached.sum == sum {
+			sealed = append(sealed, cached.sealed)
+			continue
+		}
+
+		msg, err := s.key.Encrypt(line)
+		if err != nil {
+			// The row is left out rather than reported in the clear, it is
+			// tried again on the next status
+			log.WithField("error", err.Error()).Error("Unable to encrypt a row of results.")
+			continue
+		}
+
+		s.rows[i] = sealedRow{sum: sum, sealed: []string{msg}}
+		sealed = append(sealed, s.rows[i].sealed)
+	}
+
+	if len(titles) == 1 && strings.HasPrefix(titles[0], SEALED_TITLE) {
+		return titles, sealed
+	}
+	return []string{SEALED_TITLE + string(bytes.TrimSpace(csvRow(titles)))}, sealed
+}
+
+// A row as a line of CSV, the form the row is decrypted to
+func csvRow(row []string) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(row)
+	w.Flush()
+
+	return b.Bytes()
+}
+
+// Encrypt the output of a task status for the key of its job, if it has one
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) sealStatus(j *common.Job) {
+	s, ok := q.sealers[j.UUID]
+	if !ok {
+		return
+	}
+
+	j.OutputTitles, j.OutputData = s.seal(j.OutputTitles, j.OutputData)
+}
+
+// Encrypt the results kept in a checkpoint of a task for the key of its job,
+// if it has one
+func (q *Queue) sealCheckpoint(jobUUID string, cp *common.Checkpoint) {
+	q.RLock()
+	s, ok := q.sealers[jobUUID]
+	q.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	_, cp.Output = s.seal(nil, cp.Output)
+}
+
+// Check if the results of a task are encrypted for the key of its job
+// A LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (q *Queue) sealed(jobUUID string) bool {
+	_, ok := q.sealers[jobUUID]
+	return ok
+}
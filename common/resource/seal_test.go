@@ -0,0 +1,63 @@
+package resource
+
+import (
+	"github.com/jmmcatee/cracklord/common/pgp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResultSealer(t *testing.T) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	home := t.TempDir()
+	run := func(args ...string) string {
+		out, err := exec.Command(gpg, append([]string{"--homedir", home, "--batch", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("gpg %v failed: %v", args, err)
+		}
+		return string(out)
+	}
+	run("--quick-gen-key", "results@example.com", "future-default", "default", "never")
+
+	s, err := newResultSealer(run("--armor", "--export", "results@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := [][]string{{"8846f7eaee8fb117ad06bdd830b7586c", "password"}}
+	titles, sealed := s.seal([]string{"Hash", "Plaintext"}, rows)
+	if len(titles) != 1 || titles[0] != SEALED_TITLE+"Hash,Plaintext" {
+		t.Errorf("Expected a single encrypted title, got %v", titles)
+	}
+	if len(sealed) != 1 || len(sealed[0]) != 1 || !pgp.IsArmoredMessage(sealed[0][0]) {
+		t.Fatalf("Expected the row to be encrypted, got %v", sealed)
+	}
+
+	// Rows are only encrypted once and encrypted rows are kept as they are
+	rows = append(rows, []string{"5f4dcc3b5aa765d61d8327deb882cf99", "hunter2"})
+	_, again := s.seal(titles, rows)
+	if len(again) != 2 || again[0][0] != sealed[0][0] {
+		t.Errorf("Expected the first row to be encrypted once, got %v", again)
+	}
+	if _, kept := s.seal(titles, again); kept[1][0] != again[1][0] {
+		t.Error("Expected encrypted rows to be kept as they are")
+	}
+
+	path := filepath.Join(home, "row.asc")
+	if err := os.WriteFile(path, []byte(again[1][0]), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(run("--decrypt", path)); got != "5f4dcc3b5aa765d61d8327deb882cf99,hunter2" {
+		t.Errorf("Expected the row to decrypt to CSV, got %q", got)
+	}
+
+	if _, err := newResultSealer("not a key"); err == nil {
+		t.Error("Expected an error for a result key that is not an OpenPGP key")
+	}
+}
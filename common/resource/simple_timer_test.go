@@ -6,6 +6,7 @@ import (
 	"github.com/jmmcatee/cracklord/common"
 	"io"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -59,6 +60,8 @@ func (s *SimpleTimerTooler) NewTask(j common.Job) (common.Tasker, error) {
 	}, nil
 }
 
+// The job is updated by the timer goroutine while the resource reads its status,
+// so it is only used with the mutex held
 type SimpleTimer struct {
 	s    time.Time
 	d    time.Duration
@@ -66,26 +69,33 @@ type SimpleTimer struct {
 	t    *time.Timer
 	kill chan bool
 	j    common.Job
+	mux  sync.Mutex
 }
 
 func (t *SimpleTimer) Status() common.Job {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
 	if t.j.Status == common.STATUS_PAUSED {
-		t.j.OutputData["TimeLeft"] = strconv.Itoa(int(t.r))
+		t.j.PerformanceData["TimeLeft"] = strconv.Itoa(int(t.r))
 	}
 
 	if t.j.Status == common.STATUS_RUNNING {
 		if t.r == 0 {
-			t.j.OutputData["TimeLeft"] = strconv.Itoa(int(t.d - time.Since(t.s)))
+			t.j.PerformanceData["TimeLeft"] = strconv.Itoa(int(t.d - time.Since(t.s)))
 		} else {
-			t.j.OutputData["TimeLeft"] = strconv.Itoa(int(t.d - time.Since(t.s) - t.r))
+			t.j.PerformanceData["TimeLeft"] = strconv.Itoa(int(t.d - time.Since(t.s) - t.r))
 		}
 
 	}
 
-	return t.j
+	return t.j.Clone()
 }
 
 func (t *SimpleTimer) Run() error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
 	if t.j.Status == common.STATUS_FAILED || t.j.Status == common.STATUS_DONE {
 		return errors.New("Cannot start task as its status is " + t.j.Status)
 	}
@@ -106,19 +116,24 @@ func (t *SimpleTimer) Run() error {
 	t.j.Status = common.STATUS_RUNNING
 	t.kill = make(chan bool)
 
-	go func() {
+	go func(timer *time.Timer, kill chan bool) {
 		select {
-		case <-t.t.C:
+		case <-timer.C:
+			t.mux.Lock()
 			t.j.Status = common.STATUS_DONE
-		case <-t.kill:
+			t.mux.Unlock()
+		case <-kill:
 			return
 		}
-	}()
+	}(t.t, t.kill)
 
 	return nil
 }
 
 func (t *SimpleTimer) Pause() error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
 	if t.j.Status == common.STATUS_RUNNING {
 		t.t.Stop()
 
@@ -136,16 +151,26 @@ func (t *SimpleTimer) Pause() error {
 }
 
 func (t *SimpleTimer) Quit() common.Job {
-	t.t.Stop()
-	t.kill <- true
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// The goroutine may be waiting on the mutex for the timer, so it is told
+	// to stop without waiting for it
+	if t.t != nil {
+		t.t.Stop()
+	}
+	if t.kill != nil {
+		close(t.kill)
+		t.kill = nil
+	}
 
-	if t.j.Status != common.STATUS_DONE || t.j.Status != common.STATUS_FAILED || t.j.Status != common.STATUS_QUIT {
+	if t.j.Status != common.STATUS_DONE && t.j.Status != common.STATUS_FAILED && t.j.Status != common.STATUS_QUIT {
 		t.j.Error = "Stopped by user"
 		t.j.Status = common.STATUS_QUIT
-		return t.j
+		return t.j.Clone()
 	}
 
-	return t.j
+	return t.j.Clone()
 }
 
 func (t *SimpleTimer) IOE() (io.Writer, io.Reader, io.Reader) {
@@ -0,0 +1,166 @@
+package resource
+
+import (
+	"crypto/ed25519"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How long to wait after replying to an update before exiting so the queue
+// receives the response
+var UpdateExitDelay = 2 * time.Second
+
+// Set the key pushed updates must be signed with. Updates are refused until
+// a key is set.
+func (q *Queue) SetUpdateKey(key ed25519.PublicKey) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.update = key
+}
+
+// Return the build of the running resourceserver so the queue can confirm an
+// update took effect
+func (q *Queue) ResourceBuild(rpc common.RPCCall, build *string) error {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.build == "" {
+		return errors.New("Unable to determine the build of this resource.")
+	}
+
+	*build = q.build
+
+	return nil
+}
+
+// Replace the resourceserver executable with a signed binary pushed from the
+// queue and exit so the service manager starts the new version. The build of
+// the new binary is returned.
+func (q *Queue) ResourceUpdate(rpc common.RPCCall, build *string) error {
+	log.Info("Resource update received from the queue.")
+
+	// Add a defered catch for panic from within the update
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("Recovered from Panic in Resource.ResourceUpdate: %v", err)
+		}
+	}()
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.update == nil {
+		return errors.New("Updates are not enabled on this resource.")
+	}
+
+	if rpc.Update == nil {
+		return errors.New("No update package was provided.")
+	}
+
+	err := rpc.Update.Verify(q.update)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Rejected resource update.")
+		return err
+	}
+
+	// Never swap the binary out from under running work
+	for _, t := range q.stack {
+		s := t.Status().Status
+		if s == common.STATUS_RUNNING || s == common.STATUS_PAUSED {
+			return errors.New("Resource has active tasks and cannot be updated.")
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	err = replaceExecutable(exe, rpc.Update.Binary)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to install resource update.")
+		return err
+	}
+
+	*build = rpc.Update.Build()
+
+	log.WithFields(log.Fields{
+		"executable": exe,
+		"build":      *build,
+	}).Info("Resource update installed, restarting.")
+
+	go func() {
+		time.Sleep(UpdateExitDelay)
+		os.Exit(0)
+	}()
+
+	return nil
+}
+
+// Write the new binary beside the executable and swap it into place, keeping
+// the previous version as a .old file. Renaming rather than overwriting works
+// while the executable is running on every platform.
+func replaceExecutable(exe string, bin []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(exe), filepath.Base(exe)+".update-")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(bin)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode())
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	old := exe + ".old"
+	os.Remove(old)
+
+	err = os.Rename(exe, old)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), exe)
+	if err != nil {
+		// Put the original back so the resource still starts
+		os.Rename(old, exe)
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Get the build of the running executable
+func executableBuild() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+
+	bin, err := ioutil.ReadFile(exe)
+	if err != nil {
+		return ""
+	}
+
+	return common.BuildHash(bin)
+}
@@ -0,0 +1,382 @@
+// Package s3 is a small client for Amazon S3 and compatible object stores such
+// as MinIO. It signs requests with AWS Signature Version 4 and can create
+// presigned URLs so others can get objects without credentials.
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The payload hash sent when the body is not signed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+var ErrNotFound = errors.New("The object does not exist.")
+
+// A bucket in S3 or a compatible object store
+type Client struct {
+	Endpoint     string // Such as https://s3.amazonaws.com or http://minio:9000
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // Only needed for temporary credentials
+	PathStyle    bool   // Put the bucket in the path instead of the host name, which MinIO needs
+	HTTP         *http.Client
+}
+
+func New(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool) *Client {
+	if endpoint == "" {
+		endpoint = "https://s3.amazonaws.com"
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &Client{
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		PathStyle: pathStyle,
+		HTTP:      &http.Client{Timeout: 30 * time.Minute},
+	}
+}
+
+// An error document returned by the object store
+type apiError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	status  string
+}
+
+func (e apiError) Error() string {
+	if e.Code == "" {
+		return "S3 returned " + e.status
+	}
+	return "S3 error " + e.Code + ": " + e.Message
+}
+
+// Store an object of the given size
+func (c *Client) Put(key string, body io.Reader, size int64) error {
+	req, err := c.newRequest("PUT", key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get an object, the caller must close it
+func (c *Client) Get(key string) (io.ReadCloser, error) {
+	req, err := c.newRequest("GET", key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Delete an object. Deleting an object that does not exist is not an error.
+func (c *Client) Delete(key string) error {
+	req, err := c.newRequest("DELETE", key, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List the keys of every object starting with the prefix
+func (c *Client) List(prefix string) ([]string, error) {
+	var keys []string
+	var token string
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := c.newRequest("GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range result.Contents {
+			keys = append(keys, o.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Create a URL that allows anyone with it to make the request until it expires
+func (c *Client) Presign(method, key string, expires time.Duration) (string, error) {
+	return c.presign(method, key, expires, time.Now())
+}
+
+func (c *Client) presign(method, key string, expires time.Duration, now time.Time) (string, error) {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := c.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if c.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	canonical := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", c.signature(canonical, amzDate, scope, now))
+	u.RawQuery = canonicalQuery(query)
+
+	return u.String(), nil
+}
+
+// The URL of an object, or of the bucket for an empty key
+func (c *Client) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/" + escapePath(key)
+	if c.PathStyle {
+		path = "/" + c.Bucket + path
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+	}
+
+	u.Path, err = url.PathUnescape(path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawPath = path
+
+	return u, nil
+}
+
+func (c *Client) newRequest(method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sign(req, time.Now())
+	return req, nil
+}
+
+// Sign the request with headers. The body is not hashed so it can be
+// streamed, which S3 allows over TLS.
+func (c *Client) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := c.scope(now)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	// The host and every x-amz header are signed
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	var names []string
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders string
+	for _, k := range names {
+		canonicalHeaders += k + ":" + headers[k] + "\n"
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signed,
+		unsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signed, c.signature(canonical, amzDate, scope, now)))
+}
+
+func (c *Client) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + c.Region + "/s3/aws4_request"
+}
+
+func (c *Client) signature(canonical, amzDate, scope string, now time.Time) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	e := apiError{status: resp.Status}
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	xml.Unmarshal(data, &e)
+
+	return nil, e
+}
+
+// Encode everything but unreserved characters as S3 expects
+func escape(s string) string {
+	var b bytes.Buffer
+	for _, c := range []byte(s) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func escapePath(key string) string {
+	parts := strings.Split(key, "/")
+	for i := range parts {
+		parts[i] = escape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
+
+func canonicalQuery(query url.Values) string {
+	var keys []string
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, escape(k)+"="+escape(v))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
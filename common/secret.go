@@ -0,0 +1,113 @@
+package common
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Prefix of configuration values read from the secret store in place of a file
+// or plaintext value, such as vault:secret/data/cracklord/queue#key
+const SECRET_PREFIX = "vault:"
+
+// A store of keys and passwords that configuration files refer to
+type SecretStore interface {
+	// Get the secret a reference without its prefix points to
+	Secret(ref string) (string, error)
+}
+
+// The store secret references are read from, nil when none is configured
+var Secrets SecretStore
+
+// How often secrets being used are read again so rotated ones are picked up,
+// 0 only reads them at startup
+var SecretRefresh = 5 * time.Minute
+
+var ErrNoSecretStore = errors.New("A secret is referred to but no secret store is configured.")
+
+// Check if a configuration value refers to the secret store
+func IsSecretRef(v string) bool {
+	return strings.HasPrefix(v, SECRET_PREFIX)
+}
+
+// Return a configuration value, the secret it refers to, or the value it
+// decrypts to with the master key
+func ResolveSecret(v string) (string, error) {
+	if IsEncrypted(v) {
+		b, err := DecryptValue(MasterKey, v)
+		return string(b), err
+	}
+	if !IsSecretRef(v) {
+		return v, nil
+	}
+	if Secrets == nil {
+		return "", ErrNoSecretStore
+	}
+
+	return Secrets.Secret(strings.TrimPrefix(v, SECRET_PREFIX))
+}
+
+// Read a file, or the secret the path refers to such as a PEM encoded key.
+// Files encrypted with the master key are decrypted.
+func ReadSecretFile(path string) ([]byte, error) {
+	if IsSecretRef(path) || IsEncrypted(path) {
+		v, err := ResolveSecret(path)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(v), nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(string(b)) {
+		return DecryptValue(MasterKey, string(b))
+	}
+	return b, nil
+}
+
+// Read the secrets the values refer to again every SecretRefresh and call
+// changed when any of them have been rotated. Values that are not references
+// never change so nothing is watched without any.
+func WatchSecrets(changed func(), values ...string) {
+	last := map[string]string{}
+	for _, v := range values {
+		if IsSecretRef(v) {
+			last[v], _ = ResolveSecret(v)
+		}
+	}
+	if len(last) == 0 || SecretRefresh <= 0 {
+		return
+	}
+
+	go func() {
+		for {
+			time.Sleep(SecretRefresh)
+
+			rotated := false
+			for ref, old := range last {
+				v, err := ResolveSecret(ref)
+				if err != nil {
+					log.WithFields(log.Fields{
+						"secret": ref,
+						"error":  err.Error(),
+					}).Warn("Unable to read secret to check if it was rotated.")
+					continue
+				}
+				if v != old {
+					last[ref] = v
+					rotated = true
+				}
+			}
+
+			if rotated {
+				changed()
+			}
+		}
+	}()
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+type testSecrets map[string]string
+
+func (s testSecrets) Secret(ref string) (string, error) {
+	v, ok := s[ref]
+	if !ok {
+		return "", errors.New("no such secret")
+	}
+	return v, nil
+}
+
+func TestResolveSecret(t *testing.T) {
+	old := Secrets
+	t.Cleanup(func() { Secrets = old })
+
+	Secrets = nil
+	if _, err := ResolveSecret("vault:secret/data/smtp#password"); err != ErrNoSecretStore {
+		t.Errorf("Expected an error without a secret store, got %v", err)
+	}
+
+	Secrets = testSecrets{"secret/data/smtp#password": "hunter2"}
+
+	if v, err := ResolveSecret("plaintext"); err != nil || v != "plaintext" {
+		t.Errorf("Expected values that are not references to be kept, got %q %v", v, err)
+	}
+	if v, err := ResolveSecret("vault:secret/data/smtp#password"); err != nil || v != "hunter2" {
+		t.Errorf("Expected the secret, got %q %v", v, err)
+	}
+	if b, err := ReadSecretFile("vault:secret/data/smtp#password"); err != nil || string(b) != "hunter2" {
+		t.Errorf("Expected the secret in place of the file, got %q %v", b, err)
+	}
+	if _, err := ReadSecretFile("vault:secret/data/missing"); err == nil {
+		t.Error("Expected an error for a missing secret")
+	}
+}
@@ -0,0 +1,165 @@
+// Package shared fetches wordlists, rules and other large files a resource
+// needs from a bucket shared with the queue server. The queue hands out
+// presigned URLs for the files with each call so resources never need the
+// bucket credentials and the files never pass through the queue server.
+//
+// A tool refers to a shared file with a path such as s3:wordlists/rockyou.txt
+// and calls Path to get the file in the local cache.
+package shared
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// The prefix of paths that refer to a key in the shared bucket
+const Scheme = "s3:"
+
+// Where shared files are kept on the resource, they are not fetched when empty
+var CacheDir string
+
+var client = &http.Client{Timeout: 6 * time.Hour}
+
+var (
+	urls  map[string]string
+	locks = map[string]*sync.Mutex{}
+	mux   sync.Mutex
+)
+
+// Keep the presigned URLs from the latest call by the queue
+func SetURLs(u map[string]string) {
+	if u == nil {
+		return
+	}
+
+	mux.Lock()
+	urls = u
+	mux.Unlock()
+}
+
+// Check if a path refers to the shared bucket
+func IsShared(path string) bool {
+	return strings.HasPrefix(path, Scheme)
+}
+
+// Get the local path of a file. Shared files are downloaded to the cache when
+// they are missing or have changed in the bucket, any other path is returned
+// as it is.
+func Path(path string) (string, error) {
+	if !IsShared(path) {
+		return path, nil
+	}
+	key := strings.TrimPrefix(path, Scheme)
+
+	if CacheDir == "" {
+		return "", errors.New("No cache directory is configured for shared files.")
+	}
+
+	local := filepath.Join(CacheDir, filepath.FromSlash(key))
+	if !strings.HasPrefix(local, filepath.Clean(CacheDir)+string(filepath.Separator)) {
+		return "", errors.New("The shared file is outside of the cache directory.")
+	}
+
+	// Only one download of a file at a time
+	mux.Lock()
+	u := urls[key]
+	l, ok := locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		locks[key] = l
+	}
+	mux.Unlock()
+
+	l.Lock()
+	defer l.Unlock()
+
+	if u == "" {
+		if _, err := os.Stat(local); err == nil {
+			log.WithField("key", key).Warn("No URL for the shared file, using the cached copy.")
+			return local, nil
+		}
+		return "", errors.New("The shared file " + key + " is not in the bucket used by the queue.")
+	}
+
+	err := fetch(u, local)
+	if err != nil {
+		return "", err
+	}
+
+	return local, nil
+}
+
+// Download the file unless the cached copy has the same ETag
+func fetch(u, local string) error {
+	logger := log.WithField("file", local)
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(local); err == nil {
+		if etag, err := ioutil.ReadFile(local + ".etag"); err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.New("Unable to download shared file: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		logger.Debug("Cached shared file is current.")
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Unable to download shared file: " + resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(local), 0700); err != nil {
+		return err
+	}
+
+	// Download next to the cached copy so running tasks keep the old one
+	f, err := ioutil.TempFile(filepath.Dir(local), ".fetch-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	logger.Info("Downloading shared file.")
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.New("Unable to download shared file: " + err.Error())
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return errors.New("The shared file download was incomplete.")
+	}
+
+	if err := os.Rename(f.Name(), local); err != nil {
+		return err
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		os.Remove(local + ".etag")
+	} else {
+		ioutil.WriteFile(local+".etag", []byte(etag), 0600)
+	}
+
+	logger.WithField("bytes", n).Info("Shared file downloaded.")
+	return nil
+}
@@ -0,0 +1,64 @@
+package shared
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shared-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CacheDir = dir
+
+	gets := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		gets++
+		rw.Header().Set("ETag", `"v1"`)
+		rw.Write([]byte("password\n123456\n"))
+	}))
+	defer srv.Close()
+
+	if p, err := Path("/mnt/dicts/local.txt"); err != nil || p != "/mnt/dicts/local.txt" {
+		t.Fatalf("Local path changed to %q, %v", p, err)
+	}
+
+	if _, err := Path(Scheme + "wordlists/missing.txt"); err == nil {
+		t.Fatal("A file without a URL or cached copy did not fail")
+	}
+
+	SetURLs(map[string]string{"wordlists/top.txt": srv.URL + "/top.txt"})
+
+	for i := 0; i < 2; i++ {
+		p, err := Path(Scheme + "wordlists/top.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != filepath.Join(dir, "wordlists", "top.txt") {
+			t.Fatalf("Cached at %q", p)
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil || string(data) != "password\n123456\n" {
+			t.Fatalf("Cached file is %q, %v", data, err)
+		}
+	}
+
+	if gets != 1 {
+		t.Fatalf("File was downloaded %d times, the cached copy should be used", gets)
+	}
+
+	if _, err := Path(Scheme + "../outside.txt"); err == nil {
+		t.Fatal("A key outside the cache directory was allowed")
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"strings"
+	"sync"
+)
+
+// LineTail keeps the last lines written to it so recent output can be
+// retrieved without storing everything
+type LineTail struct {
+	lines   []string
+	next    int
+	full    bool
+	partial string
+	sync.Mutex
+}
+
+// Create a tail holding up to size lines
+func NewLineTail(size int) *LineTail {
+	if size < 1 {
+		size = 1
+	}
+
+	return &LineTail{
+		lines: make([]string, size),
+	}
+}
+
+// Write adds output to the tail, partial lines are held until they are complete
+func (t *LineTail) Write(p []byte) (int, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	data := t.partial + string(p)
+	parts := strings.Split(data, "\n")
+
+	// The last part is whatever followed the final newline
+	t.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		t.add(strings.TrimRight(line, "\r"))
+	}
+
+	return len(p), nil
+}
+
+func (t *LineTail) add(line string) {
+	t.lines[t.next] = line
+	t.next++
+	if t.next == len(t.lines) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// Get up to the last n complete lines in the order they were written
+func (t *LineTail) Lines(n int) []string {
+	t.Lock()
+	defer t.Unlock()
+
+	count := t.next
+	if t.full {
+		count = len(t.lines)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	out := make([]string, 0, n)
+	for i := count - n; i < count; i++ {
+		idx := i
+		if t.full {
+			idx = (t.next + i) % len(t.lines)
+		}
+		out = append(out, t.lines[idx])
+	}
+
+	return out
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineTail(t *testing.T) {
+	tail := NewLineTail(3)
+
+	tail.Write([]byte("one\ntwo\nthr"))
+	if lines := tail.Lines(0); !reflect.DeepEqual(lines, []string{"one", "two"}) {
+		t.Errorf("Unexpected lines before wrapping: %v", lines)
+	}
+
+	// Finish the partial line and wrap around the buffer
+	tail.Write([]byte("ee\r\nfour\nfive\n"))
+	if lines := tail.Lines(0); !reflect.DeepEqual(lines, []string{"three", "four", "five"}) {
+		t.Errorf("Unexpected lines after wrapping: %v", lines)
+	}
+
+	if lines := tail.Lines(2); !reflect.DeepEqual(lines, []string{"four", "five"}) {
+		t.Errorf("Unexpected last two lines: %v", lines)
+	}
+}
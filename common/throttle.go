@@ -0,0 +1,103 @@
+package common
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Throttle limits the number of bytes per second passed through it. A rate of
+// zero or less is unlimited. The rate can be changed at any time, even while
+// connections are using the throttle.
+type Throttle struct {
+	rate int64
+	next time.Time
+	sync.Mutex
+}
+
+func NewThrottle(rate int64) *Throttle {
+	return &Throttle{rate: rate}
+}
+
+func (t *Throttle) SetRate(rate int64) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.rate = rate
+}
+
+func (t *Throttle) Rate() int64 {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.rate
+}
+
+// The largest amount of data handled at once, so that a large read or write is
+// spread out instead of arriving in a single burst
+func (t *Throttle) chunk(n int) int {
+	rate := t.Rate()
+	if rate <= 0 {
+		return n
+	}
+
+	max := int(rate / 10)
+	if max < 1 {
+		max = 1
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// Wait blocks long enough that n bytes stay within the configured rate
+func (t *Throttle) Wait(n int) {
+	t.Lock()
+	if t.rate <= 0 {
+		t.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.rate))
+	d := t.next.Sub(now)
+	t.Unlock()
+
+	time.Sleep(d)
+}
+
+// ThrottledConn wraps a connection so both reads and writes share a Throttle
+type ThrottledConn struct {
+	net.Conn
+	t *Throttle
+}
+
+func NewThrottledConn(conn net.Conn, t *Throttle) net.Conn {
+	return &ThrottledConn{Conn: conn, t: t}
+}
+
+func (c *ThrottledConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p[:c.t.chunk(len(p))])
+	c.t.Wait(n)
+	return n, err
+}
+
+func (c *ThrottledConn) Write(p []byte) (int, error) {
+	var written int
+	for written < len(p) {
+		size := c.t.chunk(len(p) - written)
+		c.t.Wait(size)
+
+		n, err := c.Conn.Write(p[written : written+size])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestThrottledConnWrite(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go io.Copy(ioutil.Discard, server)
+
+	// 500 bytes at 1000 bytes per second should take around half a second
+	conn := NewThrottledConn(client, NewThrottle(1000))
+
+	start := time.Now()
+	n, err := conn.Write(make([]byte, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 500 {
+		t.Errorf("Expected 500 bytes written but wrote %d", n)
+	}
+
+	if time.Since(start) < 400*time.Millisecond {
+		t.Errorf("Write was not throttled, took %s", time.Since(start))
+	}
+}
+
+func TestThrottleUnlimited(t *testing.T) {
+	th := NewThrottle(0)
+
+	start := time.Now()
+	th.Wait(1 << 30)
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("Unlimited throttle should not wait")
+	}
+
+	th.SetRate(100)
+	if th.Rate() != 100 {
+		t.Errorf("Expected rate of 100 but got %d", th.Rate())
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"io"
-	"io/ioutil"
 	"math/big"
 	"os"
+	"sync"
 	"time"
+
+	log "github.com/Sirupsen/logrus"
 )
 
 // GenerateResourceKeys generates the client authentication certificate and
@@ -31,6 +35,7 @@ func GenerateResourceKeys(ca *x509.Certificate, caPrivKey *rsa.PrivateKey, cn st
 			OrganizationalUnit: []string{"Operations"},
 			CommonName:         cn,
 		},
+		DNSNames:              []string{cn}, // Names are only checked against the SANs
 		SignatureAlgorithm:    x509.SHA256WithRSA,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
@@ -60,30 +65,22 @@ func GenerateResourceKeys(ca *x509.Certificate, caPrivKey *rsa.PrivateKey, cn st
 
 // Parse PEM encoded certificate and private key from file path locations
 func GetCertandKey(certPath, keyPath string) (*x509.Certificate, *rsa.PrivateKey, error) {
-	// Check if we can open the two file paths give
-	certFile, err := os.Open(certPath)
-	if err != nil {
-		return &x509.Certificate{}, &rsa.PrivateKey{}, err
-	}
-
-	keyFile, err := os.Open(keyPath)
+	// Read the two files, either of which can instead be kept in the secret store
+	certBytes, err := ReadSecretFile(certPath)
 	if err != nil {
 		return &x509.Certificate{}, &rsa.PrivateKey{}, err
 	}
 
-	// Get the bytes of the files to parse
-	certBytes, err := ioutil.ReadAll(certFile)
-	if err != nil {
-		return &x509.Certificate{}, &rsa.PrivateKey{}, err
-	}
-
-	keyBytes, err := ioutil.ReadAll(keyFile)
+	keyBytes, err := ReadSecretFile(keyPath)
 	if err != nil {
 		return &x509.Certificate{}, &rsa.PrivateKey{}, err
 	}
 
 	certBlock, _ := pem.Decode(certBytes)
 	keyBlock, _ := pem.Decode(keyBytes)
+	if certBlock == nil || keyBlock == nil {
+		return &x509.Certificate{}, &rsa.PrivateKey{}, errors.New("The certificate or key is not PEM encoded.")
+	}
 
 	// Parse cert and key
 	cert, err := x509.ParseCertificate(certBlock.Bytes)
@@ -99,6 +96,78 @@ func GetCertandKey(certPath, keyPath string) (*x509.Certificate, *rsa.PrivateKey
 	return cert, key, nil
 }
 
+// A certificate and private key that are loaded again when they are rotated
+// in the secret store, without dropping the connections using them
+type KeyPair struct {
+	CertPath string
+	KeyPath  string
+	mux      sync.RWMutex
+	cert     *tls.Certificate
+}
+
+// Load a certificate and private key from files or the secret store
+func LoadKeyPair(certPath, keyPath string) (*KeyPair, error) {
+	k := &KeyPair{CertPath: certPath, KeyPath: keyPath}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Read the certificate and private key again
+func (k *KeyPair) Reload() error {
+	certBytes, err := ReadSecretFile(k.CertPath)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := ReadSecretFile(k.KeyPath)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(certBytes, keyBytes)
+	if err != nil {
+		return err
+	}
+
+	k.mux.Lock()
+	k.cert = &cert
+	k.mux.Unlock()
+
+	return nil
+}
+
+// Load the pair again whenever the secrets it is read from are rotated, it is
+// never reloaded when it is kept in files
+func (k *KeyPair) Watch() {
+	WatchSecrets(func() {
+		if err := k.Reload(); err != nil {
+			log.WithFields(log.Fields{
+				"cert":  k.CertPath,
+				"error": err.Error(),
+			}).Error("Unable to load rotated certificate and key, still using the old ones.")
+			return
+		}
+		log.WithField("cert", k.CertPath).Info("Loaded rotated certificate and key.")
+	}, k.CertPath, k.KeyPath)
+}
+
+// Serve the current certificate, for tls.Config.GetCertificate
+func (k *KeyPair) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+	return k.cert, nil
+}
+
+// Authenticate with the current certificate, for
+// tls.Config.GetClientCertificate
+func (k *KeyPair) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	k.mux.RLock()
+	defer k.mux.RUnlock()
+	return k.cert, nil
+}
+
 // WriteCertificateToPEM converts a certificate to PEM and writes to filepath
 func WriteCertificateToFile(cert *x509.Certificate, filepath string) error {
 	return writePEMFile(cert.Raw, filepath, "CERTIFICATE")
@@ -9,6 +9,8 @@ type Tool struct {
 	UUID         string
 	Parameters   string
 	Requirements string
+	FormVersion  int             // Version of the form in Parameters, 0 when the tool does not version it
+	Migrations   []FormMigration // Steps translating the parameters of older versions of the form
 }
 
 // Compare two Tools to see if they are the same
@@ -33,5 +35,36 @@ func CompareTools(t1, t2 Tool) bool {
 		return false
 	}
 
+	if t1.FormVersion != t2.FormVersion {
+		return false
+	}
+
 	return true
 }
+
+// Parameters an Administrator has set for every job of a tool
+type ToolDefaults struct {
+	Defaults map[string]string // Used when a job does not set the parameter
+	Locked   map[string]string // Always used, replacing anything a job sets
+}
+
+// Merge the defaults and locked values into a copy of the job parameters
+func (d ToolDefaults) Apply(params map[string]string) map[string]string {
+	out := make(map[string]string, len(params)+len(d.Defaults)+len(d.Locked))
+
+	for k, v := range d.Defaults {
+		out[k] = v
+	}
+	for k, v := range params {
+		if _, ok := d.Defaults[k]; ok && v == "" {
+			// An empty form field keeps the default
+			continue
+		}
+		out[k] = v
+	}
+	for k, v := range d.Locked {
+		out[k] = v
+	}
+
+	return out
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestToolDefaultsApply(t *testing.T) {
+	d := ToolDefaults{
+		Defaults: map[string]string{"rules": "best64.rule", "workload": "3"},
+		Locked:   map[string]string{"outfile_format": "3"},
+	}
+
+	params := map[string]string{
+		"workload":       "4",
+		"rules":          "",
+		"outfile_format": "1",
+		"hashes":         "abc",
+	}
+
+	out := d.Apply(params)
+
+	want := map[string]string{
+		"rules":          "best64.rule",
+		"workload":       "4",
+		"outfile_format": "3",
+		"hashes":         "abc",
+	}
+	for k, v := range want {
+		if out[k] != v {
+			t.Errorf("Expected %s to be %q but got %q", k, v, out[k])
+		}
+	}
+
+	if params["outfile_format"] != "1" {
+		t.Error("Apply changed the parameters it was given")
+	}
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+)
+
+// A resourceserver binary pushed from the queue along with its signature
+type UpdatePackage struct {
+	Binary    []byte
+	Signature []byte // Ed25519 signature of Binary
+}
+
+// Load the PEM encoded Ed25519 public key used to verify update packages, as
+// produced by `openssl pkey -pubout`
+func ParseUpdateKey(path string) (ed25519.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("Update key is not PEM encoded.")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("Update key is not an Ed25519 public key.")
+	}
+
+	return key, nil
+}
+
+// Check the package was signed by the holder of the update key
+func (p UpdatePackage) Verify(key ed25519.PublicKey) error {
+	if len(p.Binary) == 0 {
+		return errors.New("Update package does not contain a binary.")
+	}
+
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, p.Binary, p.Signature) {
+		return errors.New("Update package signature is not valid.")
+	}
+
+	return nil
+}
+
+// The build identifier of the binary, which is the hex SHA-256 of its contents
+func (p UpdatePackage) Build() string {
+	return BuildHash(p.Binary)
+}
+
+// Get the hex SHA-256 of a binary, used to confirm which build a resource runs
+func BuildHash(bin []byte) string {
+	sum := sha256.Sum256(bin)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestUpdatePackageVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bin := []byte("resourceserver binary")
+	p := UpdatePackage{Binary: bin, Signature: ed25519.Sign(priv, bin)}
+
+	if err := p.Verify(pub); err != nil {
+		t.Errorf("Valid signature was rejected: %s", err.Error())
+	}
+
+	p.Binary = []byte("tampered binary")
+	if err := p.Verify(pub); err == nil {
+		t.Error("Signature over different contents was accepted")
+	}
+
+	if err := (UpdatePackage{Binary: bin}).Verify(pub); err == nil {
+		t.Error("Missing signature was accepted")
+	}
+}
+
+func TestParseUpdateKey(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ioutil.TempFile("", "updatekey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	pem.Encode(f, &pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	f.Close()
+
+	key, err := ParseUpdateKey(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !key.Equal(pub) {
+		t.Error("Parsed key does not match the original")
+	}
+}
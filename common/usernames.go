@@ -0,0 +1,109 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Title of the column added to results when usernames are joined back
+const UsernameTitle = "Username"
+
+// Split a list of user:hash lines into the unique hashes to crack and the
+// users of each hash. Lines in pwdump or secretsdump form
+// (user:rid:lmhash:nthash:::) use the NT hash, any other line is split at the
+// first colon. Lines without a username are kept as a bare hash.
+func SplitUsernames(input string) (string, map[string][]string) {
+	var hashes []string
+	users := map[string][]string{}
+
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		user, hash := splitUserLine(line)
+		if hash == "" {
+			continue
+		}
+
+		// Hashes are matched without case as tools may change it in the output
+		key := strings.ToLower(hash)
+		if _, ok := users[key]; !ok {
+			users[key] = []string{}
+			hashes = append(hashes, hash)
+		}
+		if user != "" {
+			users[key] = append(users[key], user)
+		}
+	}
+
+	return strings.Join(hashes, "\n"), users
+}
+
+func splitUserLine(line string) (string, string) {
+	fields := strings.Split(line, ":")
+
+	if len(fields) >= 4 && isNumber(fields[1]) && isHex(fields[2], 32) && isHex(fields[3], 32) {
+		return fields[0], fields[3]
+	}
+
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", line
+	}
+
+	return line[:i], line[i+1:]
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+
+	for _, c := range strings.ToLower(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the output of the job with a username column added. A row is returned
+// for every user of a cracked hash. Jobs submitted without usernames are
+// returned unchanged.
+func (j Job) JoinUsernames() ([]string, [][]string) {
+	if len(j.Usernames) == 0 {
+		return j.OutputTitles, j.OutputRows()
+	}
+
+	titles := append([]string{UsernameTitle}, j.OutputTitles...)
+
+	var rows [][]string
+	for _, row := range j.OutputRows() {
+		var users []string
+		for _, cell := range row {
+			if u, ok := j.Usernames[strings.ToLower(cell)]; ok {
+				users = u
+				break
+			}
+		}
+
+		if len(users) == 0 {
+			rows = append(rows, append([]string{""}, row...))
+			continue
+		}
+
+		for _, u := range users {
+			rows = append(rows, append([]string{u}, row...))
+		}
+	}
+
+	return titles, rows
+}
@@ -0,0 +1,68 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitUsernames(t *testing.T) {
+	input := "CORP\\alice:1104:aad3b435b51404eeaad3b435b51404ee:8846F7EAEE8FB117AD06BDD830B7586C:::\n" +
+		"bob:8846f7eaee8fb117ad06bdd830b7586c\n" +
+		"\n" +
+		"carol:5f4dcc3b5aa765d61d8327deb882cf99:salt\n" +
+		"e10adc3949ba59abbe56e057f20f883e\n"
+
+	hashes, users := SplitUsernames(input)
+
+	want := "8846F7EAEE8FB117AD06BDD830B7586C\n5f4dcc3b5aa765d61d8327deb882cf99:salt\ne10adc3949ba59abbe56e057f20f883e"
+	if hashes != want {
+		t.Errorf("Expected hashes %q but got %q", want, hashes)
+	}
+
+	if !reflect.DeepEqual(users["8846f7eaee8fb117ad06bdd830b7586c"], []string{"CORP\\alice", "bob"}) {
+		t.Errorf("Unexpected users for the shared hash: %v", users["8846f7eaee8fb117ad06bdd830b7586c"])
+	}
+	if !reflect.DeepEqual(users["5f4dcc3b5aa765d61d8327deb882cf99:salt"], []string{"carol"}) {
+		t.Errorf("Unexpected users for the salted hash: %v", users)
+	}
+	if len(users["e10adc3949ba59abbe56e057f20f883e"]) != 0 {
+		t.Error("A bare hash should not have any users")
+	}
+}
+
+func TestJoinUsernames(t *testing.T) {
+	j := NewJob("tool", "name", "owner", nil)
+	j.OutputTitles = []string{"Plaintext", "Hash"}
+	j.OutputData = [][]string{
+		{"password", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"other", "ffffffffffffffffffffffffffffffff"},
+	}
+
+	// Jobs without usernames are returned as they are
+	titles, rows := j.JoinUsernames()
+	if !reflect.DeepEqual(rows, j.OutputData) || len(titles) != 2 {
+		t.Error("Output of a job without usernames was changed")
+	}
+
+	j.Usernames = map[string][]string{
+		"8846f7eaee8fb117ad06bdd830b7586c": {"alice", "bob"},
+	}
+
+	titles, rows = j.JoinUsernames()
+	if !reflect.DeepEqual(titles, []string{UsernameTitle, "Plaintext", "Hash"}) {
+		t.Errorf("Unexpected titles %v", titles)
+	}
+
+	want := [][]string{
+		{"alice", "password", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"bob", "password", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{"", "other", "ffffffffffffffffffffffffffffffff"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Expected rows %v but got %v", want, rows)
+	}
+
+	if j.ForResource().Usernames != nil {
+		t.Error("Usernames must not be sent to resources")
+	}
+}
@@ -0,0 +1,269 @@
+// Package vault reads secrets from the KV secrets engine of HashiCorp Vault so
+// keys and passwords do not have to be kept in configuration files.
+package vault
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/vaughan0/go-ini"
+)
+
+// Field of a secret read when a reference does not name one
+const DEFAULT_FIELD = "value"
+
+var ErrNotFound = errors.New("The secret does not exist in Vault.")
+
+// A Vault server and the credentials secrets are read from it with
+type Client struct {
+	Addr      string // URL of the server, such as https://vault.example.com:8200
+	Namespace string // Vault Enterprise namespace, empty for the root
+	Token     string
+	TokenFile string // Read for every request so Vault Agent can renew the token
+	RoleID    string // AppRole logged in with when no token is given
+	SecretID  string
+	Refresh   time.Duration // How often secrets in use are read again
+	HTTP      *http.Client
+	mux       sync.Mutex
+	login     string // Token of the last AppRole login
+}
+
+func New(addr string, tlsConfig *tls.Config) *Client {
+	return &Client{
+		Addr:    strings.TrimRight(addr, "/"),
+		Refresh: common.SecretRefresh,
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+// Read the Vault section of a configuration file, nil without an address. The
+// address and token can also be given in the VAULT_ADDR and VAULT_TOKEN
+// environment variables the Vault CLI uses.
+func Setup(conf ini.Section) (*Client, error) {
+	get := func(key string) string {
+		return common.StripQuotes(conf[key])
+	}
+
+	addr := get("Address")
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca := get("CACertFile"); ca != "" {
+		caBytes, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caBytes) {
+			return nil, errors.New("No certificates could be read from the Vault CACertFile.")
+		}
+	}
+
+	c := New(addr, tlsConfig)
+	c.Namespace = get("Namespace")
+	c.Token = get("Token")
+	c.TokenFile = get("TokenFile")
+	c.RoleID = get("RoleID")
+	c.SecretID = get("SecretID")
+
+	if f := get("SecretIDFile"); f != "" {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		c.SecretID = strings.TrimSpace(string(b))
+	}
+	if c.Token == "" && c.TokenFile == "" && c.RoleID == "" {
+		c.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if c.Token == "" && c.TokenFile == "" && c.RoleID == "" {
+		return nil, errors.New("A Vault address is set without a Token, TokenFile or RoleID to log in with.")
+	}
+
+	if v := get("RefreshInterval"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs < 0 {
+			return nil, errors.New("Unable to parse the Vault RefreshInterval.")
+		}
+		c.Refresh = time.Duration(secs) * time.Second
+	}
+
+	return c, nil
+}
+
+// Get the token to send, logging in with the AppRole if there is no current
+// login or it is no longer accepted
+func (c *Client) token(relogin bool) (string, error) {
+	switch {
+	case c.TokenFile != "":
+		b, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	case c.Token != "":
+		return c.Token, nil
+	case c.RoleID != "":
+		c.mux.Lock()
+		defer c.mux.Unlock()
+
+		if c.login == "" || relogin {
+			token, err := c.appRoleLogin()
+			if err != nil {
+				return "", err
+			}
+			c.login = token
+		}
+		return c.login, nil
+	}
+
+	return "", errors.New("No Vault token or AppRole is configured.")
+}
+
+// Log in with the AppRole for a token
+func (c *Client) appRoleLogin() (string, error) {
+	body, _ := json.Marshal(map[string]string{
+		"role_id":   c.RoleID,
+		"secret_id": c.SecretID,
+	})
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do("POST", "auth/approle/login", "", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("Vault did not return a token for the AppRole login.")
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+// Make a request of the HTTP API, decoding the response into out
+func (c *Client) do(method, path, token string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, c.Addr+"/v1/"+strings.TrimLeft(path, "/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return &Error{Status: resp.StatusCode, Errors: apiErr.Errors}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// An error returned by the Vault server
+type Error struct {
+	Status int
+	Errors []string
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("Vault returned status %d.", e.Status)
+	}
+	return fmt.Sprintf("Vault returned status %d: %s", e.Status, strings.Join(e.Errors, "; "))
+}
+
+// Read the fields of the secret at a path such as secret/data/cracklord. The
+// data of version 2 of the KV engine is returned without its metadata.
+func (c *Client) Read(path string) (map[string]interface{}, error) {
+	token, err := c.token(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = c.do("GET", path, token, nil, &resp)
+	if e, ok := err.(*Error); ok && e.Status == http.StatusForbidden && c.RoleID != "" && c.Token == "" && c.TokenFile == "" {
+		// The token of the last login has expired
+		if token, err = c.token(true); err != nil {
+			return nil, err
+		}
+		err = c.do("GET", path, token, nil, &resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := resp.Data["data"].(map[string]interface{}); ok {
+		if _, ok := resp.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return resp.Data, nil
+}
+
+// Get one field of a secret from a reference of its path and field, such as
+// secret/data/cracklord/smtp#password. The value field is read when no field is
+// given.
+func (c *Client) Secret(ref string) (string, error) {
+	path, field := ref, DEFAULT_FIELD
+	if i := strings.LastIndex(ref, "#"); i >= 0 {
+		path, field = ref[:i], ref[i+1:]
+	}
+
+	data, err := c.Read(path)
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("The Vault secret %s has no field %s.", path, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// A Vault server with one KV version 2 secret readable with the token
+func testServer(t *testing.T, token *string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/auth/approle/login":
+			var login map[string]string
+			json.NewDecoder(r.Body).Decode(&login)
+			if login["role_id"] != "role" || login["secret_id"] != "secret" {
+				rw.WriteHeader(http.StatusBadRequest)
+				rw.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+				return
+			}
+			rw.Write([]byte(`{"auth": {"client_token": "` + *token + `"}}`))
+		case "GET /v1/secret/data/cracklord":
+			if r.Header.Get("X-Vault-Token") != *token {
+				rw.WriteHeader(http.StatusForbidden)
+				rw.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			rw.Write([]byte(`{"data": {"data": {"password": "hunter2", "port": 25}, "metadata": {"version": 3}}}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestSecret(t *testing.T) {
+	token := "s.token"
+	srv := testServer(t, &token)
+
+	c := New(srv.URL, nil)
+	c.Token = token
+
+	v, err := c.Secret("secret/data/cracklord#password")
+	if err != nil || v != "hunter2" {
+		t.Errorf("Expected the password, got %q %v", v, err)
+	}
+
+	// Fields that are not strings are returned as JSON
+	v, err = c.Secret("secret/data/cracklord#port")
+	if err != nil || v != "25" {
+		t.Errorf("Expected the port, got %q %v", v, err)
+	}
+
+	if _, err = c.Secret("secret/data/cracklord#missing"); err == nil {
+		t.Error("Expected an error for a field the secret does not have")
+	}
+	if _, err = c.Secret("secret/data/other#password"); err != ErrNotFound {
+		t.Errorf("Expected the secret to not be found, got %v", err)
+	}
+
+	c.Token = "s.wrong"
+	if _, err = c.Secret("secret/data/cracklord#password"); err == nil {
+		t.Error("Expected an error reading with the wrong token")
+	}
+}
+
+func TestAppRoleRelogin(t *testing.T) {
+	token := "s.first"
+	srv := testServer(t, &token)
+
+	c := New(srv.URL, nil)
+	c.RoleID = "role"
+	c.SecretID = "secret"
+
+	if v, err := c.Secret("secret/data/cracklord#password"); err != nil || v != "hunter2" {
+		t.Fatalf("Expected the password, got %q %v", v, err)
+	}
+
+	// The token of the first login expires, so the client logs in again
+	token = "s.second"
+	if v, err := c.Secret("secret/data/cracklord#password"); err != nil || v != "hunter2" {
+		t.Fatalf("Expected the password after logging in again, got %q %v", v, err)
+	}
+	if c.login != "s.second" {
+		t.Errorf("Expected the new token to be kept, got %q", c.login)
+	}
+
+	c.SecretID = "wrong"
+	c.login = ""
+	if _, err := c.Secret("secret/data/cracklord#password"); err == nil {
+		t.Error("Expected the login to fail with the wrong secret ID")
+	}
+}
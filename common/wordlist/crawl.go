@@ -0,0 +1,226 @@
+package wordlist
+
+import (
+	"errors"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Limits of a crawl so a large site cannot tie up the queue server
+const (
+	DefaultCrawlPages = 50
+	MaxCrawlPages     = 1000
+	maxPageSize       = 5 * 1024 * 1024
+)
+
+var (
+	regHiddenHTML = regexp.MustCompile(`(?is)<(script|style|noscript)\b.*?</(script|style|noscript)>|<!--.*?-->`)
+	regHTMLTag    = regexp.MustCompile(`(?s)<[^>]*>`)
+	regHref       = regexp.MustCompile(`(?i)\bhref\s*=\s*["']?([^"'\s>]+)`)
+	regMetaText   = regexp.MustCompile(`(?i)<meta\b[^>]*\bcontent\s*=\s*["']([^"']*)["']`)
+)
+
+// Options for crawling sites for candidate words, in the way of CeWL
+type CrawlOptions struct {
+	URLs      []string // Pages the crawl starts from
+	Depth     int      // Links followed from the start pages, only to the same host
+	MaxPages  int      // Pages fetched in all, DefaultCrawlPages when 0
+	MinLength int      // Shortest word kept, 3 when 0
+	MaxLength int      // Longest word kept, 0 does not limit it
+	Lower     bool     // Also add a lower case copy of each word
+
+	// Called with the finished wordlist to store it elsewhere, such as the
+	// shared bucket, returning where it was stored
+	Publish func(path string) (string, error)
+
+	Client *http.Client
+}
+
+// Check the start URLs of a crawl
+func (o CrawlOptions) Validate() error {
+	if len(o.URLs) == 0 {
+		return errors.New("At least one URL is needed to crawl.")
+	}
+	for _, u := range o.URLs {
+		p, err := url.Parse(u)
+		if err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			return errors.New("Only http and https URLs can be crawled: " + u)
+		}
+	}
+	if o.Depth < 0 || o.MaxPages < 0 || o.MaxPages > MaxCrawlPages || o.MinLength < 0 || o.MaxLength < 0 {
+		return errors.New("Crawl limits are out of range.")
+	}
+	return nil
+}
+
+type crawlPage struct {
+	u     *url.URL
+	depth int
+}
+
+// Crawl the pages of the options and write the words found on them to dst,
+// sorted with duplicates removed. Text is taken from the body of pages and
+// the content of meta tags. Pages that fail to load are skipped.
+func Crawl(opts CrawlOptions, dst string) (Result, error) {
+	var res Result
+
+	if err := opts.Validate(); err != nil {
+		return res, err
+	}
+	if opts.MaxPages == 0 {
+		opts.MaxPages = DefaultCrawlPages
+	}
+	if opts.MinLength == 0 {
+		opts.MinLength = 3
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	var pending []crawlPage
+	seen := map[string]bool{}
+	for _, s := range opts.URLs {
+		u, _ := url.Parse(s)
+		u.Fragment = ""
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			pending = append(pending, crawlPage{u, 0})
+		}
+	}
+
+	words := map[string]bool{}
+	for len(pending) > 0 && res.Pages < opts.MaxPages {
+		page := pending[0]
+		pending = pending[1:]
+
+		body, ok := fetchPage(client, page.u)
+		if !ok {
+			continue
+		}
+		res.Pages++
+
+		for _, w := range pageWords(body) {
+			w = strings.Trim(w, "'-")
+			n := len([]rune(w))
+			if n < opts.MinLength || (opts.MaxLength > 0 && n > opts.MaxLength) {
+				continue
+			}
+			words[w] = true
+			if opts.Lower {
+				words[strings.ToLower(w)] = true
+			}
+		}
+
+		if page.depth >= opts.Depth {
+			continue
+		}
+		for _, link := range pageLinks(page.u, body) {
+			if !seen[link.String()] {
+				seen[link.String()] = true
+				pending = append(pending, crawlPage{link, page.depth + 1})
+			}
+		}
+	}
+
+	res.InputLines = int64(len(words))
+
+	sorted := make([]string, 0, len(words))
+	for w := range words {
+		sorted = append(sorted, w)
+	}
+	sort.Strings(sorted)
+
+	out, err := ioutil.TempFile(filepath.Dir(dst), ".crawl-")
+	if err != nil {
+		return res, err
+	}
+	defer os.Remove(out.Name())
+
+	_, err = io.WriteString(out, strings.Join(sorted, "\n")+"\n")
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	res.OutputLines = int64(len(sorted))
+	res.Output = dst
+
+	if opts.Publish != nil {
+		res.Shared, err = opts.Publish(dst)
+	}
+
+	return res, err
+}
+
+// Get the text of a page, only HTML and plain text pages are read
+func fetchPage(client *http.Client, u *url.URL) (string, bool) {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("User-Agent", "cracklord")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+	if ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); ct != "" && ct != "text/html" && ct != "text/plain" && ct != "application/xhtml+xml" {
+		return "", false
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return "", false
+	}
+
+	return string(body), true
+}
+
+// Split the visible text and meta content of a page into words
+func pageWords(body string) []string {
+	var text []string
+	for _, m := range regMetaText.FindAllStringSubmatch(body, -1) {
+		text = append(text, m[1])
+	}
+	text = append(text, regHTMLTag.ReplaceAllString(regHiddenHTML.ReplaceAllString(body, " "), " "))
+
+	return strings.FieldsFunc(html.UnescapeString(strings.Join(text, " ")), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '-'
+	})
+}
+
+// The links of a page to other pages on the same host
+func pageLinks(base *url.URL, body string) []*url.URL {
+	var links []*url.URL
+	for _, m := range regHref.FindAllStringSubmatch(body, -1) {
+		u, err := base.Parse(html.UnescapeString(m[1]))
+		if err != nil || u.Host != base.Host || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		links = append(links, u)
+	}
+	return links
+}
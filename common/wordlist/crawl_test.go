@@ -0,0 +1,90 @@
+package wordlist
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCrawl(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><meta name="description" content="Rocket Sleds"><style>.hidden { color: red }</style></head>
+<body><h1>Acme&nbsp;Corporation</h1><p>Quality anvils since 1949 -- don't settle.</p>
+<script>var secret = "javascript";</script>
+<a href="/about#team">About</a> <a href="https://elsewhere.example/">Elsewhere</a> <a href="/logo.png">Logo</a></body></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<p>Founded by Wile Coyote</p><a href="/deeper">Deeper</a>`)
+	})
+	mux.HandleFunc("/deeper", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<p>Unreachable</p>`)
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "PNGDATA")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "crawl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dst := filepath.Join(dir, "acme.txt")
+	var published string
+	res, err := Crawl(CrawlOptions{
+		URLs:    []string{srv.URL + "/"},
+		Depth:   1,
+		Lower:   true,
+		Publish: func(p string) (string, error) { published = p; return "s3:acme.txt", nil },
+	}, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Pages != 2 {
+		t.Errorf("Expected 2 pages within the depth, got %d", res.Pages)
+	}
+	if published != dst || res.Shared != "s3:acme.txt" {
+		t.Errorf("Expected the wordlist to be published, got %q", res.Shared)
+	}
+
+	out, _ := ioutil.ReadFile(dst)
+	got := map[string]bool{}
+	for _, w := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		got[w] = true
+	}
+
+	for _, want := range []string{"Acme", "acme", "Corporation", "Rocket", "Sleds", "anvils", "1949", "don't", "Coyote", "About"} {
+		if !got[want] {
+			t.Errorf("Expected %q in the wordlist", want)
+		}
+	}
+	for _, unwanted := range []string{"javascript", "hidden", "Unreachable", "PNGDATA", "p", "--"} {
+		if got[unwanted] {
+			t.Errorf("Expected %q to not be in the wordlist", unwanted)
+		}
+	}
+}
+
+func TestCrawlValidate(t *testing.T) {
+	if err := (CrawlOptions{}).Validate(); err == nil {
+		t.Error("Expected an error without URLs")
+	}
+	if err := (CrawlOptions{URLs: []string{"file:///etc/passwd"}}).Validate(); err == nil {
+		t.Error("Expected an error for a file URL")
+	}
+	if err := (CrawlOptions{URLs: []string{"https://example.com"}, MaxPages: MaxCrawlPages + 1}).Validate(); err == nil {
+		t.Error("Expected an error for too many pages")
+	}
+}
@@ -0,0 +1,153 @@
+package wordlist
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The most candidates a list can be generated with, as they are held in
+// memory to be sorted
+const MaxCandidates = 10000000
+
+// Returned when the terms would make more candidates than allowed
+var ErrTooManyCandidates = errors.New("The terms make too many candidates, use fewer terms or mutations.")
+
+// The seasons added to the terms when asked for, with Fall as well as Autumn
+var Seasons = []string{"Spring", "Summer", "Autumn", "Fall", "Winter"}
+
+// Suffixes added to candidates when none are given
+var DefaultAppends = []string{"", "!", "1", "12", "123", "1234", "@", "#", "!!"}
+
+// Substitutions made for leetspeak variants of a word
+var leet = strings.NewReplacer("a", "@", "A", "@", "e", "3", "E", "3", "i", "1", "I", "1", "o", "0", "O", "0", "s", "$", "S", "$")
+
+// Terms a targeted list of candidates is generated from, such as the names of
+// a client and the words its staff are likely to use in passwords
+type Terms struct {
+	Companies []string // Names of the company and its brands, joined with the keywords and seasons
+	Keywords  []string // Products, locations, sports teams and other words
+	Seasons   bool     // Add the seasons to the keywords
+	Years     []int    // Years added after words in full and as two digits
+	Leet      bool     // Add leetspeak variants of each word
+	Appends   []string // Suffixes added last to candidates, DefaultAppends when nil
+	Prepends  []string // Prefixes added to candidates in addition to the bare word
+	Max       int      // Candidates allowed, MaxCandidates when 0
+}
+
+// The case variants of a word, lower, capitalized, upper and as it was given
+func caseVariants(w string) []string {
+	lower := strings.ToLower(w)
+	runes := []rune(lower)
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return []string{lower, string(runes), strings.ToUpper(w), w}
+}
+
+func cleanTerms(terms []string) []string {
+	var out []string
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" && !strings.ContainsAny(t, "\r\n") {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Generate the candidates of a set of terms and write them sorted with one per
+// line. Each word is a company, keyword or season, or a company followed by a
+// keyword or season, in each case variant and optionally in leetspeak. Every
+// word is written alone and followed by each year, then each prefix and suffix
+// is added to those. The number of candidates written is returned.
+func Generate(t Terms, w io.Writer) (int64, error) {
+	max := t.Max
+	if max <= 0 || max > MaxCandidates {
+		max = MaxCandidates
+	}
+
+	companies := cleanTerms(t.Companies)
+	keywords := cleanTerms(t.Keywords)
+	if t.Seasons {
+		keywords = append(keywords, Seasons...)
+	}
+	if len(companies) == 0 && len(keywords) == 0 {
+		return 0, errors.New("At least one company name or keyword is needed.")
+	}
+
+	appends := t.Appends
+	if appends == nil {
+		appends = DefaultAppends
+	}
+	prepends := append([]string{""}, t.Prepends...)
+
+	years := []string{""}
+	for _, y := range t.Years {
+		if y < 1000 || y > 9999 {
+			return 0, errors.New("Years must have four digits.")
+		}
+		full := strconv.Itoa(y)
+		years = append(years, full, full[2:])
+	}
+
+	// Words before the mutations are added
+	words := map[string]bool{}
+	addWord := func(base string) {
+		for _, v := range caseVariants(base) {
+			words[v] = true
+			if t.Leet {
+				words[leet.Replace(v)] = true
+			}
+		}
+	}
+	for _, c := range companies {
+		addWord(c)
+		for _, k := range keywords {
+			addWord(c + k)
+		}
+	}
+	for _, k := range keywords {
+		addWord(k)
+	}
+
+	if len(words)*len(years)*len(prepends)*len(appends) > 4*max {
+		// Not even worth building, most of them would be unique
+		return 0, ErrTooManyCandidates
+	}
+
+	candidates := map[string]bool{}
+	for word := range words {
+		for _, y := range years {
+			for _, p := range prepends {
+				for _, a := range appends {
+					candidates[p+word+y+a] = true
+				}
+			}
+		}
+		if len(candidates) > max {
+			return 0, ErrTooManyCandidates
+		}
+	}
+
+	sorted := make([]string, 0, len(candidates))
+	for c := range candidates {
+		sorted = append(sorted, c)
+	}
+	sort.Strings(sorted)
+
+	out := bufio.NewWriter(w)
+	for _, c := range sorted {
+		if _, err := out.WriteString(c + "\n"); err != nil {
+			return 0, err
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(sorted)), nil
+}
@@ -0,0 +1,61 @@
+package wordlist
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	var out bytes.Buffer
+	n, err := Generate(Terms{
+		Companies: []string{"Acme"},
+		Keywords:  []string{" rockets ", ""},
+		Seasons:   true,
+		Years:     []int{2024},
+		Leet:      true,
+		Appends:   []string{"", "!"},
+		Prepends:  []string{"#"},
+	}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if int64(len(lines)) != n {
+		t.Errorf("Expected %d lines, got %d", n, len(lines))
+	}
+
+	got := map[string]bool{}
+	for i, l := range lines {
+		if i > 0 && lines[i-1] >= l {
+			t.Errorf("Expected sorted and unique output, got %q after %q", l, lines[i-1])
+		}
+		got[l] = true
+	}
+
+	for _, want := range []string{"acme", "Acme2024!", "ACME24", "AcmeSummer2024!", "#Winter24", "@cm3", "Rockets!", "$umm3r2024", "Acmerockets"} {
+		if !got[want] {
+			t.Errorf("Expected %q to be generated", want)
+		}
+	}
+	if got[""] || got["2024"] {
+		t.Error("Expected no candidates without a word")
+	}
+}
+
+func TestGenerateLimits(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := Generate(Terms{}, &out); err == nil {
+		t.Error("Expected an error without terms")
+	}
+	if _, err := Generate(Terms{Keywords: []string{"acme"}, Years: []int{24}}, &out); err == nil {
+		t.Error("Expected an error for a two digit year")
+	}
+	if _, err := Generate(Terms{Keywords: []string{"acme", "rockets"}, Max: 10}, &out); err != ErrTooManyCandidates {
+		t.Errorf("Expected too many candidates, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Error("Expected nothing to be written when generation fails")
+	}
+}
@@ -0,0 +1,155 @@
+package wordlist
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/pborman/uuid"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of wordlist tasks
+const (
+	TASK_PROCESS = "process"
+	TASK_CRAWL   = "crawl"
+)
+
+// A single wordlist processing request
+type Task struct {
+	ID          string
+	Kind        string
+	Source      string
+	Destination string
+	Options     Options
+	Crawl       CrawlOptions
+	Status      string
+	Error       string
+	Result      Result
+	StartTime   time.Time
+	EndTime     time.Time
+}
+
+// Processor runs wordlist processing tasks in the background and keeps track
+// of their status
+type Processor struct {
+	tasks map[string]*Task
+	sync.RWMutex
+}
+
+func NewProcessor() Processor {
+	return Processor{
+		tasks: map[string]*Task{},
+	}
+}
+
+// Start processing a wordlist and return the ID used to check on it
+func (p *Processor) Start(src, dst string, opts Options) string {
+	t := &Task{
+		ID:          uuid.New(),
+		Kind:        TASK_PROCESS,
+		Source:      src,
+		Destination: dst,
+		Options:     opts,
+		Status:      common.STATUS_RUNNING,
+		StartTime:   time.Now(),
+	}
+
+	p.Lock()
+	p.tasks[t.ID] = t
+	p.Unlock()
+
+	go p.run(t)
+
+	return t.ID
+}
+
+// Start crawling sites for a wordlist written to dst and return the ID used
+// to check on it
+func (p *Processor) StartCrawl(opts CrawlOptions, dst string) string {
+	t := &Task{
+		ID:          uuid.New(),
+		Kind:        TASK_CRAWL,
+		Source:      strings.Join(opts.URLs, " "),
+		Destination: dst,
+		Crawl:       opts,
+		Status:      common.STATUS_RUNNING,
+		StartTime:   time.Now(),
+	}
+
+	p.Lock()
+	p.tasks[t.ID] = t
+	p.Unlock()
+
+	go p.run(t)
+
+	return t.ID
+}
+
+func (p *Processor) run(t *Task) {
+	logger := log.WithFields(log.Fields{
+		"id":          t.ID,
+		"kind":        t.Kind,
+		"source":      t.Source,
+		"destination": t.Destination,
+	})
+	logger.Info("Wordlist processing started.")
+
+	var res Result
+	var err error
+	if t.Kind == TASK_CRAWL {
+		res, err = Crawl(t.Crawl, t.Destination)
+	} else {
+		res, err = Process(t.Source, t.Destination, t.Options)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	t.Result = res
+	t.EndTime = time.Now()
+	if err != nil {
+		t.Status = common.STATUS_FAILED
+		t.Error = err.Error()
+		logger.WithField("error", err.Error()).Error("Wordlist processing failed.")
+		return
+	}
+
+	t.Status = common.STATUS_DONE
+	logger.WithFields(log.Fields{
+		"inputlines":  res.InputLines,
+		"outputlines": res.OutputLines,
+		"pages":       res.Pages,
+	}).Info("Wordlist processing complete.")
+}
+
+// Get a copy of a task by ID
+func (p *Processor) Get(id string) (Task, bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	t, ok := p.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+
+	return *t, true
+}
+
+// Get a copy of all tasks ordered by start time
+func (p *Processor) All() []Task {
+	p.RLock()
+	defer p.RUnlock()
+
+	tasks := make([]Task, 0, len(p.tasks))
+	for _, t := range p.tasks {
+		tasks = append(tasks, *t)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].StartTime.Before(tasks[j].StartTime)
+	})
+
+	return tasks
+}
@@ -0,0 +1,277 @@
+package wordlist
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The default number of lines sorted in memory before being written to disk
+// as a temporary chunk during processing
+const DefaultChunkLines = 1000000
+
+// Options for processing a wordlist
+type Options struct {
+	Compress   bool   // Write the output using gzip, adding .gz to the path
+	HcstatBin  string // Path to hcstat2gen, if set a .hcstat2 file is generated
+	ChunkLines int    // Lines sorted in memory at once, 0 uses DefaultChunkLines
+	TempDir    string // Directory for temporary chunks, defaults to the output directory
+}
+
+// The result of processing a wordlist
+type Result struct {
+	InputLines  int64  `json:"inputlines"`
+	OutputLines int64  `json:"outputlines"`
+	Output      string `json:"output"`
+	Hcstat      string `json:"hcstat"`
+	Pages       int    `json:"pages"`  // Pages fetched by a crawl
+	Shared      string `json:"shared"` // Where a crawl published its wordlist
+}
+
+// Process reads the wordlist at src and writes a sorted copy with duplicate
+// lines removed to dst. Large lists are sorted in chunks on disk and merged so
+// memory use stays bounded regardless of the size of the input.
+func Process(src, dst string, opts Options) (Result, error) {
+	var res Result
+
+	if opts.ChunkLines <= 0 {
+		opts.ChunkLines = DefaultChunkLines
+	}
+	if opts.TempDir == "" {
+		opts.TempDir = filepath.Dir(dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return res, err
+	}
+	defer in.Close()
+
+	// Sort the input into temporary chunks
+	chunks, lines, err := writeChunks(in, opts)
+	defer func() {
+		for _, c := range chunks {
+			os.Remove(c)
+		}
+	}()
+	if err != nil {
+		return res, err
+	}
+	res.InputLines = lines
+
+	// Merge the chunks into a single deduplicated output
+	plain := dst
+	if strings.HasSuffix(plain, ".gz") {
+		plain = strings.TrimSuffix(plain, ".gz")
+		opts.Compress = true
+	}
+
+	out, err := os.Create(plain)
+	if err != nil {
+		return res, err
+	}
+
+	res.OutputLines, err = mergeChunks(chunks, out)
+	out.Close()
+	if err != nil {
+		os.Remove(plain)
+		return res, err
+	}
+	res.Output = plain
+
+	// The statistics file needs the plain list so generate it before compressing
+	if opts.HcstatBin != "" {
+		res.Hcstat = strings.TrimSuffix(plain, filepath.Ext(plain)) + ".hcstat2"
+		err = generateHcstat(opts.HcstatBin, plain, res.Hcstat)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	if opts.Compress {
+		res.Output = plain + ".gz"
+		err = compressFile(plain, res.Output)
+		if err != nil {
+			return res, err
+		}
+		os.Remove(plain)
+	}
+
+	return res, nil
+}
+
+// Read the input and write sorted, deduplicated chunks to temporary files
+func writeChunks(in io.Reader, opts Options) ([]string, int64, error) {
+	var chunks []string
+	var total int64
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lines := make([]string, 0, opts.ChunkLines)
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+
+		f, err := ioutil.TempFile(opts.TempDir, "wordlist-chunk-")
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, f.Name())
+
+		sort.Strings(lines)
+		w := bufio.NewWriter(f)
+		var last string
+		for i, l := range lines {
+			if i > 0 && l == last {
+				continue
+			}
+			w.WriteString(l)
+			w.WriteByte('\n')
+			last = l
+		}
+
+		err = w.Flush()
+		f.Close()
+		lines = lines[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		total++
+		lines = append(lines, line)
+		if len(lines) >= opts.ChunkLines {
+			err := flush()
+			if err != nil {
+				return chunks, total, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return chunks, total, err
+	}
+
+	return chunks, total, flush()
+}
+
+// Entry used when merging the sorted chunks
+type mergeItem struct {
+	line    string
+	scanner *bufio.Scanner
+}
+
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].line < h[j].line }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// Merge the sorted chunks, dropping duplicates that span chunks
+func mergeChunks(chunks []string, out io.Writer) (int64, error) {
+	var count int64
+	h := &mergeHeap{}
+
+	for _, c := range chunks {
+		f, err := os.Open(c)
+		if err != nil {
+			return count, err
+		}
+		defer f.Close()
+
+		s := bufio.NewScanner(f)
+		s.Buffer(make([]byte, 64*1024), 1024*1024)
+		if s.Scan() {
+			heap.Push(h, mergeItem{line: s.Text(), scanner: s})
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	var last string
+	first := true
+
+	for h.Len() > 0 {
+		item := heap.Pop(h).(mergeItem)
+
+		if first || item.line != last {
+			w.WriteString(item.line)
+			w.WriteByte('\n')
+			last = item.line
+			first = false
+			count++
+		}
+
+		if item.scanner.Scan() {
+			item.line = item.scanner.Text()
+			heap.Push(h, item)
+		} else if err := item.scanner.Err(); err != nil {
+			return count, err
+		}
+	}
+
+	return count, w.Flush()
+}
+
+// Compress a file with gzip
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// Generate markov statistics for hashcat using the hcstat2gen utility, which
+// reads the wordlist from stdin
+func generateHcstat(bin, wordlist, dst string) error {
+	in, err := os.Open(wordlist)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	cmd := exec.Command(bin, dst)
+	cmd.Stdin = in
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New("Unable to generate hcstat2 file: " + strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package wordlist
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessDedupeAcrossChunks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wordlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "in.txt")
+	ioutil.WriteFile(src, []byte("password\nletmein\r\n\nadmin\npassword\nadmin\nzebra\nletmein\n"), 0600)
+
+	// Use tiny chunks so duplicates are split between them and must be merged
+	dst := filepath.Join(dir, "out.txt")
+	res, err := Process(src, dst, Options{ChunkLines: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.InputLines != 7 {
+		t.Errorf("Expected 7 input lines but got %d", res.InputLines)
+	}
+	if res.OutputLines != 4 {
+		t.Errorf("Expected 4 output lines but got %d", res.OutputLines)
+	}
+
+	out, _ := ioutil.ReadFile(dst)
+	if string(out) != "admin\nletmein\npassword\nzebra\n" {
+		t.Errorf("Unexpected output: %q", string(out))
+	}
+
+	// Temporary chunks should all be cleaned up
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Errorf("Expected only the input and output files but found %d files", len(files))
+	}
+}
+
+func TestProcessCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wordlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "in.txt")
+	ioutil.WriteFile(src, []byte("b\na\nb\n"), 0600)
+
+	res, err := Process(src, filepath.Join(dir, "out.txt"), Options{Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Output != filepath.Join(dir, "out.txt.gz") {
+		t.Errorf("Unexpected output path %s", res.Output)
+	}
+
+	f, err := os.Open(res.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ioutil.ReadAll(gz)
+	if string(out) != "a\nb\n" {
+		t.Errorf("Unexpected output: %q", string(out))
+	}
+}
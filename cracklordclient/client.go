@@ -0,0 +1,234 @@
+// Package cracklordclient is a client for the REST API of a cracklord queue
+// server. Requests and responses use the types of common/api so the client
+// stays in sync with the server.
+package cracklordclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common/api"
+)
+
+// Cookie the server sets the session token in when it does not return it
+const sessionCookie = "cracklord_session"
+
+// Defaults of new clients
+const (
+	DefaultRetries   = 3
+	DefaultRetryWait = 500 * time.Millisecond
+)
+
+// An error response of the API
+type Error struct {
+	StatusCode int    // HTTP status of the response
+	Message    string // Message localized by the server
+	MessageKey string // Stable key of the message, such as job.notfound
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("cracklord: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("cracklord: %d %s", e.StatusCode, e.Message)
+}
+
+// Check if an error is an API error with a status, such as http.StatusNotFound
+func IsStatus(err error, status int) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == status
+}
+
+// A client of one queue server. The fields may be changed before the client
+// is first used.
+type Client struct {
+	URL        string // Base URL of the queue server, such as https://queue:443
+	Token      string // Session token, set by Login
+	HTTPClient *http.Client
+
+	// Requests that can be sent again safely are retried this many times when
+	// the server can not be reached or is unavailable, waiting longer each time
+	Retries   int
+	RetryWait time.Duration
+}
+
+// Create a client of the queue server at a base URL
+func New(baseURL string) *Client {
+	return &Client{
+		URL:        strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute},
+		Retries:    DefaultRetries,
+		RetryWait:  DefaultRetryWait,
+	}
+}
+
+// Check if a request may be sent again without doing anything twice
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// Check if a failed attempt should be tried again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Send a request and return the response when it was successful. The body is
+// encoded as JSON unless it is an io.Reader, which is sent as is and never
+// retried as it can only be read once.
+func (c *Client) send(ctx context.Context, method, path string, body interface{}, header http.Header) (*http.Response, error) {
+	var data []byte
+	var stream io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		stream = b
+	default:
+		var err error
+		if data, err = json.Marshal(b); err != nil {
+			return nil, err
+		}
+	}
+
+	retries := c.Retries
+	if stream != nil || !idempotent(method) {
+		retries = 0
+	}
+
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		reqBody := stream
+		if data != nil {
+			reqBody = bytes.NewReader(data)
+		}
+
+		req, err := http.NewRequest(method, c.URL+path, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if data != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.Token != "" {
+			req.Header.Set("AuthorizationToken", c.Token)
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if attempt >= retries || !retryable(resp, err) {
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode >= 300 {
+				defer resp.Body.Close()
+				return nil, decodeError(resp)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Send a request and decode the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func decodeError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+
+	var body api.ErrorResp
+	if json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body) == nil {
+		e.Message = body.Message
+		e.MessageKey = body.MessageKey
+	}
+
+	return e
+}
+
+// Escape a value used as part of a path
+func pathArg(v string) string {
+	return url.PathEscape(v)
+}
+
+// Log in and keep the session token for the requests that follow
+func (c *Client) Login(ctx context.Context, username, password string) (*api.LoginResp, error) {
+	resp, err := c.send(ctx, "POST", "/api/login", api.LoginReq{Username: username, Password: password}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var login api.LoginResp
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return nil, err
+	}
+
+	c.Token = login.Token
+	if c.Token == "" {
+		// Servers that deliver sessions as cookies leave the token out
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == sessionCookie {
+				c.Token = cookie.Value
+			}
+		}
+	}
+
+	return &login, nil
+}
+
+// End the session of the client
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.do(ctx, "GET", "/api/logout", nil, nil)
+	c.Token = ""
+	return err
+}
+
+// Get the user the client is logged in as
+func (c *Client) Me(ctx context.Context) (api.APIUser, error) {
+	var resp api.UserMeResp
+	err := c.do(ctx, "GET", "/api/users/me", nil, &resp)
+	return resp.User, err
+}
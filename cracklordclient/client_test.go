@@ -0,0 +1,125 @@
+package cracklordclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmmcatee/cracklord/common/api"
+)
+
+func testClient(h http.HandlerFunc) (*Client, *httptest.Server) {
+	srv := httptest.NewServer(h)
+	c := New(srv.URL)
+	c.RetryWait = 0
+	return c, srv
+}
+
+func TestLoginToken(t *testing.T) {
+	c, srv := testClient(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/login":
+			var req api.LoginReq
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.Username != "alice" {
+				rw.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(rw).Encode(api.ErrorResp{Status: 401, Message: "Bad login", MessageKey: "login.failed"})
+				return
+			}
+			json.NewEncoder(rw).Encode(api.LoginResp{Status: 200, Token: "secret", Role: "Administrator"})
+		case "/api/users/me":
+			json.NewEncoder(rw).Encode(api.UserMeResp{User: api.APIUser{Username: r.Header.Get("AuthorizationToken")}})
+		}
+	})
+	defer srv.Close()
+
+	_, err := c.Login(context.Background(), "mallory", "x")
+	if !IsStatus(err, http.StatusUnauthorized) || err.(*Error).MessageKey != "login.failed" {
+		t.Fatalf("Expected a 401 API error, got %v", err)
+	}
+
+	if _, err := c.Login(context.Background(), "alice", "x"); err != nil {
+		t.Fatal(err)
+	}
+	me, err := c.Me(context.Background())
+	if err != nil || me.Username != "secret" {
+		t.Errorf("Expected the token to be sent, got %q %v", me.Username, err)
+	}
+}
+
+func TestLoginCookie(t *testing.T) {
+	c, srv := testClient(func(rw http.ResponseWriter, r *http.Request) {
+		http.SetCookie(rw, &http.Cookie{Name: sessionCookie, Value: "fromcookie"})
+		json.NewEncoder(rw).Encode(api.LoginResp{Status: 200})
+	})
+	defer srv.Close()
+
+	if _, err := c.Login(context.Background(), "alice", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Token != "fromcookie" {
+		t.Errorf("Expected the token from the session cookie, got %q", c.Token)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var calls int32
+	c, srv := testClient(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(rw).Encode(api.GetJobsResp{Jobs: []api.APIJob{{ID: "1"}}})
+	})
+	defer srv.Close()
+
+	jobs, err := c.Jobs(context.Background())
+	if err != nil || len(jobs) != 1 || calls != 3 {
+		t.Fatalf("Expected the list to be retried until it worked, got %v %v after %d calls", jobs, err, calls)
+	}
+
+	// Creating a job is not safe to send twice
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.CreateJob(context.Background(), api.JobCreateReq{Name: "test"})
+	if !IsStatus(err, http.StatusServiceUnavailable) || calls != 1 {
+		t.Errorf("Expected one attempt to create a job, got %v after %d calls", err, calls)
+	}
+}
+
+func TestJobResultsResume(t *testing.T) {
+	file := strings.Repeat("hash:password\n", 1000)
+
+	var calls int32
+	c, srv := testClient(func(rw http.ResponseWriter, r *http.Request) {
+		var start int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start)
+		if start > 0 {
+			rw.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(file)-1, len(file)))
+			rw.WriteHeader(http.StatusPartialContent)
+		}
+
+		// The first download is cut off half way
+		if atomic.AddInt32(&calls, 1) == 1 {
+			rw.Header().Set("Content-Length", fmt.Sprint(len(file)))
+			rw.Write([]byte(file[:len(file)/2]))
+			panic(http.ErrAbortHandler)
+		}
+		rw.Write([]byte(file[start:]))
+	})
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	n, err := c.JobResults(context.Background(), "job", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(file)) || buf.String() != file || calls != 2 {
+		t.Errorf("Expected the download to be resumed, got %d bytes after %d calls", n, calls)
+	}
+}
@@ -0,0 +1,84 @@
+package cracklordclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common/api"
+)
+
+// Write the result file of a job from offset on, returning the bytes written
+func (c *Client) jobResultsFrom(ctx context.Context, id string, w io.Writer, offset int64) (int64, error) {
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.send(ctx, "GET", "/api/jobs/"+pathArg(id)+"/results", nil, header)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("cracklord: server did not resume the result file at %d", offset)
+	}
+
+	return io.Copy(w, resp.Body)
+}
+
+// Download the result file of a job from the resource running it into w.
+// When the download is cut off it is resumed where it stopped, up to the
+// retries of the client. The bytes written are returned.
+func (c *Client) JobResults(ctx context.Context, id string, w io.Writer) (int64, error) {
+	var written int64
+
+	wait := c.RetryWait
+	for attempt := 0; ; attempt++ {
+		n, err := c.jobResultsFrom(ctx, id, w, written)
+		written += n
+
+		if err == nil {
+			return written, nil
+		}
+		if written > 0 && IsStatus(err, http.StatusRequestedRangeNotSatisfiable) {
+			// The file ended exactly where the download was cut off
+			return written, nil
+		}
+		if _, ok := err.(*Error); ok || attempt >= c.Retries {
+			return written, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return written, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Upload a Markov model for the hashcat markov attack, replacing a model of
+// the same name
+func (c *Client) UploadMarkovModel(ctx context.Context, name string, model io.Reader) (api.APIMarkovModel, error) {
+	var resp api.MarkovModelUploadResp
+	err := c.do(ctx, "PUT", "/api/wordlists/models/"+pathArg(name), model, &resp)
+	return resp.Model, err
+}
+
+// List the Markov models on the queue
+func (c *Client) MarkovModels(ctx context.Context) ([]api.APIMarkovModel, error) {
+	var resp api.MarkovModelsResp
+	err := c.do(ctx, "GET", "/api/wordlists/models", nil, &resp)
+	return resp.Models, err
+}
+
+// List the wordlists generated on the queue
+func (c *Client) GeneratedWordlists(ctx context.Context) ([]api.APIGeneratedWordlist, error) {
+	var resp api.GeneratedWordlistsResp
+	err := c.do(ctx, "GET", "/api/wordlists/generated", nil, &resp)
+	return resp.Wordlists, err
+}
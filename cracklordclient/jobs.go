@@ -0,0 +1,111 @@
+package cracklordclient
+
+import (
+	"context"
+
+	"github.com/jmmcatee/cracklord/common/api"
+)
+
+// Statuses that can be given to UpdateJob
+const (
+	JobPause  = "pause"
+	JobResume = "created"
+	JobQuit   = "quit"
+)
+
+// List the jobs of the queue
+func (c *Client) Jobs(ctx context.Context) ([]api.APIJob, error) {
+	var resp api.GetJobsResp
+	err := c.do(ctx, "GET", "/api/jobs", nil, &resp)
+	return resp.Jobs, err
+}
+
+// Get a job with its parameters, output and history
+func (c *Client) Job(ctx context.Context, id string) (api.APIJobDetail, error) {
+	var resp api.JobReadResp
+	err := c.do(ctx, "GET", "/api/jobs/"+pathArg(id), nil, &resp)
+	return resp.Job, err
+}
+
+// Create a job. The response holds the ID of the job and any problems the
+// input checks found, which stop the job from being created when they are
+// errors unless it is forced.
+func (c *Client) CreateJob(ctx context.Context, job api.JobCreateReq) (*api.JobCreateResp, error) {
+	var resp api.JobCreateResp
+	if err := c.do(ctx, "POST", "/api/jobs", job, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Create several jobs at once, or one job from a template for each set of
+// hashes given
+func (c *Client) CreateJobs(ctx context.Context, batch api.JobBatchReq) ([]api.JobBatchResult, error) {
+	var resp api.JobBatchResp
+	err := c.do(ctx, "POST", "/api/jobs/batch", batch, &resp)
+	return resp.Results, err
+}
+
+// Change the status of a job, or the parameters of a draft job
+func (c *Client) UpdateJob(ctx context.Context, id string, update api.JobUpdateReq) (api.APIJob, error) {
+	var resp api.JobUpdateResp
+	err := c.do(ctx, "PUT", "/api/jobs/"+pathArg(id), update, &resp)
+	return resp.Job, err
+}
+
+// Change the status of a job to one of JobPause, JobResume or JobQuit
+func (c *Client) SetJobStatus(ctx context.Context, id, status string) (api.APIJob, error) {
+	var update api.JobUpdateReq
+	update.Status = status
+	return c.UpdateJob(ctx, id, update)
+}
+
+// Queue a draft job to run
+func (c *Client) StartJob(ctx context.Context, id string) (api.APIJob, error) {
+	var resp api.JobUpdateResp
+	err := c.do(ctx, "POST", "/api/jobs/"+pathArg(id)+"/start", nil, &resp)
+	return resp.Job, err
+}
+
+// Let a job held back for going over the budget of its project run on
+// resources with a cost
+func (c *Client) ApproveJobCost(ctx context.Context, id string) (api.APIJob, error) {
+	var resp api.JobUpdateResp
+	err := c.do(ctx, "POST", "/api/jobs/"+pathArg(id)+"/cost", nil, &resp)
+	return resp.Job, err
+}
+
+// List the budgets of projects and what each has spent
+func (c *Client) Budgets(ctx context.Context) ([]api.APIBudget, error) {
+	var resp api.BudgetsResp
+	err := c.do(ctx, "GET", "/api/budgets", nil, &resp)
+	return resp.Budgets, err
+}
+
+// Remove a job from the queue
+func (c *Client) DeleteJob(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/jobs/"+pathArg(id), nil, nil)
+}
+
+// Get all of the output of a job, including rows the queue keeps on disk
+func (c *Client) JobOutput(ctx context.Context, id string) (*api.JobOutputResp, error) {
+	var resp api.JobOutputResp
+	if err := c.do(ctx, "GET", "/api/jobs/"+pathArg(id)+"/output", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// List the tools jobs can be created with
+func (c *Client) Tools(ctx context.Context) ([]api.APITool, error) {
+	var resp api.ToolsResp
+	err := c.do(ctx, "GET", "/api/tools", nil, &resp)
+	return resp.Tools, err
+}
+
+// Get a tool with its form and the defaults of its parameters
+func (c *Client) Tool(ctx context.Context, id string) (api.APIToolDetail, error) {
+	var resp api.ToolsGetResp
+	err := c.do(ctx, "GET", "/api/tools/"+pathArg(id), nil, &resp)
+	return resp.Tool, err
+}
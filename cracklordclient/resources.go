@@ -0,0 +1,59 @@
+package cracklordclient
+
+import (
+	"context"
+
+	"github.com/jmmcatee/cracklord/common/api"
+)
+
+// List the resources connected to the queue
+func (c *Client) Resources(ctx context.Context) ([]api.APIResource, error) {
+	var resp api.ResListResp
+	err := c.do(ctx, "GET", "/api/resources", nil, &resp)
+	return resp.Resources, err
+}
+
+// Get a resource of a resource manager
+func (c *Client) Resource(ctx context.Context, manager, id string) (api.APIResource, error) {
+	var resp api.ResReadResp
+	err := c.do(ctx, "GET", "/api/resources/"+pathArg(manager)+"/"+pathArg(id), nil, &resp)
+	return resp.Resource, err
+}
+
+// Add a resource through a resource manager
+func (c *Client) CreateResource(ctx context.Context, res api.ResCreateReq) error {
+	return c.do(ctx, "POST", "/api/resources", res, nil)
+}
+
+// Change the status, tools or parameters of a resource
+func (c *Client) UpdateResource(ctx context.Context, id string, update api.ResUpdateReq) error {
+	return c.do(ctx, "PUT", "/api/resources/"+pathArg(id), update, nil)
+}
+
+// Remove a resource from its resource manager
+func (c *Client) DeleteResource(ctx context.Context, manager, id string) error {
+	return c.do(ctx, "DELETE", "/api/resources/"+pathArg(id), api.ResDeleteReq{ID: id, Manager: manager}, nil)
+}
+
+// List the resource managers resources can be added with
+func (c *Client) ResourceManagers(ctx context.Context) ([]api.APIResourceManager, error) {
+	var resp api.ResourceManagersResp
+	err := c.do(ctx, "GET", "/api/resourcemanagers", nil, &resp)
+	return resp.ResourceManagers, err
+}
+
+// Add or change the resource registered under a name so it matches the
+// registration. The registration can be sent again without changing anything,
+// and the state of the resource afterwards is returned.
+func (c *Client) RegisterResource(ctx context.Context, name string, reg api.ResRegisterReq) (api.APIResourceConfig, error) {
+	var resp api.ResConfigResp
+	err := c.do(ctx, "PUT", "/api/resources/named/"+pathArg(name), reg, &resp)
+	return resp.Resource, err
+}
+
+// Get the state of the resource registered under a name
+func (c *Client) ResourceConfig(ctx context.Context, name string) (api.APIResourceConfig, error) {
+	var resp api.ResConfigResp
+	err := c.do(ctx, "GET", "/api/resources/named/"+pathArg(name), nil, &resp)
+	return resp.Resource, err
+}
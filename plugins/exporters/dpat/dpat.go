@@ -0,0 +1,68 @@
+package dpatexporter
+
+import (
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/vaughan0/go-ini"
+	"os"
+	"sync"
+)
+
+// Appends cracked credentials to a hashcat style potfile that the Domain
+// Password Audit Tool reads along with the NTDS dump
+type dpatExporter struct {
+	potfile string
+	sync.Mutex
+}
+
+func Setup(confpath string) (queue.Exporter, error) {
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for DPAT exporter.")
+		return &dpatExporter{}, err
+	}
+
+	confGen := confFile.Section("General")
+	potfile := common.StripQuotes(confGen["PotFile"])
+	if potfile == "" {
+		return &dpatExporter{}, errors.New("PotFile was not found in the general configuration section of the DPAT exporter config")
+	}
+
+	return &dpatExporter{potfile: potfile}, nil
+}
+
+func (e *dpatExporter) SystemName() string {
+	return "dpat"
+}
+
+func (e *dpatExporter) Export(creds []queue.Credential) error {
+	e.Lock()
+	defer e.Unlock()
+
+	f, err := os.OpenFile(e.potfile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The same hash is only needed once, no matter how many users share it
+	written := map[string]bool{}
+	for _, c := range creds {
+		if written[c.Hash] {
+			continue
+		}
+		written[c.Hash] = true
+
+		_, err = f.WriteString(c.Hash + ":" + c.Plaintext + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package neo4jexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/vaughan0/go-ini"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Marks the users of cracked credentials as owned in a BloodHound database
+// through the Neo4j HTTP API
+type neo4jExporter struct {
+	url           string
+	username      string
+	password      string
+	storePassword bool
+	defaultDomain string
+	domains       map[string]string // NetBIOS domain names to the full domain name
+	client        *http.Client
+}
+
+func Setup(confpath string) (queue.Exporter, error) {
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for Neo4j exporter.")
+		return &neo4jExporter{}, err
+	}
+
+	confGen := confFile.Section("General")
+	e := &neo4jExporter{
+		url:           strings.TrimRight(common.StripQuotes(confGen["URL"]), "/"),
+		username:      common.StripQuotes(confGen["Username"]),
+		password:      common.StripQuotes(confGen["Password"]),
+		storePassword: common.StripQuotes(confGen["StorePassword"]) == "true",
+		defaultDomain: strings.ToUpper(common.StripQuotes(confGen["DefaultDomain"])),
+		domains:       map[string]string{},
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if e.url == "" {
+		return &neo4jExporter{}, errors.New("URL was not found in the general configuration section of the Neo4j exporter config")
+	}
+
+	database := common.StripQuotes(confGen["Database"])
+	if database == "" {
+		database = "neo4j"
+	}
+	e.url += "/db/" + database + "/tx/commit"
+
+	for k, v := range confFile.Section("Domains") {
+		e.domains[strings.ToUpper(k)] = strings.ToUpper(common.StripQuotes(v))
+	}
+
+	return e, nil
+}
+
+func (e *neo4jExporter) SystemName() string {
+	return "neo4j"
+}
+
+// BloodHound names users USER@DOMAIN.LOCAL, while dumps use DOMAIN\user
+func (e *neo4jExporter) nodeName(username string) string {
+	if username == "" {
+		return ""
+	}
+
+	domain := e.defaultDomain
+	user := username
+
+	if i := strings.LastIndex(username, "\\"); i >= 0 {
+		domain = strings.ToUpper(username[:i])
+		user = username[i+1:]
+
+		if full, ok := e.domains[domain]; ok {
+			domain = full
+		}
+	} else if strings.Contains(username, "@") {
+		return strings.ToUpper(username)
+	}
+
+	if domain == "" {
+		return ""
+	}
+
+	return strings.ToUpper(user) + "@" + domain
+}
+
+type statement struct {
+	Statement  string                 `json:"statement"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+type txResponse struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *neo4jExporter) Export(creds []queue.Credential) error {
+	var users []map[string]string
+	for _, c := range creds {
+		name := e.nodeName(c.Username)
+		if name == "" {
+			continue
+		}
+
+		u := map[string]string{"name": name}
+		if e.storePassword {
+			u["password"] = c.Plaintext
+		}
+		users = append(users, u)
+	}
+
+	// Without usernames there is nothing to mark in the graph
+	if len(users) == 0 {
+		return nil
+	}
+
+	cypher := "UNWIND $users AS c MATCH (u:User {name: c.name}) SET u.owned = true, u.cracked = true"
+	if e.storePassword {
+		cypher += ", u.password = c.password"
+	}
+
+	data, err := json.Marshal(map[string][]statement{
+		"statements": {{
+			Statement:  cypher,
+			Parameters: map[string]interface{}{"users": users},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Neo4j returned " + resp.Status)
+	}
+
+	var tx txResponse
+	err = json.NewDecoder(resp.Body).Decode(&tx)
+	if err != nil {
+		return err
+	}
+	if len(tx.Errors) > 0 {
+		return errors.New("Neo4j error " + tx.Errors[0].Code + ": " + tx.Errors[0].Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package restexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/vaughan0/go-ini"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The credential fields that can be mapped to names in the request body
+var defaultFields = map[string]string{
+	"job":       "job",
+	"jobname":   "jobname",
+	"owner":     "owner",
+	"username":  "username",
+	"hash":      "hash",
+	"plaintext": "plaintext",
+	"cracked":   "cracked",
+}
+
+// Sends cracked credentials as a JSON array of objects to an HTTP endpoint
+type restExporter struct {
+	url     string
+	method  string
+	headers map[string]string
+	fields  map[string]string // Credential field to the name used in the body
+	client  *http.Client
+}
+
+func Setup(confpath string) (queue.Exporter, error) {
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for REST exporter.")
+		return &restExporter{}, err
+	}
+
+	confGen := confFile.Section("General")
+	e := &restExporter{
+		url:     common.StripQuotes(confGen["URL"]),
+		method:  common.StripQuotes(confGen["Method"]),
+		headers: map[string]string{},
+		fields:  map[string]string{},
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if e.url == "" {
+		return &restExporter{}, errors.New("URL was not found in the general configuration section of the REST exporter config")
+	}
+	if e.method == "" {
+		e.method = "POST"
+	}
+
+	if t := common.StripQuotes(confGen["Timeout"]); t != "" {
+		secs, err := strconv.Atoi(t)
+		if err != nil {
+			return &restExporter{}, errors.New("Timeout of the REST exporter must be a number of seconds")
+		}
+		e.client.Timeout = time.Duration(secs) * time.Second
+	}
+
+	for k, v := range confFile.Section("Headers") {
+		e.headers[k] = common.StripQuotes(v)
+	}
+
+	// Only the mapped fields are sent, all of them when there is no mapping
+	for k, v := range confFile.Section("Fields") {
+		if _, ok := defaultFields[k]; !ok {
+			return &restExporter{}, errors.New("Unknown credential field " + k + " in the REST exporter config")
+		}
+		e.fields[k] = common.StripQuotes(v)
+	}
+	if len(e.fields) == 0 {
+		e.fields = defaultFields
+	}
+
+	return e, nil
+}
+
+func (e *restExporter) SystemName() string {
+	return "rest"
+}
+
+// Build the body objects using the field mapping
+func (e *restExporter) body(creds []queue.Credential) []map[string]string {
+	var out []map[string]string
+
+	for _, c := range creds {
+		values := map[string]string{
+			"job":       c.JobID,
+			"jobname":   c.JobName,
+			"owner":     c.Owner,
+			"username":  c.Username,
+			"hash":      c.Hash,
+			"plaintext": c.Plaintext,
+			"cracked":   c.Cracked.UTC().Format(time.RFC3339),
+		}
+
+		obj := map[string]string{}
+		for field, name := range e.fields {
+			obj[name] = values[field]
+		}
+		out = append(out, obj)
+	}
+
+	return out
+}
+
+func (e *restExporter) Export(creds []queue.Credential) error {
+	data, err := json.Marshal(e.body(creds))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(e.method, e.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("REST endpoint returned " + resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package restexporter
+
+import (
+	"encoding/json"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	var got []map[string]string
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	conf, err := ioutil.TempFile("", "rest.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(conf.Name())
+
+	conf.WriteString("[General]\nURL=" + srv.URL + "\n[Headers]\nAuthorization=Bearer secret\n[Fields]\nusername=account\nplaintext=password\n")
+	conf.Close()
+
+	e, err := Setup(conf.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e.Export([]queue.Credential{{
+		JobID:     "job",
+		Username:  "CORP\\alice",
+		Hash:      "8846f7eaee8fb117ad06bdd830b7586c",
+		Plaintext: "password",
+		Cracked:   time.Now(),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer secret" {
+		t.Errorf("Configured header was not sent, got %q", auth)
+	}
+	if len(got) != 1 || len(got[0]) != 2 || got[0]["account"] != "CORP\\alice" || got[0]["password"] != "password" {
+		t.Errorf("Fields were not mapped, got %v", got)
+	}
+}
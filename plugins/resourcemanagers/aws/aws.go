@@ -514,10 +514,10 @@ func (this *awsResourceManager) DeleteResource(resourceid string) error {
 }
 
 func (this awsResourceManager) GetResource(resourceid string) (*queue.Resource, map[string]string, error) {
-	resource, ok := this.q.GetResource(resourceid)
+	resource, err := this.q.GetResource(resourceid)
 	//If we weren't able to gather it, return an error
-	if !ok {
-		return &queue.Resource{}, nil, errors.New("Resource with requested ID not found in the queue.")
+	if err != nil {
+		return &queue.Resource{}, nil, err
 	}
 
 	localdata, ok := this.resources.Get(resourceid)
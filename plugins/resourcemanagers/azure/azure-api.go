@@ -0,0 +1,195 @@
+package azureresourcemanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	loginURL        = "https://login.microsoftonline.com/"
+	managementURL   = "https://management.azure.com"
+	managementScope = "https://management.azure.com/.default"
+
+	computeVersion = "2023-03-01"
+	networkVersion = "2023-04-01"
+)
+
+// Gets access tokens for Azure Resource Manager with the client credentials
+// of a service principal
+type tokenSource struct {
+	tenant string
+	id     string
+	secret string
+	login  string
+	http   *http.Client
+
+	mux    sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Get a token, requesting a new one shortly before the last one expires
+func (ts *tokenSource) get() (string, error) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	if ts.token != "" && time.Now().Add(time.Minute).Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", ts.id)
+	form.Set("client_secret", ts.secret)
+	form.Set("scope", managementScope)
+
+	resp, err := ts.http.PostForm(ts.login+url.PathEscape(ts.tenant)+"/oauth2/v2.0/token", form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("unable to get an Azure access token: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// The fields of a virtual machine that are read
+type azureVM struct {
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Properties struct {
+		HardwareProfile struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+		ProvisioningState string `json:"provisioningState"`
+		InstanceView      struct {
+			Statuses []struct {
+				Code string `json:"code"`
+			} `json:"statuses"`
+		} `json:"instanceView"`
+	} `json:"properties"`
+}
+
+// Error returned by Azure Resource Manager
+type azureError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureError) Error() string {
+	return fmt.Sprintf("Azure API returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// A minimal client for the resources in one resource group
+type azureClient struct {
+	base         string
+	subscription string
+	group        string
+	tokens       *tokenSource
+	http         *http.Client
+}
+
+func (c *azureClient) do(method, provider, resource, version string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+
+	path := c.base + "/subscriptions/" + url.PathEscape(c.subscription) +
+		"/resourceGroups/" + url.PathEscape(c.group) +
+		"/providers/" + provider + "/" + resource
+	req, err := http.NewRequest(method, path+"?api-version="+version, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := c.tokens.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+		if apiErr.Error.Message == "" {
+			apiErr.Error.Message = http.StatusText(resp.StatusCode)
+		}
+		return &azureError{StatusCode: resp.StatusCode, Code: apiErr.Error.Code, Message: apiErr.Error.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Create a virtual machine, the request is accepted before it is running
+func (c *azureClient) putVM(name string, vm map[string]interface{}) error {
+	return c.do("PUT", "Microsoft.Compute", "virtualMachines/"+url.PathEscape(name), computeVersion, vm, nil)
+}
+
+// Get a virtual machine with its power state
+func (c *azureClient) getVM(name string) (azureVM, error) {
+	var vm azureVM
+	err := c.do("GET", "Microsoft.Compute", "virtualMachines/"+url.PathEscape(name), computeVersion+"&$expand=instanceView", nil, &vm)
+	return vm, err
+}
+
+// Delete a virtual machine, with the network interface, address and disk
+// that were created with it
+func (c *azureClient) deleteVM(name string) error {
+	return c.do("DELETE", "Microsoft.Compute", "virtualMachines/"+url.PathEscape(name), computeVersion, nil, nil)
+}
+
+// Get the address of a public IP address resource, empty until allocated
+func (c *azureClient) publicIP(name string) (string, error) {
+	var ip struct {
+		Properties struct {
+			IPAddress string `json:"ipAddress"`
+		} `json:"properties"`
+	}
+	err := c.do("GET", "Microsoft.Network", "publicIPAddresses/"+url.PathEscape(name), networkVersion, nil, &ip)
+	return ip.Properties.IPAddress, err
+}
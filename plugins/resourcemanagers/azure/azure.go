@@ -0,0 +1,292 @@
+package azureresourcemanager
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/cloud"
+	"github.com/vaughan0/go-ini"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type azureProvider struct {
+	client        *azureClient
+	location      string
+	subnet        string
+	securityGroup string
+	image         map[string]interface{}
+	adminUser     string
+	sshKey        string
+	spot          bool // Launch spot virtual machines that can be evicted
+}
+
+// Setup the Azure resource manager from its configuration file
+func Setup(confpath string, qpointer *queue.Queue, tlspointer *tls.Config, caCertPath, caKeyPath string) (queue.ResourceManager, error) {
+	log.Debug("Setting up Azure resource manager")
+
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for Azure resource manager.")
+		return nil, err
+	}
+
+	confGen := confFile.Section("General")
+	if len(confGen) == 0 {
+		return nil, errors.New("No \"General\" configuration section.")
+	}
+
+	// These must all be set
+	required := map[string]string{}
+	for _, key := range []string{"TenantID", "ClientID", "ClientSecret", "SubscriptionID", "ResourceGroup", "Location", "Subnet", "Image", "SSHPublicKey"} {
+		required[key] = common.StripQuotes(confGen[key])
+		if required[key] == "" {
+			return nil, errors.New("The " + key + " was not defined in the general configuration section of the Azure resource manager config")
+		}
+	}
+
+	provider := &azureProvider{
+		location:      required["Location"],
+		subnet:        required["Subnet"],
+		securityGroup: common.StripQuotes(confGen["NetworkSecurityGroup"]),
+		adminUser:     common.StripQuotes(confGen["AdminUsername"]),
+		spot:          common.StripQuotes(confGen["Spot"]) == "true",
+	}
+	if provider.adminUser == "" {
+		provider.adminUser = "cracklord"
+	}
+
+	// The image is either a marketplace image as publisher:offer:sku:version
+	// or the resource ID of a custom image
+	if parts := strings.Split(required["Image"], ":"); len(parts) == 4 {
+		provider.image = map[string]interface{}{
+			"publisher": parts[0],
+			"offer":     parts[1],
+			"sku":       parts[2],
+			"version":   parts[3],
+		}
+	} else {
+		provider.image = map[string]interface{}{
+			"id": required["Image"],
+		}
+	}
+
+	key, err := ioutil.ReadFile(required["SSHPublicKey"])
+	if err != nil {
+		return nil, err
+	}
+	provider.sshKey = strings.TrimSpace(string(key))
+
+	conf, err := cloudresourcemanager.LoadConfig(confFile, caCertPath, caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+	tokens := &tokenSource{
+		tenant: required["TenantID"],
+		id:     required["ClientID"],
+		secret: required["ClientSecret"],
+		login:  loginURL,
+		http:   httpClient,
+	}
+	provider.client = &azureClient{
+		base:         managementURL,
+		subscription: required["SubscriptionID"],
+		group:        required["ResourceGroup"],
+		tokens:       tokens,
+		http:         httpClient,
+	}
+
+	// Make sure we can authenticate before the manager is offered to users
+	if _, err := tokens.get(); err != nil {
+		return nil, err
+	}
+
+	return cloudresourcemanager.Setup(provider, conf, qpointer, tlspointer), nil
+}
+
+func (this *azureProvider) SystemName() string {
+	return "azure"
+}
+
+func (this *azureProvider) DisplayName() string {
+	return "Microsoft Azure"
+}
+
+func (this *azureProvider) Description() string {
+	return "Spawn N-series GPU virtual machines inside Microsoft Azure for use. Instances can be automatically terminated if unused for a time."
+}
+
+// Name of the public IP address created with a virtual machine
+func publicIPName(vm string) string {
+	return vm + "-ip"
+}
+
+func (this *azureProvider) Launch(spec cloudresourcemanager.LaunchSpec) (cloudresourcemanager.Instance, error) {
+	ipConfig := map[string]interface{}{
+		"subnet": map[string]string{"id": this.subnet},
+		"publicIPAddressConfiguration": map[string]interface{}{
+			"name": publicIPName(spec.Name),
+			"sku":  map[string]string{"name": "Standard"},
+			"properties": map[string]interface{}{
+				"deleteOption":             "Delete",
+				"publicIPAllocationMethod": "Static",
+			},
+		},
+	}
+
+	nic := map[string]interface{}{
+		"primary":      true,
+		"deleteOption": "Delete",
+		"ipConfigurations": []interface{}{
+			map[string]interface{}{
+				"name":       "ipconfig1",
+				"properties": ipConfig,
+			},
+		},
+	}
+	if this.securityGroup != "" {
+		nic["networkSecurityGroup"] = map[string]string{"id": this.securityGroup}
+	}
+
+	// The network interface, address and disk are created with the virtual
+	// machine and deleted with it
+	vm := map[string]interface{}{
+		"location": this.location,
+		"tags": map[string]string{
+			"cracklord": "resource",
+		},
+		"properties": map[string]interface{}{
+			"hardwareProfile": map[string]string{
+				"vmSize": spec.InstanceType,
+			},
+			"storageProfile": map[string]interface{}{
+				"imageReference": this.image,
+				"osDisk": map[string]interface{}{
+					"createOption": "FromImage",
+					"deleteOption": "Delete",
+					"managedDisk": map[string]string{
+						"storageAccountType": "Premium_LRS",
+					},
+				},
+			},
+			"osProfile": map[string]interface{}{
+				"computerName":  spec.Name,
+				"adminUsername": this.adminUser,
+				"customData":    base64.StdEncoding.EncodeToString([]byte(spec.UserData)),
+				"linuxConfiguration": map[string]interface{}{
+					"disablePasswordAuthentication": true,
+					"ssh": map[string]interface{}{
+						"publicKeys": []interface{}{
+							map[string]string{
+								"path":    "/home/" + this.adminUser + "/.ssh/authorized_keys",
+								"keyData": this.sshKey,
+							},
+						},
+					},
+				},
+			},
+			"networkProfile": map[string]interface{}{
+				"networkApiVersion": "2020-11-01",
+				"networkInterfaceConfigurations": []interface{}{
+					map[string]interface{}{
+						"name":       spec.Name + "-nic",
+						"properties": nic,
+					},
+				},
+			},
+		},
+	}
+
+	// Evicted spot machines are deleted along with their disk and address,
+	// they are never capped on price so they are only evicted for capacity
+	if this.spot {
+		props := vm["properties"].(map[string]interface{})
+		props["priority"] = "Spot"
+		props["evictionPolicy"] = "Delete"
+		props["billingProfile"] = map[string]interface{}{"maxPrice": -1}
+	}
+
+	if err := this.client.putVM(spec.Name, vm); err != nil {
+		return cloudresourcemanager.Instance{}, err
+	}
+
+	return cloudresourcemanager.Instance{
+		ID:    spec.Name,
+		Name:  spec.Name,
+		Type:  spec.InstanceType,
+		Zone:  this.location,
+		State: cloudresourcemanager.STATE_PENDING,
+	}, nil
+}
+
+func (this *azureProvider) Instance(id string) (cloudresourcemanager.Instance, error) {
+	vm, err := this.client.getVM(id)
+	if aerr, ok := err.(*azureError); ok && aerr.StatusCode == http.StatusNotFound {
+		return cloudresourcemanager.Instance{ID: id, Name: id, State: cloudresourcemanager.STATE_TERMINATED}, nil
+	}
+	if err != nil {
+		return cloudresourcemanager.Instance{}, err
+	}
+
+	instance := cloudresourcemanager.Instance{
+		ID:    id,
+		Name:  vm.Name,
+		Type:  vm.Properties.HardwareProfile.VMSize,
+		Zone:  vm.Location,
+		State: vmState(vm),
+	}
+
+	if instance.State == cloudresourcemanager.STATE_RUNNING {
+		instance.Address, err = this.client.publicIP(publicIPName(id))
+		if err != nil {
+			return instance, err
+		}
+	}
+
+	return instance, nil
+}
+
+func (this *azureProvider) Terminate(id string) error {
+	err := this.client.deleteVM(id)
+	if aerr, ok := err.(*azureError); ok && aerr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Map the provisioning and power state of a virtual machine to the states of
+// the cloud manager
+func vmState(vm azureVM) string {
+	switch vm.Properties.ProvisioningState {
+	case "Deleting":
+		return cloudresourcemanager.STATE_STOPPING
+	case "Failed":
+		return cloudresourcemanager.STATE_TERMINATED
+	}
+
+	for _, status := range vm.Properties.InstanceView.Statuses {
+		switch status.Code {
+		case "PowerState/running":
+			return cloudresourcemanager.STATE_RUNNING
+		case "PowerState/starting":
+			return cloudresourcemanager.STATE_PENDING
+		case "PowerState/stopping", "PowerState/deallocating":
+			return cloudresourcemanager.STATE_STOPPING
+		case "PowerState/stopped", "PowerState/deallocated":
+			return cloudresourcemanager.STATE_TERMINATED
+		}
+	}
+
+	// No power state is reported while the machine is being created
+	return cloudresourcemanager.STATE_PENDING
+}
@@ -0,0 +1,176 @@
+package azureresourcemanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/cloud"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVMState(t *testing.T) {
+	tests := []struct {
+		vm   string
+		want string
+	}{
+		{`{"properties": {"provisioningState": "Creating"}}`, cloudresourcemanager.STATE_PENDING},
+		{`{"properties": {"instanceView": {"statuses": [{"code": "ProvisioningState/succeeded"}, {"code": "PowerState/running"}]}}}`, cloudresourcemanager.STATE_RUNNING},
+		{`{"properties": {"instanceView": {"statuses": [{"code": "PowerState/deallocating"}]}}}`, cloudresourcemanager.STATE_STOPPING},
+		{`{"properties": {"instanceView": {"statuses": [{"code": "PowerState/deallocated"}]}}}`, cloudresourcemanager.STATE_TERMINATED},
+		{`{"properties": {"provisioningState": "Deleting"}}`, cloudresourcemanager.STATE_STOPPING},
+		{`{"properties": {"provisioningState": "Failed"}}`, cloudresourcemanager.STATE_TERMINATED},
+	}
+
+	for _, test := range tests {
+		var vm azureVM
+		if err := json.Unmarshal([]byte(test.vm), &vm); err != nil {
+			t.Fatal(err)
+		}
+		if got := vmState(vm); got != test.want {
+			t.Errorf("Expected %s for %s, got %s", test.want, test.vm, got)
+		}
+	}
+}
+
+func TestProvider(t *testing.T) {
+	var tokens int
+	var created map[string]interface{}
+	var deleted []string
+
+	vms := "/subscriptions/sub/resourceGroups/cracklord/providers/Microsoft.Compute/virtualMachines/"
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+			tokens++
+
+			if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" || r.FormValue("scope") != managementScope {
+				t.Errorf("Unexpected token request %v", r.Form)
+			}
+
+			rw.Write([]byte(`{"access_token": "token", "expires_in": 3600}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "PUT " + vms + "cracklord-1":
+			if r.URL.Query().Get("api-version") != computeVersion {
+				t.Errorf("Expected compute API version %s, got %s", computeVersion, r.URL.RawQuery)
+			}
+			json.NewDecoder(r.Body).Decode(&created)
+			rw.WriteHeader(http.StatusCreated)
+			rw.Write([]byte(`{}`))
+		case "GET " + vms + "cracklord-1":
+			if r.URL.Query().Get("$expand") != "instanceView" {
+				t.Errorf("Expected the instance view to be requested, got %s", r.URL.RawQuery)
+			}
+			rw.Write([]byte(`{
+				"name": "cracklord-1",
+				"location": "eastus",
+				"properties": {
+					"hardwareProfile": {"vmSize": "Standard_NC6s_v3"},
+					"provisioningState": "Succeeded",
+					"instanceView": {"statuses": [{"code": "ProvisioningState/succeeded"}, {"code": "PowerState/running"}]}
+				}
+			}`))
+		case "GET /subscriptions/sub/resourceGroups/cracklord/providers/Microsoft.Network/publicIPAddresses/cracklord-1-ip":
+			rw.Write([]byte(`{"properties": {"ipAddress": "203.0.113.9"}}`))
+		case "DELETE " + vms + "cracklord-1":
+			deleted = append(deleted, "cracklord-1")
+			rw.WriteHeader(http.StatusAccepted)
+		case "PUT " + vms + "cracklord-3", "DELETE " + vms + "cracklord-3":
+			rw.WriteHeader(http.StatusConflict)
+			rw.Write([]byte(`{"error": {"code": "OperationNotAllowed", "message": "quota exceeded"}}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"error": {"code": "ResourceNotFound", "message": "not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := &azureProvider{
+		client: &azureClient{
+			base:         srv.URL,
+			subscription: "sub",
+			group:        "cracklord",
+			tokens: &tokenSource{
+				tenant: "tenant",
+				id:     "client",
+				secret: "secret",
+				login:  srv.URL + "/",
+				http:   srv.Client(),
+			},
+			http: srv.Client(),
+		},
+		location:  "eastus",
+		subnet:    "/subscriptions/sub/resourceGroups/net/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default",
+		image:     map[string]interface{}{"publisher": "Canonical", "offer": "ubuntu", "sku": "22_04-lts-gen2", "version": "latest"},
+		adminUser: "cracklord",
+		sshKey:    "ssh-ed25519 AAAA",
+		spot:      true,
+	}
+
+	instance, err := p.Launch(cloudresourcemanager.LaunchSpec{Name: "cracklord-1", InstanceType: "Standard_NC6s_v3", UserData: "#cloud-config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.ID != "cracklord-1" || instance.State != cloudresourcemanager.STATE_PENDING {
+		t.Errorf("Unexpected launched instance %+v", instance)
+	}
+
+	// The machine boots the cloud-config and is a spot machine deleted when
+	// evicted
+	props, _ := created["properties"].(map[string]interface{})
+	osProfile, _ := props["osProfile"].(map[string]interface{})
+	if osProfile["customData"] != base64.StdEncoding.EncodeToString([]byte("#cloud-config")) {
+		t.Errorf("Expected the cloud-config as custom data, got %v", osProfile["customData"])
+	}
+	if props["priority"] != "Spot" || props["evictionPolicy"] != "Delete" {
+		t.Errorf("Expected a spot machine that is deleted when evicted, got %v", props)
+	}
+	hardware, _ := props["hardwareProfile"].(map[string]interface{})
+	if created["location"] != "eastus" || hardware["vmSize"] != "Standard_NC6s_v3" {
+		t.Errorf("Unexpected machine %v", created)
+	}
+
+	instance, err = p.Instance("cracklord-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.State != cloudresourcemanager.STATE_RUNNING || instance.Address != "203.0.113.9" || instance.Type != "Standard_NC6s_v3" || instance.Zone != "eastus" {
+		t.Errorf("Unexpected instance %+v", instance)
+	}
+
+	if err := p.Terminate("cracklord-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("Expected the machine to be deleted, got %v", deleted)
+	}
+
+	// Machines that were deleted are terminated
+	instance, err = p.Instance("cracklord-2")
+	if err != nil || instance.State != cloudresourcemanager.STATE_TERMINATED {
+		t.Errorf("Expected a missing machine to be terminated, got %+v %v", instance, err)
+	}
+	if err := p.Terminate("cracklord-2"); err != nil {
+		t.Errorf("Expected terminating a missing machine to work, got %v", err)
+	}
+
+	// Other errors are passed on with the message of Azure
+	if _, err := p.Launch(cloudresourcemanager.LaunchSpec{Name: "cracklord-3", InstanceType: "Standard_NC6s_v3"}); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the launch to fail with the Azure error, got %v", err)
+	}
+	if err := p.Terminate("cracklord-3"); err == nil || !strings.Contains(err.Error(), "OperationNotAllowed") {
+		t.Errorf("Expected the termination to fail with the Azure error, got %v", err)
+	}
+
+	if tokens != 1 {
+		t.Errorf("Expected the access token to be reused, got %d tokens", tokens)
+	}
+}
@@ -0,0 +1,576 @@
+package cloudresourcemanager
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/emperorcow/protectedmap"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/pborman/uuid"
+	"github.com/vaughan0/go-ini"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The states of an instance, providers map their own states to these
+const (
+	STATE_PENDING    = "pending"    // Being created or booting
+	STATE_RUNNING    = "running"    // Running and has an address
+	STATE_STOPPING   = "stopping"   // Shutting down or being deleted
+	STATE_TERMINATED = "terminated" // Stopped, deleted or not found
+)
+
+// An instance in a cloud provider running a resource server
+type Instance struct {
+	ID      string // Used to find the instance again with the provider
+	Name    string // Name the instance was launched with
+	Type    string // Instance type of the provider
+	Zone    string // Zone or location the instance runs in
+	Address string // Public address the queue connects to, empty until known
+	State   string // One of the STATE_ constants
+}
+
+// What a provider needs to launch an instance
+type LaunchSpec struct {
+	Name         string // Name for the instance, also in its certificate
+	InstanceType string // ID of the instance type in the provider
+	UserData     string // cloud-config the instance is booted with
+}
+
+// A CloudResourceManager is a cloud provider instances are started in. The
+// resource manager the queue uses is built around it with Setup, which takes
+// care of certificates, connecting, and removing unused instances, so a
+// provider only has to manage the instances themselves.
+type CloudResourceManager interface {
+	// SystemName, DisplayName, and Description are used for the resource
+	// manager as described on queue.ResourceManager
+	SystemName() string
+	DisplayName() string
+	Description() string
+	// Launch starts an instance, returning as soon as the provider accepted it
+	Launch(spec LaunchSpec) (Instance, error)
+	// Instance gets the current state of an instance launched before. An
+	// instance that no longer exists is returned as terminated, not an error.
+	Instance(id string) (Instance, error)
+	// Terminate deletes an instance and anything launched with it
+	Terminate(id string) error
+}
+
+// Settings shared by the cloud resource managers, read from the General and
+// InstanceTypes sections of their configuration files
+type Config struct {
+	InstanceTypes      map[string]string // Display name to the ID of the provider
+	InstanceTypesOrder []string
+	ConnectionAttempts int
+	Port               string
+	CACert             *x509.Certificate
+	CAKey              *rsa.PrivateKey
+}
+
+// Read the shared settings from the configuration file of a provider
+func LoadConfig(confFile ini.File, caCertPath, caKeyPath string) (Config, error) {
+	conf := Config{
+		InstanceTypes:      map[string]string{},
+		ConnectionAttempts: 10,
+		Port:               "9443",
+	}
+
+	confGen := confFile.Section("General")
+	if tmpAttempts, ok := confGen["ConnectAttempts"]; ok {
+		attempts, err := strconv.Atoi(tmpAttempts)
+		if err != nil {
+			return conf, errors.New("Unable to parse ConnectAttempts field in cloud resource manager configuration file.")
+		}
+		conf.ConnectionAttempts = attempts
+	}
+	if port := common.StripQuotes(confGen["Port"]); port != "" {
+		conf.Port = port
+	}
+
+	confTypes := confFile.Section("InstanceTypes")
+	if len(confTypes) == 0 {
+		return conf, errors.New("No 'InstanceTypes' configuration section in cloud resource manager config.")
+	}
+	for key, value := range confTypes {
+		conf.InstanceTypes[common.StripQuotes(value)] = key
+		conf.InstanceTypesOrder = append(conf.InstanceTypesOrder, common.StripQuotes(value))
+	}
+	sort.Strings(conf.InstanceTypesOrder)
+
+	var err error
+	conf.CACert, conf.CAKey, err = common.GetCertandKey(caCertPath, caKeyPath)
+	if err != nil {
+		return conf, err
+	}
+
+	return conf, nil
+}
+
+type resourceInfo struct {
+	Instance       Instance
+	StartTime      time.Time
+	LastUseTime    time.Time
+	DisconnectTime time.Duration
+}
+
+type cloudResourceManager struct {
+	provider  CloudResourceManager
+	conf      Config
+	resources protectedmap.ProtectedMap
+	q         *queue.Queue
+	tls       *tls.Config
+}
+
+// Build the resource manager for a cloud provider
+func Setup(provider CloudResourceManager, conf Config, qpointer *queue.Queue, tlspointer *tls.Config) queue.ResourceManager {
+	return &cloudResourceManager{
+		provider:  provider,
+		conf:      conf,
+		resources: protectedmap.New(),
+		q:         qpointer,
+		tls:       tlspointer,
+	}
+}
+
+func (this *cloudResourceManager) SystemName() string {
+	return this.provider.SystemName()
+}
+
+func (this *cloudResourceManager) DisplayName() string {
+	return this.provider.DisplayName()
+}
+
+func (this *cloudResourceManager) Description() string {
+	return this.provider.Description()
+}
+
+func (this *cloudResourceManager) ParametersForm() string {
+	return `[
+	{
+		"type": "section",
+		"htmlClass": "row",
+		"items": [
+			{
+				"type": "section",
+				"htmlClass": "col-xs-6",
+				"items": [
+					{
+						"key": "disconnect",
+						"type": "radiobuttons",
+						"style": {
+							"selected": "btn-success",
+							"unselected": "btn-default"
+						},
+						"titleMap": [
+							{
+								"value": "true",
+								"name": "Yes"
+							},
+							{
+								"value": "false",
+								"name": "No"
+							}
+						]
+					}
+				]
+			},
+			{
+				"type": "section",
+				"htmlClass": "col-xs-6",
+				"items": [
+					{
+						"key": "disconnecttime",
+						"condition": "model.disconnect == 'true'"
+					}
+				]
+			}
+		]
+	},
+	"instancetype",
+	"number"
+]`
+}
+
+func (this *cloudResourceManager) ParametersSchema() string {
+	types := make([]string, len(this.conf.InstanceTypesOrder))
+	for i, v := range this.conf.InstanceTypesOrder {
+		types[i] = strconv.Quote(v)
+	}
+
+	return `{
+	"type": "object",
+	"title": ` + strconv.Quote(this.provider.DisplayName()) + `,
+	"properties": {
+		"disconnect": {
+			"title": "Terminate Host When Unused?",
+			"description": "Should these instances be automatically disconnected to reduce costs?",
+			"type": "string",
+			"default": "true"
+		},
+		"disconnecttime": {
+			"title": "Terminate Time",
+			"description": "How many minutes to wait until unused instance is terminated",
+			"type": "string",
+			"default": "15"
+		},
+		"instancetype": {
+			"title": "Instance Type",
+			"type": "string",
+			"enum": [` + strings.Join(types, ",") + `]
+		},
+		"number": {
+			"title": "Number of Instances",
+			"description": "How many instances should be started and connected to CrackLord?",
+			"default": "1",
+			"type": "string"
+		}
+	},
+	"required": [
+		"instancetype",
+		"disconnect",
+		"number"
+	]
+}`
+}
+
+// Build the cloud-config an instance boots with, installing the resource
+// server with the certificate it authenticates to the queue with
+func CloudConfig(ca, crt, key string) string {
+	return `#cloud-config
+# vim: syntax=yaml
+#
+package_update: true
+package_upgrade: true
+packages:
+ - cracklord-resourced
+write_files:
+-   content: |
+` + indent(ca) + `
+    path: /etc/cracklord/ssl/cracklord_ca.pem
+-   content: |
+` + indent(crt) + `
+    path: /etc/cracklord/ssl/resourced.crt
+-   content: |
+` + indent(key) + `
+    path: /etc/cracklord/ssl/resourced.key`
+}
+
+// Indent a file so it is a proper YAML block in the cloud-config
+func indent(src string) string {
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		lines[i] = "        " + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// This function does the following things to get resources added.
+// 1. Check our input from the form to make sure it's proper
+// 2. Launch each instance with its own certificate
+// 3. Create a goroutine for each that waits for the instance to be ready
+func (this *cloudResourceManager) AddResource(params map[string]string) error {
+	num, err := strconv.Atoi(params["number"])
+	if err != nil || num < 1 {
+		return errors.New("A number of instances was not specified.")
+	}
+
+	typeKey, ok := params["instancetype"]
+	if !ok {
+		return errors.New("Instance type was not specified")
+	}
+	instancetype, ok := this.conf.InstanceTypes[typeKey]
+	if !ok {
+		return errors.New("Instance type (" + typeKey + ") is unknown.")
+	}
+
+	disconTime := -1
+	if params["disconnect"] == "true" {
+		if tmptime, ok := params["disconnecttime"]; ok {
+			disconTime, _ = strconv.Atoi(tmptime)
+		}
+	}
+
+	caCertString, err := common.WriteCertificateToString(this.conf.CACert)
+	if err != nil {
+		return err
+	}
+
+	var launched int
+	for i := 0; i < num; i++ {
+		// Each instance gets a certificate for its own name, as its address
+		// is not known until it is running
+		name := "cracklord-" + strings.ToLower(strings.Replace(uuid.New(), "-", "", -1)[:12])
+
+		cert, key, err := common.GenerateResourceKeys(this.conf.CACert, this.conf.CAKey, name)
+		if err != nil {
+			return err
+		}
+		certString, err := common.WriteCertificateToString(cert)
+		if err != nil {
+			return err
+		}
+		keyString, err := common.WriteRSAPrivateKeyToString(key)
+		if err != nil {
+			return err
+		}
+
+		instance, err := this.provider.Launch(LaunchSpec{
+			Name:         name,
+			InstanceType: instancetype,
+			UserData:     CloudConfig(caCertString, certString, keyString),
+		})
+		if err != nil {
+			if launched == 0 {
+				return errors.New("Unable to start instance: " + err.Error())
+			}
+			return fmt.Errorf("Only %d of %d instances could be started: %s", launched, num, err.Error())
+		}
+		launched++
+
+		resUUID, err := this.q.AddResource(name)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"name":  name,
+				"error": err.Error(),
+			}).Error("Unable to add resource for cloud instance.")
+			this.provider.Terminate(instance.ID)
+			continue
+		}
+
+		// Add it to our local data so we know we have the data.
+		this.resources.Set(resUUID, resourceInfo{Instance: instance})
+
+		log.WithFields(log.Fields{
+			"resource": resUUID,
+			"manager":  this.SystemName(),
+		}).Info("Added resource to queue pool in pending state, awaiting the cloud provider to finalize instance.")
+		go this.waitForResourceReady(resUUID, disconTime, instance.ID)
+	}
+
+	return nil
+}
+
+// This function will be run in a goroutine and will check every 60 seconds to
+// see if the instance we just started is ready.
+func (this *cloudResourceManager) waitForResourceReady(resUUID string, disconnect int, id string) {
+	ticker := time.NewTicker(60 * time.Second)
+	defer ticker.Stop()
+
+	logger := log.WithFields(log.Fields{
+		"resource": resUUID,
+		"manager":  this.SystemName(),
+		"instance": id,
+	})
+
+	connectAttempts := 0
+	for range ticker.C {
+		instance, err := this.provider.Instance(id)
+		if err != nil {
+			logger.WithField("error", err.Error()).Warn("Unable to gather the state of the instance, trying again in 60 seconds.")
+			continue
+		}
+
+		switch instance.State {
+		case STATE_PENDING:
+			continue
+		case STATE_STOPPING, STATE_TERMINATED:
+			logger.Error("Instance stopped before it could be connected to.")
+			this.q.RemoveResource(resUUID)
+			this.resources.Delete(resUUID)
+			return
+		}
+		if instance.Address == "" {
+			continue
+		}
+
+		// The certificate of the instance is for its name, not its address
+		tlsconfig := this.tls.Clone()
+		tlsconfig.ServerName = instance.Name
+
+		err = this.q.ConnectResource(resUUID, net.JoinHostPort(instance.Address, this.conf.Port), tlsconfig)
+		if err != nil {
+			connectAttempts++
+			if connectAttempts >= this.conf.ConnectionAttempts {
+				logger.WithFields(log.Fields{
+					"error":   err.Error(),
+					"address": instance.Address,
+				}).Error("Maximum attempts reached, giving up on connecting to new resource.")
+
+				if err := this.provider.Terminate(id); err != nil {
+					logger.Error("Unable to terminate the instance: " + err.Error())
+				}
+				this.q.RemoveResource(resUUID)
+				this.resources.Delete(resUUID)
+				return
+			}
+
+			logger.WithFields(log.Fields{
+				"error":   err.Error(),
+				"address": instance.Address,
+			}).Warn("Unable to connect to cloud resource, trying again in 60 seconds.")
+			continue
+		}
+
+		resourceData := resourceInfo{
+			Instance:       instance,
+			StartTime:      time.Now(),
+			LastUseTime:    time.Now(),
+			DisconnectTime: time.Duration(-1),
+		}
+		if disconnect > 0 {
+			resourceData.DisconnectTime = time.Duration(disconnect) * time.Minute
+		}
+		this.resources.Set(resUUID, resourceData)
+
+		logger.WithField("address", instance.Address).Info("Connected to cloud resource.")
+		return
+	}
+}
+
+// This function takes the steps necessary to both disconnect the resource from
+// the queue and then terminate it in the cloud provider.
+// 1. Remove resource from queue
+// 2. Terminate the instance
+func (this *cloudResourceManager) DeleteResource(resourceid string) error {
+	err := this.q.RemoveResource(resourceid)
+	if err != nil {
+		return err
+	}
+
+	local, ok := this.resources.Get(resourceid)
+	if !ok {
+		return errors.New("Unable to gather local cloud resource manager data to delete resource.")
+	}
+
+	// No matter what, we want to remove this from the local data because it's missing from the queue
+	this.resources.Delete(resourceid)
+
+	return this.provider.Terminate(local.(resourceInfo).Instance.ID)
+}
+
+func (this *cloudResourceManager) GetResource(resourceid string) (*queue.Resource, map[string]string, error) {
+	resource, err := this.q.GetResource(resourceid)
+	if err != nil {
+		return &queue.Resource{}, nil, err
+	}
+
+	localdata, ok := this.resources.Get(resourceid)
+	if !ok {
+		return &queue.Resource{}, nil, errors.New("Could not find local data for resource that was in the queue")
+	}
+	localres := localdata.(resourceInfo)
+
+	tmpData := make(map[string]string)
+	tmpData["instanceid"] = localres.Instance.ID
+	tmpData["instancetype"] = localres.Instance.Type
+	tmpData["zone"] = localres.Instance.Zone
+	tmpData["state"] = localres.Instance.State
+	tmpData["disconnect"] = localres.DisconnectTime.String()
+	tmpData["lastusetime"] = localres.LastUseTime.String()
+
+	return resource, tmpData, nil
+}
+
+func (this *cloudResourceManager) UpdateResource(resourceid string, newstatus string, newparams map[string]string) error {
+	oldresource, _, err := this.GetResource(resourceid)
+	if err != nil {
+		return err
+	}
+
+	if oldresource.Status != newstatus {
+		switch newstatus {
+		case common.STATUS_RUNNING:
+			return this.q.ResumeResource(resourceid)
+		case common.STATUS_PAUSED:
+			return this.q.PauseResource(resourceid)
+		}
+	}
+
+	return nil
+}
+
+func (this *cloudResourceManager) GetManagedResources() []string {
+	resourceids := make([]string, 0, this.resources.Count())
+
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		resourceids = append(resourceids, data.Key)
+	}
+
+	return resourceids
+}
+
+// Loop through each connected resource and do the following:
+// 1. Check the state of the instance, if it's gone let the queue know.
+// 2. See if a resource is in use, if so then update the last used time
+// 3. Check and see if there are any resources that have timed out and terminate them
+func (this *cloudResourceManager) Keep() {
+	logger := log.WithField("manager", this.SystemName())
+
+	// Changes are made after the loop as the iterator does not hold a lock
+	updated := map[string]resourceInfo{}
+	var gone, expired []string
+
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		resource := data.Val.(resourceInfo)
+		if resource.StartTime.IsZero() {
+			// Still waiting for the instance to be ready
+			continue
+		}
+
+		// 1. Let's get the state of the instance
+		instance, err := this.provider.Instance(resource.Instance.ID)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				"instance": resource.Instance.ID,
+				"error":    err.Error(),
+			}).Warn("Unable to gather the state of the instance.")
+		} else {
+			resource.Instance.State = instance.State
+			if instance.State == STATE_TERMINATED {
+				gone = append(gone, data.Key)
+				continue
+			}
+		}
+
+		// 2. Let's check this resource and see if it's being used
+		if len(this.q.AllJobsByResource(data.Key)) > 0 {
+			resource.LastUseTime = time.Now()
+		}
+		updated[data.Key] = resource
+
+		// 3. Let's check and see if this resource has timed out
+		if resource.DisconnectTime > 0 && time.Since(resource.LastUseTime) > resource.DisconnectTime {
+			expired = append(expired, data.Key)
+		}
+	}
+
+	for key, val := range updated {
+		this.resources.Set(key, val)
+	}
+
+	for _, key := range gone {
+		logger.WithField("resourceid", key).Warn("Cloud instance is no longer running and has been removed.")
+		this.q.RemoveResource(key)
+		this.resources.Delete(key)
+	}
+
+	for _, key := range expired {
+		if err := this.DeleteResource(key); err != nil {
+			logger.WithFields(log.Fields{
+				"resourceid": key,
+				"error":      err.Error(),
+			}).Error("Unable to remove timed out instance from the queue.")
+			continue
+		}
+		logger.WithField("resourceid", key).Info("Cloud instance has not been used and has been removed as configured.")
+	}
+}
@@ -0,0 +1,253 @@
+package cloudresourcemanager
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudConfig(t *testing.T) {
+	conf := CloudConfig("CA\n", "-----BEGIN CERTIFICATE-----\nMII\n-----END CERTIFICATE-----\n", "KEY")
+
+	// Every line of a file is inside the block of its content
+	want := "-   content: |\n        -----BEGIN CERTIFICATE-----\n        MII\n        -----END CERTIFICATE-----\n"
+	if !strings.Contains(conf, want) {
+		t.Errorf("Expected the certificate to be indented in the cloud-config, got:\n%s", conf)
+	}
+	if !strings.HasSuffix(conf, "        KEY\n    path: /etc/cracklord/ssl/resourced.key") {
+		t.Errorf("Expected the key to be written last, got:\n%s", conf)
+	}
+}
+
+// A cloud provider keeping its instances in memory
+type fakeProvider struct {
+	instances  map[string]Instance
+	launched   []LaunchSpec
+	terminated []string
+	launchErr  error // Returned once launchMax instances were launched
+	launchMax  int
+}
+
+func (p *fakeProvider) SystemName() string  { return "fake" }
+func (p *fakeProvider) DisplayName() string { return "Fake Cloud" }
+func (p *fakeProvider) Description() string { return "Instances kept in memory" }
+
+func (p *fakeProvider) Launch(spec LaunchSpec) (Instance, error) {
+	if p.launchErr != nil && len(p.launched) >= p.launchMax {
+		return Instance{}, p.launchErr
+	}
+	p.launched = append(p.launched, spec)
+
+	instance := Instance{ID: "i-" + spec.Name, Name: spec.Name, Type: spec.InstanceType, Zone: "zone-a", State: STATE_PENDING}
+	p.instances[instance.ID] = instance
+	return instance, nil
+}
+
+func (p *fakeProvider) Instance(id string) (Instance, error) {
+	instance, ok := p.instances[id]
+	if !ok {
+		return Instance{ID: id, Name: id, State: STATE_TERMINATED}, nil
+	}
+	return instance, nil
+}
+
+func (p *fakeProvider) Terminate(id string) error {
+	p.terminated = append(p.terminated, id)
+	delete(p.instances, id)
+	return nil
+}
+
+// A resource manager for a fake provider with a CA to sign instances with
+func testManager(t *testing.T) (*cloudResourceManager, *fakeProvider, *queue.Queue) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Cracklord CA"},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := queue.NewQueue(filepath.Join(t.TempDir(), "state.json"), 0, 5, 0)
+	provider := &fakeProvider{instances: map[string]Instance{}}
+	conf := Config{
+		InstanceTypes:      map[string]string{"Large GPU": "gpu.large"},
+		InstanceTypesOrder: []string{"Large GPU"},
+		ConnectionAttempts: 1,
+		Port:               "9443",
+		CACert:             ca,
+		CAKey:              key,
+	}
+
+	return Setup(provider, conf, &q, &tls.Config{}).(*cloudResourceManager), provider, &q
+}
+
+// The certificates written to the cloud-config of an instance
+func userDataCerts(t *testing.T, userData string) []*x509.Certificate {
+	rest := []byte(strings.Replace(userData, "        ", "", -1))
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func TestAddAndDeleteResource(t *testing.T) {
+	m, provider, q := testManager(t)
+
+	err := m.AddResource(map[string]string{"number": "2", "instancetype": "Large GPU", "disconnect": "true", "disconnecttime": "15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.launched) != 2 {
+		t.Fatalf("Expected 2 instances to be launched, got %d", len(provider.launched))
+	}
+
+	// Each instance boots with the CA and a certificate for its own name
+	for _, spec := range provider.launched {
+		if spec.InstanceType != "gpu.large" || !strings.HasPrefix(spec.Name, "cracklord-") {
+			t.Errorf("Unexpected launch %s %s", spec.Name, spec.InstanceType)
+		}
+
+		certs := userDataCerts(t, spec.UserData)
+		if len(certs) != 2 || !certs[0].Equal(m.conf.CACert) {
+			t.Fatalf("Expected the CA and the instance certificate in the cloud-config, got %d certificates", len(certs))
+		}
+		if err := certs[1].CheckSignatureFrom(m.conf.CACert); err != nil || certs[1].VerifyHostname(spec.Name) != nil {
+			t.Errorf("Instance %s was given a certificate for %v: %v", spec.Name, certs[1].DNSNames, err)
+		}
+	}
+
+	// The instances wait in the queue until they can be connected to
+	ids := m.GetManagedResources()
+	if len(ids) != 2 {
+		t.Fatalf("Expected 2 managed resources, got %v", ids)
+	}
+	res, data, err := m.GetResource(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != common.STATUS_PENDING || data["instanceid"] != "i-"+res.Name || data["instancetype"] != "gpu.large" {
+		t.Errorf("Unexpected resource %s %s %v", res.Name, res.Status, data)
+	}
+
+	if err := m.DeleteResource(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.terminated) != 1 || provider.terminated[0] != data["instanceid"] {
+		t.Errorf("Expected instance %s to be terminated, got %v", data["instanceid"], provider.terminated)
+	}
+	if res, _ := q.GetResource(ids[0]); res.Status != common.STATUS_QUIT {
+		t.Errorf("Deleted resource is %s in the queue", res.Status)
+	}
+	if left := m.GetManagedResources(); len(left) != 1 || left[0] == ids[0] {
+		t.Errorf("Expected only the other resource to be managed, got %v", left)
+	}
+}
+
+func TestAddResourceLaunchFailure(t *testing.T) {
+	m, provider, _ := testManager(t)
+
+	for _, params := range []map[string]string{
+		{"number": "0", "instancetype": "Large GPU"},
+		{"number": "1", "instancetype": "Tiny GPU"},
+	} {
+		if err := m.AddResource(params); err == nil {
+			t.Errorf("Expected %v to be refused", params)
+		}
+	}
+	if len(provider.launched) != 0 {
+		t.Errorf("Refused requests launched %d instances", len(provider.launched))
+	}
+
+	provider.launchErr = errors.New("quota exceeded")
+	err := m.AddResource(map[string]string{"number": "1", "instancetype": "Large GPU"})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected the launch error, got %v", err)
+	}
+	if ids := m.GetManagedResources(); len(ids) != 0 {
+		t.Errorf("Failed launch added resources %v", ids)
+	}
+
+	// Instances launched before a failure are kept
+	provider.launchMax = 1
+	err = m.AddResource(map[string]string{"number": "2", "instancetype": "Large GPU"})
+	if err == nil || !strings.HasPrefix(err.Error(), "Only 1 of 2") {
+		t.Errorf("Expected a partial launch error, got %v", err)
+	}
+	if ids := m.GetManagedResources(); len(ids) != 1 {
+		t.Errorf("Expected the launched instance to be managed, got %v", ids)
+	}
+}
+
+func TestKeepRemovesInstances(t *testing.T) {
+	m, provider, q := testManager(t)
+
+	// Connected instances, one used recently, one unused for too long and one
+	// the provider no longer has
+	add := func(name string, lastUse time.Time) string {
+		id, err := q.AddResource(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		instance := Instance{ID: "i-" + name, Name: name, State: STATE_RUNNING}
+		provider.instances[instance.ID] = instance
+		m.resources.Set(id, resourceInfo{Instance: instance, StartTime: lastUse, LastUseTime: lastUse, DisconnectTime: time.Minute})
+		return id
+	}
+	used := add("used", time.Now())
+	unused := add("unused", time.Now().Add(-time.Hour))
+	gone := add("gone", time.Now())
+	delete(provider.instances, "i-gone")
+
+	m.Keep()
+
+	if ids := m.GetManagedResources(); len(ids) != 1 || ids[0] != used {
+		t.Errorf("Expected only the used resource to be kept, got %v", ids)
+	}
+	if len(provider.terminated) != 1 || provider.terminated[0] != "i-unused" {
+		t.Errorf("Expected only the unused instance to be terminated, got %v", provider.terminated)
+	}
+	for _, id := range []string{unused, gone} {
+		if res, _ := q.GetResource(id); res.Status != common.STATUS_QUIT {
+			t.Errorf("Removed resource %s is %s in the queue", res.Name, res.Status)
+		}
+	}
+}
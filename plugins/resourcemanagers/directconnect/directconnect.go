@@ -5,12 +5,15 @@ import (
 	"errors"
 	log "github.com/Sirupsen/logrus"
 	"github.com/emperorcow/protectedmap"
+	"github.com/jmmcatee/cracklord/common"
 	"github.com/jmmcatee/cracklord/common/queue"
+	"strconv"
 	"time"
 )
 
 type resourceInfo struct {
 	notes         string
+	bandwidth     int64 // Kilobytes per second, 0 uses the queue default
 	lastGoodCheck time.Time
 }
 
@@ -44,6 +47,7 @@ func (this directResourceManager) ParametersForm() string {
 	return `[
 		"name",
 		"address",
+		"bandwidth",
 		{
 			"key": "notes",
 			"type": "textarea",
@@ -67,6 +71,11 @@ func (this directResourceManager) ParametersSchema() string {
 				"default": "localhost",
 				"description": "The full DNS name or IP address of the resource."
 			},
+			"bandwidth": {
+				"title": "Bandwidth Limit (KB/s)",
+				"type": "integer",
+				"description": "OPTIONAL: Limit traffic to and from this resource, leave empty to use the queue default."
+			},
 			"notes": {
 				"title": "Notes",
 				"type": "string"
@@ -97,6 +106,10 @@ func (this *directResourceManager) AddResource(params map[string]string) error {
 		return err
 	}
 
+	//Apply any bandwidth limit before we connect so it covers all traffic
+	info := this.parseParams(params)
+	this.setBandwidth(uuid, info)
+
 	//Now we connect to the resource, and then let the user know the status
 	err = this.q.ConnectResource(uuid, address, this.tls)
 	if err != nil {
@@ -104,7 +117,7 @@ func (this *directResourceManager) AddResource(params map[string]string) error {
 	}
 
 	//Finally, set the resource into our map
-	this.resources.Set(uuid, this.parseParams(params))
+	this.resources.Set(uuid, info)
 
 	return nil
 }
@@ -126,11 +139,11 @@ func (this *directResourceManager) DeleteResource(resourceid string) error {
 
 func (this directResourceManager) GetResource(resourceid string) (*queue.Resource, map[string]string, error) {
 	//First, get the resource itself from the queue
-	resource, ok := this.q.GetResource(resourceid)
+	resource, err := this.q.GetResource(resourceid)
 
 	//If we weren't able to gather it, return an error
-	if !ok {
-		return &queue.Resource{}, nil, errors.New("Resource with requested ID not found in the queue.")
+	if err != nil {
+		return &queue.Resource{}, nil, err
 	}
 
 	//Now we'll gather the data from our local map of parameters
@@ -145,6 +158,9 @@ func (this directResourceManager) GetResource(resourceid string) (*queue.Resourc
 	//Parse our parameters struct back into a common string map
 	parameters := make(map[string]string)
 	parameters["notes"] = localres.notes
+	if localres.bandwidth > 0 {
+		parameters["bandwidth"] = strconv.FormatInt(localres.bandwidth, 10)
+	}
 
 	return resource, parameters, nil
 }
@@ -157,7 +173,9 @@ func (this *directResourceManager) UpdateResource(resourceid string, newstatus s
 	}
 
 	//Set the internal parameters within the direct connect manager to the new data
-	this.resources.Set(resourceid, this.parseParams(newparams))
+	info := this.parseParams(newparams)
+	this.resources.Set(resourceid, info)
+	this.setBandwidth(resourceid, info)
 
 	//Check to see if the old status matches the new one, if not, we need to make a change
 	if oldresource.Status != newstatus {
@@ -206,9 +224,9 @@ func (this *directResourceManager) Keep() {
 		logger := log.WithField("resourceid", data.Key)
 		logger.Debug("Gathering data on resource")
 		localResource := data.Val.(resourceInfo)
-		queueResource, ok := this.q.GetResource(data.Key)
+		queueResource, err := this.q.GetResource(data.Key)
 
-		if !ok {
+		if err != nil {
 			logger.Error("Unable to find a resource in the queue that the direct connect manager thought it was responsible for.")
 			continue
 		}
@@ -225,6 +243,14 @@ func (this *directResourceManager) Keep() {
 		//otherwise, we'll want to see about reconnecting
 		if status {
 			localResource.lastGoodCheck = time.Now()
+		} else if queueResource.Status != common.STATUS_QUIT {
+			// Resources restart after an update so try to connect to them again
+			err = this.q.ConnectResource(data.Key, queueResource.Address, this.tls)
+			if err != nil {
+				logger.WithField("error", err.Error()).Warn("Unable to reconnect to resource.")
+			} else {
+				localResource.lastGoodCheck = time.Now()
+			}
 		}
 
 		//Update our local data for the resource
@@ -239,5 +265,29 @@ func (this *directResourceManager) parseParams(params map[string]string) resourc
 	tempresource := resourceInfo{
 		notes: params["notes"],
 	}
+
+	if bw, ok := params["bandwidth"]; ok && bw != "" {
+		rate, err := strconv.ParseInt(bw, 10, 64)
+		if err != nil || rate < 0 {
+			log.WithField("bandwidth", bw).Warn("Invalid bandwidth limit provided for resource, using the queue default.")
+		} else {
+			tempresource.bandwidth = rate
+		}
+	}
+
 	return tempresource
 }
+
+// Set the bandwidth limit of a resource in the queue, falling back to the queue
+// default when the resource does not have its own
+func (this *directResourceManager) setBandwidth(resourceid string, info resourceInfo) {
+	var rate int64
+	if info.bandwidth > 0 {
+		rate = info.bandwidth * 1024
+	}
+
+	err := this.q.SetResourceBandwidth(resourceid, rate)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("Unable to set resource bandwidth limit.")
+	}
+}
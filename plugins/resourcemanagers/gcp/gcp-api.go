@@ -0,0 +1,269 @@
+package gcpresourcemanager
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	computeAPI   = "https://compute.googleapis.com/compute/v1"
+	computeScope = "https://www.googleapis.com/auth/compute"
+	metadataURL  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// The fields of a service account key file that are used
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Gets access tokens for the compute API, either with a service account key
+// or from the metadata server when the queue itself runs on GCE
+type tokenSource struct {
+	account *serviceAccount
+	key     *rsa.PrivateKey
+	http    *http.Client
+
+	mux    sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newTokenSource(credentialsFile string, client *http.Client) (*tokenSource, error) {
+	ts := &tokenSource{http: client}
+	if credentialsFile == "" {
+		return ts, nil
+	}
+
+	buf, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(buf, &account); err != nil {
+		return nil, err
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("No private key was found in the GCP credentials file " + credentialsFile)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("The private key in the GCP credentials file is not an RSA key")
+	}
+
+	ts.account = &account
+	ts.key = key
+	return ts, nil
+}
+
+// Get a token, requesting a new one shortly before the last one expires
+func (ts *tokenSource) get() (string, error) {
+	ts.mux.Lock()
+	defer ts.mux.Unlock()
+
+	if ts.token != "" && time.Now().Add(time.Minute).Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.account == nil {
+		req, err = http.NewRequest("GET", metadataURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+	} else {
+		assertion, err := ts.assertion()
+		if err != nil {
+			return "", err
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+		req, err = http.NewRequest("POST", ts.account.TokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := ts.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("unable to get a GCP access token: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// Build the signed JWT a service account exchanges for an access token
+func (ts *tokenSource) assertion() (string, error) {
+	enc := base64.RawURLEncoding
+	now := time.Now()
+
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   ts.account.ClientEmail,
+		"scope": computeScope,
+		"aud":   ts.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signed := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + enc.EncodeToString(sig), nil
+}
+
+// The fields of a compute instance that are read
+type gceInstance struct {
+	Name              string `json:"name"`
+	MachineType       string `json:"machineType"`
+	Zone              string `json:"zone"`
+	Status            string `json:"status"`
+	NetworkInterfaces []struct {
+		AccessConfigs []struct {
+			NatIP string `json:"natIP"`
+		} `json:"accessConfigs"`
+	} `json:"networkInterfaces"`
+}
+
+// Error returned by the compute API
+type gceError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *gceError) Error() string {
+	return fmt.Sprintf("GCP compute API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// A minimal client for the instances of one zone of the compute API
+type gceClient struct {
+	base    string
+	project string
+	zone    string
+	tokens  *tokenSource
+	http    *http.Client
+}
+
+func (c *gceClient) do(method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, c.base+"/projects/"+url.PathEscape(c.project)+"/zones/"+url.PathEscape(c.zone)+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := c.tokens.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+		if apiErr.Error.Message == "" {
+			apiErr.Error.Message = http.StatusText(resp.StatusCode)
+		}
+		return &gceError{StatusCode: resp.StatusCode, Message: apiErr.Error.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Create an instance, the request is accepted before the instance is running
+func (c *gceClient) insertInstance(instance map[string]interface{}) error {
+	return c.do("POST", "/instances", instance, nil)
+}
+
+func (c *gceClient) getInstance(name string) (gceInstance, error) {
+	var instance gceInstance
+	err := c.do("GET", "/instances/"+url.PathEscape(name), nil, &instance)
+	return instance, err
+}
+
+func (c *gceClient) deleteInstance(name string) error {
+	return c.do("DELETE", "/instances/"+url.PathEscape(name), nil, nil)
+}
+
+// Get the last part of a resource URL, such as the name of a machine type
+func lastPart(resource string) string {
+	return resource[strings.LastIndex(resource, "/")+1:]
+}
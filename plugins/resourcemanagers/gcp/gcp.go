@@ -0,0 +1,260 @@
+package gcpresourcemanager
+
+import (
+	"crypto/tls"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/cloud"
+	"github.com/vaughan0/go-ini"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A GPU attached to the instances of a machine type
+type accelerator struct {
+	Type  string
+	Count int
+}
+
+type gcpProvider struct {
+	client       *gceClient
+	image        string
+	network      string
+	diskSize     int
+	accelerators map[string]accelerator // By machine type
+	spot         bool                   // Launch spot instances that can be taken back
+}
+
+// Setup the Google Compute Engine resource manager from its configuration file
+func Setup(confpath string, qpointer *queue.Queue, tlspointer *tls.Config, caCertPath, caKeyPath string) (queue.ResourceManager, error) {
+	log.Debug("Setting up GCP resource manager")
+
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for GCP resource manager.")
+		return nil, err
+	}
+
+	confGen := confFile.Section("General")
+	if len(confGen) == 0 {
+		return nil, errors.New("No \"General\" configuration section.")
+	}
+
+	project := common.StripQuotes(confGen["Project"])
+	if project == "" {
+		return nil, errors.New("The Project was not defined in the general configuration section of the GCP resource manager config")
+	}
+	zone := common.StripQuotes(confGen["Zone"])
+	if zone == "" {
+		return nil, errors.New("The Zone was not defined in the general configuration section of the GCP resource manager config")
+	}
+
+	provider := &gcpProvider{
+		image:        common.StripQuotes(confGen["Image"]),
+		network:      common.StripQuotes(confGen["Network"]),
+		diskSize:     50,
+		accelerators: map[string]accelerator{},
+		spot:         common.StripQuotes(confGen["Spot"]) == "true",
+	}
+	if provider.image == "" {
+		return nil, errors.New("The Image to boot instances from was not defined in the general configuration section of the GCP resource manager config")
+	}
+	if provider.network == "" {
+		provider.network = "global/networks/default"
+	}
+	if size, ok := confGen["DiskSizeGB"]; ok {
+		provider.diskSize, err = strconv.Atoi(size)
+		if err != nil {
+			return nil, errors.New("Unable to parse DiskSizeGB field in GCP resource manager configuration file.")
+		}
+	}
+
+	// GPUs are attached to N1 machine types, the accelerator optimized types
+	// come with their own and are not listed
+	for machine, value := range confFile.Section("Accelerators") {
+		parts := strings.SplitN(common.StripQuotes(value), ":", 2)
+		acc := accelerator{Type: parts[0], Count: 1}
+		if len(parts) == 2 {
+			if acc.Count, err = strconv.Atoi(parts[1]); err != nil {
+				return nil, errors.New("Unable to parse the accelerator count for " + machine + " in GCP resource manager configuration file.")
+			}
+		}
+		provider.accelerators[machine] = acc
+	}
+
+	conf, err := cloudresourcemanager.LoadConfig(confFile, caCertPath, caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+	tokens, err := newTokenSource(common.StripQuotes(confGen["CredentialsFile"]), httpClient)
+	if err != nil {
+		return nil, err
+	}
+	provider.client = &gceClient{
+		base:    computeAPI,
+		project: project,
+		zone:    zone,
+		tokens:  tokens,
+		http:    httpClient,
+	}
+
+	// Make sure we can authenticate before the manager is offered to users
+	if _, err := tokens.get(); err != nil {
+		return nil, err
+	}
+
+	return cloudresourcemanager.Setup(provider, conf, qpointer, tlspointer), nil
+}
+
+func (this *gcpProvider) SystemName() string {
+	return "gcp"
+}
+
+func (this *gcpProvider) DisplayName() string {
+	return "Google Cloud Platform"
+}
+
+func (this *gcpProvider) Description() string {
+	return "Spawn GPU instances inside Google Compute Engine for use. Instances can be automatically terminated if unused for a time."
+}
+
+func (this *gcpProvider) Launch(spec cloudresourcemanager.LaunchSpec) (cloudresourcemanager.Instance, error) {
+	zone := this.client.zone
+
+	instance := map[string]interface{}{
+		"name":        spec.Name,
+		"machineType": "zones/" + zone + "/machineTypes/" + spec.InstanceType,
+		"disks": []interface{}{
+			map[string]interface{}{
+				"boot":       true,
+				"autoDelete": true,
+				"initializeParams": map[string]interface{}{
+					"sourceImage": this.image,
+					"diskSizeGb":  strconv.Itoa(this.diskSize),
+				},
+			},
+		},
+		"networkInterfaces": []interface{}{
+			map[string]interface{}{
+				"network": this.network,
+				"accessConfigs": []interface{}{
+					map[string]interface{}{
+						"type": "ONE_TO_ONE_NAT",
+						"name": "External NAT",
+					},
+				},
+			},
+		},
+		// Instances with GPUs can not be live migrated
+		"scheduling": map[string]interface{}{
+			"onHostMaintenance": "TERMINATE",
+			"automaticRestart":  true,
+		},
+		// Read by cloud-init on the image
+		"metadata": map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{
+					"key":   "user-data",
+					"value": spec.UserData,
+				},
+			},
+		},
+		"labels": map[string]string{
+			"cracklord": "resource",
+		},
+		"tags": map[string]interface{}{
+			"items": []string{"cracklord-resource"},
+		},
+	}
+
+	// Spot instances are deleted when they are taken back, their jobs are
+	// moved by the queue when the resource sees the notice
+	if this.spot {
+		instance["scheduling"] = map[string]interface{}{
+			"onHostMaintenance":         "TERMINATE",
+			"automaticRestart":          false,
+			"provisioningModel":         "SPOT",
+			"instanceTerminationAction": "DELETE",
+		}
+	}
+
+	if acc, ok := this.accelerators[spec.InstanceType]; ok {
+		instance["guestAccelerators"] = []interface{}{
+			map[string]interface{}{
+				"acceleratorType":  "zones/" + zone + "/acceleratorTypes/" + acc.Type,
+				"acceleratorCount": acc.Count,
+			},
+		}
+	}
+
+	if err := this.client.insertInstance(instance); err != nil {
+		return cloudresourcemanager.Instance{}, err
+	}
+
+	return cloudresourcemanager.Instance{
+		ID:    spec.Name,
+		Name:  spec.Name,
+		Type:  spec.InstanceType,
+		Zone:  zone,
+		State: cloudresourcemanager.STATE_PENDING,
+	}, nil
+}
+
+func (this *gcpProvider) Instance(id string) (cloudresourcemanager.Instance, error) {
+	gce, err := this.client.getInstance(id)
+	if gerr, ok := err.(*gceError); ok && gerr.StatusCode == http.StatusNotFound {
+		return cloudresourcemanager.Instance{ID: id, Name: id, State: cloudresourcemanager.STATE_TERMINATED}, nil
+	}
+	if err != nil {
+		return cloudresourcemanager.Instance{}, err
+	}
+
+	instance := cloudresourcemanager.Instance{
+		ID:    id,
+		Name:  gce.Name,
+		Type:  lastPart(gce.MachineType),
+		Zone:  lastPart(gce.Zone),
+		State: gceState(gce.Status),
+	}
+	for _, nic := range gce.NetworkInterfaces {
+		for _, ac := range nic.AccessConfigs {
+			if ac.NatIP != "" && instance.Address == "" {
+				instance.Address = ac.NatIP
+			}
+		}
+	}
+
+	return instance, nil
+}
+
+func (this *gcpProvider) Terminate(id string) error {
+	err := this.client.deleteInstance(id)
+	if gerr, ok := err.(*gceError); ok && gerr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Map the status of a compute instance to the states of the cloud manager
+func gceState(status string) string {
+	switch status {
+	case "PROVISIONING", "STAGING", "REPAIRING":
+		return cloudresourcemanager.STATE_PENDING
+	case "RUNNING":
+		return cloudresourcemanager.STATE_RUNNING
+	case "STOPPING", "SUSPENDING":
+		return cloudresourcemanager.STATE_STOPPING
+	default:
+		// TERMINATED, SUSPENDED, and anything new
+		return cloudresourcemanager.STATE_TERMINATED
+	}
+}
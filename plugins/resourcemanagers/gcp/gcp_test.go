@@ -0,0 +1,150 @@
+package gcpresourcemanager
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/jmmcatee/cracklord/plugins/resourcemanagers/cloud"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Write a service account key file for a new key
+func testCredentials(t *testing.T, tokenURI string) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, _ := json.Marshal(serviceAccount{
+		ClientEmail: "queue@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	})
+
+	f, err := ioutil.TempFile("", "gcp-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(buf)
+	f.Close()
+
+	return f.Name(), key
+}
+
+func TestProvider(t *testing.T) {
+	var key *rsa.PrivateKey
+	var tokens, launched int
+	var created map[string]interface{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+
+			// The assertion is signed with the key of the service account
+			parts := strings.Split(r.FormValue("assertion"), ".")
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sig); err != nil {
+				t.Errorf("Expected a valid assertion signature, got %v", err)
+			}
+
+			rw.Write([]byte(`{"access_token": "secret", "expires_in": 3600}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /projects/cracklord/zones/us-central1-a/instances":
+			launched++
+			json.NewDecoder(r.Body).Decode(&created)
+			rw.Write([]byte(`{"kind": "compute#operation"}`))
+		case "GET /projects/cracklord/zones/us-central1-a/instances/cracklord-1":
+			rw.Write([]byte(`{
+				"name": "cracklord-1",
+				"status": "RUNNING",
+				"machineType": "https://compute.googleapis.com/compute/v1/projects/cracklord/zones/us-central1-a/machineTypes/n1-standard-8",
+				"zone": "https://compute.googleapis.com/compute/v1/projects/cracklord/zones/us-central1-a",
+				"networkInterfaces": [{"accessConfigs": [{"natIP": "203.0.113.7"}]}]
+			}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"error": {"message": "not found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	creds, k := testCredentials(t, srv.URL+"/token")
+	defer os.Remove(creds)
+	key = k
+
+	ts, err := newTokenSource(creds, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &gcpProvider{
+		client: &gceClient{
+			base:    srv.URL,
+			project: "cracklord",
+			zone:    "us-central1-a",
+			tokens:  ts,
+			http:    srv.Client(),
+		},
+		image:        "projects/ubuntu-os-cloud/global/images/family/ubuntu-2204-lts",
+		network:      "global/networks/default",
+		diskSize:     50,
+		accelerators: map[string]accelerator{"n1-standard-8": {Type: "nvidia-tesla-t4", Count: 1}},
+		spot:         true,
+	}
+
+	if _, err := p.Launch(cloudresourcemanager.LaunchSpec{Name: "cracklord-1", InstanceType: "n1-standard-8", UserData: "#cloud-config"}); err != nil {
+		t.Fatal(err)
+	}
+	accs, _ := created["guestAccelerators"].([]interface{})
+	if launched != 1 || len(accs) != 1 {
+		t.Errorf("Expected an instance with a GPU to be created, got %v", created)
+	}
+
+	scheduling, _ := created["scheduling"].(map[string]interface{})
+	if scheduling["provisioningModel"] != "SPOT" || scheduling["instanceTerminationAction"] != "DELETE" {
+		t.Errorf("Expected a spot instance that is deleted when taken back, got %v", scheduling)
+	}
+
+	instance, err := p.Instance("cracklord-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.State != cloudresourcemanager.STATE_RUNNING || instance.Address != "203.0.113.7" || instance.Type != "n1-standard-8" || instance.Zone != "us-central1-a" {
+		t.Errorf("Unexpected instance %+v", instance)
+	}
+
+	// Instances that were deleted are terminated
+	instance, err = p.Instance("cracklord-2")
+	if err != nil || instance.State != cloudresourcemanager.STATE_TERMINATED {
+		t.Errorf("Expected a missing instance to be terminated, got %+v %v", instance, err)
+	}
+	if err := p.Terminate("cracklord-2"); err != nil {
+		t.Errorf("Expected terminating a missing instance to work, got %v", err)
+	}
+
+	if tokens != 1 {
+		t.Errorf("Expected the access token to be reused, got %d tokens", tokens)
+	}
+}
@@ -0,0 +1,316 @@
+package kubernetesresourcemanager
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The parts of the Kubernetes objects the resource manager uses. Only the
+// fields that are read are declared, everything else is ignored when decoding.
+type kubeMeta struct {
+	Name              string            `json:"name"`
+	UID               string            `json:"uid"`
+	Annotations       map[string]string `json:"annotations"`
+	DeletionTimestamp *string           `json:"deletionTimestamp"`
+}
+
+type kubeContainer struct {
+	Name      string `json:"name"`
+	Resources struct {
+		Limits   map[string]string `json:"limits"`
+		Requests map[string]string `json:"requests"`
+	} `json:"resources"`
+}
+
+type kubePodSpec struct {
+	NodeName   string          `json:"nodeName"`
+	Containers []kubeContainer `json:"containers"`
+}
+
+type kubeCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type kubePod struct {
+	Metadata kubeMeta    `json:"metadata"`
+	Spec     kubePodSpec `json:"spec"`
+	Status   struct {
+		Phase      string          `json:"phase"`
+		PodIP      string          `json:"podIP"`
+		Conditions []kubeCondition `json:"conditions"`
+	} `json:"status"`
+}
+
+type kubeDeployment struct {
+	Metadata kubeMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+		Template struct {
+			Spec kubePodSpec `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+type kubeNode struct {
+	Metadata kubeMeta `json:"metadata"`
+	Spec     struct {
+		Unschedulable bool `json:"unschedulable"`
+	} `json:"spec"`
+	Status struct {
+		Allocatable map[string]string `json:"allocatable"`
+	} `json:"status"`
+}
+
+// Error returned by the API server, the status is kept so callers can tell
+// a missing object from a failed request
+type kubeError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *kubeError) Error() string {
+	return fmt.Sprintf("kubernetes API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// A minimal client for the Kubernetes API server. The token is read from its
+// file for each request, as service account tokens are rotated by the kubelet.
+type kubeClient struct {
+	server    string
+	tokenFile string
+	namespace string
+	http      *http.Client
+}
+
+func newKubeClient(server, tokenFile, caFile, namespace string) (*kubeClient, error) {
+	tlsconfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("No certificates could be loaded from the Kubernetes CA file " + caFile)
+		}
+		tlsconfig.RootCAs = pool
+	}
+
+	return &kubeClient{
+		server:    strings.TrimSuffix(server, "/"),
+		tokenFile: tokenFile,
+		namespace: namespace,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsconfig},
+		},
+	}, nil
+}
+
+// Send a request to the API server, decoding the response into out if given
+func (k *kubeClient) do(method, path, contentType string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, k.server+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if k.tokenFile != "" {
+		token, err := ioutil.ReadFile(k.tokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Errors are sent as a Status object with a message
+		var status struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&status)
+		if status.Message == "" {
+			status.Message = http.StatusText(resp.StatusCode)
+		}
+		return &kubeError{StatusCode: resp.StatusCode, Message: status.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (k *kubeClient) namespacePath(group, resource, name string) string {
+	path := group + "/namespaces/" + url.PathEscape(k.namespace) + "/" + resource
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// Get a deployment in the namespace of the client
+func (k *kubeClient) getDeployment(name string) (kubeDeployment, error) {
+	var d kubeDeployment
+	err := k.do("GET", k.namespacePath("/apis/apps/v1", "deployments", name), "", nil, &d)
+	return d, err
+}
+
+// Set the number of pods of a deployment through its scale subresource
+func (k *kubeClient) scaleDeployment(name string, replicas int) error {
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+		},
+	}
+	return k.do("PATCH", k.namespacePath("/apis/apps/v1", "deployments", name)+"/scale", "application/merge-patch+json", patch, nil)
+}
+
+// List the pods matching all of the labels given
+func (k *kubeClient) listPods(labels map[string]string) ([]kubePod, error) {
+	var list struct {
+		Items []kubePod `json:"items"`
+	}
+
+	path := k.namespacePath("/api/v1", "pods", "")
+	if selector := labelSelector(labels); selector != "" {
+		path += "?labelSelector=" + url.QueryEscape(selector)
+	}
+
+	err := k.do("GET", path, "", nil, &list)
+	return list.Items, err
+}
+
+// Delete a pod, its deployment will start a new one if it is still wanted
+func (k *kubeClient) deletePod(name string) error {
+	err := k.do("DELETE", k.namespacePath("/api/v1", "pods", name), "", nil, nil)
+	if kerr, ok := err.(*kubeError); ok && kerr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Set an annotation on a pod
+func (k *kubeClient) annotatePod(name, key, value string) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				key: value,
+			},
+		},
+	}
+	return k.do("PATCH", k.namespacePath("/api/v1", "pods", name), "application/merge-patch+json", patch, nil)
+}
+
+// List the nodes of the cluster, this needs a cluster role to be allowed
+func (k *kubeClient) listNodes() ([]kubeNode, error) {
+	var list struct {
+		Items []kubeNode `json:"items"`
+	}
+	err := k.do("GET", "/api/v1/nodes", "", nil, &list)
+	return list.Items, err
+}
+
+// Build a label selector matching all of the labels, sorted so it is stable
+func labelSelector(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Get the number of a device plugin resource, such as nvidia.com/gpu, a pod
+// spec asks for. Extended resources can only be given as limits, requests are
+// checked as well in case both are set.
+func podDevices(spec kubePodSpec, resource string) int {
+	total := 0
+	for _, c := range spec.Containers {
+		qty, ok := c.Resources.Limits[resource]
+		if !ok {
+			qty = c.Resources.Requests[resource]
+		}
+		n, _ := strconv.Atoi(qty)
+		total += n
+	}
+	return total
+}
+
+// Count the devices of a device plugin resource that schedulable nodes have
+func nodeDevices(nodes []kubeNode, resource string) int {
+	total := 0
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		n, _ := strconv.Atoi(node.Status.Allocatable[resource])
+		total += n
+	}
+	return total
+}
+
+// A pod is ready when it is running, has an address and passes its readiness
+// checks. Pods that are being deleted are never ready.
+func podReady(pod kubePod) bool {
+	if pod.Metadata.DeletionTimestamp != nil || pod.Status.Phase != "Running" || pod.Status.PodIP == "" {
+		return false
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status == "True"
+		}
+	}
+	return false
+}
+
+// A pod is unschedulable when the scheduler could not find a node for it,
+// usually because there are no free devices left
+func podUnschedulable(pod kubePod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == "PodScheduled" && cond.Status == "False" && cond.Reason == "Unschedulable" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,587 @@
+package kubernetesresourcemanager
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/emperorcow/protectedmap"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"github.com/vaughan0/go-ini"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where a pod finds the credentials of its service account
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount/"
+
+// Annotation the ReplicaSet controller uses to pick the pods to remove first
+// when a deployment is scaled down. Pods with lower costs are removed first.
+const podDeletionCost = "controller.kubernetes.io/pod-deletion-cost"
+
+type resourceInfo struct {
+	Pod       string
+	UID       string
+	Node      string
+	IP        string
+	Devices   int // Devices of the device plugin resource given to the pod
+	Connected time.Time
+}
+
+type config struct {
+	Server         string
+	TokenFile      string
+	CAFile         string
+	Namespace      string
+	Deployment     string
+	Port           string
+	ServerName     string
+	DeviceResource string
+	MinReplicas    int
+	MaxReplicas    int
+	JobsPerPod     int
+	ScaleDownDelay time.Duration
+}
+
+type kubeResourceManager struct {
+	resources protectedmap.ProtectedMap
+	q         *queue.Queue
+	tls       *tls.Config
+	kube      *kubeClient
+	conf      config
+
+	// Scaling state, used by the keeper and the API
+	sync.Mutex
+	minimum    int             // Pods kept even when the queue is empty
+	connecting map[string]bool // UIDs of pods being connected to
+	lastBusy   time.Time       // When the queue last needed the pods it had
+	replicas   int             // Replicas last read from the deployment
+	capacity   int             // Most pods the free devices of the cluster can take, 0 when unknown
+}
+
+// Read an integer setting, using the default when it is not set
+func confInt(section ini.Section, key string, def int) (int, error) {
+	tmp, ok := section[key]
+	if !ok {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(common.StripQuotes(tmp))
+	if err != nil || n < 0 {
+		return 0, errors.New("Unable to parse " + key + " in the Kubernetes resource manager configuration file.")
+	}
+	return n, nil
+}
+
+// Read a string setting, using the default when it is not set or empty
+func confString(section ini.Section, key, def string) string {
+	if v := common.StripQuotes(section[key]); v != "" {
+		return v
+	}
+	return def
+}
+
+func Setup(confpath string, qpointer *queue.Queue, tlspointer *tls.Config) (queue.ResourceManager, error) {
+	log.Debug("Setting up Kubernetes resource manager")
+
+	confFile, err := ini.LoadFile(confpath)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"error": err.Error(),
+			"file":  confpath,
+		}).Error("Unable to load configuration file for Kubernetes resource manager.")
+		return nil, err
+	}
+
+	confGen := confFile.Section("General")
+	if len(confGen) == 0 {
+		return nil, errors.New("No \"General\" configuration section.")
+	}
+
+	var conf config
+
+	conf.Deployment = confString(confGen, "Deployment", "")
+	if conf.Deployment == "" {
+		return nil, errors.New("The Deployment of resourceserver pods was not defined in the general configuration section of the Kubernetes resource manager config")
+	}
+	conf.ServerName = confString(confGen, "ServerName", "")
+	if conf.ServerName == "" {
+		return nil, errors.New("The ServerName in the certificate of the resourceserver pods was not defined in the general configuration section of the Kubernetes resource manager config")
+	}
+
+	// Without an API server the queue is expected to run in the cluster
+	conf.Server = confString(confGen, "APIServer", "")
+	if conf.Server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("The APIServer was not defined in the Kubernetes resource manager config and the queue is not running in a cluster")
+		}
+		conf.Server = "https://" + net.JoinHostPort(host, port)
+	}
+	conf.TokenFile = confString(confGen, "TokenFile", serviceAccountDir+"token")
+	conf.CAFile = confString(confGen, "CAFile", serviceAccountDir+"ca.crt")
+
+	conf.Namespace = confString(confGen, "Namespace", "")
+	if conf.Namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountDir + "namespace")
+		if err != nil {
+			return nil, errors.New("The Namespace was not defined in the Kubernetes resource manager config and could not be read from the service account")
+		}
+		conf.Namespace = strings.TrimSpace(string(ns))
+	}
+
+	conf.Port = confString(confGen, "Port", "9443")
+	conf.DeviceResource = confString(confGen, "DeviceResource", "nvidia.com/gpu")
+
+	if conf.MinReplicas, err = confInt(confGen, "MinReplicas", 0); err != nil {
+		return nil, err
+	}
+	if conf.MaxReplicas, err = confInt(confGen, "MaxReplicas", 4); err != nil {
+		return nil, err
+	}
+	if conf.MaxReplicas < conf.MinReplicas {
+		return nil, errors.New("MaxReplicas can not be less than MinReplicas in the Kubernetes resource manager configuration file.")
+	}
+	if conf.JobsPerPod, err = confInt(confGen, "JobsPerPod", 1); err != nil {
+		return nil, err
+	}
+	if conf.JobsPerPod == 0 {
+		conf.JobsPerPod = 1
+	}
+	delay, err := confInt(confGen, "ScaleDownDelay", 10)
+	if err != nil {
+		return nil, err
+	}
+	conf.ScaleDownDelay = time.Duration(delay) * time.Minute
+
+	kube, err := newKubeClient(conf.Server, conf.TokenFile, conf.CAFile, conf.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the deployment is there before we try to scale it
+	if _, err := kube.getDeployment(conf.Deployment); err != nil {
+		return nil, err
+	}
+
+	// All of the pods present the same certificate, so it is checked against
+	// a fixed name instead of their addresses
+	tlsconfig := tlspointer.Clone()
+	tlsconfig.ServerName = conf.ServerName
+
+	mgr := &kubeResourceManager{
+		resources:  protectedmap.New(),
+		q:          qpointer,
+		tls:        tlsconfig,
+		kube:       kube,
+		conf:       conf,
+		minimum:    conf.MinReplicas,
+		connecting: map[string]bool{},
+		lastBusy:   time.Now(),
+	}
+
+	log.WithFields(log.Fields{
+		"server":     conf.Server,
+		"namespace":  conf.Namespace,
+		"deployment": conf.Deployment,
+	}).Info("Kubernetes resource manager will scale the resourceserver deployment.")
+
+	return mgr, nil
+}
+
+func (this *kubeResourceManager) SystemName() string {
+	return "kubernetes"
+}
+
+func (this *kubeResourceManager) DisplayName() string {
+	return "Kubernetes"
+}
+
+func (this *kubeResourceManager) Description() string {
+	return "Run resource servers as pods on a Kubernetes cluster. Pods are added as the queue fills and removed once they are no longer needed."
+}
+
+func (this *kubeResourceManager) ParametersForm() string {
+	return `[
+		"minimum"
+	]`
+}
+
+func (this *kubeResourceManager) ParametersSchema() string {
+	return `{
+		"type": "object",
+		"title": "Kubernetes",
+		"properties": {
+			"minimum": {
+				"title": "Minimum Pods",
+				"description": "How many pods should be kept running even when there are no jobs? Up to ` + strconv.Itoa(this.conf.MaxReplicas) + ` pods are added as jobs are queued.",
+				"type": "string",
+				"default": "` + strconv.Itoa(this.conf.MinReplicas) + `"
+			}
+		},
+		"required": [
+			"minimum"
+		]
+	}`
+}
+
+// Pods are registered by the keeper as the deployment starts them, so adding
+// resources sets the number of pods that are always kept running instead.
+func (this *kubeResourceManager) AddResource(params map[string]string) error {
+	tmpnum, ok := params["minimum"]
+	if !ok {
+		return errors.New("A minimum number of pods was not specified.")
+	}
+
+	num, err := strconv.Atoi(tmpnum)
+	if err != nil || num < 0 {
+		return errors.New("The minimum number of pods must be a positive number.")
+	}
+	if num > this.conf.MaxReplicas {
+		return fmt.Errorf("At most %d pods can be run by the Kubernetes resource manager.", this.conf.MaxReplicas)
+	}
+
+	this.Lock()
+	this.minimum = num
+	this.Unlock()
+
+	log.WithField("minimum", num).Info("Minimum Kubernetes resourceserver pods changed.")
+
+	// Scale now instead of waiting on the keeper
+	go this.scale()
+
+	return nil
+}
+
+// Remove a resource from the queue and delete its pod. The deployment will
+// start another pod in its place if the queue still needs it.
+func (this *kubeResourceManager) DeleteResource(resourceid string) error {
+	err := this.q.RemoveResource(resourceid)
+	if err != nil {
+		return err
+	}
+
+	local, ok := this.resources.Get(resourceid)
+	if !ok {
+		return errors.New("Unable to gather local Kubernetes resource manager data to delete resource.")
+	}
+	this.resources.Delete(resourceid)
+
+	return this.kube.deletePod(local.(resourceInfo).Pod)
+}
+
+func (this *kubeResourceManager) GetResource(resourceid string) (*queue.Resource, map[string]string, error) {
+	resource, err := this.q.GetResource(resourceid)
+	if err != nil {
+		return &queue.Resource{}, nil, err
+	}
+
+	localdata, ok := this.resources.Get(resourceid)
+	if !ok {
+		return &queue.Resource{}, nil, errors.New("Could not find local data for resource that was in the queue")
+	}
+	localres := localdata.(resourceInfo)
+
+	tmpData := make(map[string]string)
+	tmpData["namespace"] = this.conf.Namespace
+	tmpData["deployment"] = this.conf.Deployment
+	tmpData["pod"] = localres.Pod
+	tmpData["node"] = localres.Node
+	tmpData["podip"] = localres.IP
+	tmpData["devices"] = strconv.Itoa(localres.Devices)
+	tmpData["connected"] = localres.Connected.String()
+
+	return resource, tmpData, nil
+}
+
+func (this *kubeResourceManager) UpdateResource(resourceid string, newstatus string, newparams map[string]string) error {
+	oldresource, _, err := this.GetResource(resourceid)
+	if err != nil {
+		return err
+	}
+
+	// Pods are all the same, so only the status can be changed
+	if oldresource.Status != newstatus {
+		switch newstatus {
+		case common.STATUS_RUNNING:
+			return this.q.ResumeResource(resourceid)
+		case common.STATUS_PAUSED:
+			return this.q.PauseResource(resourceid)
+		}
+	}
+
+	return nil
+}
+
+func (this *kubeResourceManager) GetManagedResources() []string {
+	resourceids := make([]string, 0, this.resources.Count())
+
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		resourceids = append(resourceids, data.Key)
+	}
+
+	return resourceids
+}
+
+// The keeper does the following each time it is run:
+// 1. Register pods of the deployment that are ready and were not seen before
+// 2. Remove resources whose pods are gone, not ready or lost their connection
+// 3. Scale the deployment to the number of pods the queue needs
+func (this *kubeResourceManager) Keep() {
+	log.Debug("Kubernetes keeper starting up")
+
+	deployment, err := this.kube.getDeployment(this.conf.Deployment)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("ResMgr (Kubernetes): Unable to read the resourceserver deployment.")
+		return
+	}
+
+	pods, err := this.kube.listPods(deployment.Spec.Selector.MatchLabels)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("ResMgr (Kubernetes): Unable to list the resourceserver pods.")
+		return
+	}
+
+	// 1. Register ready pods we don't know about yet
+	ready := map[string]kubePod{}
+	for _, pod := range pods {
+		if podReady(pod) {
+			ready[pod.Metadata.UID] = pod
+		}
+	}
+
+	known := map[string]bool{}
+	var gone []string
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		res := data.Val.(resourceInfo)
+		known[res.UID] = true
+
+		// 2. Find the resources that should be removed
+		if _, ok := ready[res.UID]; !ok {
+			log.WithField("pod", res.Pod).Info("Kubernetes resourceserver pod is gone or no longer ready.")
+			gone = append(gone, data.Key)
+			continue
+		}
+
+		queueResource, err := this.q.GetResource(data.Key)
+		if err != nil || !this.q.CheckResourceConnectionStatus(queueResource) {
+			log.WithField("pod", res.Pod).Warn("Kubernetes resourceserver pod is no longer connected.")
+			gone = append(gone, data.Key)
+		}
+	}
+
+	for _, key := range gone {
+		this.q.RemoveResource(key)
+		this.resources.Delete(key)
+	}
+
+	this.Lock()
+	for uid, pod := range ready {
+		if known[uid] || this.connecting[uid] {
+			continue
+		}
+		this.connecting[uid] = true
+		go this.register(pod)
+	}
+	this.Unlock()
+
+	// 3. Scale to the queue
+	capacity := this.deviceCapacity(deployment)
+	this.Lock()
+	if deployment.Spec.Replicas != nil {
+		this.replicas = *deployment.Spec.Replicas
+	}
+	this.capacity = capacity
+	this.Unlock()
+	this.scaleWith(pods)
+
+	log.Info("Kubernetes resource manager has successfully updated resources.")
+}
+
+// Connect to a pod that became ready and add it to the queue
+func (this *kubeResourceManager) register(pod kubePod) {
+	defer func() {
+		this.Lock()
+		delete(this.connecting, pod.Metadata.UID)
+		this.Unlock()
+	}()
+
+	logger := log.WithField("pod", pod.Metadata.Name)
+
+	name := "k8s-" + pod.Metadata.Name
+	resUUID, err := this.q.AddResource(name)
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Unable to add resource for Kubernetes pod.")
+		return
+	}
+
+	info := resourceInfo{
+		Pod:     pod.Metadata.Name,
+		UID:     pod.Metadata.UID,
+		Node:    pod.Spec.NodeName,
+		IP:      pod.Status.PodIP,
+		Devices: podDevices(pod.Spec, this.conf.DeviceResource),
+	}
+
+	err = this.q.ConnectResource(resUUID, net.JoinHostPort(pod.Status.PodIP, this.conf.Port), this.tls)
+	if err != nil {
+		// The keeper will try again while the pod stays ready
+		logger.WithField("error", err.Error()).Warn("Unable to connect to Kubernetes resourceserver pod.")
+		this.q.RemoveResource(resUUID)
+		return
+	}
+
+	info.Connected = time.Now()
+	this.resources.Set(resUUID, info)
+
+	logger.WithFields(log.Fields{
+		"resource": resUUID,
+		"node":     info.Node,
+		"devices":  info.Devices,
+	}).Info("Kubernetes resourceserver pod registered.")
+}
+
+// Work out how many pods fit on the devices the device plugin advertises.
+// Nodes can only be listed with a cluster role, without it the capacity is
+// left unknown and only MaxReplicas applies.
+func (this *kubeResourceManager) deviceCapacity(deployment kubeDeployment) int {
+	perPod := podDevices(deployment.Spec.Template.Spec, this.conf.DeviceResource)
+	if perPod == 0 {
+		return 0
+	}
+
+	nodes, err := this.kube.listNodes()
+	if err != nil {
+		log.WithField("error", err.Error()).Debug("ResMgr (Kubernetes): Unable to list nodes, device capacity is unknown.")
+		return 0
+	}
+
+	return nodeDevices(nodes, this.conf.DeviceResource) / perPod
+}
+
+// Scale the deployment with the pods read from the cluster
+func (this *kubeResourceManager) scale() {
+	deployment, err := this.kube.getDeployment(this.conf.Deployment)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("ResMgr (Kubernetes): Unable to read the resourceserver deployment.")
+		return
+	}
+	pods, err := this.kube.listPods(deployment.Spec.Selector.MatchLabels)
+	if err != nil {
+		log.WithField("error", err.Error()).Error("ResMgr (Kubernetes): Unable to list the resourceserver pods.")
+		return
+	}
+
+	this.Lock()
+	if deployment.Spec.Replicas != nil {
+		this.replicas = *deployment.Spec.Replicas
+	}
+	this.Unlock()
+	this.scaleWith(pods)
+}
+
+// Change the replicas of the deployment to the number of pods the queue needs
+func (this *kubeResourceManager) scaleWith(pods []kubePod) {
+	// Jobs waiting to be started count against every resource, running jobs
+	// only against the pods running them
+	busyPods := map[string]int{}
+	jobs := 0
+	for _, job := range this.q.AllJobs() {
+		if job.Status == common.STATUS_CREATED {
+			jobs++
+			continue
+		}
+		if job.Status != common.STATUS_RUNNING && job.Status != common.STATUS_PAUSED {
+			continue
+		}
+		if local, ok := this.resources.Get(job.ResAssigned); ok {
+			jobs++
+			busyPods[local.(resourceInfo).Pod]++
+		}
+	}
+
+	unschedulable := false
+	for _, pod := range pods {
+		if podUnschedulable(pod) {
+			unschedulable = true
+		}
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	maximum := this.conf.MaxReplicas
+	if this.capacity > 0 && this.capacity < maximum {
+		maximum = this.capacity
+	}
+	// Adding more pods when the last ones could not be placed won't help
+	if unschedulable && this.replicas < maximum {
+		maximum = this.replicas
+	}
+
+	want := desiredReplicas(jobs, this.conf.JobsPerPod, this.minimum, maximum, len(busyPods))
+	if want >= this.replicas {
+		this.lastBusy = time.Now()
+	}
+
+	if want == this.replicas {
+		return
+	}
+	if want < this.replicas && time.Since(this.lastBusy) < this.conf.ScaleDownDelay {
+		return
+	}
+
+	logger := log.WithFields(log.Fields{
+		"deployment": this.conf.Deployment,
+		"replicas":   this.replicas,
+		"want":       want,
+		"jobs":       jobs,
+	})
+
+	// Make sure the pods running jobs are the last to be removed
+	if want < this.replicas {
+		for _, pod := range pods {
+			cost := strconv.Itoa(busyPods[pod.Metadata.Name])
+			if pod.Metadata.Annotations[podDeletionCost] == cost {
+				continue
+			}
+			if err := this.kube.annotatePod(pod.Metadata.Name, podDeletionCost, cost); err != nil {
+				logger.WithField("error", err.Error()).Warn("ResMgr (Kubernetes): Unable to set the deletion cost of a pod.")
+			}
+		}
+	}
+
+	if err := this.kube.scaleDeployment(this.conf.Deployment, want); err != nil {
+		logger.WithField("error", err.Error()).Error("ResMgr (Kubernetes): Unable to scale the resourceserver deployment.")
+		return
+	}
+	this.replicas = want
+
+	logger.Info("Scaled the Kubernetes resourceserver deployment.")
+}
+
+// Get the number of pods needed to run the jobs, keeping the minimum and the
+// pods that are busy, but never more than the maximum
+func desiredReplicas(jobs, jobsPerPod, minimum, maximum, busy int) int {
+	want := (jobs + jobsPerPod - 1) / jobsPerPod
+
+	if want < busy {
+		want = busy
+	}
+	if want < minimum {
+		want = minimum
+	}
+	if want > maximum {
+		want = maximum
+	}
+
+	return want
+}
@@ -0,0 +1,131 @@
+package kubernetesresourcemanager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestDesiredReplicas(t *testing.T) {
+	tests := []struct {
+		jobs, perPod, min, max, busy, want int
+	}{
+		{0, 1, 0, 4, 0, 0},
+		{3, 1, 0, 4, 0, 3},
+		{3, 2, 0, 4, 0, 2},
+		{9, 1, 0, 4, 0, 4},
+		{0, 1, 2, 4, 0, 2},
+		{1, 1, 0, 4, 3, 3},
+		{1, 1, 0, 2, 3, 2},
+	}
+
+	for _, test := range tests {
+		got := desiredReplicas(test.jobs, test.perPod, test.min, test.max, test.busy)
+		if got != test.want {
+			t.Errorf("Expected %d replicas for %+v, got %d", test.want, test, got)
+		}
+	}
+}
+
+func TestDevices(t *testing.T) {
+	var spec kubePodSpec
+	json.Unmarshal([]byte(`{"containers": [
+		{"resources": {"limits": {"nvidia.com/gpu": "2", "cpu": "4"}}},
+		{"resources": {"requests": {"nvidia.com/gpu": "1"}}}
+	]}`), &spec)
+	if n := podDevices(spec, "nvidia.com/gpu"); n != 3 {
+		t.Errorf("Expected the pod to ask for 3 GPUs, got %d", n)
+	}
+
+	var nodes []kubeNode
+	json.Unmarshal([]byte(`[
+		{"status": {"allocatable": {"nvidia.com/gpu": "4"}}},
+		{"spec": {"unschedulable": true}, "status": {"allocatable": {"nvidia.com/gpu": "8"}}},
+		{"status": {"allocatable": {"cpu": "16"}}}
+	]`), &nodes)
+	if n := nodeDevices(nodes, "nvidia.com/gpu"); n != 4 {
+		t.Errorf("Expected 4 GPUs on schedulable nodes, got %d", n)
+	}
+}
+
+func TestPodReady(t *testing.T) {
+	var pods []kubePod
+	json.Unmarshal([]byte(`[
+		{"status": {"phase": "Running", "podIP": "10.0.0.1", "conditions": [{"type": "Ready", "status": "True"}]}},
+		{"status": {"phase": "Running", "podIP": "10.0.0.2", "conditions": [{"type": "Ready", "status": "False"}]}},
+		{"metadata": {"deletionTimestamp": "2026-01-01T00:00:00Z"}, "status": {"phase": "Running", "podIP": "10.0.0.3", "conditions": [{"type": "Ready", "status": "True"}]}},
+		{"status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable"}]}}
+	]`), &pods)
+
+	for i, want := range []bool{true, false, false, false} {
+		if podReady(pods[i]) != want {
+			t.Errorf("Expected pod %d to be ready %v", i, want)
+		}
+	}
+	if podUnschedulable(pods[0]) || !podUnschedulable(pods[3]) {
+		t.Error("Expected only the pending pod to be unschedulable")
+	}
+}
+
+func TestKubeClient(t *testing.T) {
+	token, err := ioutil.TempFile("", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(token.Name())
+	token.WriteString("secret\n")
+	token.Close()
+
+	var scaled map[string]map[string]int
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/namespaces/cracklord/pods":
+			if r.URL.Query().Get("labelSelector") != "app=resourced,tier=gpu" {
+				t.Errorf("Unexpected label selector %q", r.URL.Query().Get("labelSelector"))
+			}
+			rw.Write([]byte(`{"items": [{"metadata": {"name": "resourced-1", "uid": "1"}}]}`))
+		case "PATCH /apis/apps/v1/namespaces/cracklord/deployments/resourced/scale":
+			if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+				t.Errorf("Unexpected content type %q", ct)
+			}
+			json.NewDecoder(r.Body).Decode(&scaled)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"kind": "Status", "message": "not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	k, err := newKubeClient(srv.URL, token.Name(), "", "cracklord")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods, err := k.listPods(map[string]string{"tier": "gpu", "app": "resourced"})
+	if err != nil || len(pods) != 1 || pods[0].Metadata.Name != "resourced-1" {
+		t.Errorf("Expected one pod, got %v %v", pods, err)
+	}
+
+	if err := k.scaleDeployment("resourced", 3); err != nil {
+		t.Fatal(err)
+	}
+	if scaled["spec"]["replicas"] != 3 {
+		t.Errorf("Expected the deployment to be scaled to 3, got %v", scaled)
+	}
+
+	_, err = k.getDeployment("missing")
+	if kerr, ok := err.(*kubeError); !ok || kerr.StatusCode != http.StatusNotFound || kerr.Message != "not found" {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+	if err := k.deletePod("missing"); err != nil {
+		t.Errorf("Expected deleting a missing pod to work, got %v", err)
+	}
+}
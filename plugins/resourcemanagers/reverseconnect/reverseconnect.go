@@ -0,0 +1,340 @@
+package reverseconnectresourcemanager
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/emperorcow/protectedmap"
+	"github.com/jmmcatee/cracklord/common/queue"
+	"net"
+	"strings"
+	"time"
+)
+
+// The maximum length of the name a resource sends when it connects
+const maxNameLength = 256
+
+type resourceInfo struct {
+	name          string
+	identity      string // Fingerprint of the certificate the resource connected with
+	notes         string
+	lastGoodCheck time.Time
+}
+
+type reverseResourceManager struct {
+	resources protectedmap.ProtectedMap
+	q         *queue.Queue
+	listen    net.Listener
+}
+
+// Setup the reverse connect manager, which listens on the given address for
+// resources that dial out to the queue. This allows resources behind NAT or a
+// firewall to be used without any inbound rules on the resource side.
+func Setup(listenaddr string, qpointer *queue.Queue, tlspointer *tls.Config) (queue.ResourceManager, error) {
+	// Resources must present a certificate signed by our CA
+	tlsconfig := tlspointer.Clone()
+	tlsconfig.ClientAuth = tls.RequireAndVerifyClientCert
+
+	listen, err := tls.Listen("tcp", listenaddr, tlsconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr := &reverseResourceManager{
+		resources: protectedmap.New(),
+		q:         qpointer,
+		listen:    listen,
+	}
+
+	go mgr.accept()
+
+	log.WithField("addr", listenaddr).Info("Listening for reverse resource connections.")
+
+	return mgr, nil
+}
+
+func (this reverseResourceManager) SystemName() string {
+	return "reverseconnect"
+}
+
+func (this reverseResourceManager) DisplayName() string {
+	return "Reverse Connect"
+}
+
+func (this reverseResourceManager) Description() string {
+	return "Resource servers that connect out to the queue."
+}
+
+func (this reverseResourceManager) ParametersForm() string {
+	return `[
+		{
+			"key": "notes",
+			"type": "textarea",
+			"placeholder": "OPTIONAL: Any notes you would like to include (location, primary contact, etc.)"
+		}
+    	]`
+}
+
+func (this reverseResourceManager) ParametersSchema() string {
+	return `{
+		"type": "object",
+		"title": "Reverse Connect",
+		"properties": {
+			"notes": {
+				"title": "Notes",
+				"type": "string"
+			}
+		}
+	}`
+}
+
+func (this *reverseResourceManager) AddResource(params map[string]string) error {
+	return errors.New("Reverse connect resources are added automatically when the resource connects to the queue.")
+}
+
+func (this *reverseResourceManager) DeleteResource(resourceid string) error {
+	//First, try and delete the resource from the queue itself
+	err := this.q.RemoveResource(resourceid)
+
+	//If there was an error, log it back to the API
+	if err != nil {
+		log.WithField("error", err.Error()).Debug("Unable to remove resource through reverse connect manager")
+		return err
+	}
+
+	//Finally, delete the local data from here
+	this.resources.Delete(resourceid)
+	return nil
+}
+
+func (this reverseResourceManager) GetResource(resourceid string) (*queue.Resource, map[string]string, error) {
+	//First, get the resource itself from the queue
+	resource, err := this.q.GetResource(resourceid)
+
+	//If we weren't able to gather it, return an error
+	if err != nil {
+		return &queue.Resource{}, nil, err
+	}
+
+	//Now we'll gather the data from our local map of parameters
+	localresource, ok := this.resources.Get(resourceid)
+	if !ok {
+		return &queue.Resource{}, nil, errors.New("Resource with requested ID could not be found in reverse connect resource manager.")
+	}
+
+	localres := localresource.(resourceInfo)
+
+	parameters := make(map[string]string)
+	parameters["notes"] = localres.notes
+
+	return resource, parameters, nil
+}
+
+func (this *reverseResourceManager) UpdateResource(resourceid string, newstatus string, newparams map[string]string) error {
+	//Because we need to make some comparisons for pause/resume, let's get the current resource state
+	oldresource, _, err := this.GetResource(resourceid)
+	if err != nil {
+		return err
+	}
+
+	//Only the notes can be changed, the name comes from the resource itself
+	localresource, _ := this.resources.Get(resourceid)
+	localres := localresource.(resourceInfo)
+	localres.notes = newparams["notes"]
+	this.resources.Set(resourceid, localres)
+
+	//Check to see if the old status matches the new one, if not, we need to make a change
+	if oldresource.Status != newstatus {
+		switch newstatus {
+		case "running":
+			err = this.q.ResumeResource(resourceid)
+			if err != nil {
+				return err
+			}
+
+		case "paused":
+			err = this.q.PauseResource(resourceid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (this reverseResourceManager) GetManagedResources() []string {
+	resourceids := make([]string, 0, this.resources.Count())
+
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		resourceids = append(resourceids, data.Key)
+	}
+
+	return resourceids
+}
+
+// This function loops through all of the reverse connected resources and checks
+// they are still connected. Resources that have gone away are removed from
+// service, they will be added again when they reconnect.
+func (this *reverseResourceManager) Keep() {
+	log.Debug("Reverse connect keeper starting up")
+
+	// Changes are made after the loop as the iterator does not hold a lock
+	updated := map[string]resourceInfo{}
+	var disconnected []string
+
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		logger := log.WithField("resourceid", data.Key)
+		localResource := data.Val.(resourceInfo)
+		queueResource, err := this.q.GetResource(data.Key)
+
+		if err != nil {
+			logger.Error("Unable to find a resource in the queue that the reverse connect manager thought it was responsible for.")
+			continue
+		}
+
+		if this.q.CheckResourceConnectionStatus(queueResource) {
+			localResource.lastGoodCheck = time.Now()
+			updated[data.Key] = localResource
+			continue
+		}
+
+		logger.WithField("name", localResource.name).Warn("Reverse connected resource is no longer connected.")
+		disconnected = append(disconnected, data.Key)
+	}
+
+	for key, val := range updated {
+		this.resources.Set(key, val)
+	}
+	for _, key := range disconnected {
+		this.DeleteResource(key)
+	}
+
+	log.Info("Reverse connect resource manager has successfully updated resources.")
+}
+
+// Accept connections from resources until the listener is closed
+func (this *reverseResourceManager) accept() {
+	for {
+		conn, err := this.listen.Accept()
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Failed to accept reverse resource connection.")
+			return
+		}
+
+		go this.register(conn)
+	}
+}
+
+// Register a newly connected resource with the queue. The resource sends its
+// name on a single line and then serves RPC requests over the connection.
+func (this *reverseResourceManager) register(conn net.Conn) {
+	logger := log.WithField("addr", conn.RemoteAddr().String())
+
+	conn.SetReadDeadline(time.Now().Add(15 * time.Second))
+	name, err := readName(conn)
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Unable to read the name of a reverse connected resource.")
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	// If this resource was connected before, remove the old connection first.
+	// A connection with another certificate can only take the name over once
+	// the resource using it has gone away.
+	identity := peerIdentity(conn)
+	previous := map[string]resourceInfo{}
+	iter := this.resources.Iterator()
+	for data := range iter.Loop() {
+		if data.Val.(resourceInfo).name == name {
+			previous[data.Key] = data.Val.(resourceInfo)
+		}
+	}
+	for key, info := range previous {
+		if info.identity != identity && this.connected(key) {
+			logger.WithField("name", name).Warn("Refused a reverse connection for the name of another connected resource.")
+			conn.Close()
+			return
+		}
+	}
+	for key := range previous {
+		logger.WithField("name", name).Info("Replacing previous connection for resource.")
+		this.DeleteResource(key)
+	}
+
+	uuid, err := this.q.AddResource(name)
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Unable to add reverse connected resource.")
+		conn.Close()
+		return
+	}
+
+	this.resources.Set(uuid, resourceInfo{name: name, identity: identity, lastGoodCheck: time.Now()})
+
+	err = this.q.ConnectResourceConn(uuid, conn.RemoteAddr().String(), conn)
+	if err != nil {
+		logger.WithField("error", err.Error()).Error("Unable to connect reverse connected resource.")
+		this.q.RemoveResource(uuid)
+		this.resources.Delete(uuid)
+		conn.Close()
+		return
+	}
+
+	logger.WithField("name", name).Info("Reverse connected resource registered.")
+}
+
+// Check if a resource is still connected to the queue
+func (this *reverseResourceManager) connected(resourceid string) bool {
+	res, err := this.q.GetResource(resourceid)
+	if err != nil {
+		return false
+	}
+	return this.q.CheckResourceConnectionStatus(res)
+}
+
+// The fingerprint of the client certificate of a connection, read after the
+// handshake
+func peerIdentity(conn net.Conn) string {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(certs[0].Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Read the name line one byte at a time so nothing after it is consumed
+func readName(conn net.Conn) (string, error) {
+	var name []byte
+	b := make([]byte, 1)
+
+	for len(name) < maxNameLength {
+		_, err := conn.Read(b)
+		if err != nil {
+			return "", err
+		}
+
+		if b[0] == '\n' {
+			n := strings.TrimSpace(string(name))
+			if n == "" {
+				return "", errors.New("Resource did not provide a name.")
+			}
+			return n, nil
+		}
+
+		name = append(name, b[0])
+	}
+
+	return "", errors.New("Resource name was too long.")
+}
@@ -118,6 +118,33 @@ func (h *exampleTooler) Requirements() string {
 	return common.RES_GPU
 }
 
+/*
+	Optionally, return the version of the form returned by Parameters().  When
+	the form changes in a way that would break the parameters of older jobs,
+	such as renaming a field or changing the values of a dropdown, raise the
+	version and add a migration from the previous one.  The queue translates
+	the parameters of templates and cloned jobs made with an older form through
+	each migration in turn before they are sent to the tool.
+*/
+func (h *exampleTooler) FormVersion() int {
+	return 1
+}
+
+/*
+	Return the steps to translate parameters from each older version of the form
+	to the next.  The example form has only had one version, a migration from
+	it would look like:
+
+		common.FormMigration{
+			From:     1,
+			Rename:   map[string]string{"wordlist": "dictionary"},
+			Defaults: map[string]string{"rules": "none"},
+		}
+*/
+func (h *exampleTooler) FormMigrations() []common.FormMigration {
+	return nil
+}
+
 /*
 	Start a new job by using the tasker for this tool
 */
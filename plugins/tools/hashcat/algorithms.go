@@ -89,6 +89,12 @@ var algorithms = []hashAlgorithm{
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "NetNTLMv2", Number: "5600"},
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "IPMI2 RAKP HMAC-SHA1", Number: "7300"},
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 AS-REQ Pre-Auth etype 23", Number: "7500"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 TGS-REP etype 23", Number: "13100"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 AS-REP etype 23", Number: "18200"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 TGS-REP etype 17 (AES128-CTS-HMAC-SHA1-96)", Number: "19600"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 TGS-REP etype 18 (AES256-CTS-HMAC-SHA1-96)", Number: "19700"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 AS-REP etype 17 (AES128-CTS-HMAC-SHA1-96)", Number: "32100"},
+	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Kerberos 5 AS-REP etype 18 (AES256-CTS-HMAC-SHA1-96)", Number: "32200"},
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "DNSSEC (NSEC3)", Number: "8300"},
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "Cram MD5", Number: "10200"},
 	hashAlgorithm{Group: "Network protocols, Challenge-Response", Name: "PostgreSQL Challenge-Response Authentication (MD5)", Number: "11100"},
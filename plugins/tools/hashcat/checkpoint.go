@@ -0,0 +1,79 @@
+package hashcat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+)
+
+// Get the restore point of the task from the last hashcat status
+func (v *hascatTasker) Checkpoint() (common.Checkpoint, error) {
+	// Call status to read the latest restore point
+	v.Status()
+
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if !v.skippable {
+		return common.Checkpoint{}, errors.New("Hashcat can not continue this attack from a checkpoint.")
+	}
+
+	cp := common.Checkpoint{
+		Taken:    time.Now(),
+		Progress: v.job.Progress,
+		Point:    v.point,
+	}
+	for _, row := range v.job.OutputData {
+		cp.Output = append(cp.Output, append([]string{}, row...))
+	}
+
+	return cp, nil
+}
+
+// Start the task from a checkpoint taken on another resource. The hashes
+// cracked before the checkpoint are kept as hashcat will skip past them.
+func (v *hascatTasker) Restore(cp common.Checkpoint) error {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if !v.skippable {
+		return errors.New("Hashcat can not continue this attack from a checkpoint.")
+	}
+
+	if v.job.Status != common.STATUS_CREATED {
+		return errors.New("Only tasks that have not been started can be restored.")
+	}
+
+	out, err := os.Create(filepath.Join(v.wd, "hashes-output.txt"))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// Results are read back from the output file as hash:plaintext
+	for _, row := range cp.Output {
+		if len(row) < 2 {
+			continue
+		}
+		out.WriteString(row[1] + ":" + row[0] + "\n")
+	}
+
+	if cp.Point > 0 {
+		v.start = append([]string{"--skip=" + strconv.FormatUint(cp.Point, 10)}, v.start...)
+	}
+	v.point = cp.Point
+	v.job.Progress = cp.Progress
+	v.job.OutputData = cp.Output
+
+	log.WithFields(log.Fields{
+		"task":  v.job.UUID,
+		"point": cp.Point,
+	}).Info("Task restored from checkpoint.")
+
+	return nil
+}
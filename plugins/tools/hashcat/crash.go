@@ -0,0 +1,75 @@
+package hashcat
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Errors hashcat prints when the GPU driver or device stopped working, rather
+// than a problem with the job itself such as a bad hash or missing file
+var driverCrashSignatures = []string{
+	"CUDA_ERROR_LAUNCH_FAILED",
+	"CUDA_ERROR_ILLEGAL_ADDRESS",
+	"CUDA_ERROR_UNKNOWN",
+	"CUDA_ERROR_ECC_UNCORRECTABLE",
+	"CUDA_ERROR_HARDWARE_STACK_ERROR",
+	"CUDA_ERROR_LAUNCH_TIMEOUT",
+	"CUDA_ERROR_NOT_INITIALIZED",
+	"CUDA_ERROR_NO_DEVICE",
+	"cuInit(): ",
+	"CL_OUT_OF_RESOURCES",
+	"CL_DEVICE_NOT_AVAILABLE",
+	"CL_INVALID_COMMAND_QUEUE",
+	"CL_PLATFORM_NOT_FOUND_KHR",
+	"hipErrorLaunchFailure",
+	"hipErrorIllegalAddress",
+	"hipErrorNoDevice",
+	"Kernel exec timeout",
+}
+
+// The crash signature found in the output of hashcat, empty if there was none
+func driverCrash(stderr string) string {
+	for _, sig := range driverCrashSignatures {
+		if strings.Contains(stderr, sig) {
+			return strings.TrimSpace(strings.TrimSuffix(sig, ": "))
+		}
+	}
+	return ""
+}
+
+// Run the configured reset script before hashcat is started again. Without a
+// script the devices are given a moment to recover on their own. The script is
+// given the task and signature in CRACKLORD_TASK and CRACKLORD_CRASH.
+func resetDevices(task, crash string) error {
+	if config.ResetScript == "" {
+		time.Sleep(config.ResetDelay)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ResetTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, config.ResetScript)
+	cmd.Env = append(os.Environ(), "CRACKLORD_TASK="+task, "CRACKLORD_CRASH="+crash)
+
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.New("The reset script did not finish within " + config.ResetTimeout.String() + ".")
+	}
+	if err != nil {
+		return errors.New(err.Error() + ": " + strings.TrimSpace(string(out)))
+	}
+
+	log.WithFields(log.Fields{
+		"task":   task,
+		"output": strings.TrimSpace(string(out)),
+	}).Info("GPU devices reset.")
+
+	return nil
+}
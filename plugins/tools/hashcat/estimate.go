@@ -0,0 +1,272 @@
+package hashcat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/shared"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Benchmarks can take a while so give each algorithm a generous limit
+const benchmarkTimeout = 5 * time.Minute
+
+// Characters covered by the built in hashcat charsets
+var builtinCharsets = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// Benchmark speeds in hashes per second by algorithm, these are only run once
+// for each algorithm as the hardware of a resource doesn't change
+var benchmarks = struct {
+	speeds map[string]float64
+	sync.Mutex
+}{speeds: map[string]float64{}}
+
+// Count the unique characters a hashcat charset definition such as ?l?d covers
+func charsetSize(mask string) uint64 {
+	chars := map[byte]bool{}
+	for i := 0; i < len(mask); i++ {
+		if mask[i] == '?' && i+1 < len(mask) {
+			i++
+			switch mask[i] {
+			case 'a':
+				for _, c := range []byte{'l', 'u', 'd', 's'} {
+					for _, b := range []byte(builtinCharsets[c]) {
+						chars[b] = true
+					}
+				}
+				continue
+			case 'b':
+				return 256
+			case '?':
+				chars['?'] = true
+				continue
+			}
+
+			for _, b := range []byte(builtinCharsets[mask[i]]) {
+				chars[b] = true
+			}
+			continue
+		}
+
+		chars[mask[i]] = true
+	}
+
+	return uint64(len(chars))
+}
+
+// Count the lines of a file, skipping comments if requested as used by rules
+func countLines(path string, skipComments bool) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var count uint64
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if skipComments && (line == "" || strings.HasPrefix(line, "#")) {
+			continue
+		}
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// Multiply two keyspaces, saturating instead of overflowing
+func mulKeyspace(a, b uint64) uint64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > math.MaxUint64/b {
+		return math.MaxUint64
+	}
+	return a * b
+}
+
+// Calculate the number of candidates the job parameters would generate
+func keyspace(params map[string]string) (uint64, error) {
+	if params["pre_preprocessor"] != "" {
+		return 0, errors.New("The keyspace of a preprocessor attack cannot be calculated.")
+	}
+
+	// Dictionary attack, the keyspace is every word with every rule
+	if dictKey := params["dict_dictionaries"]; dictKey != "" {
+		sort.Sort(config.Dictionaries)
+		i := sort.Search(len(config.Dictionaries), func(i int) bool { return config.Dictionaries[i].Name >= dictKey })
+		if i >= len(config.Dictionaries) || config.Dictionaries[i].Name != dictKey {
+			return 0, errors.New("Dictionary provided does not exist.")
+		}
+
+		dictPath, err := shared.Path(config.Dictionaries[i].Path)
+		if err != nil {
+			return 0, err
+		}
+
+		words, err := countLines(dictPath, false)
+		if err != nil {
+			return 0, err
+		}
+
+		rules := uint64(1)
+		if ruleFile := previewRuleFile(params); ruleFile != "" {
+			ruleFile, err = shared.Path(ruleFile)
+			if err != nil {
+				return 0, err
+			}
+
+			rules, err = countLines(ruleFile, true)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		return mulKeyspace(words, rules), nil
+	}
+
+	// Brute force attack, the keyspace is the charset to the power of the length
+	if charsetKey := params["brute_charset"]; charsetKey != "" {
+		sort.Sort(config.CharacterSets)
+		i := sort.Search(len(config.CharacterSets), func(i int) bool { return config.CharacterSets[i].Name >= charsetKey })
+		if i >= len(config.CharacterSets) || config.CharacterSets[i].Name != charsetKey {
+			return 0, errors.New("Brute force charset provided does not exist.")
+		}
+
+		length, err := strconv.Atoi(params["brute_length"])
+		if err != nil || length < 1 {
+			return 0, errors.New("Unable to parse the brute force length provided.")
+		}
+
+		increment, _ := strconv.ParseBool(params["brute_increment"])
+		size := charsetSize(strings.TrimSpace(config.CharacterSets[i].Mask))
+
+		var total uint64
+		var current uint64 = 1
+		for l := 1; l <= length; l++ {
+			current = mulKeyspace(current, size)
+			if increment || l == length {
+				if total > math.MaxUint64-current {
+					return math.MaxUint64, nil
+				}
+				total += current
+			}
+		}
+
+		return total, nil
+	}
+
+	return 0, errors.New("Arguments were not provided to allow estimating either a brute force or dictionary attack.")
+}
+
+// Run the hashcat benchmark for an algorithm and return the total speed of all
+// devices in hashes per second
+func benchmark(algorithm string) (float64, error) {
+	benchmarks.Lock()
+	defer benchmarks.Unlock()
+
+	if speed, ok := benchmarks.speeds[algorithm]; ok {
+		return speed, nil
+	}
+
+	cmd := exec.Command(binPath(), "-b", "-m", algorithm, "--machine-readable")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	timer := time.AfterFunc(benchmarkTimeout, func() {
+		cmd.Process.Kill()
+	})
+	err = cmd.Wait()
+	timer.Stop()
+	if err != nil {
+		return 0, errors.New("Unable to run benchmark: " + strings.TrimSpace(stderr.String()))
+	}
+
+	speed := parseBenchmark(stdout.String())
+	if speed == 0 {
+		return 0, errors.New("Unable to parse the benchmark output.")
+	}
+
+	log.WithFields(log.Fields{
+		"algorithm": algorithm,
+		"speed":     speed,
+	}).Info("Tool (hashcat): Benchmark complete")
+
+	benchmarks.speeds[algorithm] = speed
+	return speed, nil
+}
+
+// Machine readable benchmark lines are colon separated with the device number
+// first and the speed in hashes per second last
+func parseBenchmark(output string) float64 {
+	var total float64
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 3 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err != nil {
+			continue
+		}
+
+		speed, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil {
+			continue
+		}
+		total += speed
+	}
+
+	return total
+}
+
+// Estimate the keyspace of a job and how long it would take on this resource
+func (h *hashcatTooler) Estimate(params map[string]string) (common.Estimate, error) {
+	var est common.Estimate
+
+	var err error
+	est.Keyspace, err = keyspace(params)
+	if err != nil {
+		return est, err
+	}
+
+	algorithm, ok := params["algorithm"]
+	if !ok {
+		return est, errors.New("Could not find the algorithm provided.")
+	}
+
+	// Without benchmark data the keyspace is still useful so don't fail
+	est.Speed, err = benchmark(algorithm)
+	if err != nil {
+		log.WithField("error", err.Error()).Warn("Tool (hashcat): Unable to benchmark algorithm")
+		return est, nil
+	}
+
+	est.Seconds = float64(est.Keyspace) / est.Speed
+
+	return est, nil
+}
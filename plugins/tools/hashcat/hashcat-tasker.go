@@ -19,9 +19,16 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/shared"
 	"sort"
 )
 
+// Number of lines of hashcat output kept for debugging
+const outputLines = 500
+
+// Number of lines kept for jobs being debugged, which is all of it for most jobs
+const debugOutputLines = 100000
+
 var regLastStatusIndex *regexp.Regexp
 var regStatus *regexp.Regexp
 var regRuleType *regexp.Regexp
@@ -35,6 +42,7 @@ var regRecovered *regexp.Regexp
 var regProgress *regexp.Regexp
 var regRejected *regexp.Regexp
 var regGPUHWMon *regexp.Regexp
+var regRestorePoint *regexp.Regexp
 
 var regGetGPUCount *regexp.Regexp
 var regGetNumerator *regexp.Regexp
@@ -84,6 +92,7 @@ func init() {
 	regProgress, err = regexp.Compile(`Progress\.{7}: (\d*)/(\d*) \((\d{1,3}\.\d{2})%\)`)
 	regRejected, err = regexp.Compile(`(Rejected)\.\.\.\.\.\.\.\:\s+(\d+\/\d+.+)`)
 	regGPUHWMon, err = regexp.Compile(`(HWMon\.GPU\.#\d+)\.\.\.\:\s+(.+)`)
+	regRestorePoint, err = regexp.Compile(`Restore\.Point\.+:\s+(\d+)/(\d+)`)
 
 	regGetGPUCount, err = regexp.Compile(`\#(\d)`)
 	regGetNumerator, err = regexp.Compile(`(\d+\)/\d+`)
@@ -109,8 +118,25 @@ type hascatTasker struct {
 	stdoutPipe io.ReadCloser
 	stdinPipe  io.WriteCloser
 
+	// Optional preprocessor piped into the stdin of hashcat
+	preArgs []string
+	preCmd  *exec.Cmd
+
+	// Recent output kept for debugging as stdout is cleared on each status
+	output *common.LineTail
+
+	// Restore point from the last status, only used when hashcat can skip to it
+	point     uint64
+	skippable bool
+
 	waitChan chan struct{}
 
+	// Set while Pause or Quit stop hashcat so its exit is not taken as a crash
+	stopping bool
+	retried  int    // Restarts after GPU driver crashes
+	crash    string // Why the job failed, put before stderr in the job error
+	bin      string // Binary the task was first started with, resumes keep using it
+
 	mux sync.Mutex
 }
 
@@ -131,6 +157,11 @@ func createWorkingDir(workdir, uuid string) (string, error) {
 func newHashcatTask(j common.Job) (common.Tasker, error) {
 	h := hascatTasker{}
 	h.waitChan = make(chan struct{}, 1)
+	if j.Debug {
+		h.output = common.NewLineTail(debugOutputLines)
+	} else {
+		h.output = common.NewLineTail(outputLines)
+	}
 
 	h.job = j
 
@@ -231,6 +262,41 @@ func newHashcatTask(j common.Job) (common.Tasker, error) {
 			dictPath = newDictPath
 		}*/
 
+	/****************************************************************************
+	* PREPROCESSOR ATTACK
+	****************************************************************************/
+	preKey, ok := h.job.Parameters["pre_preprocessor"]
+	if ok && preKey != "" {
+		sort.Sort(config.Preprocessors)
+		i := sort.Search(len(config.Preprocessors), func(i int) bool { return config.Preprocessors[i].Name >= preKey })
+		if i < len(config.Preprocessors) && config.Preprocessors[i].Name == preKey {
+			// Find the dictionary to hand to the preprocessor, if any
+			var preDict string
+			if preDictKey, ok := h.job.Parameters["pre_dictionaries"]; ok {
+				j := sort.Search(len(config.Dictionaries), func(j int) bool { return config.Dictionaries[j].Name >= preDictKey })
+				if j < len(config.Dictionaries) && config.Dictionaries[j].Name == preDictKey {
+					preDict, err = shared.Path(config.Dictionaries[j].Path)
+					if err != nil {
+						log.WithField("error", err.Error()).Error("Unable to get the shared dictionary.")
+						return &hascatTasker{}, err
+					}
+				}
+			}
+
+			h.preArgs = config.Preprocessors[i].Args(preDict)
+		} else {
+			log.Debug("Preprocessor key provided was not present")
+		}
+
+		// Rules from the preprocessor tab replace any from the dictionary tab
+		if preRuleKey, ok := h.job.Parameters["pre_rules"]; ok {
+			j := sort.Search(len(config.Rules), func(j int) bool { return config.Rules[j].Name >= preRuleKey })
+			if j < len(config.Rules) && config.Rules[j].Name == preRuleKey {
+				ruleFile = config.Rules[j].Path
+			}
+		}
+	}
+
 	/****************************************************************************
 	* BRUTE FORCE ATTACK
 	****************************************************************************/
@@ -262,6 +328,27 @@ func newHashcatTask(j common.Job) (common.Tasker, error) {
 		}
 	}
 
+	var markovModel string
+	markovKey, ok := h.job.Parameters["brute_markov"]
+	if ok && markovKey != "" {
+		sort.Sort(config.MarkovModels)
+		i := sort.Search(len(config.MarkovModels), func(i int) bool { return config.MarkovModels[i].Name >= markovKey })
+		if i < len(config.MarkovModels) && config.MarkovModels[i].Name == markovKey {
+			markovModel = config.MarkovModels[i].Path
+		} else {
+			log.Debug("Markov model provided does not exist")
+		}
+	}
+
+	// Files kept in the shared bucket are downloaded to the local cache
+	for _, p := range []*string{&dictPath, &ruleFile, &markovModel} {
+		*p, err = shared.Path(*p)
+		if err != nil {
+			log.WithField("error", err.Error()).Error("Unable to get the shared file.")
+			return &hascatTasker{}, err
+		}
+	}
+
 	var bruteIncrement bool
 	bruteIncrementString, ok := h.job.Parameters["brute_increment"]
 	if !ok {
@@ -277,12 +364,20 @@ func newHashcatTask(j common.Job) (common.Tasker, error) {
 	args = append(args, "-m", htype)                                    // Algorithm
 	args = append(args, "--status", "--status-timer=20")                // Status type and forcing of output
 	args = append(args, "-o", filepath.Join(h.wd, "hashes-output.txt")) // Output file
+	args = append(args, workloadArg(h.job.Parameters, workloadLimit())...) // Workload profile
 
 	if config.Arguments != "" {
 		args = append(args, config.Arguments) // Config file arguments
 	}
+	args = append(args, h.job.ExtraArgs...) // Administrator arguments for this job
 
-	if dictPath != "" {
+	if len(h.preArgs) > 0 {
+		// Without a dictionary hashcat reads candidates from stdin
+		if ruleFile != "" {
+			args = append(args, "-r", ruleFile) // Rules file
+		}
+		args = append(args, filepath.Join(h.wd, "hashes.txt")) // Input file
+	} else if dictPath != "" {
 		if ruleFile != "" {
 			args = append(args, "-r", ruleFile) // Rules file
 		}
@@ -290,6 +385,9 @@ func newHashcatTask(j common.Job) (common.Tasker, error) {
 		args = append(args, dictPath)                          // Dictionary file
 	} else if bruteCharSet != "" && bruteLength != "" {
 		args = append(args, "-a", "3")
+		if markovModel != "" {
+			args = append(args, markovArg(markovModel)) // Markov model
+		}
 		args = append(args, filepath.Join(h.wd, "hashes.txt")) // Input file
 		args = append(args, "-1", bruteCharSet)
 		if bruteIncrement {
@@ -317,8 +415,12 @@ func newHashcatTask(j common.Job) (common.Tasker, error) {
 	h.start = append(h.start, args...)
 	h.resume = append(h.resume, args...)
 
+	// Hashcat can not skip ahead in candidates read from stdin or with increment
+	h.skippable = len(h.preArgs) == 0 && !bruteIncrement
+
 	// Configure the return values
 	h.job.OutputTitles = []string{"Plaintext", "Hash"}
+	h.job.OutputMetrics = []common.Metric{common.PlaintextColumn, common.HashColumn}
 
 	return &h, nil
 }
@@ -381,6 +483,13 @@ func (v *hascatTasker) Status() common.Job {
 			}
 		}
 
+		pointMatch := regRestorePoint.FindStringSubmatch(status)
+		if len(pointMatch) == 3 {
+			if p, err := strconv.ParseUint(pointMatch[1], 10, 64); err == nil {
+				v.point = p
+			}
+		}
+
 		etcMatch := regTimeEstimated.FindStringSubmatch(status)
 		log.WithField("etcMatch", etcMatch).Debug("Matching estimated time of completion.")
 		if len(etcMatch) == 2 {
@@ -400,6 +509,7 @@ func (v *hascatTasker) Status() common.Job {
 					if v.job.PerformanceTitle == "" {
 						// We don't so just take the one provided
 						v.job.PerformanceTitle = speedString[3]
+						v.job.PerformanceMetric = common.RateMetric(speedString[3])
 
 						v.job.PerformanceData[timestamp] = speedString[2]
 					} else {
@@ -441,6 +551,7 @@ func (v *hascatTasker) Status() common.Job {
 				if v.job.PerformanceTitle == "" {
 					// We don't so just take the one provided
 					v.job.PerformanceTitle = speedString[3]
+					v.job.PerformanceMetric = common.RateMetric(speedString[3])
 
 					v.job.PerformanceData[timestamp] = speedString[2]
 				} else {
@@ -509,9 +620,10 @@ func (v *hascatTasker) Status() common.Job {
 		"Stderr": v.stderr,
 	}).Debug("Stdout & Stderr")
 
+	v.output.Write(v.stdout.Bytes())
 	v.stdout.Reset()
 
-	v.job.Error = v.stderr.String()
+	v.job.Error = v.crash + v.stderr.String()
 
 	log.WithFields(log.Fields{
 		"task":   v.job.UUID,
@@ -538,14 +650,34 @@ func (v *hascatTasker) Run() error {
 		return nil
 	}
 
-	// Set commands for restore or start
-	if v.job.Status == common.STATUS_CREATED {
-		v.cmd = *exec.Command(config.BinPath, v.start...)
+	err := v.launch()
+	if err != nil {
+		return err
+	}
+
+	v.job.StartTime = time.Now()
+
+	return nil
+}
+
+// Start hashcat, resuming the session unless the job has not been started.
+// THE TASK LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (v *hascatTasker) launch() error {
+	// A session is restored by the build of hashcat that saved it
+	if v.bin == "" {
+		v.bin = binPath()
+	}
+
+	// Set commands for restore or start. Hashcat is unable to restore a session
+	// reading from stdin, so preprocessor jobs always start from the beginning.
+	if v.job.Status == common.STATUS_CREATED || len(v.preArgs) > 0 {
+		v.cmd = *exec.Command(v.bin, v.start...)
 	} else {
-		v.cmd = *exec.Command(config.BinPath, v.resume...)
+		v.cmd = *exec.Command(v.bin, v.resume...)
 	}
 
 	v.cmd.Dir = v.wd
+	v.cmd.Env = v.job.CommandEnv()
 
 	log.WithFields(log.Fields{
 		"status": v.job.Status,
@@ -564,9 +696,22 @@ func (v *hascatTasker) Run() error {
 		return err
 	}
 
-	v.stdinPipe, err = v.cmd.StdinPipe()
-	if err != nil {
-		return err
+	// Either the preprocessor or the tasker is connected to stdin of hashcat
+	if len(v.preArgs) > 0 {
+		v.preCmd = exec.Command(v.preArgs[0], v.preArgs[1:]...)
+		v.preCmd.Dir = v.wd
+		v.preCmd.Env = v.cmd.Env
+
+		v.cmd.Stdin, err = v.preCmd.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		v.stdinPipe = nil
+	} else {
+		v.stdinPipe, err = v.cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
 	}
 
 	v.stderr = bytes.NewBuffer([]byte(""))
@@ -583,6 +728,17 @@ func (v *hascatTasker) Run() error {
 	// 	}
 	// }()
 
+	// Start the preprocessor first so it is ready to feed hashcat
+	if v.preCmd != nil {
+		log.WithField("argument", v.preCmd.Args).Debug("Running preprocessor.")
+		err = v.preCmd.Start()
+		if err != nil {
+			v.job.Status = common.STATUS_FAILED
+			log.Errorf("There was an error starting the preprocessor: %v", err)
+			return err
+		}
+	}
+
 	// Start the command
 	log.WithField("argument", v.cmd.Args).Debug("Running command.")
 	err = v.cmd.Start()
@@ -590,28 +746,107 @@ func (v *hascatTasker) Run() error {
 		// We had an error starting to return that and quit the job
 		v.job.Status = common.STATUS_FAILED
 		log.Errorf("There was an error starting the job: %v", err)
+		v.stopPreprocessor()
 		return err
 	}
 
-	v.job.StartTime = time.Now()
 	v.job.Status = common.STATUS_RUNNING
+	v.stopping = false
 
-	// Build goroutine to alert that the job has finished
-	go func() {
-		// Listen on commmand wait and then send signal when finished
-		// This will be read on the Status() function
-		v.cmd.Wait()
+	if v.job.Debug {
+		v.output.Write([]byte("$ " + strings.Join(v.cmd.Args, " ") + "\n"))
+	}
 
-		v.mux.Lock()
-		v.job.Status = common.STATUS_DONE
-		v.job.Progress = 100.00
-		v.waitChan <- struct{}{}
-		v.mux.Unlock()
-	}()
+	// Build goroutine to alert that the job has finished
+	go v.wait()
 
 	return nil
 }
 
+// Wait for hashcat to exit and signal Pause and Quit that it has. When it was
+// not stopped and died from a GPU driver crash the devices are reset and the
+// session resumed, up to the configured number of retries, before the job is
+// failed.
+func (v *hascatTasker) wait() {
+	err := v.cmd.Wait()
+
+	// Hashcat is finished with the candidates so stop the preprocessor
+	v.stopPreprocessor()
+
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if v.job.Debug && v.cmd.ProcessState != nil {
+		v.output.Write([]byte("hashcat exited: " + v.cmd.ProcessState.String() + "\n"))
+	}
+
+	if err != nil && !v.stopping {
+		if crash := driverCrash(v.stderr.String()); crash != "" {
+			if v.retried < config.CrashRetries && v.recover(crash) {
+				return
+			}
+
+			v.job.Status = common.STATUS_FAILED
+			v.crash = "GPU driver crash (" + crash + "): "
+			v.waitChan <- struct{}{}
+			return
+		}
+	}
+
+	v.job.Status = common.STATUS_DONE
+	v.job.Progress = 100.00
+	v.waitChan <- struct{}{}
+}
+
+// Reset the devices and resume the session after a driver crash, false when
+// the job could not be started again.
+// THE TASK LOCK SHOULD ALREADY BE HELD TO CALL THIS FUNCTION.
+func (v *hascatTasker) recover(crash string) bool {
+	v.retried++
+
+	tasklog := log.WithFields(log.Fields{
+		"task":    v.job.UUID,
+		"crash":   crash,
+		"attempt": v.retried,
+	})
+	tasklog.Warn("Hashcat died from a GPU driver crash, resetting the devices.")
+
+	// The reset can take a while so Status, Pause and Quit are not held up
+	v.mux.Unlock()
+	err := resetDevices(v.job.UUID, crash)
+	v.mux.Lock()
+
+	if err != nil {
+		tasklog.WithField("error", err.Error()).Error("Unable to reset the GPU devices.")
+		return false
+	}
+	if v.stopping {
+		return false
+	}
+
+	// Resume from the session hashcat restores from instead of the beginning
+	v.job.Status = common.STATUS_PAUSED
+	if err := v.launch(); err != nil {
+		tasklog.WithField("error", err.Error()).Error("Unable to restart hashcat after the GPU driver crash.")
+		return false
+	}
+
+	tasklog.Info("Hashcat restarted after the GPU driver crash.")
+	return true
+}
+
+// Stop the preprocessor if one is running
+func (v *hascatTasker) stopPreprocessor() {
+	if v.preCmd == nil || v.preCmd.Process == nil {
+		return
+	}
+
+	v.preCmd.Process.Kill()
+	v.preCmd.Wait()
+
+	log.WithField("task", v.job.UUID).Debug("Preprocessor stopped")
+}
+
 // Pause the hashcat run
 func (v *hascatTasker) Pause() error {
 	log.WithField("task", v.job.UUID).Debug("Attempting to pause hashcat task")
@@ -621,6 +856,7 @@ func (v *hascatTasker) Pause() error {
 
 	if v.job.Status == common.STATUS_RUNNING {
 		v.mux.Lock()
+		v.stopping = true
 
 		if runtime.GOOS == "windows" {
 			v.cmd.Process.Kill()
@@ -652,6 +888,7 @@ func (v *hascatTasker) Quit() common.Job {
 
 	if v.job.Status == common.STATUS_RUNNING {
 		v.mux.Lock()
+		v.stopping = true
 
 		if runtime.GOOS == "windows" {
 			v.cmd.Process.Kill()
@@ -674,6 +911,32 @@ func (v *hascatTasker) Quit() common.Job {
 	return v.job
 }
 
+// Get the last lines of output from hashcat followed by the end of stderr
+func (v *hascatTasker) Logs(lines int) []string {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	out := v.output.Lines(lines)
+
+	if v.stderr != nil && v.stderr.Len() > 0 {
+		errLines := strings.Split(strings.TrimSpace(v.stderr.String()), "\n")
+		if lines > 0 && len(errLines) > lines {
+			errLines = errLines[len(errLines)-lines:]
+		}
+
+		for _, l := range errLines {
+			out = append(out, "stderr: "+l)
+		}
+	}
+
+	return out
+}
+
 func (v *hascatTasker) IOE() (io.Writer, io.Reader, io.Reader) {
 	return v.stdinPipe, v.stdoutPipe, v.stderrPipe
 }
+
+// The outfile hashcat writes cracked hashes to
+func (v *hascatTasker) ResultFile() string {
+	return filepath.Join(v.wd, "hashes-output.txt")
+}
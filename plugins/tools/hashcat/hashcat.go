@@ -1,12 +1,21 @@
 package hashcat
 
 import (
+	"context"
 	"errors"
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmmcatee/cracklord/common"
+	"github.com/jmmcatee/cracklord/common/shared"
 	"github.com/jmmcatee/goschemaform"
 	"github.com/vaughan0/go-ini"
+	"os"
+	"os/exec"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type hcConfig struct {
@@ -16,12 +25,77 @@ type hcConfig struct {
 	Dictionaries  dictionaries
 	Rules         rules
 	CharacterSets charactersets
+	Preprocessors preprocessors
+	MarkovModels  dictionaries
+
+	// Recovery from GPU driver crashes
+	CrashRetries int
+	ResetScript  string
+	ResetTimeout time.Duration
+	ResetDelay   time.Duration // Wait before restarting when there is no reset script
+
+	Version string // Reported by the binary, empty if it could not be run
+}
+
+var defaultConfig = hcConfig{
+	BinPath:      "",
+	WorkDir:      "",
+	Arguments:    "",
+	CrashRetries: 1,
+	ResetTimeout: 2 * time.Minute,
+	ResetDelay:   10 * time.Second,
+}
+
+var config = defaultConfig
+
+// The file the config was read from, kept so tools can be scanned again
+var configPath string
+
+// Binary installed from the repository of the queue, run instead of the one in
+// the config file when set
+var managed struct {
+	sync.RWMutex
+	path    string
+	version string
+}
+
+// The hashcat binary new tasks run
+func binPath() string {
+	managed.RLock()
+	defer managed.RUnlock()
+
+	if managed.path != "" {
+		return managed.path
+	}
+	return config.BinPath
+}
+
+// Check a configured file is on this resource. Files in the shared bucket
+// are downloaded when a job needs them.
+func fileExists(path string) bool {
+	if shared.IsShared(path) {
+		return true
+	}
+
+	_, err := os.Stat(path)
+	return err == nil
 }
 
-var config = hcConfig{
-	BinPath:   "",
-	WorkDir:   "",
-	Arguments: "",
+// Ask the hashcat binary for its version, such as 6.2.6 from v6.2.6
+func binaryVersion(binPath string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, binPath, "--version").Output()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"binpath": binPath,
+			"error":   err.Error(),
+		}).Warn("Unable to read the hashcat version.")
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "v")
 }
 
 /*
@@ -29,6 +103,10 @@ var config = hcConfig{
 */
 func Setup(path string) error {
 	log.Debug("Setting up hashcat tool")
+	// Everything is read again into a new config so a rescan that fails
+	// leaves the one in use alone
+	c := defaultConfig
+
 	// Join the path provided
 	confFile, err := ini.LoadFile(path)
 	if err != nil {
@@ -45,14 +123,34 @@ func Setup(path string) error {
 		// Nothing retrieved, so return error
 		return errors.New("No \"Basic\" configuration section.")
 	}
-	config.BinPath = basic["binPath"]
-	config.WorkDir = basic["workingdir"]
-	config.Arguments = basic["arguments"]
+	c.BinPath = basic["binPath"]
+	c.WorkDir = basic["workingdir"]
+	c.Arguments = basic["arguments"]
+
+	// Jobs are restarted after a GPU driver crash this many times, running the
+	// reset script first if one is set
+	if v := basic["crashretries"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.WithField("crashretries", v).Error("Unable to parse crash retries in configuration file.")
+		} else {
+			c.CrashRetries = n
+		}
+	}
+	c.ResetScript = basic["resetscript"]
+	if v := basic["resettimeout"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.WithField("resettimeout", v).Error("Unable to parse reset timeout in configuration file.")
+		} else {
+			c.ResetTimeout = time.Duration(n) * time.Second
+		}
+	}
 
 	log.WithFields(log.Fields{
-		"binpath":   config.BinPath,
-		"WorkDir":   config.WorkDir,
-		"Arguments": config.Arguments,
+		"binpath":   c.BinPath,
+		"WorkDir":   c.WorkDir,
+		"Arguments": c.Arguments,
 	}).Debug("Basic configuration complete")
 
 	// Get the dictionary section
@@ -63,11 +161,19 @@ func Setup(path string) error {
 		return errors.New("No \"Dictionaries\" configuration section.")
 	}
 	for key, value := range dicts {
+		// Job forms only offer files that are on this resource
+		if !fileExists(value) {
+			log.WithFields(log.Fields{
+				"name": key,
+				"path": value,
+			}).Warn("Dictionary does not exist, it will not be offered.")
+			continue
+		}
 		log.WithFields(log.Fields{
 			"name": key,
 			"path": value,
 		}).Debug("Added dictionary")
-		config.Dictionaries = append(config.Dictionaries, dictionary{Name: key, Path: value})
+		c.Dictionaries = append(c.Dictionaries, dictionary{Name: key, Path: value})
 	}
 
 	// Get the rule section
@@ -78,11 +184,18 @@ func Setup(path string) error {
 		return errors.New("No \"Rules\" configuration section.")
 	}
 	for key, value := range rules {
+		if !fileExists(value) {
+			log.WithFields(log.Fields{
+				"name": key,
+				"path": value,
+			}).Warn("Rule file does not exist, it will not be offered.")
+			continue
+		}
 		log.WithFields(log.Fields{
 			"name": key,
 			"path": value,
 		}).Debug("Added rule")
-		config.Rules = append(config.Rules, rule{Name: key, Path: value})
+		c.Rules = append(c.Rules, rule{Name: key, Path: value})
 	}
 
 	// Store the character sets configured for brute forcing in the config file
@@ -97,18 +210,75 @@ func Setup(path string) error {
 			"name": key,
 			"path": value,
 		}).Debug("Added charset to hashcat")
-		config.CharacterSets = append(config.CharacterSets, characterset{Name: key, Mask: value})
+		c.CharacterSets = append(c.CharacterSets, characterset{Name: key, Mask: value})
+	}
+
+	// Preprocessors are optional, they feed candidates to hashcat through stdin
+	preprocs := confFile.Section("Preprocessors")
+	for key, value := range preprocs {
+		log.WithFields(log.Fields{
+			"name":    key,
+			"command": value,
+		}).Debug("Added preprocessor to hashcat")
+		c.Preprocessors = append(c.Preprocessors, preprocessor{Name: key, Command: value})
+	}
+
+	// Custom markov models are optional, each is a .hcstat or .hcstat2 file
+	models := confFile.Section("Markov")
+	for key, value := range models {
+		log.WithFields(log.Fields{
+			"name": key,
+			"path": value,
+		}).Debug("Added markov model to hashcat")
+		c.MarkovModels = append(c.MarkovModels, dictionary{Name: key, Path: value})
+	}
+
+	// Models in the markov folder are offered as well, unless one of the same
+	// name is set above
+	if dir := basic["markovdir"]; dir != "" {
+		for _, m := range markovModels(dir) {
+			if _, ok := models[m.Name]; ok {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"name": m.Name,
+				"path": m.Path,
+			}).Debug("Added markov model from the markov folder to hashcat")
+			c.MarkovModels = append(c.MarkovModels, m)
+		}
 	}
 
+	// The version of the binary is reported with the tool so upgrades show up
+	c.Version = binaryVersion(c.BinPath)
+
+	config = c
+	configPath = path
+
 	log.Info("Hashcat tool successfully setup")
 
 	return nil
 }
 
+// Highest workload level new tasks run at, set by the profile of the resource
+var workload int32
+
+func workloadLimit() int {
+	return int(atomic.LoadInt32(&workload))
+}
+
 type hashcatTooler struct {
 	toolUUID string
 }
 
+// Limit the workload of new tasks, running tasks keep the workload they were
+// started with as hashcat cannot change it
+func (h *hashcatTooler) SetWorkload(level int) {
+	if level < 0 || level > 4 {
+		level = 0
+	}
+	atomic.StoreInt32(&workload, int32(level))
+}
+
 func (h *hashcatTooler) Name() string {
 	return "oclHashcat/cudaHashcat"
 }
@@ -118,9 +288,52 @@ func (h *hashcatTooler) Type() string {
 }
 
 func (h *hashcatTooler) Version() string {
+	managed.RLock()
+	defer managed.RUnlock()
+
+	if managed.version != "" {
+		return managed.version
+	}
+	if config.Version != "" {
+		return config.Version
+	}
 	return "2.01"
 }
 
+func (h *hashcatTooler) BinaryName() string {
+	return "hashcat"
+}
+
+// Run a binary installed from the queue for new tasks instead of the one in
+// the config file, as long as it runs on this resource
+func (h *hashcatTooler) SetBinary(path, version string) error {
+	detected := binaryVersion(path)
+	if detected == "" {
+		return errors.New("The hashcat binary " + path + " could not be run.")
+	}
+
+	managed.Lock()
+	managed.path = path
+	managed.version = detected
+	managed.Unlock()
+
+	log.WithFields(log.Fields{
+		"path":    path,
+		"version": detected,
+	}).Info("Hashcat binary from the queue in use.")
+
+	return nil
+}
+
+// Read the config file again to pick up a new binary, wordlists and rules
+// without restarting the resource. Tasks already running are not touched.
+func (h *hashcatTooler) Rescan() error {
+	if configPath == "" {
+		return errors.New("Hashcat has not been setup.")
+	}
+	return Setup(configPath)
+}
+
 func (h *hashcatTooler) UUID() string {
 	return h.toolUUID
 }
@@ -146,6 +359,21 @@ func (h *hashcatTooler) Parameters() string {
 	// Add the dropdown to the form at the top
 	hashcatForm.AddElement(algoInput)
 
+	// Higher workload profiles are faster but need more GPU memory
+	workloadDropDown := goschemaform.NewDropDownInput("workload")
+	workloadDropDown.SetTitle("Select workload profile")
+	for _, w := range []struct{ value, name string }{
+		{"1", "Low"},
+		{"2", "Default"},
+		{"3", "High"},
+		{"4", "Nightmare"},
+	} {
+		option := goschemaform.NewDropDownInputOption(w.value)
+		option.SetName(w.name)
+		workloadDropDown.AddOption(option)
+	}
+	hashcatForm.AddElement(workloadDropDown)
+
 	// Build the fieldset for tabs based on attack type (Dictionary vs Bruteforce)
 	attackTypeFieldset := goschemaform.NewTabFieldset()
 	attackTypeFieldset.SetTitle("Attack Type")
@@ -200,9 +428,53 @@ func (h *hashcatTooler) Parameters() string {
 	}
 	// Add the dropdown to the tab
 	bruteForceTab.AddElement(bfCharSetDropDown)
+	// Custom markov models can change the order candidates are tried in
+	if len(config.MarkovModels) > 0 {
+		markovDropDown := goschemaform.NewDropDownInput("brute_markov")
+		markovDropDown.SetTitle("Select markov model (optional)")
+		sort.Sort(config.MarkovModels)
+		for i := range config.MarkovModels {
+			option := goschemaform.NewDropDownInputOption(config.MarkovModels[i].Name)
+			markovDropDown.AddOption(option)
+		}
+		bruteForceTab.AddElement(markovDropDown)
+	}
 	// Add the tab to the Attack Type fieldset
 	attackTypeFieldset.AddTab(bruteForceTab)
 
+	// Build the preprocessor attack tab if any preprocessors are configured
+	if len(config.Preprocessors) > 0 {
+		preprocessorTab := goschemaform.NewTab()
+		preprocessorTab.SetTitle("Preprocessor")
+		// Setup the dropdown for choosing the preprocessor
+		preDropDown := goschemaform.NewDropDownInput("pre_preprocessor")
+		preDropDown.SetTitle("Select preprocessor to use")
+		sort.Sort(config.Preprocessors)
+		for i := range config.Preprocessors {
+			option := goschemaform.NewDropDownInputOption(config.Preprocessors[i].Name)
+			preDropDown.AddOption(option)
+		}
+		preprocessorTab.AddElement(preDropDown)
+		// Setup the dropdown for the dictionary given to the preprocessor
+		preDictDropDown := goschemaform.NewDropDownInput("pre_dictionaries")
+		preDictDropDown.SetTitle("Select dictionary for the preprocessor")
+		for i := range config.Dictionaries {
+			option := goschemaform.NewDropDownInputOption(config.Dictionaries[i].Name)
+			preDictDropDown.AddOption(option)
+		}
+		preprocessorTab.AddElement(preDictDropDown)
+		// Rules can still be applied to the candidates from the preprocessor
+		preRuleDropDown := goschemaform.NewDropDownInput("pre_rules")
+		preRuleDropDown.SetTitle("Select rule file to use")
+		for i := range config.Rules {
+			option := goschemaform.NewDropDownInputOption(config.Rules[i].Name)
+			preRuleDropDown.AddOption(option)
+		}
+		preprocessorTab.AddElement(preRuleDropDown)
+		// Add the tab to the Attack Type fieldset
+		attackTypeFieldset.AddTab(preprocessorTab)
+	}
+
 	// Add the tab fieldset to the form
 	hashcatForm.AddElement(attackTypeFieldset)
 
@@ -1,7 +1,14 @@
 package hashcat
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/jmmcatee/cracklord/common"
 )
 
 func TestHashesPerSecondParsing(t *testing.T) {
@@ -47,3 +54,280 @@ Speed.GPU.#*...:   559.4 kH/s`
 		t.Errorf("Parsing is not correct for MH/s. Length of parse was %d.\n", len(parsedStringSlice))
 	}
 }
+
+func TestPreprocessorArgs(t *testing.T) {
+	p := preprocessor{Name: "PRINCE", Command: "/usr/bin/pp64.bin --pw-min=6  --wordlist={dictionary}"}
+
+	args := p.Args("/mnt/dicts/rockyou.txt")
+	if len(args) != 3 {
+		t.Fatalf("Expected 3 arguments but got %d: %v", len(args), args)
+	}
+
+	if args[0] != "/usr/bin/pp64.bin" || args[2] != "--wordlist=/mnt/dicts/rockyou.txt" {
+		t.Errorf("Preprocessor arguments were not built correctly: %v", args)
+	}
+}
+
+func TestCharsetSize(t *testing.T) {
+	tests := map[string]uint64{
+		"?l":     26,
+		"?l?d":   36,
+		"?a":     95,
+		"?d?h":   16,
+		"abc?d":  13,
+		"?b":     256,
+		"?u?u?s": 59,
+	}
+
+	for mask, expected := range tests {
+		if size := charsetSize(mask); size != expected {
+			t.Errorf("Expected charset %s to have %d characters but got %d", mask, expected, size)
+		}
+	}
+}
+
+func TestParseBenchmark(t *testing.T) {
+	output := "1:1000:1:1:25.13:1000000\n2:1000:1:1:25.13:500000\nStarted: today\n"
+	if speed := parseBenchmark(output); speed != 1500000 {
+		t.Errorf("Expected a speed of 1500000 but got %f", speed)
+	}
+}
+
+func TestJobRequirements(t *testing.T) {
+	h := &hashcatTooler{}
+
+	c, _ := h.JobRequirements(map[string]string{"algorithm": "1000", "hashes": "a\nb"})
+	if !c.IsZero() {
+		t.Errorf("NTLM should not need any special hardware, got %+v", c)
+	}
+
+	c, _ = h.JobRequirements(map[string]string{"algorithm": "8900", "workload": "3", "hashes": "a"})
+	if c.MinGPUMemory != 4096 {
+		t.Errorf("Expected scrypt at workload 3 to need 4096 MB of VRAM but got %d", c.MinGPUMemory)
+	}
+
+	if args := workloadArg(map[string]string{"workload": "9"}, 0); args != nil {
+		t.Errorf("An invalid workload should not be passed to hashcat, got %v", args)
+	}
+
+	// A profile limit lowers the workload but never raises it
+	if args := workloadArg(map[string]string{"workload": "4"}, 1); len(args) != 2 || args[1] != "1" {
+		t.Errorf("Expected the profile to limit the workload to 1, got %v", args)
+	}
+	if args := workloadArg(map[string]string{"workload": "1"}, 3); len(args) != 2 || args[1] != "1" {
+		t.Errorf("Expected the job workload of 1 to be kept, got %v", args)
+	}
+	if args := workloadArg(map[string]string{}, 2); len(args) != 2 || args[1] != "2" {
+		t.Errorf("Expected the profile workload for a job without one, got %v", args)
+	}
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	wd, err := ioutil.TempDir("", "hashcat-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(wd)
+
+	v := &hascatTasker{
+		job:       common.Job{Status: common.STATUS_CREATED},
+		wd:        wd,
+		start:     []string{"--session=test", "-m", "1000"},
+		skippable: true,
+	}
+
+	err = v.Restore(common.Checkpoint{
+		Progress: 40,
+		Point:    123456,
+		Output:   [][]string{{"Password1", "64f12cddaa88057e06a81b54e73b949b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.start[0] != "--skip=123456" {
+		t.Errorf("Expected hashcat to skip to the restore point but got %v", v.start)
+	}
+
+	out, _ := ioutil.ReadFile(filepath.Join(wd, "hashes-output.txt"))
+	if string(out) != "64f12cddaa88057e06a81b54e73b949b:Password1\n" {
+		t.Errorf("Cracked hashes were not restored, got %q", out)
+	}
+
+	v.skippable = false
+	if v.Restore(common.Checkpoint{}) == nil {
+		t.Error("Attacks that can not skip ahead should not be restored")
+	}
+}
+
+func TestCheckInput(t *testing.T) {
+	h := &hashcatTooler{}
+	ntlm := "8846f7eaee8fb117ad06bdcc6fdd1773"
+	sha1 := "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"
+
+	tests := []struct {
+		params   map[string]string
+		code     string
+		severity string
+	}{
+		{map[string]string{"algorithm": "1000", "hashes": ntlm + "\n" + ntlm}, "", ""},
+		{map[string]string{"algorithm": "1000", "hashes": sha1 + "\n" + sha1}, "hashes.length", common.ISSUE_ERROR},
+		{map[string]string{"algorithm": "1000", "hashes": ntlm + "\n" + sha1}, "hashes.length", common.ISSUE_WARNING},
+		{map[string]string{"algorithm": "1000", "hashes": "admin:" + ntlm}, "hashes.usernames", common.ISSUE_ERROR},
+		{map[string]string{"algorithm": "10", "hashes": ntlm}, "hashes.salt", common.ISSUE_ERROR},
+		{map[string]string{"algorithm": "1800", "hashes": "$1$abc$def"}, "hashes.format", common.ISSUE_ERROR},
+		{map[string]string{"algorithm": "99999", "hashes": "anything"}, "", ""},
+	}
+
+	for _, test := range tests {
+		issues := h.CheckInput(test.params)
+		if test.code == "" {
+			if len(issues) != 0 {
+				t.Errorf("Expected no issues for %v but got %+v", test.params, issues)
+			}
+			continue
+		}
+
+		if len(issues) != 1 || issues[0].Code != test.code || issues[0].Severity != test.severity {
+			t.Errorf("Expected a %s %s for %v but got %+v", test.severity, test.code, test.params, issues)
+		}
+	}
+}
+
+func TestDriverCrash(t *testing.T) {
+	crashes := map[string]string{
+		"ERROR: cuCtxSynchronize(): CUDA_ERROR_LAUNCH_FAILED\n":               "CUDA_ERROR_LAUNCH_FAILED",
+		"clEnqueueNDRangeKernel(): CL_OUT_OF_RESOURCES\n":                     "CL_OUT_OF_RESOURCES",
+		"cuInit(): 999\nATTENTION! CUDA initialization failed.\n":             "cuInit()",
+		"hipModuleLaunchKernel(): hipErrorIllegalAddress\nStarted: Mon Jan 1": "hipErrorIllegalAddress",
+	}
+	for stderr, want := range crashes {
+		if got := driverCrash(stderr); got != want {
+			t.Errorf("Expected %q for %q, got %q", want, stderr, got)
+		}
+	}
+
+	if got := driverCrash("Hashfile 'hashes.txt' on line 1: Token length exception\n"); got != "" {
+		t.Errorf("A job error must not be taken as a driver crash, got %q", got)
+	}
+}
+
+func TestSetBinary(t *testing.T) {
+	defer func() {
+		managed.path, managed.version = "", ""
+	}()
+
+	bin := filepath.Join(t.TempDir(), "hashcat")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\necho v6.2.6\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewTooler()
+	u, ok := tool.(common.BinaryUser)
+	if !ok {
+		t.Fatal("Hashcat does not use binaries from the queue")
+	}
+	if u.BinaryName() != "hashcat" {
+		t.Errorf("Unexpected binary name %s", u.BinaryName())
+	}
+
+	// A binary that does not run is not used
+	if err := u.SetBinary(filepath.Join(t.TempDir(), "missing"), "6.2.6"); err == nil {
+		t.Error("Missing binary was used")
+	}
+	if binPath() != config.BinPath {
+		t.Errorf("Missing binary changed the binary to %s", binPath())
+	}
+
+	// The version is the one the binary reports
+	if err := u.SetBinary(bin, "6.2.5"); err != nil {
+		t.Fatal(err)
+	}
+	if binPath() != bin || tool.Version() != "6.2.6" {
+		t.Errorf("Expected binary %s 6.2.6, got %s %s", bin, binPath(), tool.Version())
+	}
+}
+
+func TestMarkovModels(t *testing.T) {
+	defer func(c hcConfig, path string) { config, configPath = c, path }(config, configPath)
+
+	dir := t.TempDir()
+	for _, name := range []string{"corporate.hcstat", "rockyou.hcstat2", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models := markovModels(dir)
+	if len(models) != 2 || models[0].Name != "corporate" || models[1].Name != "rockyou" {
+		t.Fatalf("Unexpected markov models %v", models)
+	}
+	if arg := markovArg(models[0].Path); arg != "--markov-hcstat="+filepath.Join(dir, "corporate.hcstat") {
+		t.Errorf("Unexpected argument %s", arg)
+	}
+	if arg := markovArg(models[1].Path); arg != "--markov-hcstat2="+filepath.Join(dir, "rockyou.hcstat2") {
+		t.Errorf("Unexpected argument %s", arg)
+	}
+
+	// Setup offers the models of the markov folder after those set by path
+	conf := filepath.Join(dir, "hashcat.conf")
+	dict := filepath.Join(dir, "notes.txt")
+	data := "[Basic]\nbinPath=" + filepath.Join(dir, "hashcat") + "\nmarkovdir=" + dir +
+		"\n[Dictionaries]\nnotes=" + dict + "\n[Rules]\nnotes=" + dict +
+		"\n[BruteCharset]\nlower=?l\n[Markov]\nrockyou=/mnt/models/rockyou.hcstat\n"
+	if err := ioutil.WriteFile(conf, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Setup(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, m := range config.MarkovModels {
+		got[m.Name] = m.Path
+	}
+	want := map[string]string{
+		"corporate": filepath.Join(dir, "corporate.hcstat"),
+		"rockyou":   "/mnt/models/rockyou.hcstat",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected markov models %v, got %v", want, got)
+	}
+}
+
+func TestPreviewStopsReading(t *testing.T) {
+	defer func(c hcConfig) { config = c }(config)
+	defer func() {
+		managed.path, managed.version = "", ""
+	}()
+
+	// A rule file generating far more candidates than are shown
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "hashcat")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\ncat >/dev/null\nexec yes candidate\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	managed.path = bin
+	config = hcConfig{Rules: rules{{Name: "huge", Path: filepath.Join(dir, "huge.rule")}}}
+
+	tool := &hashcatTooler{}
+	start := time.Now()
+	candidates, err := tool.Preview(map[string]string{"dict_rules": "huge"}, []string{"password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != maxPreviewCandidates || candidates[0] != "candidate" {
+		t.Errorf("Expected %d candidates, got %d", maxPreviewCandidates, len(candidates))
+	}
+	if time.Since(start) >= previewTimeout {
+		t.Error("Preview waited for the timeout instead of stopping hashcat")
+	}
+
+	// One endless line is cut off rather than read into memory
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\ncat >/dev/null\nexec tr '\\0' a </dev/zero\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Preview(map[string]string{"dict_rules": "huge"}, []string{"password"}); err != nil {
+		t.Fatal(err)
+	}
+}
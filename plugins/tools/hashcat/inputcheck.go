@@ -0,0 +1,169 @@
+package hashcat
+
+import (
+	"github.com/jmmcatee/cracklord/common"
+	"strconv"
+	"strings"
+)
+
+// The format hashcat expects for each line of an algorithm
+type hashFormat struct {
+	HexLength int    // Length of a raw hex digest, 0 if the hash is not plain hex
+	Salted    bool   // The digest is followed by a colon and the salt
+	Prefix    string // Text every hash starts with
+}
+
+// Formats of common algorithms, others are not checked
+var hashFormats = map[string]hashFormat{
+	"0":     {HexLength: 32},
+	"900":   {HexLength: 32},
+	"100":   {HexLength: 40},
+	"1400":  {HexLength: 64},
+	"1700":  {HexLength: 128},
+	"1000":  {HexLength: 32},
+	"3000":  {HexLength: 32},
+	"10":    {HexLength: 32, Salted: true},
+	"20":    {HexLength: 32, Salted: true},
+	"110":   {HexLength: 40, Salted: true},
+	"1410":  {HexLength: 64, Salted: true},
+	"1710":  {HexLength: 128, Salted: true},
+	"1100":  {HexLength: 32, Salted: true},
+	"500":   {Prefix: "$1$"},
+	"1800":  {Prefix: "$6$"},
+	"3200":  {Prefix: "$2"},
+	"2100":  {Prefix: "$DCC2$"},
+	"7500":  {Prefix: "$krb5pa$23$"},
+	"13100": {Prefix: "$krb5tgs$23$"},
+	"18200": {Prefix: "$krb5asrep$23$"},
+}
+
+// Modes often picked by mistake for a hash of the given hex length
+var hexLengthModes = map[int]string{
+	32:  "MD5 (0) or NTLM (1000)",
+	40:  "SHA1 (100)",
+	64:  "SHA-256 (1400)",
+	128: "SHA-512 (1700)",
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Check the hashes match the format of the selected algorithm. When no line
+// matches the job is refused as hashcat would exit without cracking anything.
+func (h *hashcatTooler) CheckInput(params map[string]string) []common.InputIssue {
+	issues := []common.InputIssue{}
+
+	format, ok := hashFormats[params["algorithm"]]
+	if !ok {
+		return issues
+	}
+
+	var total, bad, users, unsalted int
+	var badLines []int
+	lengths := map[int]int{}
+	common.HashLines(params["hashes"], func(n int, line string) {
+		total++
+
+		hash := line
+		if format.Salted {
+			i := strings.Index(line, ":")
+			if i < 0 {
+				unsalted++
+			} else {
+				hash = line[:i]
+			}
+		}
+
+		var valid bool
+		if format.HexLength > 0 {
+			valid = len(hash) == format.HexLength && isHex(hash)
+			if !valid && isHex(hash) {
+				lengths[len(hash)]++
+			}
+
+			// A user:hash line has the hash after the last colon
+			if !valid && !format.Salted {
+				if i := strings.LastIndex(line, ":"); i >= 0 && len(line)-i-1 == format.HexLength && isHex(line[i+1:]) {
+					users++
+				}
+			}
+		} else {
+			valid = strings.HasPrefix(hash, format.Prefix)
+		}
+
+		if !valid {
+			bad++
+			if len(badLines) < 10 {
+				badLines = append(badLines, n)
+			}
+		}
+	})
+
+	if total == 0 {
+		return issues
+	}
+
+	severity := common.ISSUE_WARNING
+	if bad == total {
+		severity = common.ISSUE_ERROR
+	}
+
+	switch {
+	case bad == 0:
+	case users == bad:
+		issues = append(issues, common.InputIssue{
+			Severity: severity,
+			Code:     "hashes.usernames",
+			Message:  "The hashes look like username:hash lines, select the option that hashes include usernames.",
+			Lines:    badLines,
+			Count:    bad,
+		})
+	case format.HexLength > 0:
+		message := "Hashes do not match the selected algorithm, which expects " + strconv.Itoa(format.HexLength) + " hex characters"
+		if format.Salted {
+			message += " followed by a colon and the salt"
+		}
+		message += "."
+		for length, count := range lengths {
+			if mode, ok := hexLengthModes[length]; ok && count*2 > bad {
+				message += " Most are " + strconv.Itoa(length) + " characters long, which matches " + mode + "."
+			}
+		}
+		issues = append(issues, common.InputIssue{
+			Severity: severity,
+			Code:     "hashes.length",
+			Message:  message,
+			Lines:    badLines,
+			Count:    bad,
+		})
+	default:
+		issues = append(issues, common.InputIssue{
+			Severity: severity,
+			Code:     "hashes.format",
+			Message:  "Hashes do not match the selected algorithm, which expects hashes starting with " + format.Prefix + ".",
+			Lines:    badLines,
+			Count:    bad,
+		})
+	}
+
+	if format.Salted && unsalted > 0 {
+		severity := common.ISSUE_WARNING
+		if unsalted == total {
+			severity = common.ISSUE_ERROR
+		}
+		issues = append(issues, common.InputIssue{
+			Severity: severity,
+			Code:     "hashes.salt",
+			Message:  "The selected algorithm is salted but some hashes have no salt, give them as hash:salt.",
+			Count:    unsalted,
+		})
+	}
+
+	return issues
+}
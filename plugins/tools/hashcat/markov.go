@@ -0,0 +1,46 @@
+package hashcat
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Read the markov models in a folder, such as a mount of the models uploaded to
+// the queue server. Each .hcstat or .hcstat2 file is a model named after the
+// file without its extension.
+func markovModels(dir string) dictionaries {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"dir":   dir,
+			"error": err.Error(),
+		}).Warn("Unable to read the markov model folder.")
+		return nil
+	}
+
+	var models dictionaries
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".hcstat" && ext != ".hcstat2") {
+			continue
+		}
+
+		models = append(models, dictionary{
+			Name: strings.TrimSuffix(f.Name(), ext),
+			Path: filepath.Join(dir, f.Name()),
+		})
+	}
+	return models
+}
+
+// The argument giving hashcat a markov model, models in the .hcstat2 format of
+// newer hashcat versions have their own
+func markovArg(path string) string {
+	if filepath.Ext(path) == ".hcstat2" {
+		return "--markov-hcstat2=" + path
+	}
+	return "--markov-hcstat=" + path
+}
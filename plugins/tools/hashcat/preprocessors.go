@@ -0,0 +1,37 @@
+package hashcat
+
+import (
+	"strings"
+)
+
+// A preprocessor is a program such as princeprocessor or maskprocessor that
+// generates candidates which are piped into hashcat through stdin
+type preprocessor struct {
+	Name    string
+	Command string
+}
+
+type preprocessors []preprocessor
+
+func (p preprocessors) Len() int {
+	return len(p)
+
+}
+func (p preprocessors) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+func (p preprocessors) Less(i, j int) bool {
+	return p[i].Name < p[j].Name
+}
+
+// Build the command line for a preprocessor. The {dictionary} placeholder is
+// replaced with the path of the selected dictionary.
+func (p preprocessor) Args(dictPath string) []string {
+	args := strings.Fields(p.Command)
+	for i := range args {
+		args[i] = strings.Replace(args[i], "{dictionary}", dictPath, -1)
+	}
+
+	return args
+}
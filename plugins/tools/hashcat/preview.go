@@ -0,0 +1,109 @@
+package hashcat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	log "github.com/Sirupsen/logrus"
+	"github.com/jmmcatee/cracklord/common/shared"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Limits on previews so they stay quick enough to run from the API
+const (
+	maxPreviewWords      = 100
+	maxPreviewCandidates = 1000
+	maxPreviewBytes      = 1 << 20 // Output of hashcat read for the candidates
+	previewTimeout       = 10 * time.Second
+)
+
+// Find the rule file selected in the parameters from either attack tab
+func previewRuleFile(params map[string]string) string {
+	for _, key := range []string{"pre_rules", "dict_rules"} {
+		ruleKey, ok := params[key]
+		if !ok || ruleKey == "" {
+			continue
+		}
+
+		sort.Sort(config.Rules)
+		i := sort.Search(len(config.Rules), func(i int) bool { return config.Rules[i].Name >= ruleKey })
+		if i < len(config.Rules) && config.Rules[i].Name == ruleKey {
+			return config.Rules[i].Path
+		}
+	}
+
+	return ""
+}
+
+// Preview the candidates the selected rules would generate for the sample
+// words using hashcat --stdout
+func (h *hashcatTooler) Preview(params map[string]string, words []string) ([]string, error) {
+	if len(words) == 0 {
+		return []string{}, errors.New("No sample words were provided.")
+	}
+	if len(words) > maxPreviewWords {
+		words = words[:maxPreviewWords]
+	}
+
+	ruleFile := previewRuleFile(params)
+	if ruleFile == "" {
+		// Without rules the candidates are the words themselves
+		return words, nil
+	}
+
+	ruleFile, err := shared.Path(ruleFile)
+	if err != nil {
+		return []string{}, err
+	}
+
+	cmd := exec.Command(binPath(), "--stdout", "-r", ruleFile)
+	cmd.Stdin = strings.NewReader(strings.Join(words, "\n") + "\n")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return []string{}, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return []string{}, err
+	}
+
+	// Make sure a bad rule file can't tie up the resource
+	timer := time.AfterFunc(previewTimeout, func() {
+		cmd.Process.Kill()
+	})
+
+	// Only the candidates shown are read, a rule file can make a lot more
+	out := &io.LimitedReader{R: stdout, N: maxPreviewBytes}
+	candidates := []string{}
+	scanner := bufio.NewScanner(out)
+	for len(candidates) < maxPreviewCandidates && scanner.Scan() {
+		candidates = append(candidates, scanner.Text())
+	}
+
+	// Hashcat is stopped once there are enough candidates
+	stopped := len(candidates) == maxPreviewCandidates || out.N == 0 || scanner.Err() != nil
+	if stopped {
+		cmd.Process.Kill()
+		stdout.Close()
+	}
+
+	err = cmd.Wait()
+	timer.Stop()
+	if err != nil && !stopped {
+		log.WithFields(log.Fields{
+			"error":  err.Error(),
+			"stderr": stderr.String(),
+		}).Error("Tool (hashcat): Unable to generate preview")
+		return []string{}, errors.New("Unable to generate preview: " + strings.TrimSpace(stderr.String()))
+	}
+
+	return candidates, nil
+}
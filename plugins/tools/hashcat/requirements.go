@@ -0,0 +1,103 @@
+package hashcat
+
+import (
+	"github.com/jmmcatee/cracklord/common"
+	"strconv"
+	"strings"
+)
+
+// Approximate VRAM in megabytes each GPU needs for memory hard algorithms at
+// the default workload profile. Other algorithms fit on any GPU.
+var algorithmMemory = map[string]int64{
+	"8900":  2048, // scrypt
+	"9300":  2048, // Cisco-IOS $9$ (scrypt)
+	"15700": 8192, // Ethereum Wallet, SCRYPT
+	"22700": 2048, // MultiBit HD (scrypt)
+}
+
+// Higher workload profiles run more work at once and need more memory
+var workloadScale = map[string]float64{
+	"1": 0.5,
+	"2": 1,
+	"3": 2,
+	"4": 4,
+}
+
+// Host memory in bytes hashcat needs for each loaded hash
+const memoryPerHash = 512
+
+// Host memory in megabytes below which jobs are not constrained
+const minMemoryConstraint = 1024
+
+func (h *hashcatTooler) JobRequirements(params map[string]string) (common.Constraints, error) {
+	var c common.Constraints
+
+	if mem, ok := algorithmMemory[params["algorithm"]]; ok {
+		scale, ok := workloadScale[params["workload"]]
+		if !ok {
+			scale = 1
+		}
+		c.MinGPUMemory = int64(float64(mem) * scale)
+	}
+
+	// Very large hash lists need a lot of host memory to load, smaller lists
+	// are not limited so resources that do not report memory can run them
+	hashes := int64(strings.Count(params["hashes"], "\n") + 1)
+	if mem := hashes * memoryPerHash / (1024 * 1024); mem >= minMemoryConstraint {
+		c.MinMemory = mem
+	}
+
+	// Jobs only run on resources with the same wordlists and rules. Only
+	// files in the directories the resource scans have a hash, others are
+	// left for the resource to find.
+	for _, path := range selectedFiles(params) {
+		if sum, ok := common.CachedFileHash(path); ok {
+			c = c.Merge(common.Constraints{Files: sum})
+		}
+	}
+
+	return c, nil
+}
+
+// The paths of the dictionaries, rules and markov model the parameters select
+func selectedFiles(params map[string]string) []string {
+	var paths []string
+
+	for _, key := range []string{"dict_dictionaries", "pre_dictionaries"} {
+		for _, d := range config.Dictionaries {
+			if params[key] != "" && d.Name == params[key] {
+				paths = append(paths, d.Path)
+			}
+		}
+	}
+
+	if ruleFile := previewRuleFile(params); ruleFile != "" {
+		paths = append(paths, ruleFile)
+	}
+
+	for _, m := range config.MarkovModels {
+		if params["brute_markov"] != "" && m.Name == params["brute_markov"] {
+			paths = append(paths, m.Path)
+		}
+	}
+
+	return paths
+}
+
+// Get the workload profile argument, defaulting to hashcat's own default
+func workloadArg(params map[string]string, limit int) []string {
+	n, err := strconv.Atoi(params["workload"])
+	if err != nil || n < 1 || n > 4 {
+		n = 0
+	}
+
+	// The profile of the resource can lower the workload the job asked for
+	if limit > 0 && (n == 0 || n > limit) {
+		n = limit
+	}
+	if n == 0 {
+		return nil
+	}
+
+	return []string{"-w", strconv.Itoa(n)}
+}
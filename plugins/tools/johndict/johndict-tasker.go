@@ -92,6 +92,7 @@ type johndictTasker struct {
 	stdoutPipe   io.ReadCloser
 	stdinPipe    io.WriteCloser
 	doneWaitChan chan struct{}
+	bin          string // Binary the task was created with, its status and restores keep using it
 }
 
 /*
@@ -108,6 +109,7 @@ func newJohnDictTask(j common.Job) (common.Tasker, error) {
 
 	// Assign the job information
 	v.job = j
+	v.bin = binPath()
 
 	// Build the working directory from the configuration and job UUID
 	v.wd = filepath.Join(config.WorkingDir, v.job.UUID)
@@ -214,6 +216,7 @@ func newJohnDictTask(j common.Job) (common.Tasker, error) {
 	if config.Arguments != "" {
 		args = append(args, config.Arguments)
 	}
+	args = append(args, v.job.ExtraArgs...) // Administrator arguments for this job
 
 	// Take the hashes given and create a file
 	hashFilePath := filepath.Join(v.wd, "hashes.txt")
@@ -248,6 +251,7 @@ func newJohnDictTask(j common.Job) (common.Tasker, error) {
 
 	// Configure return values
 	v.job.OutputTitles = []string{"Plaintext", "Hash"}
+	v.job.OutputMetrics = []common.Metric{common.PlaintextColumn, common.HashColumn}
 
 	return &v, nil
 }
@@ -263,7 +267,7 @@ func (v *johndictTasker) Status() common.Job {
 	defer v.mux.Unlock()
 
 	// Run john --status command
-	statusExec := exec.Command(config.BinPath, "--status="+v.job.UUID)
+	statusExec := exec.Command(v.bin, "--status="+v.job.UUID)
 	statusExec.Dir = v.wd
 	status, err := statusExec.CombinedOutput()
 	if err != nil {
@@ -306,6 +310,7 @@ func (v *johndictTasker) Status() common.Job {
 		if v.job.PerformanceTitle == "" {
 			// We need to set the units the first time
 			v.job.PerformanceTitle = match[6]
+			v.job.PerformanceMetric = common.RateMetric(match[6])
 		}
 
 		var mag float64
@@ -393,13 +398,14 @@ func (v *johndictTasker) Run() error {
 
 	// Set commands for first start or restoring
 	if common.IsNew(v.job.Status) {
-		v.cmd = *exec.Command(config.BinPath, v.args...)
+		v.cmd = *exec.Command(v.bin, v.args...)
 	} else {
 		restoreArgs := []string{"--restore=" + v.job.UUID}
-		v.cmd = *exec.Command(config.BinPath, restoreArgs...)
+		v.cmd = *exec.Command(v.bin, restoreArgs...)
 	}
 
 	v.cmd.Dir = v.wd
+	v.cmd.Env = v.job.CommandEnv()
 
 	log.WithFields(log.Fields{
 		"status": v.job.Status,
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/jmmcatee/cracklord/common"
@@ -33,6 +34,45 @@ type johndictConfig struct {
 */
 var config johndictConfig
 
+// Binary installed from the repository of the queue, run instead of the one in
+// the config file when set
+var managed struct {
+	sync.RWMutex
+	path    string
+	version string
+}
+
+// The john binary new tasks run
+func binPath() string {
+	managed.RLock()
+	defer managed.RUnlock()
+
+	if managed.path != "" {
+		return managed.path
+	}
+	return config.BinPath
+}
+
+// Ask the john binary for its version, such as 1.9.0-jumbo-1 from the
+// Version line of its build information
+func binaryVersion(binPath string) string {
+	out, err := exec.Command(binPath, "--list=build-info").Output()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"binpath": binPath,
+			"error":   err.Error(),
+		}).Warn("Unable to read the john version.")
+		return ""
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "Version:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Version:"))
+		}
+	}
+	return ""
+}
+
 // Setup function for the John Dictionary plugin
 func Setup(path string) error {
 	log.Debug("Setting up johndict tool")
@@ -160,9 +200,40 @@ func (h *johndictTooler) Type() string {
 	tool (name + version) at a time.
 */
 func (h *johndictTooler) Version() string {
+	managed.RLock()
+	defer managed.RUnlock()
+
+	if managed.version != "" {
+		return managed.version
+	}
 	return "1.8.0-jumbo-1"
 }
 
+func (h *johndictTooler) BinaryName() string {
+	return "john"
+}
+
+// Run a binary installed from the queue for new tasks instead of the one in
+// the config file, as long as it runs on this resource
+func (h *johndictTooler) SetBinary(path, version string) error {
+	detected := binaryVersion(path)
+	if detected == "" {
+		return errors.New("The john binary " + path + " could not be run.")
+	}
+
+	managed.Lock()
+	managed.path = path
+	managed.version = detected
+	managed.Unlock()
+
+	log.WithFields(log.Fields{
+		"path":    path,
+		"version": detected,
+	}).Info("John binary from the queue in use.")
+
+	return nil
+}
+
 /*
 	Return the UUID of this tool.  Note, if the same tool is running on multiple
 	resources they may have different UUIDs, this is expected behavior, which is
@@ -3,9 +3,13 @@ package johndict
 import (
 	"bytes"
 	"encoding/csv"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/jmmcatee/cracklord/common"
 )
 
 func TestParsingFormats(t *testing.T) {
@@ -147,7 +151,7 @@ func TestParsingETA(t *testing.T) {
 		nt, _ := parseJohnETA(v.in)
 
 		if printTimeUntil(nt) != v.out {
-			t.Errorf(printTimeUntil(nt) + " != " + v.out)
+			t.Error(printTimeUntil(nt) + " != " + v.out)
 		}
 
 		// d := time.Since(t)
@@ -155,13 +159,57 @@ func TestParsingETA(t *testing.T) {
 	}
 }
 
-var testingRoot = ""
+var testingRoot = "testdata/"
 
 func TestConfigSetup(t *testing.T) {
 	err := Setup(testingRoot + "johndict.conf")
 	if err != nil {
-		t.Error(err.Error())
+		t.Fatal(err.Error())
+	}
+
+	if len(config.Formats) != 10 || config.Formats[0] != "LM" {
+		t.Errorf("Unexpected formats %v", config.Formats)
+	}
+	if config.Dictionaries["dictionary1"] != "testdata/dictionary1.txt" {
+		t.Errorf("Unexpected dictionaries %v", config.Dictionaries)
 	}
 
 	// TODO: Configure fails for empty fields
 }
+
+func TestSetBinary(t *testing.T) {
+	defer func() {
+		managed.path, managed.version = "", ""
+	}()
+
+	bin := filepath.Join(t.TempDir(), "john")
+	script := "#!/bin/sh\necho 'Version: 1.9.0-jumbo-1'\necho 'Build: linux-gnu 64-bit x86_64 AVX2 AC'\n"
+	if err := ioutil.WriteFile(bin, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewTooler()
+	u, ok := tool.(common.BinaryUser)
+	if !ok {
+		t.Fatal("John does not use binaries from the queue")
+	}
+	if u.BinaryName() != "john" {
+		t.Errorf("Unexpected binary name %s", u.BinaryName())
+	}
+
+	// A binary that does not run is not used
+	if err := u.SetBinary(filepath.Join(t.TempDir(), "missing"), "1.9.0"); err == nil {
+		t.Error("Missing binary was used")
+	}
+	if binPath() != config.BinPath || tool.Version() != "1.8.0-jumbo-1" {
+		t.Errorf("Missing binary changed the binary to %s %s", binPath(), tool.Version())
+	}
+
+	// The version is the one the binary reports
+	if err := u.SetBinary(bin, "1.9.0"); err != nil {
+		t.Fatal(err)
+	}
+	if binPath() != bin || tool.Version() != "1.9.0-jumbo-1" {
+		t.Errorf("Expected binary %s 1.9.0-jumbo-1, got %s %s", bin, binPath(), tool.Version())
+	}
+}
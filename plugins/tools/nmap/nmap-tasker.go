@@ -147,6 +147,7 @@ func newNmapTask(j common.Job) (common.Tasker, error) {
 	if config.Arguments != "" {
 		args = append(args, config.Arguments)
 	}
+	args = append(args, t.job.ExtraArgs...) // Administrator arguments for this job
 
 	// Take the target addresses given and create a file
 	inFile, err := os.Create(filepath.Join(t.wd, "input.txt"))
@@ -166,6 +167,7 @@ func newNmapTask(j common.Job) (common.Tasker, error) {
 	log.WithField("arguments", args).Debug("Arguments complete")
 
 	t.job.PerformanceTitle = "Packets / sec"
+	t.job.PerformanceMetric = common.Metric{Name: "packets", Title: t.job.PerformanceTitle, Unit: "packets/s", Kind: common.METRIC_RATE}
 	t.job.OutputTitles = []string{"IP Address", "Hostname", "Protocol", "Port", "State", "Service"}
 	t.job.TotalHashes, err = calcTotalTargets(t.job.Parameters["targets"])
 	if err != nil {
@@ -262,6 +264,7 @@ func (v *nmapTasker) Run() error {
 	}
 
 	v.cmd.Dir = v.wd
+	v.cmd.Env = v.job.CommandEnv()
 
 	log.WithFields(log.Fields{
 		"status": v.job.Status,
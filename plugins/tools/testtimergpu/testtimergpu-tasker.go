@@ -34,6 +34,7 @@ func newTestTimerTask(j common.Job) (common.Tasker, error) {
 	t.job = j
 	t.job.CrackedHashes = 0
 	t.job.PerformanceTitle = "Time data"
+	t.job.PerformanceMetric = common.Metric{Name: "cracked", Title: t.job.PerformanceTitle, Unit: "hashes", Kind: common.METRIC_COUNT}
 
 	var err error
 	t.job.TotalHashes, err = strconv.ParseInt(j.Parameters["seconds"], 10, 0)
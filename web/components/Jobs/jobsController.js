@@ -28,7 +28,7 @@ cracklord.controller('JobsController', ['$scope', 'JobsService', 'QueueService',
 						}
 					}
 					
-					if(data[i].status == "quit" || data[i].status == "failed" || data[i].status == "done") {
+					if(data[i].status == "quit" || data[i].status == "failed" || data[i].status == "done" || data[i].status == "expired") {
 						// First let's check and see if the job is running in our array of running jobs.  if so, we need to delete it.
 						cur_idx = $scope.currentjobs.map(function(e) { return e.id; }).indexOf(data[i].id);
 						if(cur_idx >= 0) {
@@ -152,7 +152,10 @@ cracklord.directive('jobDetail', ['JobsService', 'ToolsService', 'growl', 'Resou
 
 			$scope.processLine = function(animate) {
 				$scope.line = {};
-				$scope.line.series = [ $scope.detail.performancetitle ]; 
+				// Values are charted in the base unit of the metric, such as H/s
+				var metric = $scope.detail.performancemetric;
+				var scale = (metric && metric.scale) || 1;
+				$scope.line.series = [ metric ? metric.title + (metric.unit ? ' (' + metric.unit + ')' : '') : $scope.detail.performancetitle ];
 				$scope.line.data = [];
 				$scope.line.data[0] = [];
 				$scope.line.labels = [];
@@ -171,7 +174,7 @@ cracklord.directive('jobDetail', ['JobsService', 'ToolsService', 'growl', 'Resou
 				var step = len > 60 ? 3 : 1
 				for (var i = min; i <= len; i=i+step) {
 					time = sorted_times[i]
-					$scope.line.data[0].push($scope.detail.performancedata[time]);
+					$scope.line.data[0].push($scope.detail.performancedata[time] * scale);
 					if((i - min) % 20 == 0) {
 						var date = new Date(time * 1000)
 						var m = ('0'+date.getMinutes()).slice(-2);
@@ -273,6 +276,7 @@ cracklord.controller('CreateJobController', ['$scope', '$state', 'ToolsService',
 		newjob.toolid = $scope.formData.tool.id;
 		newjob.name = $scope.formData.name;
 		newjob.params = $scope.formData.params;
+		newjob.formversion = $scope.tool.formversion;
 		
 		JobsService.save(newjob, 
 			function(data) {
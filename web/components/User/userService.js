@@ -1,7 +1,10 @@
 cracklord.service('UserSession', ['$cookies', function($cookies) {
 	this.create  = function(userToken, userName, userRole) {
+		// The token is left out when the server keeps it in its own cookie
 		this.token = userToken
-		$cookies.put('usertoken', userToken);
+		if(userToken) {
+			$cookies.put('usertoken', userToken);
+		}
 		this.name = userName;
 		$cookies.put('username', userName);
 		this.role = userRole;
@@ -19,7 +22,7 @@ cracklord.service('UserSession', ['$cookies', function($cookies) {
 		this.token = $cookies.get('usertoken');
 		this.name = $cookies.get('username');
 		this.role = $cookies.get('userrole');
-		if(this.token && this.name && this.role) {
+		if(this.name && this.role) {
 			return true;
 		} 
 		return false;
@@ -196,7 +196,7 @@ cracklord.directive('draghandle', ['growl', 'AuthService', 'USER_ROLES', functio
         replace: true,
         template: '<div class="btn btn-primary draghandle"><i class="fa fa-fw fa-arrows-v"></i></div>',
         link: function($scope, $element, $attrs) {
-            if(!AuthService.isAuthorized([USER_ROLES.standard, USER_ROLES.admin])) {
+            if(!AuthService.isAuthorized([USER_ROLES.admin])) {
                 $element.remove();
             }   
         }
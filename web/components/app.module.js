@@ -48,13 +48,16 @@ cracklord.constant('USER_ROLES', {
 cracklord.constant('JOB_STATUS_RUNNING', {
    running: 'running',
    paused: 'paused',
-   created: 'created'
+   created: 'created',
+   draft: 'draft',
+   approval: 'approval'
 });
 
 cracklord.constant('JOB_STATUS_COMPLETED', {
    done: 'done',
    failed: 'failed',
-   quit: 'quit'
+   quit: 'quit',
+   expired: 'expired'
 });
 
 cracklord.constant('QUEUE_STATUS', {